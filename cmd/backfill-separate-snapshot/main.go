@@ -0,0 +1,84 @@
+// backfill-separate-snapshot 为历史分账明细补写商品名称、品牌和分类快照
+//
+// 按ID分批更新，每批一个短事务，可以在服务运行时执行，重复执行只处理仍缺少快照的明细：
+//
+//	go run ./cmd/backfill-separate-snapshot -config ./config/config.yaml -batch 1000
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"charonoms/internal/infrastructure/config"
+	"charonoms/internal/infrastructure/logger"
+	financialImpl "charonoms/internal/infrastructure/persistence/financial"
+	"charonoms/internal/infrastructure/persistence/mysql"
+
+	"go.uber.org/zap"
+)
+
+func main() {
+	configPath := flag.String("config", "./config/config.yaml", "配置文件路径")
+	batchSize := flag.Int("batch", 1000, "每批处理的分账明细ID数量")
+	flag.Parse()
+
+	if *batchSize <= 0 {
+		fmt.Println("batch must be positive")
+		os.Exit(1)
+	}
+
+	// 加载配置
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 初始化日志
+	if err := logger.Init(cfg.Logger); err != nil {
+		fmt.Printf("Failed to init logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	// 初始化数据库
+	if err := mysql.Init(cfg.Database); err != nil {
+		logger.Fatal("Failed to init database", zap.Error(err))
+	}
+	defer func() {
+		if err := mysql.Close(); err != nil {
+			logger.Error("Failed to close database", zap.Error(err))
+		}
+	}()
+
+	separateRepo := financialImpl.NewSeparateAccountRepository(mysql.DB)
+	maxID, err := separateRepo.MaxID()
+	if err != nil {
+		logger.Fatal("Failed to query max separate account id", zap.Error(err))
+	}
+
+	var updated int64
+	for afterID := 0; afterID < maxID; afterID += *batchSize {
+		upToID := afterID + *batchSize
+		if upToID > maxID {
+			upToID = maxID
+		}
+		n, err := separateRepo.BackfillGoodsSnapshots(afterID, upToID)
+		if err != nil {
+			logger.Fatal("Failed to backfill separate account snapshots",
+				zap.Int("after_id", afterID),
+				zap.Int("up_to_id", upToID),
+				zap.Error(err),
+			)
+		}
+		updated += n
+		logger.Info("Backfilled separate account snapshots",
+			zap.Int("up_to_id", upToID),
+			zap.Int("max_id", maxID),
+			zap.Int64("updated", updated),
+		)
+	}
+
+	logger.Info("Separate account snapshot backfill finished", zap.Int64("updated", updated))
+}
@@ -5,6 +5,7 @@ import (
 	"charonoms/internal/infrastructure/logger"
 	"charonoms/internal/infrastructure/persistence/mysql"
 	"charonoms/internal/interfaces/http/router"
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
@@ -40,8 +41,12 @@ func main() {
 		}
 	}()
 
+	// 后台任务随进程退出停止
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// 设置路由
-	r := router.SetupRouter(cfg)
+	r := router.SetupRouter(ctx, cfg)
 
 	// 启动服务器
 	addr := ":" + cfg.Server.Port
@@ -140,6 +140,8 @@ createApp({
             coachCurrentPage: 1,
             orderCurrentPage: 1,
             childOrderCurrentPage: 1,
+            orderTotal: 0,
+            childOrderTotal: 0,
             accountCurrentPage: 1,
             attributeCurrentPage: 1,
             classifyCurrentPage: 1,
@@ -910,23 +912,19 @@ createApp({
         coachTotalPages() {
             return Math.ceil(this.filteredCoaches.length / this.pageSize);
         },
-        // 订单分页数据
+        // 订单分页数据（服务端分页，当前页即为全部数据）
         paginatedOrders() {
-            const start = (this.orderCurrentPage - 1) * this.pageSize;
-            const end = start + this.pageSize;
-            return this.filteredOrders.slice(start, end);
+            return this.filteredOrders;
         },
         orderTotalPages() {
-            return Math.ceil(this.filteredOrders.length / this.pageSize);
+            return Math.ceil(this.orderTotal / this.pageSize);
         },
-        // 子订单分页数据
+        // 子订单分页数据（服务端分页，当前页即为全部数据）
         paginatedChildOrders() {
-            const start = (this.childOrderCurrentPage - 1) * this.pageSize;
-            const end = start + this.pageSize;
-            return this.filteredChildOrders.slice(start, end);
+            return this.filteredChildOrders;
         },
         childOrderTotalPages() {
-            return Math.ceil(this.filteredChildOrders.length / this.pageSize);
+            return Math.ceil(this.childOrderTotal / this.pageSize);
         },
         // 订单应收金额（所有商品的商品总价之和）
         orderTotalReceivable() {
@@ -2194,9 +2192,17 @@ createApp({
         async fetchOrders() {
             this.loadingOrders = true;
             try {
-                const response = await axios.get('/api/orders', { withCredentials: true });
+                const params = {
+                    page: this.orderCurrentPage,
+                    page_size: this.pageSize
+                };
+                if (this.orderFilters.id) params.id = this.orderFilters.id;
+                if (this.orderFilters.uid) params.student_id = this.orderFilters.uid;
+                if (this.orderFilters.status !== '') params.status = this.orderFilters.status;
+                const response = await axios.get('/api/orders', { params, withCredentials: true });
                 this.orders = response.data.orders;
                 this.filteredOrders = this.orders;
+                this.orderTotal = response.data.total;
             } catch (err) {
                 console.error('获取订单失败:', err);
                 this.error = '获取订单失败';
@@ -2207,12 +2213,8 @@ createApp({
 
         // 订单筛选功能
         searchOrders() {
-            this.filteredOrders = this.orders.filter(order => {
-                const idMatch = !this.orderFilters.id || order.id === parseInt(this.orderFilters.id);
-                const uidMatch = !this.orderFilters.uid || order.uid === parseInt(this.orderFilters.uid);
-                const statusMatch = this.orderFilters.status === '' || order.status === parseInt(this.orderFilters.status);
-                return idMatch && uidMatch && statusMatch;
-            });
+            this.orderCurrentPage = 1;
+            this.fetchOrders();
         },
 
         // 重置订单筛选
@@ -2222,7 +2224,8 @@ createApp({
                 uid: '',
                 status: ''
             };
-            this.filteredOrders = this.orders;
+            this.orderCurrentPage = 1;
+            this.fetchOrders();
         },
 
         // 获取订单状态文本
@@ -4855,6 +4858,7 @@ createApp({
         changeOrderPage(page) {
             if (page >= 1 && page <= this.orderTotalPages) {
                 this.orderCurrentPage = page;
+                this.fetchOrders();
             }
         },
 
@@ -4864,9 +4868,18 @@ createApp({
         async fetchChildOrders() {
             this.loadingChildOrders = true;
             try {
-                const response = await axios.get('/api/childorders', { withCredentials: true });
+                const params = {
+                    page: this.childOrderCurrentPage,
+                    page_size: this.pageSize
+                };
+                if (this.childOrderFilters.id) params.id = this.childOrderFilters.id;
+                if (this.childOrderFilters.parentsid) params.parentsid = this.childOrderFilters.parentsid;
+                if (this.childOrderFilters.goodsid) params.goodsid = this.childOrderFilters.goodsid;
+                if (this.childOrderFilters.status !== '') params.status = this.childOrderFilters.status;
+                const response = await axios.get('/api/childorders', { params, withCredentials: true });
                 this.childOrders = response.data.childorders;
                 this.filteredChildOrders = this.childOrders;
+                this.childOrderTotal = response.data.total;
             } catch (err) {
                 console.error('获取子订单数据失败:', err);
                 this.error = '获取子订单数据失败';
@@ -4877,23 +4890,8 @@ createApp({
 
         // 搜索子订单
         searchChildOrders() {
-            this.filteredChildOrders = this.childOrders.filter(order => {
-                let match = true;
-                if (this.childOrderFilters.id && order.id !== parseInt(this.childOrderFilters.id)) {
-                    match = false;
-                }
-                if (this.childOrderFilters.parentsid && order.parentsid !== parseInt(this.childOrderFilters.parentsid)) {
-                    match = false;
-                }
-                if (this.childOrderFilters.goodsid && order.goodsid !== parseInt(this.childOrderFilters.goodsid)) {
-                    match = false;
-                }
-                if (this.childOrderFilters.status && order.status !== parseInt(this.childOrderFilters.status)) {
-                    match = false;
-                }
-                return match;
-            });
             this.childOrderCurrentPage = 1;
+            this.fetchChildOrders();
         },
 
         // 重置子订单筛选
@@ -4904,8 +4902,8 @@ createApp({
                 goodsid: '',
                 status: ''
             };
-            this.filteredChildOrders = this.childOrders;
             this.childOrderCurrentPage = 1;
+            this.fetchChildOrders();
         },
 
         // 获取子订单状态文本
@@ -4924,6 +4922,7 @@ createApp({
         changeChildOrderPage(page) {
             if (page >= 1 && page <= this.childOrderTotalPages) {
                 this.childOrderCurrentPage = page;
+                this.fetchChildOrders();
             }
         },
 
@@ -7041,21 +7040,7 @@ createApp({
                     alert('请填写交易时间');
                     return;
                 }
-
-                // 检查交易时间是否与订单预计付款时间一致（只比较日期）
-                if (this.selectedOrderInfo && this.selectedOrderInfo.expected_payment_time) {
-                    const tradingDate = new Date(this.paymentCollectionForm.trading_hours);
-                    const expectedDate = new Date(this.selectedOrderInfo.expected_payment_time);
-
-                    // 只比较年月日
-                    const tradingDateStr = tradingDate.toISOString().split('T')[0];
-                    const expectedDateStr = expectedDate.toISOString().split('T')[0];
-
-                    if (tradingDateStr !== expectedDateStr) {
-                        alert('付款时间与订单不符！请重新填写！');
-                        return;
-                    }
-                }
+                // 收款由后端匹配到订单下一个未收齐的分期，不再要求交易日期与预计付款时间一致
             }
 
             try {
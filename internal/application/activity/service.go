@@ -60,6 +60,7 @@ func (s *Service) CreateActivity(ctx context.Context, req *dto.CreateActivityDTO
 		detail := &entity.ActivityDetail{
 			ThresholdAmount: d.ThresholdAmount,
 			DiscountValue:   d.DiscountValue,
+			GiftGoodsID:     d.GiftGoodsID,
 		}
 		if err := detail.Validate(template.Type); err != nil {
 			return 0, err
@@ -115,6 +116,7 @@ func (s *Service) UpdateActivity(ctx context.Context, id int, req *dto.UpdateAct
 		detail := &entity.ActivityDetail{
 			ThresholdAmount: d.ThresholdAmount,
 			DiscountValue:   d.DiscountValue,
+			GiftGoodsID:     d.GiftGoodsID,
 		}
 		if err := detail.Validate(template.Type); err != nil {
 			return err
@@ -163,6 +165,7 @@ func (s *Service) GetActivity(ctx context.Context, id int) (*dto.ActivityDetailR
 			ActivityID:      d.ActivityID,
 			ThresholdAmount: d.ThresholdAmount,
 			DiscountValue:   d.DiscountValue,
+			GiftGoodsID:     d.GiftGoodsID,
 		})
 	}
 
@@ -256,6 +259,7 @@ func (s *Service) GetActivitiesByDateRange(ctx context.Context, paymentTime time
 				ActivityID:      d.ActivityID,
 				ThresholdAmount: d.ThresholdAmount,
 				DiscountValue:   d.DiscountValue,
+				GiftGoodsID:     d.GiftGoodsID,
 			})
 		}
 
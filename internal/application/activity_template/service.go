@@ -39,6 +39,8 @@ func (s *Service) CreateTemplate(ctx context.Context, req *dto.CreateActivityTem
 		Name:       req.Name,
 		Type:       req.Type,
 		SelectType: req.SelectType,
+		StackMode:  req.StackMode,
+		StackGroup: req.StackGroup,
 		Status:     req.Status,
 	}
 
@@ -96,6 +98,8 @@ func (s *Service) UpdateTemplate(ctx context.Context, id int, req *dto.UpdateAct
 	template.Name = req.Name
 	template.Type = req.Type
 	template.SelectType = req.SelectType
+	template.StackMode = req.StackMode
+	template.StackGroup = req.StackGroup
 
 	// 验证模板
 	if err := template.Validate(); err != nil {
@@ -148,6 +152,8 @@ func (s *Service) GetTemplate(ctx context.Context, id int) (*dto.ActivityTemplat
 		Name:       template.Name,
 		Type:       template.Type,
 		SelectType: template.SelectType,
+		StackMode:  template.StackMode,
+		StackGroup: template.StackGroup,
 		Status:     template.Status,
 		CreateTime: template.CreateTime,
 		UpdateTime: template.UpdateTime,
@@ -179,6 +185,8 @@ func (s *Service) ListTemplates(ctx context.Context) ([]*dto.ActivityTemplateDTO
 			Name:       t.Name,
 			Type:       t.Type,
 			SelectType: t.SelectType,
+			StackMode:  t.StackMode,
+			StackGroup: t.StackGroup,
 			Status:     t.Status,
 			CreateTime: t.CreateTime,
 			UpdateTime: t.UpdateTime,
@@ -202,6 +210,8 @@ func (s *Service) ListActiveTemplates(ctx context.Context) ([]*dto.ActivityTempl
 			Name:       t.Name,
 			Type:       t.Type,
 			SelectType: t.SelectType,
+			StackMode:  t.StackMode,
+			StackGroup: t.StackGroup,
 			Status:     t.Status,
 			CreateTime: t.CreateTime,
 			UpdateTime: t.UpdateTime,
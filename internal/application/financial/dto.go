@@ -1,6 +1,11 @@
 package financial
 
-import "time"
+import (
+	"time"
+
+	"charonoms/internal/domain/financial/reconciliation"
+	"charonoms/internal/domain/shared/money"
+)
 
 // 此文件定义财务管理模块的DTO（数据传输对象）
 
@@ -18,21 +23,42 @@ type PaymentCollectionDTO struct {
 	TradingHours    *time.Time `json:"trading_hours"`
 	ArrivalTime     *time.Time `json:"arrival_time"`
 	MerchantOrder   string     `json:"merchant_order"`
+	InstalmentID    *int       `json:"instalment_id"`
+	GatewayTradeNo  *string    `json:"gateway_trade_no"`
 	Status          int        `json:"status"`
 	CreateTime      time.Time  `json:"create_time"`
 }
 
 // CreatePaymentCollectionRequest 新增收款请求DTO
 type CreatePaymentCollectionRequest struct {
-	OrderID         int        `json:"order_id" binding:"required"`
-	StudentID       int        `json:"student_id" binding:"required"`
-	PaymentScenario int        `json:"payment_scenario" binding:"min=0"`
-	PaymentMethod   int        `json:"payment_method" binding:"min=0"`
-	PaymentAmount   float64    `json:"payment_amount" binding:"required,gt=0"`
-	Payer           string     `json:"payer"`
-	PayeeEntity     int        `json:"payee_entity" binding:"min=0"`
-	MerchantOrder   string     `json:"merchant_order"`
-	TradingHours    *time.Time `json:"trading_hours"`
+	OrderID         int         `json:"order_id" binding:"required"`
+	StudentID       int         `json:"student_id" binding:"required"`
+	PaymentScenario int         `json:"payment_scenario" binding:"min=0"`
+	PaymentMethod   int         `json:"payment_method" binding:"min=0"`
+	PaymentAmount   money.Money `json:"payment_amount"` // 必须大于0，由应用服务校验
+	Payer           string      `json:"payer"`
+	PayeeEntity     int         `json:"payee_entity" binding:"min=0"`
+	MerchantOrder   string      `json:"merchant_order"`
+	TradingHours    *time.Time  `json:"trading_hours"`
+}
+
+// CreateOnlineChargeRequest 发起在线支付请求DTO
+type CreateOnlineChargeRequest struct {
+	OrderID       int         `json:"order_id" binding:"required"`
+	PaymentMethod int         `json:"payment_method" binding:"oneof=0 1"` // 0-微信 1-支付宝
+	PaymentAmount money.Money `json:"payment_amount"`                     // 必须大于0，由应用服务校验
+	Payer         string      `json:"payer"`
+	PayeeEntity   int         `json:"payee_entity" binding:"min=0"`
+}
+
+// OnlineChargeResponse 发起在线支付响应DTO
+type OnlineChargeResponse struct {
+	PaymentID     int       `json:"payment_id"`
+	MerchantOrder string    `json:"merchant_order"`
+	PaymentAmount float64   `json:"payment_amount"`
+	PayURL        string    `json:"pay_url"`
+	QRCode        string    `json:"qr_code"`
+	ExpireTime    time.Time `json:"expire_time"`
 }
 
 // PaymentCollectionListResponse 收款列表响应DTO
@@ -53,6 +79,10 @@ type SeparateAccountDTO struct {
 	PaymentType    int       `json:"payment_type"`
 	GoodsID        int       `json:"goods_id"`
 	GoodsName      string    `json:"goods_name"`
+	BrandID        *int      `json:"brand_id"`
+	BrandName      string    `json:"brand_name"`
+	ClassifyID     *int      `json:"classify_id"`
+	ClassifyName   string    `json:"classify_name"`
 	SeparateAmount float64   `json:"separate_amount"`
 	Type           int       `json:"type"`
 	CreateTime     time.Time `json:"create_time"`
@@ -65,3 +95,53 @@ type SeparateAccountListResponse struct {
 	Page             int                   `json:"page"`
 	PageSize         int                   `json:"page_size"`
 }
+
+// SeparateAccountGroupDTO 分账汇总响应DTO，未参与分组的维度不返回
+type SeparateAccountGroupDTO struct {
+	BrandID      *int    `json:"brand_id,omitempty"`
+	BrandName    *string `json:"brand_name,omitempty"`
+	ClassifyID   *int    `json:"classify_id,omitempty"`
+	ClassifyName *string `json:"classify_name,omitempty"`
+	GoodsID      *int    `json:"goods_id,omitempty"`
+	GoodsName    *string `json:"goods_name,omitempty"`
+	Month        *string `json:"month,omitempty"`
+	SaleAmount   float64 `json:"sale_amount"`
+	RevertAmount float64 `json:"revert_amount"`
+	RefundAmount float64 `json:"refund_amount"`
+	NetAmount    float64 `json:"net_amount"`
+	Count        int64   `json:"count"`
+}
+
+// SeparateAccountGroupListResponse 分账汇总列表响应DTO
+type SeparateAccountGroupListResponse struct {
+	GroupBy  []string                   `json:"group_by"`
+	Groups   []*SeparateAccountGroupDTO `json:"groups"`
+	Total    int64                      `json:"total"`
+	Page     int                        `json:"page"`
+	PageSize int                        `json:"page_size"`
+}
+
+// ReconciliationReportResponse 对账报告响应DTO
+type ReconciliationReportResponse struct {
+	Batch *reconciliation.Batch  `json:"batch"`
+	Items []*reconciliation.Item `json:"items"`
+}
+
+// ReconciliationBatchListResponse 对账批次列表响应DTO
+type ReconciliationBatchListResponse struct {
+	Batches  []*reconciliation.Batch `json:"batches"`
+	Total    int64                   `json:"total"`
+	Page     int                     `json:"page"`
+	PageSize int                     `json:"page_size"`
+}
+
+// ConfirmReconciledRequest 按对账结果批量确认到账请求DTO，ItemIDs 为空时确认批次中全部可确认的明细
+type ConfirmReconciledRequest struct {
+	ItemIDs []int `json:"item_ids"`
+}
+
+// ConfirmReconciledResponse 按对账结果批量确认到账响应DTO
+type ConfirmReconciledResponse struct {
+	ConfirmedCount int      `json:"confirmed_count"`
+	Errors         []string `json:"errors,omitempty"`
+}
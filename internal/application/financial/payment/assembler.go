@@ -17,12 +17,14 @@ func ToPaymentCollectionDTO(p *domainPayment.PaymentCollection, studentName stri
 		StudentName:     studentName,
 		PaymentScenario: p.PaymentScenario,
 		PaymentMethod:   p.PaymentMethod,
-		PaymentAmount:   p.PaymentAmount,
+		PaymentAmount:   p.PaymentAmount.Float64(),
 		Payer:           p.Payer,
 		PayeeEntity:     p.PayeeEntity,
 		TradingHours:    p.TradingHours,
 		ArrivalTime:     p.ArrivalTime,
 		MerchantOrder:   p.MerchantOrder,
+		InstalmentID:    p.InstalmentID,
+		GatewayTradeNo:  p.GatewayTradeNo,
 		Status:          p.Status,
 		CreateTime:      p.CreateTime,
 	}
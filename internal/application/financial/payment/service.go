@@ -1,22 +1,31 @@
 package payment
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"charonoms/internal/application/financial"
 	domainPayment "charonoms/internal/domain/financial/payment"
 	domainSeparate "charonoms/internal/domain/financial/separate"
+	orderEntity "charonoms/internal/domain/order/entity"
+	"charonoms/internal/domain/shared/money"
 	studentRepo "charonoms/internal/domain/student/repository"
 	"gorm.io/gorm"
 )
 
 // PaymentApplicationService 收款应用服务
 type PaymentApplicationService struct {
-	db                   *gorm.DB
-	paymentRepo          domainPayment.PaymentRepository
-	studentRepo          studentRepo.StudentRepository
-	paymentDomainService *domainPayment.PaymentDomainService
+	db                    *gorm.DB
+	paymentRepo           domainPayment.PaymentRepository
+	studentRepo           studentRepo.StudentRepository
+	paymentDomainService  *domainPayment.PaymentDomainService
 	separateDomainService *domainSeparate.SeparateAccountDomainService
+	gateway               domainPayment.PaymentGateway
 }
 
 // NewPaymentApplicationService 创建收款应用服务
@@ -26,6 +35,7 @@ func NewPaymentApplicationService(
 	studentRepo studentRepo.StudentRepository,
 	paymentDomainService *domainPayment.PaymentDomainService,
 	separateDomainService *domainSeparate.SeparateAccountDomainService,
+	gateway domainPayment.PaymentGateway,
 ) *PaymentApplicationService {
 	return &PaymentApplicationService{
 		db:                    db,
@@ -33,6 +43,7 @@ func NewPaymentApplicationService(
 		studentRepo:           studentRepo,
 		paymentDomainService:  paymentDomainService,
 		separateDomainService: separateDomainService,
+		gateway:               gateway,
 	}
 }
 
@@ -91,30 +102,11 @@ func (s *PaymentApplicationService) GetPaymentCollections(
 	}, nil
 }
 
-// CreatePaymentCollection 新增收款
-func (s *PaymentApplicationService) CreatePaymentCollection(req *financial.CreatePaymentCollectionRequest) (int, error) {
-	// 验证付款金额
-	err := s.paymentDomainService.ValidatePaymentAmount(req.OrderID, req.PaymentAmount)
-	if err != nil {
-		return 0, err
-	}
-
-	// 验证付款时间：如果填写了交易时间，需要与订单预计付款时间一致
-	if req.TradingHours != nil {
-		order, err := s.paymentDomainService.GetOrder(req.OrderID)
-		if err != nil {
-			return 0, errors.New("获取订单信息失败: " + err.Error())
-		}
-
-		if order.ExpectedPaymentTime != nil {
-			// 比较日期部分（忽略时分秒）
-			tradingDate := req.TradingHours.Format("2006-01-02")
-			expectedDate := order.ExpectedPaymentTime.Format("2006-01-02")
-
-			if tradingDate != expectedDate {
-				return 0, errors.New("付款时间与订单预计付款时间不符，请重新填写")
-			}
-		}
+// CreatePaymentCollection 新增收款，operator 为操作人，记录到订单状态历史
+// 付款金额校验和分期匹配在锁定订单行后进行，同一订单并发新增的收款不会超付
+func (s *PaymentApplicationService) CreatePaymentCollection(req *financial.CreatePaymentCollectionRequest, operator string) (int, error) {
+	if !req.PaymentAmount.IsPositive() {
+		return 0, errors.New("付款金额必须大于0")
 	}
 
 	// 转换为实体
@@ -122,16 +114,36 @@ func (s *PaymentApplicationService) CreatePaymentCollection(req *financial.Creat
 
 	// 在事务中执行
 	var paymentID int
-	err = s.db.Transaction(func(tx *gorm.DB) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		paymentDomainService := s.paymentDomainService.WithTx(tx)
+
+		// 锁定订单后验证付款金额
+		if err := paymentDomainService.LockOrder(req.OrderID); err != nil {
+			return err
+		}
+		if err := paymentDomainService.ValidatePaymentAmount(req.OrderID, req.PaymentAmount); err != nil {
+			return err
+		}
+
+		// 匹配下一个未收齐的分期（未设置分期的订单按预计付款时间视为一期）
+		instalment, err := paymentDomainService.MatchNextInstalment(req.OrderID)
+		if err != nil {
+			return err
+		}
+		if instalment != nil && instalment.ID > 0 {
+			instalmentID := instalment.ID
+			paymentEntity.InstalmentID = &instalmentID
+		}
+
 		// 插入收款记录
-		err := s.paymentRepo.Create(paymentEntity)
+		err = s.paymentRepo.WithTx(tx).Create(paymentEntity)
 		if err != nil {
 			return err
 		}
 		paymentID = paymentEntity.ID
 
 		// 更新订单状态
-		err = s.paymentDomainService.UpdateOrderPaymentStatus(req.OrderID)
+		err = paymentDomainService.UpdateOrderPaymentStatus(req.OrderID, operator, "新增收款")
 		if err != nil {
 			return err
 		}
@@ -146,8 +158,8 @@ func (s *PaymentApplicationService) CreatePaymentCollection(req *financial.Creat
 	return paymentID, nil
 }
 
-// ConfirmPaymentCollection 确认收款到账
-func (s *PaymentApplicationService) ConfirmPaymentCollection(id int) error {
+// ConfirmPaymentCollection 确认收款到账，operator 为操作人，记录到订单状态历史
+func (s *PaymentApplicationService) ConfirmPaymentCollection(id int, operator string) error {
 	// 查询收款记录
 	paymentEntity, err := s.paymentRepo.GetByID(id)
 	if err != nil {
@@ -164,31 +176,46 @@ func (s *PaymentApplicationService) ConfirmPaymentCollection(id int) error {
 
 	// 在事务中执行
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 更新收款状态
 		paymentEntity.Confirm()
-		err := s.paymentRepo.Update(paymentEntity)
-		if err != nil {
-			return err
-		}
+		return s.confirmTx(tx, paymentEntity, operator, "确认收款到账")
+	})
+}
 
-		// 更新订单状态
-		err = s.paymentDomainService.UpdateOrderPaymentStatus(paymentEntity.OrderID)
-		if err != nil {
-			return err
-		}
+// ConfirmArrivalTx 在调用方事务中按实际到账时间确认未核验收款，供对账等批量确认流程使用
+func (s *PaymentApplicationService) ConfirmArrivalTx(tx *gorm.DB, id int, arrivalTime time.Time, operator, reason string) error {
+	paymentEntity, err := s.paymentRepo.WithTx(tx).GetByID(id)
+	if err != nil {
+		return err
+	}
+	if paymentEntity == nil {
+		return errors.New("收款记录不存在")
+	}
+	if !paymentEntity.CanConfirm() {
+		return errors.New("只能确认未核验的收款")
+	}
 
-		// 生成分账明细
-		err = s.separateDomainService.GenerateSeparateAccounts(id, paymentEntity.OrderID)
-		if err != nil {
-			return err
-		}
+	paymentEntity.ConfirmAt(arrivalTime)
+	return s.confirmTx(tx, paymentEntity, operator, reason)
+}
 
-		return nil
-	})
+// confirmTx 在调用方事务中完成收款到账：保存收款状态、更新订单支付状态并生成分账明细
+func (s *PaymentApplicationService) confirmTx(tx *gorm.DB, paymentEntity *domainPayment.PaymentCollection, operator, reason string) error {
+	// 更新收款状态
+	if err := s.paymentRepo.WithTx(tx).Update(paymentEntity); err != nil {
+		return err
+	}
+
+	// 更新订单状态
+	if err := s.paymentDomainService.WithTx(tx).UpdateOrderPaymentStatus(paymentEntity.OrderID, operator, reason); err != nil {
+		return err
+	}
+
+	// 生成分账明细
+	return s.separateDomainService.WithTx(tx).GenerateSeparateAccounts(paymentEntity.ID, paymentEntity.OrderID)
 }
 
-// DeletePaymentCollection 删除收款
-func (s *PaymentApplicationService) DeletePaymentCollection(id int) error {
+// DeletePaymentCollection 删除收款，operator 为操作人，记录到订单状态历史
+func (s *PaymentApplicationService) DeletePaymentCollection(id int, operator string) error {
 	// 查询收款记录
 	paymentEntity, err := s.paymentRepo.GetByID(id)
 	if err != nil {
@@ -206,17 +233,181 @@ func (s *PaymentApplicationService) DeletePaymentCollection(id int) error {
 	// 在事务中执行
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		// 删除收款记录
-		err := s.paymentRepo.Delete(id)
+		err := s.paymentRepo.WithTx(tx).Delete(id)
 		if err != nil {
 			return err
 		}
 
 		// 更新订单状态
-		err = s.paymentDomainService.UpdateOrderPaymentStatus(paymentEntity.OrderID)
+		err = s.paymentDomainService.WithTx(tx).UpdateOrderPaymentStatus(paymentEntity.OrderID, operator, "删除收款")
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// CreateOnlineCharge 发起在线支付：创建待支付的在线收款并向支付网关下单
+// 收款在网关异步通知支付成功后确认到账，见 HandleGatewayNotification
+func (s *PaymentApplicationService) CreateOnlineCharge(req *financial.CreateOnlineChargeRequest) (*financial.OnlineChargeResponse, error) {
+	if s.gateway == nil {
+		return nil, errors.New("未配置在线支付网关")
+	}
+
+	amount := req.PaymentAmount
+	if !amount.IsPositive() {
+		return nil, errors.New("付款金额必须大于0")
+	}
+
+	merchantOrder, err := newMerchantOrder()
+	if err != nil {
+		return nil, err
+	}
+	paymentEntity := &domainPayment.PaymentCollection{
+		OrderID:         req.OrderID,
+		PaymentScenario: domainPayment.PaymentScenarioOnline,
+		PaymentMethod:   req.PaymentMethod,
+		PaymentAmount:   amount,
+		Payer:           req.Payer,
+		PayeeEntity:     req.PayeeEntity,
+		MerchantOrder:   merchantOrder,
+	}
+
+	// 锁定订单后校验金额、匹配分期并写入待支付记录，同一订单并发发起的在线支付在此串行
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		paymentDomainService := s.paymentDomainService.WithTx(tx)
+		if err := paymentDomainService.LockOrder(req.OrderID); err != nil {
+			return err
+		}
+
+		order, err := paymentDomainService.GetOrder(req.OrderID)
 		if err != nil {
 			return err
 		}
+		if order.Status != orderEntity.OrderStatusUnpaid && order.Status != orderEntity.OrderStatusPartialPaid {
+			return errors.New("订单状态不允许在线支付")
+		}
+		paymentEntity.StudentID = order.StudentID
+
+		// 验证付款金额，尚未支付的在线收款也占用待支付金额
+		if err := paymentDomainService.ValidateOnlineChargeAmount(req.OrderID, amount); err != nil {
+			return err
+		}
 
+		// 匹配下一个未收齐的分期
+		instalment, err := paymentDomainService.MatchNextInstalment(req.OrderID)
+		if err != nil {
+			return err
+		}
+		if instalment != nil && instalment.ID > 0 {
+			instalmentID := instalment.ID
+			paymentEntity.InstalmentID = &instalmentID
+		}
+
+		return s.paymentRepo.WithTx(tx).CreateAwaitingGateway(paymentEntity)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	charge, err := s.gateway.CreateCharge(context.Background(), domainPayment.ChargeRequest{
+		MerchantOrder: merchantOrder,
+		Amount:        amount,
+		PaymentMethod: req.PaymentMethod,
+		Subject:       fmt.Sprintf("订单%d收款", req.OrderID),
+	})
+	if err != nil {
+		// 下单失败时删除待支付记录，避免留下无法支付的收款
+		if delErr := s.paymentRepo.Delete(paymentEntity.ID); delErr != nil {
+			return nil, fmt.Errorf("支付网关下单失败: %v；删除待支付收款失败: %w", err, delErr)
+		}
+		return nil, fmt.Errorf("支付网关下单失败: %w", err)
+	}
+
+	return &financial.OnlineChargeResponse{
+		PaymentID:     paymentEntity.ID,
+		MerchantOrder: merchantOrder,
+		PaymentAmount: amount.Float64(),
+		PayURL:        charge.PayURL,
+		QRCode:        charge.QRCode,
+		ExpireTime:    charge.ExpireTime,
+	}, nil
+}
+
+// HandleGatewayNotification 处理支付网关异步通知：验签后按商户订单号找到在线收款，走正常的确认到账和分账流程
+// 通知可能重复送达：已按同一网关交易号确认过的收款直接返回成功；非支付成功的通知不处理
+func (s *PaymentApplicationService) HandleGatewayNotification(header map[string][]string, body []byte) error {
+	if s.gateway == nil {
+		return errors.New("未配置在线支付网关")
+	}
+
+	notification, err := s.gateway.ParseNotification(header, body)
+	if err != nil {
+		return err
+	}
+	if !notification.Paid {
 		return nil
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定收款记录，并发的重复通知在此串行
+		paymentEntity, err := s.paymentRepo.WithTx(tx).LockByMerchantOrder(notification.MerchantOrder)
+		if err != nil {
+			return err
+		}
+		if paymentEntity == nil {
+			return fmt.Errorf("商户订单号 %s 对应的在线收款不存在", notification.MerchantOrder)
+		}
+
+		// 重复通知
+		if paymentEntity.Status == domainPayment.PaymentStatusPaid &&
+			paymentEntity.GatewayTradeNo != nil && *paymentEntity.GatewayTradeNo == notification.TradeNo {
+			return nil
+		}
+		if !paymentEntity.IsAwaitingGateway() {
+			return fmt.Errorf("在线收款 %s 不是待支付状态，无法确认到账", notification.MerchantOrder)
+		}
+		if !paymentEntity.PaymentAmount.Equal(notification.Amount) {
+			return fmt.Errorf("支付通知金额%s与收款金额%s不一致", notification.Amount, paymentEntity.PaymentAmount)
+		}
+		// 锁定订单后按最新的已收款重新校验，避免并发到账的收款合计超付
+		if err := s.paymentDomainService.WithTx(tx).ValidateArrivalAmount(paymentEntity.OrderID, paymentEntity.PaymentAmount); err != nil {
+			return err
+		}
+
+		paymentEntity.ConfirmByGateway(notification.TradeNo, notification.PaidAt)
+		return s.confirmTx(tx, paymentEntity, orderEntity.OperatorSystem, "在线支付到账")
 	})
 }
+
+// gatewaySimulator 可以生成签名支付通知的网关（本地模拟网关）
+type gatewaySimulator interface {
+	BuildNotification(n domainPayment.Notification) []byte
+}
+
+// SimulateGatewayPayment 模拟支付方完成在线支付：由模拟网关生成签名通知，再按正常的异步通知流程处理
+// 网关交易号由商户订单号生成，重复模拟同一笔支付是幂等的；只有模拟网关支持
+func (s *PaymentApplicationService) SimulateGatewayPayment(merchantOrder string, amount money.Money) error {
+	simulator, ok := s.gateway.(gatewaySimulator)
+	if !ok {
+		return errors.New("当前支付网关不支持模拟支付")
+	}
+	body := simulator.BuildNotification(domainPayment.Notification{
+		MerchantOrder: merchantOrder,
+		TradeNo:       "MOCK" + merchantOrder,
+		Amount:        amount,
+		Paid:          true,
+		PaidAt:        time.Now(),
+	})
+	return s.HandleGatewayNotification(nil, body)
+}
+
+// newMerchantOrder 生成在线支付商户订单号：OL + 时间 + 随机串
+func newMerchantOrder() (string, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成商户订单号失败: %w", err)
+	}
+	return "OL" + time.Now().Format("20060102150405") + strings.ToUpper(hex.EncodeToString(buf)), nil
+}
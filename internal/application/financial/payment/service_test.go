@@ -0,0 +1,482 @@
+package payment
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"charonoms/internal/application/financial"
+	domainPayment "charonoms/internal/domain/financial/payment"
+	domainSeparate "charonoms/internal/domain/financial/separate"
+	orderEntity "charonoms/internal/domain/order/entity"
+	orderService "charonoms/internal/domain/order/service"
+	"charonoms/internal/domain/shared/money"
+	"charonoms/internal/infrastructure/paymentgateway"
+	financialPersistence "charonoms/internal/infrastructure/persistence/financial"
+	orderPersistence "charonoms/internal/infrastructure/persistence/order"
+)
+
+// setupMockDB 创建基于sqlmock的gorm连接
+func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm db: %v", err)
+	}
+
+	return gormDB, mock
+}
+
+// newTestGateway 创建测试用的模拟支付网关
+func newTestGateway(t *testing.T) *paymentgateway.MockGateway {
+	gateway, err := paymentgateway.NewMockGateway("test-secret", "")
+	if err != nil {
+		t.Fatalf("NewMockGateway() error = %v", err)
+	}
+	return gateway
+}
+
+// newTestPaymentService 按生产装配方式组装收款应用服务，仓储均使用同一个 mock 连接
+func newTestPaymentService(db *gorm.DB, gateway domainPayment.PaymentGateway) *PaymentApplicationService {
+	paymentRepo := financialPersistence.NewPaymentRepository(db)
+	separateRepo := financialPersistence.NewSeparateAccountRepository(db)
+	orderRepo := orderPersistence.NewOrderRepository(db)
+	childOrderRepo := orderPersistence.NewChildOrderRepository(db)
+
+	paymentDomainService := domainPayment.NewPaymentDomainService(paymentRepo, orderRepo, childOrderRepo, orderService.NewOrderStatusService(db))
+	separateDomainService := domainSeparate.NewSeparateAccountDomainService(separateRepo, paymentRepo, childOrderRepo, orderEntity.AllocationSetting{})
+	return NewPaymentApplicationService(db, paymentRepo, nil, paymentDomainService, separateDomainService, gateway)
+}
+
+func paymentRows(id, orderID int, status int) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "order_id", "student_id", "payment_amount", "status"}).
+		AddRow(id, orderID, 3, 1000.00, status)
+}
+
+// TestConfirmPaymentCollection_SeparateFailureRollsBack 分账明细写入失败时，收款确认和订单状态流转一并回滚
+// 所有写操作都在同一个事务中执行：期间不开启新事务，最终只回滚不提交
+func TestConfirmPaymentCollection_SeparateFailureRollsBack(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestPaymentService(db, nil)
+
+	mock.ExpectQuery("SELECT \\* FROM `payment_collection` WHERE id = \\?").
+		WillReturnRows(paymentRows(7, 1, domainPayment.PaymentStatusUnverified))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `payment_collection` SET").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// 订单状态：未支付 -> 已支付，在同一事务中锁定订单并写入历史
+	expectOrderAndPaid(mock, orderEntity.OrderStatusUnpaid, 1000)
+	mock.ExpectQuery("SELECT id, status FROM `orders` WHERE id = \\? .*FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, orderEntity.OrderStatusUnpaid))
+	mock.ExpectExec("UPDATE `orders` SET `status`=\\?").
+		WithArgs(orderEntity.OrderStatusPaid, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// 状态历史记录确认收款的操作人
+	mock.ExpectExec("INSERT INTO `order_status_history`").
+		WithArgs(sqlmock.AnyArg(), orderEntity.OrderStatusUnpaid, "finance01", 1, sqlmock.AnyArg(), orderEntity.OrderStatusPaid).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// 生成分账明细：插入失败
+	mock.ExpectQuery("SELECT \\* FROM `payment_collection` WHERE id = \\?").
+		WillReturnRows(paymentRows(7, 1, domainPayment.PaymentStatusPaid))
+	mock.ExpectQuery("SELECT \\* FROM `childorders` WHERE parentsid = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parentsid", "goodsid", "amount_received", "status"}).
+			AddRow(11, 1, 5, 1000.00, orderEntity.ChildOrderStatusUnpaid))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `separate_account`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT allocation_strategy FROM orders WHERE id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"allocation_strategy"}).AddRow(""))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(separate_amount\\), 0\\) FROM `separate_account`").
+		WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(0))
+	mock.ExpectQuery("SELECT g.id AS goods_id, g.name AS goods_name").
+		WillReturnRows(sqlmock.NewRows([]string{"goods_id", "goods_name", "brand_id", "brand_name", "classify_id", "classify_name"}).
+			AddRow(5, "数学课", 2, "学而思", 4, "数学"))
+	mock.ExpectExec("INSERT INTO `separate_account`").
+		WillReturnError(errors.New("deadlock found"))
+	mock.ExpectRollback()
+
+	err := s.ConfirmPaymentCollection(7, "finance01")
+	if err == nil {
+		t.Fatal("ConfirmPaymentCollection() error = nil, want separate insert failure")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// expectOrder 期望查询订单（无分期计划）
+func expectOrder(mock sqlmock.Sqlmock, status int) {
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "student_id", "amount_received", "status"}).
+			AddRow(1, 3, 1000.00, status))
+	mock.ExpectQuery("SELECT \\* FROM `order_instalment`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+}
+
+// expectOrderAndPaid 期望查询订单和已收款总额（常规收款）
+func expectOrderAndPaid(mock sqlmock.Sqlmock, status int, paid float64) {
+	expectOrder(mock, status)
+	expectPaid(mock, paid, 0)
+}
+
+// expectPaid 期望查询订单已收款总额：常规收款和淘宝收款
+func expectPaid(mock sqlmock.Sqlmock, regularPaid, taobaoPaid float64) {
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(payment_amount\\), 0\\) FROM `payment_collection` WHERE order_id = \\? AND status IN \\(10, 20\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(regularPaid))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(payment_amount\\), 0\\) FROM `taobao_payment` WHERE order_id = \\? AND status IN \\(20, 30\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(taobaoPaid))
+}
+
+// TestCreatePaymentCollection_StatusFailureRollsBack 订单状态流转失败时，新增的收款记录随事务回滚
+func TestCreatePaymentCollection_StatusFailureRollsBack(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestPaymentService(db, nil)
+
+	// 锁定订单后校验付款金额、匹配分期（订单没有分期计划）
+	mock.ExpectBegin()
+	expectLockOrder(mock)
+	expectOrderAndPaid(mock, orderEntity.OrderStatusUnpaid, 0)
+	expectOrder(mock, orderEntity.OrderStatusUnpaid)
+
+	mock.ExpectExec("INSERT INTO `payment_collection`").
+		WillReturnResult(sqlmock.NewResult(8, 1))
+	expectOrderAndPaid(mock, orderEntity.OrderStatusUnpaid, 400)
+	mock.ExpectQuery("SELECT id, status FROM `orders` WHERE id = \\? .*FOR UPDATE").
+		WillReturnError(errors.New("lock wait timeout"))
+	mock.ExpectRollback()
+
+	_, err := s.CreatePaymentCollection(&financial.CreatePaymentCollectionRequest{
+		OrderID:       1,
+		StudentID:     3,
+		PaymentAmount: money.FromFloat(400),
+	}, "finance01")
+	if err == nil {
+		t.Fatal("CreatePaymentCollection() error = nil, want status transition failure")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestCreatePaymentCollection_OverpaidRejectedUnderLock 锁定订单后发现已被并发收款付清，不再新增收款
+func TestCreatePaymentCollection_OverpaidRejectedUnderLock(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestPaymentService(db, nil)
+
+	mock.ExpectBegin()
+	expectLockOrder(mock)
+	expectOrderAndPaid(mock, orderEntity.OrderStatusPartialPaid, 800)
+	mock.ExpectRollback()
+
+	_, err := s.CreatePaymentCollection(&financial.CreatePaymentCollectionRequest{
+		OrderID:       1,
+		StudentID:     3,
+		PaymentAmount: money.FromFloat(300),
+	}, "finance01")
+	if err == nil {
+		t.Fatal("CreatePaymentCollection() error = nil, want amount exceeds unpaid")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestCreatePaymentCollection_MatchesInstalmentAfterTaobaoPayment 淘宝收款冲抵第一期后，新收款匹配第二期
+func TestCreatePaymentCollection_MatchesInstalmentAfterTaobaoPayment(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestPaymentService(db, nil)
+
+	expectInstalmentOrder := func() {
+		mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\?").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "student_id", "amount_received", "status"}).
+				AddRow(1, 3, 1000.00, orderEntity.OrderStatusPartialPaid))
+		mock.ExpectQuery("SELECT \\* FROM `order_instalment`").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "seq", "due_date", "amount"}).
+				AddRow(21, 1, 1, time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local), 400.00).
+				AddRow(22, 1, 2, time.Date(2026, 11, 1, 0, 0, 0, 0, time.Local), 600.00))
+	}
+
+	mock.ExpectBegin()
+	expectLockOrder(mock)
+	// 校验付款金额：淘宝已收400，待支付600
+	expectInstalmentOrder()
+	expectPaid(mock, 0, 400)
+	// 匹配分期：第一期已由淘宝收款收齐
+	expectInstalmentOrder()
+	expectPaid(mock, 0, 400)
+
+	mock.ExpectExec("INSERT INTO `payment_collection` .*`instalment_id`").
+		WithArgs(1, 3, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 22, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(8, 1))
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\?").
+		WillReturnError(errors.New("stop after insert"))
+	mock.ExpectRollback()
+
+	_, err := s.CreatePaymentCollection(&financial.CreatePaymentCollectionRequest{
+		OrderID:       1,
+		StudentID:     3,
+		PaymentAmount: money.FromFloat(300),
+	}, "finance01")
+	if err == nil {
+		t.Fatal("CreatePaymentCollection() error = nil, want stop after insert")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// onlinePaymentRows 在线收款记录，tradeNo 为空表示尚未收到网关通知
+func onlinePaymentRows(status int, tradeNo interface{}) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "order_id", "student_id", "payment_scenario", "payment_amount", "merchant_order", "gateway_trade_no", "status"}).
+		AddRow(7, 1, 3, domainPayment.PaymentScenarioOnline, 1000.00, "OL202610180001", tradeNo, status)
+}
+
+func paidNotification(gateway *paymentgateway.MockGateway, amount float64) []byte {
+	return gateway.BuildNotification(domainPayment.Notification{
+		MerchantOrder: "OL202610180001",
+		TradeNo:       "T9001",
+		Amount:        money.FromFloat(amount),
+		Paid:          true,
+		PaidAt:        time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local),
+	})
+}
+
+func expectLockedOnlinePayment(mock sqlmock.Sqlmock, rows *sqlmock.Rows) {
+	mock.ExpectQuery("SELECT \\* FROM `payment_collection` WHERE merchant_order = \\? AND payment_scenario = \\? .*FOR UPDATE").
+		WithArgs("OL202610180001", domainPayment.PaymentScenarioOnline, 1).
+		WillReturnRows(rows)
+}
+
+// TestHandleGatewayNotification_Confirms 支付成功通知走正常的确认到账流程：记录网关交易号、流转订单状态、生成分账
+func TestHandleGatewayNotification_Confirms(t *testing.T) {
+	db, mock := setupMockDB(t)
+	gateway := newTestGateway(t)
+	s := newTestPaymentService(db, gateway)
+
+	mock.ExpectBegin()
+	expectLockedOnlinePayment(mock, onlinePaymentRows(domainPayment.PaymentStatusWaitPay, nil))
+	expectLockedOrderAndPaid(mock, 0)
+	mock.ExpectExec("UPDATE `payment_collection` SET .*`gateway_trade_no`=\\?,`status`=\\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	expectOrderAndPaid(mock, orderEntity.OrderStatusUnpaid, 1000)
+	mock.ExpectQuery("SELECT id, status FROM `orders` WHERE id = \\? .*FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, orderEntity.OrderStatusUnpaid))
+	mock.ExpectExec("UPDATE `orders` SET `status`=\\?").
+		WithArgs(orderEntity.OrderStatusPaid, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `order_status_history`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectQuery("SELECT \\* FROM `payment_collection` WHERE id = \\?").
+		WillReturnRows(onlinePaymentRows(domainPayment.PaymentStatusPaid, "T9001"))
+	mock.ExpectQuery("SELECT \\* FROM `childorders` WHERE parentsid = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parentsid", "goodsid", "amount_received", "status"}).
+			AddRow(11, 1, 5, 1000.00, orderEntity.ChildOrderStatusUnpaid))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `separate_account`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT allocation_strategy FROM orders WHERE id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"allocation_strategy"}).AddRow(""))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(separate_amount\\), 0\\) FROM `separate_account`").
+		WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(0))
+	mock.ExpectQuery("SELECT g.id AS goods_id, g.name AS goods_name").
+		WillReturnRows(sqlmock.NewRows([]string{"goods_id", "goods_name", "brand_id", "brand_name", "classify_id", "classify_name"}).
+			AddRow(5, "数学课", 2, "学而思", 4, "数学"))
+	// 分账明细写入商品、品牌和分类快照
+	mock.ExpectExec("INSERT INTO `separate_account` \\(`uid`,`orders_id`,`childorders_id`,`payment_id`,`payment_type`,`goods_id`,`goods_name`,`brand_id`,`brand_name`,`classify_id`,`classify_name`").
+		WithArgs(3, 1, 11, 7, 0, 5, "数学课", 2, "学而思", 4, "数学", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT \\* FROM `childorders` WHERE id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parentsid", "goodsid", "amount_received", "status"}).
+			AddRow(11, 1, 5, 1000.00, orderEntity.ChildOrderStatusUnpaid))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(separate_amount\\), 0\\) FROM `separate_account`").
+		WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(1000.00))
+	mock.ExpectExec("UPDATE `childorders` SET").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := s.HandleGatewayNotification(nil, paidNotification(gateway, 1000)); err != nil {
+		t.Fatalf("HandleGatewayNotification() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestHandleGatewayNotification_Duplicate 重复送达的通知不再确认和分账，直接返回成功
+func TestHandleGatewayNotification_Duplicate(t *testing.T) {
+	db, mock := setupMockDB(t)
+	gateway := newTestGateway(t)
+	s := newTestPaymentService(db, gateway)
+
+	mock.ExpectBegin()
+	expectLockedOnlinePayment(mock, onlinePaymentRows(domainPayment.PaymentStatusPaid, "T9001"))
+	mock.ExpectCommit()
+
+	if err := s.HandleGatewayNotification(nil, paidNotification(gateway, 1000)); err != nil {
+		t.Fatalf("HandleGatewayNotification() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestHandleGatewayNotification_AmountMismatch 通知金额与收款金额不一致时不确认到账
+func TestHandleGatewayNotification_AmountMismatch(t *testing.T) {
+	db, mock := setupMockDB(t)
+	gateway := newTestGateway(t)
+	s := newTestPaymentService(db, gateway)
+
+	mock.ExpectBegin()
+	expectLockedOnlinePayment(mock, onlinePaymentRows(domainPayment.PaymentStatusWaitPay, nil))
+	mock.ExpectRollback()
+
+	if err := s.HandleGatewayNotification(nil, paidNotification(gateway, 999.99)); err == nil {
+		t.Fatal("HandleGatewayNotification() error = nil, want amount mismatch")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// expectLockOrder 期望锁定订单行
+func expectLockOrder(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT id FROM `orders` WHERE id = \\? .*FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+}
+
+// expectLockedOrderAndPaid 期望锁定订单行后查询订单和已收款总额
+func expectLockedOrderAndPaid(mock sqlmock.Sqlmock, paid float64) {
+	expectLockOrder(mock)
+	expectOrderAndPaid(mock, orderEntity.OrderStatusPartialPaid, paid)
+}
+
+// TestHandleGatewayNotification_Overpaid 订单在发起在线支付后已被其他收款付清时，通知不再确认到账
+func TestHandleGatewayNotification_Overpaid(t *testing.T) {
+	db, mock := setupMockDB(t)
+	gateway := newTestGateway(t)
+	s := newTestPaymentService(db, gateway)
+
+	mock.ExpectBegin()
+	expectLockedOnlinePayment(mock, onlinePaymentRows(domainPayment.PaymentStatusWaitPay, nil))
+	expectLockedOrderAndPaid(mock, 400)
+	mock.ExpectRollback()
+
+	err := s.HandleGatewayNotification(nil, paidNotification(gateway, 1000))
+	if err == nil || !strings.Contains(err.Error(), "不能超过待支付金额") {
+		t.Fatalf("HandleGatewayNotification() error = %v, want overpayment rejection", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestCreateOnlineCharge_CountsAwaitingCharges 尚未支付的在线收款占用待支付金额，重复发起的在线支付不能超付
+func TestCreateOnlineCharge_CountsAwaitingCharges(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		awaiting float64
+		wantErr  bool
+	}{
+		{name: "没有待支付的在线收款", amount: 1000, awaiting: 0},
+		{name: "剩余待支付金额足够", amount: 400, awaiting: 600},
+		{name: "已有在线支付占满待支付金额", amount: 1000, awaiting: 1000, wantErr: true},
+		{name: "超过剩余待支付金额", amount: 500, awaiting: 600, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			s := newTestPaymentService(db, newTestGateway(t))
+
+			// 锁定订单后校验订单状态和金额
+			mock.ExpectBegin()
+			expectLockOrder(mock)
+			expectOrder(mock, orderEntity.OrderStatusUnpaid)
+			expectOrderAndPaid(mock, orderEntity.OrderStatusUnpaid, 0)
+			mock.ExpectQuery("SELECT COALESCE\\(SUM\\(payment_amount\\), 0\\) FROM `payment_collection` WHERE order_id = \\? AND payment_scenario = \\? AND status = \\?").
+				WithArgs(1, domainPayment.PaymentScenarioOnline, domainPayment.PaymentStatusWaitPay).
+				WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(tt.awaiting))
+			if tt.wantErr {
+				mock.ExpectRollback()
+			} else {
+				// 匹配分期后在同一事务中写入待支付记录
+				expectOrder(mock, orderEntity.OrderStatusUnpaid)
+				mock.ExpectExec("SAVEPOINT").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO `payment_collection`").
+					WillReturnResult(sqlmock.NewResult(8, 1))
+				mock.ExpectExec("UPDATE `payment_collection` SET `status`=\\?").
+					WithArgs(domainPayment.PaymentStatusWaitPay, 8).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			_, err := s.CreateOnlineCharge(&financial.CreateOnlineChargeRequest{
+				OrderID:       1,
+				PaymentAmount: money.FromFloat(tt.amount),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateOnlineCharge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+// TestCreateOnlineCharge_RejectsNonPositiveAmount 付款金额不大于0时直接拒绝，不访问数据库也不调用网关
+func TestCreateOnlineCharge_RejectsNonPositiveAmount(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestPaymentService(db, newTestGateway(t))
+
+	for _, amount := range []money.Money{money.Zero, money.FromFloat(-100)} {
+		if _, err := s.CreateOnlineCharge(&financial.CreateOnlineChargeRequest{OrderID: 1, PaymentAmount: amount}); err == nil {
+			t.Errorf("CreateOnlineCharge(%s) error = nil, want rejection", amount)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestHandleGatewayNotification_InvalidSignature 签名不合法（被篡改或密钥不同）的通知不访问数据库
+func TestHandleGatewayNotification_InvalidSignature(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestPaymentService(db, newTestGateway(t))
+
+	other, err := paymentgateway.NewMockGateway("other-secret", "")
+	if err != nil {
+		t.Fatalf("NewMockGateway() error = %v", err)
+	}
+	tampered := strings.Replace(string(paidNotification(newTestGateway(t), 1000)), "amount=1000.00", "amount=1.00", 1)
+
+	for name, body := range map[string][]byte{
+		"tampered":     []byte(tampered),
+		"other secret": paidNotification(other, 1000),
+	} {
+		if err := s.HandleGatewayNotification(nil, body); !errors.Is(err, domainPayment.ErrInvalidNotifySignature) {
+			t.Errorf("%s: HandleGatewayNotification() error = %v, want ErrInvalidNotifySignature", name, err)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
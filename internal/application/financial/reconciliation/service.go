@@ -0,0 +1,217 @@
+package reconciliation
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"charonoms/internal/application/financial"
+	paymentApp "charonoms/internal/application/financial/payment"
+	"charonoms/internal/domain/financial/reconciliation"
+	"gorm.io/gorm"
+)
+
+// ReconciliationService 渠道账单对账应用服务
+type ReconciliationService struct {
+	db             *gorm.DB
+	reconRepo      reconciliation.ReconciliationRepository
+	paymentService *paymentApp.PaymentApplicationService
+	parsers        map[string]reconciliation.StatementParser
+}
+
+// NewReconciliationService 创建对账应用服务，parsers 为各渠道的账单解析器
+func NewReconciliationService(
+	db *gorm.DB,
+	reconRepo reconciliation.ReconciliationRepository,
+	paymentService *paymentApp.PaymentApplicationService,
+	parsers []reconciliation.StatementParser,
+) *ReconciliationService {
+	byChannel := make(map[string]reconciliation.StatementParser, len(parsers))
+	for _, p := range parsers {
+		byChannel[p.Channel()] = p
+	}
+	return &ReconciliationService{
+		db:             db,
+		reconRepo:      reconRepo,
+		paymentService: paymentService,
+		parsers:        byChannel,
+	}
+}
+
+// Reconcile 解析渠道账单并与系统记录逐笔匹配，保存对账批次并返回对账报告
+// 对账期间为账单中最早到最晚交易日期的自然日
+func (s *ReconciliationService) Reconcile(channel string, payeeEntity int, fileName string, r io.Reader, operator int) (*financial.ReconciliationReportResponse, error) {
+	parser, ok := s.parsers[channel]
+	if !ok {
+		return nil, fmt.Errorf("不支持的对账渠道: %s", channel)
+	}
+
+	lines, err := parser.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("账单中没有可对账的入账明细")
+	}
+
+	start, end := statementPeriod(lines)
+	records, err := s.reconRepo.ListSystemRecords(reconciliation.RecordQuery{
+		PaymentMethods: reconciliation.PaymentMethods(channel),
+		PayeeEntity:    payeeEntity,
+		IncludeTaobao:  channel == reconciliation.ChannelAlipay,
+		Start:          start,
+		End:            end,
+		MerchantOrders: merchantOrders(lines),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := reconciliation.Match(lines, records, payeeEntity)
+	batch := &reconciliation.Batch{
+		Channel:     channel,
+		PayeeEntity: payeeEntity,
+		FileName:    fileName,
+		PeriodStart: start,
+		PeriodEnd:   end.AddDate(0, 0, -1),
+		Operator:    operator,
+	}
+	for _, item := range items {
+		switch item.Result {
+		case reconciliation.ResultMatched:
+			batch.MatchedCount++
+		case reconciliation.ResultAmountMismatch:
+			batch.AmountMismatchCount++
+		case reconciliation.ResultMissingInSystem:
+			batch.MissingSystemCount++
+		case reconciliation.ResultMissingInStatement:
+			batch.MissingStmtCount++
+		}
+	}
+
+	if err := s.reconRepo.CreateBatch(batch, items); err != nil {
+		return nil, err
+	}
+	return &financial.ReconciliationReportResponse{Batch: batch, Items: items}, nil
+}
+
+// GetReport 查询对账报告，result 为 nil 时返回全部明细
+func (s *ReconciliationService) GetReport(batchID int, result *int) (*financial.ReconciliationReportResponse, error) {
+	batch, err := s.reconRepo.GetBatch(batchID)
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil {
+		return nil, errors.New("对账批次不存在")
+	}
+
+	items, err := s.reconRepo.ListItems(batchID, result)
+	if err != nil {
+		return nil, err
+	}
+	return &financial.ReconciliationReportResponse{Batch: batch, Items: items}, nil
+}
+
+// ListBatches 查询对账批次列表
+func (s *ReconciliationService) ListBatches(channel string, page, pageSize int) (*financial.ReconciliationBatchListResponse, error) {
+	batches, total, err := s.reconRepo.ListBatches(channel, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &financial.ReconciliationBatchListResponse{
+		Batches:  batches,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// ConfirmMatched 按对账结果批量确认未核验收款到账，到账时间取账单中的交易时间
+// itemIDs 为空时确认批次中全部可确认的明细；每笔收款单独提交，某一笔失败不影响其他收款，失败原因逐条返回
+// operator 为操作人，记录到订单状态历史
+func (s *ReconciliationService) ConfirmMatched(batchID int, itemIDs []int, operator string) (*financial.ConfirmReconciledResponse, error) {
+	batch, err := s.reconRepo.GetBatch(batchID)
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil {
+		return nil, errors.New("对账批次不存在")
+	}
+
+	items, err := s.reconRepo.ListItems(batchID, nil)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*reconciliation.Item, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	var targets []*reconciliation.Item
+	resp := &financial.ConfirmReconciledResponse{}
+	if len(itemIDs) == 0 {
+		for _, item := range items {
+			if item.CanConfirm() {
+				targets = append(targets, item)
+			}
+		}
+	} else {
+		for _, id := range itemIDs {
+			item, ok := byID[id]
+			if !ok {
+				resp.Errors = append(resp.Errors, fmt.Sprintf("明细%d：不属于该对账批次", id))
+				continue
+			}
+			if !item.CanConfirm() {
+				resp.Errors = append(resp.Errors, fmt.Sprintf("明细%d：只能确认已匹配且未核验的常规收款", id))
+				continue
+			}
+			targets = append(targets, item)
+		}
+	}
+
+	for _, item := range targets {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := s.paymentService.ConfirmArrivalTx(tx, *item.RecordID, *item.LineTradeTime, operator, "对账确认到账"); err != nil {
+				return err
+			}
+			return s.reconRepo.WithTx(tx).MarkConfirmed(item.ID)
+		})
+		if err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("明细%d（收款%d）：%v", item.ID, *item.RecordID, err))
+			continue
+		}
+		resp.ConfirmedCount++
+	}
+	return resp, nil
+}
+
+// statementPeriod 账单的对账期间 [start, end)，按自然日取整
+func statementPeriod(lines []reconciliation.StatementLine) (time.Time, time.Time) {
+	minTime, maxTime := lines[0].TradeTime, lines[0].TradeTime
+	for _, line := range lines[1:] {
+		if line.TradeTime.Before(minTime) {
+			minTime = line.TradeTime
+		}
+		if line.TradeTime.After(maxTime) {
+			maxTime = line.TradeTime
+		}
+	}
+	start := time.Date(minTime.Year(), minTime.Month(), minTime.Day(), 0, 0, 0, 0, minTime.Location())
+	end := time.Date(maxTime.Year(), maxTime.Month(), maxTime.Day(), 0, 0, 0, 0, maxTime.Location()).AddDate(0, 0, 1)
+	return start, end
+}
+
+// merchantOrders 账单中出现的商户订单号（去重）
+func merchantOrders(lines []reconciliation.StatementLine) []string {
+	seen := make(map[string]bool)
+	var orders []string
+	for _, line := range lines {
+		if line.MerchantOrder != "" && !seen[line.MerchantOrder] {
+			seen[line.MerchantOrder] = true
+			orders = append(orders, line.MerchantOrder)
+		}
+	}
+	return orders
+}
@@ -0,0 +1,215 @@
+package reconciliation
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	paymentApp "charonoms/internal/application/financial/payment"
+	domainPayment "charonoms/internal/domain/financial/payment"
+	"charonoms/internal/domain/financial/reconciliation"
+	domainSeparate "charonoms/internal/domain/financial/separate"
+	orderEntity "charonoms/internal/domain/order/entity"
+	orderService "charonoms/internal/domain/order/service"
+	"charonoms/internal/domain/shared/money"
+	financialPersistence "charonoms/internal/infrastructure/persistence/financial"
+	orderPersistence "charonoms/internal/infrastructure/persistence/order"
+)
+
+// setupMockDB 创建基于sqlmock的gorm连接
+func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm db: %v", err)
+	}
+
+	return gormDB, mock
+}
+
+// fakeParser 返回固定账单明细的解析器
+type fakeParser struct {
+	channel string
+	lines   []reconciliation.StatementLine
+}
+
+func (p *fakeParser) Channel() string { return p.channel }
+
+func (p *fakeParser) Parse(r io.Reader) ([]reconciliation.StatementLine, error) {
+	return p.lines, nil
+}
+
+// fakeReconRepo 内存中的对账仓储，记录查询条件、保存的批次和确认的明细
+type fakeReconRepo struct {
+	records   []reconciliation.SystemRecord
+	batch     *reconciliation.Batch
+	items     []*reconciliation.Item
+	query     reconciliation.RecordQuery
+	confirmed []int
+}
+
+func (r *fakeReconRepo) WithTx(tx *gorm.DB) reconciliation.ReconciliationRepository { return r }
+
+func (r *fakeReconRepo) ListSystemRecords(query reconciliation.RecordQuery) ([]reconciliation.SystemRecord, error) {
+	r.query = query
+	return r.records, nil
+}
+
+func (r *fakeReconRepo) CreateBatch(batch *reconciliation.Batch, items []*reconciliation.Item) error {
+	r.batch, r.items = batch, items
+	return nil
+}
+
+func (r *fakeReconRepo) GetBatch(id int) (*reconciliation.Batch, error) {
+	if r.batch == nil || r.batch.ID != id {
+		return nil, nil
+	}
+	return r.batch, nil
+}
+
+func (r *fakeReconRepo) ListBatches(channel string, page, pageSize int) ([]*reconciliation.Batch, int64, error) {
+	return nil, 0, nil
+}
+
+func (r *fakeReconRepo) ListItems(batchID int, result *int) ([]*reconciliation.Item, error) {
+	return r.items, nil
+}
+
+func (r *fakeReconRepo) MarkConfirmed(itemID int) error {
+	r.confirmed = append(r.confirmed, itemID)
+	return nil
+}
+
+func at(d, hour int) time.Time {
+	return time.Date(2026, 10, d, hour, 0, 0, 0, time.Local)
+}
+
+func payee(p int) *int {
+	return &p
+}
+
+// TestReconcile 按账单期间和商户订单号查询系统记录，批次按对账结果计数
+func TestReconcile(t *testing.T) {
+	repo := &fakeReconRepo{records: []reconciliation.SystemRecord{
+		{Source: reconciliation.SourcePayment, ID: 1, MerchantOrder: "M1", Amount: money.FromFloat(100), Time: at(1, 10), PayeeEntity: payee(1)},
+		{Source: reconciliation.SourcePayment, ID: 2, MerchantOrder: "M2", Amount: money.FromFloat(60), Time: at(3, 9), PayeeEntity: payee(1)},
+		{Source: reconciliation.SourcePayment, ID: 3, MerchantOrder: "M5", Amount: money.FromFloat(80), Time: at(2, 15), PayeeEntity: payee(1)},
+	}}
+	parser := &fakeParser{channel: reconciliation.ChannelWechat, lines: []reconciliation.StatementLine{
+		{LineNo: 2, MerchantOrder: "M1", Amount: money.FromFloat(100), TradeTime: at(1, 10)},
+		{LineNo: 3, MerchantOrder: "M2", Amount: money.FromFloat(50), TradeTime: at(3, 9)},
+		{LineNo: 4, MerchantOrder: "M9", Amount: money.FromFloat(20), TradeTime: at(2, 18)},
+	}}
+	s := NewReconciliationService(nil, repo, nil, []reconciliation.StatementParser{parser})
+
+	report, err := s.Reconcile(reconciliation.ChannelWechat, 1, "wechat.csv", nil, 7)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	wantQuery := reconciliation.RecordQuery{
+		PaymentMethods: []int{domainPayment.PaymentMethodWechat},
+		PayeeEntity:    1,
+		Start:          at(1, 0),
+		End:            at(4, 0),
+		MerchantOrders: []string{"M1", "M2", "M9"},
+	}
+	if !reflect.DeepEqual(repo.query, wantQuery) {
+		t.Errorf("ListSystemRecords() query = %+v, want %+v", repo.query, wantQuery)
+	}
+
+	b := report.Batch
+	if b.MatchedCount != 1 || b.AmountMismatchCount != 1 || b.MissingSystemCount != 1 || b.MissingStmtCount != 1 {
+		t.Errorf("batch counts = %d/%d/%d/%d, want 1/1/1/1", b.MatchedCount, b.AmountMismatchCount, b.MissingSystemCount, b.MissingStmtCount)
+	}
+	if !b.PeriodStart.Equal(at(1, 0)) || !b.PeriodEnd.Equal(at(3, 0)) {
+		t.Errorf("batch period = %s ~ %s, want 2026-10-01 ~ 2026-10-03", b.PeriodStart, b.PeriodEnd)
+	}
+	if repo.batch != b || len(repo.items) != 4 {
+		t.Errorf("CreateBatch() saved %d items, want the report batch with 4 items", len(repo.items))
+	}
+}
+
+// TestReconcile_UnsupportedChannel 没有对应解析器的渠道直接拒绝
+func TestReconcile_UnsupportedChannel(t *testing.T) {
+	s := NewReconciliationService(nil, &fakeReconRepo{}, nil, nil)
+	if _, err := s.Reconcile("unionpay", 1, "x.csv", nil, 7); err == nil {
+		t.Fatal("Reconcile() error = nil, want unsupported channel")
+	}
+}
+
+// confirmableItem 已匹配、未核验的常规收款明细
+func confirmableItem(id, recordID int) *reconciliation.Item {
+	source, status, tradeTime := reconciliation.SourcePayment, domainPayment.PaymentStatusUnverified, at(1, 10)
+	return &reconciliation.Item{ID: id, BatchID: 1, Result: reconciliation.ResultMatched,
+		RecordSource: &source, RecordID: &recordID, RecordStatus: &status, LineTradeTime: &tradeTime}
+}
+
+// TestConfirmMatched_RejectsItems 指定的明细不属于批次或不可确认时逐条返回原因，不确认任何收款
+func TestConfirmMatched_RejectsItems(t *testing.T) {
+	mismatch := confirmableItem(2, 12)
+	mismatch.Result = reconciliation.ResultAmountMismatch
+	repo := &fakeReconRepo{batch: &reconciliation.Batch{ID: 1}, items: []*reconciliation.Item{confirmableItem(1, 11), mismatch}}
+	s := NewReconciliationService(nil, repo, nil, nil)
+
+	resp, err := s.ConfirmMatched(1, []int{99, 2}, "finance01")
+	if err != nil {
+		t.Fatalf("ConfirmMatched() error = %v", err)
+	}
+	wantErrors := []string{"明细99：不属于该对账批次", "明细2：只能确认已匹配且未核验的常规收款"}
+	if resp.ConfirmedCount != 0 || !reflect.DeepEqual(resp.Errors, wantErrors) {
+		t.Errorf("ConfirmMatched() = %d %v, want 0 %v", resp.ConfirmedCount, resp.Errors, wantErrors)
+	}
+	if len(repo.confirmed) != 0 {
+		t.Errorf("MarkConfirmed() called for %v, want none", repo.confirmed)
+	}
+}
+
+// TestConfirmMatched_ConfirmFailureRollsBack 未指定明细时只确认可确认的明细；收款已被核验时该笔回滚并返回原因，不标记明细
+func TestConfirmMatched_ConfirmFailureRollsBack(t *testing.T) {
+	db, mock := setupMockDB(t)
+	paymentRepo := financialPersistence.NewPaymentRepository(db)
+	childOrderRepo := orderPersistence.NewChildOrderRepository(db)
+	paymentDomainService := domainPayment.NewPaymentDomainService(paymentRepo, orderPersistence.NewOrderRepository(db), childOrderRepo, orderService.NewOrderStatusService(db))
+	separateDomainService := domainSeparate.NewSeparateAccountDomainService(financialPersistence.NewSeparateAccountRepository(db), paymentRepo, childOrderRepo, orderEntity.AllocationSetting{})
+	paymentService := paymentApp.NewPaymentApplicationService(db, paymentRepo, nil, paymentDomainService, separateDomainService, nil)
+
+	confirmed := confirmableItem(2, 12)
+	confirmed.Confirmed = true
+	repo := &fakeReconRepo{batch: &reconciliation.Batch{ID: 1}, items: []*reconciliation.Item{confirmableItem(1, 11), confirmed}}
+	s := NewReconciliationService(db, repo, paymentService, nil)
+
+	// 收款11在对账后已被其他人核验
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `payment_collection` WHERE id = \\?").
+		WithArgs(11, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "payment_amount", "status"}).
+			AddRow(11, 1, 100.00, domainPayment.PaymentStatusPaid))
+	mock.ExpectRollback()
+
+	resp, err := s.ConfirmMatched(1, nil, "finance01")
+	if err != nil {
+		t.Fatalf("ConfirmMatched() error = %v", err)
+	}
+	if resp.ConfirmedCount != 0 || len(resp.Errors) != 1 {
+		t.Errorf("ConfirmMatched() = %d %v, want 0 and one error", resp.ConfirmedCount, resp.Errors)
+	}
+	if len(repo.confirmed) != 0 {
+		t.Errorf("MarkConfirmed() called for %v, want none", repo.confirmed)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
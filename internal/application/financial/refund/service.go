@@ -1,9 +1,15 @@
 package refund
 
 import (
+	approvalEntity "charonoms/internal/domain/approval/entity"
 	"charonoms/internal/domain/approval/repository"
+	approvalService "charonoms/internal/domain/approval/service"
+	"charonoms/internal/domain/financial/payment"
 	"charonoms/internal/domain/financial/refund"
+	orderEntity "charonoms/internal/domain/order/entity"
 	orderRepo "charonoms/internal/domain/order/repository"
+	orderService "charonoms/internal/domain/order/service"
+	"charonoms/internal/domain/shared/money"
 	"context"
 	"errors"
 	"fmt"
@@ -13,12 +19,15 @@ import (
 )
 
 type RefundService struct {
-	refundRepo       refund.RefundRepository
-	orderRepo        orderRepo.OrderRepository
-	approvalTplRepo  repository.ApprovalFlowTemplateRepository
-	approvalFlowRepo repository.ApprovalFlowManagementRepository
-	approvalNodeRepo repository.ApprovalNodeCaseRepository
-	db               *gorm.DB
+	refundRepo         refund.RefundRepository
+	orderRepo          orderRepo.OrderRepository
+	approvalTplRepo    repository.ApprovalFlowTemplateRepository
+	approvalFlowRepo   repository.ApprovalFlowManagementRepository
+	approvalNodeRepo   repository.ApprovalNodeCaseRepository
+	orderStatusService *orderService.OrderStatusService
+	amendmentService   *orderService.OrderAmendmentService
+	approvalService    *approvalService.ApprovalFlowService
+	db                 *gorm.DB
 }
 
 // NewRefundService 创建退费服务实例
@@ -28,15 +37,20 @@ func NewRefundService(
 	approvalTplRepo repository.ApprovalFlowTemplateRepository,
 	approvalFlowRepo repository.ApprovalFlowManagementRepository,
 	approvalNodeRepo repository.ApprovalNodeCaseRepository,
+	allocationSetting orderEntity.AllocationSetting,
+	approvalFlowService *approvalService.ApprovalFlowService,
 	db *gorm.DB,
 ) *RefundService {
 	return &RefundService{
-		refundRepo:       refundRepo,
-		orderRepo:        orderRepo,
-		approvalTplRepo:  approvalTplRepo,
-		approvalFlowRepo: approvalFlowRepo,
-		approvalNodeRepo: approvalNodeRepo,
-		db:               db,
+		refundRepo:         refundRepo,
+		orderRepo:          orderRepo,
+		approvalTplRepo:    approvalTplRepo,
+		approvalFlowRepo:   approvalFlowRepo,
+		approvalNodeRepo:   approvalNodeRepo,
+		orderStatusService: orderService.NewOrderStatusService(db),
+		amendmentService:   orderService.NewOrderAmendmentService(db, allocationSetting),
+		approvalService:    approvalFlowService,
+		db:                 db,
 	}
 }
 
@@ -78,11 +92,11 @@ func (s *RefundService) GetRefundOrderDetail(refundOrderID int) (map[string]inte
 	}
 
 	result := map[string]interface{}{
-		"refund_order":         refundOrder,
-		"refund_items":         refundItems,
-		"refund_payments":      refundPayments,
-		"taobao_supplement":    taobaoSupplement,
-		"regular_supplements":  regularSupplements,
+		"refund_order":        refundOrder,
+		"refund_items":        refundItems,
+		"refund_payments":     refundPayments,
+		"taobao_supplement":   taobaoSupplement,
+		"regular_supplements": regularSupplements,
 	}
 
 	return result, nil
@@ -110,63 +124,114 @@ func (s *RefundService) GetRefundPaymentDetails(filters map[string]interface{})
 
 // CreateRefundOrderRequest 创建退费订单请求
 type CreateRefundOrderRequest struct {
-	OrderID            int                                `json:"order_id"`
-	RefundItems        []RefundItemRequest                `json:"refund_items"`
-	RefundPayments     []RefundPaymentRequest             `json:"refund_payments"`
-	TaobaoSupplement   *TaobaoSupplementRequest           `json:"taobao_supplement"`
-	RegularSupplements []RegularSupplementRequest         `json:"regular_supplements"`
+	OrderID            int                        `json:"order_id"`
+	RefundItems        []RefundItemRequest        `json:"refund_items"`
+	RefundPayments     []RefundPaymentRequest     `json:"refund_payments"`
+	TaobaoSupplement   *TaobaoSupplementRequest   `json:"taobao_supplement"`
+	RegularSupplements []RegularSupplementRequest `json:"regular_supplements"`
 }
 
 type RefundItemRequest struct {
-	ChildOrderID int     `json:"childorder_id"`
-	GoodsID      int     `json:"goods_id"`
-	GoodsName    string  `json:"goods_name"`
-	RefundAmount float64 `json:"refund_amount,string"`
+	ChildOrderID int         `json:"childorder_id"`
+	GoodsID      int         `json:"goods_id"`
+	GoodsName    string      `json:"goods_name"`
+	RefundAmount money.Money `json:"refund_amount,string"`
 }
 
 type RefundPaymentRequest struct {
-	PaymentID    int     `json:"payment_id"`
-	PaymentType  int     `json:"payment_type"`
-	RefundAmount float64 `json:"refund_amount,string"`
+	PaymentID    int         `json:"payment_id"`
+	PaymentType  int         `json:"payment_type"`
+	RefundAmount money.Money `json:"refund_amount,string"`
 }
 
 type TaobaoSupplementRequest struct {
-	AlipayAccount string  `json:"alipay_account"`
-	AlipayName    string  `json:"alipay_name"`
-	RefundAmount  float64 `json:"refund_amount"`
+	AlipayAccount string      `json:"alipay_account"`
+	AlipayName    string      `json:"alipay_name"`
+	RefundAmount  money.Money `json:"refund_amount"`
 }
 
 type RegularSupplementRequest struct {
-	PayeeEntity         *int    `json:"payee_entity"`
-	IsCorporateTransfer *int    `json:"is_corporate_transfer"`
-	Payer               string  `json:"payer"`
-	BankAccount         string  `json:"bank_account"`
-	PayerReadonly       *bool   `json:"payer_readonly"`
-	RefundAmount        float64 `json:"refund_amount"`
+	PayeeEntity         *int        `json:"payee_entity"`
+	IsCorporateTransfer *int        `json:"is_corporate_transfer"`
+	Payer               string      `json:"payer"`
+	BankAccount         string      `json:"bank_account"`
+	PayerReadonly       *bool       `json:"payer_readonly"`
+	RefundAmount        money.Money `json:"refund_amount"`
+}
+
+// ResubmitRefundOrderRequest 重新提交退费订单请求：修改后的退费明细和收款分配，订单沿用原退费订单
+type ResubmitRefundOrderRequest struct {
+	CreateRefundOrderRequest
+	Remark string `json:"remark"` // 修改说明，记录在新版本审批流上
+}
+
+// resubmission 重新提交时关联的原退费订单和原审批流
+type resubmission struct {
+	previousRefundID int
+	previousFlowID   int
+	remark           string
 }
 
 // CreateRefundOrder 创建退费订单
 func (s *RefundService) CreateRefundOrder(ctx context.Context, req *CreateRefundOrderRequest, username string, userID int) (int, error) {
+	return s.createRefundOrder(ctx, req, username, userID, nil)
+}
+
+// ResubmitRefundOrder 发起人修改已驳回或已撤销的退费订单后重新提交：
+// 按修改后的内容创建新退费订单，并发起关联原审批流的新版本审批流，原退费订单和审批记录保持不变
+func (s *RefundService) ResubmitRefundOrder(ctx context.Context, previousRefundID int, req *ResubmitRefundOrderRequest, username string, userID int) (int, error) {
+	previous, err := s.refundRepo.GetRefundOrderByID(previousRefundID)
+	if err != nil {
+		return 0, fmt.Errorf("退费订单不存在: %w", err)
+	}
+	if previous.Status != refund.RefundStatusRejected && previous.Status != refund.RefundStatusCancelled {
+		return 0, errors.New("只能重新提交已驳回或已撤销的退费订单")
+	}
+	if req.OrderID != 0 && req.OrderID != previous.OrderID {
+		return 0, errors.New("重新提交不能更换退费的订单")
+	}
+	req.OrderID = previous.OrderID
+
+	var previousFlowIDs []int
+	if err := s.db.Model(&approvalEntity.ApprovalFlowManagement{}).
+		Where("business_type = ? AND business_id = ?", approvalService.BusinessTypeRefund, previousRefundID).
+		Order("id DESC").Limit(1).
+		Pluck("id", &previousFlowIDs).Error; err != nil {
+		return 0, fmt.Errorf("查询原审批流失败: %w", err)
+	}
+	if len(previousFlowIDs) == 0 {
+		return 0, errors.New("退费订单没有关联的审批流")
+	}
+
+	return s.createRefundOrder(ctx, &req.CreateRefundOrderRequest, username, userID, &resubmission{
+		previousRefundID: previousRefundID,
+		previousFlowID:   previousFlowIDs[0],
+		remark:           req.Remark,
+	})
+}
+
+// createRefundOrder 创建退费订单及审批流，resubmit 不为空时关联原退费订单并发起原审批流的新版本
+func (s *RefundService) createRefundOrder(ctx context.Context, req *CreateRefundOrderRequest, username string, userID int, resubmit *resubmission) (int, error) {
 	// 1. 参数校验
 	if req.OrderID == 0 || len(req.RefundItems) == 0 || len(req.RefundPayments) == 0 {
 		return 0, errors.New("参数不完整")
 	}
 
 	// 计算退费总额
-	var refundTotal float64
+	refundTotal := money.Zero
 	for _, item := range req.RefundItems {
-		refundTotal += item.RefundAmount
+		refundTotal = refundTotal.Add(item.RefundAmount)
 	}
 
 	// 计算收款分配总额
-	var paymentTotal float64
+	paymentTotal := money.Zero
 	for _, p := range req.RefundPayments {
-		paymentTotal += p.RefundAmount
+		paymentTotal = paymentTotal.Add(p.RefundAmount)
 	}
 
-	// 验证金额一致性
-	if refundTotal-paymentTotal > 0.01 || refundTotal-paymentTotal < -0.01 {
-		return 0, fmt.Errorf("退费金额(%.2f)与收款分配金额(%.2f)不一致", refundTotal, paymentTotal)
+	// 验证金额一致性（精确到分）
+	if !refundTotal.Equal(paymentTotal) {
+		return 0, fmt.Errorf("退费金额(%s)与收款分配金额(%s)不一致", refundTotal, paymentTotal)
 	}
 
 	// 2. 查询订单信息
@@ -175,24 +240,40 @@ func (s *RefundService) CreateRefundOrder(ctx context.Context, req *CreateRefund
 		return 0, fmt.Errorf("订单不存在: %w", err)
 	}
 
-	// 3. 查询退费类型的审批流模板（status=0表示启用）
-	var template struct {
-		ID                   int
-		ApprovalFlowTypeID   int
+	// 订单变更审批中时子订单和金额可能调整，不允许同时退费
+	pending, err := s.amendmentService.HasPending(ctx, req.OrderID)
+	if err != nil {
+		return 0, err
+	}
+	if pending {
+		return 0, errors.New("订单有审批中的变更单，暂不能退费")
+	}
+
+	// 赠品子订单零应收、作废的子订单已随订单变更移除，不允许退费
+	refundChildOrderIDs := make([]int, 0, len(req.RefundItems))
+	for _, item := range req.RefundItems {
+		refundChildOrderIDs = append(refundChildOrderIDs, item.ChildOrderID)
 	}
-	err = s.db.Raw(`
-		SELECT aft.id, aft.approval_flow_type_id
-		FROM approval_flow_template aft
-		INNER JOIN approval_flow_type aftype ON aftype.id = aft.approval_flow_type_id
-		WHERE aftype.name = '退费' AND aft.status = 0
-		LIMIT 1
-	`).Scan(&template).Error
-	if err != nil || template.ID == 0 {
-		return 0, errors.New("未找到启用的退费审批流模板")
+	var giftCount int64
+	if err := s.db.Table("childorders").
+		Where("id IN ? AND (gift_activity_id IS NOT NULL OR status = ?)", refundChildOrderIDs, orderEntity.ChildOrderStatusCancelled).
+		Count(&giftCount).Error; err != nil {
+		return 0, fmt.Errorf("查询子订单失败: %w", err)
+	}
+	if giftCount > 0 {
+		return 0, errors.New("赠品或已作废的子订单不可退费")
 	}
 
-	templateID := template.ID
-	flowTypeID := template.ApprovalFlowTypeID
+	// 3. 按退费金额、收款渠道和收款主体选择退费审批流模板
+	payeeEntities, err := s.refundPayeeEntities(req.RefundPayments)
+	if err != nil {
+		return 0, err
+	}
+	approvalPayload := refundApprovalPayload(refundTotal, req.RefundPayments, payeeEntities)
+	template, err := approvalService.SelectTemplate(s.db, approvalService.FlowTypeCodeRefund, approvalPayload)
+	if err != nil {
+		return 0, err
+	}
 
 	var refundOrderID int
 
@@ -207,6 +288,9 @@ func (s *RefundService) CreateRefundOrder(ctx context.Context, req *CreateRefund
 			SubmitTime:   time.Now(),
 			Status:       refund.RefundStatusPending,
 		}
+		if resubmit != nil {
+			refundOrder.PreviousID = &resubmit.previousRefundID
+		}
 		if err := tx.Create(refundOrder).Error; err != nil {
 			return fmt.Errorf("创建退费订单失败: %w", err)
 		}
@@ -280,12 +364,29 @@ func (s *RefundService) CreateRefundOrder(ctx context.Context, req *CreateRefund
 		}
 
 		// 4.6 更新订单状态为退费中(50)
-		if err := tx.Exec("UPDATE orders SET status = 50 WHERE id = ?", req.OrderID).Error; err != nil {
+		err := s.orderStatusService.TransitionTx(tx, orderService.StatusChange{
+			OrderID:  req.OrderID,
+			To:       orderEntity.OrderStatusRefunding,
+			Operator: username,
+			Reason:   fmt.Sprintf("申请退费（退费单 #%d）", refundOrder.ID),
+		})
+		if err != nil {
 			return fmt.Errorf("更新订单状态失败: %w", err)
 		}
 
-		// 4.7 创建审批流实例
-		if err := s.createApprovalFlowInstance(tx, templateID, flowTypeID, userID); err != nil {
+		// 4.7 创建审批流实例（重新提交时作为原审批流的新版本）
+		business := approvalService.BusinessRef{
+			Type:    approvalService.BusinessTypeRefund,
+			ID:      refundOrderID,
+			Payload: approvalPayload,
+		}
+		if resubmit != nil {
+			if _, err := s.approvalService.ResubmitFlowInstance(tx, resubmit.previousFlowID, *template, userID, business, resubmit.remark); err != nil {
+				return fmt.Errorf("重新提交审批流失败: %w", err)
+			}
+			return nil
+		}
+		if _, err := s.approvalService.StartFlowInstance(tx, template.TemplateID, template.FlowTypeID, userID, business); err != nil {
 			return fmt.Errorf("创建审批流实例失败: %w", err)
 		}
 
@@ -299,94 +400,58 @@ func (s *RefundService) CreateRefundOrder(ctx context.Context, req *CreateRefund
 	return refundOrderID, nil
 }
 
-// createApprovalFlowInstance 创建审批流实例
-func (s *RefundService) createApprovalFlowInstance(tx *gorm.DB, templateID int, flowTypeID int, createUserID int) error {
-	fmt.Printf("[DEBUG] createApprovalFlowInstance: templateID=%d, flowTypeID=%d, createUserID=%d\n", templateID, flowTypeID, createUserID)
-
-	// 1. 创建审批流管理记录
-	result := tx.Exec(`
-		INSERT INTO approval_flow_management
-		(approval_flow_template_id, approval_flow_type_id, step, create_user, status, create_time)
-		VALUES (?, ?, 0, ?, 0, NOW())
-	`, templateID, flowTypeID, createUserID)
-	if result.Error != nil {
-		fmt.Printf("[ERROR] 插入审批流管理记录失败: %v\n", result.Error)
-		return result.Error
-	}
-	fmt.Printf("[DEBUG] 审批流管理记录插入成功，影响行数: %d\n", result.RowsAffected)
-
-	// 获取刚插入的ID
-	var flowID int64
-	if err := tx.Raw("SELECT LAST_INSERT_ID()").Scan(&flowID).Error; err != nil {
-		fmt.Printf("[ERROR] 获取审批流ID失败: %v\n", err)
-		return err
-	}
-	fmt.Printf("[DEBUG] 审批流ID: %d\n", flowID)
-
-	// 2. 查询模板的第一个审批节点（按sort排序）
-	var firstNode struct {
-		ID   int
-		Type int
-		Sort int
-	}
-	if err := tx.Raw(`
-		SELECT id, type, sort
-		FROM approval_flow_template_node
-		WHERE template_id = ?
-		ORDER BY sort ASC
-		LIMIT 1
-	`, templateID).Scan(&firstNode).Error; err != nil {
-		fmt.Printf("[ERROR] 查询第一个审批节点失败: %v\n", err)
-		return fmt.Errorf("查询第一个审批节点失败: %w", err)
-	}
-	fmt.Printf("[DEBUG] 第一个审批节点: ID=%d, Type=%d, Sort=%d\n", firstNode.ID, firstNode.Type, firstNode.Sort)
-
-	// 3. 创建第一个审批节点实例
-	result = tx.Exec(`
-		INSERT INTO approval_node_case
-		(node_id, approval_flow_management_id, type, sort, result, create_time)
-		VALUES (?, ?, ?, ?, NULL, NOW())
-	`, firstNode.ID, flowID, firstNode.Type, firstNode.Sort)
-	if result.Error != nil {
-		fmt.Printf("[ERROR] 创建审批节点实例失败: %v\n", result.Error)
-		return result.Error
-	}
-	fmt.Printf("[DEBUG] 审批节点实例创建成功，影响行数: %d\n", result.RowsAffected)
-
-	// 获取刚插入的ID
-	var nodeCaseID int64
-	if err := tx.Raw("SELECT LAST_INSERT_ID()").Scan(&nodeCaseID).Error; err != nil {
-		fmt.Printf("[ERROR] 获取节点实例ID失败: %v\n", err)
-		return err
-	}
-	fmt.Printf("[DEBUG] 节点实例ID: %d\n", nodeCaseID)
-
-	// 4. 查询该节点的所有审批人
-	var approverIDs []int
-	if err := tx.Raw(`
-		SELECT useraccount_id
-		FROM approval_node_useraccount
-		WHERE node_id = ?
-	`, firstNode.ID).Scan(&approverIDs).Error; err != nil {
-		fmt.Printf("[ERROR] 查询审批人失败: %v\n", err)
-		return fmt.Errorf("查询审批人失败: %w", err)
-	}
-	fmt.Printf("[DEBUG] 查询到审批人数量: %d, IDs: %v\n", len(approverIDs), approverIDs)
-
-	// 5. 为每个审批人创建审批记录
-	for _, approverID := range approverIDs {
-		result := tx.Exec(`
-			INSERT INTO approval_node_case_user
-			(approval_node_case_id, useraccount_id, result, create_time)
-			VALUES (?, ?, NULL, NOW())
-		`, nodeCaseID, approverID)
-		if result.Error != nil {
-			fmt.Printf("[ERROR] 创建审批人记录失败 (approverID=%d): %v\n", approverID, result.Error)
-			return result.Error
+// refundPayeeEntities 查询退费涉及的常规收款的收款主体（去重），淘宝收款没有收款主体
+func (s *RefundService) refundPayeeEntities(payments []RefundPaymentRequest) ([]int, error) {
+	var regularIDs []int
+	for _, p := range payments {
+		if p.PaymentType != refund.PaymentTypeTaobao {
+			regularIDs = append(regularIDs, p.PaymentID)
 		}
-		fmt.Printf("[DEBUG] 审批人记录创建成功: approverID=%d, 影响行数: %d\n", approverID, result.RowsAffected)
 	}
+	if len(regularIDs) == 0 {
+		return nil, nil
+	}
+
+	var entities []int
+	if err := s.db.Model(&payment.PaymentCollection{}).
+		Where("id IN ?", regularIDs).
+		Distinct().
+		Pluck("payee_entity", &entities).Error; err != nil {
+		return nil, fmt.Errorf("查询收款主体失败: %w", err)
+	}
+	return entities, nil
+}
 
-	fmt.Println("[DEBUG] createApprovalFlowInstance 完成")
-	return nil
+// refundApprovalPayload 退费审批的模板适用条件和节点条件数据：退费金额、收款渠道和收款主体
+func refundApprovalPayload(refundTotal money.Money, payments []RefundPaymentRequest, payeeEntities []int) approvalEntity.BusinessPayload {
+	hasRegular, hasTaobao := false, false
+	for _, p := range payments {
+		if p.PaymentType == refund.PaymentTypeTaobao {
+			hasTaobao = true
+		} else {
+			hasRegular = true
+		}
+	}
+
+	channel := approvalEntity.PayloadChannelRegular
+	switch {
+	case hasTaobao && hasRegular:
+		channel = approvalEntity.PayloadChannelMixed
+	case hasTaobao:
+		channel = approvalEntity.PayloadChannelTaobao
+	}
+
+	result := approvalEntity.BusinessPayload{
+		approvalEntity.PayloadFieldAmount:  refundTotal.Float64(),
+		approvalEntity.PayloadFieldChannel: channel,
+	}
+	switch {
+	case len(payeeEntities) > 1:
+		result[approvalEntity.PayloadFieldPayeeEntity] = approvalEntity.PayloadPayeeMixed
+	case len(payeeEntities) == 1 && payeeEntities[0] == payment.PayeeEntityBeijing:
+		result[approvalEntity.PayloadFieldPayeeEntity] = approvalEntity.PayloadPayeeBeijing
+	case len(payeeEntities) == 1 && payeeEntities[0] == payment.PayeeEntityXian:
+		result[approvalEntity.PayloadFieldPayeeEntity] = approvalEntity.PayloadPayeeXian
+	}
+	return result
 }
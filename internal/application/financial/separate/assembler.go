@@ -19,7 +19,11 @@ func ToSeparateAccountDTO(s *domainSeparate.SeparateAccount) *financial.Separate
 		PaymentType:    s.PaymentType,
 		GoodsID:        s.GoodsID,
 		GoodsName:      s.GoodsName,
-		SeparateAmount: s.SeparateAmount,
+		BrandID:        s.BrandID,
+		BrandName:      s.BrandName,
+		ClassifyID:     s.ClassifyID,
+		ClassifyName:   s.ClassifyName,
+		SeparateAmount: s.SeparateAmount.Float64(),
 		Type:           s.Type,
 		CreateTime:     s.CreateTime,
 	}
@@ -33,3 +37,21 @@ func ToSeparateAccountDTOList(accounts []*domainSeparate.SeparateAccount) []*fin
 	}
 	return result
 }
+
+// ToSeparateAccountGroupDTO 汇总结果转DTO
+func ToSeparateAccountGroupDTO(a *domainSeparate.SeparateAggregate) *financial.SeparateAccountGroupDTO {
+	return &financial.SeparateAccountGroupDTO{
+		BrandID:      a.BrandID,
+		BrandName:    a.BrandName,
+		ClassifyID:   a.ClassifyID,
+		ClassifyName: a.ClassifyName,
+		GoodsID:      a.GoodsID,
+		GoodsName:    a.GoodsName,
+		Month:        a.Month,
+		SaleAmount:   a.SaleAmount.Float64(),
+		RevertAmount: a.RevertAmount.Float64(),
+		RefundAmount: a.RefundAmount.Float64(),
+		NetAmount:    a.NetAmount.Float64(),
+		Count:        a.Count,
+	}
+}
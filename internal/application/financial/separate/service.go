@@ -1,6 +1,9 @@
 package separate
 
 import (
+	"errors"
+	"fmt"
+
 	"charonoms/internal/application/financial"
 	domainSeparate "charonoms/internal/domain/financial/separate"
 )
@@ -20,30 +23,13 @@ func NewSeparateAccountApplicationService(
 }
 
 // GetSeparateAccounts 获取分账明细列表
-func (s *SeparateAccountApplicationService) GetSeparateAccounts(
-	id, uid, ordersID, childOrdersID, goodsID, paymentID, paymentType, separateType *int,
-	page, pageSize int,
-) (*financial.SeparateAccountListResponse, error) {
+func (s *SeparateAccountApplicationService) GetSeparateAccounts(filter domainSeparate.SeparateListFilter) (*financial.SeparateAccountListResponse, error) {
 	// 默认分页参数
-	if page <= 0 {
-		page = 1
-	}
-	if pageSize <= 0 {
-		pageSize = 20
+	if filter.Page <= 0 {
+		filter.Page = 1
 	}
-
-	// 查询分账明细列表
-	filter := domainSeparate.SeparateListFilter{
-		ID:            id,
-		UID:           uid,
-		OrdersID:      ordersID,
-		ChildOrdersID: childOrdersID,
-		GoodsID:       goodsID,
-		PaymentID:     paymentID,
-		PaymentType:   paymentType,
-		Type:          separateType,
-		Page:          page,
-		PageSize:      pageSize,
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
 	}
 
 	accounts, total, err := s.separateRepo.List(filter)
@@ -57,7 +43,52 @@ func (s *SeparateAccountApplicationService) GetSeparateAccounts(
 	return &financial.SeparateAccountListResponse{
 		SeparateAccounts: dtos,
 		Total:            total,
-		Page:             page,
-		PageSize:         pageSize,
+		Page:             filter.Page,
+		PageSize:         filter.PageSize,
+	}, nil
+}
+
+// GetSeparateAccountGroups 按品牌、分类、商品、月份汇总分账金额，维度可组合
+func (s *SeparateAccountApplicationService) GetSeparateAccountGroups(filter domainSeparate.SeparateListFilter, groupBy []string) (*financial.SeparateAccountGroupListResponse, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	// 校验并去重汇总维度
+	seen := make(map[string]bool)
+	var dims []string
+	for _, dim := range groupBy {
+		switch dim {
+		case domainSeparate.GroupByBrand, domainSeparate.GroupByClassify, domainSeparate.GroupByGoods, domainSeparate.GroupByMonth:
+		default:
+			return nil, fmt.Errorf("不支持的汇总维度: %s，仅支持 brand、classify、goods、month", dim)
+		}
+		if !seen[dim] {
+			seen[dim] = true
+			dims = append(dims, dim)
+		}
+	}
+	if len(dims) == 0 {
+		return nil, errors.New("汇总维度不能为空")
+	}
+
+	aggregates, total, err := s.separateRepo.Aggregate(filter, dims)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*financial.SeparateAccountGroupDTO, 0, len(aggregates))
+	for _, a := range aggregates {
+		groups = append(groups, ToSeparateAccountGroupDTO(a))
+	}
+	return &financial.SeparateAccountGroupListResponse{
+		GroupBy:  dims,
+		Groups:   groups,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
 	}, nil
 }
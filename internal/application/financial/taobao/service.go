@@ -4,12 +4,15 @@ import (
 	"charonoms/internal/domain/financial/payment"
 	"charonoms/internal/domain/financial/separate"
 	"charonoms/internal/domain/financial/taobao"
+	orderEntity "charonoms/internal/domain/order/entity"
 	orderRepo "charonoms/internal/domain/order/repository"
+	orderService "charonoms/internal/domain/order/service"
+	"charonoms/internal/domain/shared/money"
 	"context"
 	"fmt"
-	"time"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/xuri/excelize/v2"
 	"gorm.io/gorm"
@@ -21,7 +24,8 @@ type TaobaoPaymentService struct {
 	paymentRepo        payment.PaymentRepository
 	orderRepo          orderRepo.OrderRepository
 	childOrderRepo     orderRepo.ChildOrderRepository
-	separateRepo       separate.SeparateAccountRepository
+	separateService    *separate.SeparateAccountDomainService
+	orderStatusService *orderService.OrderStatusService
 }
 
 // NewTaobaoPaymentService 创建淘宝收款服务实例
@@ -31,15 +35,16 @@ func NewTaobaoPaymentService(
 	paymentRepo payment.PaymentRepository,
 	orderRepository orderRepo.OrderRepository,
 	childOrderRepository orderRepo.ChildOrderRepository,
-	separateRepo separate.SeparateAccountRepository,
+	separateService *separate.SeparateAccountDomainService,
 ) *TaobaoPaymentService {
 	return &TaobaoPaymentService{
-		db:             db,
-		taobaoRepo:     taobaoRepo,
-		paymentRepo:    paymentRepo,
-		orderRepo:      orderRepository,
-		childOrderRepo: childOrderRepository,
-		separateRepo:   separateRepo,
+		db:                 db,
+		taobaoRepo:         taobaoRepo,
+		paymentRepo:        paymentRepo,
+		orderRepo:          orderRepository,
+		childOrderRepo:     childOrderRepository,
+		separateService:    separateService,
+		orderStatusService: orderService.NewOrderStatusService(db),
 	}
 }
 
@@ -49,7 +54,7 @@ func (s *TaobaoPaymentService) GetList(filters map[string]interface{}) ([]*taoba
 }
 
 // Create 创建淘宝收款记录
-func (s *TaobaoPaymentService) Create(payment *taobao.TaobaoPayment) error {
+func (s *TaobaoPaymentService) Create(payment *taobao.TaobaoPayment, operator string) error {
 	ctx := context.Background()
 	// 验证订单存在且状态为20(未支付)或30(部分支付)
 	order, err := s.orderRepo.GetOrderByID(ctx, *payment.OrderID)
@@ -60,42 +65,41 @@ func (s *TaobaoPaymentService) Create(payment *taobao.TaobaoPayment) error {
 		return fmt.Errorf("订单状态不允许添加收款")
 	}
 
-	// 计算已有收款总额
-	regularPaid, err := s.paymentRepo.GetTotalPaidAmount(*payment.OrderID)
-	if err != nil {
-		return err
-	}
-	taobaoPaid, err := s.taobaoRepo.GetTotalPaid(*payment.OrderID)
+	// 计算已有收款总额（常规收款 + 淘宝收款）
+	totalPaid, err := s.paymentRepo.GetTotalPaidAmount(*payment.OrderID)
 	if err != nil {
 		return err
 	}
-	totalPaid := regularPaid + taobaoPaid
 
 	// 计算待支付金额
-	pendingAmount := order.AmountReceived - totalPaid
+	pendingAmount := order.AmountReceived.Sub(totalPaid)
 
 	// 验证新增金额不超过待支付金额
-	if payment.PaymentAmount > pendingAmount {
+	if payment.PaymentAmount.GreaterThan(pendingAmount) {
 		return fmt.Errorf("收款金额超过待支付金额")
 	}
 
 	// 设置初始状态为0(已下单)
 	payment.Status = taobao.TaobaoPaymentStatusOrdered
 
-	// 创建记录
-	if err := s.taobaoRepo.Create(payment); err != nil {
-		return err
-	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 创建记录
+		if err := s.taobaoRepo.WithTx(tx).Create(payment); err != nil {
+			return err
+		}
 
-	// 更新订单支付状态
-	return s.updateOrderPaymentStatus(*payment.OrderID)
+		// 更新订单支付状态
+		return s.updateOrderPaymentStatus(tx, *payment.OrderID, operator, "新增淘宝收款")
+	})
 }
 
 // ConfirmArrival 确认淘宝收款到账
-func (s *TaobaoPaymentService) ConfirmArrival(paymentID int) error {
+func (s *TaobaoPaymentService) ConfirmArrival(paymentID int, operator string) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
+		taobaoRepo := s.taobaoRepo.WithTx(tx)
+
 		// 获取记录
-		payment, err := s.taobaoRepo.GetByID(paymentID)
+		payment, err := taobaoRepo.GetByID(paymentID)
 		if err != nil {
 			return fmt.Errorf("淘宝收款记录不存在")
 		}
@@ -109,20 +113,20 @@ func (s *TaobaoPaymentService) ConfirmArrival(paymentID int) error {
 		now := time.Now()
 		payment.Status = taobao.TaobaoPaymentStatusArrived
 		payment.ArrivalTime = &now
-		if err := s.taobaoRepo.Update(payment); err != nil {
+		if err := taobaoRepo.Update(payment); err != nil {
 			return err
 		}
 
 		// 生成分账明细
 		if payment.OrderID != nil {
-			if err := s.generateSeparateAccounts(paymentID, *payment.OrderID); err != nil {
+			if err := s.generateSeparateAccounts(tx, paymentID, *payment.OrderID); err != nil {
 				return err
 			}
 		}
 
 		// 更新订单状态
 		if payment.OrderID != nil {
-			return s.updateOrderPaymentStatus(*payment.OrderID)
+			return s.updateOrderPaymentStatus(tx, *payment.OrderID, operator, "淘宝收款到账")
 		}
 
 		return nil
@@ -130,7 +134,7 @@ func (s *TaobaoPaymentService) ConfirmArrival(paymentID int) error {
 }
 
 // Delete 删除淘宝收款记录
-func (s *TaobaoPaymentService) Delete(paymentID int) error {
+func (s *TaobaoPaymentService) Delete(paymentID int, operator string) error {
 	// 获取记录
 	payment, err := s.taobaoRepo.GetByID(paymentID)
 	if err != nil {
@@ -142,156 +146,54 @@ func (s *TaobaoPaymentService) Delete(paymentID int) error {
 		return fmt.Errorf("只能删除已下单或待认领状态的记录")
 	}
 
-	// 删除记录
-	if err := s.taobaoRepo.Delete(paymentID); err != nil {
-		return err
-	}
-
-	// 更新订单状态
-	if payment.OrderID != nil {
-		return s.updateOrderPaymentStatus(*payment.OrderID)
-	}
-
-	return nil
-}
-
-// generateSeparateAccounts 为淘宝收款生成分账明细
-func (s *TaobaoPaymentService) generateSeparateAccounts(paymentID int, orderID int) error {
-	// 获取淘宝收款信息
-	payment, err := s.taobaoRepo.GetByID(paymentID)
-	if err != nil {
-		return err
-	}
-
-	// 获取该订单的所有子订单（按ID排序）
-	childOrders, err := s.childOrderRepo.ListByOrderID(orderID)
-	if err != nil {
-		return err
-	}
-
-	// 检查是否已生成过分账
-	exists, err := s.separateRepo.ExistsByPaymentAndOrder(paymentID, orderID, 1) // 1表示淘宝支付
-	if err != nil {
-		return err
-	}
-	if exists {
-		return nil // 已生成过，跳过
-	}
-
-	// 按子订单顺序分配收款金额
-	remainingAmount := payment.PaymentAmount
-	for _, childOrder := range childOrders {
-		if remainingAmount <= 0 {
-			break
-		}
-
-		// 获取子订单已分配金额
-		allocated, err := s.separateRepo.GetChildOrderTotalSeparate(childOrder.ID)
-		if err != nil {
-			return err
-		}
-
-		// 计算需要金额
-		needAmount := childOrder.AmountReceived - allocated
-		if needAmount <= 0 {
-			continue
-		}
-
-		// 计算本次分配金额
-		allocAmount := remainingAmount
-		if allocAmount > needAmount {
-			allocAmount = needAmount
-		}
-
-		// 创建分账记录
-		separateAccount := &separate.SeparateAccount{
-			UID:            *payment.StudentID,
-			OrdersID:       orderID,
-			ChildOrdersID:  childOrder.ID,
-			PaymentID:      paymentID,
-			PaymentType:    1, // 淘宝支付
-			GoodsID:        childOrder.GoodsID,
-			GoodsName:      "", // 商品名称（从商品表关联查询）
-			SeparateAmount: allocAmount,
-			Type:           0, // 售卖类
-			CreateTime:     time.Now(),
-		}
-
-		if err := s.separateRepo.Create(separateAccount); err != nil {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 删除记录
+		if err := s.taobaoRepo.WithTx(tx).Delete(paymentID); err != nil {
 			return err
 		}
 
-		remainingAmount -= allocAmount
-	}
-
-	// 更新所有子订单状态
-	for _, childOrder := range childOrders {
-		if err := s.updateChildOrderStatus(childOrder.ID); err != nil {
-			return err
+		// 更新订单状态
+		if payment.OrderID != nil {
+			return s.updateOrderPaymentStatus(tx, *payment.OrderID, operator, "删除淘宝收款")
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
-// updateChildOrderStatus 更新子订单状态
-func (s *TaobaoPaymentService) updateChildOrderStatus(childOrderID int) error {
-	// 获取子订单
-	childOrder, err := s.childOrderRepo.GetByID(childOrderID)
-	if err != nil {
-		return err
-	}
-
-	// 获取总分账金额
-	totalSeparate, err := s.separateRepo.GetChildOrderTotalSeparate(childOrderID)
+// generateSeparateAccounts 在调用方事务中为淘宝收款生成分账明细，分配方式与常规收款相同
+func (s *TaobaoPaymentService) generateSeparateAccounts(tx *gorm.DB, paymentID int, orderID int) error {
+	// 获取淘宝收款信息
+	payment, err := s.taobaoRepo.WithTx(tx).GetByID(paymentID)
 	if err != nil {
 		return err
 	}
 
-	// 更新状态
-	if totalSeparate <= 0 {
-		childOrder.Status = 10 // 未支付
-	} else if totalSeparate < childOrder.AmountReceived {
-		childOrder.Status = 20 // 部分支付
-	} else {
-		childOrder.Status = 30 // 已支付
-	}
-
-	return s.childOrderRepo.Update(childOrder)
+	return s.separateService.WithTx(tx).AllocatePayment(paymentID, separate.PaymentTypeTaobao, orderID, *payment.StudentID, payment.PaymentAmount)
 }
 
-// updateOrderPaymentStatus 更新订单支付状态
-func (s *TaobaoPaymentService) updateOrderPaymentStatus(orderID int) error {
+// updateOrderPaymentStatus 在调用方事务中按收款情况更新订单支付状态，operator 和 reason 记录到订单状态历史
+func (s *TaobaoPaymentService) updateOrderPaymentStatus(tx *gorm.DB, orderID int, operator, reason string) error {
 	ctx := context.Background()
 	// 获取订单
-	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	order, err := s.orderRepo.WithTx(tx).GetOrderByID(ctx, orderID)
 	if err != nil {
 		return err
 	}
 
-	// 计算总收款
-	regularPaid, err := s.paymentRepo.GetTotalPaidAmount(orderID)
+	// 计算总收款（常规收款 + 淘宝收款）
+	totalPaid, err := s.paymentRepo.WithTx(tx).GetTotalPaidAmount(orderID)
 	if err != nil {
 		return err
 	}
-	taobaoPaid, err := s.taobaoRepo.GetTotalPaid(orderID)
-	if err != nil {
-		return err
-	}
-	totalPaid := regularPaid + taobaoPaid
 
 	// 更新订单状态
-	var newStatus int
-	if totalPaid == 0 {
-		newStatus = 20 // 未支付
-	} else if totalPaid >= order.AmountReceived {
-		newStatus = 40 // 已支付
-	} else {
-		newStatus = 30 // 部分支付
-	}
-
-	// 使用 UpdateOrderStatus 更新状态（不涉及子订单级联更新，传入0）
-	return s.orderRepo.UpdateOrderStatus(ctx, orderID, newStatus, 0)
+	return s.orderStatusService.TransitionTx(tx, orderService.StatusChange{
+		OrderID:  orderID,
+		To:       orderEntity.PaymentStatus(totalPaid, order.AmountReceived),
+		Operator: operator,
+		Reason:   reason,
+	})
 }
 
 // GetUnclaimedList 获取淘宝待认领列表
@@ -300,11 +202,13 @@ func (s *TaobaoPaymentService) GetUnclaimedList(filters map[string]interface{})
 }
 
 // ClaimUnclaimed 认领淘宝待认领
-func (s *TaobaoPaymentService) ClaimUnclaimed(unclaimedID int, orderID int, userID int) error {
+func (s *TaobaoPaymentService) ClaimUnclaimed(unclaimedID int, orderID int, userID int, operator string) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		ctx := context.Background()
+		taobaoRepo := s.taobaoRepo.WithTx(tx)
+
 		// 获取待认领记录
-		unclaimed, err := s.taobaoRepo.GetByID(unclaimedID)
+		unclaimed, err := taobaoRepo.GetByID(unclaimedID)
 		if err != nil {
 			return fmt.Errorf("待认领记录不存在")
 		}
@@ -315,7 +219,7 @@ func (s *TaobaoPaymentService) ClaimUnclaimed(unclaimedID int, orderID int, user
 		}
 
 		// 验证订单存在且状态为20(未支付)或30(部分支付)
-		order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+		order, err := s.orderRepo.WithTx(tx).GetOrderByID(ctx, orderID)
 		if err != nil {
 			return fmt.Errorf("订单不存在")
 		}
@@ -323,19 +227,15 @@ func (s *TaobaoPaymentService) ClaimUnclaimed(unclaimedID int, orderID int, user
 			return fmt.Errorf("订单状态不允许认领")
 		}
 
-		// 计算已有收款总额 + 待认领金额
-		regularPaid, err := s.paymentRepo.GetTotalPaidAmount(orderID)
-		if err != nil {
-			return err
-		}
-		taobaoPaid, err := s.taobaoRepo.GetTotalPaid(orderID)
+		// 计算已有收款总额（常规收款 + 淘宝收款）+ 待认领金额
+		paid, err := s.paymentRepo.WithTx(tx).GetTotalPaidAmount(orderID)
 		if err != nil {
 			return err
 		}
-		totalPaid := regularPaid + taobaoPaid + unclaimed.PaymentAmount
+		totalPaid := paid.Add(unclaimed.PaymentAmount)
 
 		// 验证不超过订单实收金额
-		if totalPaid > order.AmountReceived {
+		if totalPaid.GreaterThan(order.AmountReceived) {
 			return fmt.Errorf("认领金额超过订单实收金额")
 		}
 
@@ -345,17 +245,17 @@ func (s *TaobaoPaymentService) ClaimUnclaimed(unclaimedID int, orderID int, user
 		studentID := order.StudentID
 		unclaimed.StudentID = &studentID
 		unclaimed.Claimer = &userID
-		if err := s.taobaoRepo.Update(unclaimed); err != nil {
+		if err := taobaoRepo.Update(unclaimed); err != nil {
 			return err
 		}
 
 		// 生成分账明细
-		if err := s.generateSeparateAccounts(unclaimedID, orderID); err != nil {
+		if err := s.generateSeparateAccounts(tx, unclaimedID, orderID); err != nil {
 			return err
 		}
 
 		// 更新订单状态
-		return s.updateOrderPaymentStatus(orderID)
+		return s.updateOrderPaymentStatus(tx, orderID, operator, "认领淘宝收款")
 	})
 }
 
@@ -378,6 +278,7 @@ func (s *TaobaoPaymentService) DeleteUnclaimed(unclaimedID int) error {
 // ImportUnclaimedExcel 导入淘宝待认领Excel
 func (s *TaobaoPaymentService) ImportUnclaimedExcel(records []map[string]interface{}) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
+		taobaoRepo := s.taobaoRepo.WithTx(tx)
 		for _, record := range records {
 			payer := record["payer"].(string)
 			zhifubaoAccount := record["zhifubao_account"].(string)
@@ -386,7 +287,7 @@ func (s *TaobaoPaymentService) ImportUnclaimedExcel(records []map[string]interfa
 			arrivalTime := record["arrival_time"].(time.Time)
 
 			// 查找匹配的记录
-			matched, err := s.taobaoRepo.FindByMerchantOrderAndAmount(merchantOrder, amount)
+			matched, err := taobaoRepo.FindByMerchantOrderAndAmount(merchantOrder, amount)
 			if err != nil {
 				return err
 			}
@@ -397,7 +298,7 @@ func (s *TaobaoPaymentService) ImportUnclaimedExcel(records []map[string]interfa
 				matched.ArrivalTime = &arrivalTime
 				matched.Payer = &payer
 				matched.ZhifubaoAccount = &zhifubaoAccount
-				if err := s.taobaoRepo.Update(matched); err != nil {
+				if err := taobaoRepo.Update(matched); err != nil {
 					return err
 				}
 			} else {
@@ -405,12 +306,12 @@ func (s *TaobaoPaymentService) ImportUnclaimedExcel(records []map[string]interfa
 				newPayment := &taobao.TaobaoPayment{
 					Payer:           &payer,
 					ZhifubaoAccount: &zhifubaoAccount,
-					PaymentAmount:   amount,
+					PaymentAmount:   money.FromFloat(amount),
 					MerchantOrder:   &merchantOrder,
 					ArrivalTime:     &arrivalTime,
 					Status:          taobao.TaobaoPaymentStatusUnclaimed,
 				}
-				if err := s.taobaoRepo.Create(newPayment); err != nil {
+				if err := taobaoRepo.Create(newPayment); err != nil {
 					return err
 				}
 			}
@@ -478,6 +379,7 @@ func (s *TaobaoPaymentService) ImportUnclaimedExcelFile(f *excelize.File) (int,
 	datePattern := regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
 
 	err = s.db.Transaction(func(tx *gorm.DB) error {
+		taobaoRepo := s.taobaoRepo.WithTx(tx)
 		// 跳过表头，从第二行开始
 		for idx := 1; idx < len(rows); idx++ {
 			row := rows[idx]
@@ -513,7 +415,7 @@ func (s *TaobaoPaymentService) ImportUnclaimedExcelFile(f *excelize.File) (int,
 			paymentAmount, err := strconv.ParseFloat(paymentAmountStr, 64)
 			if err != nil || paymentAmount <= 0 {
 				fmt.Printf("DEBUG 第%d行：付款金额字符串='%s'(长度=%d), err=%v, amount=%v\n", rowNum, paymentAmountStr, len(paymentAmountStr), err, paymentAmount)
-			errorRows = append(errorRows, fmt.Sprintf("第%d行：付款金额格式不正确，必须为正数", rowNum))
+				errorRows = append(errorRows, fmt.Sprintf("第%d行：付款金额格式不正确，必须为正数", rowNum))
 				continue
 			}
 			// 保留两位小数
@@ -545,7 +447,7 @@ func (s *TaobaoPaymentService) ImportUnclaimedExcelFile(f *excelize.File) (int,
 			}
 
 			// 自动匹配逻辑：查找状态为0(已下单)且金额和商户订单号匹配的记录
-			matched, err := s.taobaoRepo.FindByMerchantOrderAndAmount(merchantOrder, paymentAmount)
+			matched, err := taobaoRepo.FindByMerchantOrderAndAmount(merchantOrder, paymentAmount)
 			if err != nil {
 				return err
 			}
@@ -560,13 +462,13 @@ func (s *TaobaoPaymentService) ImportUnclaimedExcelFile(f *excelize.File) (int,
 				if zhifubaoAccount != "" {
 					matched.ZhifubaoAccount = &zhifubaoAccount
 				}
-				if err := s.taobaoRepo.Update(matched); err != nil {
+				if err := taobaoRepo.Update(matched); err != nil {
 					return err
 				}
 
 				// 生成分账明细
 				if matched.OrderID != nil {
-					if err := s.generateSeparateAccounts(matched.ID, *matched.OrderID); err != nil {
+					if err := s.generateSeparateAccounts(tx, matched.ID, *matched.OrderID); err != nil {
 						return err
 					}
 				}
@@ -575,10 +477,10 @@ func (s *TaobaoPaymentService) ImportUnclaimedExcelFile(f *excelize.File) (int,
 			} else {
 				// 未匹配，插入为待认领(status=10)
 				newPayment := &taobao.TaobaoPayment{
-					Status:          taobao.TaobaoPaymentStatusUnclaimed,
-					PaymentAmount:   paymentAmount,
-					MerchantOrder:   &merchantOrder,
-					ArrivalTime:     &arrivalTime,
+					Status:        taobao.TaobaoPaymentStatusUnclaimed,
+					PaymentAmount: money.FromFloat(paymentAmount),
+					MerchantOrder: &merchantOrder,
+					ArrivalTime:   &arrivalTime,
 				}
 				if payer != "" {
 					newPayment.Payer = &payer
@@ -586,7 +488,7 @@ func (s *TaobaoPaymentService) ImportUnclaimedExcelFile(f *excelize.File) (int,
 				if zhifubaoAccount != "" {
 					newPayment.ZhifubaoAccount = &zhifubaoAccount
 				}
-				if err := s.taobaoRepo.Create(newPayment); err != nil {
+				if err := taobaoRepo.Create(newPayment); err != nil {
 					return err
 				}
 			}
@@ -598,4 +500,3 @@ func (s *TaobaoPaymentService) ImportUnclaimedExcelFile(f *excelize.File) (int,
 
 	return successCount, matchedCount, errorRows, err
 }
-
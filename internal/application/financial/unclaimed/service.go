@@ -4,7 +4,10 @@ import (
 	"charonoms/internal/domain/financial/payment"
 	"charonoms/internal/domain/financial/separate"
 	"charonoms/internal/domain/financial/unclaimed"
+	orderEntity "charonoms/internal/domain/order/entity"
 	orderRepo "charonoms/internal/domain/order/repository"
+	orderService "charonoms/internal/domain/order/service"
+	"charonoms/internal/domain/shared/money"
 	"context"
 	"fmt"
 	"regexp"
@@ -16,11 +19,12 @@ import (
 )
 
 type UnclaimedService struct {
-	db             *gorm.DB
-	unclaimedRepo  unclaimed.UnclaimedRepository
-	paymentRepo    payment.PaymentRepository
-	orderRepo      orderRepo.OrderRepository
-	separateService *separate.SeparateAccountDomainService
+	db                 *gorm.DB
+	unclaimedRepo      unclaimed.UnclaimedRepository
+	paymentRepo        payment.PaymentRepository
+	orderRepo          orderRepo.OrderRepository
+	separateService    *separate.SeparateAccountDomainService
+	orderStatusService *orderService.OrderStatusService
 }
 
 // NewUnclaimedService 创建常规待认领服务实例
@@ -32,11 +36,12 @@ func NewUnclaimedService(
 	separateService *separate.SeparateAccountDomainService,
 ) *UnclaimedService {
 	return &UnclaimedService{
-		db:              db,
-		unclaimedRepo:   unclaimedRepo,
-		paymentRepo:     paymentRepo,
-		orderRepo:       orderRepository,
-		separateService: separateService,
+		db:                 db,
+		unclaimedRepo:      unclaimedRepo,
+		paymentRepo:        paymentRepo,
+		orderRepo:          orderRepository,
+		separateService:    separateService,
+		orderStatusService: orderService.NewOrderStatusService(db),
 	}
 }
 
@@ -46,12 +51,14 @@ func (s *UnclaimedService) GetList(filters map[string]interface{}) ([]*unclaimed
 }
 
 // Claim 认领待认领款项
-func (s *UnclaimedService) Claim(unclaimedID int, orderID int, userID int) error {
+func (s *UnclaimedService) Claim(unclaimedID int, orderID int, userID int, operator string) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		ctx := context.Background()
+		unclaimedRepo := s.unclaimedRepo.WithTx(tx)
+		paymentRepo := s.paymentRepo.WithTx(tx)
 
 		// 获取待认领记录
-		unclaimedRecord, err := s.unclaimedRepo.GetByID(unclaimedID)
+		unclaimedRecord, err := unclaimedRepo.GetByID(unclaimedID)
 		if err != nil {
 			return fmt.Errorf("待认领记录不存在")
 		}
@@ -62,7 +69,7 @@ func (s *UnclaimedService) Claim(unclaimedID int, orderID int, userID int) error
 		}
 
 		// 验证订单存在且状态为20(未支付)或30(部分支付)
-		order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+		order, err := s.orderRepo.WithTx(tx).GetOrderByID(ctx, orderID)
 		if err != nil {
 			return fmt.Errorf("订单不存在")
 		}
@@ -71,13 +78,13 @@ func (s *UnclaimedService) Claim(unclaimedID int, orderID int, userID int) error
 		}
 
 		// 计算已有收款总额
-		totalPaid, err := s.paymentRepo.GetTotalPaidAmount(orderID)
+		totalPaid, err := paymentRepo.GetTotalPaidAmount(orderID)
 		if err != nil {
 			return err
 		}
 
 		// 验证认领金额不超过订单实收金额
-		if totalPaid+unclaimedRecord.PaymentAmount > order.AmountReceived {
+		if totalPaid.Add(unclaimedRecord.PaymentAmount).GreaterThan(order.AmountReceived) {
 			return fmt.Errorf("收款金额超出订单实收金额")
 		}
 
@@ -96,12 +103,12 @@ func (s *UnclaimedService) Claim(unclaimedID int, orderID int, userID int) error
 			Status:          payment.PaymentStatusPaid, // 已支付
 		}
 
-		if err := s.paymentRepo.Create(paymentCollection); err != nil {
+		if err := paymentRepo.Create(paymentCollection); err != nil {
 			return err
 		}
 
 		// 生成分账明细
-		if err := s.separateService.GenerateSeparateAccounts(paymentCollection.ID, orderID); err != nil {
+		if err := s.separateService.WithTx(tx).GenerateSeparateAccounts(paymentCollection.ID, orderID); err != nil {
 			return err
 		}
 
@@ -109,12 +116,12 @@ func (s *UnclaimedService) Claim(unclaimedID int, orderID int, userID int) error
 		unclaimedRecord.Status = unclaimed.UnclaimedStatusClaimed
 		unclaimedRecord.Claimer = &userID
 		unclaimedRecord.PaymentID = &paymentCollection.ID
-		if err := s.unclaimedRepo.Update(unclaimedRecord); err != nil {
+		if err := unclaimedRepo.Update(unclaimedRecord); err != nil {
 			return err
 		}
 
 		// 更新订单支付状态
-		return s.updateOrderPaymentStatus(orderID)
+		return s.updateOrderPaymentStatus(tx, orderID, operator, "认领待认领收款")
 	})
 }
 
@@ -141,6 +148,7 @@ func (s *UnclaimedService) ImportExcelRecords(records []map[string]interface{})
 	var errors []string
 
 	err := s.db.Transaction(func(tx *gorm.DB) error {
+		unclaimedRepo := s.unclaimedRepo.WithTx(tx)
 		for i, record := range records {
 			rowNum := i + 2 // Excel从第2行开始（第1行是标题）
 
@@ -173,14 +181,14 @@ func (s *UnclaimedService) ImportExcelRecords(records []map[string]interface{})
 
 			// 尝试自动匹配已有的未核验收款记录
 			if merchantOrder != "" {
-				paymentID, err := s.unclaimedRepo.FindMatchingPayment(merchantOrder, paymentMethod, paymentAmount, payeeEntity)
+				paymentID, err := unclaimedRepo.FindMatchingPayment(merchantOrder, paymentMethod, paymentAmount, payeeEntity)
 				if err != nil {
 					return err
 				}
 
 				if paymentID > 0 {
 					// 找到匹配，更新为已支付状态
-					if err := s.unclaimedRepo.UpdatePaymentStatus(paymentID, &arrivalTime); err != nil {
+					if err := unclaimedRepo.UpdatePaymentStatus(paymentID, &arrivalTime); err != nil {
 						return err
 					}
 					matchedCount++
@@ -192,7 +200,7 @@ func (s *UnclaimedService) ImportExcelRecords(records []map[string]interface{})
 			// 未匹配到，插入为待认领记录
 			newUnclaimed := &unclaimed.Unclaimed{
 				PaymentMethod: paymentMethod,
-				PaymentAmount: paymentAmount,
+				PaymentAmount: money.FromFloat(paymentAmount),
 				Payer:         &payer,
 				PayeeEntity:   payeeEntity,
 				MerchantOrder: &merchantOrder,
@@ -200,7 +208,7 @@ func (s *UnclaimedService) ImportExcelRecords(records []map[string]interface{})
 				Status:        unclaimed.UnclaimedStatusPending,
 			}
 
-			if err := s.unclaimedRepo.Create(newUnclaimed); err != nil {
+			if err := unclaimedRepo.Create(newUnclaimed); err != nil {
 				return err
 			}
 			successCount++
@@ -211,34 +219,29 @@ func (s *UnclaimedService) ImportExcelRecords(records []map[string]interface{})
 	return successCount, matchedCount, errors, err
 }
 
-// updateOrderPaymentStatus 更新订单支付状态
-func (s *UnclaimedService) updateOrderPaymentStatus(orderID int) error {
+// updateOrderPaymentStatus 在调用方事务中按收款情况更新订单支付状态，operator 和 reason 记录到订单状态历史
+func (s *UnclaimedService) updateOrderPaymentStatus(tx *gorm.DB, orderID int, operator, reason string) error {
 	ctx := context.Background()
 
 	// 获取订单
-	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	order, err := s.orderRepo.WithTx(tx).GetOrderByID(ctx, orderID)
 	if err != nil {
 		return err
 	}
 
 	// 计算总收款
-	totalPaid, err := s.paymentRepo.GetTotalPaidAmount(orderID)
+	totalPaid, err := s.paymentRepo.WithTx(tx).GetTotalPaidAmount(orderID)
 	if err != nil {
 		return err
 	}
 
 	// 更新订单状态
-	var newStatus int
-	if totalPaid == 0 {
-		newStatus = 20 // 未支付
-	} else if totalPaid >= order.AmountReceived {
-		newStatus = 40 // 已支付
-	} else {
-		newStatus = 30 // 部分支付
-	}
-
-	// 使用 UpdateOrderStatus 更新状态
-	return s.orderRepo.UpdateOrderStatus(ctx, orderID, newStatus, 0)
+	return s.orderStatusService.TransitionTx(tx, orderService.StatusChange{
+		OrderID:  orderID,
+		To:       orderEntity.PaymentStatus(totalPaid, order.AmountReceived),
+		Operator: operator,
+		Reason:   reason,
+	})
 }
 
 // GenerateTemplate 生成常规待认领Excel模板
@@ -296,11 +299,11 @@ func (s *UnclaimedService) ImportExcelFile(f *excelize.File) (int, int, []string
 
 	// 付款方式映射（严格按照Python版本）
 	paymentMethodMap := map[string]int{
-		"微信":     0,
-		"支付宝":    1,
-		"优利支付":   2,
-		"零零购支付":  3,
-		"对公转账":   9,
+		"微信":    0,
+		"支付宝":   1,
+		"优利支付":  2,
+		"零零购支付": 3,
+		"对公转账":  9,
 	}
 
 	// 收款主体映射（严格按照Python版本）
@@ -316,6 +319,8 @@ func (s *UnclaimedService) ImportExcelFile(f *excelize.File) (int, int, []string
 	datePattern := regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
 
 	err = s.db.Transaction(func(tx *gorm.DB) error {
+		unclaimedRepo := s.unclaimedRepo.WithTx(tx)
+		separateService := s.separateService.WithTx(tx)
 		// 跳过表头，从第二行开始
 		for idx := 1; idx < len(rows); idx++ {
 			row := rows[idx]
@@ -398,25 +403,25 @@ func (s *UnclaimedService) ImportExcelFile(f *excelize.File) (int, int, []string
 			matched := false
 			if merchantOrder != "" {
 				// 查找payment_collection表中符合条件的记录
-				paymentID, err := s.unclaimedRepo.FindMatchingPayment(merchantOrder, paymentMethod, paymentAmount, payeeEntity)
+				paymentID, err := unclaimedRepo.FindMatchingPayment(merchantOrder, paymentMethod, paymentAmount, payeeEntity)
 				if err != nil {
 					return err
 				}
 
 				if paymentID > 0 {
 					// 匹配成功，更新payment_collection状态为已支付(status=20)，更新到账时间
-					if err := s.unclaimedRepo.UpdatePaymentStatus(paymentID, &arrivalTime); err != nil {
+					if err := unclaimedRepo.UpdatePaymentStatus(paymentID, &arrivalTime); err != nil {
 						return err
 					}
 
 					// 获取payment信息以获取orderID
-					paymentInfo, err := s.paymentRepo.GetByID(paymentID)
+					paymentInfo, err := s.paymentRepo.WithTx(tx).GetByID(paymentID)
 					if err != nil {
 						return err
 					}
 
 					// 生成分账明细
-					if err := s.separateService.GenerateSeparateAccounts(paymentID, paymentInfo.OrderID); err != nil {
+					if err := separateService.GenerateSeparateAccounts(paymentID, paymentInfo.OrderID); err != nil {
 						return err
 					}
 
@@ -429,14 +434,14 @@ func (s *UnclaimedService) ImportExcelFile(f *excelize.File) (int, int, []string
 			if !matched {
 				newUnclaimed := &unclaimed.Unclaimed{
 					PaymentMethod: paymentMethod,
-					PaymentAmount: paymentAmount,
+					PaymentAmount: money.FromFloat(paymentAmount),
 					Payer:         &payer,
 					PayeeEntity:   payeeEntity,
 					MerchantOrder: &merchantOrder,
 					ArrivalTime:   &arrivalTime,
 					Status:        unclaimed.UnclaimedStatusPending, // 0-待认领
 				}
-				if err := s.unclaimedRepo.Create(newUnclaimed); err != nil {
+				if err := unclaimedRepo.Create(newUnclaimed); err != nil {
 					return err
 				}
 			}
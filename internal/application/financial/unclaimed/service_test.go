@@ -0,0 +1,91 @@
+package unclaimed
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	domainSeparate "charonoms/internal/domain/financial/separate"
+	domainUnclaimed "charonoms/internal/domain/financial/unclaimed"
+	orderEntity "charonoms/internal/domain/order/entity"
+	financialPersistence "charonoms/internal/infrastructure/persistence/financial"
+	orderPersistence "charonoms/internal/infrastructure/persistence/order"
+)
+
+// setupMockDB 创建基于sqlmock的gorm连接
+func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm db: %v", err)
+	}
+
+	return gormDB, mock
+}
+
+// newTestUnclaimedService 按生产装配方式组装待认领服务，仓储均使用同一个 mock 连接
+func newTestUnclaimedService(db *gorm.DB) *UnclaimedService {
+	paymentRepo := financialPersistence.NewPaymentRepository(db)
+	childOrderRepo := orderPersistence.NewChildOrderRepository(db)
+	separateService := domainSeparate.NewSeparateAccountDomainService(financialPersistence.NewSeparateAccountRepository(db), paymentRepo, childOrderRepo, orderEntity.AllocationSetting{})
+	return NewUnclaimedService(db, financialPersistence.NewUnclaimedRepository(db), paymentRepo, orderPersistence.NewOrderRepository(db), separateService)
+}
+
+// TestClaim_SeparateFailureRollsBack 分账明细写入失败时，认领生成的收款记录随事务回滚，待认领记录保持不变
+func TestClaim_SeparateFailureRollsBack(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestUnclaimedService(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `unclaimed` WHERE id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "payment_method", "payment_amount", "payer", "payee_entity", "merchant_order", "status"}).
+			AddRow(5, 0, 500.00, "张三", 0, "M202601120001", domainUnclaimed.UnclaimedStatusPending))
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "student_id", "amount_received", "status"}).
+			AddRow(1, 3, 1000.00, orderEntity.OrderStatusUnpaid))
+	mock.ExpectQuery("SELECT \\* FROM `order_instalment`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(payment_amount\\), 0\\) FROM `payment_collection`").
+		WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(0))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(payment_amount\\), 0\\) FROM `taobao_payment`").
+		WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(0))
+	mock.ExpectExec("INSERT INTO `payment_collection`").
+		WillReturnResult(sqlmock.NewResult(8, 1))
+
+	// 生成分账明细：插入失败
+	mock.ExpectQuery("SELECT \\* FROM `payment_collection` WHERE id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "student_id", "payment_amount", "status"}).
+			AddRow(8, 1, 3, 500.00, 20))
+	mock.ExpectQuery("SELECT \\* FROM `childorders` WHERE parentsid = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parentsid", "goodsid", "amount_received", "status"}).
+			AddRow(11, 1, 5, 1000.00, orderEntity.ChildOrderStatusUnpaid))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `separate_account`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT allocation_strategy FROM orders WHERE id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"allocation_strategy"}).AddRow(""))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(separate_amount\\), 0\\) FROM `separate_account`").
+		WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(0))
+	mock.ExpectQuery("SELECT g.id AS goods_id, g.name AS goods_name").
+		WillReturnRows(sqlmock.NewRows([]string{"goods_id", "goods_name", "brand_id", "brand_name", "classify_id", "classify_name"}).
+			AddRow(5, "数学课", 2, "学而思", 4, "数学"))
+	mock.ExpectExec("INSERT INTO `separate_account`").
+		WillReturnError(errors.New("deadlock found"))
+	mock.ExpectRollback()
+
+	if err := s.Claim(5, 1, 2, "finance01"); err == nil {
+		t.Fatal("Claim() error = nil, want separate insert failure")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package notification
+
+import (
+	"charonoms/internal/domain/notification"
+)
+
+// Service 通知收件箱应用服务
+type Service struct {
+	domainService *notification.Service
+}
+
+// NewService 创建通知收件箱应用服务
+func NewService(domainService *notification.Service) *Service {
+	return &Service{domainService: domainService}
+}
+
+// GetNotifications 获取本人的通知列表及未读数
+func (s *Service) GetNotifications(userID int, unreadOnly bool) ([]notification.Notification, int64, error) {
+	list, err := s.domainService.List(userID, unreadOnly)
+	if err != nil {
+		return nil, 0, err
+	}
+	unread, err := s.domainService.UnreadCount(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return list, unread, nil
+}
+
+// GetUnreadCount 获取本人的未读通知数
+func (s *Service) GetUnreadCount(userID int) (int64, error) {
+	return s.domainService.UnreadCount(userID)
+}
+
+// MarkRead 标记通知已读
+func (s *Service) MarkRead(id int, userID int) error {
+	return s.domainService.MarkRead(id, userID)
+}
+
+// MarkAllRead 标记本人全部通知已读
+func (s *Service) MarkAllRead(userID int) error {
+	return s.domainService.MarkAllRead(userID)
+}
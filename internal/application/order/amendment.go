@@ -0,0 +1,340 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+
+	approvalEntity "charonoms/internal/domain/approval/entity"
+	approvalService "charonoms/internal/domain/approval/service"
+	"charonoms/internal/domain/order/entity"
+	"charonoms/internal/domain/order/service"
+	"charonoms/internal/domain/shared/money"
+)
+
+// ErrInvalidAmendment 变更请求不合法
+var ErrInvalidAmendment = errors.New("订单变更请求错误")
+
+// AmendGoodsRequest 变更新增的商品
+type AmendGoodsRequest struct {
+	GoodsID int `json:"goods_id"`
+}
+
+// AmendOrderRequest 订单变更请求
+type AmendOrderRequest struct {
+	AddGoods            []AmendGoodsRequest `json:"add_goods"`
+	RemoveChildOrderIDs []int               `json:"remove_childorder_ids"`
+	ActivityIDs         []int               `json:"activity_ids"` // 为 nil 时沿用订单当前活动
+	Instalments         []InstalmentRequest `json:"instalments"`  // 为空时沿用当前分期计划（金额不变时）
+	Reason              string              `json:"reason"`
+}
+
+// giftKey 赠品子订单按活动和商品匹配
+type giftKey struct {
+	ActivityID int
+	GoodsID    int
+}
+
+// AmendOrder 发起订单变更：按服务端价格和活动规则重算金额，生成变更单并提交审批
+// 审批通过前订单保持不变，返回变更单ID
+func (s *Service) AmendOrder(ctx context.Context, orderID int, req *AmendOrderRequest, username string, userID int) (int, error) {
+	// 1. 查询订单并校验状态
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errors.New("订单不存在")
+		}
+		return 0, fmt.Errorf("查询订单失败: %w", err)
+	}
+	if !order.CanAmend() {
+		return 0, fmt.Errorf("%w: 只能变更未支付、部分支付或已支付的订单", ErrInvalidAmendment)
+	}
+
+	var refundCount int64
+	if err := s.db.WithContext(ctx).Table("refund_order").
+		Where("order_id = ? AND status IN (0, 10)", orderID).
+		Count(&refundCount).Error; err != nil {
+		return 0, fmt.Errorf("查询退费订单失败: %w", err)
+	}
+	if refundCount > 0 {
+		return 0, fmt.Errorf("%w: 已发生退费的订单不支持变更", ErrInvalidAmendment)
+	}
+
+	// 2. 当前有效子订单，区分普通子订单和赠品子订单
+	children, err := s.childOrderRepo.ListByOrderID(orderID)
+	if err != nil {
+		return 0, fmt.Errorf("查询子订单失败: %w", err)
+	}
+	regular := make(map[int]*entity.ChildOrder)
+	gifts := make(map[giftKey][]*entity.ChildOrder)
+	for _, c := range children {
+		if c.IsCancelled() {
+			continue
+		}
+		if c.GiftActivityID != nil {
+			key := giftKey{ActivityID: *c.GiftActivityID, GoodsID: c.GoodsID}
+			gifts[key] = append(gifts[key], c)
+			continue
+		}
+		regular[c.ID] = c
+	}
+
+	removed := make(map[int]bool, len(req.RemoveChildOrderIDs))
+	for _, id := range req.RemoveChildOrderIDs {
+		if _, ok := regular[id]; !ok {
+			return 0, fmt.Errorf("%w: 子订单 %d 不属于该订单或不可移除", ErrInvalidAmendment, id)
+		}
+		removed[id] = true
+	}
+
+	// 3. 变更后的商品列表（保留的子订单 + 新增商品），同一商品只能出现一次
+	kept := make([]*entity.ChildOrder, 0, len(regular))
+	for _, c := range regular {
+		if !removed[c.ID] {
+			kept = append(kept, c)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].ID < kept[j].ID })
+
+	goodsIDs := make([]int, 0, len(kept)+len(req.AddGoods))
+	seen := make(map[int]bool)
+	for _, c := range kept {
+		goodsIDs = append(goodsIDs, c.GoodsID)
+		seen[c.GoodsID] = true
+	}
+	for _, g := range req.AddGoods {
+		if seen[g.GoodsID] {
+			return 0, fmt.Errorf("%w: 商品 %d 已在订单中", ErrInvalidAmendment, g.GoodsID)
+		}
+		goodsIDs = append(goodsIDs, g.GoodsID)
+		seen[g.GoodsID] = true
+	}
+	if len(goodsIDs) == 0 {
+		return 0, fmt.Errorf("%w: 变更后订单至少保留一个商品", ErrInvalidAmendment)
+	}
+
+	// 4. 按服务端价格和活动规则重算优惠与金额
+	goodsList := make([]GoodsItemRequest, 0, len(goodsIDs))
+	for _, goodsID := range goodsIDs {
+		priceInfo, err := s.goodsRepo.GetGoodsTotalPrice(ctx, goodsID)
+		if err != nil {
+			return 0, fmt.Errorf("商品 %d 不存在或查询价格失败: %w", goodsID, err)
+		}
+		goodsList = append(goodsList, GoodsItemRequest{
+			GoodsID:    goodsID,
+			TotalPrice: money.FromFloat(toFloat64(priceInfo["total_price"])),
+			Price:      money.FromFloat(toFloat64(priceInfo["price"])),
+		})
+	}
+
+	activityIDs := uniqueActivityIDs(req.ActivityIDs)
+	if activityIDs == nil {
+		if err := s.db.WithContext(ctx).Table("orders_activity").
+			Where("orders_id = ?", orderID).
+			Order("id ASC").
+			Pluck("activity_id", &activityIDs).Error; err != nil {
+			return 0, fmt.Errorf("查询订单活动失败: %w", err)
+		}
+	}
+
+	discountResult, err := s.discountService.CalculateDiscountDetail(ctx, toGoodsForDiscount(goodsList), activityIDs)
+	if err != nil {
+		return 0, fmt.Errorf("计算活动优惠失败: %w", err)
+	}
+
+	goodsItems := make([]service.GoodsItem, 0, len(goodsList))
+	for _, g := range goodsList {
+		goodsItems = append(goodsItems, service.GoodsItem{
+			GoodsID:    g.GoodsID,
+			TotalPrice: g.TotalPrice,
+			Price:      g.Price,
+		})
+	}
+	amountReceivable, amountReceived := s.orderService.CalculateOrderAmounts(goodsItems, discountResult.TotalDiscount)
+
+	// 5. 变更后实收金额不能低于已收款金额，多收部分需先走退费
+	paidAmount, err := s.getOrderPaidAmount(orderID)
+	if err != nil {
+		return 0, err
+	}
+	if amountReceived.LessThan(paidAmount) {
+		return 0, fmt.Errorf("%w: 变更后实收金额%s低于已收款金额%s，请先办理退费", ErrInvalidAmendment, amountReceived, paidAmount)
+	}
+
+	// 6. 生成变更明细
+	items := make([]*entity.OrderAmendmentItem, 0, len(children))
+	changed := len(req.RemoveChildOrderIDs) > 0 || len(req.AddGoods) > 0
+	keptByGoods := make(map[int]*entity.ChildOrder, len(kept))
+	for _, c := range kept {
+		keptByGoods[c.GoodsID] = c
+	}
+	for _, g := range goodsList {
+		childDiscount := discountResult.ChildDiscounts[g.GoodsID]
+		childReceivable, childReceived := s.orderService.CalculateChildAmounts(g.TotalPrice, g.Price, childDiscount)
+
+		item := &entity.OrderAmendmentItem{
+			Action:           entity.AmendmentActionAdd,
+			GoodsID:          g.GoodsID,
+			AmountReceivable: childReceivable,
+			AmountReceived:   childReceived,
+			DiscountAmount:   childDiscount,
+		}
+		if c, ok := keptByGoods[g.GoodsID]; ok {
+			childOrderID := c.ID
+			item.Action = entity.AmendmentActionKeep
+			item.ChildOrderID = &childOrderID
+			if !c.AmountReceived.Equal(childReceived) || !c.AmountReceivable.Equal(childReceivable) {
+				changed = true
+			}
+		}
+		items = append(items, item)
+	}
+	for _, id := range req.RemoveChildOrderIDs {
+		c := regular[id]
+		childOrderID := c.ID
+		items = append(items, &entity.OrderAmendmentItem{
+			Action:           entity.AmendmentActionRemove,
+			ChildOrderID:     &childOrderID,
+			GoodsID:          c.GoodsID,
+			AmountReceivable: c.AmountReceivable,
+			AmountReceived:   c.AmountReceived,
+			DiscountAmount:   c.DiscountAmount,
+		})
+	}
+
+	// 7. 按变更后的商品重新计算满赠赠品：已有的保留，多出的移除，缺少的新增
+	giftGoods, err := s.discountService.CalculateGifts(ctx, toGoodsForDiscount(goodsList), activityIDs)
+	if err != nil {
+		return 0, fmt.Errorf("计算满赠赠品失败: %w", err)
+	}
+	wantGifts := make(map[giftKey]int)
+	for _, g := range giftGoods {
+		wantGifts[giftKey{ActivityID: g.ActivityID, GoodsID: g.GoodsID}] += g.Quantity
+	}
+	for key, existing := range gifts {
+		for i, c := range existing {
+			childOrderID := c.ID
+			action := entity.AmendmentActionKeep
+			if i >= wantGifts[key] {
+				action = entity.AmendmentActionRemove
+				changed = true
+			}
+			activityID := key.ActivityID
+			items = append(items, &entity.OrderAmendmentItem{
+				Action:         action,
+				ChildOrderID:   &childOrderID,
+				GoodsID:        c.GoodsID,
+				GiftActivityID: &activityID,
+			})
+		}
+	}
+	for key, quantity := range wantGifts {
+		for i := len(gifts[key]); i < quantity; i++ {
+			activityID := key.ActivityID
+			items = append(items, &entity.OrderAmendmentItem{
+				Action:         entity.AmendmentActionAdd,
+				GoodsID:        key.GoodsID,
+				GiftActivityID: &activityID,
+			})
+			changed = true
+		}
+	}
+
+	// 8. 分期计划：提交了则重新校验；未提交时金额不变才沿用原计划
+	instalments := make([]*entity.OrderInstalment, 0, len(req.Instalments))
+	if len(req.Instalments) > 0 {
+		for _, r := range req.Instalments {
+			instalments = append(instalments, &entity.OrderInstalment{DueDate: r.DueDate, Amount: r.Amount})
+		}
+		if err := entity.NormalizeInstalments(instalments, amountReceived); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidInstalments, err)
+		}
+		changed = true
+	} else if len(order.Instalments) > 0 {
+		if !amountReceived.Equal(order.AmountReceived) {
+			return 0, fmt.Errorf("%w: 变更后实收金额变化，需重新提交分期计划", ErrInvalidInstalments)
+		}
+		for _, inst := range order.Instalments {
+			instalments = append(instalments, &entity.OrderInstalment{Seq: inst.Seq, DueDate: inst.DueDate, Amount: inst.Amount})
+		}
+	}
+
+	if req.ActivityIDs != nil {
+		changed = true
+	}
+	if !changed && amountReceived.Equal(order.AmountReceived) && amountReceivable.Equal(order.AmountReceivable) {
+		return 0, fmt.Errorf("%w: 订单没有变更内容", ErrInvalidAmendment)
+	}
+
+	amendment := &entity.OrderAmendment{
+		OrderID:                orderID,
+		AmountReceivableBefore: order.AmountReceivable,
+		AmountReceivedBefore:   order.AmountReceived,
+		DiscountAmountBefore:   order.DiscountAmount,
+		AmountReceivableAfter:  amountReceivable,
+		AmountReceivedAfter:    amountReceived,
+		DiscountAmountAfter:    discountResult.TotalDiscount,
+		ActivityIDs:            activityIDs,
+		ActivityDiscounts:      toOrderActivityDiscounts(discountResult),
+		Instalments:            instalments,
+		Reason:                 req.Reason,
+		Submitter:              username,
+		Items:                  items,
+	}
+
+	// 9. 按变更后金额和金额变化选择订单变更审批流模板
+	approvalPayload := approvalEntity.BusinessPayload{
+		approvalEntity.PayloadFieldAmount:      amendment.AmountReceivedAfter.Float64(),
+		approvalEntity.PayloadFieldAmountDelta: amendment.AmountReceivedAfter.Sub(amendment.AmountReceivedBefore).Float64(),
+	}
+	template, err := approvalService.SelectTemplate(s.db, approvalService.FlowTypeCodeOrderAmendment, approvalPayload)
+	if err != nil {
+		return 0, err
+	}
+
+	// 10. 在事务中保存变更单并创建审批流
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.amendmentService.CreateTx(tx, amendment); err != nil {
+			return err
+		}
+
+		flowID, err := s.approvalService.StartFlowInstance(tx, template.TemplateID, template.FlowTypeID, userID, approvalService.BusinessRef{
+			Type:    approvalService.BusinessTypeOrderAmendment,
+			ID:      amendment.ID,
+			Payload: approvalPayload,
+		})
+		if err != nil {
+			return fmt.Errorf("创建审批流实例失败: %w", err)
+		}
+
+		return s.amendmentService.BindApprovalFlowTx(tx, amendment.ID, flowID)
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrAmendmentPending) {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidAmendment, err)
+		}
+		return 0, err
+	}
+
+	return amendment.ID, nil
+}
+
+// GetOrderAmendments 获取订单的变更单列表
+func (s *Service) GetOrderAmendments(ctx context.Context, orderID int) ([]*entity.OrderAmendment, error) {
+	return s.amendmentService.ListByOrderID(ctx, orderID)
+}
+
+// GetOrderAmendment 获取变更单详情
+func (s *Service) GetOrderAmendment(ctx context.Context, orderID int, amendmentID int) (*entity.OrderAmendment, error) {
+	amendment, err := s.amendmentService.GetByID(ctx, amendmentID)
+	if err != nil {
+		return nil, err
+	}
+	if amendment == nil || amendment.OrderID != orderID {
+		return nil, errors.New("变更单不存在")
+	}
+	return amendment, nil
+}
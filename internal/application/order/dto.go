@@ -1,29 +1,129 @@
 package order
 
-import "time"
+import (
+	"time"
+
+	"charonoms/internal/domain/shared/money"
+)
 
 // GoodsItemRequest 商品项请求
 type GoodsItemRequest struct {
-	GoodsID    int     `json:"goods_id"`
-	TotalPrice float64 `json:"total_price"`
-	Price      float64 `json:"price"`
+	GoodsID    int         `json:"goods_id"`
+	TotalPrice money.Money `json:"total_price"`
+	Price      money.Money `json:"price"`
+}
+
+// InstalmentRequest 分期请求
+type InstalmentRequest struct {
+	DueDate time.Time   `json:"due_date"`
+	Amount  money.Money `json:"amount"`
 }
 
 // CreateOrderRequest 创建订单请求
 type CreateOrderRequest struct {
-	StudentID           int                  `json:"student_id"`
-	GoodsList           []GoodsItemRequest   `json:"goods_list"`
-	ExpectedPaymentTime *time.Time           `json:"expected_payment_time"`
-	ActivityIDs         []int                `json:"activity_ids"`
-	DiscountAmount      float64              `json:"discount_amount"`
-	ChildDiscounts      map[int]float64      `json:"child_discounts"`
+	StudentID           int                 `json:"student_id"`
+	GoodsList           []GoodsItemRequest  `json:"goods_list"`
+	ExpectedPaymentTime *time.Time          `json:"expected_payment_time"`
+	ActivityIDs         []int               `json:"activity_ids"`
+	DiscountAmount      money.Money         `json:"discount_amount"`
+	ChildDiscounts      map[int]money.Money `json:"child_discounts"`
+	Instalments         []InstalmentRequest `json:"instalments"`         // 分期计划，为空时按预计付款时间一次付清
+	AllocationStrategy  string              `json:"allocation_strategy"` // 收款分账策略，为空时使用系统设置
 }
 
 // UpdateOrderRequest 更新订单请求
 type UpdateOrderRequest struct {
-	GoodsList           []GoodsItemRequest   `json:"goods_list"`
-	ExpectedPaymentTime *time.Time           `json:"expected_payment_time"`
-	ActivityIDs         []int                `json:"activity_ids"`
-	DiscountAmount      float64              `json:"discount_amount"`
-	ChildDiscounts      map[int]float64      `json:"child_discounts"`
+	GoodsList           []GoodsItemRequest  `json:"goods_list"`
+	ExpectedPaymentTime *time.Time          `json:"expected_payment_time"`
+	ActivityIDs         []int               `json:"activity_ids"`
+	DiscountAmount      money.Money         `json:"discount_amount"`
+	ChildDiscounts      map[int]money.Money `json:"child_discounts"`
+	Instalments         []InstalmentRequest `json:"instalments"`         // 分期计划，为空时按预计付款时间一次付清
+	AllocationStrategy  string              `json:"allocation_strategy"` // 收款分账策略，为空时使用系统设置
+}
+
+// OverdueInstalment 逾期未收齐的分期
+type OverdueInstalment struct {
+	OrderID           int         `json:"order_id"`
+	InstalmentID      int         `json:"instalment_id"` // 未设置分期的订单按预计付款时间视为一期，此时为0
+	Seq               int         `json:"seq"`
+	DueDate           time.Time   `json:"due_date"`
+	Amount            money.Money `json:"amount"`
+	PaidAmount        money.Money `json:"paid_amount"`
+	OutstandingAmount money.Money `json:"outstanding_amount"`
+	OverdueDays       int         `json:"overdue_days"`
+}
+
+// GiftItem 满赠活动赠品
+type GiftItem struct {
+	ActivityID int    `json:"activity_id"`
+	GoodsID    int    `json:"goods_id"`
+	GoodsName  string `json:"goods_name"`
+	Quantity   int    `json:"quantity"`
+}
+
+// ActivityDiscountItem 单个活动对单个商品贡献的优惠
+type ActivityDiscountItem struct {
+	ActivityID     int         `json:"activity_id"`
+	GoodsID        int         `json:"goods_id"`
+	DiscountAmount money.Money `json:"discount_amount"`
+}
+
+// DiscountCalculation 订单优惠计算结果
+type DiscountCalculation struct {
+	TotalDiscount      money.Money            `json:"total_discount"`
+	ChildDiscounts     map[int]money.Money    `json:"child_discounts"`
+	Breakdown          []ActivityDiscountItem `json:"breakdown"`
+	AppliedActivityIDs []int                  `json:"applied_activity_ids"`
+	Gifts              []GiftItem             `json:"gifts"`
+}
+
+// OrderListQuery 订单列表查询参数
+type OrderListQuery struct {
+	ID                       *int
+	StudentID                *int
+	Status                   *int
+	CreateDateStart          *string // 格式：YYYY-MM-DD
+	CreateDateEnd            *string
+	ExpectedPaymentDateStart *string
+	ExpectedPaymentDateEnd   *string
+	AmountMin                *money.Money
+	AmountMax                *money.Money
+	ActivityID               *int
+	SortBy                   string
+	SortOrder                string // asc 或 desc，默认 desc
+	Page                     int
+	PageSize                 int
+}
+
+// OrderListResponse 订单列表响应
+type OrderListResponse struct {
+	Orders   []map[string]interface{} `json:"orders"`
+	Total    int64                    `json:"total"`
+	Page     int                      `json:"page"`
+	PageSize int                      `json:"page_size"`
+}
+
+// ChildOrderListQuery 子订单列表查询参数
+type ChildOrderListQuery struct {
+	ID              *int
+	ParentsID       *int
+	GoodsID         *int
+	Status          *int
+	CreateDateStart *string // 格式：YYYY-MM-DD
+	CreateDateEnd   *string
+	AmountMin       *money.Money
+	AmountMax       *money.Money
+	SortBy          string
+	SortOrder       string // asc 或 desc，默认 desc
+	Page            int
+	PageSize        int
+}
+
+// ChildOrderListResponse 子订单列表响应
+type ChildOrderListResponse struct {
+	ChildOrders []map[string]interface{} `json:"childorders"`
+	Total       int64                    `json:"total"`
+	Page        int                      `json:"page"`
+	PageSize    int                      `json:"page_size"`
 }
@@ -0,0 +1,419 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"charonoms/internal/domain/shared/money"
+)
+
+// 订单导入模式
+const (
+	ImportModeBestEffort   = "best_effort"    // 逐单创建，失败的订单跳过
+	ImportModeAllOrNothing = "all_or_nothing" // 所有订单在同一事务中创建，任一行或任一订单有错误则不创建任何订单
+)
+
+// 订单导入模板列（同一订单序号的多行合并为一个订单，每行一个商品）
+var orderImportHeaders = []string{"订单序号", "学生ID", "学生手机号", "商品ID", "商品名称", "活动ID", "预计付款日期"}
+
+// ImportOrdersResult 订单导入结果
+type ImportOrdersResult struct {
+	SuccessCount int      `json:"success_count"`
+	OrderIDs     []int    `json:"order_ids"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+// orderImportGroup 同一订单序号的导入行
+type orderImportGroup struct {
+	key                 string
+	rowNums             []int
+	studentID           string
+	studentPhone        string
+	activityIDs         string
+	expectedPaymentDate string
+	goods               []orderImportGoods
+	invalid             bool // 有行错误的订单不创建
+}
+
+type orderImportGoods struct {
+	rowNum    int
+	goodsID   string
+	goodsName string
+}
+
+// rowRange 返回订单涉及的行号描述
+func (g *orderImportGroup) rowRange() string {
+	nums := make([]string, 0, len(g.rowNums))
+	for _, n := range g.rowNums {
+		nums = append(nums, strconv.Itoa(n))
+	}
+	return "第" + strings.Join(nums, "、") + "行"
+}
+
+// GenerateImportTemplate 生成订单导入Excel模板
+func (s *Service) GenerateImportTemplate() (*excelize.File, error) {
+	f := excelize.NewFile()
+	sheetName := "订单导入模板"
+	index, err := f.NewSheet(sheetName)
+	if err != nil {
+		return nil, err
+	}
+	f.SetActiveSheet(index)
+
+	// 设置表头：加粗、居中
+	style, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Alignment: &excelize.Alignment{
+			Horizontal: "center",
+			Vertical:   "center",
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, header := range orderImportHeaders {
+		cell := string(rune('A'+i)) + "1"
+		f.SetCellValue(sheetName, cell, header)
+		f.SetCellStyle(sheetName, cell, cell, style)
+	}
+
+	// 示例数据：订单1包含两个商品，学生和活动只需在第一行填写
+	f.SetSheetRow(sheetName, "A2", &[]interface{}{"1", "", "13800000000", "", "示例商品A", "1,2", "2026-11-01"})
+	f.SetSheetRow(sheetName, "A3", &[]interface{}{"1", "", "", "12", "", "", ""})
+	f.SetSheetRow(sheetName, "A4", &[]interface{}{"2", "35", "", "12", "", "", ""})
+
+	// 删除默认的Sheet1
+	f.DeleteSheet("Sheet1")
+
+	return f, nil
+}
+
+// ImportOrdersFromExcel 从Excel批量创建草稿订单
+// 学生按ID或手机号匹配，商品按ID或名称匹配，价格和优惠按服务端规则计算后通过 CreateOrder 创建
+func (s *Service) ImportOrdersFromExcel(ctx context.Context, f *excelize.File, mode string) (*ImportOrdersResult, error) {
+	if mode == "" {
+		mode = ImportModeBestEffort
+	}
+	if mode != ImportModeBestEffort && mode != ImportModeAllOrNothing {
+		return nil, fmt.Errorf("导入模式不正确，仅支持：%s、%s", ImportModeBestEffort, ImportModeAllOrNothing)
+	}
+
+	sheetName := f.GetSheetName(0)
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		return nil, fmt.Errorf("读取Excel失败: %v", err)
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("Excel文件为空或只有表头")
+	}
+
+	result := &ImportOrdersResult{OrderIDs: []int{}}
+
+	// 1. 按订单序号分组
+	groups, errorRows := groupOrderImportRows(rows)
+	result.Errors = append(result.Errors, errorRows...)
+
+	// 2. 解析学生、商品、活动和价格，生成创建订单请求
+	goodsByID, goodsByName, err := s.loadImportGoods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	requests := make([]*CreateOrderRequest, len(groups))
+	for i, g := range groups {
+		if g.invalid {
+			continue
+		}
+		req, rowErrors := s.buildImportOrderRequest(ctx, g, goodsByID, goodsByName)
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			continue
+		}
+		requests[i] = req
+	}
+
+	if mode == ImportModeAllOrNothing && len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	// 3. 全部成功模式下所有订单在同一事务中创建，任一订单失败则全部回滚
+	if mode == ImportModeAllOrNothing {
+		return s.createImportOrdersTx(ctx, groups, requests, result)
+	}
+
+	// 4. 逐单创建，失败的订单跳过
+	for i, g := range groups {
+		if requests[i] == nil {
+			continue
+		}
+		orderID, err := s.CreateOrder(ctx, requests[i])
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s：%v", g.rowRange(), err))
+			continue
+		}
+		result.OrderIDs = append(result.OrderIDs, orderID)
+		result.SuccessCount++
+	}
+
+	return result, nil
+}
+
+// createImportOrdersTx 在一个事务中创建全部导入订单，任一订单失败时回滚并在结果中返回该订单的错误
+func (s *Service) createImportOrdersTx(ctx context.Context, groups []*orderImportGroup, requests []*CreateOrderRequest, result *ImportOrdersResult) (*ImportOrdersResult, error) {
+	var orderIDs []int
+	var failure string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repo := s.orderRepo.WithTx(tx)
+		for i, g := range groups {
+			if requests[i] == nil {
+				continue
+			}
+			orderID, err := s.createOrder(ctx, repo, requests[i])
+			if err != nil {
+				failure = fmt.Sprintf("%s：%v", g.rowRange(), err)
+				return err
+			}
+			orderIDs = append(orderIDs, orderID)
+		}
+		return nil
+	})
+	if failure != "" {
+		result.Errors = append(result.Errors, failure)
+		return result, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("导入订单失败: %w", err)
+	}
+
+	result.OrderIDs = append(result.OrderIDs, orderIDs...)
+	result.SuccessCount = len(orderIDs)
+	return result, nil
+}
+
+// groupOrderImportRows 跳过表头，按订单序号将行合并为订单
+func groupOrderImportRows(rows [][]string) ([]*orderImportGroup, []string) {
+	var groups []*orderImportGroup
+	var errorRows []string
+	byKey := make(map[string]*orderImportGroup)
+
+	cell := func(row []string, i int) string {
+		if i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	// 同一订单的学生、活动、预计付款日期只需填写一次，多行填写时必须一致
+	merge := func(field *string, value string) bool {
+		if value == "" {
+			return true
+		}
+		if *field != "" && *field != value {
+			return false
+		}
+		*field = value
+		return true
+	}
+
+	for idx := 1; idx < len(rows); idx++ {
+		row := rows[idx]
+		rowNum := idx + 1
+
+		// 跳过空行
+		if strings.Join(row, "") == "" {
+			continue
+		}
+
+		key := cell(row, 0)
+		if key == "" {
+			errorRows = append(errorRows, fmt.Sprintf("第%d行：订单序号不能为空", rowNum))
+			continue
+		}
+
+		g, ok := byKey[key]
+		if !ok {
+			g = &orderImportGroup{key: key}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.rowNums = append(g.rowNums, rowNum)
+
+		if !merge(&g.studentID, cell(row, 1)) || !merge(&g.studentPhone, cell(row, 2)) {
+			errorRows = append(errorRows, fmt.Sprintf("第%d行：与订单序号%s的其他行学生不一致", rowNum, key))
+			g.invalid = true
+			continue
+		}
+		if !merge(&g.activityIDs, cell(row, 5)) {
+			errorRows = append(errorRows, fmt.Sprintf("第%d行：与订单序号%s的其他行活动ID不一致", rowNum, key))
+			g.invalid = true
+			continue
+		}
+		if !merge(&g.expectedPaymentDate, cell(row, 6)) {
+			errorRows = append(errorRows, fmt.Sprintf("第%d行：与订单序号%s的其他行预计付款日期不一致", rowNum, key))
+			g.invalid = true
+			continue
+		}
+
+		goodsID, goodsName := cell(row, 3), cell(row, 4)
+		if goodsID == "" && goodsName == "" {
+			errorRows = append(errorRows, fmt.Sprintf("第%d行：商品ID和商品名称不能都为空", rowNum))
+			g.invalid = true
+			continue
+		}
+		g.goods = append(g.goods, orderImportGoods{rowNum: rowNum, goodsID: goodsID, goodsName: goodsName})
+	}
+
+	return groups, errorRows
+}
+
+// loadImportGoods 加载启用商品，按ID和名称索引（同名商品不能按名称匹配）
+func (s *Service) loadImportGoods(ctx context.Context) (map[int]GoodsItemRequest, map[string][]GoodsItemRequest, error) {
+	goodsList, err := s.goodsRepo.GetActiveGoodsForOrder(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询商品失败: %w", err)
+	}
+
+	byID := make(map[int]GoodsItemRequest, len(goodsList))
+	byName := make(map[string][]GoodsItemRequest, len(goodsList))
+	for _, g := range goodsList {
+		item := GoodsItemRequest{
+			GoodsID:    toInt(g["id"]),
+			TotalPrice: money.FromFloat(toFloat64(g["total_price"])),
+			Price:      money.FromFloat(toFloat64(g["price"])),
+		}
+		byID[item.GoodsID] = item
+		name, _ := g["name"].(string)
+		name = strings.TrimSpace(name)
+		byName[name] = append(byName[name], item)
+	}
+	return byID, byName, nil
+}
+
+// buildImportOrderRequest 解析一个导入订单，返回创建订单请求或行错误
+func (s *Service) buildImportOrderRequest(ctx context.Context, g *orderImportGroup, goodsByID map[int]GoodsItemRequest, goodsByName map[string][]GoodsItemRequest) (*CreateOrderRequest, []string) {
+	var errorRows []string
+	rowRange := g.rowRange()
+	if len(g.goods) == 0 {
+		return nil, []string{fmt.Sprintf("%s：订单序号%s没有有效的商品行", rowRange, g.key)}
+	}
+
+	// 1. 学生
+	studentID, err := s.resolveImportStudent(ctx, g.studentID, g.studentPhone)
+	if err != nil {
+		errorRows = append(errorRows, fmt.Sprintf("%s：%v", rowRange, err))
+	}
+
+	// 2. 商品（同一订单中商品不能重复）
+	goodsList := make([]GoodsItemRequest, 0, len(g.goods))
+	seen := make(map[int]bool)
+	for _, row := range g.goods {
+		var item GoodsItemRequest
+		var ok bool
+		if row.goodsID != "" {
+			id, err := strconv.Atoi(row.goodsID)
+			if err != nil {
+				errorRows = append(errorRows, fmt.Sprintf("第%d行：商品ID格式不正确", row.rowNum))
+				continue
+			}
+			item, ok = goodsByID[id]
+			if !ok {
+				errorRows = append(errorRows, fmt.Sprintf("第%d行：商品ID %d 不存在或未启用", row.rowNum, id))
+				continue
+			}
+		} else {
+			matches := goodsByName[row.goodsName]
+			if len(matches) == 0 {
+				errorRows = append(errorRows, fmt.Sprintf("第%d行：商品“%s”不存在或未启用", row.rowNum, row.goodsName))
+				continue
+			}
+			if len(matches) > 1 {
+				errorRows = append(errorRows, fmt.Sprintf("第%d行：存在多个名为“%s”的商品，请填写商品ID", row.rowNum, row.goodsName))
+				continue
+			}
+			item = matches[0]
+		}
+		if seen[item.GoodsID] {
+			errorRows = append(errorRows, fmt.Sprintf("第%d行：商品 %d 在同一订单中重复", row.rowNum, item.GoodsID))
+			continue
+		}
+		seen[item.GoodsID] = true
+		goodsList = append(goodsList, item)
+	}
+
+	// 3. 活动ID（英文或中文逗号分隔）
+	activityIDs := []int{}
+	for _, part := range strings.FieldsFunc(g.activityIDs, func(r rune) bool { return r == ',' || r == '，' }) {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id <= 0 {
+			errorRows = append(errorRows, fmt.Sprintf("%s：活动ID“%s”格式不正确", rowRange, part))
+			continue
+		}
+		activityIDs = append(activityIDs, id)
+	}
+	activityIDs = uniqueActivityIDs(activityIDs)
+
+	// 4. 预计付款日期（可选）
+	var expectedPaymentTime *time.Time
+	if g.expectedPaymentDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", g.expectedPaymentDate, time.Local)
+		if err != nil {
+			errorRows = append(errorRows, fmt.Sprintf("%s：预计付款日期格式不正确，必须为YYYY-MM-DD格式", rowRange))
+		} else {
+			expectedPaymentTime = &t
+		}
+	}
+
+	if len(errorRows) > 0 {
+		return nil, errorRows
+	}
+
+	// 5. 按服务端规则计算优惠，CreateOrder 会再次校验
+	discountResult, err := s.discountService.CalculateDiscountDetail(ctx, toGoodsForDiscount(goodsList), activityIDs)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("%s：计算活动优惠失败: %v", rowRange, err)}
+	}
+
+	return &CreateOrderRequest{
+		StudentID:           studentID,
+		GoodsList:           goodsList,
+		ExpectedPaymentTime: expectedPaymentTime,
+		ActivityIDs:         activityIDs,
+		DiscountAmount:      discountResult.TotalDiscount,
+		ChildDiscounts:      discountResult.ChildDiscounts,
+	}, nil
+}
+
+// resolveImportStudent 按学生ID或手机号查找启用的学生，同时填写时必须指向同一学生
+func (s *Service) resolveImportStudent(ctx context.Context, idStr string, phone string) (int, error) {
+	if idStr == "" && phone == "" {
+		return 0, fmt.Errorf("学生ID和学生手机号不能都为空")
+	}
+
+	query := s.db.WithContext(ctx).Table("student").Where("status = 0")
+	if idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return 0, fmt.Errorf("学生ID格式不正确")
+		}
+		query = query.Where("id = ?", id)
+	}
+	if phone != "" {
+		query = query.Where("phone = ?", phone)
+	}
+
+	var ids []int
+	if err := query.Limit(2).Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("查询学生失败: %w", err)
+	}
+	switch {
+	case len(ids) == 0:
+		return 0, fmt.Errorf("学生不存在或已禁用")
+	case len(ids) > 1:
+		return 0, fmt.Errorf("手机号%s对应多个学生，请填写学生ID", phone)
+	}
+	return ids[0], nil
+}
@@ -0,0 +1,397 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"charonoms/internal/domain/order/entity"
+	"charonoms/internal/domain/order/service"
+	orderPersistence "charonoms/internal/infrastructure/persistence/order"
+)
+
+func (r *fakeGoodsRepo) GetActiveGoodsForOrder(ctx context.Context) ([]map[string]interface{}, error) {
+	ids := make([]int, 0, len(r.names))
+	for id := range r.names {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	goods := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		p := r.prices[id]
+		goods = append(goods, map[string]interface{}{"id": id, "name": r.names[id], "price": p[0], "total_price": p[1]})
+	}
+	return goods, nil
+}
+
+// newImportService 商品1“数学课”、商品2“物理课”，商品3和4同名“英语课”
+func newImportService(db *gorm.DB) *Service {
+	return &Service{
+		orderRepo: orderPersistence.NewOrderRepository(db),
+		goodsRepo: &fakeGoodsRepo{
+			prices: map[int][2]float64{1: {100, 100}, 2: {300, 300}, 3: {200, 200}, 4: {200, 200}},
+			names:  map[int]string{1: "数学课", 2: "物理课", 3: "英语课", 4: "英语课"},
+		},
+		orderService:    service.NewOrderService(),
+		discountService: service.NewDiscountService(db),
+		db:              db,
+	}
+}
+
+func TestGroupOrderImportRows(t *testing.T) {
+	rows := [][]string{
+		orderImportHeaders,
+		{"1", "", "13800000000", "", "数学课", "1,2", "2026-11-01"},
+		{"1", "", "", "2"},
+		{"2", "35", "", "1", "", "", ""},
+		{},
+		{"", "35", "", "1"},
+		{"3", "35", "", "1"},
+		{"3", "36", "", "2"},
+		{"4", "35", "", "1", "", "1"},
+		{"4", "", "", "2", "", "2"},
+		{"5", "35", "", "1", "", "", "2026-11-01"},
+		{"5", "", "", "2", "", "", "2026-12-01"},
+		{"6", "35"},
+		{" 1 ", "", "13800000000", "3", "", "1,2"},
+	}
+
+	groups, errorRows := groupOrderImportRows(rows)
+
+	wantErrors := []string{
+		"第6行：订单序号不能为空",
+		"第8行：与订单序号3的其他行学生不一致",
+		"第10行：与订单序号4的其他行活动ID不一致",
+		"第12行：与订单序号5的其他行预计付款日期不一致",
+		"第13行：商品ID和商品名称不能都为空",
+	}
+	if !reflect.DeepEqual(errorRows, wantErrors) {
+		t.Errorf("errorRows = %v, want %v", errorRows, wantErrors)
+	}
+
+	type groupSummary struct {
+		key         string
+		rowNums     []int
+		studentID   string
+		phone       string
+		activityIDs string
+		date        string
+		goods       []orderImportGoods
+		invalid     bool
+	}
+	var got []groupSummary
+	for _, g := range groups {
+		got = append(got, groupSummary{g.key, g.rowNums, g.studentID, g.studentPhone, g.activityIDs, g.expectedPaymentDate, g.goods, g.invalid})
+	}
+	want := []groupSummary{
+		// 同一订单序号合并，学生、活动和日期只需填写一次；序号两端空格忽略
+		{"1", []int{2, 3, 14}, "", "13800000000", "1,2", "2026-11-01", []orderImportGoods{{2, "", "数学课"}, {3, "2", ""}, {14, "3", ""}}, false},
+		{"2", []int{4}, "35", "", "", "", []orderImportGoods{{4, "1", ""}}, false},
+		{"3", []int{7, 8}, "35", "", "", "", []orderImportGoods{{7, "1", ""}}, true},
+		{"4", []int{9, 10}, "35", "", "1", "", []orderImportGoods{{9, "1", ""}}, true},
+		{"5", []int{11, 12}, "35", "", "", "2026-11-01", []orderImportGoods{{11, "1", ""}}, true},
+		{"6", []int{13}, "35", "", "", "", nil, true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %+v, want %+v", got, want)
+	}
+}
+
+// expectStudent 期望按学生ID或手机号查询启用学生
+func expectStudent(mock sqlmock.Sqlmock, ids ...int) {
+	rows := sqlmock.NewRows([]string{"id"})
+	for _, id := range ids {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery("SELECT `id` FROM `student` WHERE status = 0").WillReturnRows(rows)
+}
+
+func TestBuildImportOrderRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		group      *orderImportGroup
+		students   []int // nil 表示不查询学生
+		wantGoods  []int
+		wantErrors []string
+	}{
+		{
+			name: "按商品ID和唯一名称匹配",
+			group: &orderImportGroup{key: "1", rowNums: []int{2, 3}, studentPhone: "13800000000", activityIDs: "",
+				goods: []orderImportGoods{{2, "2", ""}, {3, "", "数学课"}}},
+			students:  []int{35},
+			wantGoods: []int{2, 1},
+		},
+		{
+			name: "商品ID优先于名称",
+			group: &orderImportGroup{key: "1", rowNums: []int{2}, studentID: "35",
+				goods: []orderImportGoods{{2, "1", "物理课"}}},
+			students:  []int{35},
+			wantGoods: []int{1},
+		},
+		{
+			name: "商品无法匹配",
+			group: &orderImportGroup{key: "1", rowNums: []int{2, 3, 4, 5, 6}, studentID: "35",
+				goods: []orderImportGoods{{2, "x", ""}, {3, "9", ""}, {4, "", "化学课"}, {5, "", "英语课"}, {6, "", "数学课"}}},
+			students: []int{35},
+			wantErrors: []string{
+				"第2行：商品ID格式不正确",
+				"第3行：商品ID 9 不存在或未启用",
+				"第4行：商品“化学课”不存在或未启用",
+				"第5行：存在多个名为“英语课”的商品，请填写商品ID",
+			},
+		},
+		{
+			name: "同一订单商品重复",
+			group: &orderImportGroup{key: "1", rowNums: []int{2, 3}, studentID: "35",
+				goods: []orderImportGoods{{2, "1", ""}, {3, "", "数学课"}}},
+			students:   []int{35},
+			wantErrors: []string{"第3行：商品 1 在同一订单中重复"},
+		},
+		{
+			name: "手机号对应多个学生",
+			group: &orderImportGroup{key: "1", rowNums: []int{2}, studentPhone: "13800000000",
+				goods: []orderImportGoods{{2, "1", ""}}},
+			students:   []int{35, 36},
+			wantErrors: []string{"第2行：手机号13800000000对应多个学生，请填写学生ID"},
+		},
+		{
+			name: "学生不存在",
+			group: &orderImportGroup{key: "1", rowNums: []int{2}, studentID: "35",
+				goods: []orderImportGoods{{2, "1", ""}}},
+			students:   []int{},
+			wantErrors: []string{"第2行：学生不存在或已禁用"},
+		},
+		{
+			name: "学生ID、活动ID和日期格式不正确",
+			group: &orderImportGroup{key: "1", rowNums: []int{2}, studentID: "abc", activityIDs: "1，x", expectedPaymentDate: "2026/11/01",
+				goods: []orderImportGoods{{2, "1", ""}}},
+			wantErrors: []string{
+				"第2行：学生ID格式不正确",
+				"第2行：活动ID“x”格式不正确",
+				"第2行：预计付款日期格式不正确，必须为YYYY-MM-DD格式",
+			},
+		},
+		{
+			name:       "没有商品行",
+			group:      &orderImportGroup{key: "1", rowNums: []int{2}, studentID: "35"},
+			wantErrors: []string{"第2行：订单序号1没有有效的商品行"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			s := newImportService(db)
+			goodsByID, goodsByName, err := s.loadImportGoods(context.Background())
+			if err != nil {
+				t.Fatalf("loadImportGoods() error = %v", err)
+			}
+			if tt.students != nil {
+				expectStudent(mock, tt.students...)
+			}
+
+			req, rowErrors := s.buildImportOrderRequest(context.Background(), tt.group, goodsByID, goodsByName)
+
+			if !reflect.DeepEqual(rowErrors, tt.wantErrors) {
+				t.Errorf("rowErrors = %v, want %v", rowErrors, tt.wantErrors)
+			}
+			if tt.wantErrors == nil {
+				if req == nil {
+					t.Fatal("buildImportOrderRequest() request = nil")
+				}
+				var goodsIDs []int
+				for _, g := range req.GoodsList {
+					goodsIDs = append(goodsIDs, g.GoodsID)
+				}
+				if !reflect.DeepEqual(goodsIDs, tt.wantGoods) || req.StudentID != tt.students[0] {
+					t.Errorf("request goods = %v student = %d, want %v and %d", goodsIDs, req.StudentID, tt.wantGoods, tt.students[0])
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+// newImportFile 订单1：学生35购买商品1；订单2：学生36购买商品2
+func newImportFile(t *testing.T) *excelize.File {
+	f := excelize.NewFile()
+	sheet := f.GetSheetName(0)
+	for i, row := range [][]interface{}{
+		{"订单序号", "学生ID", "学生手机号", "商品ID", "商品名称", "活动ID", "预计付款日期"},
+		{"1", "35", "", "1"},
+		{"2", "36", "", "", "物理课"},
+	} {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			t.Fatalf("SetSheetRow() error = %v", err)
+		}
+	}
+	return f
+}
+
+// expectCreateOrder 期望保存一个订单及其子订单，insertErr 非空时子订单写入失败
+func expectCreateOrder(mock sqlmock.Sqlmock, orderID int64, studentID int, insertErr error) {
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO `orders`").
+		WithArgs(studentID, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), entity.OrderStatusDraft, "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(orderID, 1))
+	if insertErr != nil {
+		mock.ExpectExec("INSERT INTO `childorders`").WillReturnError(insertErr)
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+		return
+	}
+	mock.ExpectExec("INSERT INTO `childorders`").WillReturnResult(sqlmock.NewResult(orderID*10, 1))
+}
+
+func TestImportOrdersFromExcel(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		failSecond bool
+		wantIDs    []int
+		wantErrors []string
+		wantCommit bool
+	}{
+		{
+			name:       "全部成功模式：全部创建成功后提交",
+			mode:       ImportModeAllOrNothing,
+			wantIDs:    []int{101, 102},
+			wantCommit: true,
+		},
+		{
+			name:       "全部成功模式：任一订单失败则整体回滚",
+			mode:       ImportModeAllOrNothing,
+			failSecond: true,
+			wantIDs:    []int{},
+			wantErrors: []string{"第3行：创建订单失败: 创建子订单失败: deadlock found"},
+		},
+		{
+			name:       "逐单模式：失败的订单跳过",
+			mode:       ImportModeBestEffort,
+			failSecond: true,
+			wantIDs:    []int{101},
+			wantErrors: []string{"第3行：创建订单失败: 创建子订单失败: deadlock found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			s := newImportService(db)
+
+			expectStudent(mock, 35)
+			expectStudent(mock, 36)
+
+			var secondErr error
+			if tt.failSecond {
+				secondErr = errors.New("deadlock found")
+			}
+			if tt.mode == ImportModeAllOrNothing {
+				mock.ExpectBegin()
+				expectCreateOrder(mock, 101, 35, nil)
+				expectCreateOrder(mock, 102, 36, secondErr)
+				if tt.wantCommit {
+					mock.ExpectCommit()
+				} else {
+					mock.ExpectRollback()
+				}
+			} else {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO `orders`").WillReturnResult(sqlmock.NewResult(101, 1))
+				mock.ExpectExec("INSERT INTO `childorders`").WillReturnResult(sqlmock.NewResult(1010, 1))
+				mock.ExpectCommit()
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO `orders`").WillReturnResult(sqlmock.NewResult(102, 1))
+				mock.ExpectExec("INSERT INTO `childorders`").WillReturnError(secondErr)
+				mock.ExpectRollback()
+			}
+
+			result, err := s.ImportOrdersFromExcel(context.Background(), newImportFile(t), tt.mode)
+			if err != nil {
+				t.Fatalf("ImportOrdersFromExcel() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.OrderIDs, tt.wantIDs) || result.SuccessCount != len(tt.wantIDs) {
+				t.Errorf("OrderIDs = %v SuccessCount = %d, want %v", result.OrderIDs, result.SuccessCount, tt.wantIDs)
+			}
+			if !reflect.DeepEqual(result.Errors, tt.wantErrors) {
+				t.Errorf("Errors = %v, want %v", result.Errors, tt.wantErrors)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+// TestImportOrdersFromExcel_AllOrNothingRowErrors 全部成功模式下有行错误时不写入任何订单
+func TestImportOrdersFromExcel_AllOrNothingRowErrors(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newImportService(db)
+
+	expectStudent(mock, 35)
+	expectStudent(mock)
+
+	result, err := s.ImportOrdersFromExcel(context.Background(), newImportFile(t), ImportModeAllOrNothing)
+	if err != nil {
+		t.Fatalf("ImportOrdersFromExcel() error = %v", err)
+	}
+	if len(result.OrderIDs) != 0 || result.SuccessCount != 0 {
+		t.Errorf("OrderIDs = %v SuccessCount = %d, want none", result.OrderIDs, result.SuccessCount)
+	}
+	if want := []string{"第3行：学生不存在或已禁用"}; !reflect.DeepEqual(result.Errors, want) {
+		t.Errorf("Errors = %v, want %v", result.Errors, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestGenerateImportTemplate 模板只有一个工作表，表头与导入列一致，示例数据可以直接按订单序号分组
+func TestGenerateImportTemplate(t *testing.T) {
+	s := &Service{}
+	f, err := s.GenerateImportTemplate()
+	if err != nil {
+		t.Fatalf("GenerateImportTemplate() error = %v", err)
+	}
+
+	if sheets := f.GetSheetList(); !reflect.DeepEqual(sheets, []string{"订单导入模板"}) {
+		t.Fatalf("GetSheetList() = %v, want [订单导入模板]", sheets)
+	}
+	rows, err := f.GetRows(f.GetSheetName(0))
+	if err != nil {
+		t.Fatalf("GetRows() error = %v", err)
+	}
+	if !reflect.DeepEqual(rows[0], orderImportHeaders) {
+		t.Errorf("header = %v, want %v", rows[0], orderImportHeaders)
+	}
+
+	groups, errorRows := groupOrderImportRows(rows)
+	if len(errorRows) != 0 {
+		t.Errorf("groupOrderImportRows() errors = %v, want none", errorRows)
+	}
+	if len(groups) != 2 || len(groups[0].goods) != 2 || len(groups[1].goods) != 1 {
+		t.Errorf("groupOrderImportRows() groups = %d, want 2 orders with 2 and 1 goods", len(groups))
+	}
+}
+
+// TestImportOrdersFromExcel_InvalidMode 不支持的导入模式直接拒绝，不读取数据
+func TestImportOrdersFromExcel_InvalidMode(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newImportService(db)
+
+	if _, err := s.ImportOrdersFromExcel(context.Background(), newImportFile(t), "partial"); err == nil {
+		t.Fatal("ImportOrdersFromExcel() error = nil, want invalid mode")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
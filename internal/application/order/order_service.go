@@ -4,27 +4,34 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	"gorm.io/gorm"
 
+	approvalService "charonoms/internal/domain/approval/service"
 	"charonoms/internal/domain/financial/payment"
 	"charonoms/internal/domain/financial/taobao"
 	"charonoms/internal/domain/goods/repository"
 	"charonoms/internal/domain/order/entity"
 	orderRepo "charonoms/internal/domain/order/repository"
 	"charonoms/internal/domain/order/service"
+	"charonoms/internal/domain/shared/money"
 )
 
 // Service 订单应用服务
 type Service struct {
-	orderRepo       orderRepo.OrderRepository
-	childOrderRepo  orderRepo.ChildOrderRepository
-	goodsRepo       repository.GoodsRepository
-	paymentRepo     payment.PaymentRepository
-	taobaoRepo      taobao.TaobaoPaymentRepository
-	orderService    *service.OrderService
-	discountService *service.DiscountService
-	db              *gorm.DB
+	orderRepo        orderRepo.OrderRepository
+	childOrderRepo   orderRepo.ChildOrderRepository
+	goodsRepo        repository.GoodsRepository
+	paymentRepo      payment.PaymentRepository
+	taobaoRepo       taobao.TaobaoPaymentRepository
+	orderService     *service.OrderService
+	discountService  *service.DiscountService
+	statusService    *service.OrderStatusService
+	amendmentService *service.OrderAmendmentService
+	approvalService  *approvalService.ApprovalFlowService
+	db               *gorm.DB
 }
 
 // NewService 创建订单服务实例
@@ -34,22 +41,32 @@ func NewService(
 	goodsRepo repository.GoodsRepository,
 	paymentRepo payment.PaymentRepository,
 	taobaoRepo taobao.TaobaoPaymentRepository,
+	allocationSetting entity.AllocationSetting,
+	approvalFlowService *approvalService.ApprovalFlowService,
 	db *gorm.DB,
 ) *Service {
 	return &Service{
-		orderRepo:       orderRepo,
-		childOrderRepo:  childOrderRepo,
-		goodsRepo:       goodsRepo,
-		paymentRepo:     paymentRepo,
-		taobaoRepo:      taobaoRepo,
-		orderService:    service.NewOrderService(),
-		discountService: service.NewDiscountService(db),
-		db:              db,
+		orderRepo:        orderRepo,
+		childOrderRepo:   childOrderRepo,
+		goodsRepo:        goodsRepo,
+		paymentRepo:      paymentRepo,
+		taobaoRepo:       taobaoRepo,
+		orderService:     service.NewOrderService(),
+		discountService:  service.NewDiscountService(db),
+		statusService:    service.NewOrderStatusService(db),
+		amendmentService: service.NewOrderAmendmentService(db, allocationSetting),
+		approvalService:  approvalFlowService,
+		db:               db,
 	}
 }
 
 // CreateOrder 创建订单
 func (s *Service) CreateOrder(ctx context.Context, req *CreateOrderRequest) (int, error) {
+	return s.createOrder(ctx, s.orderRepo, req)
+}
+
+// createOrder 校验金额并创建草稿订单，repo 绑定调用方事务时订单在该事务中保存
+func (s *Service) createOrder(ctx context.Context, repo orderRepo.OrderRepository, req *CreateOrderRequest) (int, error) {
 	// 1. 验证请求
 	if req.StudentID == 0 {
 		return 0, errors.New("学生ID不能为空")
@@ -57,8 +74,19 @@ func (s *Service) CreateOrder(ctx context.Context, req *CreateOrderRequest) (int
 	if len(req.GoodsList) == 0 {
 		return 0, errors.New("必须至少选择一个商品")
 	}
+	if !entity.IsValidAllocationStrategy(req.AllocationStrategy) {
+		return 0, errors.New("无效的收款分账策略")
+	}
+	// 重复的活动ID只保留一个，优惠、赠品和订单活动关联都按去重后的列表生成
+	req.ActivityIDs = uniqueActivityIDs(req.ActivityIDs)
+
+	// 2. 以服务端价格和活动规则校验提交的金额
+	discountResult, err := s.verifyOrderPrices(ctx, req.GoodsList, req.ActivityIDs, req.DiscountAmount, req.ChildDiscounts)
+	if err != nil {
+		return 0, err
+	}
 
-	// 2. 构建商品列表用于金额计算
+	// 3. 构建商品列表用于金额计算
 	goodsItems := make([]service.GoodsItem, 0, len(req.GoodsList))
 	for _, g := range req.GoodsList {
 		goodsItems = append(goodsItems, service.GoodsItem{
@@ -68,28 +96,32 @@ func (s *Service) CreateOrder(ctx context.Context, req *CreateOrderRequest) (int
 		})
 	}
 
-	// 3. 计算订单金额
-	amountReceivable, amountReceived := s.orderService.CalculateOrderAmounts(goodsItems, req.DiscountAmount)
+	// 4. 计算订单金额
+	amountReceivable, amountReceived := s.orderService.CalculateOrderAmounts(goodsItems, discountResult.TotalDiscount)
 
-	// 4. 创建订单实体
+	// 5. 创建订单实体
 	order := &entity.Order{
 		StudentID:           req.StudentID,
 		ExpectedPaymentTime: req.ExpectedPaymentTime,
 		AmountReceivable:    amountReceivable,
 		AmountReceived:      amountReceived,
-		DiscountAmount:      req.DiscountAmount,
+		DiscountAmount:      discountResult.TotalDiscount,
 		Status:              entity.OrderStatusDraft,
+		AllocationStrategy:  req.AllocationStrategy,
 	}
 
-	// 5. 验证订单金额
+	// 6. 验证订单金额及分期计划
 	if !order.ValidateAmounts() {
 		return 0, errors.New("订单金额验证失败")
 	}
+	if err := applyInstalments(order, req.Instalments); err != nil {
+		return 0, err
+	}
 
-	// 6. 创建子订单
+	// 7. 创建子订单
 	childOrders := make([]*entity.ChildOrder, 0, len(req.GoodsList))
 	for _, g := range req.GoodsList {
-		childDiscount := req.ChildDiscounts[g.GoodsID]
+		childDiscount := discountResult.ChildDiscounts[g.GoodsID]
 		childAmountReceivable, childAmountReceived := s.orderService.CalculateChildAmounts(
 			g.TotalPrice,
 			g.Price,
@@ -111,8 +143,15 @@ func (s *Service) CreateOrder(ctx context.Context, req *CreateOrderRequest) (int
 		childOrders = append(childOrders, childOrder)
 	}
 
-	// 7. 保存订单
-	orderID, err := s.orderRepo.CreateOrder(ctx, order, childOrders, req.ActivityIDs)
+	// 8. 追加满赠活动赠品子订单
+	giftChildOrders, err := s.buildGiftChildOrders(ctx, req.GoodsList, req.ActivityIDs)
+	if err != nil {
+		return 0, err
+	}
+	childOrders = append(childOrders, giftChildOrders...)
+
+	// 9. 保存订单（含各活动的优惠明细）
+	orderID, err := repo.CreateOrder(ctx, order, childOrders, req.ActivityIDs, toOrderActivityDiscounts(discountResult))
 	if err != nil {
 		return 0, fmt.Errorf("创建订单失败: %w", err)
 	}
@@ -120,9 +159,59 @@ func (s *Service) CreateOrder(ctx context.Context, req *CreateOrderRequest) (int
 	return orderID, nil
 }
 
-// GetOrders 获取订单列表
-func (s *Service) GetOrders(ctx context.Context) ([]map[string]interface{}, error) {
-	return s.orderRepo.GetOrders(ctx)
+// GetOrders 分页查询订单列表
+func (s *Service) GetOrders(ctx context.Context, query *OrderListQuery) (*OrderListResponse, error) {
+	page, pageSize := normalizePage(query.Page, query.PageSize)
+
+	sortBy := query.SortBy
+	if sortBy == "" {
+		sortBy = orderRepo.OrderSortCreateTime
+	}
+	if !orderRepo.IsValidOrderSortField(sortBy) {
+		return nil, fmt.Errorf("%w: 不支持的排序字段 %s", ErrInvalidListQuery, sortBy)
+	}
+	sortDesc, err := parseSortOrder(query.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDateRange(query.CreateDateStart, query.CreateDateEnd); err != nil {
+		return nil, err
+	}
+	if err := validateDateRange(query.ExpectedPaymentDateStart, query.ExpectedPaymentDateEnd); err != nil {
+		return nil, err
+	}
+
+	filter := orderRepo.OrderListFilter{
+		ID:                       query.ID,
+		StudentID:                query.StudentID,
+		Status:                   query.Status,
+		CreateDateStart:          query.CreateDateStart,
+		CreateDateEnd:            query.CreateDateEnd,
+		ExpectedPaymentDateStart: query.ExpectedPaymentDateStart,
+		ExpectedPaymentDateEnd:   query.ExpectedPaymentDateEnd,
+		AmountMin:                query.AmountMin,
+		AmountMax:                query.AmountMax,
+		ActivityID:               query.ActivityID,
+		SortBy:                   sortBy,
+		SortDesc:                 sortDesc,
+		Page:                     page,
+		PageSize:                 pageSize,
+	}
+
+	orders, total, err := s.orderRepo.GetOrders(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if orders == nil {
+		orders = []map[string]interface{}{}
+	}
+
+	return &OrderListResponse{
+		Orders:   orders,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
 }
 
 // GetOrderGoods 获取订单商品列表
@@ -150,8 +239,19 @@ func (s *Service) UpdateOrder(ctx context.Context, orderID int, req *UpdateOrder
 	if len(req.GoodsList) == 0 {
 		return errors.New("必须至少选择一个商品")
 	}
+	if !entity.IsValidAllocationStrategy(req.AllocationStrategy) {
+		return errors.New("无效的收款分账策略")
+	}
+	// 重复的活动ID只保留一个，优惠、赠品和订单活动关联都按去重后的列表生成
+	req.ActivityIDs = uniqueActivityIDs(req.ActivityIDs)
 
-	// 4. 构建商品列表用于金额计算
+	// 4. 以服务端价格和活动规则校验提交的金额
+	discountResult, err := s.verifyOrderPrices(ctx, req.GoodsList, req.ActivityIDs, req.DiscountAmount, req.ChildDiscounts)
+	if err != nil {
+		return err
+	}
+
+	// 5. 构建商品列表用于金额计算
 	goodsItems := make([]service.GoodsItem, 0, len(req.GoodsList))
 	for _, g := range req.GoodsList {
 		goodsItems = append(goodsItems, service.GoodsItem{
@@ -161,24 +261,28 @@ func (s *Service) UpdateOrder(ctx context.Context, orderID int, req *UpdateOrder
 		})
 	}
 
-	// 5. 重新计算订单金额
-	amountReceivable, amountReceived := s.orderService.CalculateOrderAmounts(goodsItems, req.DiscountAmount)
+	// 6. 重新计算订单金额
+	amountReceivable, amountReceived := s.orderService.CalculateOrderAmounts(goodsItems, discountResult.TotalDiscount)
 
-	// 6. 更新订单实体
+	// 7. 更新订单实体
 	order.ExpectedPaymentTime = req.ExpectedPaymentTime
 	order.AmountReceivable = amountReceivable
 	order.AmountReceived = amountReceived
-	order.DiscountAmount = req.DiscountAmount
+	order.DiscountAmount = discountResult.TotalDiscount
+	order.AllocationStrategy = req.AllocationStrategy
 
-	// 7. 验证订单金额
+	// 8. 验证订单金额及分期计划
 	if !order.ValidateAmounts() {
 		return errors.New("订单金额验证失败")
 	}
+	if err := applyInstalments(order, req.Instalments); err != nil {
+		return err
+	}
 
-	// 8. 创建新的子订单列表
+	// 9. 创建新的子订单列表
 	childOrders := make([]*entity.ChildOrder, 0, len(req.GoodsList))
 	for _, g := range req.GoodsList {
-		childDiscount := req.ChildDiscounts[g.GoodsID]
+		childDiscount := discountResult.ChildDiscounts[g.GoodsID]
 		childAmountReceivable, childAmountReceived := s.orderService.CalculateChildAmounts(
 			g.TotalPrice,
 			g.Price,
@@ -200,8 +304,15 @@ func (s *Service) UpdateOrder(ctx context.Context, orderID int, req *UpdateOrder
 		childOrders = append(childOrders, childOrder)
 	}
 
-	// 9. 保存更新
-	err = s.orderRepo.UpdateOrder(ctx, order, childOrders, req.ActivityIDs)
+	// 10. 追加满赠活动赠品子订单
+	giftChildOrders, err := s.buildGiftChildOrders(ctx, req.GoodsList, req.ActivityIDs)
+	if err != nil {
+		return err
+	}
+	childOrders = append(childOrders, giftChildOrders...)
+
+	// 11. 保存更新（含各活动的优惠明细）
+	err = s.orderRepo.UpdateOrder(ctx, order, childOrders, req.ActivityIDs, toOrderActivityDiscounts(discountResult))
 	if err != nil {
 		return fmt.Errorf("更新订单失败: %w", err)
 	}
@@ -209,8 +320,33 @@ func (s *Service) UpdateOrder(ctx context.Context, orderID int, req *UpdateOrder
 	return nil
 }
 
+// buildGiftChildOrders 根据满赠活动生成零应收的赠品子订单
+func (s *Service) buildGiftChildOrders(ctx context.Context, goodsList []GoodsItemRequest, activityIDs []int) ([]*entity.ChildOrder, error) {
+	gifts, err := s.discountService.CalculateGifts(ctx, toGoodsForDiscount(goodsList), activityIDs)
+	if err != nil {
+		return nil, fmt.Errorf("计算满赠赠品失败: %w", err)
+	}
+
+	childOrders := make([]*entity.ChildOrder, 0, len(gifts))
+	for _, gift := range gifts {
+		activityID := gift.ActivityID
+		for i := 0; i < gift.Quantity; i++ {
+			childOrders = append(childOrders, &entity.ChildOrder{
+				GoodsID:          gift.GoodsID,
+				AmountReceivable: money.Zero,
+				AmountReceived:   money.Zero,
+				DiscountAmount:   money.Zero,
+				Status:           entity.ChildOrderStatusInit,
+				GiftActivityID:   &activityID,
+			})
+		}
+	}
+
+	return childOrders, nil
+}
+
 // SubmitOrder 提交订单
-func (s *Service) SubmitOrder(ctx context.Context, orderID int) error {
+func (s *Service) SubmitOrder(ctx context.Context, orderID int, operator string) error {
 	// 1. 查询订单
 	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
 	if err != nil {
@@ -226,7 +362,14 @@ func (s *Service) SubmitOrder(ctx context.Context, orderID int) error {
 	}
 
 	// 3. 更新订单和子订单状态
-	err = s.orderRepo.UpdateOrderStatus(ctx, orderID, entity.OrderStatusUnpaid, entity.ChildOrderStatusUnpaid)
+	childStatus := entity.ChildOrderStatusUnpaid
+	err = s.statusService.Transition(ctx, service.StatusChange{
+		OrderID:          orderID,
+		To:               entity.OrderStatusUnpaid,
+		ChildOrderStatus: &childStatus,
+		Operator:         operator,
+		Reason:           "提交订单",
+	})
 	if err != nil {
 		return fmt.Errorf("提交订单失败: %w", err)
 	}
@@ -235,7 +378,7 @@ func (s *Service) SubmitOrder(ctx context.Context, orderID int) error {
 }
 
 // CancelOrder 作废订单
-func (s *Service) CancelOrder(ctx context.Context, orderID int) error {
+func (s *Service) CancelOrder(ctx context.Context, orderID int, operator string) error {
 	// 1. 查询订单
 	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
 	if err != nil {
@@ -251,7 +394,14 @@ func (s *Service) CancelOrder(ctx context.Context, orderID int) error {
 	}
 
 	// 3. 更新订单和子订单状态
-	err = s.orderRepo.UpdateOrderStatus(ctx, orderID, entity.OrderStatusCancelled, entity.ChildOrderStatusCancelled)
+	childStatus := entity.ChildOrderStatusCancelled
+	err = s.statusService.Transition(ctx, service.StatusChange{
+		OrderID:          orderID,
+		To:               entity.OrderStatusCancelled,
+		ChildOrderStatus: &childStatus,
+		Operator:         operator,
+		Reason:           "作废订单",
+	})
 	if err != nil {
 		return fmt.Errorf("作废订单失败: %w", err)
 	}
@@ -259,9 +409,108 @@ func (s *Service) CancelOrder(ctx context.Context, orderID int) error {
 	return nil
 }
 
-// GetChildOrders 获取子订单列表
-func (s *Service) GetChildOrders(ctx context.Context) ([]map[string]interface{}, error) {
-	return s.childOrderRepo.GetChildOrders(ctx)
+// GetChildOrders 分页查询子订单列表
+func (s *Service) GetChildOrders(ctx context.Context, query *ChildOrderListQuery) (*ChildOrderListResponse, error) {
+	page, pageSize := normalizePage(query.Page, query.PageSize)
+
+	sortBy := query.SortBy
+	if sortBy == "" {
+		sortBy = orderRepo.ChildOrderSortID
+	}
+	if !orderRepo.IsValidChildOrderSortField(sortBy) {
+		return nil, fmt.Errorf("%w: 不支持的排序字段 %s", ErrInvalidListQuery, sortBy)
+	}
+	sortDesc, err := parseSortOrder(query.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDateRange(query.CreateDateStart, query.CreateDateEnd); err != nil {
+		return nil, err
+	}
+
+	filter := orderRepo.ChildOrderListFilter{
+		ID:              query.ID,
+		ParentsID:       query.ParentsID,
+		GoodsID:         query.GoodsID,
+		Status:          query.Status,
+		CreateDateStart: query.CreateDateStart,
+		CreateDateEnd:   query.CreateDateEnd,
+		AmountMin:       query.AmountMin,
+		AmountMax:       query.AmountMax,
+		SortBy:          sortBy,
+		SortDesc:        sortDesc,
+		Page:            page,
+		PageSize:        pageSize,
+	}
+
+	childOrders, total, err := s.childOrderRepo.GetChildOrders(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if childOrders == nil {
+		childOrders = []map[string]interface{}{}
+	}
+
+	return &ChildOrderListResponse{
+		ChildOrders: childOrders,
+		Total:       total,
+		Page:        page,
+		PageSize:    pageSize,
+	}, nil
+}
+
+// ErrInvalidListQuery 列表查询参数错误
+var ErrInvalidListQuery = errors.New("列表查询参数错误")
+
+// 列表分页参数
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// normalizePage 规范分页参数：页码从1开始，每页条数默认20、最多100
+func normalizePage(page, pageSize int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return page, pageSize
+}
+
+// parseSortOrder 解析排序方向，默认降序
+func parseSortOrder(sortOrder string) (bool, error) {
+	switch sortOrder {
+	case "", "desc":
+		return true, nil
+	case "asc":
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: 不支持的排序方向 %s", ErrInvalidListQuery, sortOrder)
+	}
+}
+
+// validateDateRange 校验日期筛选参数格式（YYYY-MM-DD），起止都填写时开始日期不能晚于结束日期
+func validateDateRange(start, end *string) error {
+	var parsed [2]*time.Time
+	for i, d := range []*string{start, end} {
+		if d == nil || *d == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", *d)
+		if err != nil {
+			return fmt.Errorf("%w: 日期格式错误 %s，应为 YYYY-MM-DD", ErrInvalidListQuery, *d)
+		}
+		parsed[i] = &t
+	}
+	if parsed[0] != nil && parsed[1] != nil && parsed[0].After(*parsed[1]) {
+		return fmt.Errorf("%w: 开始日期 %s 晚于结束日期 %s", ErrInvalidListQuery, *start, *end)
+	}
+	return nil
 }
 
 // GetActiveGoodsForOrder 获取启用商品列表（用于订单）
@@ -274,9 +523,98 @@ func (s *Service) GetGoodsTotalPrice(ctx context.Context, goodsID int) (map[stri
 	return s.goodsRepo.GetGoodsTotalPrice(ctx, goodsID)
 }
 
-// CalculateOrderDiscount 计算订单优惠
-func (s *Service) CalculateOrderDiscount(ctx context.Context, goodsList []GoodsItemRequest, activityIDs []int) (float64, map[int]float64, error) {
+// CalculateOrderDiscount 计算订单优惠（按活动叠加规则）及满足条件的满赠赠品
+func (s *Service) CalculateOrderDiscount(ctx context.Context, goodsList []GoodsItemRequest, activityIDs []int) (*DiscountCalculation, error) {
 	// 转换为领域服务需要的格式
+	goodsForDiscount := toGoodsForDiscount(goodsList)
+
+	// 调用领域服务计算优惠
+	result, err := s.discountService.CalculateDiscountDetail(ctx, goodsForDiscount, activityIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := make([]ActivityDiscountItem, 0, len(result.Breakdown))
+	for _, d := range result.Breakdown {
+		breakdown = append(breakdown, ActivityDiscountItem{
+			ActivityID:     d.ActivityID,
+			GoodsID:        d.GoodsID,
+			DiscountAmount: d.Amount,
+		})
+	}
+
+	// 计算满赠赠品
+	gifts, err := s.discountService.CalculateGifts(ctx, goodsForDiscount, activityIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	giftItems := make([]GiftItem, 0, len(gifts))
+	for _, gift := range gifts {
+		var goodsName string
+		if goods, err := s.goodsRepo.GetByID(gift.GoodsID); err == nil {
+			goodsName, _ = goods["name"].(string)
+		}
+		giftItems = append(giftItems, GiftItem{
+			ActivityID: gift.ActivityID,
+			GoodsID:    gift.GoodsID,
+			GoodsName:  goodsName,
+			Quantity:   gift.Quantity,
+		})
+	}
+
+	return &DiscountCalculation{
+		TotalDiscount:      result.TotalDiscount,
+		ChildDiscounts:     result.ChildDiscounts,
+		Breakdown:          breakdown,
+		AppliedActivityIDs: result.AppliedActivityIDs,
+		Gifts:              giftItems,
+	}, nil
+}
+
+// GetOrderStatusHistory 获取订单状态变更历史
+func (s *Service) GetOrderStatusHistory(ctx context.Context, orderID int) ([]*entity.OrderStatusHistory, error) {
+	if _, err := s.orderRepo.GetOrderByID(ctx, orderID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("订单不存在")
+		}
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	return s.orderRepo.GetOrderStatusHistory(ctx, orderID)
+}
+
+// GetOrderActivityDiscounts 获取订单的活动优惠明细
+func (s *Service) GetOrderActivityDiscounts(ctx context.Context, orderID int) ([]*entity.OrderActivityDiscount, error) {
+	return s.orderRepo.GetOrderActivityDiscounts(ctx, orderID)
+}
+
+// ErrInvalidInstalments 分期计划不合法
+var ErrInvalidInstalments = errors.New("分期计划错误")
+
+// applyInstalments 校验分期计划并设置到订单上
+// 设置分期时订单的预计付款时间取第一期到期日，保持列表筛选和排序可用
+func applyInstalments(order *entity.Order, reqs []InstalmentRequest) error {
+	instalments := make([]*entity.OrderInstalment, 0, len(reqs))
+	for _, r := range reqs {
+		instalments = append(instalments, &entity.OrderInstalment{
+			DueDate: r.DueDate,
+			Amount:  r.Amount,
+		})
+	}
+	if err := entity.NormalizeInstalments(instalments, order.AmountReceived); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInstalments, err)
+	}
+
+	order.Instalments = instalments
+	if len(instalments) > 0 {
+		firstDue := instalments[0].DueDate
+		order.ExpectedPaymentTime = &firstDue
+	}
+	return nil
+}
+
+// toGoodsForDiscount 转换为优惠计算领域服务需要的格式
+func toGoodsForDiscount(goodsList []GoodsItemRequest) []service.GoodsForDiscount {
 	goodsForDiscount := make([]service.GoodsForDiscount, 0, len(goodsList))
 	for _, g := range goodsList {
 		goodsForDiscount = append(goodsForDiscount, service.GoodsForDiscount{
@@ -284,9 +622,24 @@ func (s *Service) CalculateOrderDiscount(ctx context.Context, goodsList []GoodsI
 			Price:   g.Price,
 		})
 	}
+	return goodsForDiscount
+}
 
-	// 调用领域服务计算优惠
-	return s.discountService.CalculateDiscount(ctx, goodsForDiscount, activityIDs)
+// uniqueActivityIDs 按首次出现的顺序去除重复的活动ID，nil 原样返回
+func uniqueActivityIDs(activityIDs []int) []int {
+	if activityIDs == nil {
+		return nil
+	}
+	unique := make([]int, 0, len(activityIDs))
+	seen := make(map[int]bool, len(activityIDs))
+	for _, id := range activityIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
 }
 
 // GetUnpaidOrdersByStudentID 获取学生的未付款订单列表
@@ -295,36 +648,89 @@ func (s *Service) GetUnpaidOrdersByStudentID(ctx context.Context, studentID int)
 }
 
 // GetOrderPendingAmount 获取订单待付金额
-func (s *Service) GetOrderPendingAmount(ctx context.Context, orderID int) (float64, error) {
+func (s *Service) GetOrderPendingAmount(ctx context.Context, orderID int) (money.Money, error) {
 	// 获取订单信息
 	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
 	if err != nil {
-		return 0, err
+		return money.Zero, err
 	}
 
-	// 获取常规收款已付金额
-	regularPaidAmount, err := s.paymentRepo.GetTotalPaidAmount(orderID)
+	totalPaidAmount, err := s.getOrderPaidAmount(orderID)
 	if err != nil {
-		return 0, err
+		return money.Zero, err
 	}
 
-	// 获取淘宝收款已付金额
-	taobaoPaidAmount, err := s.taobaoRepo.GetTotalPaid(orderID)
+	// 计算待付金额 = 实收金额 - 已付金额
+	// 注意：使用 AmountReceived（实收金额），因为这是用户实际需要支付的金额（已扣除优惠）
+	pendingAmount := money.Max(order.AmountReceived.Sub(totalPaidAmount), money.Zero)
+
+	return pendingAmount, nil
+}
+
+// getOrderPaidAmount 获取订单已付金额（常规收款 + 淘宝收款）
+func (s *Service) getOrderPaidAmount(orderID int) (money.Money, error) {
+	return s.paymentRepo.GetTotalPaidAmount(orderID)
+}
+
+// GetOrderInstalments 获取订单分期计划及各期收款进度
+func (s *Service) GetOrderInstalments(ctx context.Context, orderID int) ([]*entity.InstalmentProgress, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
 	if err != nil {
-		return 0, err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("订单不存在")
+		}
+		return nil, fmt.Errorf("查询订单失败: %w", err)
 	}
 
-	// 总已付金额 = 常规收款 + 淘宝收款
-	totalPaidAmount := regularPaidAmount + taobaoPaidAmount
+	totalPaid, err := s.getOrderPaidAmount(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("查询订单已付金额失败: %w", err)
+	}
 
-	// 计算待付金额 = 实收金额 - 已付金额
-	// 注意：使用 AmountReceived（实收金额），因为这是用户实际需要支付的金额（已扣除优惠）
-	pendingAmount := order.AmountReceived - totalPaidAmount
-	if pendingAmount < 0 {
-		pendingAmount = 0
+	return entity.AllocateInstalmentPayments(order.InstalmentPlan(), totalPaid), nil
+}
+
+// GetOverdueInstalmentsByStudentID 获取学生未付清订单中已逾期的分期（按到期日先后）
+func (s *Service) GetOverdueInstalmentsByStudentID(ctx context.Context, studentID int) ([]*OverdueInstalment, error) {
+	orders, err := s.orderRepo.GetUnpaidOrdersByStudentID(ctx, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("查询未付款订单失败: %w", err)
 	}
 
-	return pendingAmount, nil
+	now := time.Now()
+	overdue := make([]*OverdueInstalment, 0)
+	for _, order := range orders {
+		plan := order.InstalmentPlan()
+		if len(plan) == 0 {
+			continue
+		}
+
+		totalPaid, err := s.getOrderPaidAmount(order.ID)
+		if err != nil {
+			return nil, fmt.Errorf("查询订单 %d 已付金额失败: %w", order.ID, err)
+		}
+
+		for _, p := range entity.AllocateInstalmentPayments(plan, totalPaid) {
+			if !p.IsOverdue(now) {
+				continue
+			}
+			overdue = append(overdue, &OverdueInstalment{
+				OrderID:           order.ID,
+				InstalmentID:      p.ID,
+				Seq:               p.Seq,
+				DueDate:           p.DueDate,
+				Amount:            p.Amount,
+				PaidAmount:        p.PaidAmount,
+				OutstandingAmount: p.OutstandingAmount,
+				OverdueDays:       p.OverdueDays(now),
+			})
+		}
+	}
+
+	sort.SliceStable(overdue, func(i, j int) bool {
+		return overdue[i].DueDate.Before(overdue[j].DueDate)
+	})
+	return overdue, nil
 }
 
 // GetOrderRefundInfo 获取订单退费信息
@@ -349,6 +755,11 @@ func (s *Service) GetOrderRefundInfo(ctx context.Context, orderID int) (map[stri
 	// 4. 计算每个子订单的可退金额
 	childOrderData := make([]map[string]interface{}, 0, len(childOrders))
 	for _, child := range childOrders {
+		// 赠品子订单、作废的子订单不可退费
+		if child.IsGift() || child.IsCancelled() {
+			continue
+		}
+
 		// 获取商品信息
 		goods, err := s.goodsRepo.GetByID(child.GoodsID)
 		if err != nil {
@@ -357,7 +768,7 @@ func (s *Service) GetOrderRefundInfo(ctx context.Context, orderID int) (map[stri
 		goodsName, _ := goods["name"].(string)
 
 		// 计算已退金额：查询 refund_order_item 表中所有已通过（status=10）的退费
-		var refundedAmount float64
+		var refundedAmount money.Money
 		err = s.db.Raw(`
 			SELECT COALESCE(SUM(refund_amount), 0)
 			FROM refund_order_item
@@ -368,10 +779,7 @@ func (s *Service) GetOrderRefundInfo(ctx context.Context, orderID int) (map[stri
 		}
 
 		// 可退金额 = 实收金额 - 已退金额
-		availableRefund := child.AmountReceived - refundedAmount
-		if availableRefund < 0 {
-			availableRefund = 0
-		}
+		availableRefund := money.Max(child.AmountReceived.Sub(refundedAmount), money.Zero)
 
 		childOrderData = append(childOrderData, map[string]interface{}{
 			"childorder_id":    child.ID,
@@ -458,11 +866,11 @@ func (s *Service) GetRefundPayments(ctx context.Context, orderID int, refundItem
 	type PaymentWithRefund struct {
 		PaymentID           int
 		PaymentType         int
-		PaymentAmount       float64
+		PaymentAmount       money.Money
 		PayeeEntity         *int
 		Payer               *string
 		IsCorporateTransfer int
-		RefundedAmount      float64
+		RefundedAmount      money.Money
 	}
 
 	regularPayments := []PaymentWithRefund{}
@@ -514,13 +922,10 @@ func (s *Service) GetRefundPayments(ctx context.Context, orderID int, refundItem
 	// 处理常规收款
 	for _, p := range regularPayments {
 		// 计算可退金额 = 收款金额 - 历史累计已退金额
-		availableRefund := p.PaymentAmount - p.RefundedAmount
-		if availableRefund < 0 {
-			availableRefund = 0
-		}
+		availableRefund := money.Max(p.PaymentAmount.Sub(p.RefundedAmount), money.Zero)
 
 		// 计算该收款与待退费子订单关联的分账金额
-		var separateAmount float64
+		var separateAmount money.Money
 		if len(childOrderIDs) > 0 {
 			err := s.db.Raw(`
 				SELECT COALESCE(SUM(separate_amount), 0)
@@ -528,7 +933,7 @@ func (s *Service) GetRefundPayments(ctx context.Context, orderID int, refundItem
 				WHERE payment_id = ? AND payment_type = 0 AND childorders_id IN ? AND type = 0
 			`, p.PaymentID, childOrderIDs).Scan(&separateAmount).Error
 			if err != nil {
-				separateAmount = 0
+				separateAmount = money.Zero
 			}
 		}
 
@@ -555,13 +960,10 @@ func (s *Service) GetRefundPayments(ctx context.Context, orderID int, refundItem
 	// 处理淘宝收款
 	for _, p := range taobaoPayments {
 		// 计算可退金额 = 收款金额 - 历史累计已退金额
-		availableRefund := p.PaymentAmount - p.RefundedAmount
-		if availableRefund < 0 {
-			availableRefund = 0
-		}
+		availableRefund := money.Max(p.PaymentAmount.Sub(p.RefundedAmount), money.Zero)
 
 		// 计算该收款与待退费子订单关联的分账金额
-		var separateAmount float64
+		var separateAmount money.Money
 		if len(childOrderIDs) > 0 {
 			err := s.db.Raw(`
 				SELECT COALESCE(SUM(separate_amount), 0)
@@ -569,7 +971,7 @@ func (s *Service) GetRefundPayments(ctx context.Context, orderID int, refundItem
 				WHERE payment_id = ? AND payment_type = 1 AND childorders_id IN ? AND type = 0
 			`, p.PaymentID, childOrderIDs).Scan(&separateAmount).Error
 			if err != nil {
-				separateAmount = 0
+				separateAmount = money.Zero
 			}
 		}
 
@@ -590,9 +992,9 @@ func (s *Service) GetRefundPayments(ctx context.Context, orderID int, refundItem
 	}
 
 	// 5. 计算每个子订单的总分账金额
-	childorderSeparateAmounts := make(map[int]float64)
+	childorderSeparateAmounts := make(map[int]money.Money)
 	for _, childOrderID := range childOrderIDs {
-		var totalSeparate float64
+		var totalSeparate money.Money
 		err := s.db.Raw(`
 			SELECT COALESCE(SUM(separate_amount), 0)
 			FROM separate_account
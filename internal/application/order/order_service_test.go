@@ -0,0 +1,177 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	orderRepo "charonoms/internal/domain/order/repository"
+)
+
+// fakeListOrderRepo 记录列表查询条件的订单仓储
+type fakeListOrderRepo struct {
+	orderRepo.OrderRepository
+	filter *orderRepo.OrderListFilter
+}
+
+func (r *fakeListOrderRepo) GetOrders(ctx context.Context, filter orderRepo.OrderListFilter) ([]map[string]interface{}, int64, error) {
+	r.filter = &filter
+	return nil, 0, nil
+}
+
+// fakeListChildOrderRepo 记录列表查询条件的子订单仓储
+type fakeListChildOrderRepo struct {
+	orderRepo.ChildOrderRepository
+	filter *orderRepo.ChildOrderListFilter
+}
+
+func (r *fakeListChildOrderRepo) GetChildOrders(ctx context.Context, filter orderRepo.ChildOrderListFilter) ([]map[string]interface{}, int64, error) {
+	r.filter = &filter
+	return nil, 0, nil
+}
+
+func str(s string) *string {
+	return &s
+}
+
+func TestGetOrders(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        OrderListQuery
+		wantErr      bool
+		wantSortBy   string
+		wantSortDesc bool
+		wantPage     int
+		wantPageSize int
+	}{
+		{
+			name:         "默认按创建时间降序、第1页每页20条",
+			query:        OrderListQuery{},
+			wantSortBy:   orderRepo.OrderSortCreateTime,
+			wantSortDesc: true,
+			wantPage:     1,
+			wantPageSize: 20,
+		},
+		{
+			name:         "指定排序和分页",
+			query:        OrderListQuery{SortBy: orderRepo.OrderSortAmountReceived, SortOrder: "asc", Page: 3, PageSize: 50},
+			wantSortBy:   orderRepo.OrderSortAmountReceived,
+			wantPage:     3,
+			wantPageSize: 50,
+		},
+		{
+			name:         "页码和每页条数不合法时规范化",
+			query:        OrderListQuery{Page: -2, PageSize: 500},
+			wantSortBy:   orderRepo.OrderSortCreateTime,
+			wantSortDesc: true,
+			wantPage:     1,
+			wantPageSize: 100,
+		},
+		{
+			name:         "起止日期相同",
+			query:        OrderListQuery{CreateDateStart: str("2026-10-01"), CreateDateEnd: str("2026-10-01")},
+			wantSortBy:   orderRepo.OrderSortCreateTime,
+			wantSortDesc: true,
+			wantPage:     1,
+			wantPageSize: 20,
+		},
+		{name: "不支持的排序字段", query: OrderListQuery{SortBy: "student_id; DROP TABLE orders"}, wantErr: true},
+		{name: "不支持的排序方向", query: OrderListQuery{SortOrder: "up"}, wantErr: true},
+		{name: "创建日期格式错误", query: OrderListQuery{CreateDateStart: str("2026/10/01")}, wantErr: true},
+		{name: "创建日期起止颠倒", query: OrderListQuery{CreateDateStart: str("2026-10-02"), CreateDateEnd: str("2026-10-01")}, wantErr: true},
+		{name: "预计付款日期格式错误", query: OrderListQuery{ExpectedPaymentDateEnd: str("2026-13-01")}, wantErr: true},
+		{name: "预计付款日期起止颠倒", query: OrderListQuery{ExpectedPaymentDateStart: str("2026-11-01"), ExpectedPaymentDateEnd: str("2026-10-31")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeListOrderRepo{}
+			s := &Service{orderRepo: repo}
+
+			resp, err := s.GetOrders(context.Background(), &tt.query)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidListQuery) {
+					t.Errorf("GetOrders() error = %v, want ErrInvalidListQuery", err)
+				}
+				if repo.filter != nil {
+					t.Error("GetOrders() queried repository for invalid query")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetOrders() error = %v", err)
+			}
+			f := repo.filter
+			if f.SortBy != tt.wantSortBy || f.SortDesc != tt.wantSortDesc || f.Page != tt.wantPage || f.PageSize != tt.wantPageSize {
+				t.Errorf("filter sort = %s desc = %v page = %d size = %d, want %s %v %d %d",
+					f.SortBy, f.SortDesc, f.Page, f.PageSize, tt.wantSortBy, tt.wantSortDesc, tt.wantPage, tt.wantPageSize)
+			}
+			if resp.Page != tt.wantPage || resp.PageSize != tt.wantPageSize || resp.Orders == nil {
+				t.Errorf("response page = %d size = %d orders = %v, want %d %d and empty list", resp.Page, resp.PageSize, resp.Orders, tt.wantPage, tt.wantPageSize)
+			}
+		})
+	}
+}
+
+func TestGetChildOrders(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        ChildOrderListQuery
+		wantErr      bool
+		wantSortBy   string
+		wantSortDesc bool
+		wantPage     int
+		wantPageSize int
+	}{
+		{
+			name:         "默认按子订单ID降序",
+			query:        ChildOrderListQuery{},
+			wantSortBy:   orderRepo.ChildOrderSortID,
+			wantSortDesc: true,
+			wantPage:     1,
+			wantPageSize: 20,
+		},
+		{
+			name:         "页码和每页条数不合法时规范化",
+			query:        ChildOrderListQuery{SortBy: orderRepo.ChildOrderSortStatus, SortOrder: "asc", Page: 0, PageSize: -1},
+			wantSortBy:   orderRepo.ChildOrderSortStatus,
+			wantPage:     1,
+			wantPageSize: 20,
+		},
+		{name: "不支持的排序字段", query: ChildOrderListQuery{SortBy: "expected_payment_time"}, wantErr: true},
+		{name: "不支持的排序方向", query: ChildOrderListQuery{SortOrder: "DESC"}, wantErr: true},
+		{name: "日期格式错误", query: ChildOrderListQuery{CreateDateEnd: str("20261001")}, wantErr: true},
+		{name: "日期起止颠倒", query: ChildOrderListQuery{CreateDateStart: str("2026-10-02"), CreateDateEnd: str("2026-10-01")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeListChildOrderRepo{}
+			s := &Service{childOrderRepo: repo}
+
+			resp, err := s.GetChildOrders(context.Background(), &tt.query)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidListQuery) {
+					t.Errorf("GetChildOrders() error = %v, want ErrInvalidListQuery", err)
+				}
+				if repo.filter != nil {
+					t.Error("GetChildOrders() queried repository for invalid query")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetChildOrders() error = %v", err)
+			}
+			f := repo.filter
+			if f.SortBy != tt.wantSortBy || f.SortDesc != tt.wantSortDesc || f.Page != tt.wantPage || f.PageSize != tt.wantPageSize {
+				t.Errorf("filter sort = %s desc = %v page = %d size = %d, want %s %v %d %d",
+					f.SortBy, f.SortDesc, f.Page, f.PageSize, tt.wantSortBy, tt.wantSortDesc, tt.wantPage, tt.wantPageSize)
+			}
+			if resp.Page != tt.wantPage || resp.PageSize != tt.wantPageSize || resp.ChildOrders == nil {
+				t.Errorf("response page = %d size = %d childorders = %v, want %d %d and empty list", resp.Page, resp.PageSize, resp.ChildOrders, tt.wantPage, tt.wantPageSize)
+			}
+		})
+	}
+}
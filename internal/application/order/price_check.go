@@ -0,0 +1,141 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"charonoms/internal/domain/order/entity"
+	"charonoms/internal/domain/order/service"
+	"charonoms/internal/domain/shared/money"
+)
+
+// 价格校验字段
+const (
+	PriceFieldPrice          = "price"           // 商品标准售价
+	PriceFieldTotalPrice     = "total_price"     // 商品总价
+	PriceFieldChildDiscount  = "child_discount"  // 子订单优惠
+	PriceFieldDiscountAmount = "discount_amount" // 订单优惠合计
+	PriceFieldActivity       = "activity"        // 活动未启用、不在活动时间内或不存在
+)
+
+// PriceMismatch 提交金额与服务端计算金额不一致的明细
+type PriceMismatch struct {
+	GoodsID    int         `json:"goods_id"`              // 订单级字段为0
+	ActivityID int         `json:"activity_id,omitempty"` // 不可用的活动，仅 Field 为 activity 时有值
+	Field      string      `json:"field"`
+	Submitted  money.Money `json:"submitted"`
+	Expected   money.Money `json:"expected"`
+}
+
+// PriceMismatchError 提交的订单金额与服务端计算不一致
+type PriceMismatchError struct {
+	Mismatches []PriceMismatch
+}
+
+// Error 实现error接口
+func (e *PriceMismatchError) Error() string {
+	return fmt.Sprintf("提交的订单金额与服务端计算不一致（%d 项）", len(e.Mismatches))
+}
+
+// verifyOrderPrices 以服务端商品价格和活动规则为准校验客户端提交的金额
+// 未启用、不在活动时间内或不存在的活动不参与计算，并作为不一致项返回
+// 校验通过时返回服务端计算的优惠结果，不一致时返回 *PriceMismatchError
+func (s *Service) verifyOrderPrices(ctx context.Context, goodsList []GoodsItemRequest, activityIDs []int, discountAmount money.Money, childDiscounts map[int]money.Money) (*service.DiscountResult, error) {
+	mismatches := make([]PriceMismatch, 0)
+
+	// 0. 校验活动当前可用
+	inactiveIDs, err := s.discountService.InactiveActivityIDs(ctx, activityIDs, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	activeIDs := activityIDs
+	if len(inactiveIDs) > 0 {
+		inactive := make(map[int]bool, len(inactiveIDs))
+		for _, id := range inactiveIDs {
+			inactive[id] = true
+			mismatches = append(mismatches, PriceMismatch{ActivityID: id, Field: PriceFieldActivity})
+		}
+		activeIDs = make([]int, 0, len(activityIDs))
+		for _, id := range activityIDs {
+			if !inactive[id] {
+				activeIDs = append(activeIDs, id)
+			}
+		}
+	}
+
+	// 1. 按商品库价格校验标准售价和总价
+	serverGoods := make([]GoodsItemRequest, 0, len(goodsList))
+	for _, g := range goodsList {
+		priceInfo, err := s.goodsRepo.GetGoodsTotalPrice(ctx, g.GoodsID)
+		if err != nil {
+			return nil, fmt.Errorf("商品 %d 不存在或查询价格失败: %w", g.GoodsID, err)
+		}
+
+		price := money.FromFloat(toFloat64(priceInfo["price"]))
+		totalPrice := money.FromFloat(toFloat64(priceInfo["total_price"]))
+
+		if !g.Price.Equal(price) {
+			mismatches = append(mismatches, PriceMismatch{GoodsID: g.GoodsID, Field: PriceFieldPrice, Submitted: g.Price, Expected: price})
+		}
+		if !g.TotalPrice.Equal(totalPrice) {
+			mismatches = append(mismatches, PriceMismatch{GoodsID: g.GoodsID, Field: PriceFieldTotalPrice, Submitted: g.TotalPrice, Expected: totalPrice})
+		}
+
+		serverGoods = append(serverGoods, GoodsItemRequest{
+			GoodsID:    g.GoodsID,
+			TotalPrice: totalPrice,
+			Price:      price,
+		})
+	}
+
+	// 2. 按服务端价格重新计算活动优惠
+	result, err := s.discountService.CalculateDiscountDetail(ctx, toGoodsForDiscount(serverGoods), activeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("计算活动优惠失败: %w", err)
+	}
+
+	// 3. 校验子订单优惠（包括提交了但不在商品列表中的商品）
+	goodsIDs := make([]int, 0, len(result.ChildDiscounts))
+	for goodsID := range result.ChildDiscounts {
+		goodsIDs = append(goodsIDs, goodsID)
+	}
+	for goodsID := range childDiscounts {
+		if _, ok := result.ChildDiscounts[goodsID]; !ok {
+			goodsIDs = append(goodsIDs, goodsID)
+		}
+	}
+	sort.Ints(goodsIDs)
+	for _, goodsID := range goodsIDs {
+		submitted := childDiscounts[goodsID]
+		expected := result.ChildDiscounts[goodsID]
+		if !submitted.Equal(expected) {
+			mismatches = append(mismatches, PriceMismatch{GoodsID: goodsID, Field: PriceFieldChildDiscount, Submitted: submitted, Expected: expected})
+		}
+	}
+
+	// 4. 校验订单优惠合计
+	if !discountAmount.Equal(result.TotalDiscount) {
+		mismatches = append(mismatches, PriceMismatch{Field: PriceFieldDiscountAmount, Submitted: discountAmount, Expected: result.TotalDiscount})
+	}
+
+	if len(mismatches) > 0 {
+		return nil, &PriceMismatchError{Mismatches: mismatches}
+	}
+
+	return result, nil
+}
+
+// toOrderActivityDiscounts 将优惠计算明细转换为随订单保存的活动优惠明细
+func toOrderActivityDiscounts(result *service.DiscountResult) []*entity.OrderActivityDiscount {
+	discounts := make([]*entity.OrderActivityDiscount, 0, len(result.Breakdown))
+	for _, d := range result.Breakdown {
+		discounts = append(discounts, &entity.OrderActivityDiscount{
+			ActivityID:     d.ActivityID,
+			GoodsID:        d.GoodsID,
+			DiscountAmount: d.Amount,
+		})
+	}
+	return discounts
+}
@@ -0,0 +1,286 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	activityTemplateEntity "charonoms/internal/domain/activity_template/entity"
+	"charonoms/internal/domain/goods/repository"
+	"charonoms/internal/domain/order/entity"
+	"charonoms/internal/domain/order/service"
+	"charonoms/internal/domain/shared/money"
+)
+
+func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm db: %v", err)
+	}
+
+	return gormDB, mock
+}
+
+// fakeGoodsRepo 按商品ID返回固定标准售价和总价的商品仓储
+type fakeGoodsRepo struct {
+	repository.GoodsRepository
+	prices map[int][2]float64 // 商品ID -> {price, total_price}
+	names  map[int]string     // 商品ID -> 名称，启用商品列表中只返回有名称的商品
+}
+
+func (r *fakeGoodsRepo) GetGoodsTotalPrice(ctx context.Context, goodsID int) (map[string]interface{}, error) {
+	p, ok := r.prices[goodsID]
+	if !ok {
+		return nil, errors.New("goods not found")
+	}
+	return map[string]interface{}{"goods_id": goodsID, "price": p[0], "total_price": p[1]}, nil
+}
+
+func m(v float64) money.Money {
+	return money.FromFloat(v)
+}
+
+func newPriceCheckService(db *gorm.DB) *Service {
+	return &Service{
+		goodsRepo: &fakeGoodsRepo{prices: map[int][2]float64{
+			1: {100, 100},
+			2: {300, 300},
+		}},
+		discountService: service.NewDiscountService(db),
+		db:              db,
+	}
+}
+
+// expectActiveActivities 期望查询当前可用的活动
+func expectActiveActivities(mock sqlmock.Sqlmock, activeIDs ...int) {
+	rows := sqlmock.NewRows([]string{"id"})
+	for _, id := range activeIDs {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery("SELECT `id` FROM `activity` WHERE id IN \\(.+\\) AND status = \\? AND start_time <= \\? AND end_time >= \\?").
+		WillReturnRows(rows)
+}
+
+// expectReduction500 期望计算活动500：可叠加满减，满100减10，商品1和2参与
+func expectReduction500(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT (.+) FROM activity (.+)").
+		WithArgs(500, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "template_id", "type", "select_type", "stack_mode", "stack_group"}).
+			AddRow(500, 5, service.ActivityTypeReduction, 2, activityTemplateEntity.StackModeStackable, ""))
+	mock.ExpectQuery("SELECT (.+) FROM `activity_detail` (.+)").
+		WithArgs(500).
+		WillReturnRows(sqlmock.NewRows([]string{"threshold_amount", "discount_value"}).AddRow(100.0, 10.0))
+	mock.ExpectQuery("SELECT (.+) FROM `activity_template_goods` (.+)").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"goods_id", "classify_id"}).AddRow(1, nil).AddRow(2, nil))
+}
+
+func TestVerifyOrderPrices(t *testing.T) {
+	// 服务端计算：总价400，满100减10，按标准售价分摊为 2.50 和 7.50
+	validGoods := func() []GoodsItemRequest {
+		return []GoodsItemRequest{
+			{GoodsID: 1, Price: m(100), TotalPrice: m(100)},
+			{GoodsID: 2, Price: m(300), TotalPrice: m(300)},
+		}
+	}
+	validChildDiscounts := func() map[int]money.Money {
+		return map[int]money.Money{1: m(2.50), 2: m(7.50)}
+	}
+
+	tests := []struct {
+		name           string
+		goods          func() []GoodsItemRequest
+		activityIDs    []int
+		activeIDs      []int
+		discountAmount money.Money
+		childDiscounts func() map[int]money.Money
+		want           []PriceMismatch
+	}{
+		{
+			name:           "金额一致",
+			goods:          validGoods,
+			activityIDs:    []int{500},
+			activeIDs:      []int{500},
+			discountAmount: m(10),
+			childDiscounts: validChildDiscounts,
+		},
+		{
+			name: "篡改标准售价",
+			goods: func() []GoodsItemRequest {
+				goods := validGoods()
+				goods[0].Price = m(1)
+				return goods
+			},
+			activityIDs:    []int{500},
+			activeIDs:      []int{500},
+			discountAmount: m(10),
+			childDiscounts: validChildDiscounts,
+			want:           []PriceMismatch{{GoodsID: 1, Field: PriceFieldPrice, Submitted: m(1), Expected: m(100)}},
+		},
+		{
+			name: "篡改商品总价",
+			goods: func() []GoodsItemRequest {
+				goods := validGoods()
+				goods[1].TotalPrice = m(30)
+				return goods
+			},
+			activityIDs:    []int{500},
+			activeIDs:      []int{500},
+			discountAmount: m(10),
+			childDiscounts: validChildDiscounts,
+			want:           []PriceMismatch{{GoodsID: 2, Field: PriceFieldTotalPrice, Submitted: m(30), Expected: m(300)}},
+		},
+		{
+			name:           "篡改子订单优惠",
+			goods:          validGoods,
+			activityIDs:    []int{500},
+			activeIDs:      []int{500},
+			discountAmount: m(10),
+			childDiscounts: func() map[int]money.Money {
+				return map[int]money.Money{1: m(2.50), 2: m(97.50)}
+			},
+			want: []PriceMismatch{{GoodsID: 2, Field: PriceFieldChildDiscount, Submitted: m(97.50), Expected: m(7.50)}},
+		},
+		{
+			name:           "篡改订单优惠合计",
+			goods:          validGoods,
+			activityIDs:    []int{500},
+			activeIDs:      []int{500},
+			discountAmount: m(100),
+			childDiscounts: validChildDiscounts,
+			want:           []PriceMismatch{{Field: PriceFieldDiscountAmount, Submitted: m(100), Expected: m(10)}},
+		},
+		{
+			name:           "未知或已过期的活动不参与计算",
+			goods:          validGoods,
+			activityIDs:    []int{500, 600},
+			activeIDs:      []int{500},
+			discountAmount: m(10),
+			childDiscounts: validChildDiscounts,
+			want:           []PriceMismatch{{ActivityID: 600, Field: PriceFieldActivity}},
+		},
+		{
+			name:           "全部活动不可用时按无优惠计算",
+			goods:          validGoods,
+			activityIDs:    []int{500},
+			discountAmount: m(10),
+			childDiscounts: validChildDiscounts,
+			want: []PriceMismatch{
+				{ActivityID: 500, Field: PriceFieldActivity},
+				{GoodsID: 1, Field: PriceFieldChildDiscount, Submitted: m(2.50), Expected: m(0)},
+				{GoodsID: 2, Field: PriceFieldChildDiscount, Submitted: m(7.50), Expected: m(0)},
+				{Field: PriceFieldDiscountAmount, Submitted: m(10), Expected: m(0)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			s := newPriceCheckService(db)
+
+			expectActiveActivities(mock, tt.activeIDs...)
+			if len(tt.activeIDs) > 0 {
+				expectReduction500(mock)
+			}
+
+			result, err := s.verifyOrderPrices(context.Background(), tt.goods(), tt.activityIDs, tt.discountAmount, tt.childDiscounts())
+
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("verifyOrderPrices() error = %v", err)
+				}
+				if !result.TotalDiscount.Equal(m(10)) {
+					t.Errorf("verifyOrderPrices() TotalDiscount = %s, want 10.00", result.TotalDiscount)
+				}
+			} else {
+				var mismatchErr *PriceMismatchError
+				if !errors.As(err, &mismatchErr) {
+					t.Fatalf("verifyOrderPrices() error = %v, want *PriceMismatchError", err)
+				}
+				if !reflect.DeepEqual(mismatchErr.Mismatches, tt.want) {
+					t.Errorf("verifyOrderPrices() mismatches = %+v, want %+v", mismatchErr.Mismatches, tt.want)
+				}
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+// tamperedGoods 商品1按库价100提交，商品2的标准售价被改为3
+func tamperedGoods() []GoodsItemRequest {
+	return []GoodsItemRequest{
+		{GoodsID: 1, Price: m(100), TotalPrice: m(100)},
+		{GoodsID: 2, Price: m(3), TotalPrice: m(300)},
+	}
+}
+
+// TestCreateOrder_RejectsTamperedPrice 提交金额与服务端不一致时返回差异明细，不写入订单
+func TestCreateOrder_RejectsTamperedPrice(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newImportService(db)
+
+	_, err := s.CreateOrder(context.Background(), &CreateOrderRequest{
+		StudentID: 35,
+		GoodsList: tamperedGoods(),
+	})
+
+	var mismatchErr *PriceMismatchError
+	if !errors.As(err, &mismatchErr) {
+		t.Fatalf("CreateOrder() error = %v, want *PriceMismatchError", err)
+	}
+	want := []PriceMismatch{{GoodsID: 2, Field: PriceFieldPrice, Submitted: m(3), Expected: m(300)}}
+	if !reflect.DeepEqual(mismatchErr.Mismatches, want) {
+		t.Errorf("CreateOrder() mismatches = %+v, want %+v", mismatchErr.Mismatches, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestUpdateOrder_RejectsTamperedPrice 编辑草稿订单时同样以服务端金额为准，不一致时不更新订单
+func TestUpdateOrder_RejectsTamperedPrice(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newImportService(db)
+
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "student_id", "status"}).AddRow(9, 35, entity.OrderStatusDraft))
+	mock.ExpectQuery("SELECT \\* FROM `order_instalment`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	err := s.UpdateOrder(context.Background(), 9, &UpdateOrderRequest{
+		GoodsList:      tamperedGoods(),
+		DiscountAmount: m(50),
+	})
+
+	var mismatchErr *PriceMismatchError
+	if !errors.As(err, &mismatchErr) {
+		t.Fatalf("UpdateOrder() error = %v, want *PriceMismatchError", err)
+	}
+	want := []PriceMismatch{
+		{GoodsID: 2, Field: PriceFieldPrice, Submitted: m(3), Expected: m(300)},
+		{Field: PriceFieldDiscountAmount, Submitted: m(50), Expected: m(0)},
+	}
+	if !reflect.DeepEqual(mismatchErr.Mismatches, want) {
+		t.Errorf("UpdateOrder() mismatches = %+v, want %+v", mismatchErr.Mismatches, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
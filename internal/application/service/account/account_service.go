@@ -30,13 +30,14 @@ type GetAccountListResponse struct {
 
 // AccountDTO 账号数据传输对象
 type AccountDTO struct {
-	ID       uint   `json:"id"`
-	Username string `json:"username"`
-	Name     string `json:"name"`
-	Phone    string `json:"phone"`
-	RoleID   uint   `json:"role_id"`
-	RoleName string `json:"role_name"`
-	Status   int8   `json:"status"`
+	ID        uint   `json:"id"`
+	Username  string `json:"username"`
+	Name      string `json:"name"`
+	Phone     string `json:"phone"`
+	RoleID    uint   `json:"role_id"`
+	RoleName  string `json:"role_name"`
+	ManagerID *uint  `json:"manager_id"`
+	Status    int8   `json:"status"`
 }
 
 // GetAccountList 获取账号列表
@@ -50,12 +51,13 @@ func (s *AccountService) GetAccountList(ctx context.Context, filters map[string]
 	accountDTOs := make([]*AccountDTO, 0, len(accounts))
 	for _, account := range accounts {
 		dto := &AccountDTO{
-			ID:       account.ID,
-			Username: account.Username,
-			Name:     account.Name,
-			Phone:    account.Phone,
-			RoleID:   account.RoleID,
-			Status:   account.Status,
+			ID:        account.ID,
+			Username:  account.Username,
+			Name:      account.Name,
+			Phone:     account.Phone,
+			RoleID:    account.RoleID,
+			ManagerID: account.ManagerID,
+			Status:    account.Status,
 		}
 		if account.Role != nil {
 			dto.RoleName = account.Role.Name
@@ -70,12 +72,13 @@ func (s *AccountService) GetAccountList(ctx context.Context, filters map[string]
 
 // CreateAccountRequest 创建账号请求
 type CreateAccountRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
-	Name     string `json:"name"`
-	Phone    string `json:"phone" binding:"required"`
-	RoleID   uint   `json:"role_id" binding:"required"`
-	Status   int8   `json:"status"`
+	Username  string `json:"username" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+	Name      string `json:"name"`
+	Phone     string `json:"phone" binding:"required"`
+	RoleID    uint   `json:"role_id" binding:"required"`
+	ManagerID *uint  `json:"manager_id"` // 直属上级账号ID
+	Status    int8   `json:"status"`
 }
 
 // CreateAccount 创建账号
@@ -98,6 +101,10 @@ func (s *AccountService) CreateAccount(ctx context.Context, req *CreateAccountRe
 		return errors.BadRequest("手机号已存在")
 	}
 
+	if err := s.checkManager(ctx, req.ManagerID, 0); err != nil {
+		return err
+	}
+
 	// 加密密码
 	hashedPassword, err := auth.HashPassword(req.Password)
 	if err != nil {
@@ -106,12 +113,13 @@ func (s *AccountService) CreateAccount(ctx context.Context, req *CreateAccountRe
 
 	// 创建账号
 	account := &entity.UserAccount{
-		Username: req.Username,
-		Password: hashedPassword,
-		Name:     req.Name,
-		Phone:    req.Phone,
-		RoleID:   req.RoleID,
-		Status:   req.Status,
+		Username:  req.Username,
+		Password:  hashedPassword,
+		Name:      req.Name,
+		Phone:     req.Phone,
+		RoleID:    req.RoleID,
+		ManagerID: req.ManagerID,
+		Status:    req.Status,
 	}
 
 	return s.accountRepo.CreateAccount(ctx, account)
@@ -119,11 +127,12 @@ func (s *AccountService) CreateAccount(ctx context.Context, req *CreateAccountRe
 
 // UpdateAccountRequest 更新账号请求
 type UpdateAccountRequest struct {
-	Username string `json:"username" binding:"required"`
-	Name     string `json:"name"`
-	Phone    string `json:"phone" binding:"required"`
-	RoleID   uint   `json:"role_id" binding:"required"`
-	Status   int8   `json:"status"`
+	Username  string `json:"username" binding:"required"`
+	Name      string `json:"name"`
+	Phone     string `json:"phone" binding:"required"`
+	RoleID    uint   `json:"role_id" binding:"required"`
+	ManagerID *uint  `json:"manager_id"` // 直属上级账号ID
+	Status    int8   `json:"status"`
 }
 
 // UpdateAccount 更新账号
@@ -159,16 +168,38 @@ func (s *AccountService) UpdateAccount(ctx context.Context, id uint, req *Update
 		}
 	}
 
+	if err := s.checkManager(ctx, req.ManagerID, id); err != nil {
+		return err
+	}
+
 	// 更新账号信息
 	account.Username = req.Username
 	account.Name = req.Name
 	account.Phone = req.Phone
 	account.RoleID = req.RoleID
+	account.ManagerID = req.ManagerID
 	account.Status = req.Status
 
 	return s.accountRepo.UpdateAccount(ctx, account)
 }
 
+// checkManager 检查直属上级账号存在且不是账号本人
+func (s *AccountService) checkManager(ctx context.Context, managerID *uint, selfID uint) error {
+	if managerID == nil {
+		return nil
+	}
+	if *managerID == selfID {
+		return errors.BadRequest("直属上级不能是本人")
+	}
+	if _, err := s.accountRepo.GetAccountByID(ctx, *managerID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return errors.BadRequest("直属上级账号不存在")
+		}
+		return err
+	}
+	return nil
+}
+
 // UpdateAccountStatus 更新账号状态
 func (s *AccountService) UpdateAccountStatus(ctx context.Context, id uint, status int8) error {
 	// 检查账号是否存在
@@ -0,0 +1,107 @@
+package approval
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"charonoms/internal/domain/approval/entity"
+	"charonoms/internal/infrastructure/storage"
+)
+
+// MaxAttachmentSize 审批附件大小上限
+const MaxAttachmentSize = 20 << 20
+
+// allowedAttachmentExts 允许上传的审批附件类型
+var allowedAttachmentExts = map[string]bool{
+	".pdf": true, ".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+	".doc": true, ".docx": true, ".xls": true, ".xlsx": true, ".txt": true, ".zip": true,
+}
+
+// ApprovalAttachmentService 审批附件应用服务
+type ApprovalAttachmentService struct {
+	db      *gorm.DB
+	storage storage.Storage
+}
+
+// NewApprovalAttachmentService 创建审批附件应用服务实例
+func NewApprovalAttachmentService(db *gorm.DB, storage storage.Storage) *ApprovalAttachmentService {
+	return &ApprovalAttachmentService{db: db, storage: storage}
+}
+
+// Upload 上传审批附件，返回附件记录；附件在审批通过/驳回时关联到审批操作
+func (s *ApprovalAttachmentService) Upload(uploaderID int, fileName string, contentType string, size int64, r io.Reader) (*entity.ApprovalAttachment, error) {
+	fileName = filepath.Base(fileName)
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if !allowedAttachmentExts[ext] {
+		return nil, fmt.Errorf("不支持的附件类型: %s", ext)
+	}
+	if size > MaxAttachmentSize {
+		return nil, fmt.Errorf("附件大小不能超过%dMB", MaxAttachmentSize>>20)
+	}
+
+	key, err := newAttachmentKey(ext)
+	if err != nil {
+		return nil, err
+	}
+	written, err := s.storage.Save(key, io.LimitReader(r, MaxAttachmentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if written > MaxAttachmentSize {
+		s.storage.Delete(key)
+		return nil, fmt.Errorf("附件大小不能超过%dMB", MaxAttachmentSize>>20)
+	}
+
+	attachment := &entity.ApprovalAttachment{
+		FileName:    fileName,
+		StorageKey:  key,
+		FileSize:    written,
+		ContentType: contentType,
+		UploaderID:  uploaderID,
+	}
+	if err := s.db.Create(attachment).Error; err != nil {
+		s.storage.Delete(key)
+		return nil, fmt.Errorf("保存附件记录失败: %w", err)
+	}
+	return attachment, nil
+}
+
+// Open 打开附件内容：尚未关联审批操作的附件只有上传人可以查看
+func (s *ApprovalAttachmentService) Open(id int, userID int) (*entity.ApprovalAttachment, io.ReadCloser, error) {
+	var attachment entity.ApprovalAttachment
+	if err := s.db.First(&attachment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("附件不存在")
+		}
+		return nil, nil, err
+	}
+	if attachment.ApprovalFlowActionID == nil && attachment.UploaderID != userID {
+		return nil, nil, errors.New("附件不存在")
+	}
+
+	file, err := s.storage.Open(attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, errors.New("附件文件已丢失")
+		}
+		return nil, nil, err
+	}
+	return &attachment, file, nil
+}
+
+// newAttachmentKey 生成附件存储键：approval/年/月/随机名+扩展名
+func newAttachmentKey(ext string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成附件名失败: %w", err)
+	}
+	return fmt.Sprintf("approval/%s/%s%s", time.Now().Format("2006/01"), hex.EncodeToString(buf), ext), nil
+}
@@ -1,6 +1,7 @@
 package approval
 
 import (
+	"charonoms/internal/domain/approval/entity"
 	"charonoms/internal/domain/approval/repository"
 	"charonoms/internal/domain/approval/service"
 	"errors"
@@ -53,8 +54,8 @@ func (s *ApprovalFlowManagementService) GetDetail(flowID int, userID int) (map[s
 
 // CreateFromTemplate 从模板创建审批流实例
 func (s *ApprovalFlowManagementService) CreateFromTemplate(templateID int, userID int) (int, error) {
-	// 调用仓储层从模板创建审批流实例（事务）
-	return s.flowRepo.CreateFromTemplate(templateID, userID)
+	// 由领域服务按模板创建审批流实例（事务），审批人按节点配置展开
+	return s.flowDomainService.StartFromTemplate(templateID, userID)
 }
 
 // Cancel 撤销审批流
@@ -75,12 +76,12 @@ func (s *ApprovalFlowManagementService) Cancel(flowID int, userID int) error {
 		return errors.New("只能撤销待审批的流程")
 	}
 
-	// 调用仓储层撤销
-	return s.flowRepo.Cancel(flowID, userID)
+	// 调用领域服务撤销（同时回调关联业务的撤销钩子）
+	return s.flowDomainService.ProcessCancel(flowID, userID)
 }
 
 // Approve 审批通过
-func (s *ApprovalFlowManagementService) Approve(nodeCaseUserID int, userID int) error {
+func (s *ApprovalFlowManagementService) Approve(nodeCaseUserID int, userID int, decision service.Decision) error {
 	// 获取审批人员记录
 	nodeCaseUser, err := s.nodeCaseRepo.GetNodeUserByID(nodeCaseUserID)
 	if err != nil {
@@ -98,11 +99,11 @@ func (s *ApprovalFlowManagementService) Approve(nodeCaseUserID int, userID int)
 	}
 
 	// 调用领域服务处理审批通过逻辑
-	return s.flowDomainService.ProcessApprove(nodeCaseUserID)
+	return s.flowDomainService.ProcessApprove(nodeCaseUserID, decision)
 }
 
 // Reject 审批驳回
-func (s *ApprovalFlowManagementService) Reject(nodeCaseUserID int, userID int) error {
+func (s *ApprovalFlowManagementService) Reject(nodeCaseUserID int, userID int, decision service.Decision) error {
 	// 获取审批人员记录
 	nodeCaseUser, err := s.nodeCaseRepo.GetNodeUserByID(nodeCaseUserID)
 	if err != nil {
@@ -120,5 +121,30 @@ func (s *ApprovalFlowManagementService) Reject(nodeCaseUserID int, userID int) e
 	}
 
 	// 调用领域服务处理审批驳回逻辑
-	return s.flowDomainService.ProcessReject(nodeCaseUserID)
+	return s.flowDomainService.ProcessReject(nodeCaseUserID, decision)
+}
+
+// Transfer 转交待审批任务
+func (s *ApprovalFlowManagementService) Transfer(nodeCaseUserID int, userID int, toUserID int, remark string) error {
+	return s.flowDomainService.ProcessTransfer(nodeCaseUserID, userID, toUserID, remark)
+}
+
+// AddSigner 加签
+func (s *ApprovalFlowManagementService) AddSigner(nodeCaseUserID int, userID int, req service.AddSignerRequest) error {
+	return s.flowDomainService.ProcessAddSigner(nodeCaseUserID, userID, req)
+}
+
+// GetDelegations 获取用户设置的外出委托
+func (s *ApprovalFlowManagementService) GetDelegations(userID int) ([]map[string]interface{}, error) {
+	return s.flowDomainService.ListDelegations(userID)
+}
+
+// CreateDelegation 设置外出委托
+func (s *ApprovalFlowManagementService) CreateDelegation(delegation *entity.ApprovalDelegation) error {
+	return s.flowDomainService.CreateDelegation(delegation)
+}
+
+// CancelDelegation 取消外出委托
+func (s *ApprovalFlowManagementService) CancelDelegation(delegationID int, userID int) error {
+	return s.flowDomainService.CancelDelegation(delegationID, userID)
 }
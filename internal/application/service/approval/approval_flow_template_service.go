@@ -3,23 +3,29 @@ package approval
 import (
 	"charonoms/internal/domain/approval/entity"
 	"charonoms/internal/domain/approval/repository"
+	"charonoms/internal/domain/approval/service"
 	"errors"
+	"fmt"
 )
 
 // ApprovalFlowTemplateService 审批流模板应用服务
 type ApprovalFlowTemplateService struct {
 	templateRepo repository.ApprovalFlowTemplateRepository
 	typeRepo     repository.ApprovalFlowTypeRepository
+
+	flowDomainService *service.ApprovalFlowService
 }
 
 // NewApprovalFlowTemplateService 创建审批流模板应用服务
 func NewApprovalFlowTemplateService(
 	templateRepo repository.ApprovalFlowTemplateRepository,
 	typeRepo repository.ApprovalFlowTypeRepository,
+	flowDomainService *service.ApprovalFlowService,
 ) *ApprovalFlowTemplateService {
 	return &ApprovalFlowTemplateService{
-		templateRepo: templateRepo,
-		typeRepo:     typeRepo,
+		templateRepo:      templateRepo,
+		typeRepo:          typeRepo,
+		flowDomainService: flowDomainService,
 	}
 }
 
@@ -38,11 +44,6 @@ func (s *ApprovalFlowTemplateService) GetByID(id int) (*entity.ApprovalFlowTempl
 	return s.templateRepo.GetByID(id)
 }
 
-// DisableSameTypeTemplates 禁用同类型的其他模板
-func (s *ApprovalFlowTemplateService) DisableSameTypeTemplates(typeID int, excludeID int) error {
-	return s.templateRepo.DisableSameTypeTemplates(typeID, excludeID)
-}
-
 // Create 创建审批流模板
 func (s *ApprovalFlowTemplateService) Create(
 	template *entity.ApprovalFlowTemplate,
@@ -50,22 +51,108 @@ func (s *ApprovalFlowTemplateService) Create(
 	nodeApprovers map[int][]int,
 	copyUsers []int,
 ) error {
-	// 验证：至少一个节点
-	if len(nodes) == 0 {
-		return errors.New("模板至少需要一个节点")
-	}
-
-	// 验证：每个节点至少一个审批人
-	for i := range nodes {
-		if len(nodeApprovers[i]) == 0 {
-			return errors.New("每个节点至少需要一个审批人")
-		}
+	if err := s.validateTemplateConfig(template, nodes, nodeApprovers); err != nil {
+		return err
 	}
 
 	// 创建模板
 	return s.templateRepo.Create(template, nodes, nodeApprovers, copyUsers)
 }
 
+// CreateVersion 编辑模板：以 baseID 对应的最新版本为基础生成新版本，已有版本保持不变
+// 进行中的审批流继续使用发起时的版本；原版本启用时新版本接替启用，之后发起的审批流绑定新版本
+func (s *ApprovalFlowTemplateService) CreateVersion(
+	baseID int,
+	template *entity.ApprovalFlowTemplate,
+	nodes []entity.ApprovalFlowTemplateNode,
+	nodeApprovers map[int][]int,
+	copyUsers []int,
+) error {
+	base, err := s.templateRepo.GetByID(baseID)
+	if err != nil {
+		return err
+	}
+	if err := s.validateTemplateConfig(template, nodes, nodeApprovers); err != nil {
+		return err
+	}
+	return s.templateRepo.CreateVersion(base, template, nodes, nodeApprovers, copyUsers)
+}
+
+// ListVersions 获取模板的全部版本，以及每个版本上审批中的审批流数量
+func (s *ApprovalFlowTemplateService) ListVersions(id int) ([]map[string]interface{}, error) {
+	template, err := s.templateRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.templateRepo.ListVersions(template.OriginTemplateID)
+}
+
+// GetRunningFlows 获取绑定在该模板版本上、仍在审批中的审批流
+func (s *ApprovalFlowTemplateService) GetRunningFlows(id int) ([]map[string]interface{}, error) {
+	if _, err := s.templateRepo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.templateRepo.GetRunningFlows(id)
+}
+
+// Compare 比较同一模板的两个版本
+func (s *ApprovalFlowTemplateService) Compare(fromID int, toID int) (*entity.TemplateDiff, error) {
+	from, err := s.templateRepo.GetSnapshot(fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.templateRepo.GetSnapshot(toID)
+	if err != nil {
+		return nil, err
+	}
+	if from.Template.OriginTemplateID != to.Template.OriginTemplateID {
+		return nil, errors.New("只能比较同一模板的不同版本")
+	}
+	diff := entity.CompareTemplates(*from, *to)
+	return &diff, nil
+}
+
+// validateTemplateConfig 校验模板适用条件和节点配置，并清除不按指定人员审批的节点上的审批人
+func (s *ApprovalFlowTemplateService) validateTemplateConfig(template *entity.ApprovalFlowTemplate, nodes []entity.ApprovalFlowTemplateNode, nodeApprovers map[int][]int) error {
+	if err := template.SelectionRules.Validate(); err != nil {
+		return fmt.Errorf("模板适用条件无效: %w", err)
+	}
+
+	// 验证：至少一个节点，节点条件合法，且任意业务数据都至少经过一个审批节点
+	if err := entity.ValidateTemplateNodes(nodes); err != nil {
+		return err
+	}
+
+	// 验证：每个节点按审批人来源配置审批人
+	for i, node := range nodes {
+		switch node.ApproverType {
+		case entity.ApproverTypeUser:
+			if len(nodeApprovers[i]) == 0 {
+				return errors.New("每个节点至少需要一个审批人")
+			}
+		case entity.ApproverTypeRole:
+			if node.ApproverRoleID <= 0 {
+				return fmt.Errorf("节点「%s」按角色审批，需要指定角色", node.Name)
+			}
+			nodeApprovers[i] = nil
+		case entity.ApproverTypeManager:
+			nodeApprovers[i] = nil
+		case entity.ApproverTypeResolver:
+			if !s.flowDomainService.HasApproverResolver(node.ApproverResolver) {
+				return fmt.Errorf("节点「%s」的审批人解析器 %s 不存在", node.Name, node.ApproverResolver)
+			}
+			nodeApprovers[i] = nil
+		default:
+			return fmt.Errorf("节点「%s」的审批人来源无效: %d", node.Name, node.ApproverType)
+		}
+
+		if err := node.ValidateSLA(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // UpdateStatus 更新模板状态
 func (s *ApprovalFlowTemplateService) UpdateStatus(id int, status int8) error {
 	// 获取模板信息
@@ -74,9 +161,9 @@ func (s *ApprovalFlowTemplateService) UpdateStatus(id int, status int8) error {
 		return err
 	}
 
-	// 如果启用，先禁用同类型的其他模板
+	// 如果启用，先禁用同一模板的其他版本；同类型的不同模板按适用条件选择，可以同时启用
 	if status == 0 {
-		if err := s.templateRepo.DisableSameTypeTemplates(template.ApprovalFlowTypeID, id); err != nil {
+		if err := s.templateRepo.DisableOtherVersions(template.OriginTemplateID, id); err != nil {
 			return err
 		}
 	}
@@ -24,6 +24,9 @@ func (s *ApprovalFlowTypeService) GetList(filters map[string]interface{}) ([]ent
 
 // Create 创建审批流类型
 func (s *ApprovalFlowTypeService) Create(flowType *entity.ApprovalFlowType) error {
+	if err := flowType.ValidateCode(); err != nil {
+		return err
+	}
 	return s.typeRepo.Create(flowType)
 }
 
@@ -1,11 +1,12 @@
 package entity
 
-// ActivityDetail 活动详情实体（满折规则）
+// ActivityDetail 活动详情实体（满减/满折/满赠规则）
 type ActivityDetail struct {
 	ID              int     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
 	ActivityID      int     `gorm:"column:activity_id;not null" json:"activity_id"`
 	ThresholdAmount float64 `gorm:"column:threshold_amount;type:decimal(10,2);not null" json:"threshold_amount"` // 门槛金额
-	DiscountValue   float64 `gorm:"column:discount_value;type:decimal(10,2);not null" json:"discount_value"`     // 折扣值
+	DiscountValue   float64 `gorm:"column:discount_value;type:decimal(10,2);not null" json:"discount_value"`     // 折扣值（满赠类型为赠品数量）
+	GiftGoodsID     *int    `gorm:"column:gift_goods_id" json:"gift_goods_id,omitempty"`                        // 赠品商品ID（满赠类型使用）
 }
 
 // TableName 指定表名
@@ -29,5 +30,21 @@ func (d *ActivityDetail) Validate(activityType int) error {
 		return ErrInvalidDiscountValue
 	}
 
+	// 满减类型：折扣值为减免金额，不能超过门槛金额
+	// 例如：门槛1000、折扣值100表示满1000减100
+	if activityType == 1 && d.DiscountValue > d.ThresholdAmount {
+		return ErrInvalidDiscountValue
+	}
+
+	// 满赠类型：必须指定赠品，折扣值为赠品数量（整数）
+	if activityType == 3 {
+		if d.GiftGoodsID == nil || *d.GiftGoodsID == 0 {
+			return ErrGiftGoodsRequired
+		}
+		if d.DiscountValue != float64(int(d.DiscountValue)) {
+			return ErrInvalidDiscountValue
+		}
+	}
+
 	return nil
 }
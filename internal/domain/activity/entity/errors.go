@@ -32,4 +32,7 @@ var (
 
 	// ErrInvalidDiscountValue 无效的折扣值
 	ErrInvalidDiscountValue = errors.New("折扣值无效")
+
+	// ErrGiftGoodsRequired 满赠活动必须指定赠品
+	ErrGiftGoodsRequired = errors.New("满赠活动必须指定赠品")
 )
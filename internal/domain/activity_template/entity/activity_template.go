@@ -2,12 +2,21 @@ package entity
 
 import "time"
 
+// 叠加方式常量
+const (
+	StackModeStackable = 0 // 可叠加：与其他活动同时生效
+	StackModeExclusive = 1 // 互斥：不与任何其他活动同时生效
+	StackModeBestOf    = 2 // 同组择优：同一叠加组内只取优惠最大的活动
+)
+
 // ActivityTemplate 活动模板实体
 type ActivityTemplate struct {
 	ID         int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
 	Name       string    `gorm:"column:name;type:varchar(100);not null" json:"name"`
 	Type       int       `gorm:"column:type;not null" json:"type"`             // 1=满减, 2=满折, 3=满赠
 	SelectType int       `gorm:"column:select_type;not null" json:"select_type"` // 1=按分类, 2=按商品
+	StackMode  int       `gorm:"column:stack_mode;default:0" json:"stack_mode"`  // 0=可叠加, 1=互斥, 2=同组择优
+	StackGroup string    `gorm:"column:stack_group;type:varchar(50)" json:"stack_group"` // 叠加组（同组择优时使用）
 	Status     int       `gorm:"column:status;default:0" json:"status"`         // 0=启用, 1=禁用
 	CreateTime time.Time `gorm:"column:create_time;autoCreateTime" json:"create_time"`
 	UpdateTime time.Time `gorm:"column:update_time;autoUpdateTime" json:"update_time"`
@@ -39,5 +48,11 @@ func (t *ActivityTemplate) Validate() error {
 	if t.SelectType < 1 || t.SelectType > 2 {
 		return ErrInvalidSelectType
 	}
+	if t.StackMode < StackModeStackable || t.StackMode > StackModeBestOf {
+		return ErrInvalidStackMode
+	}
+	if t.StackMode == StackModeBestOf && t.StackGroup == "" {
+		return ErrStackGroupRequired
+	}
 	return nil
 }
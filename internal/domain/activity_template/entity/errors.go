@@ -12,6 +12,12 @@ var (
 	// ErrInvalidSelectType 无效的选择方式
 	ErrInvalidSelectType = errors.New("选择方式必须为 1/2")
 
+	// ErrInvalidStackMode 无效的叠加方式
+	ErrInvalidStackMode = errors.New("叠加方式必须为 0/1/2")
+
+	// ErrStackGroupRequired 同组择优必须指定叠加组
+	ErrStackGroupRequired = errors.New("同组择优的活动模板必须指定叠加组")
+
 	// ErrTemplateNotFound 活动模板不存在
 	ErrTemplateNotFound = errors.New("活动模板不存在")
 
@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// ApprovalAttachment 审批附件：审批人处理前先上传，审批通过/驳回时关联到对应的操作记录
+type ApprovalAttachment struct {
+	ID                   int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	FileName             string    `gorm:"column:file_name;type:varchar(255);not null" json:"file_name"` // 原始文件名
+	StorageKey           string    `gorm:"column:storage_key;type:varchar(500);not null" json:"-"`       // 文件存储中的键
+	FileSize             int64     `gorm:"column:file_size;not null" json:"file_size"`                   // 文件大小（字节）
+	ContentType          string    `gorm:"column:content_type;type:varchar(100)" json:"content_type"`
+	UploaderID           int       `gorm:"column:uploader_id;not null" json:"uploader_id"`                // 上传人账号ID
+	ApprovalFlowActionID *int      `gorm:"column:approval_flow_action_id" json:"approval_flow_action_id"` // 关联的审批操作记录，NULL=尚未使用
+	CreateTime           time.Time `gorm:"column:create_time;not null;default:CURRENT_TIMESTAMP" json:"create_time"`
+}
+
+// TableName 指定表名
+func (ApprovalAttachment) TableName() string {
+	return "approval_attachment"
+}
@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+// 审批委托状态
+const (
+	DelegationStatusActive    int8 = 0 // 生效
+	DelegationStatusCancelled int8 = 1 // 已取消
+)
+
+// ApprovalDelegation 审批委托实体：审批人外出期间，新分配给审批人的任务自动交给委托人
+type ApprovalDelegation struct {
+	ID            int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserAccountID int       `gorm:"column:useraccount_id;not null" json:"useraccount_id"` // 外出的审批人
+	DelegateID    int       `gorm:"column:delegate_id;not null" json:"delegate_id"`       // 委托人
+	StartTime     time.Time `gorm:"column:start_time;not null" json:"start_time"`
+	EndTime       time.Time `gorm:"column:end_time;not null" json:"end_time"` // 不含结束时间
+	Remark        string    `gorm:"column:remark;type:varchar(255)" json:"remark"`
+	Status        int8      `gorm:"column:status;not null;default:0" json:"status"` // 0=生效，1=已取消
+	CreateTime    time.Time `gorm:"column:create_time;not null;default:CURRENT_TIMESTAMP" json:"create_time"`
+}
+
+// TableName 指定表名
+func (ApprovalDelegation) TableName() string {
+	return "approval_delegation"
+}
+
+// IsEffective 委托在指定时间是否生效
+func (d *ApprovalDelegation) IsEffective(at time.Time) bool {
+	return d.Status == DelegationStatusActive && !at.Before(d.StartTime) && at.Before(d.EndTime)
+}
@@ -0,0 +1,39 @@
+package entity
+
+import "time"
+
+// 审批流操作类型（approval_flow_action.action）
+const (
+	FlowActionApprove       = "approve"         // 审批通过
+	FlowActionReject        = "reject"          // 审批驳回
+	FlowActionCancel        = "cancel"          // 发起人撤销
+	FlowActionTransfer      = "transfer"        // 转交：审批人把待审批任务转给他人
+	FlowActionAddSignBefore = "add_sign_before" // 前加签：加签人审批通过后原审批人才能审批
+	FlowActionAddSignAfter  = "add_sign_after"  // 后加签：当前节点通过后再由加签人审批
+	FlowActionDelegate      = "delegate"        // 委托：审批人外出期间任务自动交给委托人
+	FlowActionEscalate      = "escalate"        // 超时升级：未处理的审批人替换为备用审批人
+	FlowActionAutoApprove   = "auto_approve"    // 超时自动通过
+	FlowActionAutoReject    = "auto_reject"     // 超时自动驳回
+	FlowActionResubmit      = "resubmit"        // 发起人修改后重新提交（记录在新版本审批流上）
+)
+
+// SystemOperatorID 系统自动操作（超时升级、自动审批）记录的操作人ID
+const SystemOperatorID = 0
+
+// ApprovalFlowAction 审批流操作记录实体，审批流详情按时间顺序展示
+type ApprovalFlowAction struct {
+	ID                       int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	ApprovalFlowManagementID int       `gorm:"column:approval_flow_management_id;not null" json:"approval_flow_management_id"`
+	ApprovalNodeCaseID       *int      `gorm:"column:approval_node_case_id" json:"approval_node_case_id"`
+	Action                   string    `gorm:"column:action;type:varchar(30);not null" json:"action"`
+	OperatorID               int       `gorm:"column:operator_id;not null" json:"operator_id"` // 操作人账号ID，0=系统
+	FromUserID               *int      `gorm:"column:from_user_id" json:"from_user_id"`        // 转交/委托的原审批人
+	ToUserID                 *int      `gorm:"column:to_user_id" json:"to_user_id"`            // 转交/委托/加签的目标审批人
+	Remark                   string    `gorm:"column:remark;type:varchar(500)" json:"remark"`
+	CreateTime               time.Time `gorm:"column:create_time;not null;default:CURRENT_TIMESTAMP" json:"create_time"`
+}
+
+// TableName 指定表名
+func (ApprovalFlowAction) TableName() string {
+	return "approval_flow_action"
+}
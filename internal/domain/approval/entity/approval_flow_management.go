@@ -2,16 +2,27 @@ package entity
 
 import "time"
 
+// 审批流关联的业务类型（approval_flow_management.business_type）
+const (
+	BusinessTypeRefund         = "refund"          // 退费订单
+	BusinessTypeOrderAmendment = "order_amendment" // 订单变更单
+)
+
 // ApprovalFlowManagement 审批流实例实体
 type ApprovalFlowManagement struct {
-	ID                     int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	ApprovalFlowTemplateID int        `gorm:"column:approval_flow_template_id;not null" json:"approval_flow_template_id"`
-	ApprovalFlowTypeID     int        `gorm:"column:approval_flow_type_id;not null" json:"approval_flow_type_id"`
-	Step                   int        `gorm:"column:step;not null;default:0" json:"step"`         // 当前执行到第几步节点
-	CreateUser             int        `gorm:"column:create_user;not null" json:"create_user"`     // 发起人ID
-	CreateTime             time.Time  `gorm:"column:create_time;not null;default:CURRENT_TIMESTAMP" json:"create_time"`
-	Status                 int8       `gorm:"column:status;not null;default:0" json:"status"` // 0=待审批，10=已通过，20=已驳回，99=已撤销
-	CompleteTime           *time.Time `gorm:"column:complete_time" json:"complete_time"`      // 完成时间
+	ID                     int             `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	ApprovalFlowTemplateID int             `gorm:"column:approval_flow_template_id;not null" json:"approval_flow_template_id"`
+	ApprovalFlowTypeID     int             `gorm:"column:approval_flow_type_id;not null" json:"approval_flow_type_id"`
+	Step                   int             `gorm:"column:step;not null;default:0" json:"step"`     // 当前执行到第几步节点
+	CreateUser             int             `gorm:"column:create_user;not null" json:"create_user"` // 发起人ID
+	CreateTime             time.Time       `gorm:"column:create_time;not null;default:CURRENT_TIMESTAMP" json:"create_time"`
+	Status                 int8            `gorm:"column:status;not null;default:0" json:"status"`                  // 0=待审批，10=已通过，20=已驳回，99=已撤销
+	CompleteTime           *time.Time      `gorm:"column:complete_time" json:"complete_time"`                       // 完成时间
+	BusinessType           string          `gorm:"column:business_type;type:varchar(50)" json:"business_type"`      // 关联业务类型，如 refund、order_amendment
+	BusinessID             int             `gorm:"column:business_id" json:"business_id"`                           // 关联业务单据ID
+	BusinessPayload        BusinessPayload `gorm:"column:business_payload;serializer:json" json:"business_payload"` // 业务条件数据，用于节点条件判断
+	Version                int             `gorm:"column:version;not null;default:1" json:"version"`                // 版本号，驳回/撤销后重新提交时递增
+	PreviousFlowID         *int            `gorm:"column:previous_flow_id" json:"previous_flow_id"`                 // 重新提交前的审批流ID
 }
 
 // TableName 指定表名
@@ -19,6 +30,11 @@ func (ApprovalFlowManagement) TableName() string {
 	return "approval_flow_management"
 }
 
+// IsResubmittable 审批流已驳回(20)或已撤销(99)时，发起人可以修改后重新提交为新版本
+func (f *ApprovalFlowManagement) IsResubmittable() bool {
+	return f.Status == 20 || f.Status == 99
+}
+
 // ApprovalNodeCase 审批节点实例实体
 type ApprovalNodeCase struct {
 	ID                       int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
@@ -26,7 +42,11 @@ type ApprovalNodeCase struct {
 	ApprovalFlowManagementID int        `gorm:"column:approval_flow_management_id;not null" json:"approval_flow_management_id"`
 	Type                     int8       `gorm:"column:type;not null" json:"type"` // 0=会签，1=或签
 	Sort                     int        `gorm:"column:sort;not null" json:"sort"`
-	Result                   *int8      `gorm:"column:result" json:"result"`               // NULL=审批中，0=通过，1=驳回
+	AddSignType              int8       `gorm:"column:add_sign_type;not null;default:0" json:"add_sign_type"` // 0=模板节点，1=前加签，2=后加签
+	ParentNodeCaseID         *int       `gorm:"column:parent_node_case_id" json:"parent_node_case_id"`        // 加签节点所属的节点实例
+	Result                   *int8      `gorm:"column:result" json:"result"`                                  // NULL=审批中，0=通过，1=驳回
+	Deadline                 *time.Time `gorm:"column:deadline" json:"deadline"`                              // 处理截止时间，NULL=不限时
+	SLAStatus                int8       `gorm:"column:sla_status;not null;default:0" json:"sla_status"`       // 0=未超时，1=已超时处理
 	CreateTime               time.Time  `gorm:"column:create_time;not null;default:CURRENT_TIMESTAMP" json:"create_time"`
 	CompleteTime             *time.Time `gorm:"column:complete_time" json:"complete_time"` // 完成时间
 }
@@ -36,6 +56,19 @@ func (ApprovalNodeCase) TableName() string {
 	return "approval_node_case"
 }
 
+// 节点实例的加签类型（approval_node_case.add_sign_type）
+const (
+	AddSignNone   int8 = 0 // 按模板节点创建
+	AddSignBefore int8 = 1 // 前加签：通过后原节点审批人才能审批
+	AddSignAfter  int8 = 2 // 后加签：原节点通过后审批，全部通过才流转到下一模板节点
+)
+
+// 节点实例的超时处理状态（approval_node_case.sla_status）
+const (
+	SLAStatusPending int8 = 0 // 未超时或超时尚未处理
+	SLAStatusHandled int8 = 1 // 已按超时处理方式处理（提醒/升级/自动审批）
+)
+
 // ApprovalNodeCaseUser 审批人员记录实体
 type ApprovalNodeCaseUser struct {
 	ID                  int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
@@ -1,6 +1,9 @@
 package entity
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // ApprovalFlowTemplate 审批流模板实体
 type ApprovalFlowTemplate struct {
@@ -9,6 +12,9 @@ type ApprovalFlowTemplate struct {
 	ApprovalFlowTypeID  int       `gorm:"column:approval_flow_type_id;not null" json:"approval_flow_type_id"`
 	Creator             string    `gorm:"column:creator;type:varchar(100)" json:"creator"`
 	Status              int8      `gorm:"column:status;not null;default:0" json:"status"` // 0=启用，1=禁用
+	Version             int       `gorm:"column:version;not null;default:1" json:"version"` // 版本号，编辑模板时生成新版本
+	OriginTemplateID    int       `gorm:"column:origin_template_id" json:"origin_template_id"` // 第一版模板ID，同一模板的各版本相同
+	SelectionRules      NodeConditions `gorm:"column:selection_rules;serializer:json" json:"selection_rules"` // 适用条件，同类型启用的模板中按业务数据选择，为空表示适用全部
 	CreateTime          time.Time `gorm:"column:create_time;not null;default:CURRENT_TIMESTAMP" json:"create_time"`
 	UpdateTime          time.Time `gorm:"column:update_time;not null;default:CURRENT_TIMESTAMP" json:"update_time"`
 }
@@ -20,12 +26,19 @@ func (ApprovalFlowTemplate) TableName() string {
 
 // ApprovalFlowTemplateNode 审批流模板节点实体
 type ApprovalFlowTemplateNode struct {
-	ID         int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	TemplateID int       `gorm:"column:template_id;not null" json:"template_id"`
-	Name       string    `gorm:"column:name;type:varchar(100);not null" json:"name"`
-	Sort       int       `gorm:"column:sort;not null" json:"sort"`
-	Type       int8      `gorm:"column:type;not null" json:"type"` // 0=会签，1=或签
-	CreateTime time.Time `gorm:"column:create_time;not null;default:CURRENT_TIMESTAMP" json:"create_time"`
+	ID               int            `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	TemplateID       int            `gorm:"column:template_id;not null" json:"template_id"`
+	Name             string         `gorm:"column:name;type:varchar(100);not null" json:"name"`
+	Sort             int            `gorm:"column:sort;not null" json:"sort"`
+	Type             int8           `gorm:"column:type;not null" json:"type"`                                    // 0=会签，1=或签
+	Conditions       NodeConditions `gorm:"column:conditions;serializer:json" json:"conditions"`                 // 进入节点的条件，为空表示无条件进入
+	ApproverType     int8           `gorm:"column:approver_type;not null;default:0" json:"approver_type"`        // 审批人来源：0=指定人员，1=角色，2=发起人上级，3=解析器
+	ApproverRoleID   int            `gorm:"column:approver_role_id" json:"approver_role_id"`                     // 审批人角色ID（approver_type=1）
+	ApproverResolver string         `gorm:"column:approver_resolver;type:varchar(100)" json:"approver_resolver"` // 审批人解析器名称（approver_type=3）
+	SLAHours         int            `gorm:"column:sla_hours;not null;default:0" json:"sla_hours"`                // 处理时限（小时），0=不限时
+	SLAAction        int8           `gorm:"column:sla_action;not null;default:0" json:"sla_action"`              // 超时处理：0=仅提醒，1=升级给备用审批人，2=自动通过，3=自动驳回
+	SLABackupID      *int           `gorm:"column:sla_backup_id" json:"sla_backup_id"`                           // 备用审批人账号ID（sla_action=1）
+	CreateTime       time.Time      `gorm:"column:create_time;not null;default:CURRENT_TIMESTAMP" json:"create_time"`
 }
 
 // TableName 指定表名
@@ -33,7 +46,53 @@ func (ApprovalFlowTemplateNode) TableName() string {
 	return "approval_flow_template_node"
 }
 
-// ApprovalNodeUserAccount 审批节点人员配置实体
+// 审批节点的审批人来源（approval_flow_template_node.approver_type）
+// 审批人在创建节点实例时展开为 approval_node_case_user 记录
+const (
+	ApproverTypeUser     int8 = 0 // 指定人员：approval_node_useraccount 配置的账号
+	ApproverTypeRole     int8 = 1 // 角色：该角色下所有启用的账号
+	ApproverTypeManager  int8 = 2 // 发起人上级：发起人账号的 manager_id
+	ApproverTypeResolver int8 = 3 // 解析器：按名称注册的审批人解析函数
+)
+
+// 节点超时处理方式（approval_flow_template_node.sla_action），超时时总会先提醒未处理的审批人
+const (
+	SLAActionRemind      int8 = 0 // 仅提醒
+	SLAActionEscalate    int8 = 1 // 升级：未处理的审批人替换为备用审批人
+	SLAActionAutoApprove int8 = 2 // 自动通过节点
+	SLAActionAutoReject  int8 = 3 // 自动驳回节点，审批流驳回
+)
+
+// SLADeadline 按节点处理时限计算从 from 开始的截止时间，不限时返回nil
+func (n *ApprovalFlowTemplateNode) SLADeadline(from time.Time) *time.Time {
+	if n.SLAHours <= 0 {
+		return nil
+	}
+	deadline := from.Add(time.Duration(n.SLAHours) * time.Hour)
+	return &deadline
+}
+
+// ValidateSLA 校验节点的处理时限配置
+func (n *ApprovalFlowTemplateNode) ValidateSLA() error {
+	if n.SLAHours < 0 {
+		return fmt.Errorf("节点「%s」的处理时限不能为负数", n.Name)
+	}
+	switch n.SLAAction {
+	case SLAActionRemind:
+	case SLAActionEscalate, SLAActionAutoApprove, SLAActionAutoReject:
+		if n.SLAHours == 0 {
+			return fmt.Errorf("节点「%s」设置了超时处理方式，需要同时设置处理时限", n.Name)
+		}
+		if n.SLAAction == SLAActionEscalate && (n.SLABackupID == nil || *n.SLABackupID <= 0) {
+			return fmt.Errorf("节点「%s」超时升级需要指定备用审批人", n.Name)
+		}
+	default:
+		return fmt.Errorf("节点「%s」的超时处理方式无效: %d", n.Name, n.SLAAction)
+	}
+	return nil
+}
+
+// ApprovalNodeUserAccount 审批节点人员配置实体（approver_type=0 时使用）
 type ApprovalNodeUserAccount struct {
 	ID            int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
 	NodeID        int       `gorm:"column:node_id;not null" json:"node_id"`
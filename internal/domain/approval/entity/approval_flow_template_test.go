@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApprovalFlowTemplateNode_ValidateSLA(t *testing.T) {
+	backup := 9
+	tests := []struct {
+		name    string
+		node    ApprovalFlowTemplateNode
+		wantErr bool
+	}{
+		{"不限时", ApprovalFlowTemplateNode{}, false},
+		{"仅提醒", ApprovalFlowTemplateNode{SLAHours: 24}, false},
+		{"超时升级", ApprovalFlowTemplateNode{SLAHours: 24, SLAAction: SLAActionEscalate, SLABackupID: &backup}, false},
+		{"超时自动驳回", ApprovalFlowTemplateNode{SLAHours: 48, SLAAction: SLAActionAutoReject}, false},
+		{"时限为负数", ApprovalFlowTemplateNode{SLAHours: -1}, true},
+		{"自动通过未设置时限", ApprovalFlowTemplateNode{SLAAction: SLAActionAutoApprove}, true},
+		{"升级未指定备用审批人", ApprovalFlowTemplateNode{SLAHours: 24, SLAAction: SLAActionEscalate}, true},
+		{"处理方式无效", ApprovalFlowTemplateNode{SLAHours: 24, SLAAction: 9}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.node.ValidateSLA()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSLA() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApprovalFlowTemplateNode_SLADeadline(t *testing.T) {
+	from := time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local)
+
+	if got := (&ApprovalFlowTemplateNode{}).SLADeadline(from); got != nil {
+		t.Errorf("SLADeadline() = %v, want nil for node without SLA", got)
+	}
+
+	got := (&ApprovalFlowTemplateNode{SLAHours: 24}).SLADeadline(from)
+	if got == nil || !got.Equal(from.Add(24*time.Hour)) {
+		t.Errorf("SLADeadline() = %v, want %v", got, from.Add(24*time.Hour))
+	}
+}
@@ -1,16 +1,41 @@
 package entity
 
-import "time"
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// 业务审批使用的审批流类型编码（approval_flow_type.code），业务代码按编码而不是名称查找审批流类型
+const (
+	FlowTypeCodeRefund         = "refund"          // 退费
+	FlowTypeCodeOrderAmendment = "order_amendment" // 订单变更
+)
+
+// flowTypeCodePattern 类型编码：小写字母开头，由小写字母、数字和下划线组成
+var flowTypeCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
 
 // ApprovalFlowType 审批流类型实体
 type ApprovalFlowType struct {
 	ID         int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Code       *string   `gorm:"column:code;type:varchar(50)" json:"code"` // 稳定编码，业务审批按编码选择模板；手动发起的类型可为空
 	Name       string    `gorm:"column:name;type:varchar(100);not null" json:"name"`
 	Status     int8      `gorm:"column:status;not null;default:0" json:"status"` // 0=启用，1=禁用
 	CreateTime time.Time `gorm:"column:create_time;not null;default:CURRENT_TIMESTAMP" json:"create_time"`
 	UpdateTime time.Time `gorm:"column:update_time;not null;default:CURRENT_TIMESTAMP" json:"update_time"`
 }
 
+// ValidateCode 校验类型编码格式，未设置编码时不校验
+func (t *ApprovalFlowType) ValidateCode() error {
+	if t.Code == nil {
+		return nil
+	}
+	if !flowTypeCodePattern.MatchString(*t.Code) {
+		return fmt.Errorf("类型编码 %s 无效：需以小写字母开头，由小写字母、数字和下划线组成", *t.Code)
+	}
+	return nil
+}
+
 // TableName 指定表名
 func (ApprovalFlowType) TableName() string {
 	return "approval_flow_type"
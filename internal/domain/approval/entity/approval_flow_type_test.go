@@ -0,0 +1,23 @@
+package entity
+
+import "testing"
+
+func TestApprovalFlowType_ValidateCode(t *testing.T) {
+	valid := []string{FlowTypeCodeRefund, FlowTypeCodeOrderAmendment, "leave2"}
+	for _, code := range valid {
+		code := code
+		if err := (&ApprovalFlowType{Code: &code}).ValidateCode(); err != nil {
+			t.Errorf("ValidateCode(%q) error = %v", code, err)
+		}
+	}
+	invalid := []string{"", "Refund", "2refund", "退费", "refund-order"}
+	for _, code := range invalid {
+		code := code
+		if err := (&ApprovalFlowType{Code: &code}).ValidateCode(); err == nil {
+			t.Errorf("ValidateCode(%q) expected error", code)
+		}
+	}
+	if err := (&ApprovalFlowType{}).ValidateCode(); err != nil {
+		t.Errorf("ValidateCode() without code error = %v", err)
+	}
+}
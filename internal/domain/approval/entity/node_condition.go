@@ -0,0 +1,230 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+// BusinessPayload 审批流关联业务单据的条件数据，发起审批时写入，节点条件据此判断是否进入节点
+type BusinessPayload map[string]interface{}
+
+// 节点条件可用的业务数据字段
+const (
+	PayloadFieldAmount      = "amount"       // 业务金额（元）：退费金额，订单变更后的实收金额
+	PayloadFieldAmountDelta = "amount_delta" // 金额变化（元）：订单变更后实收金额减变更前实收金额
+	PayloadFieldChannel     = "channel"      // 收款渠道：regular=常规，taobao=淘宝，mixed=混合
+	PayloadFieldPayeeEntity = "payee_entity" // 收款主体：beijing=北京，xian=西安，mixed=多个主体
+)
+
+// 收款渠道取值（PayloadFieldChannel）
+const (
+	PayloadChannelRegular = "regular"
+	PayloadChannelTaobao  = "taobao"
+	PayloadChannelMixed   = "mixed"
+)
+
+// 收款主体取值（PayloadFieldPayeeEntity），对应收款记录的 payee_entity：0=北京，1=西安
+const (
+	PayloadPayeeBeijing = "beijing"
+	PayloadPayeeXian    = "xian"
+	PayloadPayeeMixed   = "mixed"
+)
+
+// conditionFieldNumeric 条件字段是否为数值类型（数值字段支持大小比较）
+var conditionFieldNumeric = map[string]bool{
+	PayloadFieldAmount:      true,
+	PayloadFieldAmountDelta: true,
+	PayloadFieldChannel:     false,
+	PayloadFieldPayeeEntity: false,
+}
+
+// 条件运算符
+const (
+	ConditionOpEq  = "="
+	ConditionOpNe  = "!="
+	ConditionOpGt  = ">"
+	ConditionOpGte = ">="
+	ConditionOpLt  = "<"
+	ConditionOpLte = "<="
+	ConditionOpIn  = "in"
+)
+
+// NodeCondition 节点条件：业务数据字段与取值的比较
+type NodeCondition struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"` // in 运算符为取值数组
+}
+
+// NodeConditions 节点条件列表，全部满足才进入节点；为空表示无条件节点
+type NodeConditions []NodeCondition
+
+// Validate 校验条件的字段、运算符和取值类型
+func (cs NodeConditions) Validate() error {
+	for _, c := range cs {
+		numeric, ok := conditionFieldNumeric[c.Field]
+		if !ok {
+			return fmt.Errorf("不支持的条件字段: %s", c.Field)
+		}
+		switch c.Op {
+		case ConditionOpEq, ConditionOpNe:
+			if err := validateConditionValue(c.Field, numeric, c.Value); err != nil {
+				return err
+			}
+		case ConditionOpGt, ConditionOpGte, ConditionOpLt, ConditionOpLte:
+			if !numeric {
+				return fmt.Errorf("条件字段 %s 不支持运算符 %s", c.Field, c.Op)
+			}
+			if err := validateConditionValue(c.Field, numeric, c.Value); err != nil {
+				return err
+			}
+		case ConditionOpIn:
+			values, ok := c.Value.([]interface{})
+			if !ok || len(values) == 0 {
+				return fmt.Errorf("条件字段 %s 的 in 取值必须为非空数组", c.Field)
+			}
+			for _, v := range values {
+				if err := validateConditionValue(c.Field, numeric, v); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("不支持的条件运算符: %s", c.Op)
+		}
+	}
+	return nil
+}
+
+// validateConditionValue 校验条件取值与字段类型一致
+func validateConditionValue(field string, numeric bool, value interface{}) error {
+	if numeric {
+		if _, ok := toFloat(value); !ok {
+			return fmt.Errorf("条件字段 %s 的取值必须为数字", field)
+		}
+		return nil
+	}
+	if _, ok := value.(string); !ok {
+		return fmt.Errorf("条件字段 %s 的取值必须为字符串", field)
+	}
+	return nil
+}
+
+// Match 判断业务数据是否满足全部条件
+// 业务数据缺少的字段只满足 != 条件（如订单变更没有收款渠道，"channel != taobao" 视为满足）
+func (cs NodeConditions) Match(payload BusinessPayload) bool {
+	for _, c := range cs {
+		if !c.match(payload) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c NodeCondition) match(payload BusinessPayload) bool {
+	actual, ok := payload[c.Field]
+	if !ok || actual == nil {
+		return c.Op == ConditionOpNe
+	}
+
+	switch c.Op {
+	case ConditionOpEq:
+		return conditionValueEqual(actual, c.Value)
+	case ConditionOpNe:
+		return !conditionValueEqual(actual, c.Value)
+	case ConditionOpIn:
+		values, _ := c.Value.([]interface{})
+		for _, v := range values {
+			if conditionValueEqual(actual, v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	a, okA := toFloat(actual)
+	b, okB := toFloat(c.Value)
+	if !okA || !okB {
+		return false
+	}
+	switch c.Op {
+	case ConditionOpGt:
+		return a > b
+	case ConditionOpGte:
+		return a >= b
+	case ConditionOpLt:
+		return a < b
+	case ConditionOpLte:
+		return a <= b
+	}
+	return false
+}
+
+// conditionValueEqual 比较两个取值，数值按数值比较，其余按字符串比较
+func conditionValueEqual(a, b interface{}) bool {
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if okA && okB {
+		return fa == fb
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// toFloat 将JSON数值或Go数值转换为float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// NextTemplateNode 从按sort升序排列的模板节点中，选出sort大于afterSort且条件满足的第一个节点
+// 返回nil表示没有后续节点，审批流完成；afterSort 传 0 选出第一个节点
+func NextTemplateNode(nodes []ApprovalFlowTemplateNode, afterSort int, payload BusinessPayload) *ApprovalFlowTemplateNode {
+	for i := range nodes {
+		if nodes[i].Sort <= afterSort {
+			continue
+		}
+		if nodes[i].Conditions.Match(payload) {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
+
+// ValidateTemplateNodes 校验模板节点配置：
+// 节点条件合法，且至少有一个无条件节点，保证任意业务数据走出的审批路径都至少经过一个审批节点后结束，
+// 不会因条件全部不满足而不经审批直接通过
+func ValidateTemplateNodes(nodes []ApprovalFlowTemplateNode) error {
+	if len(nodes) == 0 {
+		return errors.New("模板至少需要一个节点")
+	}
+
+	hasUnconditional := false
+	sorts := make(map[int]bool, len(nodes))
+	for _, node := range nodes {
+		if sorts[node.Sort] {
+			return fmt.Errorf("节点顺序重复: %d", node.Sort)
+		}
+		sorts[node.Sort] = true
+
+		if err := node.Conditions.Validate(); err != nil {
+			return fmt.Errorf("节点「%s」条件无效: %w", node.Name, err)
+		}
+		if len(node.Conditions) == 0 {
+			hasUnconditional = true
+		}
+	}
+	if !hasUnconditional {
+		return errors.New("模板至少需要一个无条件节点，否则部分业务单据将不经审批直接通过")
+	}
+	return nil
+}
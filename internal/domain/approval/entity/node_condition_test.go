@@ -0,0 +1,130 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func mustConditions(t *testing.T, raw string) NodeConditions {
+	t.Helper()
+	var cs NodeConditions
+	if err := json.Unmarshal([]byte(raw), &cs); err != nil {
+		t.Fatalf("unmarshal conditions: %v", err)
+	}
+	return cs
+}
+
+func TestNodeConditions_Match(t *testing.T) {
+	refund := BusinessPayload{PayloadFieldAmount: 6000.0, PayloadFieldChannel: PayloadChannelTaobao}
+	amendment := BusinessPayload{PayloadFieldAmount: 3000.0, PayloadFieldAmountDelta: -200.0}
+
+	tests := []struct {
+		name       string
+		conditions string
+		payload    BusinessPayload
+		want       bool
+	}{
+		{"无条件节点", `[]`, refund, true},
+		{"金额超过5000", `[{"field":"amount","op":">","value":5000}]`, refund, true},
+		{"金额未超过5000", `[{"field":"amount","op":">","value":5000}]`, amendment, false},
+		{"金额等于边界", `[{"field":"amount","op":">=","value":6000}]`, refund, true},
+		{"淘宝退费跳过校区节点", `[{"field":"channel","op":"!=","value":"taobao"}]`, refund, false},
+		{"缺少字段满足不等于", `[{"field":"channel","op":"!=","value":"taobao"}]`, amendment, true},
+		{"缺少字段不满足等于", `[{"field":"channel","op":"=","value":"regular"}]`, amendment, false},
+		{"in 命中", `[{"field":"channel","op":"in","value":["taobao","mixed"]}]`, refund, true},
+		{"多个条件全部满足", `[{"field":"amount","op":"<","value":5000},{"field":"amount_delta","op":"<","value":0}]`, amendment, true},
+		{"多个条件部分满足", `[{"field":"amount","op":">","value":5000},{"field":"channel","op":"=","value":"taobao"}]`, amendment, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustConditions(t, tt.conditions).Match(tt.payload); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeConditions_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions string
+		wantErr    bool
+	}{
+		{"合法的金额条件", `[{"field":"amount","op":">","value":5000}]`, false},
+		{"合法的渠道条件", `[{"field":"channel","op":"in","value":["taobao"]}]`, false},
+		{"未知字段", `[{"field":"campus","op":"=","value":"A"}]`, true},
+		{"未知运算符", `[{"field":"amount","op":"~","value":1}]`, true},
+		{"字符串字段不支持大小比较", `[{"field":"channel","op":">","value":"taobao"}]`, true},
+		{"数值字段取值不是数字", `[{"field":"amount","op":">","value":"5000"}]`, true},
+		{"in 取值不是数组", `[{"field":"channel","op":"in","value":"taobao"}]`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := mustConditions(t, tt.conditions).Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNextTemplateNode(t *testing.T) {
+	nodes := []ApprovalFlowTemplateNode{
+		{ID: 1, Sort: 1, Conditions: mustConditions(t, `[{"field":"channel","op":"!=","value":"taobao"}]`)},
+		{ID: 2, Sort: 2},
+		{ID: 3, Sort: 3, Conditions: mustConditions(t, `[{"field":"amount","op":">","value":5000}]`)},
+	}
+
+	tests := []struct {
+		name      string
+		afterSort int
+		payload   BusinessPayload
+		wantID    int
+	}{
+		{"常规退费从校区节点开始", 0, BusinessPayload{PayloadFieldAmount: 100.0, PayloadFieldChannel: PayloadChannelRegular}, 1},
+		{"淘宝退费跳过校区节点", 0, BusinessPayload{PayloadFieldAmount: 100.0, PayloadFieldChannel: PayloadChannelTaobao}, 2},
+		{"大额退费进入财务总监节点", 2, BusinessPayload{PayloadFieldAmount: 6000.0}, 3},
+		{"小额退费在第二个节点后结束", 2, BusinessPayload{PayloadFieldAmount: 100.0}, 0},
+		{"最后一个节点后结束", 3, BusinessPayload{PayloadFieldAmount: 6000.0}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NextTemplateNode(nodes, tt.afterSort, tt.payload)
+			gotID := 0
+			if got != nil {
+				gotID = got.ID
+			}
+			if gotID != tt.wantID {
+				t.Errorf("NextTemplateNode() = node %d, want node %d", gotID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestValidateTemplateNodes(t *testing.T) {
+	bigRefund := mustConditions(t, `[{"field":"amount","op":">","value":5000}]`)
+
+	tests := []struct {
+		name    string
+		nodes   []ApprovalFlowTemplateNode
+		wantErr bool
+	}{
+		{"没有节点", nil, true},
+		{"有无条件节点", []ApprovalFlowTemplateNode{{Sort: 1}, {Sort: 2, Conditions: bigRefund}}, false},
+		{"全部为条件节点", []ApprovalFlowTemplateNode{{Sort: 1, Conditions: bigRefund}}, true},
+		{"节点顺序重复", []ApprovalFlowTemplateNode{{Sort: 1}, {Sort: 1}}, true},
+		{"节点条件无效", []ApprovalFlowTemplateNode{{Sort: 1}, {Sort: 2, Conditions: NodeConditions{{Field: "amount", Op: "~", Value: 1.0}}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTemplateNodes(tt.nodes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTemplateNodes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
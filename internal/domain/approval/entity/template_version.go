@@ -0,0 +1,156 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// ErrTemplateVersionOutdated 编辑的不是模板的最新版本
+var ErrTemplateVersionOutdated = errors.New("只能基于模板的最新版本编辑，请刷新后重试")
+
+// TemplateSnapshot 审批流模板某个版本的完整配置，用于版本比较
+type TemplateSnapshot struct {
+	Template      ApprovalFlowTemplate
+	Nodes         []ApprovalFlowTemplateNode // 按 sort 排序
+	NodeApprovers map[int][]int              // 模板节点ID -> 指定审批人（approver_type=0）
+	CopyUsers     []int
+}
+
+// FieldChange 字段变化
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// 节点变化类型
+const (
+	NodeChangeAdded   = "added"
+	NodeChangeRemoved = "removed"
+	NodeChangeChanged = "changed"
+)
+
+// NodeDiff 按顺序位置比较的节点变化
+type NodeDiff struct {
+	Sort   int           `json:"sort"`
+	Change string        `json:"change"` // added / removed / changed
+	Name   string        `json:"name"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// TemplateDiff 两个模板版本之间的差异，未变化的节点不列出
+type TemplateDiff struct {
+	FromTemplateID   int           `json:"from_template_id"`
+	FromVersion      int           `json:"from_version"`
+	ToTemplateID     int           `json:"to_template_id"`
+	ToVersion        int           `json:"to_version"`
+	Fields           []FieldChange `json:"fields"`
+	Nodes            []NodeDiff    `json:"nodes"`
+	CopyUsersAdded   []int         `json:"copy_users_added"`
+	CopyUsersRemoved []int         `json:"copy_users_removed"`
+}
+
+// CompareTemplates 比较两个模板版本：模板名称和适用条件、按顺序位置对应的节点配置、抄送人员
+func CompareTemplates(from, to TemplateSnapshot) TemplateDiff {
+	diff := TemplateDiff{
+		FromTemplateID: from.Template.ID,
+		FromVersion:    from.Template.Version,
+		ToTemplateID:   to.Template.ID,
+		ToVersion:      to.Template.Version,
+		Fields:         []FieldChange{},
+		Nodes:          []NodeDiff{},
+	}
+	if from.Template.Name != to.Template.Name {
+		diff.Fields = append(diff.Fields, FieldChange{Field: "name", From: from.Template.Name, To: to.Template.Name})
+	}
+	fromRules, _ := json.Marshal(from.Template.SelectionRules)
+	toRules, _ := json.Marshal(to.Template.SelectionRules)
+	if string(fromRules) != string(toRules) {
+		diff.Fields = append(diff.Fields, FieldChange{Field: "selection_rules", From: from.Template.SelectionRules, To: to.Template.SelectionRules})
+	}
+
+	for i := 0; i < len(from.Nodes) || i < len(to.Nodes); i++ {
+		switch {
+		case i >= len(from.Nodes):
+			diff.Nodes = append(diff.Nodes, NodeDiff{Sort: i + 1, Change: NodeChangeAdded, Name: to.Nodes[i].Name})
+		case i >= len(to.Nodes):
+			diff.Nodes = append(diff.Nodes, NodeDiff{Sort: i + 1, Change: NodeChangeRemoved, Name: from.Nodes[i].Name})
+		default:
+			fields := compareNodes(&from.Nodes[i], from.NodeApprovers[from.Nodes[i].ID], &to.Nodes[i], to.NodeApprovers[to.Nodes[i].ID])
+			if len(fields) > 0 {
+				diff.Nodes = append(diff.Nodes, NodeDiff{Sort: i + 1, Change: NodeChangeChanged, Name: to.Nodes[i].Name, Fields: fields})
+			}
+		}
+	}
+
+	diff.CopyUsersAdded = subtractIDs(to.CopyUsers, from.CopyUsers)
+	diff.CopyUsersRemoved = subtractIDs(from.CopyUsers, to.CopyUsers)
+	return diff
+}
+
+// compareNodes 比较两个节点的配置字段，审批人不区分顺序
+func compareNodes(a *ApprovalFlowTemplateNode, aApprovers []int, b *ApprovalFlowTemplateNode, bApprovers []int) []FieldChange {
+	var fields []FieldChange
+	add := func(field string, from, to interface{}, changed bool) {
+		if changed {
+			fields = append(fields, FieldChange{Field: field, From: from, To: to})
+		}
+	}
+
+	add("name", a.Name, b.Name, a.Name != b.Name)
+	add("type", a.Type, b.Type, a.Type != b.Type)
+	add("approver_type", a.ApproverType, b.ApproverType, a.ApproverType != b.ApproverType)
+	add("approver_role_id", a.ApproverRoleID, b.ApproverRoleID, a.ApproverRoleID != b.ApproverRoleID)
+	add("approver_resolver", a.ApproverResolver, b.ApproverResolver, a.ApproverResolver != b.ApproverResolver)
+	aSorted, bSorted := sortedIDs(aApprovers), sortedIDs(bApprovers)
+	add("approvers", aSorted, bSorted, !equalIDs(aSorted, bSorted))
+	aCond, _ := json.Marshal(a.Conditions)
+	bCond, _ := json.Marshal(b.Conditions)
+	add("conditions", a.Conditions, b.Conditions, string(aCond) != string(bCond))
+	add("sla_hours", a.SLAHours, b.SLAHours, a.SLAHours != b.SLAHours)
+	add("sla_action", a.SLAAction, b.SLAAction, a.SLAAction != b.SLAAction)
+	add("sla_backup_id", a.SLABackupID, b.SLABackupID, !equalIntPtr(a.SLABackupID, b.SLABackupID))
+	return fields
+}
+
+func sortedIDs(ids []int) []int {
+	sorted := append([]int{}, ids...)
+	sort.Ints(sorted)
+	return sorted
+}
+
+func equalIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// subtractIDs 返回在 a 中但不在 b 中的ID（升序）
+func subtractIDs(a, b []int) []int {
+	exclude := make(map[int]bool, len(b))
+	for _, id := range b {
+		exclude[id] = true
+	}
+	result := []int{}
+	for _, id := range sortedIDs(a) {
+		if !exclude[id] {
+			result = append(result, id)
+			exclude[id] = true
+		}
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareTemplates(t *testing.T) {
+	backup := 9
+	from := TemplateSnapshot{
+		Template: ApprovalFlowTemplate{ID: 1, Name: "退费审批", Version: 1},
+		Nodes: []ApprovalFlowTemplateNode{
+			{ID: 11, Name: "主管审批", Sort: 1, Type: 0},
+			{ID: 12, Name: "财务审批", Sort: 2, Type: 1},
+			{ID: 13, Name: "总经理审批", Sort: 3},
+		},
+		NodeApprovers: map[int][]int{11: {3, 2}, 12: {5}, 13: {7}},
+		CopyUsers:     []int{20, 21},
+	}
+	to := TemplateSnapshot{
+		Template: ApprovalFlowTemplate{ID: 4, Name: "退费审批（新）", Version: 2},
+		Nodes: []ApprovalFlowTemplateNode{
+			{ID: 41, Name: "主管审批", Sort: 1, Type: 0},
+			{ID: 42, Name: "财务审批", Sort: 2, Type: 1, SLAHours: 24, SLAAction: SLAActionEscalate, SLABackupID: &backup,
+				Conditions: NodeConditions{{Field: PayloadFieldAmount, Op: ">=", Value: 1000.0}}},
+		},
+		NodeApprovers: map[int][]int{41: {2, 3}, 42: {5, 6}},
+		CopyUsers:     []int{21, 22},
+	}
+
+	diff := CompareTemplates(from, to)
+
+	if diff.FromVersion != 1 || diff.ToVersion != 2 {
+		t.Errorf("versions = %d -> %d, want 1 -> 2", diff.FromVersion, diff.ToVersion)
+	}
+	if len(diff.Fields) != 1 || diff.Fields[0].Field != "name" {
+		t.Errorf("Fields = %+v, want name change", diff.Fields)
+	}
+	if len(diff.Nodes) != 2 {
+		t.Fatalf("Nodes = %+v, want 2 changes (审批人顺序不同不算变化)", diff.Nodes)
+	}
+
+	changed := diff.Nodes[0]
+	if changed.Sort != 2 || changed.Change != NodeChangeChanged {
+		t.Errorf("Nodes[0] = %+v, want changed node at sort 2", changed)
+	}
+	var fields []string
+	for _, f := range changed.Fields {
+		fields = append(fields, f.Field)
+	}
+	wantFields := []string{"approvers", "conditions", "sla_hours", "sla_action", "sla_backup_id"}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("changed fields = %v, want %v", fields, wantFields)
+	}
+
+	if removed := diff.Nodes[1]; removed.Sort != 3 || removed.Change != NodeChangeRemoved || removed.Name != "总经理审批" {
+		t.Errorf("Nodes[1] = %+v, want removed node 总经理审批 at sort 3", removed)
+	}
+	if !reflect.DeepEqual(diff.CopyUsersAdded, []int{22}) || !reflect.DeepEqual(diff.CopyUsersRemoved, []int{20}) {
+		t.Errorf("copy users added %v removed %v, want [22] / [20]", diff.CopyUsersAdded, diff.CopyUsersRemoved)
+	}
+}
+
+func TestCompareTemplates_Identical(t *testing.T) {
+	snapshot := TemplateSnapshot{
+		Template:      ApprovalFlowTemplate{ID: 1, Name: "退费审批", Version: 1},
+		Nodes:         []ApprovalFlowTemplateNode{{ID: 11, Name: "主管审批", Sort: 1}},
+		NodeApprovers: map[int][]int{11: {2}},
+		CopyUsers:     []int{20},
+	}
+
+	diff := CompareTemplates(snapshot, snapshot)
+	if len(diff.Fields) != 0 || len(diff.Nodes) != 0 || len(diff.CopyUsersAdded) != 0 || len(diff.CopyUsersRemoved) != 0 {
+		t.Errorf("CompareTemplates() of identical snapshots = %+v, want no changes", diff)
+	}
+}
+
+func TestCompareTemplates_SelectionRulesOnly(t *testing.T) {
+	from := TemplateSnapshot{
+		Template: ApprovalFlowTemplate{ID: 1, Name: "退费审批", Version: 1,
+			SelectionRules: NodeConditions{{Field: PayloadFieldAmount, Op: ">=", Value: 1000.0}}},
+		Nodes:         []ApprovalFlowTemplateNode{{ID: 11, Name: "主管审批", Sort: 1}},
+		NodeApprovers: map[int][]int{11: {2}},
+	}
+	to := from
+	to.Template.ID, to.Template.Version = 4, 2
+	to.Template.SelectionRules = NodeConditions{{Field: PayloadFieldAmount, Op: ">=", Value: 5000.0}}
+	to.Nodes = []ApprovalFlowTemplateNode{{ID: 41, Name: "主管审批", Sort: 1}}
+	to.NodeApprovers = map[int][]int{41: {2}}
+
+	diff := CompareTemplates(from, to)
+	if len(diff.Fields) != 1 || diff.Fields[0].Field != "selection_rules" {
+		t.Fatalf("Fields = %+v, want only selection_rules change", diff.Fields)
+	}
+	if len(diff.Nodes) != 0 {
+		t.Errorf("Nodes = %+v, want no node changes", diff.Nodes)
+	}
+}
@@ -22,9 +22,6 @@ type ApprovalFlowManagementRepository interface {
 	// GetByID 根据ID查询审批流
 	GetByID(id int) (*entity.ApprovalFlowManagement, error)
 
-	// CreateFromTemplate 从模板创建审批流实例（事务：审批流、第一个节点、审批人员）
-	CreateFromTemplate(templateID int, userID int) (int, error)
-
 	// UpdateStatus 更新审批流状态
 	UpdateStatus(flowID int, status int8) error
 
@@ -17,11 +17,25 @@ type ApprovalFlowTemplateRepository interface {
 	Create(template *entity.ApprovalFlowTemplate, nodes []entity.ApprovalFlowTemplateNode,
 		nodeApprovers map[int][]int, copyUsers []int) error
 
+	// CreateVersion 以 base 为基础创建模板的新版本（事务：模板、节点、人员、抄送）
+	// base 必须是该模板的最新版本；base 启用时新版本替代它成为启用版本
+	CreateVersion(base *entity.ApprovalFlowTemplate, template *entity.ApprovalFlowTemplate,
+		nodes []entity.ApprovalFlowTemplateNode, nodeApprovers map[int][]int, copyUsers []int) error
+
+	// ListVersions 获取模板各版本及每个版本上审批中和全部的审批流数量
+	ListVersions(originTemplateID int) ([]map[string]interface{}, error)
+
+	// GetRunningFlows 获取绑定在指定模板版本上、仍在审批中的审批流
+	GetRunningFlows(templateID int) ([]map[string]interface{}, error)
+
+	// GetSnapshot 获取模板版本的完整配置（节点、指定审批人、抄送人员）
+	GetSnapshot(templateID int) (*entity.TemplateSnapshot, error)
+
 	// UpdateStatus 更新模板状态
 	UpdateStatus(id int, status int8) error
 
-	// DisableSameTypeTemplates 禁用同类型的其他模板
-	DisableSameTypeTemplates(typeID int, excludeID int) error
+	// DisableOtherVersions 禁用同一模板的其他版本（同类型可以有多个启用的模板，每个模板只启用一个版本）
+	DisableOtherVersions(originTemplateID int, excludeID int) error
 
 	// GetNodesByTemplateID 获取模板的所有节点（按sort排序）
 	GetNodesByTemplateID(templateID int) ([]entity.ApprovalFlowTemplateNode, error)
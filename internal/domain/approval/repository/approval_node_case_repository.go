@@ -16,9 +16,6 @@ type ApprovalNodeCaseRepository interface {
 	// GetNodeUserByID 根据ID获取审批人员记录
 	GetNodeUserByID(id int) (*entity.ApprovalNodeCaseUser, error)
 
-	// CreateNextNode 创建下一节点实例（事务：节点实例、审批人员记录）
-	CreateNextNode(flowID int, templateNodeID int, nodeType int8, sort int, approvers []int) error
-
 	// UpdateNodeResult 更新节点结果
 	UpdateNodeResult(nodeCaseID int, result int8) error
 
@@ -33,10 +30,4 @@ type ApprovalNodeCaseRepository interface {
 
 	// GetTemplateNodeByID 获取模板节点信息
 	GetTemplateNodeByID(nodeID int) (*entity.ApprovalFlowTemplateNode, error)
-
-	// GetNextTemplateNode 获取下一个模板节点
-	GetNextTemplateNode(templateID int, currentSort int) (*entity.ApprovalFlowTemplateNode, error)
-
-	// GetNodeApprovers 获取模板节点的审批人员
-	GetNodeApprovers(nodeID int) ([]int, error)
 }
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"charonoms/internal/domain/approval/entity"
+)
+
+// ErrNoApprover 节点没有可用的审批人（审批人全部离职/禁用，或解析器未返回任何人）
+var ErrNoApprover = errors.New("审批节点没有可用的审批人")
+
+// ApproverContext 解析审批人时可用的审批流信息
+type ApproverContext struct {
+	InitiatorID int         // 发起人账号ID
+	Business    BusinessRef // 关联的业务单据，手动发起的审批流为空
+}
+
+// ApproverResolverFunc 审批人解析函数，在创建节点实例的事务中调用，返回审批人账号ID
+type ApproverResolverFunc func(tx *gorm.DB, ctx ApproverContext) ([]int, error)
+
+// ApproverResolverSuperAdmin 超级管理员解析器名称
+const ApproverResolverSuperAdmin = "super_admin"
+
+// SuperAdminApprovers 解析超级管理员角色下的账号为审批人
+func SuperAdminApprovers(tx *gorm.DB, ctx ApproverContext) ([]int, error) {
+	var ids []int
+	err := tx.Raw(`
+		SELECT ua.id
+		FROM useraccount ua
+		INNER JOIN role r ON r.id = ua.role_id
+		WHERE r.is_super_admin = 1 AND r.status = 0
+	`).Scan(&ids).Error
+	return ids, err
+}
+
+// RegisterApproverResolver 注册审批人解析器，模板节点通过 approver_resolver 按名称引用，同名重复注册时覆盖
+func (s *ApprovalFlowService) RegisterApproverResolver(name string, resolver ApproverResolverFunc) {
+	s.approverResolvers[name] = resolver
+}
+
+// HasApproverResolver 判断审批人解析器是否已注册
+func (s *ApprovalFlowService) HasApproverResolver(name string) bool {
+	_, ok := s.approverResolvers[name]
+	return ok
+}
+
+// ResolveApprovers 按模板节点的审批人来源展开审批人账号ID，只保留启用的账号并去重
+// 没有可用的审批人时返回 ErrNoApprover，避免创建永远无法完成的节点
+func (s *ApprovalFlowService) ResolveApprovers(tx *gorm.DB, node *entity.ApprovalFlowTemplateNode, ctx ApproverContext) ([]int, error) {
+	var candidates []int
+	switch node.ApproverType {
+	case entity.ApproverTypeUser:
+		if err := tx.Raw(`
+			SELECT useraccount_id
+			FROM approval_node_useraccount
+			WHERE node_id = ?
+		`, node.ID).Scan(&candidates).Error; err != nil {
+			return nil, fmt.Errorf("查询节点审批人失败: %w", err)
+		}
+
+	case entity.ApproverTypeRole:
+		if err := tx.Raw(`
+			SELECT id
+			FROM useraccount
+			WHERE role_id = ?
+		`, node.ApproverRoleID).Scan(&candidates).Error; err != nil {
+			return nil, fmt.Errorf("查询角色账号失败: %w", err)
+		}
+
+	case entity.ApproverTypeManager:
+		var initiator struct {
+			ManagerID *int
+		}
+		if err := tx.Raw(`
+			SELECT manager_id
+			FROM useraccount
+			WHERE id = ?
+		`, ctx.InitiatorID).Scan(&initiator).Error; err != nil {
+			return nil, fmt.Errorf("查询发起人上级失败: %w", err)
+		}
+		if initiator.ManagerID == nil || *initiator.ManagerID == 0 {
+			return nil, fmt.Errorf("%w: 节点「%s」按发起人上级审批，但发起人未设置上级", ErrNoApprover, node.Name)
+		}
+		candidates = []int{*initiator.ManagerID}
+
+	case entity.ApproverTypeResolver:
+		resolver, ok := s.approverResolvers[node.ApproverResolver]
+		if !ok {
+			return nil, fmt.Errorf("%w: 节点「%s」的审批人解析器 %s 未注册", ErrNoApprover, node.Name, node.ApproverResolver)
+		}
+		ids, err := resolver(tx, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("节点「%s」解析审批人失败: %w", node.Name, err)
+		}
+		candidates = ids
+
+	default:
+		return nil, fmt.Errorf("节点「%s」的审批人来源无效: %d", node.Name, node.ApproverType)
+	}
+
+	approvers, err := activeAccounts(tx, candidates)
+	if err != nil {
+		return nil, err
+	}
+	if len(approvers) == 0 {
+		return nil, fmt.Errorf("%w: 节点「%s」", ErrNoApprover, node.Name)
+	}
+	return approvers, nil
+}
+
+// activeAccounts 过滤出启用的账号（status=0），保持原有顺序并去重
+func activeAccounts(tx *gorm.DB, ids []int) ([]int, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var activeIDs []int
+	if err := tx.Raw(`
+		SELECT id
+		FROM useraccount
+		WHERE id IN ? AND status = 0
+	`, ids).Scan(&activeIDs).Error; err != nil {
+		return nil, fmt.Errorf("查询审批人账号失败: %w", err)
+	}
+	active := make(map[int]bool, len(activeIDs))
+	for _, id := range activeIDs {
+		active[id] = true
+	}
+
+	result := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if active[id] {
+			result = append(result, id)
+			delete(active, id)
+		}
+	}
+	return result, nil
+}
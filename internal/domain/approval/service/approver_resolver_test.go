@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+
+	"charonoms/internal/domain/approval/entity"
+)
+
+func expectActiveAccounts(mock sqlmock.Sqlmock, activeIDs ...int) {
+	rows := sqlmock.NewRows([]string{"id"})
+	for _, id := range activeIDs {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery("SELECT id\\s+FROM useraccount\\s+WHERE id IN \\(.*\\) AND status = 0").WillReturnRows(rows)
+}
+
+func TestResolveApprovers_Role(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	node := &entity.ApprovalFlowTemplateNode{ID: 1, Name: "财务审批", ApproverType: entity.ApproverTypeRole, ApproverRoleID: 3}
+
+	mock.ExpectQuery("SELECT id\\s+FROM useraccount\\s+WHERE role_id = \\?").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10).AddRow(11).AddRow(12))
+	// 账号11已离职禁用
+	expectActiveAccounts(mock, 10, 12)
+
+	got, err := s.ResolveApprovers(db, node, ApproverContext{InitiatorID: 7})
+	if err != nil {
+		t.Fatalf("ResolveApprovers() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != 10 || got[1] != 12 {
+		t.Errorf("ResolveApprovers() = %v, want [10 12]", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestResolveApprovers_Manager(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	node := &entity.ApprovalFlowTemplateNode{ID: 1, Name: "上级审批", ApproverType: entity.ApproverTypeManager}
+
+	mock.ExpectQuery("SELECT manager_id\\s+FROM useraccount\\s+WHERE id = \\?").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"manager_id"}).AddRow(5))
+	expectActiveAccounts(mock, 5)
+
+	got, err := s.ResolveApprovers(db, node, ApproverContext{InitiatorID: 7})
+	if err != nil {
+		t.Fatalf("ResolveApprovers() error = %v", err)
+	}
+	if len(got) != 1 || got[0] != 5 {
+		t.Errorf("ResolveApprovers() = %v, want [5]", got)
+	}
+}
+
+func TestResolveApprovers_ManagerNotSet(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	node := &entity.ApprovalFlowTemplateNode{ID: 1, Name: "上级审批", ApproverType: entity.ApproverTypeManager}
+
+	mock.ExpectQuery("SELECT manager_id").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"manager_id"}).AddRow(nil))
+
+	if _, err := s.ResolveApprovers(db, node, ApproverContext{InitiatorID: 7}); !errors.Is(err, ErrNoApprover) {
+		t.Fatalf("ResolveApprovers() error = %v, want ErrNoApprover", err)
+	}
+}
+
+func TestResolveApprovers_ResolverReturnsNobody(t *testing.T) {
+	db, _ := setupMockDB(t)
+	s := newTestFlowService(db)
+	s.RegisterApproverResolver("test_nobody", func(tx *gorm.DB, ctx ApproverContext) ([]int, error) {
+		return nil, nil
+	})
+	node := &entity.ApprovalFlowTemplateNode{ID: 1, Name: "校区负责人", ApproverType: entity.ApproverTypeResolver, ApproverResolver: "test_nobody"}
+
+	if _, err := s.ResolveApprovers(db, node, ApproverContext{InitiatorID: 7}); !errors.Is(err, ErrNoApprover) {
+		t.Fatalf("ResolveApprovers() error = %v, want ErrNoApprover", err)
+	}
+}
+
+func TestResolveApprovers_ResolverReceivesBusiness(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	var got ApproverContext
+	s.RegisterApproverResolver("test_business", func(tx *gorm.DB, ctx ApproverContext) ([]int, error) {
+		got = ctx
+		return []int{8, 8, 9}, nil
+	})
+	node := &entity.ApprovalFlowTemplateNode{ID: 1, Name: "校区负责人", ApproverType: entity.ApproverTypeResolver, ApproverResolver: "test_business"}
+	expectActiveAccounts(mock, 8, 9)
+
+	approvers, err := s.ResolveApprovers(db, node, ApproverContext{InitiatorID: 7, Business: BusinessRef{Type: BusinessTypeRefund, ID: 42}})
+	if err != nil {
+		t.Fatalf("ResolveApprovers() error = %v", err)
+	}
+	if got.InitiatorID != 7 || got.Business.Type != BusinessTypeRefund || got.Business.ID != 42 {
+		t.Errorf("resolver context = %+v, want initiator 7 and refund#42", got)
+	}
+	if len(approvers) != 2 || approvers[0] != 8 || approvers[1] != 9 {
+		t.Errorf("ResolveApprovers() = %v, want deduplicated [8 9]", approvers)
+	}
+}
+
+func TestResolveApprovers_AllFixedUsersDisabled(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	node := &entity.ApprovalFlowTemplateNode{ID: 4, Name: "校区审批", ApproverType: entity.ApproverTypeUser}
+
+	mock.ExpectQuery("SELECT useraccount_id\\s+FROM approval_node_useraccount\\s+WHERE node_id = \\?").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"useraccount_id"}).AddRow(20))
+	expectActiveAccounts(mock)
+
+	if _, err := s.ResolveApprovers(db, node, ApproverContext{InitiatorID: 7}); !errors.Is(err, ErrNoApprover) {
+		t.Fatalf("ResolveApprovers() error = %v, want ErrNoApprover", err)
+	}
+}
+
+func TestRegisterApproverResolver_PerService(t *testing.T) {
+	db, _ := setupMockDB(t)
+	s := newTestFlowService(db)
+	other := newTestFlowService(db)
+
+	s.RegisterApproverResolver("test_scoped", func(tx *gorm.DB, ctx ApproverContext) ([]int, error) {
+		return []int{1}, nil
+	})
+	if !s.HasApproverResolver("test_scoped") {
+		t.Error("HasApproverResolver() = false on the registering service, want true")
+	}
+	if other.HasApproverResolver("test_scoped") {
+		t.Error("HasApproverResolver() = true on another service, want resolvers scoped per service")
+	}
+}
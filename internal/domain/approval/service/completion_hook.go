@@ -0,0 +1,68 @@
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"charonoms/internal/infrastructure/logger"
+)
+
+// CompletionEvent 审批流完成事件
+type CompletionEvent int
+
+const (
+	CompletionApproved  CompletionEvent = iota // 审批通过
+	CompletionRejected                         // 审批驳回
+	CompletionCancelled                        // 发起人撤销
+)
+
+// CompletionHook 审批完成钩子，由各业务模块实现并按业务类型注册
+// 钩子在审批引擎的事务中执行，返回错误时整个事务回滚；businessID 为审批流关联的业务单据ID
+type CompletionHook interface {
+	OnApproved(tx *gorm.DB, businessID int) error
+	OnRejected(tx *gorm.DB, businessID int) error
+	OnCancelled(tx *gorm.DB, businessID int) error
+}
+
+// RegisterCompletionHook 注册业务类型的审批完成钩子，同一业务类型重复注册时覆盖
+func (s *ApprovalFlowService) RegisterCompletionHook(businessType string, hook CompletionHook) {
+	s.completionHooks[businessType] = hook
+}
+
+// dispatchCompletionTx 在调用方事务中按审批流关联的业务类型分发完成事件
+// 未关联业务单据的审批流（如手工发起的审批）不做处理；关联了业务单据但业务类型未注册钩子时返回错误，整个事务回滚，避免审批流结束而业务单据未处理
+func (s *ApprovalFlowService) dispatchCompletionTx(tx *gorm.DB, flowID int, event CompletionEvent) error {
+	var business struct {
+		BusinessType string `gorm:"column:business_type"`
+		BusinessID   int    `gorm:"column:business_id"`
+	}
+	err := tx.Table("approval_flow_management").
+		Select("COALESCE(business_type, '') AS business_type, COALESCE(business_id, 0) AS business_id").
+		Where("id = ?", flowID).
+		Scan(&business).Error
+	if err != nil {
+		return fmt.Errorf("查询审批流关联业务失败: %w", err)
+	}
+
+	if business.BusinessType == "" || business.BusinessID == 0 {
+		logger.Debug("Approval flow has no linked business, skip completion hook", zap.Int("flow_id", flowID))
+		return nil
+	}
+
+	hook, ok := s.completionHooks[business.BusinessType]
+	if !ok {
+		return fmt.Errorf("业务类型 %s 未注册审批完成钩子", business.BusinessType)
+	}
+
+	switch event {
+	case CompletionApproved:
+		return hook.OnApproved(tx, business.BusinessID)
+	case CompletionRejected:
+		return hook.OnRejected(tx, business.BusinessID)
+	case CompletionCancelled:
+		return hook.OnCancelled(tx, business.BusinessID)
+	}
+	return fmt.Errorf("未知的审批完成事件: %d", event)
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// setupMockDB 创建基于sqlmock的gorm连接
+func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm db: %v", err)
+	}
+
+	return gormDB, mock
+}
+
+// recordingHook 记录回调的测试钩子，err 非空时回调返回该错误
+type recordingHook struct {
+	calls []string
+	ids   []int
+	err   error
+}
+
+func (h *recordingHook) record(event string, businessID int) error {
+	h.calls = append(h.calls, event)
+	h.ids = append(h.ids, businessID)
+	return h.err
+}
+
+func (h *recordingHook) OnApproved(tx *gorm.DB, businessID int) error {
+	return h.record("approved", businessID)
+}
+
+func (h *recordingHook) OnRejected(tx *gorm.DB, businessID int) error {
+	return h.record("rejected", businessID)
+}
+
+func (h *recordingHook) OnCancelled(tx *gorm.DB, businessID int) error {
+	return h.record("cancelled", businessID)
+}
+
+func newTestFlowService(db *gorm.DB) *ApprovalFlowService {
+	return NewApprovalFlowService(nil, nil, nil, db)
+}
+
+// completeInTx 在事务中执行审批流完成回调，与审批引擎调用方式一致
+func completeInTx(s *ApprovalFlowService, flowID int, approved bool) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.handleApprovalCompleteTx(tx, flowID, approved)
+	})
+}
+
+func expectBusinessQuery(mock sqlmock.Sqlmock, flowID int, businessType string, businessID int) {
+	mock.ExpectQuery("SELECT COALESCE\\(business_type, ''\\) AS business_type, COALESCE\\(business_id, 0\\) AS business_id FROM `approval_flow_management` WHERE id = \\?").
+		WithArgs(flowID).
+		WillReturnRows(sqlmock.NewRows([]string{"business_type", "business_id"}).AddRow(businessType, businessID))
+}
+
+func TestDispatchCompletion_CallsRegisteredHook(t *testing.T) {
+	tests := []struct {
+		name     string
+		approved bool
+		want     string
+	}{
+		{"审批通过回调OnApproved", true, "approved"},
+		{"审批驳回回调OnRejected", false, "rejected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			s := newTestFlowService(db)
+			hook := &recordingHook{}
+			s.RegisterCompletionHook(BusinessTypeRefund, hook)
+
+			mock.ExpectBegin()
+			expectBusinessQuery(mock, 5, BusinessTypeRefund, 42)
+			mock.ExpectCommit()
+
+			if err := completeInTx(s, 5, tt.approved); err != nil {
+				t.Fatalf("handleApprovalComplete() error = %v", err)
+			}
+			if len(hook.calls) != 1 || hook.calls[0] != tt.want || hook.ids[0] != 42 {
+				t.Errorf("hook calls = %v ids = %v, want [%s] [42]", hook.calls, hook.ids, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestDispatchCompletion_HookErrorRollsBack(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	hookErr := errors.New("退费处理失败")
+	s.RegisterCompletionHook(BusinessTypeRefund, &recordingHook{err: hookErr})
+
+	// 钩子返回错误时事务回滚，错误原样返回给审批引擎
+	mock.ExpectBegin()
+	expectBusinessQuery(mock, 5, BusinessTypeRefund, 42)
+	mock.ExpectRollback()
+
+	if err := completeInTx(s, 5, true); !errors.Is(err, hookErr) {
+		t.Fatalf("handleApprovalComplete() error = %v, want %v", err, hookErr)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestDispatchCompletion_SkipsUnlinked(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	hook := &recordingHook{}
+	s.RegisterCompletionHook(BusinessTypeRefund, hook)
+
+	// 未关联业务单据的审批流（手工发起）不回调钩子
+	mock.ExpectBegin()
+	expectBusinessQuery(mock, 5, "", 0)
+	mock.ExpectCommit()
+
+	if err := completeInTx(s, 5, true); err != nil {
+		t.Fatalf("handleApprovalCompleteTx() error = %v", err)
+	}
+	if len(hook.calls) != 0 {
+		t.Errorf("hook calls = %v, want none", hook.calls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestDispatchCompletion_UnregisteredBusinessTypeRollsBack(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	s.RegisterCompletionHook(BusinessTypeRefund, &recordingHook{})
+
+	// 关联了业务单据但业务类型没有钩子：返回错误，审批流不能在业务单据未处理时结束
+	mock.ExpectBegin()
+	expectBusinessQuery(mock, 5, "contract", 9)
+	mock.ExpectRollback()
+
+	if err := completeInTx(s, 5, true); err == nil {
+		t.Fatal("handleApprovalCompleteTx() error = nil, want error for unregistered business type")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// expectLockedDecisionTask 审批前按审批流、节点实例、审批人员记录的顺序加锁
+func expectLockedDecisionTask(mock sqlmock.Sqlmock, nodeCaseType int8) {
+	taskRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "approval_node_case_id", "useraccount_id", "result"}).AddRow(11, 3, 7, nil)
+	}
+	nodeCaseRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "node_id", "approval_flow_management_id", "type", "sort", "result"}).
+			AddRow(3, 21, 5, nodeCaseType, 1, nil)
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case_user` WHERE `approval_node_case_user`.`id` = \\?").
+		WillReturnRows(taskRows())
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case` WHERE `approval_node_case`.`id` = \\?").
+		WillReturnRows(nodeCaseRows())
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_management` .*FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_flow_template_id", "create_user", "status"}).AddRow(5, 8, 2, 0))
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case` .*FOR UPDATE").
+		WillReturnRows(nodeCaseRows())
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case_user` .*FOR UPDATE").
+		WillReturnRows(taskRows())
+}
+
+func TestProcessApprove_HookFailureRollsBackWholeDecision(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	hookErr := errors.New("退费处理失败")
+	s.RegisterCompletionHook(BusinessTypeRefund, &recordingHook{err: hookErr})
+
+	// 或签节点通过且没有下一节点：审批结果、节点结果、审批流完成和完成钩子在同一事务中，钩子失败时全部回滚
+	expectLockedDecisionTask(mock, 1)
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `approval_node_case`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("UPDATE `approval_node_case_user` SET").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `approval_flow_action`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case_user` WHERE approval_node_case_id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_node_case_id", "useraccount_id", "result"}).
+			AddRow(11, 3, 7, 0).AddRow(12, 3, 9, nil))
+	mock.ExpectExec("DELETE FROM `approval_node_case_user`").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `approval_node_case` SET").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `approval_node_case`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_management` WHERE `approval_flow_management`.`id` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_flow_template_id", "create_user", "status"}).AddRow(5, 8, 2, 0))
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_template_node` WHERE `approval_flow_template_node`.`id` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "template_id", "sort"}).AddRow(21, 8, 1))
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_template_node` WHERE template_id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "template_id", "sort"}).AddRow(21, 8, 1))
+	mock.ExpectExec("UPDATE `approval_flow_management` SET `complete_time`=\\?,`status`=\\?").
+		WithArgs(sqlmock.AnyArg(), 10, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT \\* FROM `approval_copy_useraccount` WHERE approval_flow_template_id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_flow_template_id", "useraccount_id"}))
+	expectBusinessQuery(mock, 5, BusinessTypeRefund, 42)
+	mock.ExpectRollback()
+
+	if err := s.ProcessApprove(11, Decision{}); !errors.Is(err, hookErr) {
+		t.Fatalf("ProcessApprove() error = %v, want %v", err, hookErr)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestProcessApprove_TaskRemovedWhileWaitingForLock(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+
+	// 同一或签节点的另一审批人先完成审批并移除了本审批任务
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case_user`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_node_case_id", "useraccount_id", "result"}).AddRow(11, 3, 7, nil))
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_flow_management_id", "type"}).AddRow(3, 5, 1))
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_management` .*FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(5, 0))
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case` .*FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_flow_management_id", "type", "result"}).AddRow(3, 5, 1, 0))
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case_user` .*FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	err := s.ProcessApprove(11, Decision{})
+	if err == nil || err.Error() != "该审批已处理" {
+		t.Fatalf("ProcessApprove() error = %v, want 该审批已处理", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestProcessCancel_CallsOnCancelledInSameTransaction(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	hook := &recordingHook{}
+	s.RegisterCompletionHook(BusinessTypeOrderAmendment, hook)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_management` WHERE `approval_flow_management`.`id` = \\? .*FOR UPDATE").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "create_user", "status"}).AddRow(5, 7, 0))
+	mock.ExpectExec("UPDATE `approval_flow_management` SET `complete_time`=\\?,`status`=\\? WHERE id = \\?").
+		WithArgs(sqlmock.AnyArg(), 99, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM approval_node_case_user").
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO `approval_flow_action`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectBusinessQuery(mock, 5, BusinessTypeOrderAmendment, 3)
+	mock.ExpectCommit()
+
+	if err := s.ProcessCancel(5, 7); err != nil {
+		t.Fatalf("ProcessCancel() error = %v", err)
+	}
+	if len(hook.calls) != 1 || hook.calls[0] != "cancelled" || hook.ids[0] != 3 {
+		t.Errorf("hook calls = %v ids = %v, want [cancelled] [3]", hook.calls, hook.ids)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestProcessCancel_OnlyCreator(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	hook := &recordingHook{}
+	s.RegisterCompletionHook(BusinessTypeOrderAmendment, hook)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_management`").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "create_user", "status"}).AddRow(5, 7, 0))
+	mock.ExpectRollback()
+
+	if err := s.ProcessCancel(5, 8); err == nil {
+		t.Fatal("ProcessCancel() error = nil, want error for non-creator")
+	}
+	if len(hook.calls) != 0 {
+		t.Errorf("hook calls = %v, want none", hook.calls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
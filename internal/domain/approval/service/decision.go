@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"charonoms/internal/domain/approval/entity"
+)
+
+// 审批意见限制
+const (
+	maxDecisionCommentLength = 500 // 审批意见最大字数
+	maxDecisionAttachments   = 10  // 每次审批最多关联的附件数
+)
+
+// Decision 审批人的处理意见：驳回必须填写意见，通过时可选；附件为预先上传的附件ID
+type Decision struct {
+	Comment       string
+	AttachmentIDs []int
+}
+
+// validate 校验审批意见，reject 为 true 时意见必填
+func (d Decision) validate(reject bool) error {
+	if reject && d.Comment == "" {
+		return errors.New("驳回时必须填写审批意见")
+	}
+	if utf8.RuneCountInString(d.Comment) > maxDecisionCommentLength {
+		return fmt.Errorf("审批意见不能超过%d字", maxDecisionCommentLength)
+	}
+	if len(d.AttachmentIDs) > maxDecisionAttachments {
+		return fmt.Errorf("每次审批最多上传%d个附件", maxDecisionAttachments)
+	}
+	return nil
+}
+
+// checkDecisionAttachments 检查附件均由操作人上传且尚未关联到其他审批操作
+func checkDecisionAttachments(tx *gorm.DB, attachmentIDs []int, operatorID int) error {
+	ids := uniqueIDs(attachmentIDs)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(&entity.ApprovalAttachment{}).
+		Where("id IN ? AND uploader_id = ? AND approval_flow_action_id IS NULL", ids, operatorID).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("查询审批附件失败: %w", err)
+	}
+	if int(count) != len(ids) {
+		return errors.New("附件不存在或已被使用")
+	}
+	return nil
+}
+
+// recordDecision 在事务中记录审批操作（审批意见写入备注），并把附件关联到该操作
+func recordDecision(tx *gorm.DB, action entity.ApprovalFlowAction, decision Decision) error {
+	action.Remark = decision.Comment
+	if err := tx.Create(&action).Error; err != nil {
+		return fmt.Errorf("记录审批操作失败: %w", err)
+	}
+
+	ids := uniqueIDs(decision.AttachmentIDs)
+	if len(ids) == 0 {
+		return nil
+	}
+	// 条件更新防止同一附件被并发的两次审批同时使用
+	result := tx.Model(&entity.ApprovalAttachment{}).
+		Where("id IN ? AND uploader_id = ? AND approval_flow_action_id IS NULL", ids, action.OperatorID).
+		Update("approval_flow_action_id", action.ID)
+	if result.Error != nil {
+		return fmt.Errorf("关联审批附件失败: %w", result.Error)
+	}
+	if int(result.RowsAffected) != len(ids) {
+		return errors.New("附件不存在或已被使用")
+	}
+	return nil
+}
+
+// uniqueIDs 去除重复和无效的ID，保持原有顺序
+func uniqueIDs(ids []int) []int {
+	result := make([]int, 0, len(ids))
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if id <= 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+
+	"charonoms/internal/domain/approval/entity"
+)
+
+func TestDecision_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		decision Decision
+		reject   bool
+		wantErr  bool
+	}{
+		{"通过不填意见", Decision{}, false, false},
+		{"通过填写意见", Decision{Comment: "同意"}, false, false},
+		{"驳回不填意见", Decision{}, true, true},
+		{"驳回填写意见", Decision{Comment: "金额与合同不符"}, true, false},
+		{"意见超长", Decision{Comment: strings.Repeat("长", maxDecisionCommentLength+1)}, false, true},
+		{"附件过多", Decision{AttachmentIDs: make([]int, maxDecisionAttachments+1)}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.decision.validate(tt.reject)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessReject_RequiresComment(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+
+	if err := s.ProcessReject(11, Decision{}); err == nil {
+		t.Fatal("ProcessReject() error = nil, want error without comment")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unexpected queries: %v", err)
+	}
+}
+
+func TestRecordDecision_LinksAttachments(t *testing.T) {
+	db, mock := setupMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `approval_flow_action`").
+		WillReturnResult(sqlmock.NewResult(31, 1))
+	mock.ExpectExec("UPDATE `approval_attachment` SET `approval_flow_action_id`=\\? WHERE id IN \\(\\?,\\?\\) AND uploader_id = \\? AND approval_flow_action_id IS NULL").
+		WithArgs(31, 4, 5, 7).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return recordDecision(tx, entity.ApprovalFlowAction{
+			ApprovalFlowManagementID: 5,
+			Action:                   entity.FlowActionReject,
+			OperatorID:               7,
+		}, Decision{Comment: "缺少发票", AttachmentIDs: []int{4, 5, 4}})
+	})
+	if err != nil {
+		t.Fatalf("recordDecision() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestRecordDecision_AttachmentAlreadyUsed(t *testing.T) {
+	db, mock := setupMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `approval_flow_action`").
+		WillReturnResult(sqlmock.NewResult(31, 1))
+	mock.ExpectExec("UPDATE `approval_attachment`").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return recordDecision(tx, entity.ApprovalFlowAction{
+			ApprovalFlowManagementID: 5,
+			Action:                   entity.FlowActionApprove,
+			OperatorID:               7,
+		}, Decision{AttachmentIDs: []int{4, 5}})
+	})
+	if err == nil {
+		t.Fatal("recordDecision() error = nil, want error when an attachment is already used")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"charonoms/internal/domain/approval/entity"
+	"charonoms/internal/infrastructure/logger"
+)
+
+// 审批流类型编码（对应 approval_flow_type.code），业务单据按类型编码选择模板发起审批并在审批完成后回调
+const (
+	FlowTypeCodeRefund         = entity.FlowTypeCodeRefund
+	FlowTypeCodeOrderAmendment = entity.FlowTypeCodeOrderAmendment
+)
+
+// 审批流关联的业务类型，审批完成时按业务类型分发给注册的完成处理器
+const (
+	BusinessTypeRefund         = entity.BusinessTypeRefund
+	BusinessTypeOrderAmendment = entity.BusinessTypeOrderAmendment
+)
+
+// BusinessRef 审批流关联的业务单据（业务ID即业务单据主键）
+// Payload 为节点条件判断使用的业务数据，如退费金额、收款渠道
+type BusinessRef struct {
+	Type    string
+	ID      int
+	Payload entity.BusinessPayload
+}
+
+// FlowTemplateRef 启用中的审批流模板
+type FlowTemplateRef struct {
+	TemplateID int
+	FlowTypeID int
+}
+
+// SelectTemplate 按审批流类型编码和业务数据选择模板：在该类型启用的模板中选出适用条件满足的模板
+// 没有模板匹配或匹配到多个模板时返回错误，需要调整模板的适用条件
+func SelectTemplate(db *gorm.DB, flowTypeCode string, payload entity.BusinessPayload) (*FlowTemplateRef, error) {
+	var templates []entity.ApprovalFlowTemplate
+	err := db.Table("approval_flow_template aft").
+		Select("aft.*").
+		Joins("INNER JOIN approval_flow_type aftype ON aftype.id = aft.approval_flow_type_id").
+		Where("aftype.code = ? AND aftype.status = 0 AND aft.status = 0", flowTypeCode).
+		Order("aft.id ASC").
+		Find(&templates).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询审批流模板失败: %w", err)
+	}
+	return matchTemplate(templates, flowTypeCode, payload)
+}
+
+// matchTemplate 从启用的模板中选出唯一满足适用条件的模板
+func matchTemplate(templates []entity.ApprovalFlowTemplate, flowTypeCode string, payload entity.BusinessPayload) (*FlowTemplateRef, error) {
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("未找到启用的审批流模板（类型编码 %s）", flowTypeCode)
+	}
+
+	var matched []entity.ApprovalFlowTemplate
+	for _, t := range templates {
+		if t.SelectionRules.Match(payload) {
+			matched = append(matched, t)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return nil, fmt.Errorf("没有适用于该业务单据的审批流模板（类型编码 %s），请检查模板的适用条件", flowTypeCode)
+	case 1:
+		return &FlowTemplateRef{TemplateID: matched[0].ID, FlowTypeID: matched[0].ApprovalFlowTypeID}, nil
+	}
+	names := make([]string, 0, len(matched))
+	for _, t := range matched {
+		names = append(names, fmt.Sprintf("「%s」(#%d)", t.Name, t.ID))
+	}
+	return nil, fmt.Errorf("有多个审批流模板适用于该业务单据：%s，请调整模板的适用条件", strings.Join(names, "、"))
+}
+
+// StartFlowInstance 在调用方事务中按模板创建审批流实例及第一个节点的审批人记录，返回审批流ID
+// business 为审批流关联的业务单据，审批完成时据此回调对应的完成处理器
+func (s *ApprovalFlowService) StartFlowInstance(tx *gorm.DB, templateID int, flowTypeID int, createUserID int, business BusinessRef) (int, error) {
+	// 1. 按业务数据选出第一个满足条件的审批节点
+	var nodes []entity.ApprovalFlowTemplateNode
+	if err := tx.Where("template_id = ?", templateID).Order("sort ASC").Find(&nodes).Error; err != nil {
+		return 0, fmt.Errorf("查询审批节点失败: %w", err)
+	}
+	firstNode := entity.NextTemplateNode(nodes, 0, business.Payload)
+	if firstNode == nil {
+		return 0, fmt.Errorf("审批流模板没有满足条件的审批节点")
+	}
+
+	// 按节点的审批人来源展开审批人，没有可用审批人时直接失败
+	approverIDs, err := s.ResolveApprovers(tx, firstNode, ApproverContext{InitiatorID: createUserID, Business: business})
+	if err != nil {
+		return 0, err
+	}
+
+	payload, err := json.Marshal(business.Payload)
+	if err != nil {
+		return 0, fmt.Errorf("序列化业务条件数据失败: %w", err)
+	}
+
+	// 2. 创建审批流管理记录
+	result := tx.Exec(`
+		INSERT INTO approval_flow_management
+		(approval_flow_template_id, approval_flow_type_id, step, create_user, status, create_time, business_type, business_id, business_payload)
+		VALUES (?, ?, 0, ?, 0, NOW(), ?, ?, ?)
+	`, templateID, flowTypeID, createUserID, business.Type, business.ID, string(payload))
+	if result.Error != nil {
+		return 0, fmt.Errorf("创建审批流失败: %w", result.Error)
+	}
+
+	// 获取刚插入的ID
+	var flowID int64
+	if err := tx.Raw("SELECT LAST_INSERT_ID()").Scan(&flowID).Error; err != nil {
+		return 0, fmt.Errorf("获取审批流ID失败: %w", err)
+	}
+
+	// 3. 创建第一个审批节点实例及审批人记录（外出审批人自动替换为委托人）
+	if _, err := createNodeCase(tx, nodeCaseSpec{
+		FlowID:         int(flowID),
+		TemplateNodeID: firstNode.ID,
+		Type:           firstNode.Type,
+		Sort:           firstNode.Sort,
+		Deadline:       firstNode.SLADeadline(time.Now()),
+		Approvers:      approverIDs,
+	}); err != nil {
+		return 0, err
+	}
+
+	logger.Debug("Approval flow instance started",
+		zap.Int64("flow_id", flowID),
+		zap.Int("template_id", templateID),
+		zap.Int("first_node_id", firstNode.ID),
+		zap.Ints("approver_ids", approverIDs),
+		zap.String("business_type", business.Type),
+		zap.Int("business_id", business.ID))
+	return int(flowID), nil
+}
+
+// ResubmitFlowInstance 在调用方事务中为已驳回或已撤销的审批流发起新版本，返回新审批流ID
+// 新版本关联原审批流（previous_flow_id）、版本号递增，并在新审批流上记录重新提交操作；
+// 只有原发起人可以重新提交，同一审批流只能重新提交一次，业务单据由调用方按修改后的内容重新创建
+func (s *ApprovalFlowService) ResubmitFlowInstance(tx *gorm.DB, previousFlowID int, template FlowTemplateRef, createUserID int, business BusinessRef, remark string) (int, error) {
+	var previous entity.ApprovalFlowManagement
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&previous, previousFlowID).Error; err != nil {
+		return 0, fmt.Errorf("原审批流不存在: %w", err)
+	}
+	if previous.CreateUser != createUserID {
+		return 0, errors.New("只有发起人可以重新提交审批流")
+	}
+	if !previous.IsResubmittable() {
+		return 0, errors.New("只能重新提交已驳回或已撤销的审批流")
+	}
+	if previous.BusinessType != business.Type || previous.ApprovalFlowTypeID != template.FlowTypeID {
+		return 0, errors.New("重新提交的业务类型与原审批流不一致")
+	}
+	var resubmitted int64
+	if err := tx.Model(&entity.ApprovalFlowManagement{}).
+		Where("previous_flow_id = ?", previousFlowID).
+		Count(&resubmitted).Error; err != nil {
+		return 0, fmt.Errorf("查询审批流版本失败: %w", err)
+	}
+	if resubmitted > 0 {
+		return 0, errors.New("该审批流已重新提交过，请在最新版本上操作")
+	}
+
+	flowID, err := s.StartFlowInstance(tx, template.TemplateID, template.FlowTypeID, createUserID, business)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Model(&entity.ApprovalFlowManagement{}).
+		Where("id = ?", flowID).
+		Updates(map[string]interface{}{
+			"version":          previous.Version + 1,
+			"previous_flow_id": previousFlowID,
+		}).Error; err != nil {
+		return 0, fmt.Errorf("关联原审批流失败: %w", err)
+	}
+	if err := recordAction(tx, entity.ApprovalFlowAction{
+		ApprovalFlowManagementID: flowID,
+		Action:                   entity.FlowActionResubmit,
+		OperatorID:               createUserID,
+		Remark:                   remark,
+	}); err != nil {
+		return 0, err
+	}
+	return flowID, nil
+}
+
+// StartFromTemplate 手动从模板发起审批流（不关联业务单据），并创建抄送记录
+func (s *ApprovalFlowService) StartFromTemplate(templateID int, userID int) (int, error) {
+	var flowID int
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var template entity.ApprovalFlowTemplate
+		if err := tx.First(&template, templateID).Error; err != nil {
+			return fmt.Errorf("获取模板信息失败: %w", err)
+		}
+		if template.Status != 0 {
+			return fmt.Errorf("模板已禁用，无法创建审批流")
+		}
+
+		id, err := s.StartFlowInstance(tx, templateID, template.ApprovalFlowTypeID, userID, BusinessRef{})
+		if err != nil {
+			return err
+		}
+		flowID = id
+
+		var copyUsers []entity.ApprovalCopyUserAccount
+		if err := tx.Where("approval_flow_template_id = ?", templateID).Find(&copyUsers).Error; err != nil {
+			return fmt.Errorf("获取抄送人员失败: %w", err)
+		}
+		for _, copyUser := range copyUsers {
+			copyCase := entity.ApprovalCopyUserAccountCase{
+				ApprovalFlowManagementID: flowID,
+				UserAccountID:            copyUser.UserAccountID,
+				CopyInfo:                 "审批流已创建",
+			}
+			if err := tx.Create(&copyCase).Error; err != nil {
+				return fmt.Errorf("创建抄送记录失败: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return flowID, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"charonoms/internal/domain/approval/entity"
+)
+
+func expectLockedFlow(mock sqlmock.Sqlmock, flowID int, createUser int, status int8, businessType string) {
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_management` WHERE `approval_flow_management`.`id` = \\? ORDER BY .* FOR UPDATE").
+		WithArgs(flowID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_flow_type_id", "create_user", "status", "business_type", "version"}).
+			AddRow(flowID, 3, createUser, status, businessType, 1))
+}
+
+func TestResubmitFlowInstance_Rejects(t *testing.T) {
+	template := FlowTemplateRef{TemplateID: 5, FlowTypeID: 3}
+	business := BusinessRef{Type: BusinessTypeRefund, ID: 101}
+
+	tests := []struct {
+		name        string
+		createUser  int
+		status      int8
+		resubmitted int
+	}{
+		{name: "非发起人", createUser: 8, status: 20},
+		{name: "审批中的流程", createUser: 7, status: 0},
+		{name: "已通过的流程", createUser: 7, status: 10},
+		{name: "已重新提交过", createUser: 7, status: 99, resubmitted: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			expectLockedFlow(mock, 11, tt.createUser, tt.status, BusinessTypeRefund)
+			if tt.createUser == 7 && (tt.status == 20 || tt.status == 99) {
+				mock.ExpectQuery("SELECT count\\(\\*\\) FROM `approval_flow_management` WHERE previous_flow_id = \\?").
+					WithArgs(11).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.resubmitted))
+			}
+
+			if _, err := newTestFlowService(db).ResubmitFlowInstance(db, 11, template, 7, business, ""); err == nil {
+				t.Fatal("ResubmitFlowInstance() expected error")
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestResubmitFlowInstance_BusinessTypeMismatch(t *testing.T) {
+	db, mock := setupMockDB(t)
+	expectLockedFlow(mock, 11, 7, 20, BusinessTypeOrderAmendment)
+
+	_, err := newTestFlowService(db).ResubmitFlowInstance(db, 11, FlowTemplateRef{TemplateID: 5, FlowTypeID: 3}, 7, BusinessRef{Type: BusinessTypeRefund, ID: 101}, "")
+	if err == nil {
+		t.Fatal("ResubmitFlowInstance() expected error for business type mismatch")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestApprovalFlowManagement_IsResubmittable(t *testing.T) {
+	for status, want := range map[int8]bool{0: false, 10: false, 20: true, 99: true} {
+		f := &entity.ApprovalFlowManagement{Status: status}
+		if got := f.IsResubmittable(); got != want {
+			t.Errorf("IsResubmittable() with status %d = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"charonoms/internal/domain/approval/entity"
+)
+
+// AddSignerRequest 加签请求
+type AddSignerRequest struct {
+	Position int8  // entity.AddSignBefore=前加签，entity.AddSignAfter=后加签
+	Type     int8  // 加签节点类型：0=会签，1=或签
+	UserIDs  []int // 加签审批人
+	Remark   string
+}
+
+// lockPendingTask 按与审批决策相同的顺序（审批流、节点实例、审批任务）锁定操作人自己的待审批任务，
+// 并在锁内确认所属节点仍是审批流当前可处理的节点，避免与同节点的审批并发时在已流转的节点上转交或加签
+func lockPendingTask(tx *gorm.DB, nodeCaseUserID int, operatorID int) (*entity.ApprovalNodeCaseUser, *entity.ApprovalNodeCase, error) {
+	task, nodeCase, err := lockDecisionTask(tx, nodeCaseUserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if task.UserAccountID != operatorID {
+		return nil, nil, errors.New("无权处理此审批")
+	}
+	if err := checkNodeCaseActionable(tx, nodeCase); err != nil {
+		return nil, nil, err
+	}
+	return task, nodeCase, nil
+}
+
+// ProcessTransfer 转交：审批人把自己的待审批任务转给他人，原审批人不再需要审批
+func (s *ApprovalFlowService) ProcessTransfer(nodeCaseUserID int, operatorID int, toUserID int, remark string) error {
+	if toUserID == operatorID {
+		return errors.New("不能转交给自己")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		task, nodeCase, err := lockPendingTask(tx, nodeCaseUserID, operatorID)
+		if err != nil {
+			return err
+		}
+
+		active, err := activeAccounts(tx, []int{toUserID})
+		if err != nil {
+			return err
+		}
+		if len(active) == 0 {
+			return errors.New("转交对象账号不存在或已禁用")
+		}
+
+		var duplicated int64
+		if err := tx.Model(&entity.ApprovalNodeCaseUser{}).
+			Where("approval_node_case_id = ? AND useraccount_id = ? AND result IS NULL", task.ApprovalNodeCaseID, toUserID).
+			Count(&duplicated).Error; err != nil {
+			return err
+		}
+		if duplicated > 0 {
+			return errors.New("转交对象已是该节点的审批人")
+		}
+
+		if err := tx.Model(&entity.ApprovalNodeCaseUser{}).
+			Where("id = ?", task.ID).
+			Update("useraccount_id", toUserID).Error; err != nil {
+			return fmt.Errorf("转交审批任务失败: %w", err)
+		}
+
+		return recordAction(tx, entity.ApprovalFlowAction{
+			ApprovalFlowManagementID: nodeCase.ApprovalFlowManagementID,
+			ApprovalNodeCaseID:       &nodeCase.ID,
+			Action:                   entity.FlowActionTransfer,
+			OperatorID:               operatorID,
+			FromUserID:               &operatorID,
+			ToUserID:                 &toUserID,
+			Remark:                   remark,
+		})
+	})
+}
+
+// ProcessAddSigner 加签：在当前节点之前或之后增加审批人
+// 前加签：加签人审批通过后，当前节点的审批人才能审批；后加签：当前节点通过后再由加签人审批，全部通过才流转到下一模板节点
+func (s *ApprovalFlowService) ProcessAddSigner(nodeCaseUserID int, operatorID int, req AddSignerRequest) error {
+	if req.Position != entity.AddSignBefore && req.Position != entity.AddSignAfter {
+		return errors.New("加签位置无效")
+	}
+	if req.Type != 0 && req.Type != 1 {
+		return errors.New("加签节点类型无效")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		_, nodeCase, err := lockPendingTask(tx, nodeCaseUserID, operatorID)
+		if err != nil {
+			return err
+		}
+
+		// 加签节点归属：前加签挂在当前节点上；后加签挂在当前模板节点上（在后加签节点上继续后加签时挂到同一模板节点）
+		parentID := nodeCase.ID
+		if req.Position == entity.AddSignAfter {
+			switch nodeCase.AddSignType {
+			case entity.AddSignBefore:
+				return errors.New("前加签节点不支持后加签")
+			case entity.AddSignAfter:
+				parentID = *nodeCase.ParentNodeCaseID
+			}
+		}
+
+		userIDs := make([]int, 0, len(req.UserIDs))
+		for _, id := range req.UserIDs {
+			if id != operatorID {
+				userIDs = append(userIDs, id)
+			}
+		}
+		approvers, err := activeAccounts(tx, userIDs)
+		if err != nil {
+			return err
+		}
+		if len(approvers) == 0 {
+			return fmt.Errorf("%w: 加签审批人不存在或已禁用", ErrNoApprover)
+		}
+
+		// 加签节点按所属模板节点的处理时限重新计时
+		var templateNode entity.ApprovalFlowTemplateNode
+		if err := tx.First(&templateNode, nodeCase.NodeID).Error; err != nil {
+			return fmt.Errorf("模板节点不存在: %w", err)
+		}
+
+		addSignCaseID, err := createNodeCase(tx, nodeCaseSpec{
+			FlowID:           nodeCase.ApprovalFlowManagementID,
+			TemplateNodeID:   nodeCase.NodeID,
+			Type:             req.Type,
+			Sort:             nodeCase.Sort,
+			AddSignType:      req.Position,
+			ParentNodeCaseID: &parentID,
+			Deadline:         templateNode.SLADeadline(time.Now()),
+			Approvers:        approvers,
+		})
+		if err != nil {
+			return err
+		}
+
+		action := entity.FlowActionAddSignBefore
+		if req.Position == entity.AddSignAfter {
+			action = entity.FlowActionAddSignAfter
+		}
+		for _, id := range approvers {
+			toUserID := id
+			if err := recordAction(tx, entity.ApprovalFlowAction{
+				ApprovalFlowManagementID: nodeCase.ApprovalFlowManagementID,
+				ApprovalNodeCaseID:       &addSignCaseID,
+				Action:                   action,
+				OperatorID:               operatorID,
+				ToUserID:                 &toUserID,
+				Remark:                   req.Remark,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CreateDelegation 设置外出委托：委托期间新分配给审批人的任务自动交给委托人
+// 委托已生效时，审批人手上进行中审批流的待审批任务同时转给委托人
+func (s *ApprovalFlowService) CreateDelegation(delegation *entity.ApprovalDelegation) error {
+	if delegation.DelegateID == delegation.UserAccountID {
+		return errors.New("不能委托给自己")
+	}
+	if !delegation.EndTime.After(delegation.StartTime) {
+		return errors.New("委托结束时间必须晚于开始时间")
+	}
+	delegation.Status = entity.DelegationStatusActive
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		active, err := activeAccounts(tx, []int{delegation.DelegateID})
+		if err != nil {
+			return err
+		}
+		if len(active) == 0 {
+			return errors.New("委托人账号不存在或已禁用")
+		}
+
+		var overlapped int64
+		if err := tx.Model(&entity.ApprovalDelegation{}).
+			Where("useraccount_id = ? AND status = ? AND start_time < ? AND end_time > ?",
+				delegation.UserAccountID, entity.DelegationStatusActive, delegation.EndTime, delegation.StartTime).
+			Count(&overlapped).Error; err != nil {
+			return err
+		}
+		if overlapped > 0 {
+			return errors.New("该时间段已设置委托")
+		}
+
+		if err := tx.Create(delegation).Error; err != nil {
+			return fmt.Errorf("创建委托失败: %w", err)
+		}
+
+		if !delegation.IsEffective(time.Now()) {
+			return nil
+		}
+		return delegatePendingTasks(tx, delegation)
+	})
+}
+
+// delegatePendingTasks 把审批人在进行中审批流上的待审批任务转给委托人并记录委托操作
+// 委托人已是同一节点审批人的任务保持不变
+func delegatePendingTasks(tx *gorm.DB, delegation *entity.ApprovalDelegation) error {
+	var tasks []struct {
+		ID                       int
+		ApprovalNodeCaseID       int
+		ApprovalFlowManagementID int
+	}
+	if err := tx.Raw(`
+		SELECT ncu.id, ncu.approval_node_case_id, nc.approval_flow_management_id
+		FROM approval_node_case_user ncu
+		INNER JOIN approval_node_case nc ON ncu.approval_node_case_id = nc.id
+		INNER JOIN approval_flow_management fm ON nc.approval_flow_management_id = fm.id
+		WHERE ncu.useraccount_id = ? AND ncu.result IS NULL AND fm.status = 0
+		  AND NOT EXISTS (
+		    SELECT 1 FROM approval_node_case_user d
+		    WHERE d.approval_node_case_id = ncu.approval_node_case_id AND d.useraccount_id = ?
+		  )
+	`, delegation.UserAccountID, delegation.DelegateID).Scan(&tasks).Error; err != nil {
+		return fmt.Errorf("查询待审批任务失败: %w", err)
+	}
+
+	for _, task := range tasks {
+		if err := tx.Model(&entity.ApprovalNodeCaseUser{}).
+			Where("id = ?", task.ID).
+			Update("useraccount_id", delegation.DelegateID).Error; err != nil {
+			return fmt.Errorf("委托待审批任务失败: %w", err)
+		}
+
+		nodeCaseID := task.ApprovalNodeCaseID
+		from, to := delegation.UserAccountID, delegation.DelegateID
+		if err := recordAction(tx, entity.ApprovalFlowAction{
+			ApprovalFlowManagementID: task.ApprovalFlowManagementID,
+			ApprovalNodeCaseID:       &nodeCaseID,
+			Action:                   entity.FlowActionDelegate,
+			OperatorID:               from,
+			FromUserID:               &from,
+			ToUserID:                 &to,
+			Remark:                   delegation.Remark,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CancelDelegation 取消本人的委托，已转给委托人的任务不再收回
+func (s *ApprovalFlowService) CancelDelegation(delegationID int, userID int) error {
+	var delegation entity.ApprovalDelegation
+	if err := s.db.First(&delegation, delegationID).Error; err != nil {
+		return fmt.Errorf("委托不存在: %w", err)
+	}
+	if delegation.UserAccountID != userID {
+		return errors.New("只能取消本人的委托")
+	}
+	if delegation.Status != entity.DelegationStatusActive {
+		return errors.New("委托已取消")
+	}
+
+	return s.db.Model(&entity.ApprovalDelegation{}).
+		Where("id = ?", delegationID).
+		Update("status", entity.DelegationStatusCancelled).Error
+}
+
+// ListDelegations 查询本人设置的委托
+func (s *ApprovalFlowService) ListDelegations(userID int) ([]map[string]interface{}, error) {
+	var delegations []map[string]interface{}
+	err := s.db.Table("approval_delegation ad").
+		Select("ad.*, ua.username AS delegate_name").
+		Joins("LEFT JOIN useraccount ua ON ad.delegate_id = ua.id").
+		Where("ad.useraccount_id = ?", userID).
+		Order("ad.start_time DESC").
+		Find(&delegations).Error
+	return delegations, err
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"charonoms/internal/domain/approval/entity"
+)
+
+func TestApplyDelegations(t *testing.T) {
+	tests := []struct {
+		name        string
+		approvers   []int
+		delegations [][2]int // 审批人 -> 委托人
+		activeIDs   []int    // 启用的委托人
+		want        []approverAssignment
+	}{
+		{
+			name:        "外出审批人替换为委托人",
+			approvers:   []int{1, 2, 3},
+			delegations: [][2]int{{2, 9}},
+			activeIDs:   []int{9},
+			want:        []approverAssignment{{UserID: 1}, {UserID: 9, DelegatedFrom: 2}, {UserID: 3}},
+		},
+		{
+			name:        "委托人已在审批人中时去重",
+			approvers:   []int{1, 3},
+			delegations: [][2]int{{1, 3}},
+			activeIDs:   []int{3},
+			want:        []approverAssignment{{UserID: 3, DelegatedFrom: 1}},
+		},
+		{
+			name:        "委托人已禁用时不替换",
+			approvers:   []int{1, 2},
+			delegations: [][2]int{{2, 9}},
+			activeIDs:   nil,
+			want:        []approverAssignment{{UserID: 1}, {UserID: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+
+			rows := sqlmock.NewRows([]string{"id", "useraccount_id", "delegate_id", "status"})
+			for i, d := range tt.delegations {
+				rows.AddRow(i+1, d[0], d[1], entity.DelegationStatusActive)
+			}
+			mock.ExpectQuery("SELECT \\* FROM `approval_delegation` WHERE useraccount_id IN \\(.*\\) AND status = \\? AND start_time <= \\? AND end_time > \\?").
+				WillReturnRows(rows)
+			expectActiveAccounts(mock, tt.activeIDs...)
+
+			got, err := applyDelegations(db, tt.approvers, time.Now())
+			if err != nil {
+				t.Fatalf("applyDelegations() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("applyDelegations() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("applyDelegations()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApprovalDelegation_IsEffective(t *testing.T) {
+	start := time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local)
+	d := &entity.ApprovalDelegation{StartTime: start, EndTime: start.AddDate(0, 0, 3)}
+
+	if !d.IsEffective(start) {
+		t.Error("delegation should be effective at start time")
+	}
+	if d.IsEffective(start.AddDate(0, 0, 3)) {
+		t.Error("delegation should not be effective at end time")
+	}
+	d.Status = entity.DelegationStatusCancelled
+	if d.IsEffective(start.Add(time.Hour)) {
+		t.Error("cancelled delegation should not be effective")
+	}
+}
+
+func TestProcessTransfer_OnlyOwner(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+
+	expectLockedDecisionTask(mock, 1)
+	mock.ExpectRollback()
+
+	if err := s.ProcessTransfer(11, 8, 9, "休假"); err == nil {
+		t.Fatal("ProcessTransfer() error = nil, want error for non-owner")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestProcessAddSigner_NodeAlreadyPassed(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+
+	// 等待审批流锁期间，同一或签节点的其他审批人已审批通过，节点已流转
+	nodeCaseRows := func(result interface{}) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "node_id", "approval_flow_management_id", "type", "sort", "result"}).
+			AddRow(3, 21, 5, 1, 1, result)
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case_user` WHERE `approval_node_case_user`.`id` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_node_case_id", "useraccount_id", "result"}).AddRow(11, 3, 7, nil))
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case` WHERE `approval_node_case`.`id` = \\?").
+		WillReturnRows(nodeCaseRows(nil))
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_management` .*FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_flow_template_id", "create_user", "status"}).AddRow(5, 8, 2, 0))
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case` .*FOR UPDATE").
+		WillReturnRows(nodeCaseRows(0))
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case_user` .*FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_node_case_id", "useraccount_id", "result"}).AddRow(11, 3, 7, nil))
+	mock.ExpectRollback()
+
+	err := s.ProcessAddSigner(11, 7, AddSignerRequest{Position: entity.AddSignBefore, UserIDs: []int{9}})
+	if err == nil {
+		t.Fatal("ProcessAddSigner() error = nil, want error once the node has passed")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestProcessAddSigner_PendingBeforeSign(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+
+	expectLockedDecisionTask(mock, 0)
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `approval_node_case` WHERE parent_node_case_id = \\? AND add_sign_type = \\? AND result IS NULL").
+		WithArgs(3, entity.AddSignBefore).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	err := s.ProcessAddSigner(11, 7, AddSignerRequest{Position: entity.AddSignBefore, UserIDs: []int{9}})
+	if err == nil {
+		t.Fatal("ProcessAddSigner() error = nil, want error while before-signers are pending")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestProcessTransfer_ToSelf(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+
+	if err := s.ProcessTransfer(11, 7, 7, ""); err == nil {
+		t.Fatal("ProcessTransfer() error = nil, want error for transferring to self")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestCheckNodeCaseActionable_PendingBeforeSign(t *testing.T) {
+	db, mock := setupMockDB(t)
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `approval_node_case` WHERE parent_node_case_id = \\? AND add_sign_type = \\? AND result IS NULL").
+		WithArgs(3, entity.AddSignBefore).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	if err := checkNodeCaseActionable(db, &entity.ApprovalNodeCase{ID: 3}); err == nil {
+		t.Fatal("checkNodeCaseActionable() error = nil, want error while before-signers are pending")
+	}
+}
+
+func TestProcessAddSigner_InvalidPosition(t *testing.T) {
+	db, _ := setupMockDB(t)
+	s := newTestFlowService(db)
+
+	err := s.ProcessAddSigner(11, 7, AddSignerRequest{Position: entity.AddSignNone, UserIDs: []int{9}})
+	if err == nil {
+		t.Fatal("ProcessAddSigner() error = nil, want error for invalid position")
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"charonoms/internal/domain/approval/entity"
+)
+
+// nodeCaseSpec 待创建的节点实例
+type nodeCaseSpec struct {
+	FlowID           int
+	TemplateNodeID   int
+	Type             int8 // 0=会签，1=或签
+	Sort             int
+	AddSignType      int8
+	ParentNodeCaseID *int
+	Deadline         *time.Time // 处理截止时间，按模板节点的处理时限计算，nil=不限时
+	Approvers        []int
+}
+
+// approverAssignment 审批人分配结果，DelegatedFrom 非0表示由外出审批人委托而来
+type approverAssignment struct {
+	UserID        int
+	DelegatedFrom int
+}
+
+// createNodeCase 在事务中创建节点实例及审批人员记录，外出审批人按委托自动替换为委托人并记录委托操作
+func createNodeCase(tx *gorm.DB, spec nodeCaseSpec) (int, error) {
+	if len(spec.Approvers) == 0 {
+		return 0, ErrNoApprover
+	}
+
+	assignments, err := applyDelegations(tx, spec.Approvers, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	nodeCase := entity.ApprovalNodeCase{
+		NodeID:                   spec.TemplateNodeID,
+		ApprovalFlowManagementID: spec.FlowID,
+		Type:                     spec.Type,
+		Sort:                     spec.Sort,
+		AddSignType:              spec.AddSignType,
+		ParentNodeCaseID:         spec.ParentNodeCaseID,
+		Deadline:                 spec.Deadline,
+	}
+	if err := tx.Create(&nodeCase).Error; err != nil {
+		return 0, fmt.Errorf("创建节点实例失败: %w", err)
+	}
+
+	for _, a := range assignments {
+		userCase := entity.ApprovalNodeCaseUser{
+			ApprovalNodeCaseID: nodeCase.ID,
+			UserAccountID:      a.UserID,
+		}
+		if err := tx.Create(&userCase).Error; err != nil {
+			return 0, fmt.Errorf("创建审批人员记录失败: %w", err)
+		}
+
+		if a.DelegatedFrom != 0 {
+			from, to := a.DelegatedFrom, a.UserID
+			if err := recordAction(tx, entity.ApprovalFlowAction{
+				ApprovalFlowManagementID: spec.FlowID,
+				ApprovalNodeCaseID:       &nodeCase.ID,
+				Action:                   entity.FlowActionDelegate,
+				OperatorID:               from,
+				FromUserID:               &from,
+				ToUserID:                 &to,
+				Remark:                   "审批人外出，自动委托",
+			}); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return nodeCase.ID, nil
+}
+
+// applyDelegations 将在指定时间有生效委托的审批人替换为委托人（只替换一层，不沿委托链传递），结果去重
+// 委托人账号已禁用时不替换
+func applyDelegations(tx *gorm.DB, approvers []int, at time.Time) ([]approverAssignment, error) {
+	var delegations []entity.ApprovalDelegation
+	if err := tx.Where("useraccount_id IN ? AND status = ? AND start_time <= ? AND end_time > ?",
+		approvers, entity.DelegationStatusActive, at, at).
+		Find(&delegations).Error; err != nil {
+		return nil, fmt.Errorf("查询审批委托失败: %w", err)
+	}
+
+	delegateOf := make(map[int]int, len(delegations))
+	if len(delegations) > 0 {
+		delegateIDs := make([]int, 0, len(delegations))
+		for _, d := range delegations {
+			delegateIDs = append(delegateIDs, d.DelegateID)
+		}
+		activeDelegates, err := activeAccounts(tx, delegateIDs)
+		if err != nil {
+			return nil, err
+		}
+		active := make(map[int]bool, len(activeDelegates))
+		for _, id := range activeDelegates {
+			active[id] = true
+		}
+		for _, d := range delegations {
+			if active[d.DelegateID] {
+				delegateOf[d.UserAccountID] = d.DelegateID
+			}
+		}
+	}
+
+	assignments := make([]approverAssignment, 0, len(approvers))
+	assigned := make(map[int]bool, len(approvers))
+	for _, id := range approvers {
+		a := approverAssignment{UserID: id}
+		if delegate, ok := delegateOf[id]; ok {
+			a = approverAssignment{UserID: delegate, DelegatedFrom: id}
+		}
+		if assigned[a.UserID] {
+			continue
+		}
+		assigned[a.UserID] = true
+		assignments = append(assignments, a)
+	}
+	return assignments, nil
+}
+
+// recordAction 记录审批流操作
+func recordAction(tx *gorm.DB, action entity.ApprovalFlowAction) error {
+	if err := tx.Create(&action).Error; err != nil {
+		return fmt.Errorf("记录审批操作失败: %w", err)
+	}
+	return nil
+}
+
+// deletePendingUsersOfFlow 审批流结束（驳回、撤销）后删除所有节点上未处理的审批人员，避免残留在待审批列表中
+func deletePendingUsersOfFlow(tx *gorm.DB, flowID int) error {
+	return tx.Exec(`
+		DELETE FROM approval_node_case_user
+		WHERE result IS NULL
+		  AND approval_node_case_id IN (SELECT id FROM approval_node_case WHERE approval_flow_management_id = ?)
+	`, flowID).Error
+}
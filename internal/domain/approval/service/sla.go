@@ -0,0 +1,272 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"charonoms/internal/domain/approval/entity"
+	"charonoms/internal/domain/notification"
+)
+
+// ProcessOverdueNodeCases 处理截止时间已过、仍在审批中的节点实例，返回本轮处理的节点数
+// 每个节点在独立事务中加锁并复查状态后处理，多个实例同时扫描时同一节点只会被处理一次；
+// 有未完成前加签的节点、所属节点尚未通过的后加签节点当前不能审批，暂不处理
+func (s *ApprovalFlowService) ProcessOverdueNodeCases(now time.Time, limit int) (int, error) {
+	var ids []int
+	err := s.db.Raw(`
+		SELECT nc.id
+		FROM approval_node_case nc
+		INNER JOIN approval_flow_management fm ON fm.id = nc.approval_flow_management_id
+		WHERE nc.result IS NULL AND nc.sla_status = ? AND nc.deadline IS NOT NULL AND nc.deadline <= ?
+		  AND fm.status = 0
+		  AND NOT EXISTS (
+		    SELECT 1 FROM approval_node_case b
+		    WHERE b.parent_node_case_id = nc.id AND b.add_sign_type = ? AND b.result IS NULL
+		  )
+		  AND (nc.add_sign_type <> ? OR EXISTS (
+		    SELECT 1 FROM approval_node_case p
+		    WHERE p.id = nc.parent_node_case_id AND p.result = 0
+		  ))
+		ORDER BY nc.deadline ASC, nc.id ASC
+		LIMIT ?
+	`, entity.SLAStatusPending, now, entity.AddSignBefore, entity.AddSignAfter, limit).Scan(&ids).Error
+	if err != nil {
+		return 0, fmt.Errorf("查询超时节点失败: %w", err)
+	}
+
+	handled := 0
+	var errs []error
+	for _, id := range ids {
+		ok, err := s.handleOverdueNodeCase(id, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("节点实例%d超时处理失败: %w", id, err))
+			continue
+		}
+		if ok {
+			handled++
+		}
+	}
+	return handled, errors.Join(errs...)
+}
+
+// handleOverdueNodeCase 按模板节点的超时处理方式处理单个超时节点：
+// 先提醒未处理的审批人，再按配置升级给备用审批人或自动通过/驳回；节点已被处理时返回 false
+func (s *ApprovalFlowService) handleOverdueNodeCase(nodeCaseID int, now time.Time) (bool, error) {
+	handled := false
+	var nodeCase entity.ApprovalNodeCase
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 与审批、撤销保持相同的加锁顺序：先锁审批流，再锁节点实例
+		if err := tx.First(&nodeCase, nodeCaseID).Error; err != nil {
+			return fmt.Errorf("节点实例不存在: %w", err)
+		}
+		var flow entity.ApprovalFlowManagement
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&flow, nodeCase.ApprovalFlowManagementID).Error; err != nil {
+			return fmt.Errorf("审批流不存在: %w", err)
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&nodeCase, nodeCaseID).Error; err != nil {
+			return fmt.Errorf("节点实例不存在: %w", err)
+		}
+
+		// 复查：审批人已处理、其他实例已处理或截止时间未到时跳过
+		if flow.Status != 0 || nodeCase.Result != nil || nodeCase.SLAStatus != entity.SLAStatusPending ||
+			nodeCase.Deadline == nil || nodeCase.Deadline.After(now) {
+			return nil
+		}
+
+		var templateNode entity.ApprovalFlowTemplateNode
+		if err := tx.First(&templateNode, nodeCase.NodeID).Error; err != nil {
+			return fmt.Errorf("模板节点不存在: %w", err)
+		}
+
+		var pending []int
+		if err := tx.Model(&entity.ApprovalNodeCaseUser{}).
+			Where("approval_node_case_id = ? AND result IS NULL", nodeCaseID).
+			Pluck("useraccount_id", &pending).Error; err != nil {
+			return fmt.Errorf("查询待审批人员失败: %w", err)
+		}
+
+		if err := tx.Model(&entity.ApprovalNodeCase{}).
+			Where("id = ?", nodeCaseID).
+			Update("sla_status", entity.SLAStatusHandled).Error; err != nil {
+			return fmt.Errorf("更新节点超时状态失败: %w", err)
+		}
+		handled = true
+
+		overdue := fmt.Sprintf("审批流#%d 的节点「%s」已超过处理时限（截止 %s）",
+			flow.ID, templateNode.Name, nodeCase.Deadline.Format("2006-01-02 15:04"))
+		reminder := notification.Notification{
+			Category:     notification.CategoryApprovalReminder,
+			Title:        "审批超时提醒",
+			Content:      overdue + "，请尽快处理",
+			BusinessType: notification.BusinessTypeApprovalFlow,
+			BusinessID:   flow.ID,
+		}
+
+		switch templateNode.SLAAction {
+		case entity.SLAActionEscalate:
+			escalated, err := escalateNodeCase(tx, &nodeCase, templateNode.SLABackupID, pending)
+			if err != nil {
+				return err
+			}
+			if escalated {
+				reminder.Content = overdue + "，已升级给备用审批人处理"
+			}
+
+		case entity.SLAActionAutoApprove:
+			if err := autoDecideNodeCase(tx, &nodeCase, 0, now); err != nil {
+				return err
+			}
+			reminder.Content = overdue + "，已自动通过"
+			if err := notifyInitiator(tx, &flow, overdue+"，已自动通过"); err != nil {
+				return err
+			}
+			if err := notification.Send(tx, pending, reminder); err != nil {
+				return err
+			}
+			// 自动通过后在同一事务中按正常规则流转到下一节点或完成审批流，流转失败时超时处理整体回滚，下一轮扫描重试
+			return s.afterNodeCasePassedTx(tx, &nodeCase)
+
+		case entity.SLAActionAutoReject:
+			if err := autoDecideNodeCase(tx, &nodeCase, 1, now); err != nil {
+				return err
+			}
+			reminder.Content = overdue + "，已自动驳回"
+			if err := tx.Model(&entity.ApprovalFlowManagement{}).
+				Where("id = ?", flow.ID).
+				Updates(map[string]interface{}{
+					"status":        20,
+					"complete_time": &now,
+				}).Error; err != nil {
+				return fmt.Errorf("更新审批流状态失败: %w", err)
+			}
+			if err := deletePendingUsersOfFlow(tx, flow.ID); err != nil {
+				return err
+			}
+			if err := notifyInitiator(tx, &flow, overdue+"，已自动驳回，审批流已驳回"); err != nil {
+				return err
+			}
+			if err := notification.Send(tx, pending, reminder); err != nil {
+				return err
+			}
+			return s.dispatchCompletionTx(tx, flow.ID, CompletionRejected)
+		}
+
+		return notification.Send(tx, pending, reminder)
+	})
+	if err != nil {
+		return false, err
+	}
+	return handled, nil
+}
+
+// escalateNodeCase 超时升级：节点上未处理的审批人替换为备用审批人，并通知备用审批人
+// 未配置备用审批人、备用审批人已禁用或已在该节点处理过时不升级，只做提醒
+func escalateNodeCase(tx *gorm.DB, nodeCase *entity.ApprovalNodeCase, backupID *int, pending []int) (bool, error) {
+	if backupID == nil || len(pending) == 0 {
+		return false, nil
+	}
+	active, err := activeAccounts(tx, []int{*backupID})
+	if err != nil {
+		return false, err
+	}
+	if len(active) == 0 {
+		return false, nil
+	}
+	backup := active[0]
+
+	var existing []entity.ApprovalNodeCaseUser
+	if err := tx.Where("approval_node_case_id = ? AND useraccount_id = ?", nodeCase.ID, backup).
+		Find(&existing).Error; err != nil {
+		return false, err
+	}
+	alreadyPending := false
+	for _, u := range existing {
+		if u.Result != nil {
+			return false, nil
+		}
+		alreadyPending = true
+	}
+
+	if err := tx.Where("approval_node_case_id = ? AND result IS NULL AND useraccount_id <> ?", nodeCase.ID, backup).
+		Delete(&entity.ApprovalNodeCaseUser{}).Error; err != nil {
+		return false, fmt.Errorf("移除超时审批人失败: %w", err)
+	}
+	if !alreadyPending {
+		if err := tx.Create(&entity.ApprovalNodeCaseUser{
+			ApprovalNodeCaseID: nodeCase.ID,
+			UserAccountID:      backup,
+		}).Error; err != nil {
+			return false, fmt.Errorf("创建备用审批人记录失败: %w", err)
+		}
+	}
+
+	for _, id := range pending {
+		if id == backup {
+			continue
+		}
+		from := id
+		if err := recordAction(tx, entity.ApprovalFlowAction{
+			ApprovalFlowManagementID: nodeCase.ApprovalFlowManagementID,
+			ApprovalNodeCaseID:       &nodeCase.ID,
+			Action:                   entity.FlowActionEscalate,
+			OperatorID:               entity.SystemOperatorID,
+			FromUserID:               &from,
+			ToUserID:                 &backup,
+			Remark:                   "审批超时，升级给备用审批人",
+		}); err != nil {
+			return false, err
+		}
+	}
+
+	return true, notification.Send(tx, []int{backup}, notification.Notification{
+		Category:     notification.CategoryApprovalEscalate,
+		Title:        "超时审批升级",
+		Content:      fmt.Sprintf("审批流#%d 的节点审批已超时，已升级由您处理", nodeCase.ApprovalFlowManagementID),
+		BusinessType: notification.BusinessTypeApprovalFlow,
+		BusinessID:   nodeCase.ApprovalFlowManagementID,
+	})
+}
+
+// autoDecideNodeCase 超时自动审批：移除节点上未处理的审批人，节点结果直接置为通过(0)或驳回(1)
+func autoDecideNodeCase(tx *gorm.DB, nodeCase *entity.ApprovalNodeCase, result int8, now time.Time) error {
+	if err := tx.Where("approval_node_case_id = ? AND result IS NULL", nodeCase.ID).
+		Delete(&entity.ApprovalNodeCaseUser{}).Error; err != nil {
+		return fmt.Errorf("移除超时审批人失败: %w", err)
+	}
+	if err := tx.Model(&entity.ApprovalNodeCase{}).
+		Where("id = ?", nodeCase.ID).
+		Updates(map[string]interface{}{
+			"result":        result,
+			"complete_time": &now,
+		}).Error; err != nil {
+		return fmt.Errorf("更新节点结果失败: %w", err)
+	}
+
+	action := entity.FlowActionAutoApprove
+	if result != 0 {
+		action = entity.FlowActionAutoReject
+	}
+	return recordAction(tx, entity.ApprovalFlowAction{
+		ApprovalFlowManagementID: nodeCase.ApprovalFlowManagementID,
+		ApprovalNodeCaseID:       &nodeCase.ID,
+		Action:                   action,
+		OperatorID:               entity.SystemOperatorID,
+		Remark:                   "审批超时，系统自动处理",
+	})
+}
+
+// notifyInitiator 通知审批流发起人节点已被超时自动处理
+func notifyInitiator(tx *gorm.DB, flow *entity.ApprovalFlowManagement, content string) error {
+	return notification.Send(tx, []int{flow.CreateUser}, notification.Notification{
+		Category:     notification.CategoryApprovalAuto,
+		Title:        "审批超时自动处理",
+		Content:      content,
+		BusinessType: notification.BusinessTypeApprovalFlow,
+		BusinessID:   flow.ID,
+	})
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"charonoms/internal/domain/approval/entity"
+)
+
+func expectLockedOverdueNodeCase(mock sqlmock.Sqlmock, slaStatus int8, deadline time.Time) {
+	nodeCaseRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "node_id", "approval_flow_management_id", "type", "sort", "result", "deadline", "sla_status"}).
+			AddRow(3, 21, 5, 0, 1, nil, deadline, slaStatus)
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case` WHERE `approval_node_case`.`id` = \\?").
+		WillReturnRows(nodeCaseRows())
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_management` .*FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "create_user", "status"}).AddRow(5, 2, 0))
+	mock.ExpectQuery("SELECT \\* FROM `approval_node_case` .*FOR UPDATE").
+		WillReturnRows(nodeCaseRows())
+}
+
+func TestHandleOverdueNodeCase_AlreadyHandled(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	now := time.Now()
+
+	// 其他实例已处理（sla_status=1），复查后跳过
+	expectLockedOverdueNodeCase(mock, entity.SLAStatusHandled, now.Add(-time.Hour))
+	mock.ExpectCommit()
+
+	handled, err := s.handleOverdueNodeCase(3, now)
+	if err != nil {
+		t.Fatalf("handleOverdueNodeCase() error = %v", err)
+	}
+	if handled {
+		t.Error("handleOverdueNodeCase() handled = true, want false for node already handled")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestHandleOverdueNodeCase_Remind(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	now := time.Now()
+
+	expectLockedOverdueNodeCase(mock, entity.SLAStatusPending, now.Add(-time.Hour))
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_template_node`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "sla_hours", "sla_action"}).AddRow(21, "主管审批", 24, entity.SLAActionRemind))
+	mock.ExpectQuery("SELECT `useraccount_id` FROM `approval_node_case_user` WHERE approval_node_case_id = \\? AND result IS NULL").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"useraccount_id"}).AddRow(7).AddRow(8))
+	mock.ExpectExec("UPDATE `approval_node_case` SET `sla_status`=\\? WHERE id = \\?").
+		WithArgs(entity.SLAStatusHandled, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `notification`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO `notification`").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	handled, err := s.handleOverdueNodeCase(3, now)
+	if err != nil {
+		t.Fatalf("handleOverdueNodeCase() error = %v", err)
+	}
+	if !handled {
+		t.Error("handleOverdueNodeCase() handled = false, want true")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestHandleOverdueNodeCase_NotYetDue(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	now := time.Now()
+
+	// 截止时间未到，复查后跳过
+	expectLockedOverdueNodeCase(mock, entity.SLAStatusPending, now.Add(time.Hour))
+	mock.ExpectCommit()
+
+	handled, err := s.handleOverdueNodeCase(3, now)
+	if err != nil || handled {
+		t.Fatalf("handleOverdueNodeCase() = (%v, %v), want (false, nil)", handled, err)
+	}
+}
+
+func TestHandleOverdueNodeCase_AutoApproveAdvanceFailureRollsBack(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := newTestFlowService(db)
+	now := time.Now()
+
+	// 自动通过与流转在同一事务中：流转失败时节点不会停留在已通过、超时已处理而审批流仍在审批中的状态
+	expectLockedOverdueNodeCase(mock, entity.SLAStatusPending, now.Add(-time.Hour))
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_template_node`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "template_id", "name", "sort", "sla_hours", "sla_action"}).AddRow(21, 8, "主管审批", 1, 24, entity.SLAActionAutoApprove))
+	mock.ExpectQuery("SELECT `useraccount_id` FROM `approval_node_case_user`").
+		WillReturnRows(sqlmock.NewRows([]string{"useraccount_id"}).AddRow(7))
+	mock.ExpectExec("UPDATE `approval_node_case` SET `sla_status`=\\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM `approval_node_case_user`").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `approval_node_case` SET `complete_time`=\\?,`result`=\\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `approval_flow_action`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO `notification`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO `notification`").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `approval_node_case`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_management` WHERE `approval_flow_management`.`id` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "approval_flow_template_id", "create_user", "status"}).AddRow(5, 8, 2, 0))
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_template_node` WHERE `approval_flow_template_node`.`id` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "template_id", "sort"}).AddRow(21, 8, 1))
+	mock.ExpectQuery("SELECT \\* FROM `approval_flow_template_node` WHERE template_id = \\?").
+		WillReturnError(errors.New("connection lost"))
+	mock.ExpectRollback()
+
+	handled, err := s.handleOverdueNodeCase(3, now)
+	if err == nil {
+		t.Fatal("handleOverdueNodeCase() error = nil, want advance error")
+	}
+	if handled {
+		t.Error("handleOverdueNodeCase() handled = true, want false when the transaction rolled back")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
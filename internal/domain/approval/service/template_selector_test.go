@@ -0,0 +1,80 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"charonoms/internal/domain/approval/entity"
+)
+
+func TestMatchTemplate(t *testing.T) {
+	beijing := entity.ApprovalFlowTemplate{ID: 1, Name: "北京退费", ApprovalFlowTypeID: 3, SelectionRules: entity.NodeConditions{
+		{Field: entity.PayloadFieldPayeeEntity, Op: entity.ConditionOpEq, Value: entity.PayloadPayeeBeijing},
+	}}
+	xianSmall := entity.ApprovalFlowTemplate{ID: 2, Name: "西安小额退费", ApprovalFlowTypeID: 3, SelectionRules: entity.NodeConditions{
+		{Field: entity.PayloadFieldPayeeEntity, Op: entity.ConditionOpEq, Value: entity.PayloadPayeeXian},
+		{Field: entity.PayloadFieldAmount, Op: entity.ConditionOpLt, Value: 5000.0},
+	}}
+	xianLarge := entity.ApprovalFlowTemplate{ID: 4, Name: "西安大额退费", ApprovalFlowTypeID: 3, SelectionRules: entity.NodeConditions{
+		{Field: entity.PayloadFieldPayeeEntity, Op: entity.ConditionOpEq, Value: entity.PayloadPayeeXian},
+		{Field: entity.PayloadFieldAmount, Op: entity.ConditionOpGte, Value: 5000.0},
+	}}
+	catchAll := entity.ApprovalFlowTemplate{ID: 5, Name: "通用退费", ApprovalFlowTypeID: 3}
+
+	tests := []struct {
+		name      string
+		templates []entity.ApprovalFlowTemplate
+		payload   entity.BusinessPayload
+		wantID    int
+		wantErr   string
+	}{
+		{
+			name:      "按收款主体选择",
+			templates: []entity.ApprovalFlowTemplate{beijing, xianSmall, xianLarge},
+			payload:   entity.BusinessPayload{entity.PayloadFieldPayeeEntity: entity.PayloadPayeeBeijing, entity.PayloadFieldAmount: 8000.0},
+			wantID:    1,
+		},
+		{
+			name:      "按金额区间选择",
+			templates: []entity.ApprovalFlowTemplate{beijing, xianSmall, xianLarge},
+			payload:   entity.BusinessPayload{entity.PayloadFieldPayeeEntity: entity.PayloadPayeeXian, entity.PayloadFieldAmount: 5000.0},
+			wantID:    4,
+		},
+		{
+			name:      "没有启用的模板",
+			templates: nil,
+			payload:   entity.BusinessPayload{},
+			wantErr:   "未找到启用的审批流模板",
+		},
+		{
+			name:      "没有匹配的模板",
+			templates: []entity.ApprovalFlowTemplate{beijing, xianSmall},
+			payload:   entity.BusinessPayload{entity.PayloadFieldPayeeEntity: entity.PayloadPayeeMixed, entity.PayloadFieldAmount: 100.0},
+			wantErr:   "没有适用于该业务单据的审批流模板",
+		},
+		{
+			name:      "匹配到多个模板",
+			templates: []entity.ApprovalFlowTemplate{beijing, catchAll},
+			payload:   entity.BusinessPayload{entity.PayloadFieldPayeeEntity: entity.PayloadPayeeBeijing},
+			wantErr:   "「北京退费」(#1)、「通用退费」(#5)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchTemplate(tt.templates, FlowTypeCodeRefund, tt.payload)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("matchTemplate() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("matchTemplate() error = %v", err)
+			}
+			if got.TemplateID != tt.wantID || got.FlowTypeID != 3 {
+				t.Errorf("matchTemplate() = %+v, want template %d of type 3", got, tt.wantID)
+			}
+		})
+	}
+}
@@ -2,13 +2,14 @@ package entity
 
 // UserAccount 用户账号实体
 type UserAccount struct {
-	ID       uint   `gorm:"primaryKey" json:"id"`
-	Username string `gorm:"size:50;not null;uniqueIndex" json:"username"`
-	Password string `gorm:"size:255;not null" json:"-"` // 不返回给前端
-	Name     string `gorm:"size:100" json:"name"`
-	Phone    string `gorm:"size:20;uniqueIndex" json:"phone"`
-	RoleID   uint   `gorm:"not null" json:"role_id"`
-	Status   int8   `gorm:"default:0" json:"status"` // 0-正常 1-禁用
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	Username  string `gorm:"size:50;not null;uniqueIndex" json:"username"`
+	Password  string `gorm:"size:255;not null" json:"-"` // 不返回给前端
+	Name      string `gorm:"size:100" json:"name"`
+	Phone     string `gorm:"size:20;uniqueIndex" json:"phone"`
+	RoleID    uint   `gorm:"not null" json:"role_id"`
+	ManagerID *uint  `gorm:"column:manager_id" json:"manager_id"` // 直属上级账号ID，审批节点按发起人上级审批时使用
+	Status    int8   `gorm:"default:0" json:"status"`             // 0-正常 1-禁用
 
 	// 关联
 	Role *Role `gorm:"foreignKey:RoleID" json:"role,omitempty"`
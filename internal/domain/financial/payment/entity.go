@@ -1,6 +1,10 @@
 package payment
 
-import "time"
+import (
+	"time"
+
+	"charonoms/internal/domain/shared/money"
+)
 
 // 付款场景常量
 const (
@@ -10,11 +14,11 @@ const (
 
 // 付款方式常量
 const (
-	PaymentMethodWechat    = 0 // 微信
-	PaymentMethodAlipay    = 1 // 支付宝
-	PaymentMethodYouli     = 2 // 优利支付
-	PaymentMethodLingling  = 3 // 零零购支付
-	PaymentMethodPublic    = 9 // 对公转账
+	PaymentMethodWechat   = 0 // 微信
+	PaymentMethodAlipay   = 1 // 支付宝
+	PaymentMethodYouli    = 2 // 优利支付
+	PaymentMethodLingling = 3 // 零零购支付
+	PaymentMethodPublic   = 9 // 对公转账
 )
 
 // 收款主体常量
@@ -32,19 +36,21 @@ const (
 
 // PaymentCollection 收款记录实体
 type PaymentCollection struct {
-	ID              int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	OrderID         int        `gorm:"column:order_id;not null" json:"order_id"`
-	StudentID       int        `gorm:"column:student_id;not null" json:"student_id"`
-	PaymentScenario int        `gorm:"column:payment_scenario;not null" json:"payment_scenario"`
-	PaymentMethod   int        `gorm:"column:payment_method;not null" json:"payment_method"`
-	PaymentAmount   float64    `gorm:"column:payment_amount;type:decimal(10,2);not null" json:"payment_amount"`
-	Payer           string     `gorm:"column:payer;type:varchar(100)" json:"payer"`
-	PayeeEntity     int        `gorm:"column:payee_entity;not null" json:"payee_entity"`
-	TradingHours    *time.Time `gorm:"column:trading_hours" json:"trading_hours"`
-	ArrivalTime     *time.Time `gorm:"column:arrival_time" json:"arrival_time"`
-	MerchantOrder   string     `gorm:"column:merchant_order;type:varchar(100)" json:"merchant_order"`
-	Status          int        `gorm:"column:status;default:10" json:"status"`
-	CreateTime      time.Time  `gorm:"column:create_time;autoCreateTime" json:"create_time"`
+	ID              int         `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	OrderID         int         `gorm:"column:order_id;not null" json:"order_id"`
+	StudentID       int         `gorm:"column:student_id;not null" json:"student_id"`
+	PaymentScenario int         `gorm:"column:payment_scenario;not null" json:"payment_scenario"`
+	PaymentMethod   int         `gorm:"column:payment_method;not null" json:"payment_method"`
+	PaymentAmount   money.Money `gorm:"column:payment_amount;type:decimal(10,2);not null" json:"payment_amount"`
+	Payer           string      `gorm:"column:payer;type:varchar(100)" json:"payer"`
+	PayeeEntity     int         `gorm:"column:payee_entity;not null" json:"payee_entity"`
+	TradingHours    *time.Time  `gorm:"column:trading_hours" json:"trading_hours"`
+	ArrivalTime     *time.Time  `gorm:"column:arrival_time" json:"arrival_time"`
+	MerchantOrder   string      `gorm:"column:merchant_order;type:varchar(100)" json:"merchant_order"`
+	InstalmentID    *int        `gorm:"column:instalment_id" json:"instalment_id"`       // 收款时匹配到的订单分期
+	GatewayTradeNo  *string     `gorm:"column:gateway_trade_no" json:"gateway_trade_no"` // 在线支付的网关交易号
+	Status          int         `gorm:"column:status;default:10" json:"status"`
+	CreateTime      time.Time   `gorm:"column:create_time;autoCreateTime" json:"create_time"`
 }
 
 // TableName 指定表名
@@ -64,7 +70,24 @@ func (p *PaymentCollection) CanDelete() bool {
 
 // Confirm 确认到账
 func (p *PaymentCollection) Confirm() {
+	p.ConfirmAt(time.Now())
+}
+
+// ConfirmAt 按实际到账时间确认到账，如对账时取账单中的交易时间
+func (p *PaymentCollection) ConfirmAt(arrivalTime time.Time) {
+	p.Status = PaymentStatusPaid
+	p.ArrivalTime = &arrivalTime
+}
+
+// IsAwaitingGateway 判断是否为等待网关支付通知的在线收款
+func (p *PaymentCollection) IsAwaitingGateway() bool {
+	return p.PaymentScenario == PaymentScenarioOnline && p.Status == PaymentStatusWaitPay
+}
+
+// ConfirmByGateway 按网关支付通知确认到账，记录网关交易号和支付时间
+func (p *PaymentCollection) ConfirmByGateway(tradeNo string, paidAt time.Time) {
 	p.Status = PaymentStatusPaid
-	now := time.Now()
-	p.ArrivalTime = &now
+	p.GatewayTradeNo = &tradeNo
+	p.TradingHours = &paidAt
+	p.ArrivalTime = &paidAt
 }
@@ -0,0 +1,48 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"charonoms/internal/domain/shared/money"
+)
+
+// ErrInvalidNotifySignature 支付网关异步通知验签失败
+var ErrInvalidNotifySignature = errors.New("支付通知签名校验失败")
+
+// ChargeRequest 在线支付下单请求
+type ChargeRequest struct {
+	MerchantOrder string      // 商户订单号，网关通知时原样返回
+	Amount        money.Money // 支付金额
+	PaymentMethod int         // 付款方式：微信、支付宝
+	Subject       string      // 商品描述，展示在支付页面
+}
+
+// Charge 在线支付下单结果，前端跳转支付链接或展示二维码
+type Charge struct {
+	PayURL     string    `json:"pay_url"`
+	QRCode     string    `json:"qr_code"` // 二维码内容
+	ExpireTime time.Time `json:"expire_time"`
+}
+
+// Notification 已验签的支付网关异步通知
+type Notification struct {
+	MerchantOrder string
+	TradeNo       string // 网关交易号
+	Amount        money.Money
+	Paid          bool // 是否支付成功，关闭、失败等通知为 false
+	PaidAt        time.Time
+}
+
+// PaymentGateway 在线支付网关端口，每个渠道一个适配器
+type PaymentGateway interface {
+	// Name 网关名称
+	Name() string
+
+	// CreateCharge 向网关下单，返回支付链接或二维码内容
+	CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error)
+
+	// ParseNotification 校验异步通知签名并解析通知内容，签名不合法时返回 ErrInvalidNotifySignature
+	ParseNotification(header map[string][]string, body []byte) (*Notification, error)
+}
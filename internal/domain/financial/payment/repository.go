@@ -1,5 +1,11 @@
 package payment
 
+import (
+	"charonoms/internal/domain/shared/money"
+
+	"gorm.io/gorm"
+)
+
 // PaymentListFilter 收款列表查询条件
 type PaymentListFilter struct {
 	ID            *int
@@ -15,12 +21,21 @@ type PaymentListFilter struct {
 
 // PaymentRepository 收款仓储接口
 type PaymentRepository interface {
+	// WithTx 返回绑定调用方事务的仓储实例，其读写都在该事务中执行
+	WithTx(tx *gorm.DB) PaymentRepository
+
 	// Create 创建收款记录
 	Create(payment *PaymentCollection) error
 
+	// CreateAwaitingGateway 创建待支付（status=0）的在线收款，等待网关支付通知
+	CreateAwaitingGateway(payment *PaymentCollection) error
+
 	// GetByID 根据ID查询收款记录
 	GetByID(id int) (*PaymentCollection, error)
 
+	// LockByMerchantOrder 按商户订单号查询在线收款并加行锁，不存在时返回 nil（需在事务中调用）
+	LockByMerchantOrder(merchantOrder string) (*PaymentCollection, error)
+
 	// List 查询收款列表
 	List(filter PaymentListFilter) ([]*PaymentCollection, int64, error)
 
@@ -31,8 +46,11 @@ type PaymentRepository interface {
 	Delete(id int) error
 
 	// GetTotalPaidAmount 查询订单已收款总额
-	// 包括常规收款（status=10或20）和淘宝收款（status=30）
-	GetTotalPaidAmount(orderID int) (float64, error)
+	// 包括常规收款（status=10或20）和淘宝收款（status=20或30）
+	GetTotalPaidAmount(orderID int) (money.Money, error)
+
+	// GetAwaitingGatewayAmount 查询订单等待网关支付通知（status=0）的在线收款总额
+	GetAwaitingGatewayAmount(orderID int) (money.Money, error)
 
 	// CountByOrderAndStatus 统计订单指定状态的收款数量
 	CountByOrderAndStatus(orderID int, status int) (int64, error)
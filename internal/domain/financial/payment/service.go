@@ -7,13 +7,20 @@ import (
 
 	orderEntity "charonoms/internal/domain/order/entity"
 	orderRepo "charonoms/internal/domain/order/repository"
+	orderService "charonoms/internal/domain/order/service"
+	"charonoms/internal/domain/shared/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // PaymentDomainService 收款领域服务
 type PaymentDomainService struct {
-	paymentRepo     PaymentRepository
-	orderRepo       orderRepo.OrderRepository
-	childOrderRepo  orderRepo.ChildOrderRepository
+	paymentRepo        PaymentRepository
+	orderRepo          orderRepo.OrderRepository
+	childOrderRepo     orderRepo.ChildOrderRepository
+	orderStatusService *orderService.OrderStatusService
+	tx                 *gorm.DB // 非空时订单状态流转在该事务中完成
 }
 
 // NewPaymentDomainService 创建收款领域服务
@@ -21,47 +28,119 @@ func NewPaymentDomainService(
 	paymentRepo PaymentRepository,
 	orderRepo orderRepo.OrderRepository,
 	childOrderRepo orderRepo.ChildOrderRepository,
+	orderStatusService *orderService.OrderStatusService,
 ) *PaymentDomainService {
 	return &PaymentDomainService{
-		paymentRepo:    paymentRepo,
-		orderRepo:      orderRepo,
-		childOrderRepo: childOrderRepo,
+		paymentRepo:        paymentRepo,
+		orderRepo:          orderRepo,
+		childOrderRepo:     childOrderRepo,
+		orderStatusService: orderStatusService,
+	}
+}
+
+// WithTx 返回在调用方事务中读写的领域服务：仓储和订单状态流转都使用 tx
+func (s *PaymentDomainService) WithTx(tx *gorm.DB) *PaymentDomainService {
+	return &PaymentDomainService{
+		paymentRepo:        s.paymentRepo.WithTx(tx),
+		orderRepo:          s.orderRepo.WithTx(tx),
+		childOrderRepo:     s.childOrderRepo.WithTx(tx),
+		orderStatusService: s.orderStatusService,
+		tx:                 tx,
 	}
 }
 
 // ValidatePaymentAmount 验证付款金额不超过待支付金额
-func (s *PaymentDomainService) ValidatePaymentAmount(orderID int, paymentAmount float64) error {
+func (s *PaymentDomainService) ValidatePaymentAmount(orderID int, paymentAmount money.Money) error {
+	unpaidAmount, err := s.unpaidAmount(orderID)
+	if err != nil {
+		return err
+	}
+
+	// 验证付款金额不超过待支付金额
+	if paymentAmount.GreaterThan(unpaidAmount) {
+		return fmt.Errorf("付款金额%s不能超过待支付金额%s", paymentAmount, unpaidAmount)
+	}
+
+	return nil
+}
+
+// ValidateOnlineChargeAmount 验证在线支付金额不超过待支付金额减去尚未支付的在线收款
+// 同一订单发起的多笔在线支付都到账后合计不会超过待支付金额
+func (s *PaymentDomainService) ValidateOnlineChargeAmount(orderID int, paymentAmount money.Money) error {
+	unpaidAmount, err := s.unpaidAmount(orderID)
+	if err != nil {
+		return err
+	}
+
+	awaiting, err := s.paymentRepo.GetAwaitingGatewayAmount(orderID)
+	if err != nil {
+		return fmt.Errorf("计算待支付在线收款总额失败: %w", err)
+	}
+	if awaiting.IsPositive() && paymentAmount.GreaterThan(unpaidAmount.Sub(awaiting)) {
+		return fmt.Errorf("订单已有%s在线支付尚未完成，付款金额%s不能超过剩余待支付金额%s",
+			awaiting, paymentAmount, unpaidAmount.Sub(awaiting))
+	}
+	if paymentAmount.GreaterThan(unpaidAmount) {
+		return fmt.Errorf("付款金额%s不能超过待支付金额%s", paymentAmount, unpaidAmount)
+	}
+
+	return nil
+}
+
+// ValidateArrivalAmount 锁定订单行后验证到账金额不超过待支付金额，同一订单并发到账的收款在此串行
+// 需通过 WithTx 在事务中调用
+func (s *PaymentDomainService) ValidateArrivalAmount(orderID int, paymentAmount money.Money) error {
+	if err := s.LockOrder(orderID); err != nil {
+		return err
+	}
+	return s.ValidatePaymentAmount(orderID, paymentAmount)
+}
+
+// LockOrder 锁定订单行，同一订单并发的收款校验、分期匹配和到账在锁内串行
+// 需通过 WithTx 在事务中调用
+func (s *PaymentDomainService) LockOrder(orderID int) error {
+	if s.tx == nil {
+		return errors.New("锁定订单需要在事务中执行")
+	}
+
+	var id int
+	err := s.tx.Table("orders").
+		Select("id").
+		Where("id = ?", orderID).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scan(&id).Error
+	if err != nil {
+		return fmt.Errorf("锁定订单失败: %w", err)
+	}
+	if id == 0 {
+		return errors.New("订单不存在")
+	}
+	return nil
+}
+
+// unpaidAmount 计算订单待支付金额：实收金额 - 已收款总额
+func (s *PaymentDomainService) unpaidAmount(orderID int) (money.Money, error) {
 	// 1. 查询订单实收金额
 	ctx := context.Background()
 	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
 	if err != nil {
-		return fmt.Errorf("查询订单失败: %w", err)
+		return money.Zero, fmt.Errorf("查询订单失败: %w", err)
 	}
 	if order == nil {
-		return errors.New("订单不存在")
+		return money.Zero, errors.New("订单不存在")
 	}
 
-	actualAmount := order.AmountReceived
-
 	// 2. 计算已收款总额
 	totalPaid, err := s.paymentRepo.GetTotalPaidAmount(orderID)
 	if err != nil {
-		return fmt.Errorf("计算已收款总额失败: %w", err)
+		return money.Zero, fmt.Errorf("计算已收款总额失败: %w", err)
 	}
 
-	// 3. 计算待支付金额
-	unpaidAmount := actualAmount - totalPaid
-
-	// 4. 验证付款金额不超过待支付金额
-	if paymentAmount > unpaidAmount {
-		return fmt.Errorf("付款金额%.2f不能超过待支付金额%.2f", paymentAmount, unpaidAmount)
-	}
-
-	return nil
+	return order.AmountReceived.Sub(totalPaid), nil
 }
 
-// UpdateOrderPaymentStatus 更新订单支付状态
-func (s *PaymentDomainService) UpdateOrderPaymentStatus(orderID int) error {
+// UpdateOrderPaymentStatus 按收款情况更新订单支付状态，operator 和 reason 记录到订单状态历史
+func (s *PaymentDomainService) UpdateOrderPaymentStatus(orderID int, operator, reason string) error {
 	// 1. 查询订单实收金额
 	ctx := context.Background()
 	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
@@ -81,18 +160,21 @@ func (s *PaymentDomainService) UpdateOrderPaymentStatus(orderID int) error {
 	}
 
 	// 3. 根据收款情况确定订单状态
-	var newStatus int
-	if totalPaid == 0 {
-		newStatus = orderEntity.OrderStatusUnpaid // 20-未支付
-	} else if totalPaid < actualAmount {
-		newStatus = orderEntity.OrderStatusPartialPaid // 30-部分支付
-	} else {
-		newStatus = orderEntity.OrderStatusPaid // 40-已支付
-	}
+	newStatus := orderEntity.PaymentStatus(totalPaid, actualAmount)
 
 	// 4. 更新订单状态
 	if order.Status != newStatus {
-		err = s.orderRepo.UpdateOrderStatus(ctx, orderID, newStatus, 0)
+		change := orderService.StatusChange{
+			OrderID:  orderID,
+			To:       newStatus,
+			Operator: operator,
+			Reason:   reason,
+		}
+		if s.tx != nil {
+			err = s.orderStatusService.TransitionTx(s.tx, change)
+		} else {
+			err = s.orderStatusService.Transition(ctx, change)
+		}
 		if err != nil {
 			return fmt.Errorf("更新订单状态失败: %w", err)
 		}
@@ -101,6 +183,35 @@ func (s *PaymentDomainService) UpdateOrderPaymentStatus(orderID int) error {
 	return nil
 }
 
+// MatchNextInstalment 获取收款应匹配的订单分期：按期数顺序冲抵已收款后第一个未收齐的分期
+// 订单未设置分期且没有预计付款时间时返回 nil；分期已全部收齐时返回错误
+func (s *PaymentDomainService) MatchNextInstalment(orderID int) (*orderEntity.InstalmentProgress, error) {
+	ctx := context.Background()
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	if order == nil {
+		return nil, errors.New("订单不存在")
+	}
+
+	plan := order.InstalmentPlan()
+	if len(plan) == 0 {
+		return nil, nil
+	}
+
+	totalPaid, err := s.paymentRepo.GetTotalPaidAmount(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("计算已收款总额失败: %w", err)
+	}
+
+	next := orderEntity.NextOpenInstalment(orderEntity.AllocateInstalmentPayments(plan, totalPaid))
+	if next == nil {
+		return nil, errors.New("订单各期款项均已收齐")
+	}
+	return next, nil
+}
+
 // GetOrder 获取订单信息
 func (s *PaymentDomainService) GetOrder(orderID int) (*orderEntity.Order, error) {
 	ctx := context.Background()
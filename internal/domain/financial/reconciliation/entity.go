@@ -0,0 +1,134 @@
+package reconciliation
+
+import (
+	"time"
+
+	"charonoms/internal/domain/financial/payment"
+	"charonoms/internal/domain/shared/money"
+)
+
+// 对账渠道常量，每个渠道一种账单格式
+const (
+	ChannelWechat = "wechat" // 微信支付账单（CSV）
+	ChannelAlipay = "alipay" // 支付宝账单（CSV），淘宝收款也在支付宝账单中
+	ChannelBank   = "bank"   // 对公账户银行流水（Excel）
+)
+
+// channelPaymentMethods 各渠道账单对应的收款付款方式
+var channelPaymentMethods = map[string][]int{
+	ChannelWechat: {payment.PaymentMethodWechat},
+	ChannelAlipay: {payment.PaymentMethodAlipay},
+	ChannelBank:   {payment.PaymentMethodPublic},
+}
+
+// PaymentMethods 返回渠道账单对应的付款方式，未知渠道返回 nil
+func PaymentMethods(channel string) []int {
+	return channelPaymentMethods[channel]
+}
+
+// 系统记录来源常量
+const (
+	SourcePayment   = "payment_collection" // 常规收款
+	SourceTaobao    = "taobao_payment"     // 淘宝收款
+	SourceUnclaimed = "unclaimed"          // 常规待认领
+)
+
+// 对账结果常量
+const (
+	ResultMatched            = 0 // 已匹配
+	ResultAmountMismatch     = 1 // 金额不一致
+	ResultMissingInSystem    = 2 // 账单有、系统无
+	ResultMissingInStatement = 3 // 系统有、账单无
+)
+
+// 匹配方式常量
+const (
+	MatchByMerchantOrder = "merchant_order" // 商户订单号一致
+	MatchByAmountDate    = "amount_date"    // 无商户订单号时按金额、日期和收款主体匹配
+)
+
+// StatementLine 渠道账单中的一笔入账
+type StatementLine struct {
+	LineNo        int         // 账单中的行号，用于定位原始记录
+	MerchantOrder string      // 商户订单号，银行流水通常没有
+	TradeNo       string      // 渠道交易号或银行流水号
+	Amount        money.Money // 入账金额
+	TradeTime     time.Time   // 交易时间
+	Payer         string      // 付款方
+}
+
+// SystemRecord 参与对账的系统收款记录
+type SystemRecord struct {
+	Source        string
+	ID            int
+	MerchantOrder string
+	Amount        money.Money
+	Time          time.Time // 到账时间，尚未到账的记录为交易时间或创建时间
+	PayeeEntity   *int      // 收款主体，淘宝收款没有收款主体
+	Status        int       // 来源表中的状态
+}
+
+// RecordQuery 系统记录查询条件：对账期间内的记录，以及账单中出现的商户订单号对应的记录
+type RecordQuery struct {
+	PaymentMethods []int // 渠道对应的付款方式
+	PayeeEntity    int
+	IncludeTaobao  bool
+	Start          time.Time // 含
+	End            time.Time // 不含
+	MerchantOrders []string
+}
+
+// Batch 一次账单上传的对账批次
+type Batch struct {
+	ID                  int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Channel             string    `gorm:"column:channel;type:varchar(20);not null" json:"channel"`
+	PayeeEntity         int       `gorm:"column:payee_entity;not null" json:"payee_entity"`
+	FileName            string    `gorm:"column:file_name;type:varchar(255)" json:"file_name"`
+	PeriodStart         time.Time `gorm:"column:period_start" json:"period_start"`
+	PeriodEnd           time.Time `gorm:"column:period_end" json:"period_end"`
+	MatchedCount        int       `gorm:"column:matched_count" json:"matched_count"`
+	AmountMismatchCount int       `gorm:"column:amount_mismatch_count" json:"amount_mismatch_count"`
+	MissingSystemCount  int       `gorm:"column:missing_system_count" json:"missing_system_count"`
+	MissingStmtCount    int       `gorm:"column:missing_statement_count" json:"missing_statement_count"`
+	Operator            int       `gorm:"column:operator" json:"operator"`
+	CreateTime          time.Time `gorm:"column:create_time;autoCreateTime" json:"create_time"`
+}
+
+// TableName 指定表名
+func (Batch) TableName() string {
+	return "reconciliation_batch"
+}
+
+// Item 对账明细，一行账单和/或一条系统记录的对账结果
+type Item struct {
+	ID                int          `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	BatchID           int          `gorm:"column:batch_id;not null" json:"batch_id"`
+	Result            int          `gorm:"column:result;not null" json:"result"`
+	MatchedBy         string       `gorm:"column:matched_by;type:varchar(20)" json:"matched_by"`
+	LineNo            *int         `gorm:"column:line_no" json:"line_no"`
+	LineMerchantOrder *string      `gorm:"column:line_merchant_order;type:varchar(100)" json:"line_merchant_order"`
+	LineTradeNo       *string      `gorm:"column:line_trade_no;type:varchar(100)" json:"line_trade_no"`
+	LineAmount        *money.Money `gorm:"column:line_amount;type:decimal(10,2)" json:"line_amount"`
+	LineTradeTime     *time.Time   `gorm:"column:line_trade_time" json:"line_trade_time"`
+	LinePayer         *string      `gorm:"column:line_payer;type:varchar(100)" json:"line_payer"`
+	RecordSource      *string      `gorm:"column:record_source;type:varchar(30)" json:"record_source"`
+	RecordID          *int         `gorm:"column:record_id" json:"record_id"`
+	RecordAmount      *money.Money `gorm:"column:record_amount;type:decimal(10,2)" json:"record_amount"`
+	RecordStatus      *int         `gorm:"column:record_status" json:"record_status"`
+	Confirmed         bool         `gorm:"column:confirmed" json:"confirmed"`
+	ConfirmTime       *time.Time   `gorm:"column:confirm_time" json:"confirm_time"`
+	CreateTime        time.Time    `gorm:"column:create_time;autoCreateTime" json:"create_time"`
+}
+
+// TableName 指定表名
+func (Item) TableName() string {
+	return "reconciliation_item"
+}
+
+// CanConfirm 判断能否按对账结果确认到账：已匹配、尚未确认过的未核验常规收款
+// RecordStatus 是对账时的状态快照，确认时仍需按收款记录的当前状态再次校验
+func (i *Item) CanConfirm() bool {
+	return i.Result == ResultMatched && !i.Confirmed &&
+		i.RecordSource != nil && *i.RecordSource == SourcePayment &&
+		i.RecordID != nil && i.RecordStatus != nil && *i.RecordStatus == payment.PaymentStatusUnverified
+}
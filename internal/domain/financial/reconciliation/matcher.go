@@ -0,0 +1,140 @@
+package reconciliation
+
+import "time"
+
+// dateTolerance 按金额匹配时，账单交易日期与系统记录日期允许相差的天数（跨日到账）
+const dateTolerance = 1
+
+// Match 将账单明细与收款主体 payeeEntity 的系统记录逐笔匹配，每条系统记录最多匹配一行账单
+// 1. 商户订单号一致的记录：金额相同为已匹配，金额不同为金额不一致
+// 2. 其余账单行在未匹配的记录中按金额、日期（相差不超过 dateTolerance 天）匹配，至少一方没有商户订单号，取日期最接近的一条
+// 3. 剩余的账单行为系统缺失，剩余的系统记录为账单缺失
+// 返回的明细按账单行顺序排列，账单缺失的记录排在最后
+func Match(lines []StatementLine, records []SystemRecord, payeeEntity int) []*Item {
+	used := make([]bool, len(records))
+	byOrder := make(map[string][]int)
+	for i, r := range records {
+		if r.MerchantOrder != "" && r.matchesPayee(payeeEntity) {
+			byOrder[r.MerchantOrder] = append(byOrder[r.MerchantOrder], i)
+		}
+	}
+
+	items := make([]*Item, len(lines))
+	var pending []int
+	for li, line := range lines {
+		items[li] = newLineItem(line)
+		if line.MerchantOrder == "" {
+			pending = append(pending, li)
+			continue
+		}
+
+		// 同一订单号有多条记录时优先取金额一致的
+		candidates := byOrder[line.MerchantOrder]
+		hit := -1
+		for _, ri := range candidates {
+			if used[ri] {
+				continue
+			}
+			if hit < 0 || records[ri].Amount.Equal(line.Amount) && !records[hit].Amount.Equal(line.Amount) {
+				hit = ri
+			}
+		}
+		if hit < 0 {
+			pending = append(pending, li)
+			continue
+		}
+
+		used[hit] = true
+		items[li].attach(records[hit], MatchByMerchantOrder)
+		if !records[hit].Amount.Equal(line.Amount) {
+			items[li].Result = ResultAmountMismatch
+		}
+	}
+
+	for _, li := range pending {
+		line := lines[li]
+		hit, bestDays := -1, 0
+		for ri, r := range records {
+			if used[ri] || !r.matchesPayee(payeeEntity) || !r.Amount.Equal(line.Amount) {
+				continue
+			}
+			if line.MerchantOrder != "" && r.MerchantOrder != "" {
+				continue
+			}
+			days := daysBetween(line.TradeTime, r.Time)
+			if days > dateTolerance {
+				continue
+			}
+			if hit < 0 || days < bestDays {
+				hit, bestDays = ri, days
+			}
+		}
+		if hit >= 0 {
+			used[hit] = true
+			items[li].attach(records[hit], MatchByAmountDate)
+		}
+	}
+
+	for ri, r := range records {
+		if !used[ri] && r.matchesPayee(payeeEntity) {
+			item := &Item{}
+			item.attach(r, "")
+			item.Result = ResultMissingInStatement
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// matchesPayee 收款主体是否一致，没有收款主体的记录（淘宝收款）不限制
+func (r SystemRecord) matchesPayee(payeeEntity int) bool {
+	return r.PayeeEntity == nil || *r.PayeeEntity == payeeEntity
+}
+
+// newLineItem 按账单行创建明细，未关联系统记录前为系统缺失
+func newLineItem(line StatementLine) *Item {
+	lineNo := line.LineNo
+	amount := line.Amount
+	tradeTime := line.TradeTime
+	item := &Item{
+		Result:        ResultMissingInSystem,
+		LineNo:        &lineNo,
+		LineAmount:    &amount,
+		LineTradeTime: &tradeTime,
+	}
+	if line.MerchantOrder != "" {
+		merchantOrder := line.MerchantOrder
+		item.LineMerchantOrder = &merchantOrder
+	}
+	if line.TradeNo != "" {
+		tradeNo := line.TradeNo
+		item.LineTradeNo = &tradeNo
+	}
+	if line.Payer != "" {
+		payer := line.Payer
+		item.LinePayer = &payer
+	}
+	return item
+}
+
+// attach 关联系统记录并标记为已匹配
+func (i *Item) attach(r SystemRecord, matchedBy string) {
+	source, id, amount, status := r.Source, r.ID, r.Amount, r.Status
+	i.Result = ResultMatched
+	i.MatchedBy = matchedBy
+	i.RecordSource = &source
+	i.RecordID = &id
+	i.RecordAmount = &amount
+	i.RecordStatus = &status
+}
+
+// daysBetween 两个时间相差的自然日天数
+func daysBetween(a, b time.Time) int {
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(da.Sub(db).Hours() / 24)
+	if days < 0 {
+		days = -days
+	}
+	return days
+}
@@ -0,0 +1,160 @@
+package reconciliation
+
+import (
+	"testing"
+	"time"
+
+	"charonoms/internal/domain/shared/money"
+)
+
+func day(d int) time.Time {
+	return time.Date(2026, 10, d, 10, 0, 0, 0, time.Local)
+}
+
+func payee(p int) *int {
+	return &p
+}
+
+func line(no int, merchantOrder string, amount float64, at time.Time) StatementLine {
+	return StatementLine{LineNo: no, MerchantOrder: merchantOrder, Amount: money.FromFloat(amount), TradeTime: at}
+}
+
+func record(source string, id int, merchantOrder string, amount float64, at time.Time, payeeEntity *int) SystemRecord {
+	return SystemRecord{Source: source, ID: id, MerchantOrder: merchantOrder, Amount: money.FromFloat(amount), Time: at, PayeeEntity: payeeEntity, Status: 10}
+}
+
+func TestMatch(t *testing.T) {
+	lines := []StatementLine{
+		line(2, "M001", 1000, day(10)), // 订单号一致
+		line(3, "M002", 500, day(10)),  // 订单号一致但金额不同
+		line(4, "", 300, day(11)),      // 无订单号，按金额和日期匹配到次日到账的待认领
+		line(5, "M404", 200, day(12)),  // 系统中没有
+		line(6, "", 800, day(12)),      // 金额相同但日期相差超过一天
+		line(7, "T001", 99.9, day(12)), // 淘宝收款没有收款主体
+	}
+	records := []SystemRecord{
+		record(SourcePayment, 1, "M001", 1000, day(10), payee(0)),
+		record(SourcePayment, 2, "M002", 550, day(10), payee(0)),
+		record(SourceUnclaimed, 3, "", 300, day(12), payee(0)),
+		record(SourcePayment, 4, "", 800, day(15), payee(0)),
+		record(SourceTaobao, 5, "T001", 99.9, day(12), nil),
+		record(SourcePayment, 6, "M001", 1000, day(10), payee(1)), // 其他收款主体
+	}
+
+	items := Match(lines, records, 0)
+
+	want := []struct {
+		result    int
+		matchedBy string
+		recordID  int // 0 表示未关联系统记录
+		lineNo    int // 0 表示不是账单行
+	}{
+		{ResultMatched, MatchByMerchantOrder, 1, 2},
+		{ResultAmountMismatch, MatchByMerchantOrder, 2, 3},
+		{ResultMatched, MatchByAmountDate, 3, 4},
+		{ResultMissingInSystem, "", 0, 5},
+		{ResultMissingInSystem, "", 0, 6},
+		{ResultMatched, MatchByMerchantOrder, 5, 7},
+		{ResultMissingInStatement, "", 4, 0},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("Match() returned %d items, want %d", len(items), len(want))
+	}
+	for i, w := range want {
+		item := items[i]
+		if item.Result != w.result || item.MatchedBy != w.matchedBy {
+			t.Errorf("item %d: result = %d matchedBy = %q, want %d %q", i, item.Result, item.MatchedBy, w.result, w.matchedBy)
+		}
+		gotRecord := 0
+		if item.RecordID != nil {
+			gotRecord = *item.RecordID
+		}
+		if gotRecord != w.recordID {
+			t.Errorf("item %d: record id = %d, want %d", i, gotRecord, w.recordID)
+		}
+		gotLine := 0
+		if item.LineNo != nil {
+			gotLine = *item.LineNo
+		}
+		if gotLine != w.lineNo {
+			t.Errorf("item %d: line no = %d, want %d", i, gotLine, w.lineNo)
+		}
+	}
+}
+
+// TestMatch_EachRecordOnce 同金额同日期的多行账单各自匹配一条记录，不会重复使用同一条记录
+func TestMatch_EachRecordOnce(t *testing.T) {
+	lines := []StatementLine{
+		line(2, "", 100, day(10)),
+		line(3, "", 100, day(10)),
+		line(4, "", 100, day(10)),
+	}
+	records := []SystemRecord{
+		record(SourceUnclaimed, 1, "", 100, day(11), payee(0)),
+		record(SourceUnclaimed, 2, "", 100, day(10), payee(0)),
+	}
+
+	items := Match(lines, records, 0)
+
+	if len(items) != 3 {
+		t.Fatalf("Match() returned %d items, want 3", len(items))
+	}
+	// 日期最接近的记录优先
+	if items[0].RecordID == nil || *items[0].RecordID != 2 {
+		t.Errorf("line 2 should match record 2 (same day)")
+	}
+	if items[1].RecordID == nil || *items[1].RecordID != 1 {
+		t.Errorf("line 3 should match record 1")
+	}
+	if items[2].Result != ResultMissingInSystem {
+		t.Errorf("line 4 result = %d, want missing in system", items[2].Result)
+	}
+}
+
+// TestMatch_PreferEqualAmount 同一商户订单号有多条记录时优先匹配金额一致的记录
+func TestMatch_PreferEqualAmount(t *testing.T) {
+	lines := []StatementLine{line(2, "M001", 300, day(10))}
+	records := []SystemRecord{
+		record(SourcePayment, 1, "M001", 200, day(10), payee(0)),
+		record(SourcePayment, 2, "M001", 300, day(10), payee(0)),
+	}
+
+	items := Match(lines, records, 0)
+
+	if items[0].Result != ResultMatched || *items[0].RecordID != 2 {
+		t.Errorf("line matched record %d with result %d, want record 2 matched", *items[0].RecordID, items[0].Result)
+	}
+	if len(items) != 2 || items[1].Result != ResultMissingInStatement || *items[1].RecordID != 1 {
+		t.Errorf("record 1 should be missing in statement")
+	}
+}
+
+func TestItem_CanConfirm(t *testing.T) {
+	matched := func(source string, status int, confirmed bool) *Item {
+		item := &Item{}
+		item.attach(SystemRecord{Source: source, ID: 1, Status: status}, MatchByMerchantOrder)
+		item.Confirmed = confirmed
+		return item
+	}
+	mismatch := matched(SourcePayment, 10, false)
+	mismatch.Result = ResultAmountMismatch
+
+	tests := []struct {
+		name string
+		item *Item
+		want bool
+	}{
+		{"已匹配的未核验收款", matched(SourcePayment, 10, false), true},
+		{"已确认过", matched(SourcePayment, 10, true), false},
+		{"收款已到账", matched(SourcePayment, 20, false), false},
+		{"待认领记录", matched(SourceUnclaimed, 0, false), false},
+		{"金额不一致", mismatch, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.item.CanConfirm(); got != tt.want {
+				t.Errorf("CanConfirm() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package reconciliation
+
+import "io"
+
+// StatementParser 渠道账单解析器端口，每个渠道一个实现
+type StatementParser interface {
+	// Channel 渠道编码
+	Channel() string
+
+	// Parse 解析账单文件，只返回入账成功的明细，汇总行、退款行等不参与对账
+	Parse(r io.Reader) ([]StatementLine, error)
+}
@@ -0,0 +1,27 @@
+package reconciliation
+
+import "gorm.io/gorm"
+
+// ReconciliationRepository 对账仓储接口
+type ReconciliationRepository interface {
+	// WithTx 返回绑定调用方事务的仓储实例，其读写都在该事务中执行
+	WithTx(tx *gorm.DB) ReconciliationRepository
+
+	// ListSystemRecords 查询参与对账的收款、淘宝收款和待认领记录
+	ListSystemRecords(query RecordQuery) ([]SystemRecord, error)
+
+	// CreateBatch 保存对账批次及其明细
+	CreateBatch(batch *Batch, items []*Item) error
+
+	// GetBatch 根据ID查询对账批次，不存在时返回 nil
+	GetBatch(id int) (*Batch, error)
+
+	// ListBatches 查询对账批次列表，按创建时间倒序
+	ListBatches(channel string, page, pageSize int) ([]*Batch, int64, error)
+
+	// ListItems 查询批次明细，result 为 nil 时返回全部
+	ListItems(batchID int, result *int) ([]*Item, error)
+
+	// MarkConfirmed 标记明细已按对账结果确认到账
+	MarkConfirmed(itemID int) error
+}
@@ -0,0 +1,645 @@
+package refund
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	orderEntity "charonoms/internal/domain/order/entity"
+	orderService "charonoms/internal/domain/order/service"
+	"charonoms/internal/domain/shared/money"
+)
+
+// ApprovalHook 退费审批完成钩子，由审批引擎在同一事务中回调（businessID 为退费订单ID）
+type ApprovalHook struct {
+	orderStatus *orderService.OrderStatusService
+	setting     orderEntity.AllocationSetting
+}
+
+// NewApprovalHook 创建退费审批完成钩子，setting 为系统默认的收款分账策略，重新分账时使用
+func NewApprovalHook(db *gorm.DB, setting orderEntity.AllocationSetting) *ApprovalHook {
+	return &ApprovalHook{
+		orderStatus: orderService.NewOrderStatusService(db),
+		setting:     setting,
+	}
+}
+
+// OnApproved 退费审批通过：冲回原分账、按退费后金额重新分账，并按净收款刷新订单状态
+func (h *ApprovalHook) OnApproved(tx *gorm.DB, refundOrderID int) error {
+	// 1. 锁定待审批的退费订单
+	refundOrder, err := lockPendingRefundOrder(tx, refundOrderID)
+	if err != nil {
+		return err
+	}
+	orderID := refundOrder.OrderID
+	studentID := refundOrder.StudentID
+
+	// 2. 更新退费订单及明细状态为已通过(10)
+	if err := updateRefundStatus(tx, refundOrderID, RefundStatusApproved); err != nil {
+		return err
+	}
+
+	// 3. 执行退费冲回和重分账逻辑（按照Python版本实现）
+	// 3.1 获取退费相关信息
+	// 获取该订单的所有常规收款（已到账）
+	var regularPayments []struct {
+		PaymentID     int         `gorm:"column:payment_id"`
+		PaymentAmount money.Money `gorm:"column:payment_amount"`
+		PaymentType   int         `gorm:"column:payment_type"`
+	}
+	if err := tx.Raw(`
+		SELECT id as payment_id, payment_amount, 0 as payment_type
+		FROM payment_collection
+		WHERE order_id = ? AND status IN (10, 20)
+		ORDER BY id ASC
+	`, orderID).Scan(&regularPayments).Error; err != nil {
+		return err
+	}
+
+	// 获取该订单的所有淘宝收款（已到账）
+	var taobaoPayments []struct {
+		PaymentID     int         `gorm:"column:payment_id"`
+		PaymentAmount money.Money `gorm:"column:payment_amount"`
+		PaymentType   int         `gorm:"column:payment_type"`
+	}
+	if err := tx.Raw(`
+		SELECT id as payment_id, payment_amount, 1 as payment_type
+		FROM taobao_payment
+		WHERE order_id = ? AND status = 30
+		ORDER BY id ASC
+	`, orderID).Scan(&taobaoPayments).Error; err != nil {
+		return err
+	}
+
+	// 检查是否有收款信息
+	hasPayment := len(regularPayments) > 0 || len(taobaoPayments) > 0
+	if !hasPayment {
+		// 无收款信息，跳过分账逻辑
+		return nil
+	}
+
+	// 3.2 检查是否需要执行冲回
+	needChargeback := false
+
+	// 获取本次退费涉及的子订单ID列表
+	var refundChildOrderIDs []int
+	if err := tx.Table("refund_order_item").
+		Select("DISTINCT childorder_id").
+		Where("refund_order_id = ?", refundOrderID).
+		Pluck("childorder_id", &refundChildOrderIDs).Error; err != nil {
+		return err
+	}
+
+	// 获取收款列表区填写的退费金额（按收款维度）
+	var refundPaymentsList []struct {
+		PaymentID    int         `gorm:"column:payment_id"`
+		PaymentType  int         `gorm:"column:payment_type"`
+		RefundAmount money.Money `gorm:"column:refund_amount"`
+	}
+	if err := tx.Table("refund_payment").
+		Select("payment_id, payment_type, refund_amount").
+		Where("refund_order_id = ?", refundOrderID).
+		Scan(&refundPaymentsList).Error; err != nil {
+		return err
+	}
+
+	// 检查每个收款的分账金额是否充足
+	for _, rp := range refundPaymentsList {
+		if len(refundChildOrderIDs) == 0 {
+			break
+		}
+
+		// 计算该收款在被退费子订单上的分账总额（仅计算未冲回的售卖类）
+		var totalSeparate money.Money
+		if err := tx.Raw(`
+			SELECT COALESCE(SUM(separate_amount), 0) as total_separate
+			FROM separate_account
+			WHERE payment_id = ? AND payment_type = ?
+				AND childorders_id IN (?)
+				AND type = 0
+				AND id NOT IN (
+					SELECT parent_id FROM separate_account
+					WHERE payment_id = ? AND payment_type = ? AND parent_id IS NOT NULL
+				)
+		`, rp.PaymentID, rp.PaymentType, refundChildOrderIDs, rp.PaymentID, rp.PaymentType).
+			Scan(&totalSeparate).Error; err != nil {
+			return err
+		}
+
+		// 如果分账金额 < 退费金额，需要冲回
+		if totalSeparate.LessThan(rp.RefundAmount) {
+			needChargeback = true
+			break
+		}
+	}
+
+	// 3.3 执行冲回和重新分账，重新分账按订单的分账策略分配
+	if needChargeback {
+		var orderStrategy string
+		if err := tx.Raw("SELECT allocation_strategy FROM orders WHERE id = ?", orderID).Scan(&orderStrategy).Error; err != nil {
+			return err
+		}
+		allocator := h.setting.Allocator(orderStrategy)
+
+		// 3.3a. 冲回所有未冲回的售卖类分账明细
+		// 获取该订单所有未冲回的售卖类分账明细
+		var originalSeparates []struct {
+			ID             int         `gorm:"column:id"`
+			UID            int         `gorm:"column:uid"`
+			OrdersID       int         `gorm:"column:orders_id"`
+			ChildOrdersID  int         `gorm:"column:childorders_id"`
+			PaymentID      int         `gorm:"column:payment_id"`
+			PaymentType    int         `gorm:"column:payment_type"`
+			GoodsID        int         `gorm:"column:goods_id"`
+			GoodsName      string      `gorm:"column:goods_name"`
+			BrandID        *int        `gorm:"column:brand_id"`
+			BrandName      *string     `gorm:"column:brand_name"`
+			ClassifyID     *int        `gorm:"column:classify_id"`
+			ClassifyName   *string     `gorm:"column:classify_name"`
+			SeparateAmount money.Money `gorm:"column:separate_amount"`
+		}
+		if err := tx.Raw(`
+			SELECT id, uid, orders_id, childorders_id, payment_id, payment_type,
+				   goods_id, goods_name, brand_id, brand_name, classify_id, classify_name, separate_amount
+			FROM separate_account
+			WHERE orders_id = ? AND type = 0
+				AND id NOT IN (
+					SELECT parent_id FROM separate_account
+					WHERE orders_id = ? AND parent_id IS NOT NULL
+				)
+		`, orderID, orderID).Scan(&originalSeparates).Error; err != nil {
+			return err
+		}
+
+		// 生成冲回记录（type=1，负金额，记录parent_id）
+		for _, sep := range originalSeparates {
+			if err := tx.Table("separate_account").Create(map[string]interface{}{
+				"uid":             sep.UID,
+				"orders_id":       sep.OrdersID,
+				"childorders_id":  sep.ChildOrdersID,
+				"payment_id":      sep.PaymentID,
+				"payment_type":    sep.PaymentType,
+				"goods_id":        sep.GoodsID,
+				"goods_name":      sep.GoodsName,
+				"brand_id":        sep.BrandID,
+				"brand_name":      sep.BrandName,
+				"classify_id":     sep.ClassifyID,
+				"classify_name":   sep.ClassifyName,
+				"separate_amount": sep.SeparateAmount.Neg(),
+				"type":            1,
+				"parent_id":       sep.ID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		// 3.3b. 重新生成售卖类分账明细
+		// 获取退费子订单列表
+		var refundItemsList []struct {
+			ChildOrderID   int         `gorm:"column:childorder_id"`
+			RefundAmount   money.Money `gorm:"column:refund_amount"`
+			AmountReceived money.Money `gorm:"column:amount_received"`
+			GoodsID        int         `gorm:"column:goodsid"`
+			GoodsName      string      `gorm:"column:goods_name"`
+			BrandID        *int        `gorm:"column:brand_id"`
+			BrandName      *string     `gorm:"column:brand_name"`
+			ClassifyID     *int        `gorm:"column:classify_id"`
+			ClassifyName   *string     `gorm:"column:classify_name"`
+		}
+		if err := tx.Raw(`
+			SELECT roi.childorder_id, roi.refund_amount, co.amount_received, co.goodsid, g.name as goods_name,
+				g.brandid AS brand_id, b.name AS brand_name, g.classifyid AS classify_id, c.name AS classify_name
+			FROM refund_order_item roi
+			INNER JOIN childorders co ON roi.childorder_id = co.id
+			LEFT JOIN goods g ON co.goodsid = g.id
+			LEFT JOIN brand b ON g.brandid = b.id
+			LEFT JOIN classify c ON g.classifyid = c.id
+			WHERE roi.refund_order_id = ?
+			ORDER BY roi.childorder_id ASC
+		`, refundOrderID).Scan(&refundItemsList).Error; err != nil {
+			return err
+		}
+
+		// 第一批售卖分账：用退费收款分配给退费子订单
+		// 为退费子订单记录剩余需求
+		refundChildRemaining := make(map[int]money.Money)
+		for _, item := range refundItemsList {
+			refundChildRemaining[item.ChildOrderID] = item.RefundAmount
+		}
+
+		// 按退费收款顺序，将每笔退费收款按分账策略分配到退费子订单
+		for _, rp := range refundPaymentsList {
+			targets := make([]orderEntity.AllocationTarget, len(refundItemsList))
+			for i, ri := range refundItemsList {
+				targets[i] = orderEntity.AllocationTarget{
+					ChildOrder: &orderEntity.ChildOrder{ID: ri.ChildOrderID, GoodsID: ri.GoodsID, AmountReceived: ri.AmountReceived},
+					ClassifyID: ri.ClassifyID,
+					Needed:     refundChildRemaining[ri.ChildOrderID],
+				}
+			}
+			shares := allocator.Allocate(rp.RefundAmount, targets)
+
+			for i, ri := range refundItemsList {
+				allocateAmount := shares[i]
+				if !allocateAmount.IsPositive() {
+					continue
+				}
+
+				// 插入第一批售卖分账明细
+				if err := tx.Table("separate_account").Create(map[string]interface{}{
+					"uid":             studentID,
+					"orders_id":       orderID,
+					"childorders_id":  ri.ChildOrderID,
+					"payment_id":      rp.PaymentID,
+					"payment_type":    rp.PaymentType,
+					"goods_id":        ri.GoodsID,
+					"goods_name":      ri.GoodsName,
+					"brand_id":        ri.BrandID,
+					"brand_name":      ri.BrandName,
+					"classify_id":     ri.ClassifyID,
+					"classify_name":   ri.ClassifyName,
+					"separate_amount": allocateAmount,
+					"type":            0,
+				}).Error; err != nil {
+					return err
+				}
+
+				refundChildRemaining[ri.ChildOrderID] = refundChildRemaining[ri.ChildOrderID].Sub(allocateAmount)
+			}
+		}
+
+		// 第二批售卖分账：用剩余收款分配给剩余子订单需求
+		// 合并所有收款
+		allPayments := make([]struct {
+			PaymentID     int
+			PaymentType   int
+			PaymentAmount money.Money
+		}, 0, len(regularPayments)+len(taobaoPayments))
+		for _, p := range regularPayments {
+			allPayments = append(allPayments, struct {
+				PaymentID     int
+				PaymentType   int
+				PaymentAmount money.Money
+			}{p.PaymentID, p.PaymentType, p.PaymentAmount})
+		}
+		for _, p := range taobaoPayments {
+			allPayments = append(allPayments, struct {
+				PaymentID     int
+				PaymentType   int
+				PaymentAmount money.Money
+			}{p.PaymentID, p.PaymentType, p.PaymentAmount})
+		}
+
+		// 构建退费收款map
+		refundPaymentMap := make(map[string]money.Money)
+		for _, rp := range refundPaymentsList {
+			key := fmt.Sprintf("%d_%d", rp.PaymentID, rp.PaymentType)
+			refundPaymentMap[key] = rp.RefundAmount
+		}
+
+		// 计算每个收款的剩余金额 = 总金额 - 退费金额
+		paymentRemaining := make(map[string]money.Money)
+		for _, payment := range allPayments {
+			key := fmt.Sprintf("%d_%d", payment.PaymentID, payment.PaymentType)
+			refundAmount := refundPaymentMap[key]
+			remaining := payment.PaymentAmount.Sub(refundAmount)
+			paymentRemaining[key] = remaining
+		}
+
+		// 获取所有子订单
+		var allChildOrders []struct {
+			ID             int         `gorm:"column:id"`
+			GoodsID        int         `gorm:"column:goodsid"`
+			AmountReceived money.Money `gorm:"column:amount_received"`
+			GoodsName      string      `gorm:"column:goods_name"`
+			BrandID        *int        `gorm:"column:brand_id"`
+			BrandName      *string     `gorm:"column:brand_name"`
+			ClassifyID     *int        `gorm:"column:classify_id"`
+			ClassifyName   *string     `gorm:"column:classify_name"`
+		}
+		if err := tx.Raw(`
+			SELECT co.id, co.goodsid, co.amount_received, g.name AS goods_name,
+				g.brandid AS brand_id, b.name AS brand_name, g.classifyid AS classify_id, c.name AS classify_name
+			FROM childorders co
+			LEFT JOIN goods g ON co.goodsid = g.id
+			LEFT JOIN brand b ON g.brandid = b.id
+			LEFT JOIN classify c ON g.classifyid = c.id
+			WHERE co.parentsid = ? AND co.gift_activity_id IS NULL AND co.status <> ?
+			ORDER BY co.id ASC
+		`, orderID, orderEntity.ChildOrderStatusCancelled).Scan(&allChildOrders).Error; err != nil {
+			return err
+		}
+
+		// 构建退费金额map
+		refundItemMap := make(map[int]money.Money)
+		for _, item := range refundItemsList {
+			refundItemMap[item.ChildOrderID] = item.RefundAmount
+		}
+
+		// 计算每个子订单的剩余需求 = 实收金额 - 退费金额
+		childRemainingNeed := make(map[int]money.Money)
+		for _, child := range allChildOrders {
+			refundAmount := refundItemMap[child.ID]
+			remainingNeed := child.AmountReceived.Sub(refundAmount)
+			childRemainingNeed[child.ID] = remainingNeed
+		}
+
+		// 按收款顺序，将每笔收款的剩余金额按分账策略分配到子订单
+		for _, payment := range allPayments {
+			key := fmt.Sprintf("%d_%d", payment.PaymentID, payment.PaymentType)
+
+			if !paymentRemaining[key].IsPositive() {
+				continue
+			}
+
+			targets := make([]orderEntity.AllocationTarget, len(allChildOrders))
+			for i, child := range allChildOrders {
+				targets[i] = orderEntity.AllocationTarget{
+					ChildOrder: &orderEntity.ChildOrder{ID: child.ID, GoodsID: child.GoodsID, AmountReceived: child.AmountReceived},
+					ClassifyID: child.ClassifyID,
+					Needed:     childRemainingNeed[child.ID],
+				}
+			}
+			shares := allocator.Allocate(paymentRemaining[key], targets)
+
+			for i, child := range allChildOrders {
+				allocateAmount := shares[i]
+				if !allocateAmount.IsPositive() {
+					continue
+				}
+
+				// 插入第二批售卖分账明细
+				if err := tx.Table("separate_account").Create(map[string]interface{}{
+					"uid":             studentID,
+					"orders_id":       orderID,
+					"childorders_id":  child.ID,
+					"payment_id":      payment.PaymentID,
+					"payment_type":    payment.PaymentType,
+					"goods_id":        child.GoodsID,
+					"goods_name":      child.GoodsName,
+					"brand_id":        child.BrandID,
+					"brand_name":      child.BrandName,
+					"classify_id":     child.ClassifyID,
+					"classify_name":   child.ClassifyName,
+					"separate_amount": allocateAmount,
+					"type":            0,
+				}).Error; err != nil {
+					return err
+				}
+
+				paymentRemaining[key] = paymentRemaining[key].Sub(allocateAmount)
+				childRemainingNeed[child.ID] = childRemainingNeed[child.ID].Sub(allocateAmount)
+			}
+		}
+	}
+
+	// 3.4 生成退费类分账明细（无论是否冲回都执行）
+	// 重新查询所有退费子订单和退费金额（确保数据最新且避免变量冲突）
+	var refundItemsForRefundSeparate []struct {
+		ChildOrderID int         `gorm:"column:childorder_id"`
+		RefundAmount money.Money `gorm:"column:refund_amount"`
+		GoodsID      int         `gorm:"column:goodsid"`
+		GoodsName    string      `gorm:"column:goods_name"`
+		BrandID      *int        `gorm:"column:brand_id"`
+		BrandName    *string     `gorm:"column:brand_name"`
+		ClassifyID   *int        `gorm:"column:classify_id"`
+		ClassifyName *string     `gorm:"column:classify_name"`
+	}
+	if err := tx.Raw(`
+		SELECT roi.childorder_id, roi.refund_amount, co.goodsid, g.name as goods_name,
+			g.brandid AS brand_id, b.name AS brand_name, g.classifyid AS classify_id, c.name AS classify_name
+		FROM refund_order_item roi
+		INNER JOIN childorders co ON roi.childorder_id = co.id
+		LEFT JOIN goods g ON co.goodsid = g.id
+		LEFT JOIN brand b ON g.brandid = b.id
+		LEFT JOIN classify c ON g.classifyid = c.id
+		WHERE roi.refund_order_id = ?
+		ORDER BY roi.childorder_id ASC
+	`, refundOrderID).Scan(&refundItemsForRefundSeparate).Error; err != nil {
+		return err
+	}
+
+	// 为每个退费子订单按照其售卖分账的分布生成退费类分账明细
+	for _, item := range refundItemsForRefundSeparate {
+		// 查询该子订单当前的售卖分账分布（按收款ID升序）
+		var childSeparates []struct {
+			PaymentID      int         `gorm:"column:payment_id"`
+			PaymentType    int         `gorm:"column:payment_type"`
+			SeparateAmount money.Money `gorm:"column:separate_amount"`
+		}
+		if err := tx.Raw(`
+			SELECT payment_id, payment_type, separate_amount
+			FROM separate_account
+			WHERE childorders_id = ? AND type = 0
+				AND id NOT IN (
+					SELECT parent_id FROM separate_account
+					WHERE childorders_id = ? AND parent_id IS NOT NULL
+				)
+			ORDER BY payment_id ASC
+		`, item.ChildOrderID, item.ChildOrderID).Scan(&childSeparates).Error; err != nil {
+			return err
+		}
+
+		// 按照售卖分账的分布生成退费类分账
+		remainingRefund := item.RefundAmount
+		for _, separate := range childSeparates {
+			if !remainingRefund.IsPositive() {
+				break
+			}
+
+			// 本次退费金额 = min(剩余退费金额, 该收款的售卖分账金额)
+			refundAmount := money.Min(remainingRefund, separate.SeparateAmount)
+
+			// 插入退费类分账明细（负金额）
+			if err := tx.Table("separate_account").Create(map[string]interface{}{
+				"uid":             studentID,
+				"orders_id":       orderID,
+				"childorders_id":  item.ChildOrderID,
+				"payment_id":      separate.PaymentID,
+				"payment_type":    separate.PaymentType,
+				"goods_id":        item.GoodsID,
+				"goods_name":      item.GoodsName,
+				"brand_id":        item.BrandID,
+				"brand_name":      item.BrandName,
+				"classify_id":     item.ClassifyID,
+				"classify_name":   item.ClassifyName,
+				"separate_amount": refundAmount.Neg(),
+				"type":            2,
+			}).Error; err != nil {
+				return err
+			}
+
+			remainingRefund = remainingRefund.Sub(refundAmount)
+		}
+	}
+
+	// 7. 更新子订单状态（根据净分账金额计算）
+	// 查询订单的所有子订单
+	var allChildOrders []struct {
+		ID             int         `gorm:"column:id"`
+		AmountReceived money.Money `gorm:"column:amount_received"`
+	}
+	if err := tx.Table("childorders").
+		Select("id, amount_received").
+		Where("parentsid = ? AND gift_activity_id IS NULL AND status <> ?", orderID, orderEntity.ChildOrderStatusCancelled).
+		Scan(&allChildOrders).Error; err != nil {
+		return err
+	}
+
+	// 更新每个子订单的状态
+	for _, childOrder := range allChildOrders {
+		// 计算子订单的净分账金额（未冲回的售卖类 + 未冲回的退费类，订单变更重新分账时退费类会被冲回）
+		var netAmount money.Money
+		if err := tx.Raw(`
+			SELECT COALESCE(SUM(separate_amount), 0) as net_allocated
+			FROM separate_account
+			WHERE childorders_id = ?
+				AND type IN (0, 2)
+				AND id NOT IN (
+					SELECT parent_id FROM separate_account
+					WHERE childorders_id = ? AND parent_id IS NOT NULL
+				)
+		`, childOrder.ID, childOrder.ID).Scan(&netAmount).Error; err != nil {
+			return err
+		}
+
+		// 根据净分账金额确定子订单状态
+		var newStatus int
+		if !netAmount.IsPositive() {
+			newStatus = orderEntity.ChildOrderStatusUnpaid // 10 未支付
+		} else if netAmount.LessThan(childOrder.AmountReceived) {
+			newStatus = orderEntity.ChildOrderStatusPartialPaid // 20 部分支付
+		} else {
+			newStatus = orderEntity.ChildOrderStatusPaid // 30 已支付
+		}
+
+		if err := tx.Table("childorders").
+			Where("id = ?", childOrder.ID).
+			Update("status", newStatus).Error; err != nil {
+			return err
+		}
+	}
+
+	// 8. 更新订单状态（根据净收款计算）
+	// 计算总收款金额（常规+淘宝）
+	totalPaid, err := orderService.OrderPaidAmount(tx, orderID)
+	if err != nil {
+		return err
+	}
+
+	// 计算总退费金额（包含当前退费订单和其他已通过的退费订单）
+	var regularRefund money.Money
+	if err := tx.Raw(`
+		SELECT COALESCE(SUM(refund_amount), 0) as total
+		FROM refund_regular_supplement
+		WHERE refund_order_id IN (
+			SELECT id FROM refund_order
+			WHERE order_id = ? AND (status = 10 OR id = ?)
+		)
+	`, orderID, refundOrderID).Scan(&regularRefund).Error; err != nil {
+		return err
+	}
+
+	var taobaoRefund money.Money
+	if err := tx.Raw(`
+		SELECT COALESCE(SUM(refund_amount), 0) as total
+		FROM refund_taobao_supplement
+		WHERE refund_order_id IN (
+			SELECT id FROM refund_order
+			WHERE order_id = ? AND (status = 10 OR id = ?)
+		)
+	`, orderID, refundOrderID).Scan(&taobaoRefund).Error; err != nil {
+		return err
+	}
+
+	totalRefund := regularRefund.Add(taobaoRefund)
+
+	// 净收款 = 总收款 - 总退费
+	netPaid := totalPaid.Sub(totalRefund)
+
+	// 获取订单应收金额
+	var amountReceived money.Money
+	if err := tx.Table("orders").
+		Select("amount_received").
+		Where("id = ?", orderID).
+		Scan(&amountReceived).Error; err != nil {
+		return err
+	}
+
+	// 根据净收款确定订单状态（未支付/部分支付/已支付）
+	return h.orderStatus.TransitionTx(tx, orderService.StatusChange{
+		OrderID:  orderID,
+		To:       orderEntity.PaymentStatus(netPaid, amountReceived),
+		Operator: orderEntity.OperatorSystem,
+		Reason:   fmt.Sprintf("退费审批通过（退费单 #%d）", refundOrderID),
+	})
+}
+
+// OnRejected 退费审批驳回：关闭退费订单并恢复订单为申请退费前的状态
+func (h *ApprovalHook) OnRejected(tx *gorm.DB, refundOrderID int) error {
+	return h.closeRefund(tx, refundOrderID, RefundStatusRejected, "退费审批驳回（退费单 #%d）")
+}
+
+// OnCancelled 退费审批被发起人撤销：处理同驳回，退费订单记为已撤销
+func (h *ApprovalHook) OnCancelled(tx *gorm.DB, refundOrderID int) error {
+	return h.closeRefund(tx, refundOrderID, RefundStatusCancelled, "退费审批撤销（退费单 #%d）")
+}
+
+// closeRefund 关闭退费订单并恢复订单为申请退费前的状态，无历史记录时按部分支付处理
+func (h *ApprovalHook) closeRefund(tx *gorm.DB, refundOrderID int, status int, reasonFormat string) error {
+	refundOrder, err := lockPendingRefundOrder(tx, refundOrderID)
+	if err != nil {
+		return err
+	}
+
+	if err := updateRefundStatus(tx, refundOrderID, status); err != nil {
+		return err
+	}
+
+	restoreStatus, found, err := h.orderStatus.StatusBefore(tx, refundOrder.OrderID, orderEntity.OrderStatusRefunding)
+	if err != nil {
+		return err
+	}
+	if !found {
+		restoreStatus = orderEntity.OrderStatusPartialPaid
+	}
+	return h.orderStatus.TransitionTx(tx, orderService.StatusChange{
+		OrderID:  refundOrder.OrderID,
+		To:       restoreStatus,
+		Operator: orderEntity.OperatorSystem,
+		Reason:   fmt.Sprintf(reasonFormat, refundOrderID),
+	})
+}
+
+// lockPendingRefundOrder 锁定退费订单并校验仍为待审批状态，防止重复回调
+func lockPendingRefundOrder(tx *gorm.DB, refundOrderID int) (*RefundOrder, error) {
+	var refundOrders []RefundOrder
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", refundOrderID).
+		Limit(1).
+		Find(&refundOrders).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询退费订单失败: %w", err)
+	}
+	if len(refundOrders) == 0 {
+		return nil, fmt.Errorf("退费订单 %d 不存在", refundOrderID)
+	}
+	if refundOrders[0].Status != RefundStatusPending {
+		return nil, fmt.Errorf("退费订单 %d 已处理", refundOrderID)
+	}
+	return &refundOrders[0], nil
+}
+
+// updateRefundStatus 同步更新退费订单、退费子订单和退费补充信息的状态
+func updateRefundStatus(tx *gorm.DB, refundOrderID int, status int) error {
+	if err := tx.Table("refund_order").Where("id = ?", refundOrderID).Update("status", status).Error; err != nil {
+		return fmt.Errorf("更新退费订单状态失败: %w", err)
+	}
+	for _, table := range []string{"refund_order_item", "refund_taobao_supplement", "refund_regular_supplement"} {
+		if err := tx.Table(table).Where("refund_order_id = ?", refundOrderID).Update("status", status).Error; err != nil {
+			return fmt.Errorf("更新%s状态失败: %w", table, err)
+		}
+	}
+	return nil
+}
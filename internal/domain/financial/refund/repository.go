@@ -1,7 +1,12 @@
 package refund
 
+import "gorm.io/gorm"
+
 // RefundRepository 退费订单仓储接口
 type RefundRepository interface {
+	// WithTx 返回绑定调用方事务的仓储实例，其读写都在该事务中执行
+	WithTx(tx *gorm.DB) RefundRepository
+
 	// CreateRefundOrder 创建退费订单
 	CreateRefundOrder(refundOrder *RefundOrder) error
 
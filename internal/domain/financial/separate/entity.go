@@ -1,6 +1,10 @@
 package separate
 
-import "time"
+import (
+	"time"
+
+	"charonoms/internal/domain/shared/money"
+)
 
 // 收款类型常量
 const (
@@ -17,20 +21,71 @@ const (
 
 // SeparateAccount 分账明细实体
 type SeparateAccount struct {
-	ID             int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	UID            int       `gorm:"column:uid;not null" json:"uid"`
-	OrdersID       int       `gorm:"column:orders_id;not null" json:"orders_id"`
-	ChildOrdersID  int       `gorm:"column:childorders_id;not null" json:"childorders_id"`
-	PaymentID      int       `gorm:"column:payment_id;not null" json:"payment_id"`
-	PaymentType    int       `gorm:"column:payment_type;not null" json:"payment_type"`
-	GoodsID        int       `gorm:"column:goods_id;not null" json:"goods_id"`
-	GoodsName      string    `gorm:"column:goods_name;type:varchar(100);not null" json:"goods_name"`
-	SeparateAmount float64   `gorm:"column:separate_amount;type:decimal(10,2);not null" json:"separate_amount"`
-	Type           int       `gorm:"column:type;default:0" json:"type"`
-	CreateTime     time.Time `gorm:"column:create_time;autoCreateTime" json:"create_time"`
+	ID             int         `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UID            int         `gorm:"column:uid;not null" json:"uid"`
+	OrdersID       int         `gorm:"column:orders_id;not null" json:"orders_id"`
+	ChildOrdersID  int         `gorm:"column:childorders_id;not null" json:"childorders_id"`
+	PaymentID      int         `gorm:"column:payment_id;not null" json:"payment_id"`
+	PaymentType    int         `gorm:"column:payment_type;not null" json:"payment_type"`
+	GoodsID        int         `gorm:"column:goods_id;not null" json:"goods_id"`
+	GoodsName      string      `gorm:"column:goods_name;type:varchar(100);not null" json:"goods_name"`
+	BrandID        *int        `gorm:"column:brand_id" json:"brand_id"`                       // 分账时商品所属品牌
+	BrandName      string      `gorm:"column:brand_name;type:varchar(100)" json:"brand_name"` // 分账时的品牌名称快照
+	ClassifyID     *int        `gorm:"column:classify_id" json:"classify_id"`                 // 分账时商品所属分类
+	ClassifyName   string      `gorm:"column:classify_name;type:varchar(100)" json:"classify_name"`
+	SeparateAmount money.Money `gorm:"column:separate_amount;type:decimal(10,2);not null" json:"separate_amount"`
+	Type           int         `gorm:"column:type;default:0" json:"type"`
+	CreateTime     time.Time   `gorm:"column:create_time;autoCreateTime" json:"create_time"`
 }
 
 // TableName 指定表名
 func (SeparateAccount) TableName() string {
 	return "separate_account"
 }
+
+// GoodsSnapshot 分账时的商品、品牌和分类快照，商品改名或调整分类不影响已生成的分账
+type GoodsSnapshot struct {
+	GoodsID      int     `gorm:"column:goods_id"`
+	GoodsName    string  `gorm:"column:goods_name"`
+	BrandID      *int    `gorm:"column:brand_id"`
+	BrandName    *string `gorm:"column:brand_name"`
+	ClassifyID   *int    `gorm:"column:classify_id"`
+	ClassifyName *string `gorm:"column:classify_name"`
+}
+
+// ApplyGoodsSnapshot 写入商品快照
+func (a *SeparateAccount) ApplyGoodsSnapshot(snapshot GoodsSnapshot) {
+	a.GoodsName = snapshot.GoodsName
+	a.BrandID = snapshot.BrandID
+	a.ClassifyID = snapshot.ClassifyID
+	if snapshot.BrandName != nil {
+		a.BrandName = *snapshot.BrandName
+	}
+	if snapshot.ClassifyName != nil {
+		a.ClassifyName = *snapshot.ClassifyName
+	}
+}
+
+// 分账汇总维度常量
+const (
+	GroupByBrand    = "brand"    // 品牌
+	GroupByClassify = "classify" // 分类
+	GroupByGoods    = "goods"    // 商品
+	GroupByMonth    = "month"    // 分账月份（YYYY-MM）
+)
+
+// SeparateAggregate 按维度汇总的分账金额，未参与分组的维度为空
+type SeparateAggregate struct {
+	BrandID      *int        `gorm:"column:brand_id" json:"brand_id,omitempty"`
+	BrandName    *string     `gorm:"column:brand_name" json:"brand_name,omitempty"`
+	ClassifyID   *int        `gorm:"column:classify_id" json:"classify_id,omitempty"`
+	ClassifyName *string     `gorm:"column:classify_name" json:"classify_name,omitempty"`
+	GoodsID      *int        `gorm:"column:goods_id" json:"goods_id,omitempty"`
+	GoodsName    *string     `gorm:"column:goods_name" json:"goods_name,omitempty"`
+	Month        *string     `gorm:"column:month" json:"month,omitempty"`
+	SaleAmount   money.Money `gorm:"column:sale_amount" json:"sale_amount"`     // 售卖
+	RevertAmount money.Money `gorm:"column:revert_amount" json:"revert_amount"` // 冲回
+	RefundAmount money.Money `gorm:"column:refund_amount" json:"refund_amount"` // 退费
+	NetAmount    money.Money `gorm:"column:net_amount" json:"net_amount"`       // 合计
+	Count        int64       `gorm:"column:count" json:"count"`
+}
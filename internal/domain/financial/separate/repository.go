@@ -1,5 +1,11 @@
 package separate
 
+import (
+	"charonoms/internal/domain/shared/money"
+
+	"gorm.io/gorm"
+)
+
 // SeparateListFilter 分账明细列表查询条件
 type SeparateListFilter struct {
 	ID            *int
@@ -10,12 +16,19 @@ type SeparateListFilter struct {
 	PaymentID     *int
 	PaymentType   *int
 	Type          *int
+	BrandID       *int
+	ClassifyID    *int
+	StartDate     *string // 分账日期起（含），格式：YYYY-MM-DD
+	EndDate       *string // 分账日期止（含），格式：YYYY-MM-DD
 	Page          int
 	PageSize      int
 }
 
 // SeparateAccountRepository 分账明细仓储接口
 type SeparateAccountRepository interface {
+	// WithTx 返回绑定调用方事务的仓储实例，其读写都在该事务中执行
+	WithTx(tx *gorm.DB) SeparateAccountRepository
+
 	// Create 创建分账明细
 	Create(account *SeparateAccount) error
 
@@ -28,13 +41,29 @@ type SeparateAccountRepository interface {
 	// List 查询分账明细列表
 	List(filter SeparateListFilter) ([]*SeparateAccount, int64, error)
 
+	// Aggregate 按维度（GroupByBrand 等）汇总分账金额，返回当前页的分组和分组总数
+	Aggregate(filter SeparateListFilter, groupBy []string) ([]*SeparateAggregate, int64, error)
+
+	// GetGoodsSnapshots 查询商品当前的名称、品牌和分类，用于写入分账快照
+	GetGoodsSnapshots(goodsIDs []int) (map[int]GoodsSnapshot, error)
+
+	// BackfillGoodsSnapshots 为ID在 (afterID, upToID] 范围内缺少商品快照的分账明细补写快照，返回更新行数
+	// 已有的商品名称不覆盖
+	BackfillGoodsSnapshots(afterID, upToID int) (int64, error)
+
+	// MaxID 查询分账明细的最大ID
+	MaxID() (int, error)
+
 	// ExistsByPaymentAndOrder 检查指定收款和订单是否已生成分账
 	ExistsByPaymentAndOrder(paymentID int, orderID int, paymentType int) (bool, error)
 
+	// GetOrderAllocationStrategy 查询订单指定的收款分账策略，未指定时返回空字符串
+	GetOrderAllocationStrategy(orderID int) (string, error)
+
 	// GetChildOrderTotalSeparate 查询子订单的总分账金额
 	// 只统计售卖类型（type=0）
-	GetChildOrderTotalSeparate(childOrderID int) (float64, error)
+	GetChildOrderTotalSeparate(childOrderID int) (money.Money, error)
 
 	// GetChildOrderAllocatedAmount 查询子订单已分配金额
-	GetChildOrderAllocatedAmount(childOrderID int) (float64, error)
+	GetChildOrderAllocatedAmount(childOrderID int) (money.Money, error)
 }
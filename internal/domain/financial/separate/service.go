@@ -3,11 +3,13 @@ package separate
 import (
 	"errors"
 	"fmt"
-	"math"
 
+	"charonoms/internal/domain/financial/payment"
 	orderEntity "charonoms/internal/domain/order/entity"
 	orderRepo "charonoms/internal/domain/order/repository"
-	"charonoms/internal/domain/financial/payment"
+	"charonoms/internal/domain/shared/money"
+
+	"gorm.io/gorm"
 )
 
 // SeparateAccountDomainService 分账明细领域服务
@@ -15,24 +17,37 @@ type SeparateAccountDomainService struct {
 	separateRepo   SeparateAccountRepository
 	paymentRepo    payment.PaymentRepository
 	childOrderRepo orderRepo.ChildOrderRepository
+	setting        orderEntity.AllocationSetting
 }
 
-// NewSeparateAccountDomainService 创建分账明细领域服务
+// NewSeparateAccountDomainService 创建分账明细领域服务，setting 为系统默认的收款分账策略
 func NewSeparateAccountDomainService(
 	separateRepo SeparateAccountRepository,
 	paymentRepo payment.PaymentRepository,
 	childOrderRepo orderRepo.ChildOrderRepository,
+	setting orderEntity.AllocationSetting,
 ) *SeparateAccountDomainService {
 	return &SeparateAccountDomainService{
 		separateRepo:   separateRepo,
 		paymentRepo:    paymentRepo,
 		childOrderRepo: childOrderRepo,
+		setting:        setting,
+	}
+}
+
+// WithTx 返回在调用方事务中读写的领域服务
+func (s *SeparateAccountDomainService) WithTx(tx *gorm.DB) *SeparateAccountDomainService {
+	return &SeparateAccountDomainService{
+		separateRepo:   s.separateRepo.WithTx(tx),
+		paymentRepo:    s.paymentRepo.WithTx(tx),
+		childOrderRepo: s.childOrderRepo.WithTx(tx),
+		setting:        s.setting,
 	}
 }
 
 // GenerateSeparateAccounts 生成分账明细
 func (s *SeparateAccountDomainService) GenerateSeparateAccounts(paymentID int, orderID int) error {
-	// 1. 查询收款信息
+	// 查询收款信息
 	paymentCollection, err := s.paymentRepo.GetByID(paymentID)
 	if err != nil {
 		return fmt.Errorf("查询收款信息失败: %w", err)
@@ -41,10 +56,13 @@ func (s *SeparateAccountDomainService) GenerateSeparateAccounts(paymentID int, o
 		return errors.New("收款记录不存在")
 	}
 
-	studentID := paymentCollection.StudentID
-	paymentAmount := paymentCollection.PaymentAmount
+	return s.AllocatePayment(paymentID, PaymentTypeRegular, orderID, paymentCollection.StudentID, paymentCollection.PaymentAmount)
+}
 
-	// 2. 查询子订单列表（按ID升序）
+// AllocatePayment 按订单的分账策略将一笔收款分配到子订单，写入售卖类分账明细并更新子订单状态
+// paymentType 区分常规收款和淘宝收款；该收款已为订单生成过分账时直接返回
+func (s *SeparateAccountDomainService) AllocatePayment(paymentID, paymentType, orderID, studentID int, paymentAmount money.Money) error {
+	// 1. 查询子订单列表（按ID升序）
 	childOrders, err := s.childOrderRepo.ListByOrderID(orderID)
 	if err != nil {
 		return fmt.Errorf("查询子订单列表失败: %w", err)
@@ -55,8 +73,8 @@ func (s *SeparateAccountDomainService) GenerateSeparateAccounts(paymentID int, o
 		return nil
 	}
 
-	// 3. 防重复检查
-	exists, err := s.separateRepo.ExistsByPaymentAndOrder(paymentID, orderID, PaymentTypeRegular)
+	// 2. 防重复检查
+	exists, err := s.separateRepo.ExistsByPaymentAndOrder(paymentID, orderID, paymentType)
 	if err != nil {
 		return fmt.Errorf("检查分账是否存在失败: %w", err)
 	}
@@ -65,60 +83,75 @@ func (s *SeparateAccountDomainService) GenerateSeparateAccounts(paymentID int, o
 		return nil
 	}
 
-	// 4. 遍历子订单，按序分配收款金额
-	remainingAmount := paymentAmount
-	var accounts []*SeparateAccount
+	// 3. 确定分账策略：订单指定的优先，否则使用系统设置
+	orderStrategy, err := s.separateRepo.GetOrderAllocationStrategy(orderID)
+	if err != nil {
+		return fmt.Errorf("查询订单分账策略失败: %w", err)
+	}
+	allocator := s.setting.Allocator(orderStrategy)
 
+	// 4. 计算各子订单还需分配的金额
+	var targets []orderEntity.AllocationTarget
 	for _, child := range childOrders {
-		// 4.1 计算子订单已分配金额
+		// 赠品子订单零应收、作废的子订单，不参与分账
+		if child.IsGift() || child.IsCancelled() {
+			continue
+		}
+
 		allocatedAmount, err := s.separateRepo.GetChildOrderAllocatedAmount(child.ID)
 		if err != nil {
 			return fmt.Errorf("计算子订单已分配金额失败: %w", err)
 		}
 
-		// 4.2 计算子订单还需金额
-		neededAmount := child.AmountReceived - allocatedAmount
-
-		if neededAmount <= 0 {
+		neededAmount := child.AmountReceived.Sub(allocatedAmount)
+		if !neededAmount.IsPositive() {
 			// 子订单已满额，跳过
 			continue
 		}
+		targets = append(targets, orderEntity.AllocationTarget{ChildOrder: child, Needed: neededAmount})
+	}
+	if len(targets) == 0 {
+		return nil
+	}
 
-		// 4.3 计算本次分账金额（取剩余收款和还需金额的较小值）
-		separateAmount := math.Min(remainingAmount, neededAmount)
-		separateAmount = roundToTwoDecimal(separateAmount)
+	// 按分类优先级分配时需要子订单商品的分类
+	if _, ok := allocator.(orderEntity.ClassifyPriorityAllocator); ok {
+		if err := s.fillTargetClassifies(targets); err != nil {
+			return err
+		}
+	}
 
-		// 4.4 创建分账明细记录
-		account := &SeparateAccount{
+	// 5. 按策略分配收款金额
+	shares := allocator.Allocate(paymentAmount, targets)
+	var accounts []*SeparateAccount
+	for i, target := range targets {
+		if !shares[i].IsPositive() {
+			continue
+		}
+		accounts = append(accounts, &SeparateAccount{
 			UID:            studentID,
 			OrdersID:       orderID,
-			ChildOrdersID:  child.ID,
+			ChildOrdersID:  target.ChildOrder.ID,
 			PaymentID:      paymentID,
-			PaymentType:    PaymentTypeRegular,
-			GoodsID:        child.GoodsID,
-			GoodsName:      "", // 商品名称需要从商品表查询，暂时留空
-			SeparateAmount: separateAmount,
+			PaymentType:    paymentType,
+			GoodsID:        target.ChildOrder.GoodsID,
+			SeparateAmount: shares[i],
 			Type:           SeparateTypeSale,
-		}
-		accounts = append(accounts, account)
-
-		// 4.5 更新剩余收款金额
-		remainingAmount -= separateAmount
-		remainingAmount = roundToTwoDecimal(remainingAmount)
-
-		if remainingAmount <= 0 {
-			break
-		}
+		})
 	}
 
-	// 5. 批量插入分账明细
+	// 6. 批量插入分账明细
 	if len(accounts) > 0 {
+		if err := FillGoodsSnapshots(s.separateRepo, accounts); err != nil {
+			return err
+		}
+
 		err = s.separateRepo.BatchCreate(accounts)
 		if err != nil {
 			return fmt.Errorf("插入分账明细失败: %w", err)
 		}
 
-		// 6. 更新所有相关子订单的状态
+		// 7. 更新所有相关子订单的状态
 		for _, account := range accounts {
 			err = s.UpdateChildOrderStatus(account.ChildOrdersID)
 			if err != nil {
@@ -130,6 +163,24 @@ func (s *SeparateAccountDomainService) GenerateSeparateAccounts(paymentID int, o
 	return nil
 }
 
+// fillTargetClassifies 按商品当前分类填充待分配子订单的分类
+func (s *SeparateAccountDomainService) fillTargetClassifies(targets []orderEntity.AllocationTarget) error {
+	goodsIDs := make([]int, 0, len(targets))
+	for _, target := range targets {
+		goodsIDs = append(goodsIDs, target.ChildOrder.GoodsID)
+	}
+	snapshots, err := s.separateRepo.GetGoodsSnapshots(goodsIDs)
+	if err != nil {
+		return fmt.Errorf("查询商品分类失败: %w", err)
+	}
+	for i := range targets {
+		if snapshot, ok := snapshots[targets[i].ChildOrder.GoodsID]; ok {
+			targets[i].ClassifyID = snapshot.ClassifyID
+		}
+	}
+	return nil
+}
+
 // UpdateChildOrderStatus 更新子订单状态
 func (s *SeparateAccountDomainService) UpdateChildOrderStatus(childOrderID int) error {
 	// 1. 查询子订单
@@ -151,9 +202,9 @@ func (s *SeparateAccountDomainService) UpdateChildOrderStatus(childOrderID int)
 
 	// 3. 根据分账情况确定子订单状态
 	var newStatus int
-	if totalSeparate == 0 {
+	if totalSeparate.IsZero() {
 		newStatus = orderEntity.ChildOrderStatusUnpaid // 10-未支付
-	} else if totalSeparate < actualAmount {
+	} else if totalSeparate.LessThan(actualAmount) {
 		newStatus = orderEntity.ChildOrderStatusPartialPaid // 20-部分支付
 	} else {
 		newStatus = orderEntity.ChildOrderStatusPaid // 30-已支付
@@ -171,7 +222,28 @@ func (s *SeparateAccountDomainService) UpdateChildOrderStatus(childOrderID int)
 	return nil
 }
 
-// roundToTwoDecimal 四舍五入到两位小数
-func roundToTwoDecimal(value float64) float64 {
-	return math.Round(value*100) / 100
+// FillGoodsSnapshots 为待写入的分账明细填充商品名称、品牌和分类快照
+func FillGoodsSnapshots(repo SeparateAccountRepository, accounts []*SeparateAccount) error {
+	seen := make(map[int]bool)
+	var goodsIDs []int
+	for _, account := range accounts {
+		if !seen[account.GoodsID] {
+			seen[account.GoodsID] = true
+			goodsIDs = append(goodsIDs, account.GoodsID)
+		}
+	}
+	if len(goodsIDs) == 0 {
+		return nil
+	}
+
+	snapshots, err := repo.GetGoodsSnapshots(goodsIDs)
+	if err != nil {
+		return fmt.Errorf("查询商品快照失败: %w", err)
+	}
+	for _, account := range accounts {
+		if snapshot, ok := snapshots[account.GoodsID]; ok {
+			account.ApplyGoodsSnapshot(snapshot)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,75 @@
+package separate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeSnapshotRepo 返回固定商品快照的分账仓储
+type fakeSnapshotRepo struct {
+	SeparateAccountRepository
+	snapshots map[int]GoodsSnapshot
+	err       error
+	queried   [][]int
+}
+
+func (r *fakeSnapshotRepo) GetGoodsSnapshots(goodsIDs []int) (map[int]GoodsSnapshot, error) {
+	r.queried = append(r.queried, goodsIDs)
+	return r.snapshots, r.err
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestFillGoodsSnapshots(t *testing.T) {
+	repo := &fakeSnapshotRepo{snapshots: map[int]GoodsSnapshot{
+		5: {GoodsID: 5, GoodsName: "数学课", BrandID: intPtr(2), BrandName: strPtr("学而思"), ClassifyID: intPtr(4), ClassifyName: strPtr("数学")},
+		6: {GoodsID: 6, GoodsName: "物理课", BrandID: intPtr(2), BrandName: strPtr("学而思")},
+	}}
+	accounts := []*SeparateAccount{{GoodsID: 5}, {GoodsID: 6}, {GoodsID: 5}, {GoodsID: 9, GoodsName: "已删除商品"}}
+
+	if err := FillGoodsSnapshots(repo, accounts); err != nil {
+		t.Fatalf("FillGoodsSnapshots() error = %v", err)
+	}
+
+	// 同一商品只查询一次
+	if want := [][]int{{5, 6, 9}}; !reflect.DeepEqual(repo.queried, want) {
+		t.Errorf("GetGoodsSnapshots() calls = %v, want %v", repo.queried, want)
+	}
+
+	want := []SeparateAccount{
+		{GoodsID: 5, GoodsName: "数学课", BrandID: intPtr(2), BrandName: "学而思", ClassifyID: intPtr(4), ClassifyName: "数学"},
+		{GoodsID: 6, GoodsName: "物理课", BrandID: intPtr(2), BrandName: "学而思"},
+		{GoodsID: 5, GoodsName: "数学课", BrandID: intPtr(2), BrandName: "学而思", ClassifyID: intPtr(4), ClassifyName: "数学"},
+		// 查不到快照的商品保持原值
+		{GoodsID: 9, GoodsName: "已删除商品"},
+	}
+	for i := range want {
+		if !reflect.DeepEqual(*accounts[i], want[i]) {
+			t.Errorf("accounts[%d] = %+v, want %+v", i, *accounts[i], want[i])
+		}
+	}
+}
+
+func TestFillGoodsSnapshots_Empty(t *testing.T) {
+	repo := &fakeSnapshotRepo{}
+	if err := FillGoodsSnapshots(repo, nil); err != nil {
+		t.Fatalf("FillGoodsSnapshots() error = %v", err)
+	}
+	if len(repo.queried) != 0 {
+		t.Errorf("GetGoodsSnapshots() called %d times, want 0", len(repo.queried))
+	}
+}
+
+func TestFillGoodsSnapshots_QueryError(t *testing.T) {
+	repo := &fakeSnapshotRepo{err: errors.New("connection refused")}
+	if err := FillGoodsSnapshots(repo, []*SeparateAccount{{GoodsID: 5}}); err == nil {
+		t.Fatal("FillGoodsSnapshots() error = nil, want query error")
+	}
+}
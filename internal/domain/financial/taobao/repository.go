@@ -1,7 +1,16 @@
 package taobao
 
+import (
+	"charonoms/internal/domain/shared/money"
+
+	"gorm.io/gorm"
+)
+
 // TaobaoPaymentRepository 淘宝收款仓储接口
 type TaobaoPaymentRepository interface {
+	// WithTx 返回绑定调用方事务的仓储实例，其读写都在该事务中执行
+	WithTx(tx *gorm.DB) TaobaoPaymentRepository
+
 	// Create 创建淘宝收款记录
 	Create(payment *TaobaoPayment) error
 
@@ -17,8 +26,8 @@ type TaobaoPaymentRepository interface {
 	// Delete 删除淘宝收款记录
 	Delete(id int) error
 
-	// GetTotalPaid 获取订单的淘宝收款总额（仅统计已到账状态）
-	GetTotalPaid(orderID int) (float64, error)
+	// GetTotalPaid 获取订单的淘宝收款总额（统计已认领和已到账状态）
+	GetTotalPaid(orderID int) (money.Money, error)
 
 	// ListUnclaimed 获取淘宝待认领列表（状态10和20）
 	ListUnclaimed(filters map[string]interface{}) ([]*TaobaoPayment, error)
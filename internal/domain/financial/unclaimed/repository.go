@@ -1,9 +1,16 @@
 package unclaimed
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // UnclaimedRepository 常规待认领款项仓储接口
 type UnclaimedRepository interface {
+	// WithTx 返回绑定调用方事务的仓储实例，其读写都在该事务中执行
+	WithTx(tx *gorm.DB) UnclaimedRepository
+
 	// Create 创建待认领记录
 	Create(unclaimed *Unclaimed) error
 
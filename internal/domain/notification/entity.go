@@ -0,0 +1,32 @@
+package notification
+
+import "time"
+
+// 通知类别（notification.category）
+const (
+	CategoryApprovalReminder = "approval_reminder" // 审批超时提醒
+	CategoryApprovalEscalate = "approval_escalate" // 审批超时升级，备用审批人收到待审批任务
+	CategoryApprovalAuto     = "approval_auto"     // 审批节点超时自动通过/驳回，通知发起人
+)
+
+// Notification 站内通知（通知收件箱）
+type Notification struct {
+	ID            int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserAccountID int        `gorm:"column:useraccount_id;not null" json:"useraccount_id"` // 接收人账号ID
+	Category      string     `gorm:"column:category;type:varchar(50);not null" json:"category"`
+	Title         string     `gorm:"column:title;type:varchar(200);not null" json:"title"`
+	Content       string     `gorm:"column:content;type:varchar(1000)" json:"content"`
+	BusinessType  string     `gorm:"column:business_type;type:varchar(50)" json:"business_type"` // 关联对象类型，如 approval_flow
+	BusinessID    int        `gorm:"column:business_id" json:"business_id"`                      // 关联对象ID
+	IsRead        int8       `gorm:"column:is_read;not null;default:0" json:"is_read"`           // 0=未读，1=已读
+	CreateTime    time.Time  `gorm:"column:create_time;not null;default:CURRENT_TIMESTAMP" json:"create_time"`
+	ReadTime      *time.Time `gorm:"column:read_time" json:"read_time"`
+}
+
+// TableName 指定表名
+func (Notification) TableName() string {
+	return "notification"
+}
+
+// BusinessTypeApprovalFlow 通知关联审批流（business_id 为审批流ID）
+const BusinessTypeApprovalFlow = "approval_flow"
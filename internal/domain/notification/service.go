@@ -0,0 +1,86 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Send 在调用方事务中给每个接收人写入一条通知，接收人去重
+func Send(tx *gorm.DB, userIDs []int, n Notification) error {
+	sent := make(map[int]bool, len(userIDs))
+	for _, userID := range userIDs {
+		if userID <= 0 || sent[userID] {
+			continue
+		}
+		sent[userID] = true
+
+		record := n
+		record.ID = 0
+		record.UserAccountID = userID
+		record.IsRead = 0
+		if err := tx.Create(&record).Error; err != nil {
+			return fmt.Errorf("写入通知失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// Service 通知收件箱领域服务
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService 创建通知收件箱领域服务
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// List 查询本人的通知，按时间倒序；unreadOnly 为 true 时只返回未读通知
+func (s *Service) List(userID int, unreadOnly bool) ([]Notification, error) {
+	query := s.db.Where("useraccount_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("is_read = 0")
+	}
+	var notifications []Notification
+	err := query.Order("create_time DESC, id DESC").Limit(200).Find(&notifications).Error
+	return notifications, err
+}
+
+// UnreadCount 统计本人的未读通知数
+func (s *Service) UnreadCount(userID int) (int64, error) {
+	var count int64
+	err := s.db.Model(&Notification{}).
+		Where("useraccount_id = ? AND is_read = 0", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkRead 将本人的一条通知标记为已读
+func (s *Service) MarkRead(id int, userID int) error {
+	result := s.db.Model(&Notification{}).
+		Where("id = ? AND useraccount_id = ? AND is_read = 0", id, userID).
+		Updates(map[string]interface{}{"is_read": 1, "read_time": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := s.db.Model(&Notification{}).Where("id = ? AND useraccount_id = ?", id, userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New("通知不存在")
+		}
+	}
+	return nil
+}
+
+// MarkAllRead 将本人的全部未读通知标记为已读
+func (s *Service) MarkAllRead(userID int) error {
+	return s.db.Model(&Notification{}).
+		Where("useraccount_id = ? AND is_read = 0", userID).
+		Updates(map[string]interface{}{"is_read": 1, "read_time": time.Now()}).Error
+}
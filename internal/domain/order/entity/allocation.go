@@ -0,0 +1,149 @@
+package entity
+
+import (
+	"sort"
+
+	"charonoms/internal/domain/shared/money"
+)
+
+// AllocationTarget 参与收款分配的子订单
+type AllocationTarget struct {
+	ChildOrder *ChildOrder
+	ClassifyID *int        // 商品分类，仅按分类优先级分配时使用
+	Needed     money.Money // 子订单还需分配的金额
+}
+
+// Allocator 收款分配策略
+// Allocate 返回与 targets 一一对应的分配金额，单个不超过 Needed，合计不超过 amount
+type Allocator interface {
+	Allocate(amount money.Money, targets []AllocationTarget) []money.Money
+}
+
+// AllocationSetting 系统默认的收款分账策略，订单未指定策略时使用
+type AllocationSetting struct {
+	Strategy         string // 为空时按顺序分配
+	ClassifyPriority []int  // 按分类优先级分配时的分类ID顺序，未列出的分类排在最后
+}
+
+// Allocator 返回订单使用的分配策略，orderStrategy 为空时使用系统设置
+func (s AllocationSetting) Allocator(orderStrategy string) Allocator {
+	strategy := orderStrategy
+	if strategy == "" {
+		strategy = s.Strategy
+	}
+	switch strategy {
+	case AllocationStrategyProRata:
+		return ProRataAllocator{}
+	case AllocationStrategyClassifyPriority:
+		return ClassifyPriorityAllocator{Priority: s.ClassifyPriority}
+	default:
+		return SequentialAllocator{}
+	}
+}
+
+// SequentialAllocator 按子订单顺序依次分满
+type SequentialAllocator struct{}
+
+// Allocate 实现 Allocator
+func (SequentialAllocator) Allocate(amount money.Money, targets []AllocationTarget) []money.Money {
+	order := make([]int, len(targets))
+	for i := range targets {
+		order[i] = i
+	}
+	return fillInOrder(amount, targets, order)
+}
+
+// ProRataAllocator 按子订单实收金额比例分摊，分满的子订单多出的部分再按比例分给其余子订单
+type ProRataAllocator struct{}
+
+// Allocate 实现 Allocator
+func (ProRataAllocator) Allocate(amount money.Money, targets []AllocationTarget) []money.Money {
+	shares := make([]money.Money, len(targets))
+	remaining := amount
+
+	var active []int
+	for i, t := range targets {
+		if t.Needed.IsPositive() {
+			active = append(active, i)
+		}
+	}
+
+	for remaining.IsPositive() && len(active) > 0 {
+		weights := make([]money.Money, len(active))
+		for i, idx := range active {
+			weights[i] = targets[idx].ChildOrder.AmountReceived
+		}
+		parts := remaining.Allocate(weights)
+
+		capped := false
+		var next []int
+		for i, idx := range active {
+			need := targets[idx].Needed.Sub(shares[idx])
+			part := money.Min(parts[i], need)
+			if part.LessThan(parts[i]) {
+				capped = true
+			}
+			shares[idx] = shares[idx].Add(part)
+			remaining = remaining.Sub(part)
+			if shares[idx].LessThan(targets[idx].Needed) {
+				next = append(next, idx)
+			}
+		}
+		// 没有子订单被分满时本轮已分完全部金额（或权重均为零无法分配）
+		if !capped {
+			break
+		}
+		active = next
+	}
+
+	return shares
+}
+
+// ClassifyPriorityAllocator 按商品分类优先级依次分满，同一优先级内按子订单顺序
+type ClassifyPriorityAllocator struct {
+	Priority []int
+}
+
+// Allocate 实现 Allocator
+func (a ClassifyPriorityAllocator) Allocate(amount money.Money, targets []AllocationTarget) []money.Money {
+	rank := make(map[int]int, len(a.Priority))
+	for i, classifyID := range a.Priority {
+		if _, ok := rank[classifyID]; !ok {
+			rank[classifyID] = i
+		}
+	}
+	rankOf := func(t AllocationTarget) int {
+		if t.ClassifyID != nil {
+			if r, ok := rank[*t.ClassifyID]; ok {
+				return r
+			}
+		}
+		return len(a.Priority)
+	}
+
+	order := make([]int, len(targets))
+	for i := range targets {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return rankOf(targets[order[i]]) < rankOf(targets[order[j]])
+	})
+	return fillInOrder(amount, targets, order)
+}
+
+// fillInOrder 按给定顺序依次分满子订单，直到金额分完
+func fillInOrder(amount money.Money, targets []AllocationTarget, order []int) []money.Money {
+	shares := make([]money.Money, len(targets))
+	remaining := amount
+	for _, idx := range order {
+		if !remaining.IsPositive() {
+			break
+		}
+		if !targets[idx].Needed.IsPositive() {
+			continue
+		}
+		shares[idx] = money.Min(remaining, targets[idx].Needed)
+		remaining = remaining.Sub(shares[idx])
+	}
+	return shares
+}
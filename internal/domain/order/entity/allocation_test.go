@@ -0,0 +1,121 @@
+package entity
+
+import (
+	"testing"
+
+	"charonoms/internal/domain/shared/money"
+)
+
+func classify(id int) *int {
+	return &id
+}
+
+func target(id int, received, needed float64, classifyID *int) AllocationTarget {
+	return AllocationTarget{
+		ChildOrder: &ChildOrder{ID: id, AmountReceived: money.FromFloat(received)},
+		ClassifyID: classifyID,
+		Needed:     money.FromFloat(needed),
+	}
+}
+
+func assertShares(t *testing.T, got []money.Money, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("len(shares) = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Equal(money.FromFloat(want[i])) {
+			t.Errorf("shares[%d] = %s, want %.2f", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSequentialAllocator(t *testing.T) {
+	targets := []AllocationTarget{
+		target(1, 1000, 1000, nil),
+		target(2, 500, 0, nil), // 已分满
+		target(3, 800, 800, nil),
+		target(4, 300, 300, nil),
+	}
+
+	shares := SequentialAllocator{}.Allocate(money.FromFloat(1500), targets)
+
+	assertShares(t, shares, []float64{1000, 0, 500, 0})
+}
+
+func TestProRataAllocator(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  float64
+		targets []AllocationTarget
+		want    []float64
+	}{
+		{
+			name:    "按实收金额比例分摊",
+			amount:  600,
+			targets: []AllocationTarget{target(1, 1000, 1000, nil), target(2, 500, 500, nil)},
+			want:    []float64{400, 200},
+		},
+		{
+			name:    "分满的子订单多出的部分分给其余子订单",
+			amount:  900,
+			targets: []AllocationTarget{target(1, 1000, 1000, nil), target(2, 1000, 100, nil), target(3, 2000, 2000, nil)},
+			want:    []float64{266.67, 100, 533.33},
+		},
+		{
+			name:    "收款超过全部还需金额时只分到满额",
+			amount:  2000,
+			targets: []AllocationTarget{target(1, 1000, 300, nil), target(2, 500, 500, nil)},
+			want:    []float64{300, 500},
+		},
+		{
+			name:    "分摊的分差由最后一个子订单承担",
+			amount:  100,
+			targets: []AllocationTarget{target(1, 100, 100, nil), target(2, 100, 100, nil), target(3, 100, 100, nil)},
+			want:    []float64{33.33, 33.33, 33.34},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares := ProRataAllocator{}.Allocate(money.FromFloat(tt.amount), tt.targets)
+			assertShares(t, shares, tt.want)
+
+			total := money.Sum(shares...)
+			if total.GreaterThan(money.FromFloat(tt.amount)) {
+				t.Errorf("分配合计 %s 超过收款金额 %.2f", total, tt.amount)
+			}
+		})
+	}
+}
+
+func TestClassifyPriorityAllocator(t *testing.T) {
+	targets := []AllocationTarget{
+		target(1, 1000, 1000, classify(3)),
+		target(2, 500, 500, classify(7)),
+		target(3, 800, 800, nil), // 没有分类排在最后
+		target(4, 300, 300, classify(7)),
+	}
+
+	shares := ClassifyPriorityAllocator{Priority: []int{7, 3}}.Allocate(money.FromFloat(1200), targets)
+
+	assertShares(t, shares, []float64{400, 500, 0, 300})
+}
+
+func TestAllocationSetting_Allocator(t *testing.T) {
+	setting := AllocationSetting{Strategy: AllocationStrategyProRata, ClassifyPriority: []int{5}}
+
+	if _, ok := setting.Allocator("").(ProRataAllocator); !ok {
+		t.Errorf("订单未指定策略时应使用系统设置")
+	}
+	if _, ok := setting.Allocator(AllocationStrategySequential).(SequentialAllocator); !ok {
+		t.Errorf("订单指定的策略应优先于系统设置")
+	}
+	allocator, ok := setting.Allocator(AllocationStrategyClassifyPriority).(ClassifyPriorityAllocator)
+	if !ok || len(allocator.Priority) != 1 || allocator.Priority[0] != 5 {
+		t.Errorf("分类优先级策略应使用系统设置的分类顺序, got %+v", allocator)
+	}
+	if _, ok := (AllocationSetting{}).Allocator("").(SequentialAllocator); !ok {
+		t.Errorf("未设置策略时应按顺序分配")
+	}
+}
@@ -1,6 +1,10 @@
 package entity
 
-import "time"
+import (
+	"time"
+
+	"charonoms/internal/domain/shared/money"
+)
 
 // 子订单状态常量
 const (
@@ -13,14 +17,15 @@ const (
 
 // ChildOrder 子订单实体
 type ChildOrder struct {
-	ID               int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	ParentsID        int       `gorm:"column:parentsid;not null" json:"parentsid"`
-	GoodsID          int       `gorm:"column:goodsid;not null" json:"goodsid"`
-	AmountReceivable float64   `gorm:"column:amount_receivable;type:decimal(10,2);default:0" json:"amount_receivable"`
-	AmountReceived   float64   `gorm:"column:amount_received;type:decimal(10,2);default:0" json:"amount_received"`
-	DiscountAmount   float64   `gorm:"column:discount_amount;type:decimal(10,2);default:0" json:"discount_amount"`
-	Status           int       `gorm:"column:status;default:0" json:"status"`
-	CreateTime       time.Time `gorm:"column:create_time;autoCreateTime" json:"create_time"`
+	ID               int         `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	ParentsID        int         `gorm:"column:parentsid;not null" json:"parentsid"`
+	GoodsID          int         `gorm:"column:goodsid;not null" json:"goodsid"`
+	AmountReceivable money.Money `gorm:"column:amount_receivable;type:decimal(10,2);default:0" json:"amount_receivable"`
+	AmountReceived   money.Money `gorm:"column:amount_received;type:decimal(10,2);default:0" json:"amount_received"`
+	DiscountAmount   money.Money `gorm:"column:discount_amount;type:decimal(10,2);default:0" json:"discount_amount"`
+	Status           int         `gorm:"column:status;default:0" json:"status"`
+	GiftActivityID   *int        `gorm:"column:gift_activity_id" json:"gift_activity_id"` // 赠品关联的满赠活动ID，非赠品为空
+	CreateTime       time.Time   `gorm:"column:create_time;autoCreateTime" json:"create_time"`
 }
 
 // TableName 指定表名
@@ -28,22 +33,27 @@ func (ChildOrder) TableName() string {
 	return "childorders"
 }
 
+// IsGift 判断是否为满赠活动赠送的子订单（零应收，不参与分账和退费）
+func (c *ChildOrder) IsGift() bool {
+	return c.GiftActivityID != nil
+}
+
 // ValidateAmounts 验证子订单金额的合理性
 func (c *ChildOrder) ValidateAmounts() bool {
 	// 应收金额应该大于等于0
-	if c.AmountReceivable < 0 {
+	if c.AmountReceivable.IsNegative() {
 		return false
 	}
 	// 实收金额应该大于等于0
-	if c.AmountReceived < 0 {
+	if c.AmountReceived.IsNegative() {
 		return false
 	}
 	// 优惠金额应该大于等于0
-	if c.DiscountAmount < 0 {
+	if c.DiscountAmount.IsNegative() {
 		return false
 	}
 	// 实收金额不应该大于应收金额
-	if c.AmountReceived > c.AmountReceivable {
+	if c.AmountReceived.GreaterThan(c.AmountReceivable) {
 		return false
 	}
 	return true
@@ -1,14 +1,18 @@
 package entity
 
-import "testing"
+import (
+	"testing"
+
+	"charonoms/internal/domain/shared/money"
+)
 
 func TestChildOrder_ValidateAmounts(t *testing.T) {
 	tests := []struct {
-		name              string
-		amountReceivable  float64
-		amountReceived    float64
-		discountAmount    float64
-		want              bool
+		name             string
+		amountReceivable float64
+		amountReceived   float64
+		discountAmount   float64
+		want             bool
 	}{
 		{"正常金额", 100.00, 90.00, 10.00, true},
 		{"应收金额为零", 0, 0, 0, true},
@@ -22,9 +26,9 @@ func TestChildOrder_ValidateAmounts(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &ChildOrder{
-				AmountReceivable: tt.amountReceivable,
-				AmountReceived:   tt.amountReceived,
-				DiscountAmount:   tt.discountAmount,
+				AmountReceivable: money.FromFloat(tt.amountReceivable),
+				AmountReceived:   money.FromFloat(tt.amountReceived),
+				DiscountAmount:   money.FromFloat(tt.discountAmount),
 			}
 			if got := c.ValidateAmounts(); got != tt.want {
 				t.Errorf("ChildOrder.ValidateAmounts() = %v, want %v", got, tt.want)
@@ -64,9 +68,9 @@ func TestChildOrder_Creation(t *testing.T) {
 		ID:               1,
 		ParentsID:        100,
 		GoodsID:          200,
-		AmountReceivable: 500.00,
-		AmountReceived:   450.00,
-		DiscountAmount:   50.00,
+		AmountReceivable: money.FromFloat(500.00),
+		AmountReceived:   money.FromFloat(450.00),
+		DiscountAmount:   money.FromFloat(50.00),
 		Status:           ChildOrderStatusInit,
 	}
 
@@ -79,16 +83,27 @@ func TestChildOrder_Creation(t *testing.T) {
 	if childOrder.GoodsID != 200 {
 		t.Errorf("ChildOrder.GoodsID = %d, want 200", childOrder.GoodsID)
 	}
-	if childOrder.AmountReceivable != 500.00 {
-		t.Errorf("ChildOrder.AmountReceivable = %f, want 500.00", childOrder.AmountReceivable)
+	if childOrder.AmountReceivable != money.FromFloat(500.00) {
+		t.Errorf("ChildOrder.AmountReceivable = %v, want 500.00", childOrder.AmountReceivable)
 	}
-	if childOrder.AmountReceived != 450.00 {
-		t.Errorf("ChildOrder.AmountReceived = %f, want 450.00", childOrder.AmountReceived)
+	if childOrder.AmountReceived != money.FromFloat(450.00) {
+		t.Errorf("ChildOrder.AmountReceived = %v, want 450.00", childOrder.AmountReceived)
 	}
-	if childOrder.DiscountAmount != 50.00 {
-		t.Errorf("ChildOrder.DiscountAmount = %f, want 50.00", childOrder.DiscountAmount)
+	if childOrder.DiscountAmount != money.FromFloat(50.00) {
+		t.Errorf("ChildOrder.DiscountAmount = %v, want 50.00", childOrder.DiscountAmount)
 	}
 	if !childOrder.ValidateAmounts() {
 		t.Error("ChildOrder.ValidateAmounts() should return true for valid amounts")
 	}
 }
+
+func TestChildOrder_IsGift(t *testing.T) {
+	activityID := 5
+
+	if (&ChildOrder{}).IsGift() {
+		t.Error("ChildOrder.IsGift() = true for regular child order, want false")
+	}
+	if !(&ChildOrder{GiftActivityID: &activityID}).IsGift() {
+		t.Error("ChildOrder.IsGift() = false for gift child order, want true")
+	}
+}
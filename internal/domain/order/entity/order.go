@@ -1,27 +1,41 @@
 package entity
 
-import "time"
+import (
+	"time"
+
+	"charonoms/internal/domain/shared/money"
+)
 
 // 订单状态常量
 const (
-	OrderStatusDraft      = 10 // 草稿
-	OrderStatusUnpaid     = 20 // 未支付
+	OrderStatusDraft       = 10 // 草稿
+	OrderStatusUnpaid      = 20 // 未支付
 	OrderStatusPartialPaid = 30 // 部分支付
-	OrderStatusPaid       = 40 // 已支付
-	OrderStatusRefunding  = 50 // 退费中
-	OrderStatusCancelled  = 99 // 已作废
+	OrderStatusPaid        = 40 // 已支付
+	OrderStatusRefunding   = 50 // 退费中
+	OrderStatusCancelled   = 99 // 已作废
+)
+
+// 收款分账策略常量，订单未指定时使用系统设置
+const (
+	AllocationStrategySequential       = "sequential"        // 按子订单ID顺序依次分满
+	AllocationStrategyProRata          = "pro_rata"          // 按子订单实收金额比例分摊
+	AllocationStrategyClassifyPriority = "classify_priority" // 按商品分类优先级依次分满
 )
 
 // Order 订单实体
 type Order struct {
-	ID                  int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	StudentID           int       `gorm:"column:student_id;not null" json:"student_id"`
-	ExpectedPaymentTime *time.Time `gorm:"column:expected_payment_time" json:"expected_payment_time"`
-	AmountReceivable    float64   `gorm:"column:amount_receivable;type:decimal(10,2);default:0" json:"amount_receivable"`
-	AmountReceived      float64   `gorm:"column:amount_received;type:decimal(10,2);default:0" json:"amount_received"`
-	DiscountAmount      float64   `gorm:"column:discount_amount;type:decimal(10,2);default:0" json:"discount_amount"`
-	Status              int       `gorm:"column:status;default:10" json:"status"`
-	CreateTime          time.Time `gorm:"column:create_time;autoCreateTime" json:"create_time"`
+	ID                  int         `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	StudentID           int         `gorm:"column:student_id;not null" json:"student_id"`
+	ExpectedPaymentTime *time.Time  `gorm:"column:expected_payment_time" json:"expected_payment_time"`
+	AmountReceivable    money.Money `gorm:"column:amount_receivable;type:decimal(10,2);default:0" json:"amount_receivable"`
+	AmountReceived      money.Money `gorm:"column:amount_received;type:decimal(10,2);default:0" json:"amount_received"`
+	DiscountAmount      money.Money `gorm:"column:discount_amount;type:decimal(10,2);default:0" json:"discount_amount"`
+	Status              int         `gorm:"column:status;default:10" json:"status"`
+	AllocationStrategy  string      `gorm:"column:allocation_strategy" json:"allocation_strategy"` // 收款分账策略，为空时使用系统设置
+	CreateTime          time.Time   `gorm:"column:create_time;autoCreateTime" json:"create_time"`
+
+	Instalments []*OrderInstalment `gorm:"-" json:"instalments,omitempty"` // 分期计划（为空时按预计付款时间一次付清）
 }
 
 // TableName 指定表名
@@ -29,6 +43,15 @@ func (Order) TableName() string {
 	return "orders"
 }
 
+// IsValidAllocationStrategy 判断收款分账策略是否有效，空值表示使用系统设置
+func IsValidAllocationStrategy(strategy string) bool {
+	switch strategy {
+	case "", AllocationStrategySequential, AllocationStrategyProRata, AllocationStrategyClassifyPriority:
+		return true
+	}
+	return false
+}
+
 // CanEdit 判断订单是否可以编辑
 func (o *Order) CanEdit() bool {
 	return o.Status == OrderStatusDraft
@@ -47,19 +70,19 @@ func (o *Order) CanCancel() bool {
 // ValidateAmounts 验证订单金额的合理性
 func (o *Order) ValidateAmounts() bool {
 	// 应收金额应该大于等于0
-	if o.AmountReceivable < 0 {
+	if o.AmountReceivable.IsNegative() {
 		return false
 	}
 	// 实收金额应该大于等于0
-	if o.AmountReceived < 0 {
+	if o.AmountReceived.IsNegative() {
 		return false
 	}
 	// 优惠金额应该大于等于0
-	if o.DiscountAmount < 0 {
+	if o.DiscountAmount.IsNegative() {
 		return false
 	}
 	// 实收金额不应该大于应收金额
-	if o.AmountReceived > o.AmountReceivable {
+	if o.AmountReceived.GreaterThan(o.AmountReceivable) {
 		return false
 	}
 	return true
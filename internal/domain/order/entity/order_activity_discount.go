@@ -0,0 +1,13 @@
+package entity
+
+import "charonoms/internal/domain/shared/money"
+
+// OrderActivityDiscount 订单活动优惠明细（某个活动对某个商品贡献的优惠金额）
+type OrderActivityDiscount struct {
+	ID             int         `json:"id"`
+	OrdersID       int         `json:"orders_id"`
+	ActivityID     int         `json:"activity_id"`
+	ChildOrderID   int         `json:"childorder_id"`
+	GoodsID        int         `json:"goods_id"`
+	DiscountAmount money.Money `json:"discount_amount"`
+}
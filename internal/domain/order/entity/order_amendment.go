@@ -0,0 +1,87 @@
+package entity
+
+import (
+	"time"
+
+	"charonoms/internal/domain/shared/money"
+)
+
+// 订单变更单状态常量（与退费单一致）
+const (
+	AmendmentStatusPending   = 0  // 审批中
+	AmendmentStatusApproved  = 10 // 已通过
+	AmendmentStatusRejected  = 20 // 已驳回
+	AmendmentStatusCancelled = 99 // 已撤销
+)
+
+// 变更明细动作常量
+const (
+	AmendmentActionKeep   = 0 // 保留的子订单（金额可能因优惠重算而变化）
+	AmendmentActionAdd    = 1 // 新增的子订单
+	AmendmentActionRemove = 2 // 移除的子订单（审批通过后作废）
+)
+
+// OrderAmendment 订单变更单：对已提交订单增删子订单，审批通过后生效
+type OrderAmendment struct {
+	ID                     int                      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	OrderID                int                      `gorm:"column:order_id;not null" json:"order_id"`
+	ApprovalFlowID         int                      `gorm:"column:approval_flow_id" json:"approval_flow_id"`
+	AmountReceivableBefore money.Money              `gorm:"column:amount_receivable_before;type:decimal(10,2)" json:"amount_receivable_before"`
+	AmountReceivedBefore   money.Money              `gorm:"column:amount_received_before;type:decimal(10,2)" json:"amount_received_before"`
+	DiscountAmountBefore   money.Money              `gorm:"column:discount_amount_before;type:decimal(10,2)" json:"discount_amount_before"`
+	AmountReceivableAfter  money.Money              `gorm:"column:amount_receivable_after;type:decimal(10,2)" json:"amount_receivable_after"`
+	AmountReceivedAfter    money.Money              `gorm:"column:amount_received_after;type:decimal(10,2)" json:"amount_received_after"`
+	DiscountAmountAfter    money.Money              `gorm:"column:discount_amount_after;type:decimal(10,2)" json:"discount_amount_after"`
+	ActivityIDs            []int                    `gorm:"column:activity_ids;serializer:json" json:"activity_ids"`
+	ActivityDiscounts      []*OrderActivityDiscount `gorm:"column:activity_discounts;serializer:json" json:"activity_discounts"`
+	Instalments            []*OrderInstalment       `gorm:"column:instalments;serializer:json" json:"instalments"` // 变更后的分期计划，为空表示不设分期
+	Reason                 string                   `gorm:"column:reason;type:varchar(255)" json:"reason"`
+	Submitter              string                   `gorm:"column:submitter;type:varchar(100)" json:"submitter"`
+	SubmitTime             time.Time                `gorm:"column:submit_time" json:"submit_time"`
+	Status                 int                      `gorm:"column:status;default:0" json:"status"`
+	ProcessTime            *time.Time               `gorm:"column:process_time" json:"process_time"`
+
+	Items []*OrderAmendmentItem `gorm:"-" json:"items"`
+}
+
+// TableName 指定表名
+func (OrderAmendment) TableName() string {
+	return "order_amendment"
+}
+
+// OrderAmendmentItem 订单变更明细：记录变更后每个子订单的金额
+type OrderAmendmentItem struct {
+	ID               int         `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	AmendmentID      int         `gorm:"column:amendment_id;not null" json:"amendment_id"`
+	Action           int         `gorm:"column:action;not null" json:"action"`
+	ChildOrderID     *int        `gorm:"column:childorder_id" json:"childorder_id"` // 新增子订单在审批通过后回填
+	GoodsID          int         `gorm:"column:goods_id;not null" json:"goods_id"`
+	GiftActivityID   *int        `gorm:"column:gift_activity_id" json:"gift_activity_id"`
+	AmountReceivable money.Money `gorm:"column:amount_receivable;type:decimal(10,2);default:0" json:"amount_receivable"`
+	AmountReceived   money.Money `gorm:"column:amount_received;type:decimal(10,2);default:0" json:"amount_received"`
+	DiscountAmount   money.Money `gorm:"column:discount_amount;type:decimal(10,2);default:0" json:"discount_amount"`
+}
+
+// TableName 指定表名
+func (OrderAmendmentItem) TableName() string {
+	return "order_amendment_item"
+}
+
+// IsPending 判断变更单是否仍在审批中
+func (a *OrderAmendment) IsPending() bool {
+	return a.Status == AmendmentStatusPending
+}
+
+// CanAmend 判断订单是否可以发起变更：已提交且未作废、未在退费中
+func (o *Order) CanAmend() bool {
+	switch o.Status {
+	case OrderStatusUnpaid, OrderStatusPartialPaid, OrderStatusPaid:
+		return true
+	}
+	return false
+}
+
+// IsCancelled 判断子订单是否已作废（作废的子订单不参与分账和退费）
+func (c *ChildOrder) IsCancelled() bool {
+	return c.Status == ChildOrderStatusCancelled
+}
@@ -0,0 +1,42 @@
+package entity
+
+import "testing"
+
+func TestOrder_CanAmend(t *testing.T) {
+	tests := []struct {
+		status int
+		want   bool
+	}{
+		{OrderStatusDraft, false},
+		{OrderStatusUnpaid, true},
+		{OrderStatusPartialPaid, true},
+		{OrderStatusPaid, true},
+		{OrderStatusRefunding, false},
+		{OrderStatusCancelled, false},
+	}
+
+	for _, tt := range tests {
+		o := &Order{Status: tt.status}
+		if got := o.CanAmend(); got != tt.want {
+			t.Errorf("Order{Status: %d}.CanAmend() = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestChildOrder_IsCancelled(t *testing.T) {
+	if (&ChildOrder{Status: ChildOrderStatusPaid}).IsCancelled() {
+		t.Error("paid child order should not be cancelled")
+	}
+	if !(&ChildOrder{Status: ChildOrderStatusCancelled}).IsCancelled() {
+		t.Error("cancelled child order should be cancelled")
+	}
+}
+
+func TestOrderAmendment_IsPending(t *testing.T) {
+	if !(&OrderAmendment{Status: AmendmentStatusPending}).IsPending() {
+		t.Error("new amendment should be pending")
+	}
+	if (&OrderAmendment{Status: AmendmentStatusRejected}).IsPending() {
+		t.Error("rejected amendment should not be pending")
+	}
+}
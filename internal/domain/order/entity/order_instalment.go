@@ -0,0 +1,128 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"charonoms/internal/domain/shared/money"
+)
+
+// OrderInstalment 订单分期：一个订单可按多个预计付款日期分期收款
+type OrderInstalment struct {
+	ID         int         `json:"id"`
+	OrderID    int         `json:"order_id"`
+	Seq        int         `json:"seq"` // 期数，从1开始按到期日递增
+	DueDate    time.Time   `json:"due_date"`
+	Amount     money.Money `json:"amount"`
+	CreateTime time.Time   `json:"create_time"`
+}
+
+// InstalmentProgress 分期收款进度
+type InstalmentProgress struct {
+	*OrderInstalment
+	PaidAmount        money.Money `json:"paid_amount"`
+	OutstandingAmount money.Money `json:"outstanding_amount"`
+}
+
+// IsOpen 判断该期是否仍有未收金额
+func (p *InstalmentProgress) IsOpen() bool {
+	return p.OutstandingAmount.IsPositive()
+}
+
+// IsOverdue 判断该期在指定日期是否已逾期（到期日早于当天且仍有未收金额）
+func (p *InstalmentProgress) IsOverdue(now time.Time) bool {
+	return p.IsOpen() && p.DueDate.Before(truncateToDay(now))
+}
+
+// OverdueDays 计算该期在指定日期的逾期天数，未逾期返回0
+func (p *InstalmentProgress) OverdueDays(now time.Time) int {
+	if !p.IsOverdue(now) {
+		return 0
+	}
+	// 按天四舍五入，避免夏令时切换导致少算一天
+	return int(math.Round(truncateToDay(now).Sub(truncateToDay(p.DueDate)).Hours() / 24))
+}
+
+// NormalizeInstalments 校验并整理分期计划：按到期日排序、编号，且分期金额合计必须等于订单实收金额
+func NormalizeInstalments(instalments []*OrderInstalment, amountReceived money.Money) error {
+	if len(instalments) == 0 {
+		return nil
+	}
+
+	total := money.Zero
+	for _, inst := range instalments {
+		if inst.DueDate.IsZero() {
+			return errors.New("分期到期日不能为空")
+		}
+		if !inst.Amount.IsPositive() {
+			return errors.New("分期金额必须大于0")
+		}
+		inst.DueDate = truncateToDay(inst.DueDate)
+		total = total.Add(inst.Amount)
+	}
+	if !total.Equal(amountReceived) {
+		return fmt.Errorf("分期金额合计%s与订单实收金额%s不一致", total, amountReceived)
+	}
+
+	sort.SliceStable(instalments, func(i, j int) bool {
+		return instalments[i].DueDate.Before(instalments[j].DueDate)
+	})
+	for i, inst := range instalments {
+		if i > 0 && inst.DueDate.Equal(instalments[i-1].DueDate) {
+			return fmt.Errorf("分期到期日 %s 重复", inst.DueDate.Format("2006-01-02"))
+		}
+		inst.Seq = i + 1
+	}
+	return nil
+}
+
+// InstalmentPlan 获取订单的分期计划
+// 未设置分期的订单视为一期：到期日为预计付款时间，金额为实收金额；两者都没有时返回空
+func (o *Order) InstalmentPlan() []*OrderInstalment {
+	if len(o.Instalments) > 0 {
+		return o.Instalments
+	}
+	if o.ExpectedPaymentTime == nil {
+		return nil
+	}
+	return []*OrderInstalment{{
+		OrderID: o.ID,
+		Seq:     1,
+		DueDate: truncateToDay(*o.ExpectedPaymentTime),
+		Amount:  o.AmountReceived,
+	}}
+}
+
+// AllocateInstalmentPayments 将订单已收款总额按期数顺序依次冲抵各期，计算每期的收款进度
+func AllocateInstalmentPayments(plan []*OrderInstalment, totalPaid money.Money) []*InstalmentProgress {
+	remaining := money.Max(totalPaid, money.Zero)
+	progress := make([]*InstalmentProgress, 0, len(plan))
+	for _, inst := range plan {
+		paid := money.Min(remaining, inst.Amount)
+		remaining = remaining.Sub(paid)
+		progress = append(progress, &InstalmentProgress{
+			OrderInstalment:   inst,
+			PaidAmount:        paid,
+			OutstandingAmount: inst.Amount.Sub(paid),
+		})
+	}
+	return progress
+}
+
+// NextOpenInstalment 获取下一个尚未收齐的分期，全部收齐时返回 nil
+func NextOpenInstalment(progress []*InstalmentProgress) *InstalmentProgress {
+	for _, p := range progress {
+		if p.IsOpen() {
+			return p
+		}
+	}
+	return nil
+}
+
+// truncateToDay 截断到当天零点（保留时区）
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,164 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"charonoms/internal/domain/shared/money"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+func TestNormalizeInstalments(t *testing.T) {
+	t.Run("按到期日排序并编号", func(t *testing.T) {
+		instalments := []*OrderInstalment{
+			{DueDate: time.Date(2026, 3, 1, 15, 30, 0, 0, time.Local), Amount: money.FromCents(6000)},
+			{DueDate: date(2026, 1, 1), Amount: money.FromCents(4000)},
+		}
+		if err := NormalizeInstalments(instalments, money.FromCents(10000)); err != nil {
+			t.Fatalf("NormalizeInstalments() error = %v", err)
+		}
+		if !instalments[0].DueDate.Equal(date(2026, 1, 1)) || instalments[0].Seq != 1 {
+			t.Errorf("instalments[0] = %+v, want 2026-01-01 seq 1", instalments[0])
+		}
+		if !instalments[1].DueDate.Equal(date(2026, 3, 1)) || instalments[1].Seq != 2 {
+			t.Errorf("instalments[1] = %+v, want 2026-03-01 seq 2", instalments[1])
+		}
+	})
+
+	tests := []struct {
+		name        string
+		instalments []*OrderInstalment
+	}{
+		{"合计金额与实收金额不一致", []*OrderInstalment{
+			{DueDate: date(2026, 1, 1), Amount: money.FromCents(4000)},
+			{DueDate: date(2026, 2, 1), Amount: money.FromCents(5000)},
+		}},
+		{"分期金额为0", []*OrderInstalment{
+			{DueDate: date(2026, 1, 1), Amount: money.FromCents(10000)},
+			{DueDate: date(2026, 2, 1), Amount: money.Zero},
+		}},
+		{"到期日为空", []*OrderInstalment{
+			{Amount: money.FromCents(10000)},
+		}},
+		{"到期日重复", []*OrderInstalment{
+			{DueDate: date(2026, 1, 1), Amount: money.FromCents(5000)},
+			{DueDate: time.Date(2026, 1, 1, 9, 0, 0, 0, time.Local), Amount: money.FromCents(5000)},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NormalizeInstalments(tt.instalments, money.FromCents(10000)); err == nil {
+				t.Error("NormalizeInstalments() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestOrder_InstalmentPlan(t *testing.T) {
+	expected := time.Date(2026, 5, 20, 10, 0, 0, 0, time.Local)
+
+	t.Run("未设置分期按预计付款时间视为一期", func(t *testing.T) {
+		o := &Order{ID: 1, ExpectedPaymentTime: &expected, AmountReceived: money.FromCents(8000)}
+		plan := o.InstalmentPlan()
+		if len(plan) != 1 {
+			t.Fatalf("len(plan) = %d, want 1", len(plan))
+		}
+		if !plan[0].DueDate.Equal(date(2026, 5, 20)) || !plan[0].Amount.Equal(money.FromCents(8000)) {
+			t.Errorf("plan[0] = %+v", plan[0])
+		}
+	})
+
+	t.Run("没有分期也没有预计付款时间", func(t *testing.T) {
+		o := &Order{ID: 1, AmountReceived: money.FromCents(8000)}
+		if plan := o.InstalmentPlan(); len(plan) != 0 {
+			t.Errorf("len(plan) = %d, want 0", len(plan))
+		}
+	})
+
+	t.Run("设置分期时使用分期计划", func(t *testing.T) {
+		o := &Order{ID: 1, ExpectedPaymentTime: &expected, Instalments: []*OrderInstalment{
+			{Seq: 1, DueDate: date(2026, 1, 1), Amount: money.FromCents(3000)},
+			{Seq: 2, DueDate: date(2026, 2, 1), Amount: money.FromCents(5000)},
+		}}
+		if plan := o.InstalmentPlan(); len(plan) != 2 {
+			t.Errorf("len(plan) = %d, want 2", len(plan))
+		}
+	})
+}
+
+func TestAllocateInstalmentPayments(t *testing.T) {
+	plan := []*OrderInstalment{
+		{ID: 1, Seq: 1, DueDate: date(2026, 1, 1), Amount: money.FromCents(3000)},
+		{ID: 2, Seq: 2, DueDate: date(2026, 2, 1), Amount: money.FromCents(3000)},
+		{ID: 3, Seq: 3, DueDate: date(2026, 3, 1), Amount: money.FromCents(4000)},
+	}
+
+	tests := []struct {
+		name      string
+		totalPaid money.Money
+		wantPaid  []int64
+		wantNext  int
+	}{
+		{"未收款", money.Zero, []int64{0, 0, 0}, 1},
+		{"第一期部分收款", money.FromCents(1000), []int64{1000, 0, 0}, 1},
+		{"跨期冲抵", money.FromCents(4500), []int64{3000, 1500, 0}, 2},
+		{"恰好收齐前两期", money.FromCents(6000), []int64{3000, 3000, 0}, 3},
+		{"全部收齐", money.FromCents(10000), []int64{3000, 3000, 4000}, 0},
+		{"退费后净收款为负", money.FromCents(-500), []int64{0, 0, 0}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progress := AllocateInstalmentPayments(plan, tt.totalPaid)
+			for i, p := range progress {
+				if p.PaidAmount.Cents() != tt.wantPaid[i] {
+					t.Errorf("progress[%d].PaidAmount = %v, want %d cents", i, p.PaidAmount, tt.wantPaid[i])
+				}
+				if !p.PaidAmount.Add(p.OutstandingAmount).Equal(p.Amount) {
+					t.Errorf("progress[%d] paid + outstanding != amount", i)
+				}
+			}
+			next := NextOpenInstalment(progress)
+			gotNext := 0
+			if next != nil {
+				gotNext = next.ID
+			}
+			if gotNext != tt.wantNext {
+				t.Errorf("NextOpenInstalment() = %d, want %d", gotNext, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestInstalmentProgress_IsOverdue(t *testing.T) {
+	now := time.Date(2026, 2, 10, 9, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		name        string
+		dueDate     time.Time
+		outstanding money.Money
+		wantOverdue bool
+		wantDays    int
+	}{
+		{"到期日已过且未收齐", date(2026, 2, 1), money.FromCents(100), true, 9},
+		{"当天到期不算逾期", date(2026, 2, 10), money.FromCents(100), false, 0},
+		{"未到期", date(2026, 3, 1), money.FromCents(100), false, 0},
+		{"已收齐不算逾期", date(2026, 1, 1), money.Zero, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &InstalmentProgress{
+				OrderInstalment:   &OrderInstalment{DueDate: tt.dueDate},
+				OutstandingAmount: tt.outstanding,
+			}
+			if got := p.IsOverdue(now); got != tt.wantOverdue {
+				t.Errorf("IsOverdue() = %v, want %v", got, tt.wantOverdue)
+			}
+			if got := p.OverdueDays(now); got != tt.wantDays {
+				t.Errorf("OverdueDays() = %d, want %d", got, tt.wantDays)
+			}
+		})
+	}
+}
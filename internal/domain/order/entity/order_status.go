@@ -0,0 +1,95 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+
+	"charonoms/internal/domain/shared/money"
+)
+
+// OperatorSystem 系统自动触发状态变更时记录的操作人
+const OperatorSystem = "system"
+
+// orderStatusTransitions 订单状态机：当前状态 -> 允许流转到的状态
+//
+//	草稿 -> 未支付（提交）、已作废（作废）
+//	未支付/部分支付/已支付 之间按收款情况互相流转（新增、删除、认领收款）
+//	部分支付/已支付 -> 退费中（申请退费）
+//	退费中 -> 未支付/部分支付/已支付（退费审批通过按净收款重算，驳回恢复原状态）
+var orderStatusTransitions = map[int][]int{
+	OrderStatusDraft:       {OrderStatusUnpaid, OrderStatusCancelled},
+	OrderStatusUnpaid:      {OrderStatusPartialPaid, OrderStatusPaid},
+	OrderStatusPartialPaid: {OrderStatusUnpaid, OrderStatusPaid, OrderStatusRefunding},
+	OrderStatusPaid:        {OrderStatusUnpaid, OrderStatusPartialPaid, OrderStatusRefunding},
+	OrderStatusRefunding:   {OrderStatusUnpaid, OrderStatusPartialPaid, OrderStatusPaid},
+	OrderStatusCancelled:   {},
+}
+
+// OrderStatusHistory 订单状态变更记录
+type OrderStatusHistory struct {
+	ID         int       `json:"id"`
+	OrderID    int       `json:"order_id"`
+	FromStatus int       `json:"from_status"`
+	ToStatus   int       `json:"to_status"`
+	Operator   string    `json:"operator"`
+	Reason     string    `json:"reason"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+// IllegalTransitionError 订单状态流转不合法
+type IllegalTransitionError struct {
+	OrderID int
+	From    int
+	To      int
+}
+
+// Error 实现error接口
+func (e *IllegalTransitionError) Error() string {
+	return fmt.Sprintf("订单 %d 不允许从状态 %d 变更为 %d", e.OrderID, e.From, e.To)
+}
+
+// CanTransitionTo 判断订单能否流转到目标状态
+func (o *Order) CanTransitionTo(to int) bool {
+	for _, allowed := range orderStatusTransitions[o.Status] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionTo 按状态机流转订单状态，返回需要保存的状态变更记录
+// 目标状态与当前状态相同时不产生变更，返回 nil；不允许的流转返回 *IllegalTransitionError
+func (o *Order) TransitionTo(to int, operator, reason string) (*OrderStatusHistory, error) {
+	if o.Status == to {
+		return nil, nil
+	}
+	if !o.CanTransitionTo(to) {
+		return nil, &IllegalTransitionError{OrderID: o.ID, From: o.Status, To: to}
+	}
+	if operator == "" {
+		operator = OperatorSystem
+	}
+
+	history := &OrderStatusHistory{
+		OrderID:    o.ID,
+		FromStatus: o.Status,
+		ToStatus:   to,
+		Operator:   operator,
+		Reason:     reason,
+		CreateTime: time.Now(),
+	}
+	o.Status = to
+	return history, nil
+}
+
+// PaymentStatus 根据净收款金额计算订单应处的支付状态
+func PaymentStatus(netPaid, amountReceived money.Money) int {
+	if !netPaid.IsPositive() {
+		return OrderStatusUnpaid
+	}
+	if netPaid.LessThan(amountReceived) {
+		return OrderStatusPartialPaid
+	}
+	return OrderStatusPaid
+}
@@ -0,0 +1,128 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+
+	"charonoms/internal/domain/shared/money"
+)
+
+func TestOrder_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		name string
+		from int
+		to   int
+		want bool
+	}{
+		{"草稿可以提交为未支付", OrderStatusDraft, OrderStatusUnpaid, true},
+		{"草稿可以作废", OrderStatusDraft, OrderStatusCancelled, true},
+		{"草稿不能直接变为已支付", OrderStatusDraft, OrderStatusPaid, false},
+		{"未支付可以变为部分支付", OrderStatusUnpaid, OrderStatusPartialPaid, true},
+		{"未支付可以变为已支付", OrderStatusUnpaid, OrderStatusPaid, true},
+		{"未支付不能作废", OrderStatusUnpaid, OrderStatusCancelled, false},
+		{"未支付不能申请退费", OrderStatusUnpaid, OrderStatusRefunding, false},
+		{"部分支付可以申请退费", OrderStatusPartialPaid, OrderStatusRefunding, true},
+		{"已支付可以申请退费", OrderStatusPaid, OrderStatusRefunding, true},
+		{"已支付可以退回部分支付", OrderStatusPaid, OrderStatusPartialPaid, true},
+		{"退费中可以恢复为已支付", OrderStatusRefunding, OrderStatusPaid, true},
+		{"退费中不能回到草稿", OrderStatusRefunding, OrderStatusDraft, false},
+		{"已作废不能再流转", OrderStatusCancelled, OrderStatusUnpaid, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Order{Status: tt.from}
+			if got := o.CanTransitionTo(tt.to); got != tt.want {
+				t.Errorf("Order.CanTransitionTo(%d) = %v, want %v", tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrder_TransitionTo(t *testing.T) {
+	t.Run("合法流转返回变更记录并更新状态", func(t *testing.T) {
+		o := &Order{ID: 7, Status: OrderStatusDraft}
+		history, err := o.TransitionTo(OrderStatusUnpaid, "alice", "提交订单")
+		if err != nil {
+			t.Fatalf("TransitionTo() error = %v", err)
+		}
+		if history == nil {
+			t.Fatal("TransitionTo() history = nil, want non-nil")
+		}
+		if history.OrderID != 7 || history.FromStatus != OrderStatusDraft || history.ToStatus != OrderStatusUnpaid {
+			t.Errorf("history = %+v, want order 7 from %d to %d", history, OrderStatusDraft, OrderStatusUnpaid)
+		}
+		if history.Operator != "alice" || history.Reason != "提交订单" {
+			t.Errorf("history operator/reason = %q/%q", history.Operator, history.Reason)
+		}
+		if history.CreateTime.IsZero() {
+			t.Error("history.CreateTime should be set")
+		}
+		if o.Status != OrderStatusUnpaid {
+			t.Errorf("Order.Status = %d, want %d", o.Status, OrderStatusUnpaid)
+		}
+	})
+
+	t.Run("未指定操作人时记录为系统", func(t *testing.T) {
+		o := &Order{ID: 7, Status: OrderStatusUnpaid}
+		history, err := o.TransitionTo(OrderStatusPaid, "", "新增收款")
+		if err != nil {
+			t.Fatalf("TransitionTo() error = %v", err)
+		}
+		if history.Operator != OperatorSystem {
+			t.Errorf("history.Operator = %q, want %q", history.Operator, OperatorSystem)
+		}
+	})
+
+	t.Run("状态不变时不产生记录", func(t *testing.T) {
+		o := &Order{ID: 7, Status: OrderStatusPaid}
+		history, err := o.TransitionTo(OrderStatusPaid, "alice", "")
+		if err != nil {
+			t.Fatalf("TransitionTo() error = %v", err)
+		}
+		if history != nil {
+			t.Errorf("TransitionTo() history = %+v, want nil", history)
+		}
+	})
+
+	t.Run("非法流转返回错误且不修改状态", func(t *testing.T) {
+		o := &Order{ID: 7, Status: OrderStatusCancelled}
+		history, err := o.TransitionTo(OrderStatusUnpaid, "alice", "")
+		if history != nil {
+			t.Errorf("TransitionTo() history = %+v, want nil", history)
+		}
+		var illegal *IllegalTransitionError
+		if !errors.As(err, &illegal) {
+			t.Fatalf("TransitionTo() error = %v, want *IllegalTransitionError", err)
+		}
+		if illegal.OrderID != 7 || illegal.From != OrderStatusCancelled || illegal.To != OrderStatusUnpaid {
+			t.Errorf("IllegalTransitionError = %+v", illegal)
+		}
+		if o.Status != OrderStatusCancelled {
+			t.Errorf("Order.Status = %d, want %d", o.Status, OrderStatusCancelled)
+		}
+	})
+}
+
+func TestPaymentStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		netPaid        money.Money
+		amountReceived money.Money
+		want           int
+	}{
+		{"无收款为未支付", money.Zero, money.FromCents(10000), OrderStatusUnpaid},
+		{"净收款为负为未支付", money.FromCents(-100), money.FromCents(10000), OrderStatusUnpaid},
+		{"收款不足为部分支付", money.FromCents(9999), money.FromCents(10000), OrderStatusPartialPaid},
+		{"收款足额为已支付", money.FromCents(10000), money.FromCents(10000), OrderStatusPaid},
+		{"超额收款为已支付", money.FromCents(10001), money.FromCents(10000), OrderStatusPaid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PaymentStatus(tt.netPaid, tt.amountReceived); got != tt.want {
+				t.Errorf("PaymentStatus() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package entity
 import (
 	"testing"
 	"time"
+
+	"charonoms/internal/domain/shared/money"
 )
 
 func TestOrder_CanEdit(t *testing.T) {
@@ -79,11 +81,11 @@ func TestOrder_CanCancel(t *testing.T) {
 
 func TestOrder_ValidateAmounts(t *testing.T) {
 	tests := []struct {
-		name              string
-		amountReceivable  float64
-		amountReceived    float64
-		discountAmount    float64
-		want              bool
+		name             string
+		amountReceivable float64
+		amountReceived   float64
+		discountAmount   float64
+		want             bool
 	}{
 		{"正常金额", 100.00, 90.00, 10.00, true},
 		{"应收金额为零", 0, 0, 0, true},
@@ -97,9 +99,9 @@ func TestOrder_ValidateAmounts(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			o := &Order{
-				AmountReceivable: tt.amountReceivable,
-				AmountReceived:   tt.amountReceived,
-				DiscountAmount:   tt.discountAmount,
+				AmountReceivable: money.FromFloat(tt.amountReceivable),
+				AmountReceived:   money.FromFloat(tt.amountReceived),
+				DiscountAmount:   money.FromFloat(tt.discountAmount),
 			}
 			if got := o.ValidateAmounts(); got != tt.want {
 				t.Errorf("Order.ValidateAmounts() = %v, want %v", got, tt.want)
@@ -145,9 +147,9 @@ func TestOrder_Creation(t *testing.T) {
 		ID:                  1,
 		StudentID:           100,
 		ExpectedPaymentTime: &expectedPaymentTime,
-		AmountReceivable:    1000.00,
-		AmountReceived:      900.00,
-		DiscountAmount:      100.00,
+		AmountReceivable:    money.FromFloat(1000.00),
+		AmountReceived:      money.FromFloat(900.00),
+		DiscountAmount:      money.FromFloat(100.00),
 		Status:              OrderStatusDraft,
 		CreateTime:          now,
 	}
@@ -158,16 +160,35 @@ func TestOrder_Creation(t *testing.T) {
 	if order.StudentID != 100 {
 		t.Errorf("Order.StudentID = %d, want 100", order.StudentID)
 	}
-	if order.AmountReceivable != 1000.00 {
-		t.Errorf("Order.AmountReceivable = %f, want 1000.00", order.AmountReceivable)
+	if order.AmountReceivable != money.FromFloat(1000.00) {
+		t.Errorf("Order.AmountReceivable = %v, want 1000.00", order.AmountReceivable)
 	}
-	if order.AmountReceived != 900.00 {
-		t.Errorf("Order.AmountReceived = %f, want 900.00", order.AmountReceived)
+	if order.AmountReceived != money.FromFloat(900.00) {
+		t.Errorf("Order.AmountReceived = %v, want 900.00", order.AmountReceived)
 	}
-	if order.DiscountAmount != 100.00 {
-		t.Errorf("Order.DiscountAmount = %f, want 100.00", order.DiscountAmount)
+	if order.DiscountAmount != money.FromFloat(100.00) {
+		t.Errorf("Order.DiscountAmount = %v, want 100.00", order.DiscountAmount)
 	}
 	if !order.ValidateAmounts() {
 		t.Error("Order.ValidateAmounts() should return true for valid amounts")
 	}
 }
+
+func TestIsValidAllocationStrategy(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     bool
+	}{
+		{"", true},
+		{AllocationStrategySequential, true},
+		{AllocationStrategyProRata, true},
+		{AllocationStrategyClassifyPriority, true},
+		{"random", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsValidAllocationStrategy(tt.strategy); got != tt.want {
+			t.Errorf("IsValidAllocationStrategy(%q) = %v, want %v", tt.strategy, got, tt.want)
+		}
+	}
+}
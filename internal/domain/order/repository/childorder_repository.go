@@ -2,13 +2,54 @@ package repository
 
 import (
 	"charonoms/internal/domain/order/entity"
+	"charonoms/internal/domain/shared/money"
 	"context"
+
+	"gorm.io/gorm"
+)
+
+// 子订单列表排序字段
+const (
+	ChildOrderSortID               = "id"
+	ChildOrderSortCreateTime       = "create_time"
+	ChildOrderSortAmountReceivable = "amount_receivable"
+	ChildOrderSortAmountReceived   = "amount_received"
+	ChildOrderSortStatus           = "status"
 )
 
+// ChildOrderListFilter 子订单列表查询条件
+type ChildOrderListFilter struct {
+	ID              *int
+	ParentsID       *int
+	GoodsID         *int
+	Status          *int
+	CreateDateStart *string      // 格式：YYYY-MM-DD，含当天
+	CreateDateEnd   *string      // 格式：YYYY-MM-DD，含当天
+	AmountMin       *money.Money // 实收金额下限
+	AmountMax       *money.Money // 实收金额上限
+	SortBy          string       // 排序字段，见 ChildOrderSort* 常量，默认子订单ID
+	SortDesc        bool
+	Page            int
+	PageSize        int
+}
+
+// IsValidChildOrderSortField 判断是否为支持的子订单排序字段
+func IsValidChildOrderSortField(field string) bool {
+	switch field {
+	case ChildOrderSortID, ChildOrderSortCreateTime, ChildOrderSortAmountReceivable,
+		ChildOrderSortAmountReceived, ChildOrderSortStatus:
+		return true
+	}
+	return false
+}
+
 // ChildOrderRepository 子订单仓储接口
 type ChildOrderRepository interface {
-	// GetChildOrders 获取子订单列表（含商品信息）
-	GetChildOrders(ctx context.Context) ([]map[string]interface{}, error)
+	// WithTx 返回绑定调用方事务的仓储实例，其读写都在该事务中执行
+	WithTx(tx *gorm.DB) ChildOrderRepository
+
+	// GetChildOrders 分页查询子订单列表（含商品信息），返回当前页数据和总数
+	GetChildOrders(ctx context.Context, filter ChildOrderListFilter) ([]map[string]interface{}, int64, error)
 
 	// GetChildOrdersByParentID 根据父订单ID获取子订单列表
 	GetChildOrdersByParentID(ctx context.Context, parentID int) ([]map[string]interface{}, error)
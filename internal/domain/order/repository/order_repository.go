@@ -2,25 +2,72 @@ package repository
 
 import (
 	"charonoms/internal/domain/order/entity"
+	"charonoms/internal/domain/shared/money"
 	"context"
+
+	"gorm.io/gorm"
+)
+
+// 订单列表排序字段
+const (
+	OrderSortID                  = "id"
+	OrderSortCreateTime          = "create_time"
+	OrderSortExpectedPaymentTime = "expected_payment_time"
+	OrderSortAmountReceivable    = "amount_receivable"
+	OrderSortAmountReceived      = "amount_received"
+	OrderSortStatus              = "status"
 )
 
+// OrderListFilter 订单列表查询条件
+type OrderListFilter struct {
+	ID                       *int
+	StudentID                *int
+	Status                   *int
+	CreateDateStart          *string      // 格式：YYYY-MM-DD，含当天
+	CreateDateEnd            *string      // 格式：YYYY-MM-DD，含当天
+	ExpectedPaymentDateStart *string      // 格式：YYYY-MM-DD，含当天
+	ExpectedPaymentDateEnd   *string      // 格式：YYYY-MM-DD，含当天
+	AmountMin                *money.Money // 实收金额下限
+	AmountMax                *money.Money // 实收金额上限
+	ActivityID               *int         // 参与的活动
+	SortBy                   string       // 排序字段，见 OrderSort* 常量，默认创建时间
+	SortDesc                 bool
+	Page                     int
+	PageSize                 int
+}
+
+// IsValidOrderSortField 判断是否为支持的订单排序字段
+func IsValidOrderSortField(field string) bool {
+	switch field {
+	case OrderSortID, OrderSortCreateTime, OrderSortExpectedPaymentTime,
+		OrderSortAmountReceivable, OrderSortAmountReceived, OrderSortStatus:
+		return true
+	}
+	return false
+}
+
 // OrderRepository 订单仓储接口
 type OrderRepository interface {
-	// GetOrders 获取订单列表（含学生信息）
-	GetOrders(ctx context.Context) ([]map[string]interface{}, error)
+	// WithTx 返回绑定调用方事务的仓储实例，其读写都在该事务中执行
+	WithTx(tx *gorm.DB) OrderRepository
 
-	// GetOrderByID 根据ID查询订单
+	// GetOrders 分页查询订单列表（含学生信息），返回当前页数据和总数
+	GetOrders(ctx context.Context, filter OrderListFilter) ([]map[string]interface{}, int64, error)
+
+	// GetOrderByID 根据ID查询订单（含分期计划）
 	GetOrderByID(ctx context.Context, id int) (*entity.Order, error)
 
-	// CreateOrder 创建订单（含事务：订单、子订单、活动关联）
-	CreateOrder(ctx context.Context, order *entity.Order, childOrders []*entity.ChildOrder, activityIDs []int) (int, error)
+	// CreateOrder 创建订单（含事务：订单、子订单、活动关联、活动优惠明细及分期计划）
+	CreateOrder(ctx context.Context, order *entity.Order, childOrders []*entity.ChildOrder, activityIDs []int, activityDiscounts []*entity.OrderActivityDiscount) (int, error)
+
+	// UpdateOrder 更新订单（含事务：订单、子订单、活动关联、活动优惠明细及分期计划）
+	UpdateOrder(ctx context.Context, order *entity.Order, childOrders []*entity.ChildOrder, activityIDs []int, activityDiscounts []*entity.OrderActivityDiscount) error
 
-	// UpdateOrder 更新订单（含事务：订单、子订单、活动关联）
-	UpdateOrder(ctx context.Context, order *entity.Order, childOrders []*entity.ChildOrder, activityIDs []int) error
+	// GetOrderActivityDiscounts 获取订单的活动优惠明细
+	GetOrderActivityDiscounts(ctx context.Context, orderID int) ([]*entity.OrderActivityDiscount, error)
 
-	// UpdateOrderStatus 更新订单状态（含子订单状态级联更新）
-	UpdateOrderStatus(ctx context.Context, orderID int, orderStatus int, childOrderStatus int) error
+	// GetOrderStatusHistory 获取订单状态变更历史（状态变更统一通过 service.OrderStatusService 完成）
+	GetOrderStatusHistory(ctx context.Context, orderID int) ([]*entity.OrderStatusHistory, error)
 
 	// GetOrderGoods 获取订单商品列表（关联商品、品牌、分类、属性）
 	GetOrderGoods(ctx context.Context, orderID int) ([]map[string]interface{}, error)
@@ -31,6 +78,6 @@ type OrderRepository interface {
 	// DeleteOrderActivities 删除订单的所有活动关联
 	DeleteOrderActivities(ctx context.Context, orderID int) error
 
-	// GetUnpaidOrdersByStudentID 获取学生的未付款订单列表
+	// GetUnpaidOrdersByStudentID 获取学生的未付款订单列表（含分期计划）
 	GetUnpaidOrdersByStudentID(ctx context.Context, studentID int) ([]*entity.Order, error)
 }
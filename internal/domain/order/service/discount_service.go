@@ -2,8 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"gorm.io/gorm"
+
+	"charonoms/internal/domain/shared/money"
 )
 
 // DiscountService 优惠计算服务
@@ -16,85 +21,218 @@ func NewDiscountService(db *gorm.DB) *DiscountService {
 	return &DiscountService{db: db}
 }
 
+// 活动类型（对应 activity_template.type）
+const (
+	ActivityTypeReduction = 1 // 满减
+	ActivityTypeDiscount  = 2 // 满折
+	ActivityTypeGift      = 3 // 满赠
+)
+
 // GoodsForDiscount 用于优惠计算的商品信息
 type GoodsForDiscount struct {
 	GoodsID int
-	Price   float64
+	Price   money.Money
+}
+
+// GiftGoods 满赠活动赠送的商品
+type GiftGoods struct {
+	ActivityID int
+	GoodsID    int
+	Quantity   int
+}
+
+// ActivityDiscount 单个活动对单个商品贡献的优惠金额
+type ActivityDiscount struct {
+	ActivityID int
+	GoodsID    int
+	Amount     money.Money
+}
+
+// DiscountResult 订单优惠计算结果
+type DiscountResult struct {
+	TotalDiscount      money.Money
+	ChildDiscounts     map[int]money.Money // 商品ID -> 优惠金额
+	Breakdown          []ActivityDiscount  // 各活动对各商品的优惠明细
+	AppliedActivityIDs []int               // 按叠加规则实际生效的活动
+}
+
+// InactiveActivityIDs 返回未启用、不在活动时间内或不存在的活动ID（按提交顺序，重复的只返回一次）
+func (s *DiscountService) InactiveActivityIDs(ctx context.Context, activityIDs []int, at time.Time) ([]int, error) {
+	inactive := make([]int, 0)
+	if len(activityIDs) == 0 {
+		return inactive, nil
+	}
+
+	var activeIDs []int
+	err := s.db.WithContext(ctx).
+		Table("activity").
+		Where("id IN ? AND status = ? AND start_time <= ? AND end_time >= ?", activityIDs, 0, at, at).
+		Pluck("id", &activeIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询活动状态失败: %w", err)
+	}
+
+	active := make(map[int]bool, len(activeIDs))
+	for _, id := range activeIDs {
+		active[id] = true
+	}
+	seen := make(map[int]bool)
+	for _, id := range activityIDs {
+		if !active[id] && !seen[id] {
+			inactive = append(inactive, id)
+		}
+		seen[id] = true
+	}
+	return inactive, nil
 }
 
 // CalculateDiscount 计算订单优惠
-func (s *DiscountService) CalculateDiscount(ctx context.Context, goodsList []GoodsForDiscount, activityIDs []int) (totalDiscount float64, childDiscounts map[int]float64, err error) {
-	childDiscounts = make(map[int]float64)
+func (s *DiscountService) CalculateDiscount(ctx context.Context, goodsList []GoodsForDiscount, activityIDs []int) (totalDiscount money.Money, childDiscounts map[int]money.Money, err error) {
+	result, err := s.CalculateDiscountDetail(ctx, goodsList, activityIDs)
+	if err != nil {
+		return money.Zero, nil, err
+	}
+	return result.TotalDiscount, result.ChildDiscounts, nil
+}
+
+// CalculateDiscountDetail 计算订单优惠，按活动模板的叠加规则取舍活动并返回优惠明细
+func (s *DiscountService) CalculateDiscountDetail(ctx context.Context, goodsList []GoodsForDiscount, activityIDs []int) (*DiscountResult, error) {
+	result := &DiscountResult{
+		ChildDiscounts:     make(map[int]money.Money),
+		Breakdown:          make([]ActivityDiscount, 0),
+		AppliedActivityIDs: make([]int, 0),
+	}
 
 	// 初始化每个商品的优惠为0
+	prices := make(map[int]money.Money)
 	for _, goods := range goodsList {
-		childDiscounts[goods.GoodsID] = 0
+		result.ChildDiscounts[goods.GoodsID] = money.Zero
+		prices[goods.GoodsID] = prices[goods.GoodsID].Add(goods.Price)
 	}
 
 	// 如果没有商品或活动，返回零优惠
 	if len(goodsList) == 0 || len(activityIDs) == 0 {
-		return 0, childDiscounts, nil
+		return result, nil
 	}
 
-	// 遍历每个活动计算优惠
-	for _, activityID := range activityIDs {
-		discount, childDisc, err := s.calculateActivityDiscount(ctx, goodsList, activityID)
-		if err != nil {
-			continue // 忽略单个活动的错误，继续处理其他活动
-		}
+	// 1. 独立计算各活动的优惠和赠品，按叠加规则确定生效的活动
+	applied, err := s.evaluateActivities(ctx, goodsList, activityIDs)
+	if err != nil {
+		return nil, err
+	}
 
-		totalDiscount += discount
+	// 2. 依次应用生效活动，单个商品的累计优惠不超过其标准售价
+	totalDiscount := money.Zero
+	for _, r := range applied {
+		for _, goods := range goodsList {
+			amount, ok := r.ChildDiscounts[goods.GoodsID]
+			if !ok {
+				continue
+			}
+			remaining := prices[goods.GoodsID].Sub(result.ChildDiscounts[goods.GoodsID])
+			amount = money.Min(amount, remaining)
+			if !amount.IsPositive() {
+				continue
+			}
 
-		// 累加子订单优惠
-		for goodsID, amount := range childDisc {
-			childDiscounts[goodsID] += amount
+			result.ChildDiscounts[goods.GoodsID] = result.ChildDiscounts[goods.GoodsID].Add(amount)
+			result.Breakdown = append(result.Breakdown, ActivityDiscount{
+				ActivityID: r.ActivityID,
+				GoodsID:    goods.GoodsID,
+				Amount:     amount,
+			})
+			totalDiscount = totalDiscount.Add(amount)
+			delete(r.ChildDiscounts, goods.GoodsID) // 同一商品重复出现时只分摊一次
 		}
+		result.AppliedActivityIDs = append(result.AppliedActivityIDs, r.ActivityID)
 	}
 
-	// 四舍五入到两位小数
-	totalDiscount = roundToTwoDecimal(totalDiscount)
-	for goodsID := range childDiscounts {
-		childDiscounts[goodsID] = roundToTwoDecimal(childDiscounts[goodsID])
-	}
+	result.TotalDiscount = totalDiscount
+
+	return result, nil
+}
 
-	return totalDiscount, childDiscounts, nil
+// activityInfo 活动及其模板的类型和叠加规则
+type activityInfo struct {
+	ID         int
+	TemplateID int
+	Type       int
+	SelectType int
+	StackMode  int
+	StackGroup string
 }
 
-// calculateActivityDiscount 计算单个活动的优惠
-func (s *DiscountService) calculateActivityDiscount(ctx context.Context, goodsList []GoodsForDiscount, activityID int) (float64, map[int]float64, error) {
-	childDiscounts := make(map[int]float64)
+// evaluateActivities 独立计算每个活动的优惠或赠品（重复的活动ID只计算一次），再按叠加规则返回生效的活动
+// 优惠和赠品使用同一次叠加判定，互斥的满赠活动与满减、满折活动不会同时生效
+func (s *DiscountService) evaluateActivities(ctx context.Context, goodsList []GoodsForDiscount, activityIDs []int) ([]activityResult, error) {
+	candidates := make([]activityResult, 0, len(activityIDs))
+	seen := make(map[int]bool)
+	for _, activityID := range activityIDs {
+		if seen[activityID] {
+			continue
+		}
+		seen[activityID] = true
+
+		activity, err := s.loadActivity(ctx, activityID)
+		if err != nil {
+			return nil, err
+		}
 
-	// 1. 查询活动信息
-	var activity struct {
-		ID         int
-		TemplateID int
-		Type       int
-		SelectType int
+		var r *activityResult
+		switch activity.Type {
+		case ActivityTypeReduction, ActivityTypeDiscount:
+			r, err = s.calculateActivityDiscount(ctx, goodsList, activity)
+		case ActivityTypeGift:
+			r, err = s.calculateActivityGift(ctx, goodsList, activity)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("计算活动 %d 的优惠失败: %w", activityID, err)
+		}
+		if r.Discount.IsPositive() || r.Gift != nil {
+			candidates = append(candidates, *r)
+		}
 	}
 
+	return resolveStacking(candidates), nil
+}
+
+// loadActivity 查询活动信息及其模板的叠加规则
+func (s *DiscountService) loadActivity(ctx context.Context, activityID int) (*activityInfo, error) {
+	var activity activityInfo
 	err := s.db.WithContext(ctx).
 		Table("activity a").
-		Select("a.id, a.template_id, t.type, t.select_type").
+		Select("a.id, a.template_id, t.type, t.select_type, t.stack_mode, t.stack_group").
 		Joins("JOIN activity_template t ON a.template_id = t.id").
 		Where("a.id = ?", activityID).
 		First(&activity).Error
-
 	if err != nil {
-		return 0, childDiscounts, err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("活动 %d 不存在", activityID)
+		}
+		return nil, fmt.Errorf("查询活动 %d 失败: %w", activityID, err)
 	}
+	return &activity, nil
+}
 
-	// 2. 仅处理满折类型（type=2）
-	if activity.Type != 2 {
-		return 0, childDiscounts, nil
+// calculateActivityDiscount 独立计算单个满减或满折活动的优惠（不考虑叠加规则）
+func (s *DiscountService) calculateActivityDiscount(ctx context.Context, goodsList []GoodsForDiscount, activity *activityInfo) (*activityResult, error) {
+	activityID := activity.ID
+	result := &activityResult{
+		ActivityID:     activityID,
+		StackMode:      activity.StackMode,
+		StackGroup:     activity.StackGroup,
+		ChildDiscounts: make(map[int]money.Money),
 	}
 
-	// 3. 查询活动折扣规则（按threshold_amount降序）
+	// 1. 查询活动优惠规则（按threshold_amount降序）
 	var details []struct {
 		ThresholdAmount float64
 		DiscountValue   float64
 	}
 
-	err = s.db.WithContext(ctx).
+	err := s.db.WithContext(ctx).
 		Table("activity_detail").
 		Select("threshold_amount, discount_value").
 		Where("activity_id = ?", activityID).
@@ -102,10 +240,10 @@ func (s *DiscountService) calculateActivityDiscount(ctx context.Context, goodsLi
 		Find(&details).Error
 
 	if err != nil || len(details) == 0 {
-		return 0, childDiscounts, err
+		return result, err
 	}
 
-	// 4. 查询活动模板关联的商品/类型
+	// 2. 查询活动模板关联的商品/类型
 	var templateGoods []struct {
 		GoodsID    *int
 		ClassifyID *int
@@ -118,58 +256,193 @@ func (s *DiscountService) calculateActivityDiscount(ctx context.Context, goodsLi
 		Find(&templateGoods).Error
 
 	if err != nil || len(templateGoods) == 0 {
-		return 0, childDiscounts, err
+		return result, err
 	}
 
-	// 5. 筛选参与活动的商品
+	// 3. 筛选参与活动的商品
 	eligibleGoods, err := s.filterEligibleGoods(ctx, goodsList, templateGoods, activity.SelectType)
 	if err != nil || len(eligibleGoods) == 0 {
-		return 0, childDiscounts, err
+		return result, err
 	}
 
-	// 6. 匹配最大满足的折扣档位（按商品数量匹配）
-	eligibleCount := float64(len(eligibleGoods))
-	var matchedDiscount float64 = 0
+	eligiblePriceSum := sumPrices(eligibleGoods)
 
-	for _, detail := range details {
-		if eligibleCount >= detail.ThresholdAmount {
-			matchedDiscount = detail.DiscountValue
-			break
+	// 4. 匹配最大满足的档位并计算总优惠
+	activityDiscount := money.Zero
+	if activity.Type == ActivityTypeReduction {
+		// 满减：按参与商品标准售价之和匹配档位，discount_value为减免金额
+		for _, detail := range details {
+			if !eligiblePriceSum.LessThan(money.FromFloat(detail.ThresholdAmount)) {
+				activityDiscount = money.FromFloat(detail.DiscountValue)
+				break
+			}
+		}
+		// 减免金额不能超过参与商品总价
+		activityDiscount = money.Min(activityDiscount, eligiblePriceSum)
+	} else {
+		// 满折：按商品数量匹配档位
+		eligibleCount := float64(len(eligibleGoods))
+		var matchedDiscount float64 = 0
+		for _, detail := range details {
+			if eligibleCount >= detail.ThresholdAmount {
+				matchedDiscount = detail.DiscountValue
+				break
+			}
+		}
+		if matchedDiscount == 0 {
+			return result, nil
+		}
+
+		// 总优惠 = (1 - 折扣/100) × 参与商品标准售价之和
+		// discount_value=90表示9折(付90%)，discount_value=80表示8折(付80%)
+		discountRate := (100 - matchedDiscount) / 100 // 80 -> 0.2 (优惠20%)
+		activityDiscount = eligiblePriceSum.MulRate(discountRate)
+	}
+
+	if !activityDiscount.IsPositive() {
+		return result, nil
+	}
+
+	// 5. 按比例分摊优惠到各商品
+	result.Discount = activityDiscount
+	result.ChildDiscounts = allocateByPrice(eligibleGoods, activityDiscount)
+
+	return result, nil
+}
+
+// CalculateGifts 计算订单满足的满赠活动赠品，满赠活动与满减、满折活动按同样的叠加规则取舍
+func (s *DiscountService) CalculateGifts(ctx context.Context, goodsList []GoodsForDiscount, activityIDs []int) ([]GiftGoods, error) {
+	gifts := make([]GiftGoods, 0)
+
+	if len(goodsList) == 0 || len(activityIDs) == 0 {
+		return gifts, nil
+	}
+
+	applied, err := s.evaluateActivities(ctx, goodsList, activityIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range applied {
+		if r.Gift != nil {
+			gifts = append(gifts, *r.Gift)
 		}
 	}
 
-	if matchedDiscount == 0 {
-		return 0, childDiscounts, nil
+	return gifts, nil
+}
+
+// calculateActivityGift 独立计算单个满赠活动的赠品（不考虑叠加规则），未满足门槛时 Gift 为 nil
+// 赠品按标准售价计算的价值记为活动优惠金额，用于叠加规则的比较
+func (s *DiscountService) calculateActivityGift(ctx context.Context, goodsList []GoodsForDiscount, activity *activityInfo) (*activityResult, error) {
+	activityID := activity.ID
+	result := &activityResult{
+		ActivityID:     activityID,
+		StackMode:      activity.StackMode,
+		StackGroup:     activity.StackGroup,
+		ChildDiscounts: make(map[int]money.Money),
+	}
+
+	// 1. 查询活动赠品规则（按threshold_amount降序）
+	var details []struct {
+		ThresholdAmount float64
+		DiscountValue   float64
+		GiftGoodsID     *int
+	}
+
+	err := s.db.WithContext(ctx).
+		Table("activity_detail").
+		Select("threshold_amount, discount_value, gift_goods_id").
+		Where("activity_id = ?", activityID).
+		Order("threshold_amount DESC").
+		Find(&details).Error
+
+	if err != nil || len(details) == 0 {
+		return result, err
 	}
 
-	// 7. 计算总优惠：(1 - 折扣/100) × 参与商品标准售价之和
-	// discount_value=90表示9折(付90%)，discount_value=80表示8折(付80%)
-	var eligiblePriceSum float64
-	for _, goods := range eligibleGoods {
-		eligiblePriceSum += goods.Price
+	// 2. 查询活动模板关联的商品/类型
+	var templateGoods []struct {
+		GoodsID    *int
+		ClassifyID *int
 	}
 
-	discountRate := 1 - matchedDiscount/100 // 80 -> 1 - 0.8 = 0.2 (优惠20%)
-	activityDiscount := discountRate * eligiblePriceSum
+	err = s.db.WithContext(ctx).
+		Table("activity_template_goods").
+		Select("goods_id, classify_id").
+		Where("template_id = ?", activity.TemplateID).
+		Find(&templateGoods).Error
 
-	// 8. 按比例分摊优惠到各商品
-	if activityDiscount > 0 && eligiblePriceSum > 0 {
-		allocated := 0.0
-		for i, goods := range eligibleGoods {
-			var childDiscount float64
-			if i == len(eligibleGoods)-1 {
-				// 最后一个商品用减法避免精度误差
-				childDiscount = activityDiscount - allocated
-			} else {
-				ratio := goods.Price / eligiblePriceSum
-				childDiscount = roundToTwoDecimal(activityDiscount * ratio)
-				allocated += childDiscount
-			}
-			childDiscounts[goods.GoodsID] = childDiscount
+	if err != nil || len(templateGoods) == 0 {
+		return result, err
+	}
+
+	// 3. 筛选参与活动的商品
+	eligibleGoods, err := s.filterEligibleGoods(ctx, goodsList, templateGoods, activity.SelectType)
+	if err != nil || len(eligibleGoods) == 0 {
+		return result, err
+	}
+
+	eligiblePriceSum := sumPrices(eligibleGoods)
+
+	// 4. 按参与商品标准售价之和匹配最大满足的档位
+	for _, detail := range details {
+		if eligiblePriceSum.LessThan(money.FromFloat(detail.ThresholdAmount)) {
+			continue
+		}
+		if detail.GiftGoodsID == nil || detail.DiscountValue < 1 {
+			return result, nil
+		}
+		gift := &GiftGoods{
+			ActivityID: activityID,
+			GoodsID:    *detail.GiftGoodsID,
+			Quantity:   int(detail.DiscountValue),
+		}
+
+		// 5. 赠品价值 = 赠品标准售价 × 数量
+		var giftPrice money.Money
+		err = s.db.WithContext(ctx).
+			Table("goods").
+			Select("price").
+			Where("id = ?", gift.GoodsID).
+			Scan(&giftPrice).Error
+		if err != nil {
+			return nil, fmt.Errorf("查询赠品 %d 价格失败: %w", gift.GoodsID, err)
 		}
+
+		result.Gift = gift
+		result.Discount = giftPrice.Mul(int64(gift.Quantity))
+		return result, nil
+	}
+
+	return result, nil
+}
+
+// allocateByPrice 按标准售价比例将优惠分摊到各商品，分摊结果之和严格等于总优惠
+func allocateByPrice(goodsList []GoodsForDiscount, discount money.Money) map[int]money.Money {
+	result := make(map[int]money.Money, len(goodsList))
+	if !discount.IsPositive() {
+		return result
+	}
+
+	weights := make([]money.Money, 0, len(goodsList))
+	for _, goods := range goodsList {
+		weights = append(weights, goods.Price)
+	}
+
+	for i, share := range discount.Allocate(weights) {
+		result[goodsList[i].GoodsID] = result[goodsList[i].GoodsID].Add(share)
 	}
 
-	return activityDiscount, childDiscounts, nil
+	return result
+}
+
+// sumPrices 计算商品标准售价之和
+func sumPrices(goodsList []GoodsForDiscount) money.Money {
+	total := money.Zero
+	for _, goods := range goodsList {
+		total = total.Add(goods.Price)
+	}
+	return total
 }
 
 // filterEligibleGoods 筛选参与活动的商品
@@ -198,13 +471,14 @@ func (s *DiscountService) filterEligibleGoods(ctx context.Context, goodsList []G
 				Select("classifyid").
 				Where("id = ?", goods.GoodsID).
 				Scan(&classifyID).Error
+			if err != nil {
+				return nil, fmt.Errorf("查询商品 %d 分类失败: %w", goods.GoodsID, err)
+			}
 
-			if err == nil {
-				for _, tg := range templateGoods {
-					if tg.ClassifyID != nil && *tg.ClassifyID == classifyID {
-						isEligible = true
-						break
-					}
+			for _, tg := range templateGoods {
+				if tg.ClassifyID != nil && *tg.ClassifyID == classifyID {
+					isEligible = true
+					break
 				}
 			}
 		}
@@ -8,6 +8,9 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+
+	activityTemplateEntity "charonoms/internal/domain/activity_template/entity"
+	"charonoms/internal/domain/shared/money"
 )
 
 func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
@@ -33,8 +36,8 @@ func TestDiscountService_CalculateDiscount_NoActivities(t *testing.T) {
 	ctx := context.Background()
 
 	goodsList := []GoodsForDiscount{
-		{GoodsID: 1, Price: 100.00},
-		{GoodsID: 2, Price: 200.00},
+		{GoodsID: 1, Price: m(100.00)},
+		{GoodsID: 2, Price: m(200.00)},
 	}
 
 	// 没有活动时应该返回零优惠
@@ -42,14 +45,14 @@ func TestDiscountService_CalculateDiscount_NoActivities(t *testing.T) {
 	if err != nil {
 		t.Errorf("CalculateDiscount() error = %v, want nil", err)
 	}
-	if totalDiscount != 0 {
+	if totalDiscount != m(0) {
 		t.Errorf("CalculateDiscount() totalDiscount = %v, want 0", totalDiscount)
 	}
 	if len(childDiscounts) != 2 {
 		t.Errorf("CalculateDiscount() len(childDiscounts) = %v, want 2", len(childDiscounts))
 	}
 	for goodsID, discount := range childDiscounts {
-		if discount != 0 {
+		if !discount.IsZero() {
 			t.Errorf("CalculateDiscount() childDiscounts[%d] = %v, want 0", goodsID, discount)
 		}
 	}
@@ -65,7 +68,7 @@ func TestDiscountService_CalculateDiscount_NoGoods(t *testing.T) {
 	if err != nil {
 		t.Errorf("CalculateDiscount() error = %v, want nil", err)
 	}
-	if totalDiscount != 0 {
+	if totalDiscount != m(0) {
 		t.Errorf("CalculateDiscount() totalDiscount = %v, want 0", totalDiscount)
 	}
 	if len(childDiscounts) != 0 {
@@ -101,8 +104,8 @@ func TestDiscountService_CalculateDiscount_ByGoods(t *testing.T) {
 		WillReturnRows(templateGoodsRows)
 
 	goodsList := []GoodsForDiscount{
-		{GoodsID: 1, Price: 100.00},
-		{GoodsID: 2, Price: 200.00},
+		{GoodsID: 1, Price: m(100.00)},
+		{GoodsID: 2, Price: m(200.00)},
 	}
 
 	totalDiscount, childDiscounts, err := s.CalculateDiscount(ctx, goodsList, []int{100})
@@ -111,7 +114,7 @@ func TestDiscountService_CalculateDiscount_ByGoods(t *testing.T) {
 	}
 
 	// 总价300，8折优惠20% = 60
-	if totalDiscount != 60.00 {
+	if totalDiscount != m(60.00) {
 		t.Errorf("CalculateDiscount() totalDiscount = %v, want 60.00", totalDiscount)
 	}
 
@@ -122,7 +125,7 @@ func TestDiscountService_CalculateDiscount_ByGoods(t *testing.T) {
 	}
 
 	for goodsID, expected := range expectedChildDiscounts {
-		if childDiscounts[goodsID] != expected {
+		if childDiscounts[goodsID] != m(expected) {
 			t.Errorf("CalculateDiscount() childDiscounts[%d] = %v, want %v", goodsID, childDiscounts[goodsID], expected)
 		}
 	}
@@ -170,8 +173,8 @@ func TestDiscountService_CalculateDiscount_ByClassify(t *testing.T) {
 		WillReturnRows(goodsClassifyRows2)
 
 	goodsList := []GoodsForDiscount{
-		{GoodsID: 1, Price: 100.00},
-		{GoodsID: 2, Price: 200.00},
+		{GoodsID: 1, Price: m(100.00)},
+		{GoodsID: 2, Price: m(200.00)},
 	}
 
 	totalDiscount, childDiscounts, err := s.CalculateDiscount(ctx, goodsList, []int{200})
@@ -180,7 +183,7 @@ func TestDiscountService_CalculateDiscount_ByClassify(t *testing.T) {
 	}
 
 	// 总价300，9折优惠10% = 30
-	if totalDiscount != 30.00 {
+	if totalDiscount != m(30.00) {
 		t.Errorf("CalculateDiscount() totalDiscount = %v, want 30.00", totalDiscount)
 	}
 
@@ -191,7 +194,7 @@ func TestDiscountService_CalculateDiscount_ByClassify(t *testing.T) {
 	}
 
 	for goodsID, expected := range expectedChildDiscounts {
-		if childDiscounts[goodsID] != expected {
+		if childDiscounts[goodsID] != m(expected) {
 			t.Errorf("CalculateDiscount() childDiscounts[%d] = %v, want %v", goodsID, childDiscounts[goodsID], expected)
 		}
 	}
@@ -230,8 +233,8 @@ func TestDiscountService_CalculateDiscount_NotMeetThreshold(t *testing.T) {
 
 	// 只有2件商品，不满足3件门槛
 	goodsList := []GoodsForDiscount{
-		{GoodsID: 1, Price: 100.00},
-		{GoodsID: 2, Price: 200.00},
+		{GoodsID: 1, Price: m(100.00)},
+		{GoodsID: 2, Price: m(200.00)},
 	}
 
 	totalDiscount, childDiscounts, err := s.CalculateDiscount(ctx, goodsList, []int{300})
@@ -240,12 +243,12 @@ func TestDiscountService_CalculateDiscount_NotMeetThreshold(t *testing.T) {
 	}
 
 	// 不满足门槛，应该没有优惠
-	if totalDiscount != 0 {
+	if totalDiscount != m(0) {
 		t.Errorf("CalculateDiscount() totalDiscount = %v, want 0", totalDiscount)
 	}
 
 	for goodsID, discount := range childDiscounts {
-		if discount != 0 {
+		if !discount.IsZero() {
 			t.Errorf("CalculateDiscount() childDiscounts[%d] = %v, want 0", goodsID, discount)
 		}
 	}
@@ -260,16 +263,19 @@ func TestDiscountService_CalculateDiscount_NonDiscountActivity(t *testing.T) {
 	s := NewDiscountService(db)
 	ctx := context.Background()
 
-	// 模拟查询活动信息：非满折活动（type != 2）
+	// 模拟查询活动信息：满赠活动（type=3）不产生金额优惠
 	activityRows := sqlmock.NewRows([]string{"id", "template_id", "type", "select_type"}).
-		AddRow(600, 6, 1, 2) // type=1，不是满折类型
+		AddRow(600, 6, 3, 2) // type=3，满赠类型
 	mock.ExpectQuery("SELECT (.+) FROM activity (.+)").
 		WithArgs(600, 1).
 		WillReturnRows(activityRows)
+	mock.ExpectQuery("SELECT (.+) FROM `activity_detail` (.+)").
+		WithArgs(600).
+		WillReturnRows(sqlmock.NewRows([]string{"threshold_amount", "discount_value", "gift_goods_id"}))
 
 	goodsList := []GoodsForDiscount{
-		{GoodsID: 1, Price: 100.00},
-		{GoodsID: 2, Price: 200.00},
+		{GoodsID: 1, Price: m(100.00)},
+		{GoodsID: 2, Price: m(200.00)},
 	}
 
 	totalDiscount, childDiscounts, err := s.CalculateDiscount(ctx, goodsList, []int{600})
@@ -277,13 +283,13 @@ func TestDiscountService_CalculateDiscount_NonDiscountActivity(t *testing.T) {
 		t.Fatalf("CalculateDiscount() error = %v", err)
 	}
 
-	// 非满折活动，应该没有优惠
-	if totalDiscount != 0 {
+	// 满赠活动，应该没有金额优惠
+	if totalDiscount != m(0) {
 		t.Errorf("CalculateDiscount() totalDiscount = %v, want 0", totalDiscount)
 	}
 
 	for goodsID, discount := range childDiscounts {
-		if discount != 0 {
+		if !discount.IsZero() {
 			t.Errorf("CalculateDiscount() childDiscounts[%d] = %v, want 0", goodsID, discount)
 		}
 	}
@@ -304,20 +310,451 @@ func TestDiscountService_CalculateDiscount_ActivityNotFound(t *testing.T) {
 		WillReturnError(sql.ErrNoRows)
 
 	goodsList := []GoodsForDiscount{
-		{GoodsID: 1, Price: 100.00},
+		{GoodsID: 1, Price: m(100.00)},
+	}
+
+	// 活动不存在时返回错误，不能按零优惠静默下单
+	if _, _, err := s.CalculateDiscount(ctx, goodsList, []int{999}); err == nil {
+		t.Fatal("CalculateDiscount() error = nil, want activity not found")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDiscountService_CalculateDiscount_Reduction(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := NewDiscountService(db)
+	ctx := context.Background()
+
+	// 模拟查询活动信息
+	activityRows := sqlmock.NewRows([]string{"id", "template_id", "type", "select_type"}).
+		AddRow(400, 4, 1, 2) // type=1(满减), select_type=2(按商品)
+	mock.ExpectQuery("SELECT (.+) FROM activity (.+)").
+		WithArgs(400, 1).
+		WillReturnRows(activityRows)
+
+	// 模拟查询活动满减规则：满500减50，满200减10
+	detailRows := sqlmock.NewRows([]string{"threshold_amount", "discount_value"}).
+		AddRow(500.0, 50.0).
+		AddRow(200.0, 10.0)
+	mock.ExpectQuery("SELECT (.+) FROM `activity_detail` (.+)").
+		WithArgs(400).
+		WillReturnRows(detailRows)
+
+	// 模拟查询活动模板关联的商品：仅商品1和2参与
+	templateGoodsRows := sqlmock.NewRows([]string{"goods_id", "classify_id"}).
+		AddRow(1, nil).
+		AddRow(2, nil)
+	mock.ExpectQuery("SELECT (.+) FROM `activity_template_goods` (.+)").
+		WithArgs(4).
+		WillReturnRows(templateGoodsRows)
+
+	goodsList := []GoodsForDiscount{
+		{GoodsID: 1, Price: m(100.00)},
+		{GoodsID: 2, Price: m(300.00)},
+		{GoodsID: 3, Price: m(500.00)},
+	}
+
+	totalDiscount, childDiscounts, err := s.CalculateDiscount(ctx, goodsList, []int{400})
+	if err != nil {
+		t.Fatalf("CalculateDiscount() error = %v", err)
+	}
+
+	// 参与商品总价400，满足满200减10档位
+	if totalDiscount != m(10.00) {
+		t.Errorf("CalculateDiscount() totalDiscount = %v, want 10.00", totalDiscount)
+	}
+
+	expectedChildDiscounts := map[int]float64{
+		1: 2.50, // 100/400 * 10 = 2.5
+		2: 7.50, // 300/400 * 10 = 7.5
+		3: 0,    // 不参与活动
+	}
+
+	for goodsID, expected := range expectedChildDiscounts {
+		if childDiscounts[goodsID] != m(expected) {
+			t.Errorf("CalculateDiscount() childDiscounts[%d] = %v, want %v", goodsID, childDiscounts[goodsID], expected)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDiscountService_CalculateDiscount_ReductionRounding(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := NewDiscountService(db)
+	ctx := context.Background()
+
+	activityRows := sqlmock.NewRows([]string{"id", "template_id", "type", "select_type"}).
+		AddRow(500, 5, 1, 2)
+	mock.ExpectQuery("SELECT (.+) FROM activity (.+)").
+		WithArgs(500, 1).
+		WillReturnRows(activityRows)
+
+	// 满300减100
+	detailRows := sqlmock.NewRows([]string{"threshold_amount", "discount_value"}).
+		AddRow(300.0, 100.0)
+	mock.ExpectQuery("SELECT (.+) FROM `activity_detail` (.+)").
+		WithArgs(500).
+		WillReturnRows(detailRows)
+
+	templateGoodsRows := sqlmock.NewRows([]string{"goods_id", "classify_id"}).
+		AddRow(1, nil).
+		AddRow(2, nil).
+		AddRow(3, nil)
+	mock.ExpectQuery("SELECT (.+) FROM `activity_template_goods` (.+)").
+		WithArgs(5).
+		WillReturnRows(templateGoodsRows)
+
+	goodsList := []GoodsForDiscount{
+		{GoodsID: 1, Price: m(100.00)},
+		{GoodsID: 2, Price: m(100.00)},
+		{GoodsID: 3, Price: m(100.00)},
+	}
+
+	totalDiscount, childDiscounts, err := s.CalculateDiscount(ctx, goodsList, []int{500})
+	if err != nil {
+		t.Fatalf("CalculateDiscount() error = %v", err)
+	}
+
+	if totalDiscount != m(100.00) {
+		t.Errorf("CalculateDiscount() totalDiscount = %v, want 100.00", totalDiscount)
+	}
+
+	// 三等分无法整除，最后一个商品承担尾差
+	expectedChildDiscounts := map[int]float64{
+		1: 33.33,
+		2: 33.33,
+		3: 33.34,
+	}
+
+	sum := money.Zero
+	for goodsID, expected := range expectedChildDiscounts {
+		if childDiscounts[goodsID] != m(expected) {
+			t.Errorf("CalculateDiscount() childDiscounts[%d] = %v, want %v", goodsID, childDiscounts[goodsID], expected)
+		}
+		sum = sum.Add(childDiscounts[goodsID])
+	}
+	if sum != totalDiscount {
+		t.Errorf("sum of childDiscounts = %v, want %v", sum, totalDiscount)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDiscountService_CalculateDiscount_ReductionNotMeetThreshold(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := NewDiscountService(db)
+	ctx := context.Background()
+
+	activityRows := sqlmock.NewRows([]string{"id", "template_id", "type", "select_type"}).
+		AddRow(700, 7, 1, 2)
+	mock.ExpectQuery("SELECT (.+) FROM activity (.+)").
+		WithArgs(700, 1).
+		WillReturnRows(activityRows)
+
+	// 满1000减100
+	detailRows := sqlmock.NewRows([]string{"threshold_amount", "discount_value"}).
+		AddRow(1000.0, 100.0)
+	mock.ExpectQuery("SELECT (.+) FROM `activity_detail` (.+)").
+		WithArgs(700).
+		WillReturnRows(detailRows)
+
+	templateGoodsRows := sqlmock.NewRows([]string{"goods_id", "classify_id"}).
+		AddRow(1, nil).
+		AddRow(2, nil)
+	mock.ExpectQuery("SELECT (.+) FROM `activity_template_goods` (.+)").
+		WithArgs(7).
+		WillReturnRows(templateGoodsRows)
+
+	// 参与商品总价300，不满足1000门槛
+	goodsList := []GoodsForDiscount{
+		{GoodsID: 1, Price: m(100.00)},
+		{GoodsID: 2, Price: m(200.00)},
 	}
 
-	totalDiscount, _, err := s.CalculateDiscount(ctx, goodsList, []int{999})
+	totalDiscount, childDiscounts, err := s.CalculateDiscount(ctx, goodsList, []int{700})
 	if err != nil {
 		t.Fatalf("CalculateDiscount() error = %v", err)
 	}
 
-	// 活动不存在时，忽略错误继续处理，返回零优惠
-	if totalDiscount != 0 {
+	if totalDiscount != m(0) {
 		t.Errorf("CalculateDiscount() totalDiscount = %v, want 0", totalDiscount)
 	}
 
+	for goodsID, discount := range childDiscounts {
+		if !discount.IsZero() {
+			t.Errorf("CalculateDiscount() childDiscounts[%d] = %v, want 0", goodsID, discount)
+		}
+	}
+
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unfulfilled expectations: %v", err)
 	}
 }
+
+func TestDiscountService_CalculateGifts(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := NewDiscountService(db)
+	ctx := context.Background()
+
+	// 模拟查询活动信息
+	activityRows := sqlmock.NewRows([]string{"id", "template_id", "type", "select_type"}).
+		AddRow(800, 8, 3, 2) // type=3(满赠), select_type=2(按商品)
+	mock.ExpectQuery("SELECT (.+) FROM activity (.+)").
+		WithArgs(800, 1).
+		WillReturnRows(activityRows)
+
+	// 模拟查询活动赠品规则：满500赠商品99两件，满200赠商品98一件
+	detailRows := sqlmock.NewRows([]string{"threshold_amount", "discount_value", "gift_goods_id"}).
+		AddRow(500.0, 2.0, 99).
+		AddRow(200.0, 1.0, 98)
+	mock.ExpectQuery("SELECT (.+) FROM `activity_detail` (.+)").
+		WithArgs(800).
+		WillReturnRows(detailRows)
+
+	templateGoodsRows := sqlmock.NewRows([]string{"goods_id", "classify_id"}).
+		AddRow(1, nil).
+		AddRow(2, nil)
+	mock.ExpectQuery("SELECT (.+) FROM `activity_template_goods` (.+)").
+		WithArgs(8).
+		WillReturnRows(templateGoodsRows)
+	mock.ExpectQuery("SELECT price FROM `goods` WHERE id = \\?").
+		WithArgs(98).
+		WillReturnRows(sqlmock.NewRows([]string{"price"}).AddRow(50.0))
+
+	goodsList := []GoodsForDiscount{
+		{GoodsID: 1, Price: m(100.00)},
+		{GoodsID: 2, Price: m(200.00)},
+	}
+
+	gifts, err := s.CalculateGifts(ctx, goodsList, []int{800})
+	if err != nil {
+		t.Fatalf("CalculateGifts() error = %v", err)
+	}
+
+	// 参与商品总价300，满足满200档位
+	if len(gifts) != 1 {
+		t.Fatalf("CalculateGifts() len(gifts) = %v, want 1", len(gifts))
+	}
+	want := GiftGoods{ActivityID: 800, GoodsID: 98, Quantity: 1}
+	if gifts[0] != want {
+		t.Errorf("CalculateGifts() gifts[0] = %+v, want %+v", gifts[0], want)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDiscountService_CalculateGifts_DuplicateActivity(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := NewDiscountService(db)
+	ctx := context.Background()
+
+	// 重复提交的活动只查询和赠送一次
+	mock.ExpectQuery("SELECT (.+) FROM activity (.+)").
+		WithArgs(800, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "template_id", "type", "select_type"}).
+			AddRow(800, 8, 3, 2))
+	mock.ExpectQuery("SELECT (.+) FROM `activity_detail` (.+)").
+		WithArgs(800).
+		WillReturnRows(sqlmock.NewRows([]string{"threshold_amount", "discount_value", "gift_goods_id"}).
+			AddRow(200.0, 1.0, 98))
+	mock.ExpectQuery("SELECT (.+) FROM `activity_template_goods` (.+)").
+		WithArgs(8).
+		WillReturnRows(sqlmock.NewRows([]string{"goods_id", "classify_id"}).AddRow(1, nil))
+	mock.ExpectQuery("SELECT price FROM `goods` WHERE id = \\?").
+		WithArgs(98).
+		WillReturnRows(sqlmock.NewRows([]string{"price"}).AddRow(50.0))
+
+	goodsList := []GoodsForDiscount{
+		{GoodsID: 1, Price: m(300.00)},
+	}
+
+	gifts, err := s.CalculateGifts(ctx, goodsList, []int{800, 800})
+	if err != nil {
+		t.Fatalf("CalculateGifts() error = %v", err)
+	}
+	if len(gifts) != 1 {
+		t.Fatalf("CalculateGifts() len(gifts) = %v, want 1", len(gifts))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDiscountService_CalculateGifts_NonGiftActivity(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := NewDiscountService(db)
+	ctx := context.Background()
+
+	// 满折活动不产生赠品
+	activityRows := sqlmock.NewRows([]string{"id", "template_id", "type", "select_type"}).
+		AddRow(900, 9, 2, 2)
+	mock.ExpectQuery("SELECT (.+) FROM activity (.+)").
+		WithArgs(900, 1).
+		WillReturnRows(activityRows)
+	mock.ExpectQuery("SELECT (.+) FROM `activity_detail` (.+)").
+		WithArgs(900).
+		WillReturnRows(sqlmock.NewRows([]string{"threshold_amount", "discount_value"}))
+
+	goodsList := []GoodsForDiscount{
+		{GoodsID: 1, Price: m(100.00)},
+	}
+
+	gifts, err := s.CalculateGifts(ctx, goodsList, []int{900})
+	if err != nil {
+		t.Fatalf("CalculateGifts() error = %v", err)
+	}
+	if len(gifts) != 0 {
+		t.Errorf("CalculateGifts() len(gifts) = %v, want 0", len(gifts))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDiscountService_CalculateDiscountDetail_Stacking(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := NewDiscountService(db)
+	ctx := context.Background()
+
+	// 活动500：可叠加满减，满100减10，商品1和2参与
+	mock.ExpectQuery("SELECT (.+) FROM activity (.+)").
+		WithArgs(500, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "template_id", "type", "select_type", "stack_mode", "stack_group"}).
+			AddRow(500, 5, 1, 2, activityTemplateEntity.StackModeStackable, ""))
+	mock.ExpectQuery("SELECT (.+) FROM `activity_detail` (.+)").
+		WithArgs(500).
+		WillReturnRows(sqlmock.NewRows([]string{"threshold_amount", "discount_value"}).AddRow(100.0, 10.0))
+	mock.ExpectQuery("SELECT (.+) FROM `activity_template_goods` (.+)").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"goods_id", "classify_id"}).AddRow(1, nil).AddRow(2, nil))
+
+	// 活动600：互斥满减，满100减5，仅商品1参与（优惠小于叠加组合，不生效）
+	mock.ExpectQuery("SELECT (.+) FROM activity (.+)").
+		WithArgs(600, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "template_id", "type", "select_type", "stack_mode", "stack_group"}).
+			AddRow(600, 6, 1, 2, activityTemplateEntity.StackModeExclusive, ""))
+	mock.ExpectQuery("SELECT (.+) FROM `activity_detail` (.+)").
+		WithArgs(600).
+		WillReturnRows(sqlmock.NewRows([]string{"threshold_amount", "discount_value"}).AddRow(100.0, 5.0))
+	mock.ExpectQuery("SELECT (.+) FROM `activity_template_goods` (.+)").
+		WithArgs(6).
+		WillReturnRows(sqlmock.NewRows([]string{"goods_id", "classify_id"}).AddRow(1, nil))
+
+	goodsList := []GoodsForDiscount{
+		{GoodsID: 1, Price: m(100.00)},
+		{GoodsID: 2, Price: m(300.00)},
+	}
+
+	result, err := s.CalculateDiscountDetail(ctx, goodsList, []int{500, 600, 500})
+	if err != nil {
+		t.Fatalf("CalculateDiscountDetail() error = %v", err)
+	}
+
+	if result.TotalDiscount != m(10.00) {
+		t.Errorf("CalculateDiscountDetail() TotalDiscount = %v, want 10.00", result.TotalDiscount)
+	}
+	if len(result.AppliedActivityIDs) != 1 || result.AppliedActivityIDs[0] != 500 {
+		t.Errorf("CalculateDiscountDetail() AppliedActivityIDs = %v, want [500]", result.AppliedActivityIDs)
+	}
+
+	expectedBreakdown := []ActivityDiscount{
+		{ActivityID: 500, GoodsID: 1, Amount: m(2.50)},
+		{ActivityID: 500, GoodsID: 2, Amount: m(7.50)},
+	}
+	if len(result.Breakdown) != len(expectedBreakdown) {
+		t.Fatalf("CalculateDiscountDetail() Breakdown = %v, want %v", result.Breakdown, expectedBreakdown)
+	}
+	for i, expected := range expectedBreakdown {
+		if result.Breakdown[i] != expected {
+			t.Errorf("CalculateDiscountDetail() Breakdown[%d] = %v, want %v", i, result.Breakdown[i], expected)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDiscountService_GiftStacking(t *testing.T) {
+	// 活动500：可叠加满减，满100减10；活动800：互斥满赠，满100赠商品98一件（价值50）
+	// 互斥满赠的价值大于叠加组合，单独生效：只赠送赠品，不再减免
+	expectActivities := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery("SELECT (.+) FROM activity (.+)").
+			WithArgs(500, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "template_id", "type", "select_type", "stack_mode", "stack_group"}).
+				AddRow(500, 5, ActivityTypeReduction, 2, activityTemplateEntity.StackModeStackable, ""))
+		mock.ExpectQuery("SELECT (.+) FROM `activity_detail` (.+)").
+			WithArgs(500).
+			WillReturnRows(sqlmock.NewRows([]string{"threshold_amount", "discount_value"}).AddRow(100.0, 10.0))
+		mock.ExpectQuery("SELECT (.+) FROM `activity_template_goods` (.+)").
+			WithArgs(5).
+			WillReturnRows(sqlmock.NewRows([]string{"goods_id", "classify_id"}).AddRow(1, nil))
+
+		mock.ExpectQuery("SELECT (.+) FROM activity (.+)").
+			WithArgs(800, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "template_id", "type", "select_type", "stack_mode", "stack_group"}).
+				AddRow(800, 8, ActivityTypeGift, 2, activityTemplateEntity.StackModeExclusive, ""))
+		mock.ExpectQuery("SELECT (.+) FROM `activity_detail` (.+)").
+			WithArgs(800).
+			WillReturnRows(sqlmock.NewRows([]string{"threshold_amount", "discount_value", "gift_goods_id"}).AddRow(100.0, 1.0, 98))
+		mock.ExpectQuery("SELECT (.+) FROM `activity_template_goods` (.+)").
+			WithArgs(8).
+			WillReturnRows(sqlmock.NewRows([]string{"goods_id", "classify_id"}).AddRow(1, nil))
+		mock.ExpectQuery("SELECT price FROM `goods` WHERE id = \\?").
+			WithArgs(98).
+			WillReturnRows(sqlmock.NewRows([]string{"price"}).AddRow(50.0))
+	}
+
+	goodsList := []GoodsForDiscount{
+		{GoodsID: 1, Price: m(200.00)},
+	}
+	ctx := context.Background()
+
+	t.Run("赠品", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		expectActivities(mock)
+
+		gifts, err := NewDiscountService(db).CalculateGifts(ctx, goodsList, []int{500, 800})
+		if err != nil {
+			t.Fatalf("CalculateGifts() error = %v", err)
+		}
+		want := GiftGoods{ActivityID: 800, GoodsID: 98, Quantity: 1}
+		if len(gifts) != 1 || gifts[0] != want {
+			t.Errorf("CalculateGifts() = %+v, want [%+v]", gifts, want)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("优惠", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		expectActivities(mock)
+
+		result, err := NewDiscountService(db).CalculateDiscountDetail(ctx, goodsList, []int{500, 800})
+		if err != nil {
+			t.Fatalf("CalculateDiscountDetail() error = %v", err)
+		}
+		if !result.TotalDiscount.IsZero() {
+			t.Errorf("CalculateDiscountDetail() TotalDiscount = %v, want 0", result.TotalDiscount)
+		}
+		if len(result.AppliedActivityIDs) != 1 || result.AppliedActivityIDs[0] != 800 {
+			t.Errorf("CalculateDiscountDetail() AppliedActivityIDs = %v, want [800]", result.AppliedActivityIDs)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+}
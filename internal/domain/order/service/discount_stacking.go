@@ -0,0 +1,71 @@
+package service
+
+import (
+	"sort"
+
+	activityTemplateEntity "charonoms/internal/domain/activity_template/entity"
+	"charonoms/internal/domain/shared/money"
+)
+
+// activityResult 单个活动独立计算出的优惠
+type activityResult struct {
+	ActivityID     int
+	StackMode      int // 叠加方式，取值见 activityTemplateEntity.StackMode*
+	StackGroup     string
+	Discount       money.Money         // 优惠金额；满赠活动为赠品价值，仅用于叠加比较
+	ChildDiscounts map[int]money.Money // 商品ID -> 优惠金额
+	Gift           *GiftGoods          // 满赠活动的赠品
+}
+
+// resolveStacking 按叠加规则从候选活动中确定最终生效的活动
+//
+// 规则：
+//  1. 同组择优的活动在各自叠加组内只保留优惠最大的一个（金额相同取活动ID较小者）
+//  2. 可叠加活动与各组胜出活动组成叠加组合，优惠金额相加
+//  3. 互斥活动只能单独生效；优惠最大的互斥活动严格大于叠加组合时取该互斥活动，否则取叠加组合
+//
+// 返回结果按活动ID升序排列，保证同样的输入得到同样的结果
+func resolveStacking(candidates []activityResult) []activityResult {
+	sorted := make([]activityResult, len(candidates))
+	copy(sorted, candidates)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ActivityID < sorted[j].ActivityID
+	})
+
+	var combo []activityResult
+	comboTotal := money.Zero
+	var bestExclusive *activityResult
+	groupWinners := make(map[string]int) // 叠加组 -> combo 中的下标
+
+	for i := range sorted {
+		r := sorted[i]
+		switch r.StackMode {
+		case activityTemplateEntity.StackModeExclusive:
+			if bestExclusive == nil || r.Discount.GreaterThan(bestExclusive.Discount) {
+				bestExclusive = &sorted[i]
+			}
+		case activityTemplateEntity.StackModeBestOf:
+			idx, ok := groupWinners[r.StackGroup]
+			if !ok {
+				groupWinners[r.StackGroup] = len(combo)
+				combo = append(combo, r)
+				comboTotal = comboTotal.Add(r.Discount)
+			} else if r.Discount.GreaterThan(combo[idx].Discount) {
+				comboTotal = comboTotal.Add(r.Discount.Sub(combo[idx].Discount))
+				combo[idx] = r
+			}
+		default:
+			combo = append(combo, r)
+			comboTotal = comboTotal.Add(r.Discount)
+		}
+	}
+
+	if bestExclusive != nil && bestExclusive.Discount.GreaterThan(comboTotal) {
+		return []activityResult{*bestExclusive}
+	}
+
+	sort.Slice(combo, func(i, j int) bool {
+		return combo[i].ActivityID < combo[j].ActivityID
+	})
+	return combo
+}
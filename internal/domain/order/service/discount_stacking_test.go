@@ -0,0 +1,89 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	activityTemplateEntity "charonoms/internal/domain/activity_template/entity"
+	"charonoms/internal/domain/shared/money"
+)
+
+func TestResolveStacking(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []activityResult
+		wantIDs    []int
+	}{
+		{
+			name:       "无候选活动",
+			candidates: nil,
+			wantIDs:    []int{},
+		},
+		{
+			name: "可叠加活动全部生效",
+			candidates: []activityResult{
+				{ActivityID: 3, StackMode: activityTemplateEntity.StackModeStackable, Discount: money.FromCents(1000)},
+				{ActivityID: 1, StackMode: activityTemplateEntity.StackModeStackable, Discount: money.FromCents(2000)},
+			},
+			wantIDs: []int{1, 3},
+		},
+		{
+			name: "同组择优只取优惠最大的活动",
+			candidates: []activityResult{
+				{ActivityID: 1, StackMode: activityTemplateEntity.StackModeBestOf, StackGroup: "A", Discount: money.FromCents(1000)},
+				{ActivityID: 2, StackMode: activityTemplateEntity.StackModeBestOf, StackGroup: "A", Discount: money.FromCents(3000)},
+				{ActivityID: 3, StackMode: activityTemplateEntity.StackModeBestOf, StackGroup: "B", Discount: money.FromCents(500)},
+				{ActivityID: 4, StackMode: activityTemplateEntity.StackModeStackable, Discount: money.FromCents(100)},
+			},
+			wantIDs: []int{2, 3, 4},
+		},
+		{
+			name: "同组择优金额相同取活动ID较小者",
+			candidates: []activityResult{
+				{ActivityID: 8, StackMode: activityTemplateEntity.StackModeBestOf, StackGroup: "A", Discount: money.FromCents(1000)},
+				{ActivityID: 5, StackMode: activityTemplateEntity.StackModeBestOf, StackGroup: "A", Discount: money.FromCents(1000)},
+			},
+			wantIDs: []int{5},
+		},
+		{
+			name: "互斥活动优惠更大时单独生效",
+			candidates: []activityResult{
+				{ActivityID: 1, StackMode: activityTemplateEntity.StackModeStackable, Discount: money.FromCents(1000)},
+				{ActivityID: 2, StackMode: activityTemplateEntity.StackModeStackable, Discount: money.FromCents(1500)},
+				{ActivityID: 3, StackMode: activityTemplateEntity.StackModeExclusive, Discount: money.FromCents(3000)},
+			},
+			wantIDs: []int{3},
+		},
+		{
+			name: "叠加组合不小于互斥活动时取叠加组合",
+			candidates: []activityResult{
+				{ActivityID: 1, StackMode: activityTemplateEntity.StackModeStackable, Discount: money.FromCents(1000)},
+				{ActivityID: 2, StackMode: activityTemplateEntity.StackModeStackable, Discount: money.FromCents(2000)},
+				{ActivityID: 3, StackMode: activityTemplateEntity.StackModeExclusive, Discount: money.FromCents(3000)},
+			},
+			wantIDs: []int{1, 2},
+		},
+		{
+			name: "多个互斥活动只取优惠最大的一个",
+			candidates: []activityResult{
+				{ActivityID: 1, StackMode: activityTemplateEntity.StackModeExclusive, Discount: money.FromCents(3000)},
+				{ActivityID: 2, StackMode: activityTemplateEntity.StackModeExclusive, Discount: money.FromCents(5000)},
+				{ActivityID: 3, StackMode: activityTemplateEntity.StackModeExclusive, Discount: money.FromCents(5000)},
+			},
+			wantIDs: []int{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveStacking(tt.candidates)
+			gotIDs := make([]int, 0, len(got))
+			for _, r := range got {
+				gotIDs = append(gotIDs, r.ActivityID)
+			}
+			if !reflect.DeepEqual(gotIDs, tt.wantIDs) {
+				t.Errorf("resolveStacking() = %v, want %v", gotIDs, tt.wantIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,636 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"charonoms/internal/domain/order/entity"
+	"charonoms/internal/domain/shared/money"
+)
+
+// ErrAmendmentPending 订单已有审批中的变更单
+var ErrAmendmentPending = errors.New("订单有审批中的变更单")
+
+// OrderAmendmentService 订单变更领域服务
+// 负责保存变更单，并在审批完成后将变更应用到订单、子订单、活动优惠、分期计划和分账明细
+type OrderAmendmentService struct {
+	db            *gorm.DB
+	statusService *OrderStatusService
+	setting       entity.AllocationSetting
+}
+
+// NewOrderAmendmentService 创建订单变更领域服务，setting 为系统默认的收款分账策略，变更后重新分账时使用
+func NewOrderAmendmentService(db *gorm.DB, setting entity.AllocationSetting) *OrderAmendmentService {
+	return &OrderAmendmentService{
+		db:            db,
+		statusService: NewOrderStatusService(db),
+		setting:       setting,
+	}
+}
+
+// HasPending 判断订单是否有审批中的变更单（审批流已撤销的不计）
+func (s *OrderAmendmentService) HasPending(ctx context.Context, orderID int) (bool, error) {
+	return hasPendingAmendment(s.db.WithContext(ctx), orderID)
+}
+
+func hasPendingAmendment(db *gorm.DB, orderID int) (bool, error) {
+	var count int64
+	err := db.Table("order_amendment oa").
+		Joins("LEFT JOIN approval_flow_management afm ON afm.id = oa.approval_flow_id").
+		Where("oa.order_id = ? AND oa.status = ?", orderID, entity.AmendmentStatusPending).
+		Where("afm.id IS NULL OR afm.status = 0").
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("查询审批中的变更单失败: %w", err)
+	}
+	return count > 0, nil
+}
+
+// CreateTx 在调用方事务中保存变更单及明细
+// 锁定订单行后再检查是否已有审批中的变更单，避免并发重复发起
+func (s *OrderAmendmentService) CreateTx(tx *gorm.DB, amendment *entity.OrderAmendment) error {
+	if err := lockOrder(tx, amendment.OrderID); err != nil {
+		return err
+	}
+
+	pending, err := hasPendingAmendment(tx, amendment.OrderID)
+	if err != nil {
+		return err
+	}
+	if pending {
+		return ErrAmendmentPending
+	}
+
+	amendment.Status = entity.AmendmentStatusPending
+	if amendment.SubmitTime.IsZero() {
+		amendment.SubmitTime = time.Now()
+	}
+	if err := tx.Create(amendment).Error; err != nil {
+		return fmt.Errorf("创建变更单失败: %w", err)
+	}
+
+	for _, item := range amendment.Items {
+		item.AmendmentID = amendment.ID
+	}
+	if len(amendment.Items) > 0 {
+		if err := tx.Create(&amendment.Items).Error; err != nil {
+			return fmt.Errorf("创建变更明细失败: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// BindApprovalFlowTx 在调用方事务中关联变更单与审批流
+func (s *OrderAmendmentService) BindApprovalFlowTx(tx *gorm.DB, amendmentID int, flowID int) error {
+	err := tx.Model(&entity.OrderAmendment{}).
+		Where("id = ?", amendmentID).
+		Update("approval_flow_id", flowID).Error
+	if err != nil {
+		return fmt.Errorf("关联审批流失败: %w", err)
+	}
+	return nil
+}
+
+// GetByID 查询变更单（含明细），不存在时返回 nil
+func (s *OrderAmendmentService) GetByID(ctx context.Context, id int) (*entity.OrderAmendment, error) {
+	return getAmendment(s.db.WithContext(ctx), "id = ?", id)
+}
+
+// ListByOrderID 查询订单的变更单（含明细，按提交先后）
+func (s *OrderAmendmentService) ListByOrderID(ctx context.Context, orderID int) ([]*entity.OrderAmendment, error) {
+	db := s.db.WithContext(ctx)
+
+	var amendments []*entity.OrderAmendment
+	if err := db.Where("order_id = ?", orderID).Order("id ASC").Find(&amendments).Error; err != nil {
+		return nil, fmt.Errorf("查询变更单失败: %w", err)
+	}
+	for _, a := range amendments {
+		if err := loadAmendmentItems(db, a); err != nil {
+			return nil, err
+		}
+	}
+	return amendments, nil
+}
+
+// OnApproved 变更审批通过（审批完成钩子，在审批引擎的事务中执行）：将变更应用到订单
+func (s *OrderAmendmentService) OnApproved(tx *gorm.DB, amendmentID int) error {
+	amendment, err := getPendingAmendment(tx, amendmentID)
+	if err != nil {
+		return err
+	}
+	return s.applyTx(tx, amendment)
+}
+
+// OnRejected 变更审批驳回：关闭变更单，订单保持不变
+func (s *OrderAmendmentService) OnRejected(tx *gorm.DB, amendmentID int) error {
+	amendment, err := getPendingAmendment(tx, amendmentID)
+	if err != nil {
+		return err
+	}
+	return s.closeTx(tx, amendment, entity.AmendmentStatusRejected)
+}
+
+// OnCancelled 变更审批被发起人撤销：关闭变更单，订单保持不变
+func (s *OrderAmendmentService) OnCancelled(tx *gorm.DB, amendmentID int) error {
+	amendment, err := getPendingAmendment(tx, amendmentID)
+	if err != nil {
+		return err
+	}
+	return s.closeTx(tx, amendment, entity.AmendmentStatusCancelled)
+}
+
+// getPendingAmendment 查询审批中的变更单，不存在或已处理时返回错误
+func getPendingAmendment(tx *gorm.DB, amendmentID int) (*entity.OrderAmendment, error) {
+	amendment, err := getAmendment(tx, "id = ?", amendmentID)
+	if err != nil {
+		return nil, err
+	}
+	if amendment == nil {
+		return nil, fmt.Errorf("变更单 %d 不存在", amendmentID)
+	}
+	if !amendment.IsPending() {
+		return nil, fmt.Errorf("变更单 %d 已处理", amendment.ID)
+	}
+	return amendment, nil
+}
+
+// applyTx 将变更应用到订单
+func (s *OrderAmendmentService) applyTx(tx *gorm.DB, amendment *entity.OrderAmendment) error {
+	orderID := amendment.OrderID
+
+	// 1. 锁定订单并校验仍可变更
+	if err := lockOrder(tx, orderID); err != nil {
+		return err
+	}
+	var order entity.Order
+	if err := tx.Where("id = ?", orderID).Take(&order).Error; err != nil {
+		return fmt.Errorf("查询订单失败: %w", err)
+	}
+	if !order.CanAmend() {
+		return fmt.Errorf("订单当前状态(%d)不允许变更", order.Status)
+	}
+
+	// 2. 审批期间可能有新收款，变更后实收金额不能低于已收款金额
+	totalPaid, err := OrderPaidAmount(tx, orderID)
+	if err != nil {
+		return err
+	}
+	if totalPaid.GreaterThan(amendment.AmountReceivedAfter) {
+		return fmt.Errorf("变更后实收金额%s低于已收款金额%s", amendment.AmountReceivedAfter, totalPaid)
+	}
+
+	// 3. 判断原分账能否沿用：移除的子订单已有分账，或保留的子订单分账超过变更后金额时需要冲回重分
+	allocated, err := childNetAllocations(tx, orderID)
+	if err != nil {
+		return err
+	}
+	needRebalance := false
+	for _, item := range amendment.Items {
+		if item.ChildOrderID == nil {
+			continue
+		}
+		current := allocated[*item.ChildOrderID]
+		switch item.Action {
+		case entity.AmendmentActionRemove:
+			if current.IsPositive() {
+				needRebalance = true
+			}
+		case entity.AmendmentActionKeep:
+			if current.GreaterThan(item.AmountReceived) {
+				needRebalance = true
+			}
+		}
+	}
+
+	// 4. 更新子订单：保留的更新金额，移除的作废，新增的创建并回填到变更明细
+	for _, item := range amendment.Items {
+		switch item.Action {
+		case entity.AmendmentActionKeep:
+			err = tx.Model(&entity.ChildOrder{}).
+				Where("id = ? AND parentsid = ?", *item.ChildOrderID, orderID).
+				Updates(map[string]interface{}{
+					"amount_receivable": item.AmountReceivable,
+					"amount_received":   item.AmountReceived,
+					"discount_amount":   item.DiscountAmount,
+				}).Error
+		case entity.AmendmentActionRemove:
+			err = tx.Model(&entity.ChildOrder{}).
+				Where("id = ? AND parentsid = ?", *item.ChildOrderID, orderID).
+				Update("status", entity.ChildOrderStatusCancelled).Error
+		case entity.AmendmentActionAdd:
+			child := &entity.ChildOrder{
+				ParentsID:        orderID,
+				GoodsID:          item.GoodsID,
+				AmountReceivable: item.AmountReceivable,
+				AmountReceived:   item.AmountReceived,
+				DiscountAmount:   item.DiscountAmount,
+				Status:           entity.ChildOrderStatusUnpaid,
+				GiftActivityID:   item.GiftActivityID,
+			}
+			if err = tx.Create(child).Error; err != nil {
+				break
+			}
+			childOrderID := child.ID
+			item.ChildOrderID = &childOrderID
+			err = tx.Model(&entity.OrderAmendmentItem{}).
+				Where("id = ?", item.ID).
+				Update("childorder_id", childOrderID).Error
+		}
+		if err != nil {
+			return fmt.Errorf("更新子订单失败: %w", err)
+		}
+	}
+
+	// 5. 更新订单金额（设置分期时预计付款时间取第一期到期日）
+	orderUpdates := map[string]interface{}{
+		"amount_receivable": amendment.AmountReceivableAfter,
+		"amount_received":   amendment.AmountReceivedAfter,
+		"discount_amount":   amendment.DiscountAmountAfter,
+	}
+	if len(amendment.Instalments) > 0 {
+		orderUpdates["expected_payment_time"] = amendment.Instalments[0].DueDate
+	}
+	if err := tx.Table("orders").Where("id = ?", orderID).Updates(orderUpdates).Error; err != nil {
+		return fmt.Errorf("更新订单金额失败: %w", err)
+	}
+
+	// 6. 重建活动关联、活动优惠明细和分期计划
+	if err := replaceOrderActivities(tx, orderID, amendment.ActivityIDs, amendment.ActivityDiscounts); err != nil {
+		return err
+	}
+	if err := replaceOrderInstalments(tx, orderID, amendment.Instalments); err != nil {
+		return err
+	}
+
+	// 7. 分账明细：能沿用则保留原分账，否则冲回后按收款重新分账
+	if needRebalance {
+		allocator := s.setting.Allocator(order.AllocationStrategy)
+		if err := rebalanceSeparateAccounts(tx, orderID, order.StudentID, allocator); err != nil {
+			return err
+		}
+	}
+
+	// 8. 按净分账刷新子订单状态，按净收款刷新订单状态
+	if err := refreshChildOrderStatuses(tx, orderID); err != nil {
+		return err
+	}
+	err = s.statusService.TransitionTx(tx, StatusChange{
+		OrderID:  orderID,
+		To:       entity.PaymentStatus(totalPaid, amendment.AmountReceivedAfter),
+		Operator: entity.OperatorSystem,
+		Reason:   fmt.Sprintf("订单变更审批通过（变更单 #%d）", amendment.ID),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.closeTx(tx, amendment, entity.AmendmentStatusApproved)
+}
+
+// closeTx 更新变更单为已通过、已驳回或已撤销
+func (s *OrderAmendmentService) closeTx(tx *gorm.DB, amendment *entity.OrderAmendment, status int) error {
+	now := time.Now()
+	err := tx.Model(&entity.OrderAmendment{}).
+		Where("id = ?", amendment.ID).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"process_time": now,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("更新变更单状态失败: %w", err)
+	}
+	amendment.Status = status
+	amendment.ProcessTime = &now
+	return nil
+}
+
+// getAmendment 按条件查询一个变更单（含明细），不存在时返回 nil
+func getAmendment(db *gorm.DB, query string, args ...interface{}) (*entity.OrderAmendment, error) {
+	var amendments []*entity.OrderAmendment
+	if err := db.Where(query, args...).Limit(1).Find(&amendments).Error; err != nil {
+		return nil, fmt.Errorf("查询变更单失败: %w", err)
+	}
+	if len(amendments) == 0 {
+		return nil, nil
+	}
+	if err := loadAmendmentItems(db, amendments[0]); err != nil {
+		return nil, err
+	}
+	return amendments[0], nil
+}
+
+func loadAmendmentItems(db *gorm.DB, amendment *entity.OrderAmendment) error {
+	var items []*entity.OrderAmendmentItem
+	if err := db.Where("amendment_id = ?", amendment.ID).Order("id ASC").Find(&items).Error; err != nil {
+		return fmt.Errorf("查询变更明细失败: %w", err)
+	}
+	amendment.Items = items
+	return nil
+}
+
+// lockOrder 锁定订单行
+func lockOrder(tx *gorm.DB, orderID int) error {
+	var id int
+	err := tx.Table("orders").
+		Select("id").
+		Where("id = ?", orderID).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scan(&id).Error
+	if err != nil {
+		return fmt.Errorf("锁定订单失败: %w", err)
+	}
+	if id == 0 {
+		return fmt.Errorf("订单 %d 不存在", orderID)
+	}
+	return nil
+}
+
+// childNetAllocations 查询订单各子订单的净分账金额（未冲回的售卖类 + 未冲回的退费类）
+func childNetAllocations(tx *gorm.DB, orderID int) (map[int]money.Money, error) {
+	var rows []struct {
+		ChildOrdersID int         `gorm:"column:childorders_id"`
+		NetAllocated  money.Money `gorm:"column:net_allocated"`
+	}
+	err := tx.Raw(`
+		SELECT childorders_id, COALESCE(SUM(separate_amount), 0) AS net_allocated
+		FROM separate_account
+		WHERE orders_id = ?
+			AND type IN (0, 2)
+			AND id NOT IN (
+				SELECT parent_id FROM separate_account
+				WHERE orders_id = ? AND parent_id IS NOT NULL
+			)
+		GROUP BY childorders_id
+	`, orderID, orderID).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询子订单分账失败: %w", err)
+	}
+
+	allocated := make(map[int]money.Money, len(rows))
+	for _, r := range rows {
+		allocated[r.ChildOrdersID] = r.NetAllocated
+	}
+	return allocated, nil
+}
+
+// replaceOrderActivities 重建订单活动关联及活动优惠明细
+// 优惠明细按商品ID关联到该商品的第一个有效的非赠品子订单
+func replaceOrderActivities(tx *gorm.DB, orderID int, activityIDs []int, discounts []*entity.OrderActivityDiscount) error {
+	if err := tx.Table("orders_activity_detail").Where("orders_id = ?", orderID).Delete(nil).Error; err != nil {
+		return fmt.Errorf("删除旧活动优惠明细失败: %w", err)
+	}
+	if err := tx.Table("orders_activity").Where("orders_id = ?", orderID).Delete(nil).Error; err != nil {
+		return fmt.Errorf("删除旧活动关联失败: %w", err)
+	}
+	if len(activityIDs) == 0 {
+		return nil
+	}
+
+	var children []entity.ChildOrder
+	if err := tx.Where("parentsid = ? AND gift_activity_id IS NULL AND status <> ?", orderID, entity.ChildOrderStatusCancelled).
+		Order("id ASC").
+		Find(&children).Error; err != nil {
+		return fmt.Errorf("查询子订单失败: %w", err)
+	}
+	childOrderIDs := make(map[int]int)
+	for _, c := range children {
+		if _, ok := childOrderIDs[c.GoodsID]; !ok {
+			childOrderIDs[c.GoodsID] = c.ID
+		}
+	}
+
+	activityTotals := make(map[int]money.Money)
+	for _, d := range discounts {
+		childOrderID, ok := childOrderIDs[d.GoodsID]
+		if !ok {
+			return fmt.Errorf("活动 %d 的优惠商品 %d 不在订单中", d.ActivityID, d.GoodsID)
+		}
+		activityTotals[d.ActivityID] = activityTotals[d.ActivityID].Add(d.DiscountAmount)
+		if err := tx.Table("orders_activity_detail").Create(map[string]interface{}{
+			"orders_id":       orderID,
+			"activity_id":     d.ActivityID,
+			"childorder_id":   childOrderID,
+			"goods_id":        d.GoodsID,
+			"discount_amount": d.DiscountAmount,
+		}).Error; err != nil {
+			return fmt.Errorf("创建订单活动优惠明细失败: %w", err)
+		}
+	}
+
+	for _, activityID := range activityIDs {
+		if err := tx.Table("orders_activity").Create(map[string]interface{}{
+			"orders_id":       orderID,
+			"activity_id":     activityID,
+			"discount_amount": activityTotals[activityID],
+		}).Error; err != nil {
+			return fmt.Errorf("创建订单活动关联失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// replaceOrderInstalments 重建订单分期计划
+func replaceOrderInstalments(tx *gorm.DB, orderID int, instalments []*entity.OrderInstalment) error {
+	if err := tx.Table("order_instalment").Where("order_id = ?", orderID).Delete(nil).Error; err != nil {
+		return fmt.Errorf("删除旧分期计划失败: %w", err)
+	}
+	for _, inst := range instalments {
+		if err := tx.Table("order_instalment").Create(map[string]interface{}{
+			"order_id": orderID,
+			"seq":      inst.Seq,
+			"due_date": inst.DueDate,
+			"amount":   inst.Amount,
+		}).Error; err != nil {
+			return fmt.Errorf("创建分期计划失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// rebalanceSeparateAccounts 冲回订单所有未冲回的售卖类和退费类分账，再将各收款冲回的净额（收款扣除已退费）按订单的分账策略重新分账
+// 子订单的分账需求为实收金额扣除该子订单已退费金额，收款维度的净分账合计保持不变；净额无法全部分配时返回错误
+// 冲回明细沿用原明细的商品快照，重新分账的明细写入商品当前的名称、品牌和分类
+func rebalanceSeparateAccounts(tx *gorm.DB, orderID int, studentID int, allocator entity.Allocator) error {
+	// 1. 查询未冲回的售卖类和退费类分账明细
+	var originals []struct {
+		ID             int         `gorm:"column:id"`
+		Type           int         `gorm:"column:type"`
+		UID            int         `gorm:"column:uid"`
+		ChildOrdersID  int         `gorm:"column:childorders_id"`
+		PaymentID      int         `gorm:"column:payment_id"`
+		PaymentType    int         `gorm:"column:payment_type"`
+		GoodsID        int         `gorm:"column:goods_id"`
+		GoodsName      string      `gorm:"column:goods_name"`
+		BrandID        *int        `gorm:"column:brand_id"`
+		BrandName      *string     `gorm:"column:brand_name"`
+		ClassifyID     *int        `gorm:"column:classify_id"`
+		ClassifyName   *string     `gorm:"column:classify_name"`
+		SeparateAmount money.Money `gorm:"column:separate_amount"`
+	}
+	if err := tx.Raw(`
+		SELECT id, type, uid, childorders_id, payment_id, payment_type,
+			goods_id, goods_name, brand_id, brand_name, classify_id, classify_name, separate_amount
+		FROM separate_account
+		WHERE orders_id = ? AND type IN (0, 2)
+			AND id NOT IN (
+				SELECT parent_id FROM separate_account
+				WHERE orders_id = ? AND parent_id IS NOT NULL
+			)
+		ORDER BY payment_type ASC, payment_id ASC, id ASC
+	`, orderID, orderID).Scan(&originals).Error; err != nil {
+		return fmt.Errorf("查询分账明细失败: %w", err)
+	}
+
+	// 2. 生成冲回记录（type=1，金额取反，记录parent_id），按收款汇总冲回的净额，按子订单汇总已退费金额
+	type paymentKey struct {
+		PaymentID   int
+		PaymentType int
+	}
+	var paymentOrder []paymentKey
+	reversed := make(map[paymentKey]money.Money)
+	refunded := make(map[int]money.Money)
+	for _, sep := range originals {
+		if err := tx.Table("separate_account").Create(map[string]interface{}{
+			"uid":             sep.UID,
+			"orders_id":       orderID,
+			"childorders_id":  sep.ChildOrdersID,
+			"payment_id":      sep.PaymentID,
+			"payment_type":    sep.PaymentType,
+			"goods_id":        sep.GoodsID,
+			"goods_name":      sep.GoodsName,
+			"brand_id":        sep.BrandID,
+			"brand_name":      sep.BrandName,
+			"classify_id":     sep.ClassifyID,
+			"classify_name":   sep.ClassifyName,
+			"separate_amount": sep.SeparateAmount.Neg(),
+			"type":            1,
+			"parent_id":       sep.ID,
+		}).Error; err != nil {
+			return fmt.Errorf("生成冲回分账失败: %w", err)
+		}
+
+		key := paymentKey{PaymentID: sep.PaymentID, PaymentType: sep.PaymentType}
+		if _, ok := reversed[key]; !ok {
+			paymentOrder = append(paymentOrder, key)
+		}
+		reversed[key] = reversed[key].Add(sep.SeparateAmount)
+		if sep.Type == 2 {
+			refunded[sep.ChildOrdersID] = refunded[sep.ChildOrdersID].Sub(sep.SeparateAmount)
+		}
+	}
+
+	// 3. 查询变更后的有效子订单
+	var children []struct {
+		ID             int         `gorm:"column:id"`
+		GoodsID        int         `gorm:"column:goodsid"`
+		GoodsName      string      `gorm:"column:goods_name"`
+		BrandID        *int        `gorm:"column:brand_id"`
+		BrandName      *string     `gorm:"column:brand_name"`
+		ClassifyID     *int        `gorm:"column:classify_id"`
+		ClassifyName   *string     `gorm:"column:classify_name"`
+		AmountReceived money.Money `gorm:"column:amount_received"`
+	}
+	if err := tx.Raw(`
+		SELECT co.id, co.goodsid, g.name AS goods_name,
+			g.brandid AS brand_id, b.name AS brand_name, g.classifyid AS classify_id, c.name AS classify_name,
+			co.amount_received
+		FROM childorders co
+		LEFT JOIN goods g ON co.goodsid = g.id
+		LEFT JOIN brand b ON g.brandid = b.id
+		LEFT JOIN classify c ON g.classifyid = c.id
+		WHERE co.parentsid = ? AND co.gift_activity_id IS NULL AND co.status <> ?
+		ORDER BY co.id ASC
+	`, orderID, entity.ChildOrderStatusCancelled).Scan(&children).Error; err != nil {
+		return fmt.Errorf("查询子订单失败: %w", err)
+	}
+
+	// 4. 按收款顺序，将每笔收款冲回的净额按分账策略分配到仍需分账的子订单（需求已扣除退费）
+	need := make(map[int]money.Money, len(children))
+	for _, c := range children {
+		need[c.ID] = money.Max(c.AmountReceived.Sub(refunded[c.ID]), money.Zero)
+	}
+	for _, key := range paymentOrder {
+		if !reversed[key].IsPositive() {
+			continue
+		}
+		targets := make([]entity.AllocationTarget, len(children))
+		for i, c := range children {
+			targets[i] = entity.AllocationTarget{
+				ChildOrder: &entity.ChildOrder{ID: c.ID, GoodsID: c.GoodsID, AmountReceived: c.AmountReceived},
+				ClassifyID: c.ClassifyID,
+				Needed:     need[c.ID],
+			}
+		}
+		shares := allocator.Allocate(reversed[key], targets)
+
+		allocated := money.Zero
+		for i, c := range children {
+			amount := shares[i]
+			if !amount.IsPositive() {
+				continue
+			}
+			allocated = allocated.Add(amount)
+			if err := tx.Table("separate_account").Create(map[string]interface{}{
+				"uid":             studentID,
+				"orders_id":       orderID,
+				"childorders_id":  c.ID,
+				"payment_id":      key.PaymentID,
+				"payment_type":    key.PaymentType,
+				"goods_id":        c.GoodsID,
+				"goods_name":      c.GoodsName,
+				"brand_id":        c.BrandID,
+				"brand_name":      c.BrandName,
+				"classify_id":     c.ClassifyID,
+				"classify_name":   c.ClassifyName,
+				"separate_amount": amount,
+				"type":            0,
+			}).Error; err != nil {
+				return fmt.Errorf("重新生成分账失败: %w", err)
+			}
+
+			need[c.ID] = need[c.ID].Sub(amount)
+		}
+		if !allocated.Equal(reversed[key]) {
+			return fmt.Errorf("收款 %d 重新分账失败：净额%s仅能分配%s", key.PaymentID, reversed[key], allocated)
+		}
+	}
+
+	return nil
+}
+
+// refreshChildOrderStatuses 按净分账金额刷新订单有效的非赠品子订单状态
+func refreshChildOrderStatuses(tx *gorm.DB, orderID int) error {
+	var children []entity.ChildOrder
+	if err := tx.Where("parentsid = ? AND gift_activity_id IS NULL AND status <> ?", orderID, entity.ChildOrderStatusCancelled).
+		Find(&children).Error; err != nil {
+		return fmt.Errorf("查询子订单失败: %w", err)
+	}
+
+	allocated, err := childNetAllocations(tx, orderID)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range children {
+		net := allocated[c.ID]
+		status := entity.ChildOrderStatusPaid
+		if !net.IsPositive() {
+			status = entity.ChildOrderStatusUnpaid
+		} else if net.LessThan(c.AmountReceived) {
+			status = entity.ChildOrderStatusPartialPaid
+		}
+		if status == c.Status {
+			continue
+		}
+		if err := tx.Model(&entity.ChildOrder{}).Where("id = ?", c.ID).Update("status", status).Error; err != nil {
+			return fmt.Errorf("更新子订单状态失败: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+
+	"charonoms/internal/domain/order/entity"
+)
+
+func TestOrderAmendmentService_HasPending(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := NewOrderAmendmentService(db, entity.AllocationSetting{})
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM order_amendment oa LEFT JOIN approval_flow_management afm .*oa.order_id = \\? AND oa.status = \\?").
+		WithArgs(1, entity.AmendmentStatusPending).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	pending, err := s.HasPending(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("HasPending() error = %v", err)
+	}
+	if !pending {
+		t.Error("HasPending() = false, want true")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestOrderAmendmentService_OnRejected(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := NewOrderAmendmentService(db, entity.AllocationSetting{})
+
+	// 驳回只关闭变更单，不改动订单
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `order_amendment` WHERE id = \\?").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "approval_flow_id", "status"}).
+			AddRow(3, 1, 7, entity.AmendmentStatusPending))
+	mock.ExpectQuery("SELECT \\* FROM `order_amendment_item` WHERE amendment_id = \\?").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "amendment_id", "action", "goods_id"}).
+			AddRow(1, 3, entity.AmendmentActionAdd, 5))
+	mock.ExpectExec("UPDATE `order_amendment` SET `process_time`=\\?,`status`=\\? WHERE id = \\?").
+		WithArgs(sqlmock.AnyArg(), entity.AmendmentStatusRejected, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return s.OnRejected(tx, 3)
+	})
+	if err != nil {
+		t.Fatalf("OnRejected() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestOrderAmendmentService_OnApproved_NotFound(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := NewOrderAmendmentService(db, entity.AllocationSetting{})
+
+	// 审批流关联的变更单不存在时返回错误，审批引擎回滚
+	mock.ExpectQuery("SELECT \\* FROM `order_amendment` WHERE id = \\?").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if err := s.OnApproved(db, 3); err == nil {
+		t.Fatal("OnApproved() error = nil, want error for missing amendment")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestOrderAmendmentService_OnApproved_AlreadyProcessed(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := NewOrderAmendmentService(db, entity.AllocationSetting{})
+
+	mock.ExpectQuery("SELECT \\* FROM `order_amendment` WHERE id = \\?").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "approval_flow_id", "status"}).
+			AddRow(3, 1, 7, entity.AmendmentStatusApproved))
+	mock.ExpectQuery("SELECT \\* FROM `order_amendment_item` WHERE amendment_id = \\?").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if err := s.OnApproved(db, 3); err == nil {
+		t.Fatal("OnApproved() error = nil, want error for processed amendment")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestRebalanceSeparateAccounts_CopiesSnapshots 冲回明细沿用原明细的商品快照，重新分账明细写入商品当前的品牌和分类
+func TestRebalanceSeparateAccounts_CopiesSnapshots(t *testing.T) {
+	db, mock := setupMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, type, uid, childorders_id, payment_id, payment_type,\\s+goods_id, goods_name, brand_id, brand_name, classify_id, classify_name, separate_amount\\s+FROM separate_account\\s+WHERE orders_id = \\? AND type IN \\(0, 2\\)").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "uid", "childorders_id", "payment_id", "payment_type", "goods_id", "goods_name", "brand_id", "brand_name", "classify_id", "classify_name", "separate_amount"}).
+			AddRow(50, 0, 3, 11, 7, 0, 5, "数学课(旧)", 2, "学而思", 4, "数学", 1000.00))
+	mock.ExpectExec("INSERT INTO `separate_account` \\(`brand_id`,`brand_name`,`childorders_id`,`classify_id`,`classify_name`,`goods_id`,`goods_name`,`orders_id`,`parent_id`,`payment_id`,`payment_type`,`separate_amount`,`type`,`uid`\\)").
+		WithArgs(2, "学而思", 11, 4, "数学", 5, "数学课(旧)", 1, 50, 7, 0, "-1000.00", 1, 3).
+		WillReturnResult(sqlmock.NewResult(51, 1))
+
+	mock.ExpectQuery("SELECT co.id, co.goodsid, g.name AS goods_name,\\s+g.brandid AS brand_id, b.name AS brand_name, g.classifyid AS classify_id, c.name AS classify_name").
+		WithArgs(1, entity.ChildOrderStatusCancelled).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "goodsid", "goods_name", "brand_id", "brand_name", "classify_id", "classify_name", "amount_received"}).
+			AddRow(11, 5, "数学课", 2, "学而思", 4, "数学", 600.00).
+			AddRow(12, 6, "物理课", 2, "学而思", nil, nil, 400.00))
+	mock.ExpectExec("INSERT INTO `separate_account` \\(`brand_id`,`brand_name`,`childorders_id`,`classify_id`,`classify_name`,`goods_id`,`goods_name`,`orders_id`,`payment_id`,`payment_type`,`separate_amount`,`type`,`uid`\\)").
+		WithArgs(2, "学而思", 11, 4, "数学", 5, "数学课", 1, 7, 0, "600.00", 0, 3).
+		WillReturnResult(sqlmock.NewResult(52, 1))
+	mock.ExpectExec("INSERT INTO `separate_account` \\(`brand_id`,`brand_name`,`childorders_id`,`classify_id`,`classify_name`,`goods_id`,`goods_name`,`orders_id`,`payment_id`,`payment_type`,`separate_amount`,`type`,`uid`\\)").
+		WithArgs(2, "学而思", 12, nil, nil, 6, "物理课", 1, 7, 0, "400.00", 0, 3).
+		WillReturnResult(sqlmock.NewResult(53, 1))
+	mock.ExpectCommit()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return rebalanceSeparateAccounts(tx, 1, 3, entity.SequentialAllocator{})
+	})
+	if err != nil {
+		t.Fatalf("rebalanceSeparateAccounts() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestRebalanceSeparateAccounts_UsesAllocator 重新分账按订单的分账策略分配：按比例分摊时每笔收款冲回的金额按子订单实收比例分配
+func TestRebalanceSeparateAccounts_UsesAllocator(t *testing.T) {
+	db, mock := setupMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, type, uid, childorders_id, payment_id, payment_type,").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "uid", "childorders_id", "payment_id", "payment_type", "goods_id", "goods_name", "separate_amount"}).
+			AddRow(50, 0, 3, 11, 7, 0, 5, "数学课", 600.00).
+			AddRow(51, 0, 3, 12, 8, 1, 6, "物理课", 400.00))
+	mock.ExpectExec("INSERT INTO `separate_account`").
+		WillReturnResult(sqlmock.NewResult(60, 1))
+	mock.ExpectExec("INSERT INTO `separate_account`").
+		WillReturnResult(sqlmock.NewResult(61, 1))
+
+	mock.ExpectQuery("SELECT co.id, co.goodsid, g.name AS goods_name,").
+		WithArgs(1, entity.ChildOrderStatusCancelled).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "goodsid", "goods_name", "amount_received"}).
+			AddRow(11, 5, "数学课", 750.00).
+			AddRow(12, 6, "物理课", 250.00))
+
+	// 收款7冲回600：按 750:250 分为 450 和 150
+	expectResplit := func(childID, goodsID int, goodsName string, paymentID, paymentType int, amount string) {
+		mock.ExpectExec("INSERT INTO `separate_account` \\(`brand_id`,`brand_name`,`childorders_id`,`classify_id`,`classify_name`,`goods_id`,`goods_name`,`orders_id`,`payment_id`,`payment_type`,`separate_amount`,`type`,`uid`\\)").
+			WithArgs(nil, nil, childID, nil, nil, goodsID, goodsName, 1, paymentID, paymentType, amount, 0, 3).
+			WillReturnResult(sqlmock.NewResult(70, 1))
+	}
+	expectResplit(11, 5, "数学课", 7, 0, "450.00")
+	expectResplit(12, 6, "物理课", 7, 0, "150.00")
+	// 淘宝收款8冲回400：按同样比例分为 300 和 100，两个子订单正好分满
+	expectResplit(11, 5, "数学课", 8, 1, "300.00")
+	expectResplit(12, 6, "物理课", 8, 1, "100.00")
+	mock.ExpectCommit()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return rebalanceSeparateAccounts(tx, 1, 3, entity.AllocationSetting{}.Allocator(entity.AllocationStrategyProRata))
+	})
+	if err != nil {
+		t.Fatalf("rebalanceSeparateAccounts() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestRebalanceSeparateAccounts_AfterPartialRefund 部分退费后变更订单：退费类分账一并冲回，收款按扣除退费后的净额重新分账，
+// 已退费子订单的需求扣除退费金额，不会被多分
+func TestRebalanceSeparateAccounts_AfterPartialRefund(t *testing.T) {
+	db, mock := setupMockDB(t)
+
+	// 收款7共1000：数学课600、物理课400，数学课已退费200
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, type, uid, childorders_id, payment_id, payment_type,").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "uid", "childorders_id", "payment_id", "payment_type", "goods_id", "goods_name", "separate_amount"}).
+			AddRow(50, 0, 3, 11, 7, 0, 5, "数学课", 600.00).
+			AddRow(51, 0, 3, 12, 7, 0, 6, "物理课", 400.00).
+			AddRow(52, 2, 3, 11, 7, 0, 5, "数学课", -200.00))
+	expectReversal := func(childID, goodsID int, goodsName string, parentID int, amount string) {
+		mock.ExpectExec("INSERT INTO `separate_account` \\(`brand_id`,`brand_name`,`childorders_id`,`classify_id`,`classify_name`,`goods_id`,`goods_name`,`orders_id`,`parent_id`,`payment_id`,`payment_type`,`separate_amount`,`type`,`uid`\\)").
+			WithArgs(nil, nil, childID, nil, nil, goodsID, goodsName, 1, parentID, 7, 0, amount, 1, 3).
+			WillReturnResult(sqlmock.NewResult(60, 1))
+	}
+	expectReversal(11, 5, "数学课", 50, "-600.00")
+	expectReversal(12, 6, "物理课", 51, "-400.00")
+	expectReversal(11, 5, "数学课", 52, "200.00")
+
+	// 变更：移除物理课，新增英语课500
+	mock.ExpectQuery("SELECT co.id, co.goodsid, g.name AS goods_name,").
+		WithArgs(1, entity.ChildOrderStatusCancelled).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "goodsid", "goods_name", "amount_received"}).
+			AddRow(11, 5, "数学课", 600.00).
+			AddRow(13, 7, "英语课", 500.00))
+
+	// 净额800：数学课只需600-200=400，其余400分给英语课
+	expectResplit := func(childID, goodsID int, goodsName string, amount string) {
+		mock.ExpectExec("INSERT INTO `separate_account` \\(`brand_id`,`brand_name`,`childorders_id`,`classify_id`,`classify_name`,`goods_id`,`goods_name`,`orders_id`,`payment_id`,`payment_type`,`separate_amount`,`type`,`uid`\\)").
+			WithArgs(nil, nil, childID, nil, nil, goodsID, goodsName, 1, 7, 0, amount, 0, 3).
+			WillReturnResult(sqlmock.NewResult(70, 1))
+	}
+	expectResplit(11, 5, "数学课", "400.00")
+	expectResplit(13, 7, "英语课", "400.00")
+	mock.ExpectCommit()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return rebalanceSeparateAccounts(tx, 1, 3, entity.SequentialAllocator{})
+	})
+	if err != nil {
+		t.Fatalf("rebalanceSeparateAccounts() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestRebalanceSeparateAccounts_UnallocatedRemainder 收款净额超过子订单需求时返回错误，不静默丢弃未分配的金额
+func TestRebalanceSeparateAccounts_UnallocatedRemainder(t *testing.T) {
+	db, mock := setupMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, type, uid, childorders_id, payment_id, payment_type,").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "uid", "childorders_id", "payment_id", "payment_type", "goods_id", "goods_name", "separate_amount"}).
+			AddRow(50, 0, 3, 11, 7, 0, 5, "数学课", 1000.00))
+	mock.ExpectExec("INSERT INTO `separate_account`").
+		WillReturnResult(sqlmock.NewResult(60, 1))
+	mock.ExpectQuery("SELECT co.id, co.goodsid, g.name AS goods_name,").
+		WithArgs(1, entity.ChildOrderStatusCancelled).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "goodsid", "goods_name", "amount_received"}).
+			AddRow(11, 5, "数学课", 600.00))
+	mock.ExpectExec("INSERT INTO `separate_account`").
+		WillReturnResult(sqlmock.NewResult(61, 1))
+	mock.ExpectRollback()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return rebalanceSeparateAccounts(tx, 1, 3, entity.SequentialAllocator{})
+	})
+	if err == nil {
+		t.Fatal("rebalanceSeparateAccounts() error = nil, want error for unallocated remainder")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
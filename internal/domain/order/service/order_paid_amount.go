@@ -0,0 +1,28 @@
+package service
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"charonoms/internal/domain/shared/money"
+)
+
+// OrderPaidAmount 查询订单已收款总额：常规收款（未核验、已支付）+ 淘宝收款（已认领、已到账）
+// 订单支付状态、分期匹配和金额校验都按这一口径计算；tx 为调用方的事务，读取事务内已写入的收款
+func OrderPaidAmount(tx *gorm.DB, orderID int) (money.Money, error) {
+	var regularPaid, taobaoPaid money.Money
+	if err := tx.Table("payment_collection").
+		Select("COALESCE(SUM(payment_amount), 0)").
+		Where("order_id = ? AND status IN (10, 20)", orderID).
+		Scan(&regularPaid).Error; err != nil {
+		return money.Zero, fmt.Errorf("查询常规收款失败: %w", err)
+	}
+	if err := tx.Table("taobao_payment").
+		Select("COALESCE(SUM(payment_amount), 0)").
+		Where("order_id = ? AND status IN (20, 30)", orderID).
+		Scan(&taobaoPaid).Error; err != nil {
+		return money.Zero, fmt.Errorf("查询淘宝收款失败: %w", err)
+	}
+	return regularPaid.Add(taobaoPaid), nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"charonoms/internal/domain/shared/money"
+)
+
+// TestOrderPaidAmount 已收款总额为常规收款与淘宝收款之和
+func TestOrderPaidAmount(t *testing.T) {
+	db, mock := setupMockDB(t)
+
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(payment_amount\\), 0\\) FROM `payment_collection` WHERE order_id = \\? AND status IN \\(10, 20\\)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(600.00))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(payment_amount\\), 0\\) FROM `taobao_payment` WHERE order_id = \\? AND status IN \\(20, 30\\)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(150.50))
+
+	got, err := OrderPaidAmount(db, 1)
+	if err != nil {
+		t.Fatalf("OrderPaidAmount() error = %v", err)
+	}
+	if want := money.FromFloat(750.50); !got.Equal(want) {
+		t.Errorf("OrderPaidAmount() = %s, want %s", got, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
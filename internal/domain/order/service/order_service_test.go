@@ -2,22 +2,29 @@ package service
 
 import (
 	"testing"
+
+	"charonoms/internal/domain/shared/money"
 )
 
+// m 由元创建金额，简化测试数据书写
+func m(yuan float64) money.Money {
+	return money.FromFloat(yuan)
+}
+
 func TestOrderService_CalculateOrderAmounts(t *testing.T) {
 	s := NewOrderService()
 
 	tests := []struct {
-		name                    string
-		goodsList               []GoodsItem
-		discountAmount          float64
-		wantAmountReceivable    float64
-		wantAmountReceived      float64
+		name                 string
+		goodsList            []GoodsItem
+		discountAmount       float64
+		wantAmountReceivable float64
+		wantAmountReceived   float64
 	}{
 		{
 			name: "单个商品无优惠",
 			goodsList: []GoodsItem{
-				{GoodsID: 1, TotalPrice: 100.00, Price: 100.00},
+				{GoodsID: 1, TotalPrice: m(100.00), Price: m(100.00)},
 			},
 			discountAmount:       0,
 			wantAmountReceivable: 100.00,
@@ -26,7 +33,7 @@ func TestOrderService_CalculateOrderAmounts(t *testing.T) {
 		{
 			name: "单个商品有优惠",
 			goodsList: []GoodsItem{
-				{GoodsID: 1, TotalPrice: 100.00, Price: 100.00},
+				{GoodsID: 1, TotalPrice: m(100.00), Price: m(100.00)},
 			},
 			discountAmount:       10.00,
 			wantAmountReceivable: 100.00,
@@ -35,9 +42,9 @@ func TestOrderService_CalculateOrderAmounts(t *testing.T) {
 		{
 			name: "多个商品无优惠",
 			goodsList: []GoodsItem{
-				{GoodsID: 1, TotalPrice: 100.00, Price: 100.00},
-				{GoodsID: 2, TotalPrice: 200.00, Price: 200.00},
-				{GoodsID: 3, TotalPrice: 300.00, Price: 300.00},
+				{GoodsID: 1, TotalPrice: m(100.00), Price: m(100.00)},
+				{GoodsID: 2, TotalPrice: m(200.00), Price: m(200.00)},
+				{GoodsID: 3, TotalPrice: m(300.00), Price: m(300.00)},
 			},
 			discountAmount:       0,
 			wantAmountReceivable: 600.00,
@@ -46,9 +53,9 @@ func TestOrderService_CalculateOrderAmounts(t *testing.T) {
 		{
 			name: "多个商品有优惠",
 			goodsList: []GoodsItem{
-				{GoodsID: 1, TotalPrice: 100.00, Price: 100.00},
-				{GoodsID: 2, TotalPrice: 200.00, Price: 200.00},
-				{GoodsID: 3, TotalPrice: 300.00, Price: 300.00},
+				{GoodsID: 1, TotalPrice: m(100.00), Price: m(100.00)},
+				{GoodsID: 2, TotalPrice: m(200.00), Price: m(200.00)},
+				{GoodsID: 3, TotalPrice: m(300.00), Price: m(300.00)},
 			},
 			discountAmount:       50.00,
 			wantAmountReceivable: 600.00,
@@ -57,8 +64,8 @@ func TestOrderService_CalculateOrderAmounts(t *testing.T) {
 		{
 			name: "组合商品：TotalPrice与Price不同",
 			goodsList: []GoodsItem{
-				{GoodsID: 1, TotalPrice: 150.00, Price: 100.00}, // 组合商品
-				{GoodsID: 2, TotalPrice: 200.00, Price: 200.00},
+				{GoodsID: 1, TotalPrice: m(150.00), Price: m(100.00)}, // 组合商品
+				{GoodsID: 2, TotalPrice: m(200.00), Price: m(200.00)},
 			},
 			discountAmount:       20.00,
 			wantAmountReceivable: 350.00,
@@ -74,8 +81,8 @@ func TestOrderService_CalculateOrderAmounts(t *testing.T) {
 		{
 			name: "精度测试：需要四舍五入",
 			goodsList: []GoodsItem{
-				{GoodsID: 1, TotalPrice: 33.333, Price: 33.333},
-				{GoodsID: 2, TotalPrice: 66.666, Price: 66.666},
+				{GoodsID: 1, TotalPrice: m(33.333), Price: m(33.333)},
+				{GoodsID: 2, TotalPrice: m(66.666), Price: m(66.666)},
 			},
 			discountAmount:       9.999,
 			wantAmountReceivable: 100.00, // 33.333 + 66.666 = 99.999 -> 100.00
@@ -84,7 +91,7 @@ func TestOrderService_CalculateOrderAmounts(t *testing.T) {
 		{
 			name: "全额优惠",
 			goodsList: []GoodsItem{
-				{GoodsID: 1, TotalPrice: 100.00, Price: 100.00},
+				{GoodsID: 1, TotalPrice: m(100.00), Price: m(100.00)},
 			},
 			discountAmount:       100.00,
 			wantAmountReceivable: 100.00,
@@ -94,11 +101,11 @@ func TestOrderService_CalculateOrderAmounts(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotAmountReceivable, gotAmountReceived := s.CalculateOrderAmounts(tt.goodsList, tt.discountAmount)
-			if gotAmountReceivable != tt.wantAmountReceivable {
+			gotAmountReceivable, gotAmountReceived := s.CalculateOrderAmounts(tt.goodsList, m(tt.discountAmount))
+			if gotAmountReceivable != m(tt.wantAmountReceivable) {
 				t.Errorf("CalculateOrderAmounts() amountReceivable = %v, want %v", gotAmountReceivable, tt.wantAmountReceivable)
 			}
-			if gotAmountReceived != tt.wantAmountReceived {
+			if gotAmountReceived != m(tt.wantAmountReceived) {
 				t.Errorf("CalculateOrderAmounts() amountReceived = %v, want %v", gotAmountReceived, tt.wantAmountReceived)
 			}
 		})
@@ -117,7 +124,7 @@ func TestOrderService_AllocateChildDiscounts(t *testing.T) {
 		{
 			name: "单个商品分摊",
 			goodsList: []GoodsItem{
-				{GoodsID: 1, TotalPrice: 100.00, Price: 100.00},
+				{GoodsID: 1, TotalPrice: m(100.00), Price: m(100.00)},
 			},
 			childDiscounts: map[int]float64{
 				1: 10.00,
@@ -129,9 +136,9 @@ func TestOrderService_AllocateChildDiscounts(t *testing.T) {
 		{
 			name: "多个商品分摊",
 			goodsList: []GoodsItem{
-				{GoodsID: 1, TotalPrice: 100.00, Price: 100.00},
-				{GoodsID: 2, TotalPrice: 200.00, Price: 200.00},
-				{GoodsID: 3, TotalPrice: 300.00, Price: 300.00},
+				{GoodsID: 1, TotalPrice: m(100.00), Price: m(100.00)},
+				{GoodsID: 2, TotalPrice: m(200.00), Price: m(200.00)},
+				{GoodsID: 3, TotalPrice: m(300.00), Price: m(300.00)},
 			},
 			childDiscounts: map[int]float64{
 				1: 5.00,
@@ -147,8 +154,8 @@ func TestOrderService_AllocateChildDiscounts(t *testing.T) {
 		{
 			name: "部分商品无优惠",
 			goodsList: []GoodsItem{
-				{GoodsID: 1, TotalPrice: 100.00, Price: 100.00},
-				{GoodsID: 2, TotalPrice: 200.00, Price: 200.00},
+				{GoodsID: 1, TotalPrice: m(100.00), Price: m(100.00)},
+				{GoodsID: 2, TotalPrice: m(200.00), Price: m(200.00)},
 			},
 			childDiscounts: map[int]float64{
 				1: 10.00,
@@ -162,7 +169,7 @@ func TestOrderService_AllocateChildDiscounts(t *testing.T) {
 		{
 			name: "精度四舍五入",
 			goodsList: []GoodsItem{
-				{GoodsID: 1, TotalPrice: 100.00, Price: 100.00},
+				{GoodsID: 1, TotalPrice: m(100.00), Price: m(100.00)},
 			},
 			childDiscounts: map[int]float64{
 				1: 10.555, // 应该四舍五入到10.56
@@ -181,7 +188,11 @@ func TestOrderService_AllocateChildDiscounts(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := s.AllocateChildDiscounts(tt.goodsList, tt.childDiscounts)
+			childDiscounts := make(map[int]money.Money, len(tt.childDiscounts))
+			for goodsID, discount := range tt.childDiscounts {
+				childDiscounts[goodsID] = m(discount)
+			}
+			got := s.AllocateChildDiscounts(tt.goodsList, childDiscounts)
 			if len(got) != len(tt.want) {
 				t.Errorf("AllocateChildDiscounts() length = %v, want %v", len(got), len(tt.want))
 				return
@@ -192,7 +203,7 @@ func TestOrderService_AllocateChildDiscounts(t *testing.T) {
 					t.Errorf("AllocateChildDiscounts() missing goodsID %d", goodsID)
 					continue
 				}
-				if gotDiscount != wantDiscount {
+				if gotDiscount != m(wantDiscount) {
 					t.Errorf("AllocateChildDiscounts() goodsID %d = %v, want %v", goodsID, gotDiscount, wantDiscount)
 				}
 			}
@@ -263,41 +274,13 @@ func TestOrderService_CalculateChildAmounts(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotAmountReceivable, gotAmountReceived := s.CalculateChildAmounts(tt.totalPrice, tt.price, tt.discountAmount)
-			if gotAmountReceivable != tt.wantAmountReceivable {
+			gotAmountReceivable, gotAmountReceived := s.CalculateChildAmounts(m(tt.totalPrice), m(tt.price), m(tt.discountAmount))
+			if gotAmountReceivable != m(tt.wantAmountReceivable) {
 				t.Errorf("CalculateChildAmounts() amountReceivable = %v, want %v", gotAmountReceivable, tt.wantAmountReceivable)
 			}
-			if gotAmountReceived != tt.wantAmountReceived {
+			if gotAmountReceived != m(tt.wantAmountReceived) {
 				t.Errorf("CalculateChildAmounts() amountReceived = %v, want %v", gotAmountReceived, tt.wantAmountReceived)
 			}
 		})
 	}
 }
-
-func TestRoundToTwoDecimal(t *testing.T) {
-	tests := []struct {
-		name  string
-		value float64
-		want  float64
-	}{
-		{"整数", 100.00, 100.00},
-		{"一位小数", 100.5, 100.5},
-		{"两位小数", 100.55, 100.55},
-		{"三位小数向上舍入", 100.555, 100.56},
-		{"三位小数向下舍入", 100.554, 100.55},
-		{"多位小数", 100.556789, 100.56},
-		{"负数向上舍入", -100.555, -100.56},
-		{"负数向下舍入", -100.554, -100.55},
-		{"零", 0, 0},
-		{"极小值", 0.001, 0.00},
-		{"极小值舍入", 0.005, 0.01},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := roundToTwoDecimal(tt.value); got != tt.want {
-				t.Errorf("roundToTwoDecimal(%v) = %v, want %v", tt.value, got, tt.want)
-			}
-		})
-	}
-}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"charonoms/internal/domain/order/entity"
+)
+
+// StatusChange 订单状态变更请求
+type StatusChange struct {
+	OrderID          int
+	To               int
+	ChildOrderStatus *int // 非空时同步更新该订单所有子订单的状态
+	Operator         string
+	Reason           string
+}
+
+// OrderStatusService 订单状态流转领域服务
+// 所有订单状态变更都应通过该服务完成：按状态机校验流转并写入 order_status_history
+type OrderStatusService struct {
+	db *gorm.DB
+}
+
+// NewOrderStatusService 创建订单状态流转服务
+func NewOrderStatusService(db *gorm.DB) *OrderStatusService {
+	return &OrderStatusService{db: db}
+}
+
+// Transition 在新事务中流转订单状态
+func (s *OrderStatusService) Transition(ctx context.Context, change StatusChange) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.TransitionTx(tx, change)
+	})
+}
+
+// TransitionTx 在调用方事务中流转订单状态
+// 目标状态与当前状态相同时不更新也不记录历史；不允许的流转返回 *entity.IllegalTransitionError
+func (s *OrderStatusService) TransitionTx(tx *gorm.DB, change StatusChange) error {
+	// 1. 锁定订单行，避免并发流转基于过期状态
+	var current struct {
+		ID     int
+		Status int
+	}
+	err := tx.Table("orders").
+		Select("id, status").
+		Where("id = ?", change.OrderID).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Take(&current).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("订单 %d 不存在", change.OrderID)
+		}
+		return fmt.Errorf("查询订单状态失败: %w", err)
+	}
+
+	// 2. 按状态机校验流转
+	order := &entity.Order{ID: current.ID, Status: current.Status}
+	history, err := order.TransitionTo(change.To, change.Operator, change.Reason)
+	if err != nil {
+		return err
+	}
+
+	// 3. 更新订单和子订单状态
+	if history != nil {
+		if err := tx.Table("orders").Where("id = ?", change.OrderID).Update("status", change.To).Error; err != nil {
+			return fmt.Errorf("更新订单状态失败: %w", err)
+		}
+	}
+	if change.ChildOrderStatus != nil {
+		if err := tx.Table("childorders").Where("parentsid = ?", change.OrderID).Update("status", *change.ChildOrderStatus).Error; err != nil {
+			return fmt.Errorf("更新子订单状态失败: %w", err)
+		}
+	}
+	if history == nil {
+		return nil
+	}
+
+	// 4. 记录状态变更历史
+	err = tx.Table("order_status_history").Create(map[string]interface{}{
+		"order_id":    history.OrderID,
+		"from_status": history.FromStatus,
+		"to_status":   history.ToStatus,
+		"operator":    history.Operator,
+		"reason":      history.Reason,
+		"create_time": history.CreateTime,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("记录订单状态历史失败: %w", err)
+	}
+
+	return nil
+}
+
+// StatusBefore 查询订单最近一次进入指定状态之前的状态
+// 没有对应历史记录时返回 found=false
+func (s *OrderStatusService) StatusBefore(tx *gorm.DB, orderID int, status int) (int, bool, error) {
+	var histories []struct {
+		FromStatus int
+	}
+	err := tx.Table("order_status_history").
+		Select("from_status").
+		Where("order_id = ? AND to_status = ?", orderID, status).
+		Order("id DESC").
+		Limit(1).
+		Find(&histories).Error
+	if err != nil {
+		return 0, false, fmt.Errorf("查询订单状态历史失败: %w", err)
+	}
+	if len(histories) == 0 {
+		return 0, false, nil
+	}
+	return histories[0].FromStatus, true, nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"charonoms/internal/domain/order/entity"
+)
+
+func TestOrderStatusService_Transition(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := NewOrderStatusService(db)
+
+	childStatus := entity.ChildOrderStatusUnpaid
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, status FROM `orders` WHERE id = \\? .*FOR UPDATE").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, entity.OrderStatusDraft))
+	mock.ExpectExec("UPDATE `orders` SET `status`=\\? WHERE id = \\?").
+		WithArgs(entity.OrderStatusUnpaid, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `childorders` SET `status`=\\? WHERE parentsid = \\?").
+		WithArgs(entity.ChildOrderStatusUnpaid, 1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO `order_status_history`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := s.Transition(context.Background(), StatusChange{
+		OrderID:          1,
+		To:               entity.OrderStatusUnpaid,
+		ChildOrderStatus: &childStatus,
+		Operator:         "alice",
+		Reason:           "提交订单",
+	})
+	if err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestOrderStatusService_Transition_Illegal(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := NewOrderStatusService(db)
+
+	// 非法流转不更新订单、不写历史，事务回滚
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, status FROM `orders`").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, entity.OrderStatusCancelled))
+	mock.ExpectRollback()
+
+	err := s.Transition(context.Background(), StatusChange{OrderID: 1, To: entity.OrderStatusPaid})
+	var illegal *entity.IllegalTransitionError
+	if !errors.As(err, &illegal) {
+		t.Fatalf("Transition() error = %v, want *entity.IllegalTransitionError", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestOrderStatusService_Transition_SameStatus(t *testing.T) {
+	db, mock := setupMockDB(t)
+	s := NewOrderStatusService(db)
+
+	// 状态未变化时不更新订单也不写历史
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, status FROM `orders`").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, entity.OrderStatusPaid))
+	mock.ExpectCommit()
+
+	if err := s.Transition(context.Background(), StatusChange{OrderID: 1, To: entity.OrderStatusPaid}); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
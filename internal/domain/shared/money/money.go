@@ -0,0 +1,297 @@
+// Package money 提供以分为单位的定点金额类型，对应数据库 decimal(10,2) 字段
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money 金额（以分为单位存储，避免浮点误差）
+type Money struct {
+	cents int64
+}
+
+// Zero 零金额
+var Zero = Money{}
+
+// ErrInvalidAmount 无效的金额格式
+var ErrInvalidAmount = errors.New("无效的金额格式")
+
+// FromCents 由分创建金额
+func FromCents(cents int64) Money {
+	return Money{cents: cents}
+}
+
+// FromFloat 由元创建金额，按分四舍五入
+func FromFloat(yuan float64) Money {
+	m, err := Parse(strconv.FormatFloat(yuan, 'f', -1, 64))
+	if err != nil {
+		return Money{cents: int64(math.Round(yuan * 100))}
+	}
+	return m
+}
+
+// Parse 解析十进制金额字符串（如 "12.34"），超过两位的小数按分四舍五入
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Zero, ErrInvalidAmount
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return Zero, ErrInvalidAmount
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	for _, c := range intPart + fracPart {
+		if c < '0' || c > '9' {
+			return Zero, ErrInvalidAmount
+		}
+	}
+
+	yuan, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return Zero, ErrInvalidAmount
+	}
+
+	// 取前两位小数，第三位决定是否进位
+	padded := fracPart + "00"
+	fen, _ := strconv.ParseInt(padded[:2], 10, 64)
+	cents := yuan*100 + fen
+	if len(fracPart) > 2 && fracPart[2] >= '5' {
+		cents++
+	}
+
+	if negative {
+		cents = -cents
+	}
+	return Money{cents: cents}, nil
+}
+
+// Cents 返回以分为单位的金额
+func (m Money) Cents() int64 {
+	return m.cents
+}
+
+// Float64 返回以元为单位的浮点金额（仅用于展示和兼容旧接口）
+func (m Money) Float64() float64 {
+	return float64(m.cents) / 100
+}
+
+// String 返回两位小数的字符串（如 "12.30"）
+func (m Money) String() string {
+	cents := m.cents
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// Add 加法
+func (m Money) Add(other Money) Money {
+	return Money{cents: m.cents + other.cents}
+}
+
+// Sub 减法
+func (m Money) Sub(other Money) Money {
+	return Money{cents: m.cents - other.cents}
+}
+
+// Neg 取相反数
+func (m Money) Neg() Money {
+	return Money{cents: -m.cents}
+}
+
+// Mul 乘以整数
+func (m Money) Mul(n int64) Money {
+	return Money{cents: m.cents * n}
+}
+
+// MulRate 乘以比例，结果按分四舍五入
+func (m Money) MulRate(rate float64) Money {
+	return Money{cents: int64(math.Round(float64(m.cents) * rate))}
+}
+
+// Cmp 比较大小：小于返回-1，等于返回0，大于返回1
+func (m Money) Cmp(other Money) int {
+	switch {
+	case m.cents < other.cents:
+		return -1
+	case m.cents > other.cents:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Equal 判断是否相等
+func (m Money) Equal(other Money) bool {
+	return m.cents == other.cents
+}
+
+// LessThan 判断是否小于
+func (m Money) LessThan(other Money) bool {
+	return m.cents < other.cents
+}
+
+// GreaterThan 判断是否大于
+func (m Money) GreaterThan(other Money) bool {
+	return m.cents > other.cents
+}
+
+// IsZero 判断是否为零
+func (m Money) IsZero() bool {
+	return m.cents == 0
+}
+
+// IsPositive 判断是否大于零
+func (m Money) IsPositive() bool {
+	return m.cents > 0
+}
+
+// IsNegative 判断是否小于零
+func (m Money) IsNegative() bool {
+	return m.cents < 0
+}
+
+// Min 返回较小的金额
+func Min(a, b Money) Money {
+	if a.cents < b.cents {
+		return a
+	}
+	return b
+}
+
+// Max 返回较大的金额
+func Max(a, b Money) Money {
+	if a.cents > b.cents {
+		return a
+	}
+	return b
+}
+
+// Sum 求和
+func Sum(amounts ...Money) Money {
+	var total int64
+	for _, a := range amounts {
+		total += a.cents
+	}
+	return Money{cents: total}
+}
+
+// Allocate 按权重将金额分摊为若干份，各份之和严格等于原金额
+// 前 n-1 份按比例四舍五入（不超过剩余金额），最后一份取剩余金额；权重合计为零时全部为零
+func (m Money) Allocate(weights []Money) []Money {
+	shares := make([]Money, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+
+	var totalWeight int64
+	for _, w := range weights {
+		totalWeight += w.cents
+	}
+	if totalWeight == 0 {
+		return shares
+	}
+
+	remaining := m.cents
+	for i, w := range weights {
+		if i == len(weights)-1 {
+			shares[i] = Money{cents: remaining}
+			break
+		}
+		share := int64(math.Round(float64(m.cents) * float64(w.cents) / float64(totalWeight)))
+		if (m.cents >= 0 && share > remaining) || (m.cents < 0 && share < remaining) {
+			share = remaining
+		}
+		shares[i] = Money{cents: share}
+		remaining -= share
+	}
+
+	return shares
+}
+
+// Scan 实现 sql.Scanner 接口，读取 decimal(10,2) 字段
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = Zero
+		return nil
+	case []byte:
+		parsed, err := Parse(string(v))
+		if err != nil {
+			return fmt.Errorf("扫描金额失败: %q: %w", v, err)
+		}
+		*m = parsed
+		return nil
+	case string:
+		parsed, err := Parse(v)
+		if err != nil {
+			return fmt.Errorf("扫描金额失败: %q: %w", v, err)
+		}
+		*m = parsed
+		return nil
+	case float64:
+		*m = FromFloat(v)
+		return nil
+	case float32:
+		*m = FromFloat(float64(v))
+		return nil
+	case int64:
+		*m = Money{cents: v * 100}
+		return nil
+	default:
+		return fmt.Errorf("扫描金额失败: 不支持的类型 %T", value)
+	}
+}
+
+// Value 实现 driver.Valuer 接口，以十进制字符串写入避免精度丢失
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// MarshalJSON 序列化为两位小数的JSON数字
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON 支持JSON数字和字符串
+func (m *Money) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), "\"")
+	if s == "null" || s == "" {
+		*m = Zero
+		return nil
+	}
+	parsed, err := Parse(s)
+	if err != nil {
+		// 兼容科学计数法等格式
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr != nil {
+			return err
+		}
+		parsed = FromFloat(f)
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,152 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFromFloat(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+		want  string
+	}{
+		{"整数", 100.00, "100.00"},
+		{"一位小数", 100.5, "100.50"},
+		{"两位小数", 100.55, "100.55"},
+		{"三位小数向上舍入", 100.555, "100.56"},
+		{"三位小数向下舍入", 100.554, "100.55"},
+		{"多位小数", 100.556789, "100.56"},
+		{"负数向上舍入", -100.555, "-100.56"},
+		{"负数向下舍入", -100.554, "-100.55"},
+		{"零", 0, "0.00"},
+		{"极小值", 0.001, "0.00"},
+		{"极小值舍入", 0.005, "0.01"},
+		{"浮点误差", 0.1 + 0.2, "0.30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromFloat(tt.value).String(); got != tt.want {
+				t.Errorf("FromFloat(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"12.34", 1234, false},
+		{"12", 1200, false},
+		{"12.3", 1230, false},
+		{".5", 50, false},
+		{"-0.01", -1, false},
+		{"+1.005", 101, false},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"1.2.3", 0, true},
+		{"-", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Cents() != tt.want {
+				t.Errorf("Parse(%q) = %d, want %d", tt.input, got.Cents(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_Allocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int64
+		weights []int64
+		want    []int64
+	}{
+		{"按比例分摊", 1000, []int64{10000, 30000}, []int64{250, 750}},
+		{"尾差归最后一项", 10000, []int64{10000, 10000, 10000}, []int64{3333, 3333, 3334}},
+		{"前项进位不超过总额", 2, []int64{100, 100, 100, 100}, []int64{1, 1, 0, 0}},
+		{"权重为零", 100, []int64{0, 0}, []int64{0, 0}},
+		{"空权重", 100, nil, []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weights := make([]Money, 0, len(tt.weights))
+			for _, w := range tt.weights {
+				weights = append(weights, FromCents(w))
+			}
+
+			got := FromCents(tt.total).Allocate(weights)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Allocate() length = %d, want %d", len(got), len(tt.want))
+			}
+
+			var sum int64
+			for i, share := range got {
+				if share.Cents() != tt.want[i] {
+					t.Errorf("Allocate()[%d] = %d, want %d", i, share.Cents(), tt.want[i])
+				}
+				sum += share.Cents()
+			}
+			if len(tt.want) > 0 && tt.weights[0] != 0 && sum != tt.total {
+				t.Errorf("Allocate() sum = %d, want %d", sum, tt.total)
+			}
+		})
+	}
+}
+
+func TestMoney_ScanAndValue(t *testing.T) {
+	var m Money
+	if err := m.Scan([]byte("1234.50")); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if m.Cents() != 123450 {
+		t.Errorf("Scan() = %d, want 123450", m.Cents())
+	}
+
+	v, err := m.Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+	if v != "1234.50" {
+		t.Errorf("Value() = %v, want 1234.50", v)
+	}
+
+	if err := m.Scan(nil); err != nil || !m.IsZero() {
+		t.Errorf("Scan(nil) = %v, %v, want zero", m, err)
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Amount Money `json:"amount"`
+	}{Amount: FromCents(-5)})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(data) != `{"amount":-0.05}` {
+		t.Errorf("Marshal() = %s", data)
+	}
+
+	var got struct {
+		Amount Money `json:"amount"`
+	}
+	for _, input := range []string{`{"amount":12.5}`, `{"amount":"12.50"}`, `{"amount":1.25e1}`} {
+		if err := json.Unmarshal([]byte(input), &got); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", input, err)
+		}
+		if got.Amount.Cents() != 1250 {
+			t.Errorf("Unmarshal(%s) = %d, want 1250", input, got.Amount.Cents())
+		}
+	}
+}
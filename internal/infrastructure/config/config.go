@@ -7,11 +7,15 @@ import (
 
 // Config 全局配置结构
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Logger   LoggerConfig   `mapstructure:"logger"`
-	CORS     CORSConfig     `mapstructure:"cors"`
+	Server         ServerConfig         `mapstructure:"server"`
+	Database       DatabaseConfig       `mapstructure:"database"`
+	JWT            JWTConfig            `mapstructure:"jwt"`
+	Logger         LoggerConfig         `mapstructure:"logger"`
+	CORS           CORSConfig           `mapstructure:"cors"`
+	Scheduler      SchedulerConfig      `mapstructure:"scheduler"`
+	Storage        StorageConfig        `mapstructure:"storage"`
+	PaymentGateway PaymentGatewayConfig `mapstructure:"payment_gateway"`
+	Separate       SeparateConfig       `mapstructure:"separate"`
 }
 
 // ServerConfig 服务器配置
@@ -59,6 +63,35 @@ type CORSConfig struct {
 	AllowCredentials bool     `mapstructure:"allow_credentials"`
 }
 
+// SchedulerConfig 后台任务配置
+type SchedulerConfig struct {
+	Enabled             bool `mapstructure:"enabled"`
+	LeaseTTL            int  `mapstructure:"lease_ttl"`             // 任务租约有效期（秒），需大于任务执行间隔
+	ApprovalSLAInterval int  `mapstructure:"approval_sla_interval"` // 审批超时扫描间隔（秒）
+	ApprovalSLABatch    int  `mapstructure:"approval_sla_batch"`    // 每轮最多处理的超时节点数
+}
+
+// StorageConfig 文件存储配置
+type StorageConfig struct {
+	Type     string `mapstructure:"type"`      // 存储类型：local=本地磁盘
+	LocalDir string `mapstructure:"local_dir"` // 本地存储根目录
+}
+
+// PaymentGatewayConfig 在线支付网关配置
+type PaymentGatewayConfig struct {
+	Type         string `mapstructure:"type"`          // 网关类型：mock=本地模拟网关
+	NotifySecret string `mapstructure:"notify_secret"` // 异步通知签名密钥
+	MockPayURL   string `mapstructure:"mock_pay_url"`  // 模拟网关的支付页面地址
+	// EnableMockPay 开放模拟付款接口（任意登录用户可将在线收款置为已支付），仅限开发和测试环境开启
+	EnableMockPay bool `mapstructure:"enable_mock_pay"`
+}
+
+// SeparateConfig 收款分账配置
+type SeparateConfig struct {
+	AllocationStrategy string `mapstructure:"allocation_strategy"` // 默认分账策略：sequential、pro_rata、classify_priority，订单可单独指定
+	ClassifyPriority   []int  `mapstructure:"classify_priority"`   // classify_priority 策略下的分类ID，靠前的优先分满
+}
+
 var GlobalConfig *Config
 
 // Load 加载配置文件
@@ -71,6 +104,7 @@ func Load(configPath string) (*Config, error) {
 
 	// 绑定特定的环境变量到配置项
 	viper.BindEnv("database.password", "DB_PASSWORD")
+	viper.BindEnv("payment_gateway.notify_secret", "PAYMENT_NOTIFY_SECRET")
 
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
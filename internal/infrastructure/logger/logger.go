@@ -9,8 +9,9 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-var Logger *zap.Logger
-var SugarLogger *zap.SugaredLogger
+// Logger 和 SugarLogger 在 Init 之前为空日志器，单元测试等未初始化日志的场景可直接调用
+var Logger = zap.NewNop()
+var SugarLogger = Logger.Sugar()
 
 // Init 初始化日志系统
 func Init(cfg config.LoggerConfig) error {
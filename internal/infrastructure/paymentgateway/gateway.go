@@ -0,0 +1,28 @@
+package paymentgateway
+
+import (
+	"errors"
+	"fmt"
+
+	"charonoms/internal/domain/financial/payment"
+	"charonoms/internal/infrastructure/config"
+)
+
+// placeholderNotifySecret 配置文件模板中的示例密钥，不能用于运行
+const placeholderNotifySecret = "change-this-notify-secret"
+
+// New 按配置创建在线支付网关，网关类型必须显式配置，通知签名密钥不能为空或沿用模板中的示例值
+func New(cfg config.PaymentGatewayConfig) (payment.PaymentGateway, error) {
+	if cfg.NotifySecret == "" || cfg.NotifySecret == placeholderNotifySecret {
+		return nil, errors.New("支付网关通知签名密钥未配置，请通过 payment_gateway.notify_secret 或环境变量 PAYMENT_NOTIFY_SECRET 设置")
+	}
+
+	switch cfg.Type {
+	case "":
+		return nil, errors.New("支付网关类型未配置")
+	case "mock":
+		return NewMockGateway(cfg.NotifySecret, cfg.MockPayURL)
+	default:
+		return nil, fmt.Errorf("不支持的支付网关类型: %s", cfg.Type)
+	}
+}
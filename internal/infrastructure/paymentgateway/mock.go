@@ -0,0 +1,131 @@
+package paymentgateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"charonoms/internal/domain/financial/payment"
+	"charonoms/internal/domain/shared/money"
+)
+
+// 模拟网关通知中的支付状态
+const (
+	mockTradeSuccess = "SUCCESS"
+	mockTradeClosed  = "CLOSED"
+)
+
+// mockChargeTTL 模拟网关支付链接有效期
+const mockChargeTTL = 30 * time.Minute
+
+// MockGateway 本地模拟支付网关，用于开发和离线测试
+// 异步通知为表单格式，sign 为其余字段按键名排序拼接后的 HMAC-SHA256 签名
+type MockGateway struct {
+	secret []byte
+	payURL string
+}
+
+// NewMockGateway 创建模拟支付网关，secret 为通知签名密钥
+func NewMockGateway(secret string, payURL string) (*MockGateway, error) {
+	if secret == "" {
+		return nil, errors.New("支付网关通知签名密钥未配置")
+	}
+	if payURL == "" {
+		payURL = "http://localhost:5001/api/payment-gateway/mock/pay"
+	}
+	return &MockGateway{secret: []byte(secret), payURL: payURL}, nil
+}
+
+// Name 网关名称
+func (g *MockGateway) Name() string {
+	return "mock"
+}
+
+// CreateCharge 模拟下单：支付链接指向模拟支付接口，二维码内容为同样参数的 mockpay 链接
+func (g *MockGateway) CreateCharge(ctx context.Context, req payment.ChargeRequest) (*payment.Charge, error) {
+	if req.MerchantOrder == "" {
+		return nil, errors.New("商户订单号不能为空")
+	}
+	if !req.Amount.IsPositive() {
+		return nil, errors.New("支付金额必须大于0")
+	}
+
+	query := url.Values{}
+	query.Set("merchant_order", req.MerchantOrder)
+	query.Set("amount", req.Amount.String())
+	return &payment.Charge{
+		PayURL:     g.payURL + "?" + query.Encode(),
+		QRCode:     "mockpay://charge?" + query.Encode(),
+		ExpireTime: time.Now().Add(mockChargeTTL),
+	}, nil
+}
+
+// ParseNotification 校验签名并解析模拟网关的异步通知
+func (g *MockGateway) ParseNotification(header map[string][]string, body []byte) (*payment.Notification, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, payment.ErrInvalidNotifySignature
+	}
+	if !hmac.Equal([]byte(values.Get("sign")), []byte(g.sign(values))) {
+		return nil, payment.ErrInvalidNotifySignature
+	}
+
+	amount, err := money.Parse(values.Get("amount"))
+	if err != nil {
+		return nil, fmt.Errorf("支付通知金额格式错误: %w", err)
+	}
+	paidAt, err := strconv.ParseInt(values.Get("paid_at"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("支付通知时间格式错误: %w", err)
+	}
+	return &payment.Notification{
+		MerchantOrder: values.Get("merchant_order"),
+		TradeNo:       values.Get("trade_no"),
+		Amount:        amount,
+		Paid:          values.Get("trade_status") == mockTradeSuccess,
+		PaidAt:        time.Unix(paidAt, 0),
+	}, nil
+}
+
+// BuildNotification 生成签名后的异步通知报文，模拟支付方完成支付后网关回调
+func (g *MockGateway) BuildNotification(n payment.Notification) []byte {
+	status := mockTradeClosed
+	if n.Paid {
+		status = mockTradeSuccess
+	}
+	values := url.Values{}
+	values.Set("merchant_order", n.MerchantOrder)
+	values.Set("trade_no", n.TradeNo)
+	values.Set("amount", n.Amount.String())
+	values.Set("trade_status", status)
+	values.Set("paid_at", strconv.FormatInt(n.PaidAt.Unix(), 10))
+	values.Set("sign", g.sign(values))
+	return []byte(values.Encode())
+}
+
+// sign 按键名排序拼接除 sign 外的字段（key=value&...）后计算 HMAC-SHA256
+func (g *MockGateway) sign(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		if k != "sign" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+values.Get(k))
+	}
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write([]byte(strings.Join(parts, "&")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -49,8 +49,10 @@ func (r *GormActivityTemplateRepository) Update(ctx context.Context, template *e
 // UpdateWithGoods 更新活动模板及关联商品/分类
 func (r *GormActivityTemplateRepository) UpdateWithGoods(ctx context.Context, template *entity.ActivityTemplate, goods []*entity.ActivityTemplateGoods) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 更新模板
-		if err := tx.Model(template).Updates(template).Error; err != nil {
+		// 更新模板（显式指定字段，避免零值的叠加方式被忽略）
+		if err := tx.Model(template).
+			Select("name", "type", "select_type", "stack_mode", "stack_group").
+			Updates(template).Error; err != nil {
 			return err
 		}
 
@@ -244,14 +244,18 @@ func (r *GormApprovalFlowManagementRepository) GetDetailByID(flowID int, userID
 			nc.approval_flow_management_id,
 			nc.type,
 			nc.sort,
+			nc.add_sign_type,
+			nc.parent_node_case_id,
 			nc.result as node_result,
+			nc.deadline,
+			nc.sla_status,
 			nc.create_time,
 			nc.complete_time,
 			tn.name as node_name
 		`).
 		Joins("LEFT JOIN approval_flow_template_node tn ON nc.node_id = tn.id").
 		Where("nc.approval_flow_management_id = ?", flowID).
-		Order("nc.sort ASC").
+		Order("nc.sort ASC, nc.id ASC").
 		Find(&nodes).Error
 	if err != nil {
 		return nil, err
@@ -314,6 +318,18 @@ func (r *GormApprovalFlowManagementRepository) GetDetailByID(flowID int, userID
 		return nil, err
 	}
 
+	// 4.1 获取操作记录（审批、转交、加签、委托、撤销）及审批附件
+	actions, err := r.flowActions(flowID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4.2 重新提交形成的版本链，按版本号展示每一版的审批记录
+	versions, err := r.flowVersions(flowID)
+	if err != nil {
+		return nil, err
+	}
+
 	// 5. 获取当前用户的审批记录
 	var userApproval map[string]interface{}
 	err = r.db.Table("approval_node_case_user ncu").
@@ -337,11 +353,18 @@ func (r *GormApprovalFlowManagementRepository) GetDetailByID(flowID int, userID
 		userApproval = nil // 如果没有审批记录，设为nil
 	}
 
-	// 6. 如果是退费类型，获取退费信息
+	// 6. 如果关联的是退费订单，获取退费信息
 	var refundOrderInfo map[string]interface{}
-	flowTypeName, _ := flowInfo["approval_flow_type_name"].(string)
-	if flowTypeName == "退费" {
-		// 通过审批流ID关联退费订单
+	var business struct {
+		BusinessType string `gorm:"column:business_type"`
+		BusinessID   int    `gorm:"column:business_id"`
+	}
+	r.db.Table("approval_flow_management").
+		Select("COALESCE(business_type, '') AS business_type, COALESCE(business_id, 0) AS business_id").
+		Where("id = ?", flowID).
+		Scan(&business)
+	if business.BusinessType == entity.BusinessTypeRefund && business.BusinessID > 0 {
+		// 通过审批流关联的业务ID查询退费订单
 		var refundOrder map[string]interface{}
 		err = r.db.Table("refund_order ro").
 			Select(`
@@ -357,7 +380,7 @@ func (r *GormApprovalFlowManagementRepository) GetDetailByID(flowID int, userID
 			`).
 			Joins("LEFT JOIN student s ON ro.student_id = s.id").
 			Joins("LEFT JOIN grade g ON s.grade_id = g.id").
-			Where("ro.id = (SELECT MAX(id) FROM refund_order WHERE create_time >= (SELECT create_time FROM approval_flow_management WHERE id = ?))", flowID).
+			Where("ro.id = ?", business.BusinessID).
 			Limit(1).
 			Find(&refundOrder).Error
 
@@ -451,6 +474,16 @@ func (r *GormApprovalFlowManagementRepository) GetDetailByID(flowID int, userID
 		}
 	}
 
+	// 检查是否可以重新提交（发起人、已驳回或已撤销、尚未重新提交过，目前支持退费审批）
+	canResubmit := false
+	if latest := versions[len(versions)-1]; latest["is_current"] == true {
+		var flow entity.ApprovalFlowManagement
+		if err := r.db.First(&flow, flowID).Error; err == nil {
+			canResubmit = flow.CreateUser == userID && flow.IsResubmittable() &&
+				flow.BusinessType == entity.BusinessTypeRefund
+		}
+	}
+
 	// 组装结果
 	result := map[string]interface{}{
 		"flow_info":         flowInfo,
@@ -458,120 +491,143 @@ func (r *GormApprovalFlowManagementRepository) GetDetailByID(flowID int, userID
 		"all_nodes":         nodes,
 		"refund_order_info": refundOrderInfo,
 		"copy_records":      copyRecords,
+		"actions":           actions,
+		"versions":          versions,
 		"can_approve":       canApprove,
 		"can_cancel":        canCancel,
+		"can_resubmit":      canResubmit,
 	}
 
 	return result, nil
 }
 
-// GetByID 根据ID查询审批流
-func (r *GormApprovalFlowManagementRepository) GetByID(id int) (*entity.ApprovalFlowManagement, error) {
-	var flow entity.ApprovalFlowManagement
-	err := r.db.First(&flow, id).Error
+// flowActions 获取审批流的操作记录（审批、转交、加签、委托、撤销），并附上审批操作的附件
+func (r *GormApprovalFlowManagementRepository) flowActions(flowID int) ([]map[string]interface{}, error) {
+	var actions []map[string]interface{}
+	err := r.db.Table("approval_flow_action fa").
+		Select(`
+			fa.id,
+			fa.approval_node_case_id,
+			fa.action,
+			fa.operator_id,
+			op.username as operator_name,
+			fa.from_user_id,
+			fu.username as from_username,
+			fa.to_user_id,
+			tu.username as to_username,
+			fa.remark,
+			fa.create_time
+		`).
+		Joins("LEFT JOIN useraccount op ON fa.operator_id = op.id").
+		Joins("LEFT JOIN useraccount fu ON fa.from_user_id = fu.id").
+		Joins("LEFT JOIN useraccount tu ON fa.to_user_id = tu.id").
+		Where("fa.approval_flow_management_id = ?", flowID).
+		Order("fa.id ASC").
+		Find(&actions).Error
 	if err != nil {
 		return nil, err
 	}
-	return &flow, nil
-}
-
-// CreateFromTemplate 从模板创建审批流实例（事务：审批流、第一个节点、审批人员）
-func (r *GormApprovalFlowManagementRepository) CreateFromTemplate(templateID int, userID int) (int, error) {
-	var flowID int
-
-	err := r.db.Transaction(func(tx *gorm.DB) error {
-		// 1. 获取模板信息
-		var template entity.ApprovalFlowTemplate
-		if err := tx.First(&template, templateID).Error; err != nil {
-			return fmt.Errorf("获取模板信息失败: %w", err)
-		}
 
-		// 检查模板是否启用
-		if template.Status != 0 {
-			return fmt.Errorf("模板已禁用，无法创建审批流")
-		}
-
-		// 2. 创建审批流实例
-		flow := entity.ApprovalFlowManagement{
-			ApprovalFlowTemplateID: templateID,
-			ApprovalFlowTypeID:     template.ApprovalFlowTypeID,
-			Step:                   0,
-			CreateUser:             userID,
-			Status:                 0, // 待审批
+	// 审批操作的附件，审批意见即操作备注
+	var attachments []entity.ApprovalAttachment
+	err = r.db.Table("approval_attachment a").
+		Select("a.*").
+		Joins("INNER JOIN approval_flow_action fa ON a.approval_flow_action_id = fa.id").
+		Where("fa.approval_flow_management_id = ?", flowID).
+		Order("a.id ASC").
+		Find(&attachments).Error
+	if err != nil {
+		return nil, err
+	}
+	actionAttachments := make(map[int][]map[string]interface{})
+	for _, a := range attachments {
+		actionAttachments[*a.ApprovalFlowActionID] = append(actionAttachments[*a.ApprovalFlowActionID], map[string]interface{}{
+			"id":        a.ID,
+			"file_name": a.FileName,
+			"file_size": a.FileSize,
+			"url":       fmt.Sprintf("/api/approval-attachments/%d", a.ID),
+		})
+	}
+	for i := range actions {
+		var actionID int
+		switch v := actions[i]["id"].(type) {
+		case int:
+			actionID = v
+		case int32:
+			actionID = int(v)
+		case int64:
+			actionID = int(v)
 		}
-		if err := tx.Create(&flow).Error; err != nil {
-			return fmt.Errorf("创建审批流实例失败: %w", err)
+		files := actionAttachments[actionID]
+		if files == nil {
+			files = []map[string]interface{}{}
 		}
-		flowID = flow.ID
+		actions[i]["attachments"] = files
+	}
+	return actions, nil
+}
 
-		// 3. 获取第一个节点（sort最小的节点）
-		var firstNode entity.ApprovalFlowTemplateNode
-		err := tx.Where("template_id = ?", templateID).
-			Order("sort ASC").
-			First(&firstNode).Error
-		if err != nil {
-			return fmt.Errorf("获取第一个节点失败: %w", err)
-		}
+// maxFlowVersions 版本链的最大长度，防止异常数据导致循环
+const maxFlowVersions = 100
 
-		// 4. 创建第一个节点实例
-		nodeCase := entity.ApprovalNodeCase{
-			NodeID:                   firstNode.ID,
-			ApprovalFlowManagementID: flowID,
-			Type:                     firstNode.Type,
-			Sort:                     firstNode.Sort,
-		}
-		if err := tx.Create(&nodeCase).Error; err != nil {
-			return fmt.Errorf("创建节点实例失败: %w", err)
-		}
+// flowVersions 获取审批流所在的版本链（驳回/撤销后重新提交形成），按版本号升序返回每一版的概要和操作记录
+func (r *GormApprovalFlowManagementRepository) flowVersions(flowID int) ([]map[string]interface{}, error) {
+	var current entity.ApprovalFlowManagement
+	if err := r.db.First(&current, flowID).Error; err != nil {
+		return nil, err
+	}
 
-		// 5. 获取第一个节点的审批人员
-		var approvers []entity.ApprovalNodeUserAccount
-		err = tx.Where("node_id = ?", firstNode.ID).Find(&approvers).Error
-		if err != nil {
-			return fmt.Errorf("获取节点审批人员失败: %w", err)
+	// 先沿 previous_flow_id 回溯到第一版，再顺着后续版本找到最新版
+	chain := []entity.ApprovalFlowManagement{current}
+	for len(chain) < maxFlowVersions && chain[0].PreviousFlowID != nil {
+		var previous entity.ApprovalFlowManagement
+		if err := r.db.First(&previous, *chain[0].PreviousFlowID).Error; err != nil {
+			return nil, err
 		}
-
-		if len(approvers) == 0 {
-			return fmt.Errorf("节点未配置审批人员")
+		chain = append([]entity.ApprovalFlowManagement{previous}, chain...)
+	}
+	for len(chain) < maxFlowVersions {
+		var next []entity.ApprovalFlowManagement
+		if err := r.db.Where("previous_flow_id = ?", chain[len(chain)-1].ID).
+			Order("id ASC").Limit(1).Find(&next).Error; err != nil {
+			return nil, err
 		}
-
-		// 6. 创建审批人员记录
-		for _, approver := range approvers {
-			userCase := entity.ApprovalNodeCaseUser{
-				ApprovalNodeCaseID: nodeCase.ID,
-				UserAccountID:      approver.UserAccountID,
-			}
-			if err := tx.Create(&userCase).Error; err != nil {
-				return fmt.Errorf("创建审批人员记录失败: %w", err)
-			}
+		if len(next) == 0 {
+			break
 		}
+		chain = append(chain, next[0])
+	}
 
-		// 7. 获取并创建抄送记录
-		var copyUsers []entity.ApprovalCopyUserAccount
-		err = tx.Where("approval_flow_template_id = ?", templateID).Find(&copyUsers).Error
+	versions := make([]map[string]interface{}, 0, len(chain))
+	for _, flow := range chain {
+		actions, err := r.flowActions(flow.ID)
 		if err != nil {
-			return fmt.Errorf("获取抄送人员失败: %w", err)
-		}
-
-		for _, copyUser := range copyUsers {
-			copyCase := entity.ApprovalCopyUserAccountCase{
-				ApprovalFlowManagementID: flowID,
-				UserAccountID:            copyUser.UserAccountID,
-				CopyInfo:                 "审批流已创建",
-			}
-			if err := tx.Create(&copyCase).Error; err != nil {
-				return fmt.Errorf("创建抄送记录失败: %w", err)
-			}
+			return nil, err
 		}
+		versions = append(versions, map[string]interface{}{
+			"flow_id":          flow.ID,
+			"version":          flow.Version,
+			"previous_flow_id": flow.PreviousFlowID,
+			"status":           flow.Status,
+			"business_type":    flow.BusinessType,
+			"business_id":      flow.BusinessID,
+			"create_time":      flow.CreateTime,
+			"complete_time":    flow.CompleteTime,
+			"is_current":       flow.ID == flowID,
+			"actions":          actions,
+		})
+	}
+	return versions, nil
+}
 
-		return nil
-	})
-
+// GetByID 根据ID查询审批流
+func (r *GormApprovalFlowManagementRepository) GetByID(id int) (*entity.ApprovalFlowManagement, error) {
+	var flow entity.ApprovalFlowManagement
+	err := r.db.First(&flow, id).Error
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-
-	return flowID, nil
+	return &flow, nil
 }
 
 // UpdateStatus 更新审批流状态
@@ -3,7 +3,9 @@ package approval
 import (
 	"charonoms/internal/domain/approval/entity"
 	"charonoms/internal/domain/approval/repository"
+	"encoding/json"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GormApprovalFlowTemplateRepository GORM实现的审批流模板仓储
@@ -98,6 +100,24 @@ func (r *GormApprovalFlowTemplateRepository) GetDetailByID(id int) (map[string]i
 			return nil, err
 		}
 		nodes[i]["approvers"] = approvers
+
+		// 节点条件以JSON存储，解析后返回
+		conditions := entity.NodeConditions{}
+		switch v := nodes[i]["conditions"].(type) {
+		case string:
+			if v != "" {
+				if err := json.Unmarshal([]byte(v), &conditions); err != nil {
+					return nil, err
+				}
+			}
+		case []byte:
+			if len(v) > 0 {
+				if err := json.Unmarshal(v, &conditions); err != nil {
+					return nil, err
+				}
+			}
+		}
+		nodes[i]["conditions"] = conditions
 	}
 
 	// 获取抄送人员
@@ -129,48 +149,179 @@ func (r *GormApprovalFlowTemplateRepository) Create(
 	copyUsers []int,
 ) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
-		// 1. 创建模板
+		// 1. 创建模板（第一版，各版本以第一版ID关联）
+		template.Version = 1
+		if err := tx.Create(template).Error; err != nil {
+			return err
+		}
+		template.OriginTemplateID = template.ID
+		if err := tx.Model(template).Update("origin_template_id", template.ID).Error; err != nil {
+			return err
+		}
+
+		return createTemplateConfig(tx, template, nodes, nodeApprovers, copyUsers)
+	})
+}
+
+// CreateVersion 以 base 为基础创建模板的新版本，已发起的审批流仍使用原版本的节点
+func (r *GormApprovalFlowTemplateRepository) CreateVersion(
+	base *entity.ApprovalFlowTemplate,
+	template *entity.ApprovalFlowTemplate,
+	nodes []entity.ApprovalFlowTemplateNode,
+	nodeApprovers map[int][]int,
+	copyUsers []int,
+) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定基础版本并复查是否为最新版本，防止并发编辑产生同号版本
+		var locked entity.ApprovalFlowTemplate
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, base.ID).Error; err != nil {
+			return err
+		}
+		var latest int
+		if err := tx.Model(&entity.ApprovalFlowTemplate{}).
+			Where("origin_template_id = ?", locked.OriginTemplateID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+		if locked.Version != latest {
+			return entity.ErrTemplateVersionOutdated
+		}
+
+		template.ApprovalFlowTypeID = locked.ApprovalFlowTypeID
+		template.OriginTemplateID = locked.OriginTemplateID
+		template.Version = latest + 1
+		template.Status = locked.Status
 		if err := tx.Create(template).Error; err != nil {
 			return err
 		}
 
-		// 2. 创建节点并设置template_id
-		nodeIDMap := make(map[int]int) // 索引 -> 数据库ID
-		for i := range nodes {
-			nodes[i].TemplateID = template.ID
-			if err := tx.Create(&nodes[i]).Error; err != nil {
+		// 基础版本启用时新版本接替启用，新发起的审批流绑定新版本
+		if template.Status == 0 {
+			if err := tx.Model(&entity.ApprovalFlowTemplate{}).
+				Where("origin_template_id = ? AND id != ?", template.OriginTemplateID, template.ID).
+				Update("status", 1).Error; err != nil {
 				return err
 			}
-			nodeIDMap[i] = nodes[i].ID
 		}
 
-		// 3. 创建节点审批人员
-		for nodeIndex, approverIDs := range nodeApprovers {
-			nodeID := nodeIDMap[nodeIndex]
-			for _, approverID := range approverIDs {
-				approver := entity.ApprovalNodeUserAccount{
-					NodeID:        nodeID,
-					UserAccountID: approverID,
-				}
-				if err := tx.Create(&approver).Error; err != nil {
-					return err
-				}
-			}
+		return createTemplateConfig(tx, template, nodes, nodeApprovers, copyUsers)
+	})
+}
+
+// createTemplateConfig 创建模板版本的节点、节点审批人员和抄送人员
+func createTemplateConfig(
+	tx *gorm.DB,
+	template *entity.ApprovalFlowTemplate,
+	nodes []entity.ApprovalFlowTemplateNode,
+	nodeApprovers map[int][]int,
+	copyUsers []int,
+) error {
+	// 2. 创建节点并设置template_id
+	nodeIDMap := make(map[int]int) // 索引 -> 数据库ID
+	for i := range nodes {
+		nodes[i].TemplateID = template.ID
+		if err := tx.Create(&nodes[i]).Error; err != nil {
+			return err
 		}
+		nodeIDMap[i] = nodes[i].ID
+	}
 
-		// 4. 创建抄送人员
-		for _, userID := range copyUsers {
-			copyUser := entity.ApprovalCopyUserAccount{
-				ApprovalFlowTemplateID: template.ID,
-				UserAccountID:          userID,
+	// 3. 创建节点审批人员
+	for nodeIndex, approverIDs := range nodeApprovers {
+		nodeID := nodeIDMap[nodeIndex]
+		for _, approverID := range approverIDs {
+			approver := entity.ApprovalNodeUserAccount{
+				NodeID:        nodeID,
+				UserAccountID: approverID,
 			}
-			if err := tx.Create(&copyUser).Error; err != nil {
+			if err := tx.Create(&approver).Error; err != nil {
 				return err
 			}
 		}
+	}
 
-		return nil
-	})
+	// 4. 创建抄送人员
+	for _, userID := range copyUsers {
+		copyUser := entity.ApprovalCopyUserAccount{
+			ApprovalFlowTemplateID: template.ID,
+			UserAccountID:          userID,
+		}
+		if err := tx.Create(&copyUser).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListVersions 获取模板各版本（按版本号升序）及每个版本上审批中和全部的审批流数量
+func (r *GormApprovalFlowTemplateRepository) ListVersions(originTemplateID int) ([]map[string]interface{}, error) {
+	var results []map[string]interface{}
+	err := r.db.Raw(`
+		SELECT t.id, t.name, t.version, t.status, t.creator, t.create_time,
+		       COUNT(CASE WHEN fm.status = 0 THEN 1 END) AS running_flow_count,
+		       COUNT(fm.id) AS flow_count
+		FROM approval_flow_template t
+		LEFT JOIN approval_flow_management fm ON fm.approval_flow_template_id = t.id
+		WHERE t.origin_template_id = ?
+		GROUP BY t.id, t.name, t.version, t.status, t.creator, t.create_time
+		ORDER BY t.version ASC
+	`, originTemplateID).Scan(&results).Error
+	return results, err
+}
+
+// GetRunningFlows 获取绑定在指定模板版本上、仍在审批中的审批流
+func (r *GormApprovalFlowTemplateRepository) GetRunningFlows(templateID int) ([]map[string]interface{}, error) {
+	var results []map[string]interface{}
+	err := r.db.Table("approval_flow_management fm").
+		Select(`
+			fm.id,
+			fm.step,
+			fm.create_user,
+			ua.username as create_user_name,
+			fm.create_time,
+			fm.business_type,
+			fm.business_id,
+			fm.version
+		`).
+		Joins("LEFT JOIN useraccount ua ON fm.create_user = ua.id").
+		Where("fm.approval_flow_template_id = ? AND fm.status = 0", templateID).
+		Order("fm.create_time DESC").
+		Find(&results).Error
+	return results, err
+}
+
+// GetSnapshot 获取模板版本的完整配置，用于版本比较
+func (r *GormApprovalFlowTemplateRepository) GetSnapshot(templateID int) (*entity.TemplateSnapshot, error) {
+	snapshot := &entity.TemplateSnapshot{NodeApprovers: make(map[int][]int)}
+	if err := r.db.First(&snapshot.Template, templateID).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("template_id = ?", templateID).Order("sort ASC").Find(&snapshot.Nodes).Error; err != nil {
+		return nil, err
+	}
+
+	nodeIDs := make([]int, 0, len(snapshot.Nodes))
+	for _, node := range snapshot.Nodes {
+		nodeIDs = append(nodeIDs, node.ID)
+	}
+	if len(nodeIDs) > 0 {
+		var approvers []entity.ApprovalNodeUserAccount
+		if err := r.db.Where("node_id IN ?", nodeIDs).Find(&approvers).Error; err != nil {
+			return nil, err
+		}
+		for _, a := range approvers {
+			snapshot.NodeApprovers[a.NodeID] = append(snapshot.NodeApprovers[a.NodeID], a.UserAccountID)
+		}
+	}
+
+	copyUsers, err := r.GetCopyUsers(templateID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.CopyUsers = copyUsers
+	return snapshot, nil
 }
 
 // UpdateStatus 更新模板状态
@@ -180,10 +331,10 @@ func (r *GormApprovalFlowTemplateRepository) UpdateStatus(id int, status int8) e
 		Update("status", status).Error
 }
 
-// DisableSameTypeTemplates 禁用同类型的其他模板
-func (r *GormApprovalFlowTemplateRepository) DisableSameTypeTemplates(typeID int, excludeID int) error {
+// DisableOtherVersions 禁用同一模板的其他版本
+func (r *GormApprovalFlowTemplateRepository) DisableOtherVersions(originTemplateID int, excludeID int) error {
 	return r.db.Model(&entity.ApprovalFlowTemplate{}).
-		Where("approval_flow_type_id = ? AND id != ?", typeID, excludeID).
+		Where("origin_template_id = ? AND id != ?", originTemplateID, excludeID).
 		Update("status", 1).Error
 }
 
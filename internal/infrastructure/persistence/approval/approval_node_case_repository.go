@@ -58,39 +58,6 @@ func (r *GormApprovalNodeCaseRepository) GetNodeUserByID(id int) (*entity.Approv
 	return &user, nil
 }
 
-// CreateNextNode 创建下一节点实例（事务：节点实例、审批人员记录）
-func (r *GormApprovalNodeCaseRepository) CreateNextNode(flowID int, templateNodeID int, nodeType int8, sort int, approvers []int) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		// 1. 创建节点实例
-		nodeCase := entity.ApprovalNodeCase{
-			NodeID:                   templateNodeID,
-			ApprovalFlowManagementID: flowID,
-			Type:                     nodeType,
-			Sort:                     sort,
-		}
-		if err := tx.Create(&nodeCase).Error; err != nil {
-			return fmt.Errorf("创建节点实例失败: %w", err)
-		}
-
-		// 2. 创建审批人员记录
-		if len(approvers) == 0 {
-			return fmt.Errorf("审批人员列表为空")
-		}
-
-		for _, approverID := range approvers {
-			userCase := entity.ApprovalNodeCaseUser{
-				ApprovalNodeCaseID: nodeCase.ID,
-				UserAccountID:      approverID,
-			}
-			if err := tx.Create(&userCase).Error; err != nil {
-				return fmt.Errorf("创建审批人员记录失败: %w", err)
-			}
-		}
-
-		return nil
-	})
-}
-
 // UpdateNodeResult 更新节点结果
 func (r *GormApprovalNodeCaseRepository) UpdateNodeResult(nodeCaseID int, result int8) error {
 	now := time.Now()
@@ -173,38 +140,3 @@ func (r *GormApprovalNodeCaseRepository) GetTemplateNodeByID(nodeID int) (*entit
 	}
 	return &node, nil
 }
-
-// GetNextTemplateNode 获取下一个模板节点
-func (r *GormApprovalNodeCaseRepository) GetNextTemplateNode(templateID int, currentSort int) (*entity.ApprovalFlowTemplateNode, error) {
-	var node entity.ApprovalFlowTemplateNode
-	err := r.db.Where("template_id = ? AND sort > ?", templateID, currentSort).
-		Order("sort ASC").
-		First(&node).Error
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// 没有下一个节点，返回nil而不是错误
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &node, nil
-}
-
-// GetNodeApprovers 获取模板节点的审批人员
-func (r *GormApprovalNodeCaseRepository) GetNodeApprovers(nodeID int) ([]int, error) {
-	var approvers []entity.ApprovalNodeUserAccount
-	err := r.db.Where("node_id = ?", nodeID).Find(&approvers).Error
-	if err != nil {
-		return nil, err
-	}
-
-	if len(approvers) == 0 {
-		return nil, fmt.Errorf("节点未配置审批人员")
-	}
-
-	approverIDs := make([]int, len(approvers))
-	for i, a := range approvers {
-		approverIDs[i] = a.UserAccountID
-	}
-	return approverIDs, nil
-}
@@ -2,7 +2,10 @@ package financial
 
 import (
 	"charonoms/internal/domain/financial/payment"
+	orderService "charonoms/internal/domain/order/service"
+	"charonoms/internal/domain/shared/money"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // PaymentRepositoryImpl 收款仓储实现
@@ -15,11 +18,28 @@ func NewPaymentRepository(db *gorm.DB) payment.PaymentRepository {
 	return &PaymentRepositoryImpl{db: db}
 }
 
+// WithTx 返回使用调用方事务的仓储实例
+func (r *PaymentRepositoryImpl) WithTx(tx *gorm.DB) payment.PaymentRepository {
+	return &PaymentRepositoryImpl{db: tx}
+}
+
 // Create 创建收款记录
 func (r *PaymentRepositoryImpl) Create(p *payment.PaymentCollection) error {
 	return r.db.Create(p).Error
 }
 
+// CreateAwaitingGateway 创建待支付的在线收款
+// status 字段带默认值，GORM 插入时会把零值替换为默认值，因此插入后显式写回待支付状态
+func (r *PaymentRepositoryImpl) CreateAwaitingGateway(p *payment.PaymentCollection) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(p).Error; err != nil {
+			return err
+		}
+		p.Status = payment.PaymentStatusWaitPay
+		return tx.Model(p).Update("status", payment.PaymentStatusWaitPay).Error
+	})
+}
+
 // GetByID 根据ID查询收款记录
 func (r *PaymentRepositoryImpl) GetByID(id int) (*payment.PaymentCollection, error) {
 	var p payment.PaymentCollection
@@ -33,6 +53,21 @@ func (r *PaymentRepositoryImpl) GetByID(id int) (*payment.PaymentCollection, err
 	return &p, nil
 }
 
+// LockByMerchantOrder 按商户订单号查询在线收款并加行锁
+func (r *PaymentRepositoryImpl) LockByMerchantOrder(merchantOrder string) (*payment.PaymentCollection, error) {
+	var p payment.PaymentCollection
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("merchant_order = ? AND payment_scenario = ?", merchantOrder, payment.PaymentScenarioOnline).
+		First(&p).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
 // List 查询收款列表
 func (r *PaymentRepositoryImpl) List(filter payment.PaymentListFilter) ([]*payment.PaymentCollection, int64, error) {
 	query := r.db.Model(&payment.PaymentCollection{})
@@ -88,22 +123,19 @@ func (r *PaymentRepositoryImpl) Delete(id int) error {
 	return r.db.Delete(&payment.PaymentCollection{}, id).Error
 }
 
-// GetTotalPaidAmount 查询订单已收款总额
-func (r *PaymentRepositoryImpl) GetTotalPaidAmount(orderID int) (float64, error) {
-	var totalRegular float64
-	// 常规收款：status=10或20
+// GetTotalPaidAmount 查询订单已收款总额（常规收款 + 淘宝收款）
+func (r *PaymentRepositoryImpl) GetTotalPaidAmount(orderID int) (money.Money, error) {
+	return orderService.OrderPaidAmount(r.db, orderID)
+}
+
+// GetAwaitingGatewayAmount 查询订单等待网关支付通知的在线收款总额
+func (r *PaymentRepositoryImpl) GetAwaitingGatewayAmount(orderID int) (money.Money, error) {
+	var total money.Money
 	err := r.db.Model(&payment.PaymentCollection{}).
-		Where("order_id = ? AND status IN (?, ?)", orderID, payment.PaymentStatusUnverified, payment.PaymentStatusPaid).
+		Where("order_id = ? AND payment_scenario = ? AND status = ?", orderID, payment.PaymentScenarioOnline, payment.PaymentStatusWaitPay).
 		Select("COALESCE(SUM(payment_amount), 0)").
-		Scan(&totalRegular).Error
-	if err != nil {
-		return 0, err
-	}
-
-	// 淘宝收款暂不实现，返回0
-	totalTaobao := 0.0
-
-	return totalRegular + totalTaobao, nil
+		Scan(&total).Error
+	return total, err
 }
 
 // CountByOrderAndStatus 统计订单指定状态的收款数量
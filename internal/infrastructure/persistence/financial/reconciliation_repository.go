@@ -0,0 +1,189 @@
+package financial
+
+import (
+	"time"
+
+	"charonoms/internal/domain/financial/payment"
+	"charonoms/internal/domain/financial/reconciliation"
+	"charonoms/internal/domain/financial/taobao"
+	"charonoms/internal/domain/financial/unclaimed"
+	"charonoms/internal/domain/shared/money"
+	"gorm.io/gorm"
+)
+
+// ReconciliationRepositoryImpl 对账仓储实现
+type ReconciliationRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewReconciliationRepository 创建对账仓储实例
+func NewReconciliationRepository(db *gorm.DB) reconciliation.ReconciliationRepository {
+	return &ReconciliationRepositoryImpl{db: db}
+}
+
+// WithTx 返回使用调用方事务的仓储实例
+func (r *ReconciliationRepositoryImpl) WithTx(tx *gorm.DB) reconciliation.ReconciliationRepository {
+	return &ReconciliationRepositoryImpl{db: tx}
+}
+
+// systemRecordRow 各来源表查询结果的公共列
+type systemRecordRow struct {
+	ID            int
+	MerchantOrder *string
+	Amount        money.Money
+	RecordTime    time.Time
+	PayeeEntity   *int
+	Status        int
+}
+
+// ListSystemRecords 查询参与对账的系统记录
+// 常规收款取未核验和已支付的记录；待认领只取待认领的记录，已认领的款项以认领生成的收款记录参与对账；
+// 淘宝收款取待认领、已认领和已到账的记录。记录时间取到账时间，尚未到账的取交易（下单）时间或创建时间
+func (r *ReconciliationRepositoryImpl) ListSystemRecords(query reconciliation.RecordQuery) ([]reconciliation.SystemRecord, error) {
+	var records []reconciliation.SystemRecord
+
+	var payments []systemRecordRow
+	err := r.db.Raw(`
+		SELECT id, merchant_order, payment_amount AS amount, record_time, payee_entity, status
+		FROM (
+			SELECT id, merchant_order, payment_amount, payee_entity, status, payment_method,
+				COALESCE(arrival_time, trading_hours, create_time) AS record_time
+			FROM payment_collection
+		) p
+		WHERE payment_method IN ? AND payee_entity = ? AND status IN ?
+			AND ((record_time >= ? AND record_time < ?) OR merchant_order IN ?)
+		ORDER BY id
+	`, query.PaymentMethods, query.PayeeEntity,
+		[]int{payment.PaymentStatusUnverified, payment.PaymentStatusPaid},
+		query.Start, query.End, query.MerchantOrders).Scan(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	records = appendRecords(records, reconciliation.SourcePayment, payments)
+
+	var pending []systemRecordRow
+	err = r.db.Raw(`
+		SELECT id, merchant_order, payment_amount AS amount, arrival_time AS record_time, payee_entity, status
+		FROM unclaimed
+		WHERE payment_method IN ? AND payee_entity = ? AND status = ?
+			AND ((arrival_time >= ? AND arrival_time < ?) OR merchant_order IN ?)
+		ORDER BY id
+	`, query.PaymentMethods, query.PayeeEntity, unclaimed.UnclaimedStatusPending,
+		query.Start, query.End, query.MerchantOrders).Scan(&pending).Error
+	if err != nil {
+		return nil, err
+	}
+	records = appendRecords(records, reconciliation.SourceUnclaimed, pending)
+
+	if query.IncludeTaobao {
+		var taobaoPayments []systemRecordRow
+		err = r.db.Raw(`
+			SELECT id, merchant_order, payment_amount AS amount, record_time, NULL AS payee_entity, status
+			FROM (
+				SELECT id, merchant_order, payment_amount, status,
+					COALESCE(arrival_time, order_time, create_time) AS record_time
+				FROM taobao_payment
+			) t
+			WHERE status IN ? AND ((record_time >= ? AND record_time < ?) OR merchant_order IN ?)
+			ORDER BY id
+		`, []int{taobao.TaobaoPaymentStatusUnclaimed, taobao.TaobaoPaymentStatusClaimed, taobao.TaobaoPaymentStatusArrived},
+			query.Start, query.End, query.MerchantOrders).Scan(&taobaoPayments).Error
+		if err != nil {
+			return nil, err
+		}
+		records = appendRecords(records, reconciliation.SourceTaobao, taobaoPayments)
+	}
+
+	return records, nil
+}
+
+// appendRecords 将查询结果转换为对账记录
+func appendRecords(records []reconciliation.SystemRecord, source string, rows []systemRecordRow) []reconciliation.SystemRecord {
+	for _, row := range rows {
+		record := reconciliation.SystemRecord{
+			Source:      source,
+			ID:          row.ID,
+			Amount:      row.Amount,
+			Time:        row.RecordTime,
+			PayeeEntity: row.PayeeEntity,
+			Status:      row.Status,
+		}
+		if row.MerchantOrder != nil {
+			record.MerchantOrder = *row.MerchantOrder
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// CreateBatch 保存对账批次及其明细
+func (r *ReconciliationRepositoryImpl) CreateBatch(batch *reconciliation.Batch, items []*reconciliation.Item) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		for _, item := range items {
+			item.BatchID = batch.ID
+		}
+		return tx.CreateInBatches(items, 200).Error
+	})
+}
+
+// GetBatch 根据ID查询对账批次
+func (r *ReconciliationRepositoryImpl) GetBatch(id int) (*reconciliation.Batch, error) {
+	var batch reconciliation.Batch
+	err := r.db.Where("id = ?", id).First(&batch).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ListBatches 查询对账批次列表
+func (r *ReconciliationRepositoryImpl) ListBatches(channel string, page, pageSize int) ([]*reconciliation.Batch, int64, error) {
+	query := r.db.Model(&reconciliation.Batch{})
+	if channel != "" {
+		query = query.Where("channel = ?", channel)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page > 0 && pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+
+	var batches []*reconciliation.Batch
+	err := query.Order("id DESC").Find(&batches).Error
+	return batches, total, err
+}
+
+// ListItems 查询批次明细
+func (r *ReconciliationRepositoryImpl) ListItems(batchID int, result *int) ([]*reconciliation.Item, error) {
+	query := r.db.Where("batch_id = ?", batchID)
+	if result != nil {
+		query = query.Where("result = ?", *result)
+	}
+
+	var items []*reconciliation.Item
+	err := query.Order("id").Find(&items).Error
+	return items, err
+}
+
+// MarkConfirmed 标记明细已确认到账
+func (r *ReconciliationRepositoryImpl) MarkConfirmed(itemID int) error {
+	return r.db.Model(&reconciliation.Item{}).
+		Where("id = ?", itemID).
+		Updates(map[string]interface{}{
+			"confirmed":    true,
+			"confirm_time": time.Now(),
+		}).Error
+}
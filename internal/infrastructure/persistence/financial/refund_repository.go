@@ -15,6 +15,11 @@ func NewRefundRepository(db *gorm.DB) refund.RefundRepository {
 	return &refundRepository{db: db}
 }
 
+// WithTx 返回使用调用方事务的仓储实例
+func (r *refundRepository) WithTx(tx *gorm.DB) refund.RefundRepository {
+	return &refundRepository{db: tx}
+}
+
 // CreateRefundOrder 创建退费订单
 func (r *refundRepository) CreateRefundOrder(refundOrder *refund.RefundOrder) error {
 	return r.db.Create(refundOrder).Error
@@ -1,7 +1,12 @@
 package financial
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"charonoms/internal/domain/financial/separate"
+	"charonoms/internal/domain/shared/money"
 	"gorm.io/gorm"
 )
 
@@ -15,6 +20,11 @@ func NewSeparateAccountRepository(db *gorm.DB) separate.SeparateAccountRepositor
 	return &SeparateAccountRepositoryImpl{db: db}
 }
 
+// WithTx 返回使用调用方事务的仓储实例
+func (r *SeparateAccountRepositoryImpl) WithTx(tx *gorm.DB) separate.SeparateAccountRepository {
+	return &SeparateAccountRepositoryImpl{db: tx}
+}
+
 // Create 创建分账明细
 func (r *SeparateAccountRepositoryImpl) Create(account *separate.SeparateAccount) error {
 	return r.db.Create(account).Error
@@ -43,9 +53,28 @@ func (r *SeparateAccountRepositoryImpl) GetByID(id int) (*separate.SeparateAccou
 
 // List 查询分账明细列表
 func (r *SeparateAccountRepositoryImpl) List(filter separate.SeparateListFilter) ([]*separate.SeparateAccount, int64, error) {
-	query := r.db.Model(&separate.SeparateAccount{})
+	query := applySeparateFilter(r.db.Model(&separate.SeparateAccount{}), filter)
+
+	// 查询总数
+	var total int64
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	var accounts []*separate.SeparateAccount
+	offset := (filter.Page - 1) * filter.PageSize
+	err = query.Order("create_time DESC").Offset(offset).Limit(filter.PageSize).Find(&accounts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return accounts, total, nil
+}
 
-	// 构建查询条件
+// applySeparateFilter 构建分账明细查询条件
+func applySeparateFilter(query *gorm.DB, filter separate.SeparateListFilter) *gorm.DB {
 	if filter.ID != nil {
 		query = query.Where("id = ?", *filter.ID)
 	}
@@ -70,23 +99,124 @@ func (r *SeparateAccountRepositoryImpl) List(filter separate.SeparateListFilter)
 	if filter.Type != nil {
 		query = query.Where("type = ?", *filter.Type)
 	}
+	if filter.BrandID != nil {
+		query = query.Where("brand_id = ?", *filter.BrandID)
+	}
+	if filter.ClassifyID != nil {
+		query = query.Where("classify_id = ?", *filter.ClassifyID)
+	}
+	if filter.StartDate != nil && *filter.StartDate != "" {
+		query = query.Where("create_time >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil && *filter.EndDate != "" {
+		query = query.Where("create_time < DATE_ADD(?, INTERVAL 1 DAY)", *filter.EndDate)
+	}
+	return query
+}
 
-	// 查询总数
+// separateGroupColumns 各汇总维度的查询列和分组列
+// 名称是分账时的快照，同一ID可能因改名出现多个名称，按ID分组并取其中一个名称展示
+var separateGroupColumns = map[string]struct {
+	selects []string
+	groups  []string
+}{
+	separate.GroupByBrand:    {[]string{"brand_id", "MAX(brand_name) AS brand_name"}, []string{"brand_id"}},
+	separate.GroupByClassify: {[]string{"classify_id", "MAX(classify_name) AS classify_name"}, []string{"classify_id"}},
+	separate.GroupByGoods:    {[]string{"goods_id", "MAX(goods_name) AS goods_name"}, []string{"goods_id"}},
+	separate.GroupByMonth:    {[]string{"DATE_FORMAT(create_time, '%Y-%m') AS month"}, []string{"month"}},
+}
+
+// Aggregate 按维度汇总分账金额
+func (r *SeparateAccountRepositoryImpl) Aggregate(filter separate.SeparateListFilter, groupBy []string) ([]*separate.SeparateAggregate, int64, error) {
+	var selects, groups []string
+	for _, dim := range groupBy {
+		cols, ok := separateGroupColumns[dim]
+		if !ok {
+			return nil, 0, fmt.Errorf("不支持的汇总维度: %s", dim)
+		}
+		selects = append(selects, cols.selects...)
+		groups = append(groups, cols.groups...)
+	}
+	if len(groups) == 0 {
+		return nil, 0, errors.New("汇总维度不能为空")
+	}
+	selects = append(selects,
+		fmt.Sprintf("COALESCE(SUM(CASE WHEN type = %d THEN separate_amount END), 0) AS sale_amount", separate.SeparateTypeSale),
+		fmt.Sprintf("COALESCE(SUM(CASE WHEN type = %d THEN separate_amount END), 0) AS revert_amount", separate.SeparateTypeRevert),
+		fmt.Sprintf("COALESCE(SUM(CASE WHEN type = %d THEN separate_amount END), 0) AS refund_amount", separate.SeparateTypeRefund),
+		"COALESCE(SUM(separate_amount), 0) AS net_amount",
+		"COUNT(*) AS count",
+	)
+
+	query := applySeparateFilter(r.db.Model(&separate.SeparateAccount{}), filter).
+		Select(strings.Join(selects, ", ")).
+		Group(strings.Join(groups, ", "))
+
+	// 查询分组总数
 	var total int64
-	err := query.Count(&total).Error
-	if err != nil {
+	if err := r.db.Table("(?) AS g", query).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	// 分页查询
-	var accounts []*separate.SeparateAccount
-	offset := (filter.Page - 1) * filter.PageSize
-	err = query.Order("create_time DESC").Offset(offset).Limit(filter.PageSize).Find(&accounts).Error
-	if err != nil {
+	var aggregates []*separate.SeparateAggregate
+	if filter.Page > 0 && filter.PageSize > 0 {
+		query = query.Offset((filter.Page - 1) * filter.PageSize).Limit(filter.PageSize)
+	}
+	if err := query.Order(strings.Join(groups, ", ")).Scan(&aggregates).Error; err != nil {
 		return nil, 0, err
 	}
+	return aggregates, total, nil
+}
 
-	return accounts, total, nil
+// GetGoodsSnapshots 查询商品当前的名称、品牌和分类
+func (r *SeparateAccountRepositoryImpl) GetGoodsSnapshots(goodsIDs []int) (map[int]separate.GoodsSnapshot, error) {
+	var rows []separate.GoodsSnapshot
+	err := r.db.Raw(`
+		SELECT g.id AS goods_id, g.name AS goods_name,
+			g.brandid AS brand_id, b.name AS brand_name,
+			g.classifyid AS classify_id, c.name AS classify_name
+		FROM goods g
+		LEFT JOIN brand b ON g.brandid = b.id
+		LEFT JOIN classify c ON g.classifyid = c.id
+		WHERE g.id IN ?
+	`, goodsIDs).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make(map[int]separate.GoodsSnapshot, len(rows))
+	for _, row := range rows {
+		snapshots[row.GoodsID] = row
+	}
+	return snapshots, nil
+}
+
+// BackfillGoodsSnapshots 为指定ID范围内缺少快照的分账明细补写商品、品牌和分类
+// 冲回和退费明细优先沿用原售卖明细已有的商品名称；商品已删除的明细保持不变
+func (r *SeparateAccountRepositoryImpl) BackfillGoodsSnapshots(afterID, upToID int) (int64, error) {
+	result := r.db.Exec(`
+		UPDATE separate_account sa
+		INNER JOIN goods g ON sa.goods_id = g.id
+		LEFT JOIN brand b ON g.brandid = b.id
+		LEFT JOIN classify c ON g.classifyid = c.id
+		SET sa.goods_name = IF(sa.goods_name = '', g.name, sa.goods_name),
+			sa.brand_id = g.brandid,
+			sa.brand_name = b.name,
+			sa.classify_id = g.classifyid,
+			sa.classify_name = c.name
+		WHERE sa.id > ? AND sa.id <= ?
+			AND (sa.goods_name = '' OR sa.brand_id IS NULL OR sa.classify_id IS NULL)
+	`, afterID, upToID)
+	return result.RowsAffected, result.Error
+}
+
+// MaxID 查询分账明细的最大ID
+func (r *SeparateAccountRepositoryImpl) MaxID() (int, error) {
+	var maxID int
+	err := r.db.Model(&separate.SeparateAccount{}).
+		Select("COALESCE(MAX(id), 0)").
+		Scan(&maxID).Error
+	return maxID, err
 }
 
 // ExistsByPaymentAndOrder 检查指定收款和订单是否已生成分账
@@ -101,9 +231,16 @@ func (r *SeparateAccountRepositoryImpl) ExistsByPaymentAndOrder(paymentID int, o
 	return count > 0, nil
 }
 
+// GetOrderAllocationStrategy 查询订单指定的收款分账策略
+func (r *SeparateAccountRepositoryImpl) GetOrderAllocationStrategy(orderID int) (string, error) {
+	var strategy string
+	err := r.db.Raw("SELECT allocation_strategy FROM orders WHERE id = ?", orderID).Scan(&strategy).Error
+	return strategy, err
+}
+
 // GetChildOrderTotalSeparate 查询子订单的总分账金额（只统计售卖类型）
-func (r *SeparateAccountRepositoryImpl) GetChildOrderTotalSeparate(childOrderID int) (float64, error) {
-	var total float64
+func (r *SeparateAccountRepositoryImpl) GetChildOrderTotalSeparate(childOrderID int) (money.Money, error) {
+	var total money.Money
 	err := r.db.Model(&separate.SeparateAccount{}).
 		Where("childorders_id = ? AND type = ?", childOrderID, separate.SeparateTypeSale).
 		Select("COALESCE(SUM(separate_amount), 0)").
@@ -112,8 +249,8 @@ func (r *SeparateAccountRepositoryImpl) GetChildOrderTotalSeparate(childOrderID
 }
 
 // GetChildOrderAllocatedAmount 查询子订单已分配金额
-func (r *SeparateAccountRepositoryImpl) GetChildOrderAllocatedAmount(childOrderID int) (float64, error) {
-	var allocated float64
+func (r *SeparateAccountRepositoryImpl) GetChildOrderAllocatedAmount(childOrderID int) (money.Money, error) {
+	var allocated money.Money
 	err := r.db.Model(&separate.SeparateAccount{}).
 		Where("childorders_id = ?", childOrderID).
 		Select("COALESCE(SUM(separate_amount), 0)").
@@ -0,0 +1,126 @@
+package financial
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"charonoms/internal/domain/financial/separate"
+	"charonoms/internal/domain/shared/money"
+)
+
+func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm db: %v", err)
+	}
+
+	return gormDB, mock
+}
+
+func TestSeparateAccountRepository_Aggregate(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewSeparateAccountRepository(db)
+
+	brandID := 2
+	filter := separate.SeparateListFilter{BrandID: &brandID, Page: 2, PageSize: 10}
+
+	// 按品牌、月份分组，品牌名称取分账时的快照
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM \\(SELECT brand_id, MAX\\(brand_name\\) AS brand_name, DATE_FORMAT\\(create_time, '%Y-%m'\\) AS month, .* FROM `separate_account` WHERE brand_id = \\? GROUP BY brand_id, month\\) AS g").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+	mock.ExpectQuery("SELECT brand_id, MAX\\(brand_name\\) AS brand_name, DATE_FORMAT\\(create_time, '%Y-%m'\\) AS month, "+
+		"COALESCE\\(SUM\\(CASE WHEN type = 0 THEN separate_amount END\\), 0\\) AS sale_amount, "+
+		"COALESCE\\(SUM\\(CASE WHEN type = 1 THEN separate_amount END\\), 0\\) AS revert_amount, "+
+		"COALESCE\\(SUM\\(CASE WHEN type = 2 THEN separate_amount END\\), 0\\) AS refund_amount, "+
+		"COALESCE\\(SUM\\(separate_amount\\), 0\\) AS net_amount, COUNT\\(\\*\\) AS count "+
+		"FROM `separate_account` WHERE brand_id = \\? GROUP BY brand_id, month ORDER BY brand_id, month LIMIT \\? OFFSET \\?").
+		WithArgs(2, 10, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"brand_id", "brand_name", "month", "sale_amount", "revert_amount", "refund_amount", "net_amount", "count"}).
+			AddRow(2, "学而思", "2026-10", "1000.00", "-400.00", "-100.00", "500.00", 4))
+
+	aggregates, total, err := repo.Aggregate(filter, []string{separate.GroupByBrand, separate.GroupByMonth})
+	if err != nil {
+		t.Fatalf("Aggregate() error = %v", err)
+	}
+	if total != 12 {
+		t.Errorf("Aggregate() total = %d, want 12", total)
+	}
+	if len(aggregates) != 1 {
+		t.Fatalf("len(aggregates) = %d, want 1", len(aggregates))
+	}
+	got := aggregates[0]
+	if got.BrandID == nil || *got.BrandID != 2 || got.BrandName == nil || *got.BrandName != "学而思" || got.Month == nil || *got.Month != "2026-10" {
+		t.Errorf("Aggregate() group = %+v, want brand 2 学而思 2026-10", got)
+	}
+	if got.ClassifyID != nil || got.GoodsID != nil {
+		t.Errorf("Aggregate() group = %+v, want only brand and month dimensions", got)
+	}
+	if !got.SaleAmount.Equal(money.FromFloat(1000)) || !got.RevertAmount.Equal(money.FromFloat(-400)) ||
+		!got.RefundAmount.Equal(money.FromFloat(-100)) || !got.NetAmount.Equal(money.FromFloat(500)) || got.Count != 4 {
+		t.Errorf("Aggregate() amounts = %+v, want sale 1000 revert -400 refund -100 net 500 count 4", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestSeparateAccountRepository_Aggregate_InvalidGroupBy(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewSeparateAccountRepository(db)
+
+	tests := []struct {
+		name    string
+		groupBy []string
+	}{
+		{"不支持的维度", []string{separate.GroupByBrand, "student"}},
+		{"维度为空", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := repo.Aggregate(separate.SeparateListFilter{}, tt.groupBy); err == nil {
+				t.Errorf("Aggregate(%v) error = nil, want error", tt.groupBy)
+			}
+		})
+	}
+	// 维度校验失败时不查询数据库
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestSeparateAccountRepository_BackfillGoodsSnapshots(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewSeparateAccountRepository(db)
+
+	// 只补写ID范围内缺少快照的明细，已有的商品名称不覆盖
+	mock.ExpectExec("UPDATE separate_account sa\\s+INNER JOIN goods g ON sa.goods_id = g.id\\s+"+
+		"LEFT JOIN brand b ON g.brandid = b.id\\s+LEFT JOIN classify c ON g.classifyid = c.id\\s+"+
+		"SET sa.goods_name = IF\\(sa.goods_name = '', g.name, sa.goods_name\\),\\s+"+
+		"sa.brand_id = g.brandid,\\s+sa.brand_name = b.name,\\s+sa.classify_id = g.classifyid,\\s+sa.classify_name = c.name\\s+"+
+		"WHERE sa.id > \\? AND sa.id <= \\?\\s+"+
+		"AND \\(sa.goods_name = '' OR sa.brand_id IS NULL OR sa.classify_id IS NULL\\)").
+		WithArgs(1000, 2000).
+		WillReturnResult(sqlmock.NewResult(0, 37))
+
+	n, err := repo.BackfillGoodsSnapshots(1000, 2000)
+	if err != nil {
+		t.Fatalf("BackfillGoodsSnapshots() error = %v", err)
+	}
+	if n != 37 {
+		t.Errorf("BackfillGoodsSnapshots() = %d, want 37", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package financial
 
 import (
 	"charonoms/internal/domain/financial/taobao"
+	"charonoms/internal/domain/shared/money"
 	"fmt"
 
 	"gorm.io/gorm"
@@ -16,6 +17,11 @@ func NewTaobaoPaymentRepository(db *gorm.DB) taobao.TaobaoPaymentRepository {
 	return &taobaoPaymentRepository{db: db}
 }
 
+// WithTx 返回使用调用方事务的仓储实例
+func (r *taobaoPaymentRepository) WithTx(tx *gorm.DB) taobao.TaobaoPaymentRepository {
+	return &taobaoPaymentRepository{db: tx}
+}
+
 func (r *taobaoPaymentRepository) Create(payment *taobao.TaobaoPayment) error {
 	return r.db.Table("taobao_payment").Create(payment).Error
 }
@@ -68,13 +74,13 @@ func (r *taobaoPaymentRepository) Delete(id int) error {
 	return r.db.Table("taobao_payment").Where("id = ?", id).Delete(&taobao.TaobaoPayment{}).Error
 }
 
-func (r *taobaoPaymentRepository) GetTotalPaid(orderID int) (float64, error) {
-	var total float64
+func (r *taobaoPaymentRepository) GetTotalPaid(orderID int) (money.Money, error) {
+	var total money.Money
 	err := r.db.Table("taobao_payment").
 		Select("COALESCE(SUM(payment_amount), 0) as total_paid").
 		Where("order_id = ? AND status IN ?", orderID, []int{
-			taobao.TaobaoPaymentStatusClaimed,  // 20-已认领
-			taobao.TaobaoPaymentStatusArrived,  // 30-已到账
+			taobao.TaobaoPaymentStatusClaimed, // 20-已认领
+			taobao.TaobaoPaymentStatusArrived, // 30-已到账
 		}).
 		Scan(&total).Error
 	return total, err
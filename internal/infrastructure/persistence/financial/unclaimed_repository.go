@@ -16,6 +16,11 @@ func NewUnclaimedRepository(db *gorm.DB) unclaimed.UnclaimedRepository {
 	return &unclaimedRepository{db: db}
 }
 
+// WithTx 返回使用调用方事务的仓储实例
+func (r *unclaimedRepository) WithTx(tx *gorm.DB) unclaimed.UnclaimedRepository {
+	return &unclaimedRepository{db: tx}
+}
+
 // Create 创建待认领记录
 func (r *unclaimedRepository) Create(u *unclaimed.Unclaimed) error {
 	return r.db.Create(u).Error
@@ -60,11 +60,12 @@ func (r *accountRepositoryImpl) UpdateAccount(ctx context.Context, account *enti
 		Model(&entity.UserAccount{}).
 		Where("id = ?", account.ID).
 		Updates(map[string]interface{}{
-			"username": account.Username,
-			"name":     account.Name,
-			"phone":    account.Phone,
-			"role_id":  account.RoleID,
-			"status":   account.Status,
+			"username":   account.Username,
+			"name":       account.Name,
+			"phone":      account.Phone,
+			"role_id":    account.RoleID,
+			"manager_id": account.ManagerID,
+			"status":     account.Status,
 		}).Error
 }
 
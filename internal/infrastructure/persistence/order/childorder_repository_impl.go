@@ -19,12 +19,76 @@ func NewChildOrderRepository(db *gorm.DB) repository.ChildOrderRepository {
 	return &GormChildOrderRepository{db: db}
 }
 
-// GetChildOrders 获取子订单列表（含商品信息）
-func (r *GormChildOrderRepository) GetChildOrders(ctx context.Context) ([]map[string]interface{}, error) {
-	var results []map[string]interface{}
+// WithTx 返回使用调用方事务的仓储实例
+func (r *GormChildOrderRepository) WithTx(tx *gorm.DB) repository.ChildOrderRepository {
+	return &GormChildOrderRepository{db: tx}
+}
 
-	err := r.db.WithContext(ctx).
+// childOrderSortColumns 子订单列表排序字段对应的列
+var childOrderSortColumns = map[string]string{
+	repository.ChildOrderSortID:               "c.id",
+	repository.ChildOrderSortCreateTime:       "c.create_time",
+	repository.ChildOrderSortAmountReceivable: "c.amount_receivable",
+	repository.ChildOrderSortAmountReceived:   "c.amount_received",
+	repository.ChildOrderSortStatus:           "c.status",
+}
+
+// GetChildOrders 分页查询子订单列表（含商品信息）
+func (r *GormChildOrderRepository) GetChildOrders(ctx context.Context, filter repository.ChildOrderListFilter) ([]map[string]interface{}, int64, error) {
+	query := r.db.WithContext(ctx).
 		Table("childorders c").
+		Joins("JOIN goods g ON c.goodsid = g.id")
+
+	// 构建查询条件
+	if filter.ID != nil {
+		query = query.Where("c.id = ?", *filter.ID)
+	}
+	if filter.ParentsID != nil {
+		query = query.Where("c.parentsid = ?", *filter.ParentsID)
+	}
+	if filter.GoodsID != nil {
+		query = query.Where("c.goodsid = ?", *filter.GoodsID)
+	}
+	if filter.Status != nil {
+		query = query.Where("c.status = ?", *filter.Status)
+	}
+	if filter.CreateDateStart != nil && *filter.CreateDateStart != "" {
+		query = query.Where("c.create_time >= ?", *filter.CreateDateStart)
+	}
+	if filter.CreateDateEnd != nil && *filter.CreateDateEnd != "" {
+		query = query.Where("c.create_time < DATE_ADD(?, INTERVAL 1 DAY)", *filter.CreateDateEnd)
+	}
+	if filter.AmountMin != nil {
+		query = query.Where("c.amount_received >= ?", *filter.AmountMin)
+	}
+	if filter.AmountMax != nil {
+		query = query.Where("c.amount_received <= ?", *filter.AmountMax)
+	}
+
+	// 查询总数
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 排序：相同排序值时按子订单ID保证分页稳定
+	column, ok := childOrderSortColumns[filter.SortBy]
+	if !ok {
+		column = "c.id"
+	}
+	direction := "ASC"
+	if filter.SortDesc {
+		direction = "DESC"
+	}
+	order := column + " " + direction
+	if column != "c.id" {
+		order += ", c.id " + direction
+	}
+
+	// 分页查询
+	var results []map[string]interface{}
+	offset := (filter.Page - 1) * filter.PageSize
+	err := query.
 		Select(`
 			c.id,
 			c.parentsid,
@@ -34,13 +98,18 @@ func (r *GormChildOrderRepository) GetChildOrders(ctx context.Context) ([]map[st
 			c.discount_amount,
 			c.amount_received,
 			c.status,
+			c.gift_activity_id,
 			c.create_time
 		`).
-		Joins("JOIN goods g ON c.goodsid = g.id").
-		Order("c.id DESC").
+		Order(order).
+		Offset(offset).
+		Limit(filter.PageSize).
 		Find(&results).Error
+	if err != nil {
+		return nil, 0, err
+	}
 
-	return results, err
+	return results, total, nil
 }
 
 // GetChildOrdersByParentID 根据父订单ID获取子订单列表
@@ -58,6 +127,7 @@ func (r *GormChildOrderRepository) GetChildOrdersByParentID(ctx context.Context,
 			c.discount_amount,
 			c.amount_received,
 			c.status,
+			c.gift_activity_id,
 			c.create_time
 		`).
 		Joins("JOIN goods g ON c.goodsid = g.id").
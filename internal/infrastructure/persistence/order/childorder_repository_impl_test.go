@@ -0,0 +1,49 @@
+package order
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"charonoms/internal/domain/order/repository"
+	"charonoms/internal/domain/shared/money"
+)
+
+// TestGormChildOrderRepository_GetChildOrders 按订单、状态和金额区间筛选子订单，指定排序时追加子订单ID保证分页稳定
+func TestGormChildOrderRepository_GetChildOrders(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewChildOrderRepository(db)
+
+	parentsID, status := 9, 10
+	amountMin, amountMax := money.FromFloat(50), money.FromFloat(500)
+
+	where := "WHERE c.parentsid = \\? AND c.status = \\? AND c.amount_received >= \\? AND c.amount_received <= \\?"
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM childorders c JOIN goods g ON c.goodsid = g.id "+where).
+		WithArgs(parentsID, status, "50.00", "500.00").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery("SELECT .*c.id,.*FROM childorders c JOIN goods g ON c.goodsid = g.id "+where+
+		" ORDER BY c.amount_receivable ASC, c.id ASC LIMIT \\?").
+		WithArgs(parentsID, status, "50.00", "500.00", 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parentsid", "goods_name"}).
+			AddRow(91, 9, "数学课").AddRow(92, 9, "物理课").AddRow(93, 9, "英语课"))
+
+	childOrders, total, err := repo.GetChildOrders(context.Background(), repository.ChildOrderListFilter{
+		ParentsID: &parentsID,
+		Status:    &status,
+		AmountMin: &amountMin,
+		AmountMax: &amountMax,
+		SortBy:    repository.ChildOrderSortAmountReceivable,
+		Page:      1,
+		PageSize:  20,
+	})
+	if err != nil {
+		t.Fatalf("GetChildOrders() error = %v", err)
+	}
+	if total != 3 || len(childOrders) != 3 {
+		t.Errorf("GetChildOrders() total = %d len = %d, want 3 and 3", total, len(childOrders))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
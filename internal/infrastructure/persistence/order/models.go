@@ -1,17 +1,22 @@
 package order
 
-import "time"
+import (
+	"time"
+
+	"charonoms/internal/domain/shared/money"
+)
 
 // OrderDO orders表数据对象
 type OrderDO struct {
-	ID                  int        `gorm:"column:id;primaryKey;autoIncrement"`
-	StudentID           int        `gorm:"column:student_id;not null"`
-	ExpectedPaymentTime *time.Time `gorm:"column:expected_payment_time"`
-	AmountReceivable    float64    `gorm:"column:amount_receivable;type:decimal(10,2);default:0"`
-	AmountReceived      float64    `gorm:"column:amount_received;type:decimal(10,2);default:0"`
-	DiscountAmount      float64    `gorm:"column:discount_amount;type:decimal(10,2);default:0"`
-	Status              int        `gorm:"column:status;default:10"`
-	CreateTime          time.Time  `gorm:"column:create_time;autoCreateTime"`
+	ID                  int         `gorm:"column:id;primaryKey;autoIncrement"`
+	StudentID           int         `gorm:"column:student_id;not null"`
+	ExpectedPaymentTime *time.Time  `gorm:"column:expected_payment_time"`
+	AmountReceivable    money.Money `gorm:"column:amount_receivable;type:decimal(10,2);default:0"`
+	AmountReceived      money.Money `gorm:"column:amount_received;type:decimal(10,2);default:0"`
+	DiscountAmount      money.Money `gorm:"column:discount_amount;type:decimal(10,2);default:0"`
+	Status              int         `gorm:"column:status;default:10"`
+	AllocationStrategy  string      `gorm:"column:allocation_strategy"`
+	CreateTime          time.Time   `gorm:"column:create_time;autoCreateTime"`
 }
 
 // TableName 指定表名
@@ -21,14 +26,15 @@ func (OrderDO) TableName() string {
 
 // ChildOrderDO childorders表数据对象
 type ChildOrderDO struct {
-	ID               int       `gorm:"column:id;primaryKey;autoIncrement"`
-	ParentsID        int       `gorm:"column:parentsid;not null"`
-	GoodsID          int       `gorm:"column:goodsid;not null"`
-	AmountReceivable float64   `gorm:"column:amount_receivable;type:decimal(10,2);default:0"`
-	AmountReceived   float64   `gorm:"column:amount_received;type:decimal(10,2);default:0"`
-	DiscountAmount   float64   `gorm:"column:discount_amount;type:decimal(10,2);default:0"`
-	Status           int       `gorm:"column:status;default:0"`
-	CreateTime       time.Time `gorm:"column:create_time;autoCreateTime"`
+	ID               int         `gorm:"column:id;primaryKey;autoIncrement"`
+	ParentsID        int         `gorm:"column:parentsid;not null"`
+	GoodsID          int         `gorm:"column:goodsid;not null"`
+	AmountReceivable money.Money `gorm:"column:amount_receivable;type:decimal(10,2);default:0"`
+	AmountReceived   money.Money `gorm:"column:amount_received;type:decimal(10,2);default:0"`
+	DiscountAmount   money.Money `gorm:"column:discount_amount;type:decimal(10,2);default:0"`
+	Status           int         `gorm:"column:status;default:0"`
+	GiftActivityID   *int        `gorm:"column:gift_activity_id"`
+	CreateTime       time.Time   `gorm:"column:create_time;autoCreateTime"`
 }
 
 // TableName 指定表名
@@ -38,13 +44,61 @@ func (ChildOrderDO) TableName() string {
 
 // OrdersActivityDO orders_activity表数据对象
 type OrdersActivityDO struct {
-	ID         int       `gorm:"column:id;primaryKey;autoIncrement"`
-	OrdersID   int       `gorm:"column:orders_id;not null"`
-	ActivityID int       `gorm:"column:activity_id;not null"`
-	CreateTime time.Time `gorm:"column:create_time;autoCreateTime"`
+	ID             int         `gorm:"column:id;primaryKey;autoIncrement"`
+	OrdersID       int         `gorm:"column:orders_id;not null"`
+	ActivityID     int         `gorm:"column:activity_id;not null"`
+	DiscountAmount money.Money `gorm:"column:discount_amount;type:decimal(10,2);default:0"`
+	CreateTime     time.Time   `gorm:"column:create_time;autoCreateTime"`
 }
 
 // TableName 指定表名
 func (OrdersActivityDO) TableName() string {
 	return "orders_activity"
 }
+
+// OrdersActivityDetailDO orders_activity_detail表数据对象
+type OrdersActivityDetailDO struct {
+	ID             int         `gorm:"column:id;primaryKey;autoIncrement"`
+	OrdersID       int         `gorm:"column:orders_id;not null"`
+	ActivityID     int         `gorm:"column:activity_id;not null"`
+	ChildOrderID   int         `gorm:"column:childorder_id;not null"`
+	GoodsID        int         `gorm:"column:goods_id;not null"`
+	DiscountAmount money.Money `gorm:"column:discount_amount;type:decimal(10,2);default:0"`
+	CreateTime     time.Time   `gorm:"column:create_time;autoCreateTime"`
+}
+
+// TableName 指定表名
+func (OrdersActivityDetailDO) TableName() string {
+	return "orders_activity_detail"
+}
+
+// OrderStatusHistoryDO order_status_history表数据对象
+type OrderStatusHistoryDO struct {
+	ID         int       `gorm:"column:id;primaryKey;autoIncrement"`
+	OrderID    int       `gorm:"column:order_id;not null"`
+	FromStatus int       `gorm:"column:from_status;not null"`
+	ToStatus   int       `gorm:"column:to_status;not null"`
+	Operator   string    `gorm:"column:operator;type:varchar(100)"`
+	Reason     string    `gorm:"column:reason;type:varchar(255)"`
+	CreateTime time.Time `gorm:"column:create_time;autoCreateTime"`
+}
+
+// TableName 指定表名
+func (OrderStatusHistoryDO) TableName() string {
+	return "order_status_history"
+}
+
+// OrderInstalmentDO order_instalment表数据对象
+type OrderInstalmentDO struct {
+	ID         int         `gorm:"column:id;primaryKey;autoIncrement"`
+	OrderID    int         `gorm:"column:order_id;not null"`
+	Seq        int         `gorm:"column:seq;not null"`
+	DueDate    time.Time   `gorm:"column:due_date;type:date;not null"`
+	Amount     money.Money `gorm:"column:amount;type:decimal(10,2);default:0"`
+	CreateTime time.Time   `gorm:"column:create_time;autoCreateTime"`
+}
+
+// TableName 指定表名
+func (OrderInstalmentDO) TableName() string {
+	return "order_instalment"
+}
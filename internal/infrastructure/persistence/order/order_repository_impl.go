@@ -8,6 +8,7 @@ import (
 
 	"charonoms/internal/domain/order/entity"
 	"charonoms/internal/domain/order/repository"
+	"charonoms/internal/domain/shared/money"
 )
 
 // GormOrderRepository GORM实现的订单仓储
@@ -20,12 +21,83 @@ func NewOrderRepository(db *gorm.DB) repository.OrderRepository {
 	return &GormOrderRepository{db: db}
 }
 
-// GetOrders 获取订单列表（含学生信息）
-func (r *GormOrderRepository) GetOrders(ctx context.Context) ([]map[string]interface{}, error) {
-	var results []map[string]interface{}
+// WithTx 返回使用调用方事务的仓储实例
+func (r *GormOrderRepository) WithTx(tx *gorm.DB) repository.OrderRepository {
+	return &GormOrderRepository{db: tx}
+}
 
-	err := r.db.WithContext(ctx).
+// orderSortColumns 订单列表排序字段对应的列
+var orderSortColumns = map[string]string{
+	repository.OrderSortID:                  "o.id",
+	repository.OrderSortCreateTime:          "o.create_time",
+	repository.OrderSortExpectedPaymentTime: "o.expected_payment_time",
+	repository.OrderSortAmountReceivable:    "o.amount_receivable",
+	repository.OrderSortAmountReceived:      "o.amount_received",
+	repository.OrderSortStatus:              "o.status",
+}
+
+// GetOrders 分页查询订单列表（含学生信息）
+func (r *GormOrderRepository) GetOrders(ctx context.Context, filter repository.OrderListFilter) ([]map[string]interface{}, int64, error) {
+	query := r.db.WithContext(ctx).
 		Table("orders o").
+		Joins("JOIN student s ON o.student_id = s.id")
+
+	// 构建查询条件
+	if filter.ID != nil {
+		query = query.Where("o.id = ?", *filter.ID)
+	}
+	if filter.StudentID != nil {
+		query = query.Where("o.student_id = ?", *filter.StudentID)
+	}
+	if filter.Status != nil {
+		query = query.Where("o.status = ?", *filter.Status)
+	}
+	if filter.CreateDateStart != nil && *filter.CreateDateStart != "" {
+		query = query.Where("o.create_time >= ?", *filter.CreateDateStart)
+	}
+	if filter.CreateDateEnd != nil && *filter.CreateDateEnd != "" {
+		query = query.Where("o.create_time < DATE_ADD(?, INTERVAL 1 DAY)", *filter.CreateDateEnd)
+	}
+	if filter.ExpectedPaymentDateStart != nil && *filter.ExpectedPaymentDateStart != "" {
+		query = query.Where("o.expected_payment_time >= ?", *filter.ExpectedPaymentDateStart)
+	}
+	if filter.ExpectedPaymentDateEnd != nil && *filter.ExpectedPaymentDateEnd != "" {
+		query = query.Where("o.expected_payment_time < DATE_ADD(?, INTERVAL 1 DAY)", *filter.ExpectedPaymentDateEnd)
+	}
+	if filter.AmountMin != nil {
+		query = query.Where("o.amount_received >= ?", *filter.AmountMin)
+	}
+	if filter.AmountMax != nil {
+		query = query.Where("o.amount_received <= ?", *filter.AmountMax)
+	}
+	if filter.ActivityID != nil {
+		query = query.Where("EXISTS (SELECT 1 FROM orders_activity oa WHERE oa.orders_id = o.id AND oa.activity_id = ?)", *filter.ActivityID)
+	}
+
+	// 查询总数
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 排序：相同排序值时按订单ID保证分页稳定
+	column, ok := orderSortColumns[filter.SortBy]
+	if !ok {
+		column = "o.create_time"
+	}
+	direction := "ASC"
+	if filter.SortDesc {
+		direction = "DESC"
+	}
+	order := column + " " + direction
+	if column != "o.id" {
+		order += ", o.id " + direction
+	}
+
+	// 分页查询
+	var results []map[string]interface{}
+	offset := (filter.Page - 1) * filter.PageSize
+	err := query.
 		Select(`
 			o.id,
 			o.student_id AS uid,
@@ -37,14 +109,18 @@ func (r *GormOrderRepository) GetOrders(ctx context.Context) ([]map[string]inter
 			o.create_time,
 			o.status
 		`).
-		Joins("JOIN student s ON o.student_id = s.id").
-		Order("o.create_time DESC").
+		Order(order).
+		Offset(offset).
+		Limit(filter.PageSize).
 		Find(&results).Error
+	if err != nil {
+		return nil, 0, err
+	}
 
-	return results, err
+	return results, total, nil
 }
 
-// GetOrderByID 根据ID查询订单
+// GetOrderByID 根据ID查询订单（含分期计划）
 func (r *GormOrderRepository) GetOrderByID(ctx context.Context, id int) (*entity.Order, error) {
 	var order OrderDO
 	err := r.db.WithContext(ctx).Where("id = ?", id).First(&order).Error
@@ -52,6 +128,11 @@ func (r *GormOrderRepository) GetOrderByID(ctx context.Context, id int) (*entity
 		return nil, err
 	}
 
+	instalments, err := r.getInstalmentsByOrderIDs(ctx, []int{order.ID})
+	if err != nil {
+		return nil, fmt.Errorf("查询订单分期失败: %w", err)
+	}
+
 	return &entity.Order{
 		ID:                  order.ID,
 		StudentID:           order.StudentID,
@@ -60,12 +141,14 @@ func (r *GormOrderRepository) GetOrderByID(ctx context.Context, id int) (*entity
 		AmountReceived:      order.AmountReceived,
 		DiscountAmount:      order.DiscountAmount,
 		Status:              order.Status,
+		AllocationStrategy:  order.AllocationStrategy,
 		CreateTime:          order.CreateTime,
+		Instalments:         instalments[order.ID],
 	}, nil
 }
 
 // CreateOrder 创建订单（含事务：订单、子订单、活动关联）
-func (r *GormOrderRepository) CreateOrder(ctx context.Context, order *entity.Order, childOrders []*entity.ChildOrder, activityIDs []int) (int, error) {
+func (r *GormOrderRepository) CreateOrder(ctx context.Context, order *entity.Order, childOrders []*entity.ChildOrder, activityIDs []int, activityDiscounts []*entity.OrderActivityDiscount) (int, error) {
 	var orderID int
 
 	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
@@ -77,6 +160,7 @@ func (r *GormOrderRepository) CreateOrder(ctx context.Context, order *entity.Ord
 			AmountReceived:      order.AmountReceived,
 			DiscountAmount:      order.DiscountAmount,
 			Status:              order.Status,
+			AllocationStrategy:  order.AllocationStrategy,
 		}
 		if err := tx.Create(orderDO).Error; err != nil {
 			return fmt.Errorf("创建订单失败: %w", err)
@@ -84,8 +168,9 @@ func (r *GormOrderRepository) CreateOrder(ctx context.Context, order *entity.Ord
 		orderID = orderDO.ID
 
 		// 2. 创建子订单
+		var childOrderDOs []ChildOrderDO
 		if len(childOrders) > 0 {
-			childOrderDOs := make([]ChildOrderDO, 0, len(childOrders))
+			childOrderDOs = make([]ChildOrderDO, 0, len(childOrders))
 			for _, co := range childOrders {
 				childOrderDOs = append(childOrderDOs, ChildOrderDO{
 					ParentsID:        orderID,
@@ -94,6 +179,7 @@ func (r *GormOrderRepository) CreateOrder(ctx context.Context, order *entity.Ord
 					AmountReceived:   co.AmountReceived,
 					DiscountAmount:   co.DiscountAmount,
 					Status:           co.Status,
+					GiftActivityID:   co.GiftActivityID,
 				})
 			}
 			if err := tx.Create(&childOrderDOs).Error; err != nil {
@@ -101,18 +187,14 @@ func (r *GormOrderRepository) CreateOrder(ctx context.Context, order *entity.Ord
 			}
 		}
 
-		// 3. 创建订单活动关联
-		if len(activityIDs) > 0 {
-			activityDOs := make([]OrdersActivityDO, 0, len(activityIDs))
-			for _, activityID := range activityIDs {
-				activityDOs = append(activityDOs, OrdersActivityDO{
-					OrdersID:   orderID,
-					ActivityID: activityID,
-				})
-			}
-			if err := tx.Create(&activityDOs).Error; err != nil {
-				return fmt.Errorf("创建订单活动关联失败: %w", err)
-			}
+		// 3. 创建订单活动关联及优惠明细
+		if err := createOrderActivities(tx, orderID, childOrderDOs, activityIDs, activityDiscounts); err != nil {
+			return err
+		}
+
+		// 4. 创建分期计划
+		if err := createOrderInstalments(tx, orderID, order.Instalments); err != nil {
+			return err
 		}
 
 		return nil
@@ -126,14 +208,15 @@ func (r *GormOrderRepository) CreateOrder(ctx context.Context, order *entity.Ord
 }
 
 // UpdateOrder 更新订单（含事务：订单、子订单、活动关联）
-func (r *GormOrderRepository) UpdateOrder(ctx context.Context, order *entity.Order, childOrders []*entity.ChildOrder, activityIDs []int) error {
+func (r *GormOrderRepository) UpdateOrder(ctx context.Context, order *entity.Order, childOrders []*entity.ChildOrder, activityIDs []int, activityDiscounts []*entity.OrderActivityDiscount) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// 1. 更新订单
 		updates := map[string]interface{}{
-			"amount_receivable":      order.AmountReceivable,
-			"amount_received":        order.AmountReceived,
-			"discount_amount":        order.DiscountAmount,
-			"expected_payment_time":  order.ExpectedPaymentTime,
+			"amount_receivable":     order.AmountReceivable,
+			"amount_received":       order.AmountReceived,
+			"discount_amount":       order.DiscountAmount,
+			"expected_payment_time": order.ExpectedPaymentTime,
+			"allocation_strategy":   order.AllocationStrategy,
 		}
 		if err := tx.Model(&OrderDO{}).Where("id = ?", order.ID).Updates(updates).Error; err != nil {
 			return fmt.Errorf("更新订单失败: %w", err)
@@ -145,8 +228,9 @@ func (r *GormOrderRepository) UpdateOrder(ctx context.Context, order *entity.Ord
 		}
 
 		// 3. 创建新的子订单
+		var childOrderDOs []ChildOrderDO
 		if len(childOrders) > 0 {
-			childOrderDOs := make([]ChildOrderDO, 0, len(childOrders))
+			childOrderDOs = make([]ChildOrderDO, 0, len(childOrders))
 			for _, co := range childOrders {
 				childOrderDOs = append(childOrderDOs, ChildOrderDO{
 					ParentsID:        order.ID,
@@ -155,6 +239,7 @@ func (r *GormOrderRepository) UpdateOrder(ctx context.Context, order *entity.Ord
 					AmountReceived:   co.AmountReceived,
 					DiscountAmount:   co.DiscountAmount,
 					Status:           co.Status,
+					GiftActivityID:   co.GiftActivityID,
 				})
 			}
 			if err := tx.Create(&childOrderDOs).Error; err != nil {
@@ -162,44 +247,193 @@ func (r *GormOrderRepository) UpdateOrder(ctx context.Context, order *entity.Ord
 			}
 		}
 
-		// 4. 删除旧的活动关联
+		// 4. 删除旧的活动关联及优惠明细
+		if err := tx.Where("orders_id = ?", order.ID).Delete(&OrdersActivityDetailDO{}).Error; err != nil {
+			return fmt.Errorf("删除旧活动优惠明细失败: %w", err)
+		}
 		if err := tx.Where("orders_id = ?", order.ID).Delete(&OrdersActivityDO{}).Error; err != nil {
 			return fmt.Errorf("删除旧活动关联失败: %w", err)
 		}
 
-		// 5. 创建新的活动关联
-		if len(activityIDs) > 0 {
-			activityDOs := make([]OrdersActivityDO, 0, len(activityIDs))
-			for _, activityID := range activityIDs {
-				activityDOs = append(activityDOs, OrdersActivityDO{
-					OrdersID:   order.ID,
-					ActivityID: activityID,
-				})
-			}
-			if err := tx.Create(&activityDOs).Error; err != nil {
-				return fmt.Errorf("创建新活动关联失败: %w", err)
-			}
+		// 5. 创建新的活动关联及优惠明细
+		if err := createOrderActivities(tx, order.ID, childOrderDOs, activityIDs, activityDiscounts); err != nil {
+			return err
+		}
+
+		// 6. 重建分期计划
+		if err := tx.Where("order_id = ?", order.ID).Delete(&OrderInstalmentDO{}).Error; err != nil {
+			return fmt.Errorf("删除旧分期计划失败: %w", err)
+		}
+		if err := createOrderInstalments(tx, order.ID, order.Instalments); err != nil {
+			return err
 		}
 
 		return nil
 	})
 }
 
-// UpdateOrderStatus 更新订单状态（含子订单状态级联更新）
-func (r *GormOrderRepository) UpdateOrderStatus(ctx context.Context, orderID int, orderStatus int, childOrderStatus int) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 1. 更新订单状态
-		if err := tx.Model(&OrderDO{}).Where("id = ?", orderID).Update("status", orderStatus).Error; err != nil {
-			return fmt.Errorf("更新订单状态失败: %w", err)
+// createOrderInstalments 在事务中创建订单分期计划
+func createOrderInstalments(tx *gorm.DB, orderID int, instalments []*entity.OrderInstalment) error {
+	if len(instalments) == 0 {
+		return nil
+	}
+
+	instalmentDOs := make([]OrderInstalmentDO, 0, len(instalments))
+	for _, inst := range instalments {
+		instalmentDOs = append(instalmentDOs, OrderInstalmentDO{
+			OrderID: orderID,
+			Seq:     inst.Seq,
+			DueDate: inst.DueDate,
+			Amount:  inst.Amount,
+		})
+	}
+	if err := tx.Create(&instalmentDOs).Error; err != nil {
+		return fmt.Errorf("创建分期计划失败: %w", err)
+	}
+	for i := range instalmentDOs {
+		instalments[i].ID = instalmentDOs[i].ID
+		instalments[i].OrderID = orderID
+	}
+	return nil
+}
+
+// getInstalmentsByOrderIDs 批量查询订单分期计划，返回 订单ID -> 按期数排序的分期
+func (r *GormOrderRepository) getInstalmentsByOrderIDs(ctx context.Context, orderIDs []int) (map[int][]*entity.OrderInstalment, error) {
+	result := make(map[int][]*entity.OrderInstalment)
+	if len(orderIDs) == 0 {
+		return result, nil
+	}
+
+	var instalmentDOs []OrderInstalmentDO
+	err := r.db.WithContext(ctx).
+		Where("order_id IN ?", orderIDs).
+		Order("order_id ASC, seq ASC").
+		Find(&instalmentDOs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, do := range instalmentDOs {
+		result[do.OrderID] = append(result[do.OrderID], &entity.OrderInstalment{
+			ID:         do.ID,
+			OrderID:    do.OrderID,
+			Seq:        do.Seq,
+			DueDate:    do.DueDate,
+			Amount:     do.Amount,
+			CreateTime: do.CreateTime,
+		})
+	}
+	return result, nil
+}
+
+// createOrderActivities 在事务中创建订单活动关联及各活动对子订单的优惠明细
+// 优惠明细按商品ID关联到该商品的第一个非赠品子订单
+func createOrderActivities(tx *gorm.DB, orderID int, childOrderDOs []ChildOrderDO, activityIDs []int, activityDiscounts []*entity.OrderActivityDiscount) error {
+	if len(activityIDs) == 0 {
+		return nil
+	}
+
+	// 商品ID -> 子订单ID
+	childOrderIDs := make(map[int]int)
+	for _, co := range childOrderDOs {
+		if co.GiftActivityID != nil {
+			continue
+		}
+		if _, ok := childOrderIDs[co.GoodsID]; !ok {
+			childOrderIDs[co.GoodsID] = co.ID
 		}
+	}
 
-		// 2. 更新子订单状态
-		if err := tx.Model(&ChildOrderDO{}).Where("parentsid = ?", orderID).Update("status", childOrderStatus).Error; err != nil {
-			return fmt.Errorf("更新子订单状态失败: %w", err)
+	// 活动ID -> 优惠合计
+	activityTotals := make(map[int]money.Money)
+	detailDOs := make([]OrdersActivityDetailDO, 0, len(activityDiscounts))
+	for _, d := range activityDiscounts {
+		childOrderID, ok := childOrderIDs[d.GoodsID]
+		if !ok {
+			return fmt.Errorf("活动 %d 的优惠商品 %d 不在订单中", d.ActivityID, d.GoodsID)
 		}
+		activityTotals[d.ActivityID] = activityTotals[d.ActivityID].Add(d.DiscountAmount)
+		detailDOs = append(detailDOs, OrdersActivityDetailDO{
+			OrdersID:       orderID,
+			ActivityID:     d.ActivityID,
+			ChildOrderID:   childOrderID,
+			GoodsID:        d.GoodsID,
+			DiscountAmount: d.DiscountAmount,
+		})
+	}
 
-		return nil
-	})
+	activityDOs := make([]OrdersActivityDO, 0, len(activityIDs))
+	for _, activityID := range activityIDs {
+		activityDOs = append(activityDOs, OrdersActivityDO{
+			OrdersID:       orderID,
+			ActivityID:     activityID,
+			DiscountAmount: activityTotals[activityID],
+		})
+	}
+	if err := tx.Create(&activityDOs).Error; err != nil {
+		return fmt.Errorf("创建订单活动关联失败: %w", err)
+	}
+
+	if len(detailDOs) > 0 {
+		if err := tx.Create(&detailDOs).Error; err != nil {
+			return fmt.Errorf("创建订单活动优惠明细失败: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetOrderActivityDiscounts 获取订单的活动优惠明细
+func (r *GormOrderRepository) GetOrderActivityDiscounts(ctx context.Context, orderID int) ([]*entity.OrderActivityDiscount, error) {
+	var detailDOs []OrdersActivityDetailDO
+	err := r.db.WithContext(ctx).
+		Where("orders_id = ?", orderID).
+		Order("activity_id ASC, childorder_id ASC").
+		Find(&detailDOs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	discounts := make([]*entity.OrderActivityDiscount, 0, len(detailDOs))
+	for _, d := range detailDOs {
+		discounts = append(discounts, &entity.OrderActivityDiscount{
+			ID:             d.ID,
+			OrdersID:       d.OrdersID,
+			ActivityID:     d.ActivityID,
+			ChildOrderID:   d.ChildOrderID,
+			GoodsID:        d.GoodsID,
+			DiscountAmount: d.DiscountAmount,
+		})
+	}
+
+	return discounts, nil
+}
+
+// GetOrderStatusHistory 获取订单状态变更历史（按时间先后）
+func (r *GormOrderRepository) GetOrderStatusHistory(ctx context.Context, orderID int) ([]*entity.OrderStatusHistory, error) {
+	var historyDOs []OrderStatusHistoryDO
+	err := r.db.WithContext(ctx).
+		Where("order_id = ?", orderID).
+		Order("id ASC").
+		Find(&historyDOs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	histories := make([]*entity.OrderStatusHistory, 0, len(historyDOs))
+	for _, h := range historyDOs {
+		histories = append(histories, &entity.OrderStatusHistory{
+			ID:         h.ID,
+			OrderID:    h.OrderID,
+			FromStatus: h.FromStatus,
+			ToStatus:   h.ToStatus,
+			Operator:   h.Operator,
+			Reason:     h.Reason,
+			CreateTime: h.CreateTime,
+		})
+	}
+
+	return histories, nil
 }
 
 // GetOrderGoods 获取订单商品列表（关联商品、品牌、分类、属性）
@@ -221,7 +455,8 @@ func (r *GormOrderRepository) GetOrderGoods(ctx context.Context, orderID int) ([
 			b.name AS brand_name,
 			cl.name AS classify_name,
 			c.amount_receivable,
-			c.amount_received
+			c.amount_received,
+			c.gift_activity_id
 		`).
 		Joins("JOIN goods g ON c.goodsid = g.id").
 		Joins("LEFT JOIN brand b ON g.brandid = b.id").
@@ -283,9 +518,14 @@ func (r *GormOrderRepository) DeleteOrderChildOrders(ctx context.Context, orderI
 	return r.db.WithContext(ctx).Where("parentsid = ?", orderID).Delete(&ChildOrderDO{}).Error
 }
 
-// DeleteOrderActivities 删除订单的所有活动关联
+// DeleteOrderActivities 删除订单的所有活动关联（含活动优惠明细）
 func (r *GormOrderRepository) DeleteOrderActivities(ctx context.Context, orderID int) error {
-	return r.db.WithContext(ctx).Where("orders_id = ?", orderID).Delete(&OrdersActivityDO{}).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("orders_id = ?", orderID).Delete(&OrdersActivityDetailDO{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("orders_id = ?", orderID).Delete(&OrdersActivityDO{}).Error
+	})
 }
 
 // GetUnpaidOrdersByStudentID 获取学生的未付款订单列表
@@ -303,6 +543,15 @@ func (r *GormOrderRepository) GetUnpaidOrdersByStudentID(ctx context.Context, st
 		return nil, err
 	}
 
+	orderIDs := make([]int, 0, len(orders))
+	for _, do := range orders {
+		orderIDs = append(orderIDs, do.ID)
+	}
+	instalments, err := r.getInstalmentsByOrderIDs(ctx, orderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("查询订单分期失败: %w", err)
+	}
+
 	// 转换为领域实体
 	result := make([]*entity.Order, 0, len(orders))
 	for _, do := range orders {
@@ -314,7 +563,9 @@ func (r *GormOrderRepository) GetUnpaidOrdersByStudentID(ctx context.Context, st
 			AmountReceived:      do.AmountReceived,
 			DiscountAmount:      do.DiscountAmount,
 			Status:              do.Status,
+			AllocationStrategy:  do.AllocationStrategy,
 			CreateTime:          do.CreateTime,
+			Instalments:         instalments[do.ID],
 		})
 	}
 
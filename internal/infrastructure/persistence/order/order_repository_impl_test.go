@@ -0,0 +1,91 @@
+package order
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"charonoms/internal/domain/order/repository"
+	"charonoms/internal/domain/shared/money"
+)
+
+func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm db: %v", err)
+	}
+
+	return gormDB, mock
+}
+
+// TestGormOrderRepository_GetOrders 筛选条件同时作用于总数和分页查询，排序相同时按订单ID稳定分页
+func TestGormOrderRepository_GetOrders(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewOrderRepository(db)
+
+	studentID, status, activityID := 35, 20, 500
+	createStart, createEnd := "2026-09-01", "2026-09-30"
+	amountMin := money.FromFloat(100)
+
+	where := "WHERE o.student_id = \\? AND o.status = \\? AND o.create_time >= \\? AND o.create_time < DATE_ADD\\(\\?, INTERVAL 1 DAY\\) " +
+		"AND o.amount_received >= \\? AND \\(EXISTS \\(SELECT 1 FROM orders_activity oa WHERE oa.orders_id = o.id AND oa.activity_id = \\?\\)\\)"
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM orders o JOIN student s ON o.student_id = s.id "+where).
+		WithArgs(studentID, status, createStart, createEnd, "100.00", activityID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(25))
+	mock.ExpectQuery("SELECT .*o.id,.*FROM orders o JOIN student s ON o.student_id = s.id "+where+
+		" ORDER BY o.amount_received DESC, o.id DESC LIMIT \\? OFFSET \\?").
+		WithArgs(studentID, status, createStart, createEnd, "100.00", activityID, 10, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "student_name", "status"}).AddRow(11, 35, "张三", 20))
+
+	orders, total, err := repo.GetOrders(context.Background(), repository.OrderListFilter{
+		StudentID:       &studentID,
+		Status:          &status,
+		CreateDateStart: &createStart,
+		CreateDateEnd:   &createEnd,
+		AmountMin:       &amountMin,
+		ActivityID:      &activityID,
+		SortBy:          repository.OrderSortAmountReceived,
+		SortDesc:        true,
+		Page:            2,
+		PageSize:        10,
+	})
+	if err != nil {
+		t.Fatalf("GetOrders() error = %v", err)
+	}
+	if total != 25 || len(orders) != 1 {
+		t.Errorf("GetOrders() total = %d len = %d, want 25 and 1", total, len(orders))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestGormOrderRepository_GetOrders_DefaultSort 未指定或不支持的排序字段按创建时间升序
+func TestGormOrderRepository_GetOrders_DefaultSort(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewOrderRepository(db)
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM orders o JOIN student s ON o.student_id = s.id$").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("ORDER BY o.create_time ASC, o.id ASC LIMIT \\?$").
+		WithArgs(20).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if _, _, err := repo.GetOrders(context.Background(), repository.OrderListFilter{SortBy: "unknown", Page: 1, PageSize: 20}); err != nil {
+		t.Fatalf("GetOrders() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
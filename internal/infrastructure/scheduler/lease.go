@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Lease 基于数据库的任务租约（scheduler_lease 表），多个服务实例竞争同一任务时只有持有租约的实例执行
+// 租约到期时间使用数据库时间，避免各实例时钟不一致；持有者在到期前续约，实例宕机后租约到期由其他实例接管
+type Lease struct {
+	db    *gorm.DB
+	owner string
+	ttl   time.Duration
+}
+
+// NewLease 创建任务租约，owner 为当前实例标识
+func NewLease(db *gorm.DB, owner string, ttl time.Duration) *Lease {
+	return &Lease{db: db, owner: owner, ttl: ttl}
+}
+
+// DefaultOwner 生成当前实例标识：主机名-进程号
+func DefaultOwner() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// TryAcquire 尝试获取或续约指定任务的租约，租约空闲、已到期或本实例已持有时返回 true
+func (l *Lease) TryAcquire(ctx context.Context, name string) (bool, error) {
+	db := l.db.WithContext(ctx)
+
+	// 首次使用的任务先插入一条已到期的租约记录
+	if err := db.Exec(`
+		INSERT IGNORE INTO scheduler_lease (name, owner, expires_at)
+		VALUES (?, '', NOW())
+	`, name).Error; err != nil {
+		return false, fmt.Errorf("初始化任务租约失败: %w", err)
+	}
+
+	result := db.Exec(`
+		UPDATE scheduler_lease
+		SET owner = ?, expires_at = DATE_ADD(NOW(), INTERVAL ? SECOND)
+		WHERE name = ? AND (owner = ? OR expires_at < NOW())
+	`, l.owner, int(l.ttl/time.Second), name, l.owner)
+	if result.Error != nil {
+		return false, fmt.Errorf("获取任务租约失败: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Release 释放本实例持有的租约，其他实例下一轮即可接管
+func (l *Lease) Release(ctx context.Context, name string) error {
+	return l.db.WithContext(ctx).Exec(`
+		UPDATE scheduler_lease
+		SET expires_at = NOW()
+		WHERE name = ? AND owner = ?
+	`, name, l.owner).Error
+}
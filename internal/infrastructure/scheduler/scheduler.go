@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"charonoms/internal/infrastructure/logger"
+)
+
+// JobFunc 后台任务
+type JobFunc func(ctx context.Context) error
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      JobFunc
+}
+
+// Scheduler 进程内后台任务调度器，每个任务按固定间隔执行，执行前先获取数据库租约，
+// 多实例部署时同一任务同一时刻只在一个实例上执行
+type Scheduler struct {
+	lease *Lease
+	jobs  []job
+}
+
+// New 创建后台任务调度器
+func New(lease *Lease) *Scheduler {
+	return &Scheduler{lease: lease}
+}
+
+// Register 注册后台任务，需在 Start 之前调用
+func (s *Scheduler) Register(name string, interval time.Duration, run JobFunc) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: run})
+}
+
+// Start 为每个任务启动一个执行协程，ctx 取消后停止调度并释放租约
+// 进程退出前未释放的租约在到期后由其他实例接管
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, j := range s.jobs {
+		go s.loop(ctx, j)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, j job) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// 使用独立的上下文释放租约，ctx 已取消
+			releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := s.lease.Release(releaseCtx, j.name); err != nil {
+				logger.Warn("Failed to release scheduler lease", zap.String("job", j.name), zap.Error(err))
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			s.runOnce(ctx, j)
+		}
+	}
+}
+
+// runOnce 获取租约后执行一次任务，任务 panic 不影响后续调度
+func (s *Scheduler) runOnce(ctx context.Context, j job) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Scheduler job panicked", zap.String("job", j.name), zap.Any("panic", r))
+		}
+	}()
+
+	acquired, err := s.lease.TryAcquire(ctx, j.name)
+	if err != nil {
+		logger.Error("Failed to acquire scheduler lease", zap.String("job", j.name), zap.Error(err))
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	if err := j.run(ctx); err != nil {
+		logger.Error("Scheduler job failed", zap.String("job", j.name), zap.Error(err))
+	}
+}
@@ -0,0 +1,74 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"charonoms/internal/domain/financial/reconciliation"
+)
+
+// alipayBizTrade 支付宝账单中收款交易的业务类型，退款等其他业务类型不参与对账
+const alipayBizTrade = "交易"
+
+// AlipayParser 支付宝商家中心下载的业务明细账单（CSV，默认 GBK 编码）
+// # 开头的行为账单说明和汇总，表头和明细之间没有其他内容
+type AlipayParser struct{}
+
+// Channel 渠道编码
+func (p *AlipayParser) Channel() string {
+	return reconciliation.ChannelAlipay
+}
+
+// Parse 解析业务明细账单，只保留业务类型为“交易”的明细
+func (p *AlipayParser) Parse(r io.Reader) ([]reconciliation.StatementLine, error) {
+	all, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	// 去掉说明行，保留原始行号
+	var rows [][]string
+	var rowNos []int
+	for i, row := range all {
+		if len(row) > 0 && strings.HasPrefix(strings.TrimSpace(row[0]), "#") {
+			continue
+		}
+		rows = append(rows, row)
+		rowNos = append(rowNos, i+1)
+	}
+
+	headerRow, cols, err := findHeader(rows, 5, "支付宝交易号", "商户订单号", "业务类型", "完成时间", "订单金额（元）")
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []reconciliation.StatementLine
+	for i := headerRow + 1; i < len(rows); i++ {
+		row := rows[i]
+		if len(row) == 0 || cleanCell(row[0]) == "" {
+			continue
+		}
+		if cols.get(row, "业务类型") != alipayBizTrade {
+			continue
+		}
+
+		lineNo := rowNos[i]
+		amount, err := parseAmount(cols.get(row, "订单金额（元）"))
+		if err != nil {
+			return nil, fmt.Errorf("第%d行：订单金额格式不正确", lineNo)
+		}
+		tradeTime, err := parseTime(cols.get(row, "完成时间"))
+		if err != nil {
+			return nil, fmt.Errorf("第%d行：%v", lineNo, err)
+		}
+		lines = append(lines, reconciliation.StatementLine{
+			LineNo:        lineNo,
+			MerchantOrder: cols.get(row, "商户订单号"),
+			TradeNo:       cols.get(row, "支付宝交易号"),
+			Amount:        amount,
+			TradeTime:     tradeTime,
+			Payer:         cols.get(row, "对方账户"),
+		})
+	}
+	return lines, nil
+}
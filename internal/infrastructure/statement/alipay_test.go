@@ -0,0 +1,21 @@
+package statement
+
+import (
+	"testing"
+
+	"charonoms/internal/domain/financial/reconciliation"
+)
+
+func TestAlipayParser_Parse(t *testing.T) {
+	// 账单为 GBK 编码，交易号和订单号以 ="..." 包裹
+	lines, err := (&AlipayParser{}).Parse(openFixture(t, "alipay.csv"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	// 退款明细跳过；# 开头的说明和合计行不参与解析，行号保留原始位置
+	assertLines(t, lines, []reconciliation.StatementLine{
+		line(6, "AL202610170001", "2026101722001400001001", 800, "2026-10-17 09:00:12", "张三(zha***@163.com)"),
+		line(8, "AL202610170003", "2026101722001400001003", 1500, "2026-10-17 14:02:30", "李四(138****0000)"),
+	})
+}
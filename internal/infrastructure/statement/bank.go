@@ -0,0 +1,114 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"charonoms/internal/domain/financial/reconciliation"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 各银行流水中的同义列名，按顺序取第一个存在的列
+var (
+	bankCreditColumns  = []string{"贷方金额", "贷方发生额", "收入金额", "收入"}
+	bankPayerColumns   = []string{"对方户名", "对方账户名称", "付款人名称"}
+	bankTradeNoColumns = []string{"交易流水号", "流水号", "凭证号"}
+)
+
+// bankSummaryPrefixes 明细之后的汇总行在交易日期列中的标记
+var bankSummaryPrefixes = []string{"合计", "本页合计", "总计"}
+
+// BankParser 对公账户网银导出的交易流水（Excel）
+// 表头之前通常有账号、户名等说明行，明细之后可能有合计行；只有贷方（收入）金额大于 0 的行参与对账
+// 银行流水一般没有商户订单号，如有“商户订单号”列则一并读取
+type BankParser struct{}
+
+// Channel 渠道编码
+func (p *BankParser) Channel() string {
+	return reconciliation.ChannelBank
+}
+
+// Parse 解析交易流水的第一个工作表
+func (p *BankParser) Parse(r io.Reader) ([]reconciliation.StatementLine, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("读取Excel账单失败: %w", err)
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows(f.GetSheetName(0))
+	if err != nil {
+		return nil, fmt.Errorf("读取Excel账单失败: %w", err)
+	}
+	headerRow, cols, err := findHeader(rows, 20, "交易日期")
+	if err != nil {
+		return nil, err
+	}
+	creditCol := cols.first(bankCreditColumns...)
+	if creditCol == "" {
+		return nil, fmt.Errorf("账单格式不正确，未找到收入金额列（%v）", bankCreditColumns)
+	}
+	payerCol := cols.first(bankPayerColumns...)
+	tradeNoCol := cols.first(bankTradeNoColumns...)
+
+	var lines []reconciliation.StatementLine
+	for i := headerRow + 1; i < len(rows); i++ {
+		row := rows[i]
+		if isBankSummary(cols.get(row, "交易日期")) {
+			break
+		}
+		credit := cols.get(row, creditCol)
+		if credit == "" {
+			continue
+		}
+
+		lineNo := i + 1
+		amount, err := parseAmount(credit)
+		if err != nil {
+			return nil, fmt.Errorf("第%d行：收入金额格式不正确", lineNo)
+		}
+		if !amount.IsPositive() {
+			continue
+		}
+		// 交易时间单独一列时与交易日期拼接
+		tradeTimeStr := cols.get(row, "交易日期")
+		if clock := cols.get(row, "交易时间"); len(clock) == len("15:04:05") {
+			tradeTimeStr += " " + clock
+		}
+		tradeTime, err := parseTime(tradeTimeStr)
+		if err != nil {
+			return nil, fmt.Errorf("第%d行：%v", lineNo, err)
+		}
+		lines = append(lines, reconciliation.StatementLine{
+			LineNo:        lineNo,
+			MerchantOrder: cols.get(row, "商户订单号"),
+			TradeNo:       cols.get(row, tradeNoCol),
+			Amount:        amount,
+			TradeTime:     tradeTime,
+			Payer:         cols.get(row, payerCol),
+		})
+	}
+	return lines, nil
+}
+
+// first 返回第一个存在的列名，都不存在时返回空字符串
+func (c columns) first(names ...string) string {
+	for _, name := range names {
+		if _, ok := c[name]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// isBankSummary 判断是否为明细之后的汇总行
+func isBankSummary(tradeDate string) bool {
+	for _, prefix := range bankSummaryPrefixes {
+		if strings.HasPrefix(tradeDate, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package statement
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+
+	"charonoms/internal/domain/financial/reconciliation"
+)
+
+// bankFixture 生成网银导出的交易流水：表头前有账户说明行，日期和时间分列，明细后有合计行
+func bankFixture(t *testing.T, rows [][]interface{}) *bytes.Reader {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := f.GetSheetName(0)
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			t.Fatalf("SetSheetRow() error = %v", err)
+		}
+	}
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatalf("WriteToBuffer() error = %v", err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestBankParser_Parse(t *testing.T) {
+	r := bankFixture(t, [][]interface{}{
+		{"账号：6222020200112233445"},
+		{"户名：某某教育科技有限公司"},
+		{"起止日期：20261017-20261017"},
+		{"交易日期", "交易时间", "借方发生额", "贷方发生额", "余额", "对方账户名称", "流水号", "摘要"},
+		{"20261017", "09:30:15", "", "5,000.00", "105000.00", "张三", "`B202610170001", "学费"},
+		{"20261017", "10:00:00", "200.00", "", "104800.00", "某某物业", "B202610170002", "物业费"},
+		{"20261017", "11:15:00", "", "0.00", "104800.00", "银行", "B202610170003", "冲正"},
+		{"20261017", "14:20:30", "", "￥1,200.50", "106000.50", "李四", `="B202610170004"`, "学费"},
+		{"合计", "", "200.00", "6200.50", "", "", "", ""},
+		{"20261018", "bad", "", "1.00", "", "", "", ""},
+	})
+
+	lines, err := (&BankParser{}).Parse(r)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	// 借方和零金额行跳过；日期和时间分列拼接为 YYYYMMDD HH:MM:SS；遇到合计行停止
+	assertLines(t, lines, []reconciliation.StatementLine{
+		line(5, "", "B202610170001", 5000, "2026-10-17 09:30:15", "张三"),
+		line(8, "", "B202610170004", 1200.50, "2026-10-17 14:20:30", "李四"),
+	})
+}
+
+func TestBankParser_Parse_DateOnly(t *testing.T) {
+	r := bankFixture(t, [][]interface{}{
+		{"交易日期", "收入金额", "付款人名称", "凭证号", "商户订单号"},
+		{"2026/10/17 09:30", "300", "王五", "V001", "OF202610170001"},
+	})
+
+	lines, err := (&BankParser{}).Parse(r)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	assertLines(t, lines, []reconciliation.StatementLine{
+		line(2, "OF202610170001", "V001", 300, "2026-10-17 09:30:00", "王五"),
+	})
+}
+
+func TestBankParser_Parse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		rows [][]interface{}
+		want string
+	}{
+		{"缺少收入金额列", [][]interface{}{{"交易日期", "借方发生额"}}, "未找到收入金额列"},
+		{"缺少表头", [][]interface{}{{"日期", "金额"}}, "未找到表头"},
+		{"金额格式不正确", [][]interface{}{{"交易日期", "贷方金额"}, {"20261017", "abc"}}, "第2行"},
+		{"时间格式不正确", [][]interface{}{{"交易日期", "贷方金额"}, {"17.10.2026", "1.00"}}, "第2行"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&BankParser{}).Parse(bankFixture(t, tt.rows))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"charonoms/internal/domain/financial/reconciliation"
+	"charonoms/internal/domain/shared/money"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// Parsers 返回全部渠道的账单解析器
+func Parsers() []reconciliation.StatementParser {
+	return []reconciliation.StatementParser{
+		&WechatParser{},
+		&AlipayParser{},
+		&BankParser{},
+	}
+}
+
+// timeLayouts 账单中常见的时间格式
+var timeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/1/2 15:04:05",
+	"2006/1/2 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"2006/1/2",
+	"20060102 15:04:05", // 银行流水中日期和时间分列时拼接而成
+	"20060102",
+	"01-02-06", // Excel 未设置格式的日期单元格
+}
+
+// readCSV 读取 CSV 账单，非 UTF-8 编码的文件按 GBK 解码（支付宝等渠道导出的账单默认为 GBK）
+func readCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("读取账单文件失败: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		if data, err = simplifiedchinese.GBK.NewDecoder().Bytes(data); err != nil {
+			return nil, fmt.Errorf("账单文件编码无法识别: %w", err)
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析CSV账单失败: %w", err)
+	}
+	return rows, nil
+}
+
+// columns 账单表头中各列的位置
+type columns map[string]int
+
+// findHeader 在前 maxScan 行中查找包含全部必需列的表头行，返回表头行号和列位置
+func findHeader(rows [][]string, maxScan int, required ...string) (int, columns, error) {
+	for i := 0; i < len(rows) && i < maxScan; i++ {
+		cols := make(columns)
+		for j, cell := range rows[i] {
+			cols[cleanCell(cell)] = j
+		}
+		found := true
+		for _, name := range required {
+			if _, ok := cols[name]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			return i, cols, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("账单格式不正确，未找到表头（需包含：%s）", strings.Join(required, "、"))
+}
+
+// get 读取指定列的值，列不存在或越界时返回空字符串
+func (c columns) get(row []string, name string) string {
+	i, ok := c[name]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return cleanCell(row[i])
+}
+
+// cleanCell 去除单元格中的空白，以及渠道为防止科学计数法加上的 ` 前缀和 ="..." 包裹
+func cleanCell(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "`")
+	if len(s) >= 3 && strings.HasPrefix(s, "=\"") && strings.HasSuffix(s, "\"") {
+		s = s[2 : len(s)-1]
+	}
+	return strings.TrimSpace(s)
+}
+
+// parseAmount 解析账单金额，允许千分位逗号和货币符号
+func parseAmount(s string) (money.Money, error) {
+	s = strings.NewReplacer(",", "", "¥", "", "￥", "").Replace(s)
+	return money.Parse(s)
+}
+
+// parseTime 按常见格式解析账单中的时间，使用本地时区
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法识别的时间格式: %s", s)
+}
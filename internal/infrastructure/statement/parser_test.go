@@ -0,0 +1,97 @@
+package statement
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"charonoms/internal/domain/financial/reconciliation"
+	"charonoms/internal/domain/shared/money"
+)
+
+func openFixture(t *testing.T, name string) *os.File {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatalf("open fixture %s: %v", name, err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func at(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func line(lineNo int, merchantOrder, tradeNo string, amount float64, tradeTime, payer string) reconciliation.StatementLine {
+	return reconciliation.StatementLine{
+		LineNo:        lineNo,
+		MerchantOrder: merchantOrder,
+		TradeNo:       tradeNo,
+		Amount:        money.FromFloat(amount),
+		TradeTime:     at(tradeTime),
+		Payer:         payer,
+	}
+}
+
+func assertLines(t *testing.T, got, want []reconciliation.StatementLine) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("len(lines) = %d, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("lines[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCleanCell(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"`4200001234202610170001", "4200001234202610170001"},
+		{`="2026101722001400001001"`, "2026101722001400001001"},
+		{"  ` SUCCESS ", "SUCCESS"},
+		{`="`, `="`},
+		{"交易", "交易"},
+	}
+	for _, tt := range tests {
+		if got := cleanCell(tt.in); got != tt.want {
+			t.Errorf("cleanCell(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"2026-10-17 09:30:15", "2026-10-17 09:30:15"},
+		{"2026/10/17 09:30:15", "2026-10-17 09:30:15"},
+		{"2026/1/5 09:30", "2026-01-05 09:30:00"},
+		{"20261017 09:30:15", "2026-10-17 09:30:15"},
+		{"20261017", "2026-10-17 00:00:00"},
+		{"10-17-26", "2026-10-17 00:00:00"},
+	}
+	for _, tt := range tests {
+		got, err := parseTime(tt.in)
+		if err != nil {
+			t.Errorf("parseTime(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(at(tt.want)) {
+			t.Errorf("parseTime(%q) = %v, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := parseTime("17.10.2026"); err == nil {
+		t.Error("parseTime(17.10.2026) error = nil, want error")
+	}
+}
@@ -0,0 +1,65 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"charonoms/internal/domain/financial/reconciliation"
+)
+
+// wechatTradeSuccess 微信账单中支付成功的交易状态
+const wechatTradeSuccess = "SUCCESS"
+
+// WechatParser 微信支付商户平台下载的交易账单（CSV）
+// 明细行的值带 ` 前缀，明细之后是“总交易单数”开头的汇总行
+type WechatParser struct{}
+
+// Channel 渠道编码
+func (p *WechatParser) Channel() string {
+	return reconciliation.ChannelWechat
+}
+
+// Parse 解析交易账单，只保留交易状态为 SUCCESS 的明细
+func (p *WechatParser) Parse(r io.Reader) ([]reconciliation.StatementLine, error) {
+	rows, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	headerRow, cols, err := findHeader(rows, 5, "交易时间", "微信订单号", "商户订单号", "交易状态", "订单金额")
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []reconciliation.StatementLine
+	for i := headerRow + 1; i < len(rows); i++ {
+		row := rows[i]
+		if len(row) == 0 || cleanCell(row[0]) == "" {
+			continue
+		}
+		if strings.HasPrefix(cleanCell(row[0]), "总交易单数") {
+			break
+		}
+		if cols.get(row, "交易状态") != wechatTradeSuccess {
+			continue
+		}
+
+		lineNo := i + 1
+		amount, err := parseAmount(cols.get(row, "订单金额"))
+		if err != nil {
+			return nil, fmt.Errorf("第%d行：订单金额格式不正确", lineNo)
+		}
+		tradeTime, err := parseTime(cols.get(row, "交易时间"))
+		if err != nil {
+			return nil, fmt.Errorf("第%d行：%v", lineNo, err)
+		}
+		lines = append(lines, reconciliation.StatementLine{
+			LineNo:        lineNo,
+			MerchantOrder: cols.get(row, "商户订单号"),
+			TradeNo:       cols.get(row, "微信订单号"),
+			Amount:        amount,
+			TradeTime:     tradeTime,
+		})
+	}
+	return lines, nil
+}
@@ -0,0 +1,35 @@
+package statement
+
+import (
+	"strings"
+	"testing"
+
+	"charonoms/internal/domain/financial/reconciliation"
+)
+
+func TestWechatParser_Parse(t *testing.T) {
+	lines, err := (&WechatParser{}).Parse(openFixture(t, "wechat.csv"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	// 去掉 ` 前缀；REFUND 的明细跳过；遇到“总交易单数”汇总行停止
+	assertLines(t, lines, []reconciliation.StatementLine{
+		line(2, "OL202610170001", "4200001234202610170001", 1000, "2026-10-17 09:30:15", ""),
+		line(4, "OL202610170003", "4200001234202610170003", 1234.50, "2026-10-17 11:20:45", ""),
+	})
+}
+
+func TestWechatParser_Parse_InvalidAmount(t *testing.T) {
+	csv := "交易时间,微信订单号,商户订单号,交易状态,订单金额\n" +
+		"`2026-10-17 09:30:15,`4200001234202610170001,`OL202610170001,`SUCCESS,`abc\n"
+	if _, err := (&WechatParser{}).Parse(strings.NewReader(csv)); err == nil || !strings.Contains(err.Error(), "第2行") {
+		t.Errorf("Parse() error = %v, want 第2行 amount error", err)
+	}
+}
+
+func TestWechatParser_Parse_MissingHeader(t *testing.T) {
+	if _, err := (&WechatParser{}).Parse(strings.NewReader("交易时间,订单金额\n")); err == nil {
+		t.Error("Parse() error = nil, want header error")
+	}
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"charonoms/internal/infrastructure/config"
+)
+
+// ErrNotFound 文件不存在
+var ErrNotFound = errors.New("文件不存在")
+
+// Storage 文件存储，业务只保存存储键，具体存储位置由实现决定（本地磁盘、对象存储等）
+type Storage interface {
+	// Save 保存文件内容到指定键，返回写入的字节数
+	Save(key string, r io.Reader) (int64, error)
+	// Open 打开指定键的文件，文件不存在时返回 ErrNotFound
+	Open(key string) (io.ReadCloser, error)
+	// Delete 删除指定键的文件，文件不存在时不报错
+	Delete(key string) error
+}
+
+// New 按配置创建文件存储
+func New(cfg config.StorageConfig) (Storage, error) {
+	switch cfg.Type {
+	case "", "local":
+		return NewLocalStorage(cfg.LocalDir)
+	default:
+		return nil, fmt.Errorf("不支持的文件存储类型: %s", cfg.Type)
+	}
+}
@@ -71,6 +71,7 @@ type ActivityDetailDTO struct {
 	ActivityID      int     `json:"activity_id,omitempty"`
 	ThresholdAmount float64 `json:"threshold_amount"`
 	DiscountValue   float64 `json:"discount_value"`
+	GiftGoodsID     *int    `json:"gift_goods_id,omitempty"`
 }
 
 // ActivityDTO 活动响应
@@ -7,6 +7,8 @@ type CreateActivityTemplateDTO struct {
 	Name        string `json:"name" binding:"required"`
 	Type        int    `json:"type" binding:"required"`
 	SelectType  int    `json:"select_type" binding:"required"`
+	StackMode   int    `json:"stack_mode"`
+	StackGroup  string `json:"stack_group"`
 	ClassifyIDs []int  `json:"classify_ids"`
 	GoodsIDs    []int  `json:"goods_ids"`
 	Status      int    `json:"status"`
@@ -17,6 +19,8 @@ type UpdateActivityTemplateDTO struct {
 	Name        string `json:"name" binding:"required"`
 	Type        int    `json:"type" binding:"required"`
 	SelectType  int    `json:"select_type" binding:"required"`
+	StackMode   int    `json:"stack_mode"`
+	StackGroup  string `json:"stack_group"`
 	ClassifyIDs []int  `json:"classify_ids"`
 	GoodsIDs    []int  `json:"goods_ids"`
 	Status      int    `json:"status"`
@@ -33,6 +37,8 @@ type ActivityTemplateDTO struct {
 	Name       string    `json:"name"`
 	Type       int       `json:"type"`
 	SelectType int       `json:"select_type"`
+	StackMode  int       `json:"stack_mode"`
+	StackGroup string    `json:"stack_group"`
 	Status     int       `json:"status"`
 	CreateTime time.Time `json:"create_time"`
 	UpdateTime time.Time `json:"update_time"`
@@ -59,6 +65,8 @@ type ActivityTemplateDetailDTO struct {
 	Name         string                `json:"name"`
 	Type         int                   `json:"type"`
 	SelectType   int                   `json:"select_type"`
+	StackMode    int                   `json:"stack_mode"`
+	StackGroup   string                `json:"stack_group"`
 	Status       int                   `json:"status"`
 	CreateTime   time.Time             `json:"create_time"`
 	UpdateTime   time.Time             `json:"update_time"`
@@ -1,6 +1,10 @@
 package approval
 
-import "time"
+import (
+	"charonoms/internal/domain/approval/entity"
+	"charonoms/internal/interfaces/http/dto"
+	"time"
+)
 
 // ApprovalFlowTypeListRequest 审批流类型列表请求
 type ApprovalFlowTypeListRequest struct {
@@ -11,6 +15,7 @@ type ApprovalFlowTypeListRequest struct {
 
 // ApprovalFlowTypeCreateRequest 审批流类型创建请求
 type ApprovalFlowTypeCreateRequest struct {
+	Code   string `json:"code"` // 稳定编码，业务审批按编码选择模板（可选）
 	Name   string `json:"name" binding:"required"`
 	Status int8   `json:"status"`
 }
@@ -30,19 +35,41 @@ type ApprovalFlowTemplateListRequest struct {
 
 // ApprovalFlowTemplateNode 审批流模板节点
 type ApprovalFlowTemplateNode struct {
-	Name      string `json:"name"`
-	Type      int8   `json:"type"` // 0=会签，1=或签
-	Approvers []int  `json:"approvers"`
+	Name             string                `json:"name"`
+	Type             int8                  `json:"type"`          // 0=会签，1=或签
+	ApproverType     int8                  `json:"approver_type"` // 审批人来源：0=指定人员，1=角色，2=发起人上级，3=解析器
+	Approvers        []int                 `json:"approvers"`     // 指定人员（approver_type=0）
+	ApproverRoleID   int                   `json:"approver_role_id"`
+	ApproverResolver string                `json:"approver_resolver"`
+	Conditions       entity.NodeConditions `json:"conditions"`    // 进入节点的条件（全部满足才进入），为空表示无条件进入
+	SLAHours         int                   `json:"sla_hours"`     // 处理时限（小时），0=不限时
+	SLAAction        int8                  `json:"sla_action"`    // 超时处理：0=仅提醒，1=升级给备用审批人，2=自动通过，3=自动驳回
+	SLABackupID      *int                  `json:"sla_backup_id"` // 备用审批人（sla_action=1）
 }
 
 // CreateApprovalFlowTemplateRequest 创建审批流模板请求
 type CreateApprovalFlowTemplateRequest struct {
 	Name               string                     `json:"name"`
 	ApprovalFlowTypeID int                        `json:"approval_flow_type_id"`
+	SelectionRules     entity.NodeConditions      `json:"selection_rules"` // 适用条件（全部满足才选用），为空表示适用全部
 	Nodes              []ApprovalFlowTemplateNode `json:"nodes"`
 	CopyUsers          []int                      `json:"copy_users"`
 }
 
+// UpdateApprovalFlowTemplateRequest 编辑审批流模板请求（保存为新版本，审批流类型沿用原模板）
+type UpdateApprovalFlowTemplateRequest struct {
+	Name           string                     `json:"name"`
+	SelectionRules entity.NodeConditions      `json:"selection_rules"`
+	Nodes          []ApprovalFlowTemplateNode `json:"nodes"`
+	CopyUsers      []int                      `json:"copy_users"`
+}
+
+// CompareTemplateVersionsRequest 模板版本比较请求
+type CompareTemplateVersionsRequest struct {
+	From int `form:"from" binding:"required"`
+	To   int `form:"to" binding:"required"`
+}
+
 // ApprovalFlowTemplateStatusRequest 审批流模板状态更新请求
 type ApprovalFlowTemplateStatusRequest struct {
 	Status int8 `json:"status"`
@@ -85,17 +112,46 @@ type CreateFromTemplateRequest struct {
 
 // ApproveRequest 审批通过请求
 type ApproveRequest struct {
-	NodeCaseUserID int `json:"node_case_user_id" binding:"required"`
+	NodeCaseUserID int    `json:"node_case_user_id" binding:"required"`
+	Comment        string `json:"comment"`        // 审批意见（可选）
+	AttachmentIDs  []int  `json:"attachment_ids"` // 预先上传的附件ID
 }
 
 // RejectRequest 审批驳回请求
 type RejectRequest struct {
-	NodeCaseUserID int `json:"node_case_user_id" binding:"required"`
+	NodeCaseUserID int    `json:"node_case_user_id" binding:"required"`
+	Comment        string `json:"comment"`        // 驳回意见（必填）
+	AttachmentIDs  []int  `json:"attachment_ids"` // 预先上传的附件ID
+}
+
+// TransferRequest 转交请求
+type TransferRequest struct {
+	NodeCaseUserID int    `json:"node_case_user_id" binding:"required"`
+	ToUserID       int    `json:"to_user_id" binding:"required"`
+	Remark         string `json:"remark"`
+}
+
+// AddSignerRequest 加签请求
+type AddSignerRequest struct {
+	NodeCaseUserID int    `json:"node_case_user_id" binding:"required"`
+	Position       string `json:"position" binding:"required"` // before=前加签，after=后加签
+	Type           int8   `json:"type"`                        // 0=会签，1=或签
+	UserIDs        []int  `json:"user_ids" binding:"required"`
+	Remark         string `json:"remark"`
+}
+
+// CreateDelegationRequest 设置外出委托请求
+type CreateDelegationRequest struct {
+	DelegateID int              `json:"delegate_id" binding:"required"`
+	StartTime  dto.FlexibleTime `json:"start_time" binding:"required"`
+	EndTime    dto.FlexibleTime `json:"end_time" binding:"required"` // 只有日期时包含当天
+	Remark     string           `json:"remark"`
 }
 
 // ApprovalFlowTypeResponse 审批流类型响应
 type ApprovalFlowTypeResponse struct {
 	ID         int       `json:"id"`
+	Code       *string   `json:"code"`
 	Name       string    `json:"name"`
 	Status     int8      `json:"status"`
 	CreateTime time.Time `json:"create_time"`
@@ -1,12 +1,18 @@
 package financial
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
 	"charonoms/internal/application/financial"
 	paymentApp "charonoms/internal/application/financial/payment"
+	domainPayment "charonoms/internal/domain/financial/payment"
+	"charonoms/internal/domain/shared/money"
+	"charonoms/internal/infrastructure/logger"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // PaymentHandler 收款接口处理器
@@ -106,7 +112,7 @@ func (h *PaymentHandler) CreatePaymentCollection(c *gin.Context) {
 	}
 
 	// 调用应用服务
-	paymentID, err := h.paymentService.CreatePaymentCollection(&req)
+	paymentID, err := h.paymentService.CreatePaymentCollection(&req, c.GetString("username"))
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"code":    1,
@@ -140,7 +146,7 @@ func (h *PaymentHandler) ConfirmPaymentCollection(c *gin.Context) {
 	}
 
 	// 调用应用服务
-	err = h.paymentService.ConfirmPaymentCollection(id)
+	err = h.paymentService.ConfirmPaymentCollection(id, c.GetString("username"))
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"code":    1,
@@ -172,7 +178,7 @@ func (h *PaymentHandler) DeletePaymentCollection(c *gin.Context) {
 	}
 
 	// 调用应用服务
-	err = h.paymentService.DeletePaymentCollection(id)
+	err = h.paymentService.DeletePaymentCollection(id, c.GetString("username"))
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"code":    1,
@@ -188,3 +194,88 @@ func (h *PaymentHandler) DeletePaymentCollection(c *gin.Context) {
 		"data":    nil,
 	})
 }
+
+// CreateOnlineCharge 发起在线支付
+// POST /api/payment-collections/online
+func (h *PaymentHandler) CreateOnlineCharge(c *gin.Context) {
+	var req financial.CreateOnlineChargeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	// 调用应用服务
+	charge, err := h.paymentService.CreateOnlineCharge(&req)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    1,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "在线支付下单成功",
+		"data":    charge,
+	})
+}
+
+// HandleGatewayNotify 接收支付网关异步通知（无需登录，靠通知签名鉴权）
+// 处理成功返回 success，网关收到其他响应会重试通知
+// POST /api/payment-gateway/notify
+func (h *PaymentHandler) HandleGatewayNotify(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.String(http.StatusBadRequest, "fail")
+		return
+	}
+
+	if err := h.paymentService.HandleGatewayNotification(c.Request.Header, body); err != nil {
+		logger.Warn("处理支付网关通知失败", zap.Error(err))
+		if errors.Is(err, domainPayment.ErrInvalidNotifySignature) {
+			c.String(http.StatusBadRequest, "fail")
+			return
+		}
+		c.String(http.StatusInternalServerError, "fail")
+		return
+	}
+
+	c.String(http.StatusOK, "success")
+}
+
+// SimulateGatewayPayment 模拟支付方完成在线支付（仅本地模拟网关可用）
+// POST /api/payment-gateway/mock/pay?merchant_order=xxx&amount=100.00
+func (h *PaymentHandler) SimulateGatewayPayment(c *gin.Context) {
+	merchantOrder := c.Query("merchant_order")
+	amount, err := money.Parse(c.Query("amount"))
+	if merchantOrder == "" || err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    1,
+			"message": "参数错误: 需要商户订单号和支付金额",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.paymentService.SimulateGatewayPayment(merchantOrder, amount); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    1,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "模拟支付成功",
+		"data":    nil,
+	})
+}
@@ -0,0 +1,133 @@
+package financial
+
+import (
+	"net/http"
+	"strconv"
+
+	"charonoms/internal/application/financial"
+	reconciliationApp "charonoms/internal/application/financial/reconciliation"
+	"github.com/gin-gonic/gin"
+)
+
+// ReconciliationHandler 渠道账单对账接口处理器
+type ReconciliationHandler struct {
+	service *reconciliationApp.ReconciliationService
+}
+
+// NewReconciliationHandler 创建对账接口处理器
+func NewReconciliationHandler(service *reconciliationApp.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{service: service}
+}
+
+// UploadStatement 上传渠道账单并对账
+// POST /api/reconciliations
+// 表单字段：file 账单文件，channel 渠道（wechat/alipay/bank），payee_entity 账单所属收款主体
+func (h *ReconciliationHandler) UploadStatement(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "未上传账单文件", "data": nil})
+		return
+	}
+	channel := c.PostForm("channel")
+	if channel == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "请选择对账渠道", "data": nil})
+		return
+	}
+	payeeEntity, err := strconv.Atoi(c.PostForm("payee_entity"))
+	if err != nil || payeeEntity < 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "无效的收款主体", "data": nil})
+		return
+	}
+
+	operator := 0
+	if userID, exists := c.Get("user_id"); exists {
+		operator = int(userID.(uint))
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "打开文件失败", "data": nil})
+		return
+	}
+	defer file.Close()
+
+	report, err := h.service.Reconcile(channel, payeeEntity, fileHeader.Filename, file, operator)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": err.Error(), "data": nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "对账完成", "data": report})
+}
+
+// GetBatches 获取对账批次列表
+// GET /api/reconciliations
+func (h *ReconciliationHandler) GetBatches(c *gin.Context) {
+	page, pageSize := 1, 20
+	if pageVal, err := strconv.Atoi(c.Query("page")); err == nil && pageVal > 0 {
+		page = pageVal
+	}
+	if pageSizeVal, err := strconv.Atoi(c.Query("page_size")); err == nil && pageSizeVal > 0 {
+		pageSize = pageSizeVal
+	}
+
+	response, err := h.service.ListBatches(c.Query("channel"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": err.Error(), "data": nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": response})
+}
+
+// GetReport 获取对账报告
+// GET /api/reconciliations/:id?result=
+// result：0-已匹配 1-金额不一致 2-账单有系统无 3-系统有账单无，不传返回全部
+func (h *ReconciliationHandler) GetReport(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "无效的对账批次ID", "data": nil})
+		return
+	}
+
+	var result *int
+	if resultStr := c.Query("result"); resultStr != "" {
+		if resultVal, err := strconv.Atoi(resultStr); err == nil {
+			result = &resultVal
+		}
+	}
+
+	report, err := h.service.GetReport(id, result)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": err.Error(), "data": nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": report})
+}
+
+// ConfirmMatched 按对账结果批量确认未核验收款到账
+// POST /api/reconciliations/:id/confirm
+func (h *ReconciliationHandler) ConfirmMatched(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "无效的对账批次ID", "data": nil})
+		return
+	}
+
+	var req financial.ConfirmReconciledRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": 1, "message": "参数错误: " + err.Error(), "data": nil})
+			return
+		}
+	}
+
+	response, err := h.service.ConfirmMatched(id, req.ItemIDs, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": err.Error(), "data": nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "确认完成", "data": response})
+}
@@ -251,3 +251,47 @@ func (h *RefundHandler) CreateRefundOrder(c *gin.Context) {
 		"message":         "退费申请提交成功",
 	})
 }
+
+// ResubmitRefundOrder 修改已驳回或已撤销的退费订单后重新提交
+// POST /api/refund-orders/:id/resubmit
+func (h *RefundHandler) ResubmitRefundOrder(c *gin.Context) {
+	previousRefundID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的退费订单ID"})
+		return
+	}
+
+	var req refund.ResubmitRefundOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未获取到用户信息"})
+		return
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未获取到用户ID"})
+		return
+	}
+
+	refundOrderID, err := h.service.ResubmitRefundOrder(
+		c.Request.Context(),
+		previousRefundID,
+		&req,
+		username.(string),
+		int(userID.(uint)),
+	)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"refund_order_id": refundOrderID,
+		"message":         "退费申请已重新提交",
+	})
+}
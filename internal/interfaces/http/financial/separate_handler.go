@@ -3,8 +3,10 @@ package financial
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	separateApp "charonoms/internal/application/financial/separate"
+	domainSeparate "charonoms/internal/domain/financial/separate"
 	"github.com/gin-gonic/gin"
 )
 
@@ -22,9 +24,11 @@ func NewSeparateAccountHandler(separateService *separateApp.SeparateAccountAppli
 
 // GetSeparateAccounts 获取分账明细列表
 // GET /api/separate-accounts
+// 传入 group_by（brand、classify、goods、month，逗号分隔可组合）时返回按维度汇总的分账金额
 func (h *SeparateAccountHandler) GetSeparateAccounts(c *gin.Context) {
 	// 解析查询参数
-	var id, uid, ordersID, childOrdersID, goodsID, paymentID, paymentType, separateType *int
+	var id, uid, ordersID, childOrdersID, goodsID, paymentID, paymentType, separateType, brandID, classifyID *int
+	var startDate, endDate *string
 	var page, pageSize int = 1, 20
 
 	if idStr := c.Query("id"); idStr != "" {
@@ -67,6 +71,22 @@ func (h *SeparateAccountHandler) GetSeparateAccounts(c *gin.Context) {
 			separateType = &typeVal
 		}
 	}
+	if brandIDStr := c.Query("brand_id"); brandIDStr != "" {
+		if brandIDVal, err := strconv.Atoi(brandIDStr); err == nil {
+			brandID = &brandIDVal
+		}
+	}
+	if classifyIDStr := c.Query("classify_id"); classifyIDStr != "" {
+		if classifyIDVal, err := strconv.Atoi(classifyIDStr); err == nil {
+			classifyID = &classifyIDVal
+		}
+	}
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		startDate = &startDateStr
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		endDate = &endDateStr
+	}
 	if pageStr := c.Query("page"); pageStr != "" {
 		if pageVal, err := strconv.Atoi(pageStr); err == nil && pageVal > 0 {
 			page = pageVal
@@ -78,10 +98,31 @@ func (h *SeparateAccountHandler) GetSeparateAccounts(c *gin.Context) {
 		}
 	}
 
+	filter := domainSeparate.SeparateListFilter{
+		ID:            id,
+		UID:           uid,
+		OrdersID:      ordersID,
+		ChildOrdersID: childOrdersID,
+		GoodsID:       goodsID,
+		PaymentID:     paymentID,
+		PaymentType:   paymentType,
+		Type:          separateType,
+		BrandID:       brandID,
+		ClassifyID:    classifyID,
+		StartDate:     startDate,
+		EndDate:       endDate,
+		Page:          page,
+		PageSize:      pageSize,
+	}
+
 	// 调用应用服务
-	response, err := h.separateService.GetSeparateAccounts(
-		id, uid, ordersID, childOrdersID, goodsID, paymentID, paymentType, separateType, page, pageSize,
-	)
+	var response interface{}
+	var err error
+	if groupBy := c.Query("group_by"); groupBy != "" {
+		response, err = h.separateService.GetSeparateAccountGroups(filter, strings.Split(groupBy, ","))
+	} else {
+		response, err = h.separateService.GetSeparateAccounts(filter)
+	}
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"code":    1,
@@ -3,6 +3,7 @@ package financial
 import (
 	"charonoms/internal/application/financial/taobao"
 	taobaoEntity "charonoms/internal/domain/financial/taobao"
+	"charonoms/internal/domain/shared/money"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -101,12 +102,12 @@ func (h *TaobaoHandler) CreateTaobaoPayment(c *gin.Context) {
 		OrderID:         &req.OrderID,
 		ZhifubaoAccount: &req.ZhifubaoAccount,
 		Payer:           &req.Payer,
-		PaymentAmount:   req.PaymentAmount,
+		PaymentAmount:   money.FromFloat(req.PaymentAmount),
 		OrderTime:       &orderTime,
 		MerchantOrder:   &req.MerchantOrder,
 	}
 
-	if err := h.service.Create(payment); err != nil {
+	if err := h.service.Create(payment, c.GetString("username")); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
 		return
 	}
@@ -123,7 +124,7 @@ func (h *TaobaoHandler) ConfirmArrival(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.ConfirmArrival(id); err != nil {
+	if err := h.service.ConfirmArrival(id, c.GetString("username")); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
 		return
 	}
@@ -140,7 +141,7 @@ func (h *TaobaoHandler) DeleteTaobaoPayment(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.Delete(id); err != nil {
+	if err := h.service.Delete(id, c.GetString("username")); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
 		return
 	}
@@ -199,7 +200,7 @@ func (h *TaobaoHandler) ClaimTaobaoPayment(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.ClaimUnclaimed(id, req.OrderID, int(userID.(uint))); err != nil {
+	if err := h.service.ClaimUnclaimed(id, req.OrderID, int(userID.(uint)), c.GetString("username")); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
 		return
 	}
@@ -83,11 +83,18 @@ func (h *UnclaimedHandler) Claim(c *gin.Context) {
 		return
 	}
 
-	// 获取当前用户ID（从会话或token中获取）
-	userID := 1 // TODO: 从认证中间件获取实际用户ID
+	// 获取当前用户ID (从JWT中间件获取)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "未登录",
+		})
+		return
+	}
 
 	// 执行认领操作
-	if err := h.service.Claim(unclaimedID, req.OrderID, userID); err != nil {
+	if err := h.service.Claim(unclaimedID, req.OrderID, int(userID.(uint)), c.GetString("username")); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
 			"message": err.Error(),
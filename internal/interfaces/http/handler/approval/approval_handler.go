@@ -3,10 +3,15 @@ package approval
 import (
 	approvalService "charonoms/internal/application/service/approval"
 	"charonoms/internal/domain/approval/entity"
+	approvalDomainService "charonoms/internal/domain/approval/service"
 	approvalDTO "charonoms/internal/interfaces/http/dto/approval"
 	"charonoms/pkg/response"
 	"fmt"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +21,7 @@ type ApprovalHandler struct {
 	flowTypeService     *approvalService.ApprovalFlowTypeService
 	flowTemplateService *approvalService.ApprovalFlowTemplateService
 	flowMgmtService     *approvalService.ApprovalFlowManagementService
+	attachmentService   *approvalService.ApprovalAttachmentService
 }
 
 // NewApprovalHandler 创建审批流处理器实例
@@ -23,11 +29,13 @@ func NewApprovalHandler(
 	flowTypeService *approvalService.ApprovalFlowTypeService,
 	flowTemplateService *approvalService.ApprovalFlowTemplateService,
 	flowMgmtService *approvalService.ApprovalFlowManagementService,
+	attachmentService *approvalService.ApprovalAttachmentService,
 ) *ApprovalHandler {
 	return &ApprovalHandler{
 		flowTypeService:     flowTypeService,
 		flowTemplateService: flowTemplateService,
 		flowMgmtService:     flowMgmtService,
+		attachmentService:   attachmentService,
 	}
 }
 
@@ -64,6 +72,7 @@ func (h *ApprovalHandler) GetApprovalFlowTypes(c *gin.Context) {
 	for _, t := range types {
 		responseData = append(responseData, approvalDTO.ApprovalFlowTypeResponse{
 			ID:         t.ID,
+			Code:       t.Code,
 			Name:       t.Name,
 			Status:     t.Status,
 			CreateTime: t.CreateTime,
@@ -124,6 +133,9 @@ func (h *ApprovalHandler) CreateApprovalFlowType(c *gin.Context) {
 		Name:   req.Name,
 		Status: req.Status,
 	}
+	if req.Code != "" {
+		flowType.Code = &req.Code
+	}
 
 	// 调用服务创建
 	if err := h.flowTypeService.Create(flowType); err != nil {
@@ -229,31 +241,142 @@ func (h *ApprovalHandler) CreateApprovalFlowTemplate(c *gin.Context) {
 		ApprovalFlowTypeID: req.ApprovalFlowTypeID,
 		Creator:            strconv.Itoa(userID),
 		Status:             0, // 默认启用
+		SelectionRules:     req.SelectionRules,
 	}
 
 	// 创建节点实体列表
+	nodes, nodeApprovers := templateNodesFromRequest(req.Nodes)
+
+	// 调用仓储创建（事务）
+	if err := h.flowTemplateService.Create(template, nodes, nodeApprovers, req.CopyUsers); err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "模板创建成功", gin.H{
+		"id": template.ID,
+	})
+}
+
+// templateNodesFromRequest 将请求中的节点转换为模板节点实体和按节点下标的审批人列表
+func templateNodesFromRequest(reqNodes []approvalDTO.ApprovalFlowTemplateNode) ([]entity.ApprovalFlowTemplateNode, map[int][]int) {
 	var nodes []entity.ApprovalFlowTemplateNode
 	nodeApprovers := make(map[int][]int)
-	for i, nodeReq := range req.Nodes {
+	for i, nodeReq := range reqNodes {
 		nodes = append(nodes, entity.ApprovalFlowTemplateNode{
-			Name: nodeReq.Name,
-			Sort: i + 1,
-			Type: nodeReq.Type,
+			Name:             nodeReq.Name,
+			Sort:             i + 1,
+			Type:             nodeReq.Type,
+			Conditions:       nodeReq.Conditions,
+			ApproverType:     nodeReq.ApproverType,
+			ApproverRoleID:   nodeReq.ApproverRoleID,
+			ApproverResolver: nodeReq.ApproverResolver,
+			SLAHours:         nodeReq.SLAHours,
+			SLAAction:        nodeReq.SLAAction,
+			SLABackupID:      nodeReq.SLABackupID,
 		})
 		nodeApprovers[i] = nodeReq.Approvers
 	}
+	return nodes, nodeApprovers
+}
 
-	// 调用仓储创建（事务）
-	if err := h.flowTemplateService.Create(template, nodes, nodeApprovers, req.CopyUsers); err != nil {
+// UpdateApprovalFlowTemplate 编辑审批流模板：生成新版本，进行中的审批流不受影响
+// @route PUT /api/approval-flow-templates/:id
+func (h *ApprovalHandler) UpdateApprovalFlowTemplate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "模板ID无效")
+		return
+	}
+
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "未授权")
+		return
+	}
+	userID := int(userIDVal.(uint))
+
+	var req approvalDTO.UpdateApprovalFlowTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	template := &entity.ApprovalFlowTemplate{
+		Name:           req.Name,
+		Creator:        strconv.Itoa(userID),
+		SelectionRules: req.SelectionRules,
+	}
+	nodes, nodeApprovers := templateNodesFromRequest(req.Nodes)
+	if err := h.flowTemplateService.CreateVersion(id, template, nodes, nodeApprovers, req.CopyUsers); err != nil {
 		response.HandleError(c, err)
 		return
 	}
 
-	response.SuccessWithMessage(c, "模板创建成功", gin.H{
-		"id": template.ID,
+	response.SuccessWithMessage(c, "模板已保存为新版本", gin.H{
+		"id":      template.ID,
+		"version": template.Version,
+	})
+}
+
+// GetApprovalFlowTemplateVersions 获取模板的全部版本及各版本上审批中的审批流数量
+// @route GET /api/approval-flow-templates/:id/versions
+func (h *ApprovalHandler) GetApprovalFlowTemplateVersions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "模板ID无效")
+		return
+	}
+
+	versions, err := h.flowTemplateService.ListVersions(id)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"versions": versions,
 	})
 }
 
+// GetApprovalFlowTemplateRunningFlows 获取绑定在该模板版本上、仍在审批中的审批流
+// @route GET /api/approval-flow-templates/:id/running-flows
+func (h *ApprovalHandler) GetApprovalFlowTemplateRunningFlows(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "模板ID无效")
+		return
+	}
+
+	flows, err := h.flowTemplateService.GetRunningFlows(id)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"flows": flows,
+	})
+}
+
+// CompareApprovalFlowTemplates 比较同一模板的两个版本
+// @route GET /api/approval-flow-templates/compare?from=1&to=2
+func (h *ApprovalHandler) CompareApprovalFlowTemplates(c *gin.Context) {
+	var req approvalDTO.CompareTemplateVersionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	diff, err := h.flowTemplateService.Compare(req.From, req.To)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, diff)
+}
+
 // UpdateApprovalFlowTemplateStatus 更新审批流模板状态
 // @route PUT /api/approval_flow_template/:id/status
 func (h *ApprovalHandler) UpdateApprovalFlowTemplateStatus(c *gin.Context) {
@@ -272,21 +395,7 @@ func (h *ApprovalHandler) UpdateApprovalFlowTemplateStatus(c *gin.Context) {
 		return
 	}
 
-	// 如果启用模板，需要禁用同类型的其他模板
-	if req.Status == 0 {
-		template, err := h.flowTemplateService.GetByID(id)
-		if err != nil {
-			response.HandleError(c, err)
-			return
-		}
-
-		if err := h.flowTemplateService.DisableSameTypeTemplates(template.ApprovalFlowTypeID, id); err != nil {
-			response.HandleError(c, err)
-			return
-		}
-	}
-
-	// 更新状态
+	// 更新状态（启用时禁用同一模板的其他版本）
 	if err := h.flowTemplateService.UpdateStatus(id, req.Status); err != nil {
 		response.HandleError(c, err)
 		return
@@ -565,7 +674,11 @@ func (h *ApprovalHandler) ApproveFlow(c *gin.Context) {
 	}
 
 	// 调用领域服务处理审批通过逻辑
-	if err := h.flowMgmtService.Approve(req.NodeCaseUserID, userID); err != nil {
+	decision := approvalDomainService.Decision{
+		Comment:       strings.TrimSpace(req.Comment),
+		AttachmentIDs: req.AttachmentIDs,
+	}
+	if err := h.flowMgmtService.Approve(req.NodeCaseUserID, userID, decision); err != nil {
 		response.HandleError(c, err)
 		return
 	}
@@ -592,10 +705,249 @@ func (h *ApprovalHandler) RejectFlow(c *gin.Context) {
 	}
 
 	// 调用领域服务处理审批驳回逻辑
-	if err := h.flowMgmtService.Reject(req.NodeCaseUserID, userID); err != nil {
+	decision := approvalDomainService.Decision{
+		Comment:       strings.TrimSpace(req.Comment),
+		AttachmentIDs: req.AttachmentIDs,
+	}
+	if err := h.flowMgmtService.Reject(req.NodeCaseUserID, userID, decision); err != nil {
 		response.HandleError(c, err)
 		return
 	}
 
 	response.SuccessWithMessage(c, "审批已驳回", nil)
 }
+
+// TransferFlow 转交待审批任务
+// @route POST /api/approval-flows/transfer
+func (h *ApprovalHandler) TransferFlow(c *gin.Context) {
+	// 获取当前用户ID
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "未授权")
+		return
+	}
+	userID := int(userIDVal.(uint))
+
+	// 绑定请求体
+	var req approvalDTO.TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.flowMgmtService.Transfer(req.NodeCaseUserID, userID, req.ToUserID, req.Remark); err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "审批已转交", nil)
+}
+
+// AddSigner 加签
+// @route POST /api/approval-flows/add-signer
+func (h *ApprovalHandler) AddSigner(c *gin.Context) {
+	// 获取当前用户ID
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "未授权")
+		return
+	}
+	userID := int(userIDVal.(uint))
+
+	// 绑定请求体
+	var req approvalDTO.AddSignerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	var position int8
+	switch req.Position {
+	case "before":
+		position = entity.AddSignBefore
+	case "after":
+		position = entity.AddSignAfter
+	default:
+		response.BadRequest(c, "加签位置无效，可选值：before、after")
+		return
+	}
+
+	err := h.flowMgmtService.AddSigner(req.NodeCaseUserID, userID, approvalDomainService.AddSignerRequest{
+		Position: position,
+		Type:     req.Type,
+		UserIDs:  req.UserIDs,
+		Remark:   req.Remark,
+	})
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "加签成功", nil)
+}
+
+// GetDelegations 获取我的外出委托
+// @route GET /api/approval-delegations
+func (h *ApprovalHandler) GetDelegations(c *gin.Context) {
+	// 获取当前用户ID
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "未授权")
+		return
+	}
+	userID := int(userIDVal.(uint))
+
+	delegations, err := h.flowMgmtService.GetDelegations(userID)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"delegations": delegations,
+	})
+}
+
+// CreateDelegation 设置外出委托
+// @route POST /api/approval-delegations
+func (h *ApprovalHandler) CreateDelegation(c *gin.Context) {
+	// 获取当前用户ID
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "未授权")
+		return
+	}
+	userID := int(userIDVal.(uint))
+
+	// 绑定请求体
+	var req approvalDTO.CreateDelegationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 按本地时间解释请求中的时间；结束时间只有日期时包含当天
+	startTime := localWallClock(req.StartTime.Time)
+	endTime := localWallClock(req.EndTime.Time)
+	if endTime.Equal(time.Date(endTime.Year(), endTime.Month(), endTime.Day(), 0, 0, 0, 0, time.Local)) {
+		endTime = endTime.AddDate(0, 0, 1)
+	}
+
+	delegation := &entity.ApprovalDelegation{
+		UserAccountID: userID,
+		DelegateID:    req.DelegateID,
+		StartTime:     startTime,
+		EndTime:       endTime,
+		Remark:        req.Remark,
+	}
+	if err := h.flowMgmtService.CreateDelegation(delegation); err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "委托设置成功", gin.H{
+		"id": delegation.ID,
+	})
+}
+
+// CancelDelegation 取消外出委托
+// @route PUT /api/approval-delegations/:id/cancel
+func (h *ApprovalHandler) CancelDelegation(c *gin.Context) {
+	// 获取当前用户ID
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "未授权")
+		return
+	}
+	userID := int(userIDVal.(uint))
+
+	// 解析路径参数
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "委托ID无效")
+		return
+	}
+
+	if err := h.flowMgmtService.CancelDelegation(id, userID); err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "委托已取消", nil)
+}
+
+// UploadAttachment 上传审批附件，审批通过/驳回时通过 attachment_ids 关联
+// @route POST /api/approval-attachments（表单字段 file）
+func (h *ApprovalHandler) UploadAttachment(c *gin.Context) {
+	// 获取当前用户ID
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "未授权")
+		return
+	}
+	userID := int(userIDVal.(uint))
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		response.BadRequest(c, "未上传文件")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.BadRequest(c, "打开文件失败")
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.attachmentService.Upload(userID, fileHeader.Filename, fileHeader.Header.Get("Content-Type"), fileHeader.Size, file)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"id":        attachment.ID,
+		"file_name": attachment.FileName,
+		"file_size": attachment.FileSize,
+		"url":       fmt.Sprintf("/api/approval-attachments/%d", attachment.ID),
+	})
+}
+
+// DownloadAttachment 下载审批附件
+// @route GET /api/approval-attachments/:id
+func (h *ApprovalHandler) DownloadAttachment(c *gin.Context) {
+	// 获取当前用户ID
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "未授权")
+		return
+	}
+	userID := int(userIDVal.(uint))
+
+	// 解析路径参数
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "附件ID无效")
+		return
+	}
+
+	attachment, file, err := h.attachmentService.Open(id, userID)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+	defer file.Close()
+
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.DataFromReader(http.StatusOK, attachment.FileSize, contentType, file, map[string]string{
+		"Content-Disposition": "attachment; filename*=UTF-8''" + url.PathEscape(attachment.FileName),
+	})
+}
+
+// localWallClock 将解析出的时间按本地时区解释（请求中的时间不带时区）
+func localWallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+}
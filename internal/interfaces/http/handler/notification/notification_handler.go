@@ -0,0 +1,110 @@
+package notification
+
+import (
+	notificationService "charonoms/internal/application/notification"
+	"charonoms/pkg/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler 通知收件箱HTTP处理器
+type NotificationHandler struct {
+	service *notificationService.Service
+}
+
+// NewNotificationHandler 创建通知收件箱处理器实例
+func NewNotificationHandler(service *notificationService.Service) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+// GetNotifications 获取本人的通知列表
+// @route GET /api/notifications?unread=1
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	// 获取当前用户ID
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "未授权")
+		return
+	}
+	userID := int(userIDVal.(uint))
+
+	unreadOnly := c.Query("unread") == "1"
+	list, unread, err := h.service.GetNotifications(userID, unreadOnly)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"notifications": list,
+		"unread_count":  unread,
+	})
+}
+
+// GetUnreadCount 获取本人的未读通知数
+// @route GET /api/notifications/unread-count
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	// 获取当前用户ID
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "未授权")
+		return
+	}
+	userID := int(userIDVal.(uint))
+
+	unread, err := h.service.GetUnreadCount(userID)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"unread_count": unread,
+	})
+}
+
+// MarkRead 标记通知已读
+// @route PUT /api/notifications/:id/read
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	// 获取当前用户ID
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "未授权")
+		return
+	}
+	userID := int(userIDVal.(uint))
+
+	// 解析路径参数
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "通知ID无效")
+		return
+	}
+
+	if err := h.service.MarkRead(id, userID); err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已标记为已读", nil)
+}
+
+// MarkAllRead 标记本人全部通知已读
+// @route PUT /api/notifications/read-all
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	// 获取当前用户ID
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "未授权")
+		return
+	}
+	userID := int(userIDVal.(uint))
+
+	if err := h.service.MarkAllRead(userID); err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "已全部标记为已读", nil)
+}
@@ -1,14 +1,19 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
 
 	"charonoms/internal/application/order"
+	"charonoms/internal/domain/order/entity"
+	"charonoms/internal/domain/shared/money"
 	orderDTO "charonoms/internal/interfaces/http/order"
 )
 
@@ -24,22 +29,46 @@ func NewOrderHandler(service *order.Service) *OrderHandler {
 	}
 }
 
-// GetOrders 获取订单列表
+// GetOrders 分页查询订单列表
+// GET /api/orders?page=1&page_size=20&student_id=&status=&create_date_start=&create_date_end=
+// &expected_payment_date_start=&expected_payment_date_end=&amount_min=&amount_max=&activity_id=&sort_by=&sort_order=
 func (h *OrderHandler) GetOrders(c *gin.Context) {
-	orders, err := h.service.GetOrders(c.Request.Context())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	query := &order.OrderListQuery{
+		ID:                       queryInt(c, "id"),
+		StudentID:                queryInt(c, "student_id"),
+		Status:                   queryInt(c, "status"),
+		CreateDateStart:          queryString(c, "create_date_start"),
+		CreateDateEnd:            queryString(c, "create_date_end"),
+		ExpectedPaymentDateStart: queryString(c, "expected_payment_date_start"),
+		ExpectedPaymentDateEnd:   queryString(c, "expected_payment_date_end"),
+		ActivityID:               queryInt(c, "activity_id"),
+		SortBy:                   c.Query("sort_by"),
+		SortOrder:                c.Query("sort_order"),
+		Page:                     queryPositiveInt(c, "page"),
+		PageSize:                 queryPositiveInt(c, "page_size"),
+	}
+
+	var err error
+	if query.AmountMin, err = queryMoney(c, "amount_min"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.AmountMax, err = queryMoney(c, "amount_max"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 确保返回空数组而不是null
-	if orders == nil {
-		orders = []map[string]interface{}{}
+	response, err := h.service.GetOrders(c.Request.Context(), query)
+	if err != nil {
+		if errors.Is(err, order.ErrInvalidListQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"orders": orders,
-	})
+	c.JSON(http.StatusOK, response)
 }
 
 // CreateOrder 创建订单
@@ -64,6 +93,8 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		ActivityIDs:         req.ActivityIDs,
 		DiscountAmount:      req.DiscountAmount,
 		ChildDiscounts:      req.ChildDiscounts,
+		Instalments:         toInstalmentRequests(req.Instalments),
+		AllocationStrategy:  req.AllocationStrategy,
 	}
 
 	for i, g := range req.GoodsList {
@@ -79,11 +110,20 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		appReq.ActivityIDs = []int{}
 	}
 	if appReq.ChildDiscounts == nil {
-		appReq.ChildDiscounts = make(map[int]float64)
+		appReq.ChildDiscounts = make(map[int]money.Money)
 	}
 
 	orderID, err := h.service.CreateOrder(c.Request.Context(), appReq)
 	if err != nil {
+		var mismatchErr *order.PriceMismatchError
+		if errors.As(err, &mismatchErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "mismatches": mismatchErr.Mismatches})
+			return
+		}
+		if errors.Is(err, order.ErrInvalidInstalments) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -94,6 +134,18 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	})
 }
 
+// toInstalmentRequests 转换分期请求为应用层DTO
+func toInstalmentRequests(reqs []orderDTO.InstalmentRequest) []order.InstalmentRequest {
+	instalments := make([]order.InstalmentRequest, 0, len(reqs))
+	for _, r := range reqs {
+		instalments = append(instalments, order.InstalmentRequest{
+			DueDate: r.DueDate.Time,
+			Amount:  r.Amount,
+		})
+	}
+	return instalments
+}
+
 // GetOrderGoods 获取订单商品列表
 func (h *OrderHandler) GetOrderGoods(c *gin.Context) {
 	orderID, err := strconv.Atoi(c.Param("id"))
@@ -120,6 +172,50 @@ func (h *OrderHandler) GetOrderGoods(c *gin.Context) {
 	})
 }
 
+// GetOrderActivityDiscounts 获取订单的活动优惠明细
+// GET /api/orders/:id/activity-discounts
+func (h *OrderHandler) GetOrderActivityDiscounts(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	discounts, err := h.service.GetOrderActivityDiscounts(c.Request.Context(), orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"activity_discounts": discounts,
+	})
+}
+
+// GetOrderStatusHistory 获取订单状态变更历史
+// GET /api/orders/:id/status-history
+func (h *OrderHandler) GetOrderStatusHistory(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	histories, err := h.service.GetOrderStatusHistory(c.Request.Context(), orderID)
+	if err != nil {
+		if err.Error() == "订单不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status_history": histories,
+	})
+}
+
 // UpdateOrder 更新订单
 func (h *OrderHandler) UpdateOrder(c *gin.Context) {
 	orderID, err := strconv.Atoi(c.Param("id"))
@@ -147,6 +243,8 @@ func (h *OrderHandler) UpdateOrder(c *gin.Context) {
 		ActivityIDs:         req.ActivityIDs,
 		DiscountAmount:      req.DiscountAmount,
 		ChildDiscounts:      req.ChildDiscounts,
+		Instalments:         toInstalmentRequests(req.Instalments),
+		AllocationStrategy:  req.AllocationStrategy,
 	}
 
 	for i, g := range req.GoodsList {
@@ -162,11 +260,20 @@ func (h *OrderHandler) UpdateOrder(c *gin.Context) {
 		appReq.ActivityIDs = []int{}
 	}
 	if appReq.ChildDiscounts == nil {
-		appReq.ChildDiscounts = make(map[int]float64)
+		appReq.ChildDiscounts = make(map[int]money.Money)
 	}
 
 	err = h.service.UpdateOrder(c.Request.Context(), orderID, appReq)
 	if err != nil {
+		var mismatchErr *order.PriceMismatchError
+		if errors.As(err, &mismatchErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "mismatches": mismatchErr.Mismatches})
+			return
+		}
+		if errors.Is(err, order.ErrInvalidInstalments) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		// 根据错误类型返回不同的状态码
 		if err.Error() == "订单不存在" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -196,13 +303,14 @@ func (h *OrderHandler) SubmitOrder(c *gin.Context) {
 		return
 	}
 
-	err = h.service.SubmitOrder(c.Request.Context(), orderID)
+	err = h.service.SubmitOrder(c.Request.Context(), orderID, c.GetString("username"))
 	if err != nil {
 		if err.Error() == "订单不存在" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		if err.Error() == "只能提交草稿状态的订单" {
+		var transitionErr *entity.IllegalTransitionError
+		if err.Error() == "只能提交草稿状态的订单" || errors.As(err, &transitionErr) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
@@ -223,13 +331,14 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 		return
 	}
 
-	err = h.service.CancelOrder(c.Request.Context(), orderID)
+	err = h.service.CancelOrder(c.Request.Context(), orderID, c.GetString("username"))
 	if err != nil {
 		if err.Error() == "订单不存在" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		if err.Error() == "只能作废草稿状态的订单" {
+		var transitionErr *entity.IllegalTransitionError
+		if err.Error() == "只能作废草稿状态的订单" || errors.As(err, &transitionErr) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
@@ -242,22 +351,44 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 	})
 }
 
-// GetChildOrders 获取子订单列表
+// GetChildOrders 分页查询子订单列表
+// GET /api/childorders?page=1&page_size=20&parentsid=&goodsid=&status=&create_date_start=&create_date_end=
+// &amount_min=&amount_max=&sort_by=&sort_order=
 func (h *OrderHandler) GetChildOrders(c *gin.Context) {
-	childOrders, err := h.service.GetChildOrders(c.Request.Context())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	query := &order.ChildOrderListQuery{
+		ID:              queryInt(c, "id"),
+		ParentsID:       queryInt(c, "parentsid"),
+		GoodsID:         queryInt(c, "goodsid"),
+		Status:          queryInt(c, "status"),
+		CreateDateStart: queryString(c, "create_date_start"),
+		CreateDateEnd:   queryString(c, "create_date_end"),
+		SortBy:          c.Query("sort_by"),
+		SortOrder:       c.Query("sort_order"),
+		Page:            queryPositiveInt(c, "page"),
+		PageSize:        queryPositiveInt(c, "page_size"),
+	}
+
+	var err error
+	if query.AmountMin, err = queryMoney(c, "amount_min"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.AmountMax, err = queryMoney(c, "amount_max"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 确保返回空数组而不是null
-	if childOrders == nil {
-		childOrders = []map[string]interface{}{}
+	response, err := h.service.GetChildOrders(c.Request.Context(), query)
+	if err != nil {
+		if errors.Is(err, order.ErrInvalidListQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"childorders": childOrders,
-	})
+	c.JSON(http.StatusOK, response)
 }
 
 // GetActiveGoodsForOrder 获取启用商品列表（用于订单）
@@ -323,15 +454,18 @@ func (h *OrderHandler) CalculateOrderDiscount(c *gin.Context) {
 	}
 
 	// 调用服务计算优惠
-	totalDiscount, childDiscounts, err := h.service.CalculateOrderDiscount(c.Request.Context(), goodsList, activityIDs)
+	result, err := h.service.CalculateOrderDiscount(c.Request.Context(), goodsList, activityIDs)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"total_discount":  totalDiscount,
-		"child_discounts": childDiscounts,
+		"total_discount":       result.TotalDiscount,
+		"child_discounts":      result.ChildDiscounts,
+		"breakdown":            result.Breakdown,
+		"applied_activity_ids": result.AppliedActivityIDs,
+		"gifts":                result.Gifts,
 	})
 }
 
@@ -362,6 +496,7 @@ func (h *OrderHandler) GetStudentUnpaidOrders(c *gin.Context) {
 			"discount_amount":       order.DiscountAmount,
 			"status":                order.Status,
 			"create_time":           order.CreateTime,
+			"instalments":           order.Instalments,
 		})
 	}
 
@@ -370,6 +505,222 @@ func (h *OrderHandler) GetStudentUnpaidOrders(c *gin.Context) {
 	})
 }
 
+// GetStudentOverdueInstalments 获取学生已逾期未收齐的分期
+// GET /api/students/:id/overdue-instalments
+func (h *OrderHandler) GetStudentOverdueInstalments(c *gin.Context) {
+	studentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid student id"})
+		return
+	}
+
+	instalments, err := h.service.GetOverdueInstalmentsByStudentID(c.Request.Context(), studentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instalments": instalments,
+	})
+}
+
+// GetOrderInstalments 获取订单分期计划及各期收款进度
+// GET /api/orders/:id/instalments
+func (h *OrderHandler) GetOrderInstalments(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	instalments, err := h.service.GetOrderInstalments(c.Request.Context(), orderID)
+	if err != nil {
+		if err.Error() == "订单不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instalments": instalments,
+	})
+}
+
+// AmendOrder 发起订单变更
+// POST /api/orders/:id/amendments
+func (h *OrderHandler) AmendOrder(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	var req orderDTO.AmendOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未获取到用户信息"})
+		return
+	}
+
+	appReq := &order.AmendOrderRequest{
+		AddGoods:            make([]order.AmendGoodsRequest, 0, len(req.AddGoods)),
+		RemoveChildOrderIDs: req.RemoveChildOrderIDs,
+		ActivityIDs:         req.ActivityIDs,
+		Instalments:         toInstalmentRequests(req.Instalments),
+		Reason:              req.Reason,
+	}
+	for _, g := range req.AddGoods {
+		appReq.AddGoods = append(appReq.AddGoods, order.AmendGoodsRequest{GoodsID: g.GoodsID})
+	}
+
+	amendmentID, err := h.service.AmendOrder(c.Request.Context(), orderID, appReq, c.GetString("username"), int(userID.(uint)))
+	if err != nil {
+		if err.Error() == "订单不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, order.ErrInvalidAmendment) || errors.Is(err, order.ErrInvalidInstalments) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "订单变更已提交审批",
+		"amendment_id": amendmentID,
+	})
+}
+
+// GetOrderAmendments 获取订单的变更单列表
+// GET /api/orders/:id/amendments
+func (h *OrderHandler) GetOrderAmendments(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	amendments, err := h.service.GetOrderAmendments(c.Request.Context(), orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"amendments": amendments,
+	})
+}
+
+// GetOrderAmendment 获取变更单详情
+// GET /api/orders/:id/amendments/:amendmentId
+func (h *OrderHandler) GetOrderAmendment(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+	amendmentID, err := strconv.Atoi(c.Param("amendmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amendment id"})
+		return
+	}
+
+	amendment, err := h.service.GetOrderAmendment(c.Request.Context(), orderID, amendmentID)
+	if err != nil {
+		if err.Error() == "变更单不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"amendment": amendment,
+	})
+}
+
+// DownloadImportTemplate 下载订单导入模板
+// GET /api/orders/import-template
+func (h *OrderHandler) DownloadImportTemplate(c *gin.Context) {
+	file, err := h.service.GenerateImportTemplate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成模板失败: " + err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", "attachment; filename=\"订单导入模板.xlsx\"")
+	c.Header("Content-Transfer-Encoding", "binary")
+
+	if err := file.Write(c.Writer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "下载模板失败: " + err.Error()})
+		return
+	}
+}
+
+// ImportOrders 从Excel批量创建草稿订单
+// POST /api/orders/import（表单字段 mode：best_effort 或 all_or_nothing）
+func (h *OrderHandler) ImportOrders(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未上传文件"})
+		return
+	}
+
+	// 检查文件格式
+	if !strings.HasSuffix(fileHeader.Filename, ".xlsx") && !strings.HasSuffix(fileHeader.Filename, ".xls") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "仅支持.xls和.xlsx格式"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "打开文件失败"})
+		return
+	}
+	defer file.Close()
+
+	f, err := excelize.OpenReader(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取Excel文件失败: " + err.Error()})
+		return
+	}
+	defer f.Close()
+
+	result, err := h.service.ImportOrdersFromExcel(c.Request.Context(), f, c.PostForm("mode"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "导入失败: " + err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"message":       fmt.Sprintf("导入完成，成功创建%d个订单", result.SuccessCount),
+		"success_count": result.SuccessCount,
+		"order_ids":     result.OrderIDs,
+	}
+	if len(result.Errors) > 0 {
+		response["errors"] = result.Errors
+	}
+
+	// 有成功创建的订单返回200；全部失败返回400
+	if result.SuccessCount > 0 {
+		c.JSON(http.StatusOK, response)
+	} else {
+		c.JSON(http.StatusBadRequest, response)
+	}
+}
+
 // GetOrderPendingAmount 获取订单待付金额
 // GET /api/orders/:id/pending-amount
 func (h *OrderHandler) GetOrderPendingAmount(c *gin.Context) {
@@ -433,3 +784,42 @@ func (h *OrderHandler) GetRefundPayments(c *gin.Context) {
 
 	c.JSON(http.StatusOK, result)
 }
+
+// queryInt 读取整数查询参数，缺省或格式错误时返回nil
+func queryInt(c *gin.Context, key string) *int {
+	if str := c.Query(key); str != "" {
+		if val, err := strconv.Atoi(str); err == nil {
+			return &val
+		}
+	}
+	return nil
+}
+
+// queryPositiveInt 读取正整数查询参数，缺省或非法时返回0
+func queryPositiveInt(c *gin.Context, key string) int {
+	if val := queryInt(c, key); val != nil && *val > 0 {
+		return *val
+	}
+	return 0
+}
+
+// queryString 读取字符串查询参数，缺省时返回nil
+func queryString(c *gin.Context, key string) *string {
+	if str := c.Query(key); str != "" {
+		return &str
+	}
+	return nil
+}
+
+// queryMoney 读取金额查询参数，缺省时返回nil
+func queryMoney(c *gin.Context, key string) (*money.Money, error) {
+	str := c.Query(key)
+	if str == "" {
+		return nil, nil
+	}
+	amount, err := money.Parse(str)
+	if err != nil {
+		return nil, fmt.Errorf("%s 金额格式错误: %s", key, str)
+	}
+	return &amount, nil
+}
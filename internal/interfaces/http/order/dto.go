@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"strings"
 	"time"
+
+	"charonoms/internal/domain/shared/money"
 )
 
 // CustomTime 自定义时间类型，支持多种格式解析
@@ -32,8 +34,8 @@ func (ct *CustomTime) UnmarshalJSON(b []byte) error {
 		"2006-01-02 15:04:05",           // MySQL datetime (使用本地时区)
 		"2006-01-02 15:04",              // datetime without seconds (使用本地时区)
 		"2006-01-02",                    // Date only (使用本地时区)
-		time.RFC3339,                     // RFC3339 (带时区，使用UTC)
-		time.RFC3339Nano,                 // RFC3339Nano (带时区，使用UTC)
+		time.RFC3339,                    // RFC3339 (带时区，使用UTC)
+		time.RFC3339Nano,                // RFC3339Nano (带时区，使用UTC)
 	}
 
 	var err error
@@ -63,28 +65,52 @@ func (ct CustomTime) MarshalJSON() ([]byte, error) {
 
 // GoodsItemRequest 商品项请求
 type GoodsItemRequest struct {
-	GoodsID    int     `json:"goods_id"`
-	TotalPrice float64 `json:"total_price"`
-	Price      float64 `json:"price"`
+	GoodsID    int         `json:"goods_id"`
+	TotalPrice money.Money `json:"total_price"`
+	Price      money.Money `json:"price"`
+}
+
+// InstalmentRequest 分期请求
+type InstalmentRequest struct {
+	DueDate CustomTime  `json:"due_date"`
+	Amount  money.Money `json:"amount"`
 }
 
 // CreateOrderRequest 创建订单请求
 type CreateOrderRequest struct {
-	StudentID           int                  `json:"student_id"`
-	GoodsList           []GoodsItemRequest   `json:"goods_list"`
-	ExpectedPaymentTime *CustomTime          `json:"expected_payment_time"`
-	ActivityIDs         []int                `json:"activity_ids"`
-	DiscountAmount      float64              `json:"discount_amount"`
-	ChildDiscounts      map[int]float64      `json:"child_discounts"`
+	StudentID           int                 `json:"student_id"`
+	GoodsList           []GoodsItemRequest  `json:"goods_list"`
+	ExpectedPaymentTime *CustomTime         `json:"expected_payment_time"`
+	ActivityIDs         []int               `json:"activity_ids"`
+	DiscountAmount      money.Money         `json:"discount_amount"`
+	ChildDiscounts      map[int]money.Money `json:"child_discounts"`
+	Instalments         []InstalmentRequest `json:"instalments"`
+	AllocationStrategy  string              `json:"allocation_strategy"`
+}
+
+// AmendGoodsRequest 变更新增的商品
+type AmendGoodsRequest struct {
+	GoodsID int `json:"goods_id"`
+}
+
+// AmendOrderRequest 订单变更请求，activity_ids 不传时沿用订单当前活动
+type AmendOrderRequest struct {
+	AddGoods            []AmendGoodsRequest `json:"add_goods"`
+	RemoveChildOrderIDs []int               `json:"remove_childorder_ids"`
+	ActivityIDs         []int               `json:"activity_ids"`
+	Instalments         []InstalmentRequest `json:"instalments"`
+	Reason              string              `json:"reason"`
 }
 
 // UpdateOrderRequest 更新订单请求
 type UpdateOrderRequest struct {
-	GoodsList           []GoodsItemRequest   `json:"goods_list"`
-	ExpectedPaymentTime *CustomTime          `json:"expected_payment_time"`
-	ActivityIDs         []int                `json:"activity_ids"`
-	DiscountAmount      float64              `json:"discount_amount"`
-	ChildDiscounts      map[int]float64      `json:"child_discounts"`
+	GoodsList           []GoodsItemRequest  `json:"goods_list"`
+	ExpectedPaymentTime *CustomTime         `json:"expected_payment_time"`
+	ActivityIDs         []int               `json:"activity_ids"`
+	DiscountAmount      money.Money         `json:"discount_amount"`
+	ChildDiscounts      map[int]money.Money `json:"child_discounts"`
+	Instalments         []InstalmentRequest `json:"instalments"`
+	AllocationStrategy  string              `json:"allocation_strategy"`
 }
 
 // CalculateDiscountRequest 优惠计算请求
@@ -95,6 +121,15 @@ type CalculateDiscountRequest struct {
 
 // CalculateDiscountResponse 优惠计算响应
 type CalculateDiscountResponse struct {
-	TotalDiscount  float64         `json:"total_discount"`
-	ChildDiscounts map[int]float64 `json:"child_discounts"`
+	TotalDiscount  money.Money         `json:"total_discount"`
+	ChildDiscounts map[int]money.Money `json:"child_discounts"`
+	Gifts          []GiftResponse      `json:"gifts"`
+}
+
+// GiftResponse 满赠赠品响应
+type GiftResponse struct {
+	ActivityID int    `json:"activity_id"`
+	GoodsID    int    `json:"goods_id"`
+	GoodsName  string `json:"goods_name"`
+	Quantity   int    `json:"quantity"`
 }
@@ -20,7 +20,9 @@ import (
 	separateAppService "charonoms/internal/application/financial/separate"
 	taobaoAppService "charonoms/internal/application/financial/taobao"
 	unclaimedAppService "charonoms/internal/application/financial/unclaimed"
+	reconciliationAppService "charonoms/internal/application/financial/reconciliation"
 	refundAppService "charonoms/internal/application/financial/refund"
+	notificationAppService "charonoms/internal/application/notification"
 	"charonoms/internal/infrastructure/config"
 	"charonoms/internal/infrastructure/persistence"
 	financialImpl "charonoms/internal/infrastructure/persistence/financial"
@@ -37,6 +39,11 @@ import (
 	studentImpl "charonoms/internal/infrastructure/persistence/mysql/student"
 	orderImpl "charonoms/internal/infrastructure/persistence/order"
 	"charonoms/internal/infrastructure/persistence/mysql"
+	"charonoms/internal/infrastructure/paymentgateway"
+	"charonoms/internal/infrastructure/scheduler"
+	"charonoms/internal/infrastructure/storage"
+	"charonoms/internal/infrastructure/statement"
+	"charonoms/internal/infrastructure/logger"
 	"charonoms/internal/interfaces/http/handler/account"
 	"charonoms/internal/interfaces/http/handler/approval"
 	"charonoms/internal/interfaces/http/handler/attribute"
@@ -47,6 +54,7 @@ import (
 	"charonoms/internal/interfaces/http/handler/coach"
 	"charonoms/internal/interfaces/http/handler/contract"
 	"charonoms/internal/interfaces/http/handler/goods"
+	"charonoms/internal/interfaces/http/handler/notification"
 	"charonoms/internal/interfaces/http/handler/placeholder"
 	"charonoms/internal/interfaces/http/handler/rbac"
 	"charonoms/internal/interfaces/http/handler/student"
@@ -56,12 +64,20 @@ import (
 	approvalDomainService "charonoms/internal/domain/approval/service"
 	paymentDomainService "charonoms/internal/domain/financial/payment"
 	separateDomainService "charonoms/internal/domain/financial/separate"
+	refundDomain "charonoms/internal/domain/financial/refund"
+	orderEntity "charonoms/internal/domain/order/entity"
+	orderDomainService "charonoms/internal/domain/order/service"
+	notificationDomain "charonoms/internal/domain/notification"
+	"context"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // SetupRouter configure router
-func SetupRouter(cfg *config.Config) *gin.Engine {
+// ctx 控制后台任务的生命周期，取消后后台任务停止
+func SetupRouter(ctx context.Context, cfg *config.Config) *gin.Engine {
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
 
@@ -73,7 +89,7 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 	r.Use(middleware.CORS(cfg.CORS))
 
 	// Initialize dependencies
-	setupDependencies(r, cfg)
+	setupDependencies(ctx, r, cfg)
 
 	// Static files (frontend)
 	r.Static("/frontend", "./frontend")
@@ -83,7 +99,7 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 }
 
 // setupDependencies setup dependency injection
-func setupDependencies(r *gin.Engine, cfg *config.Config) {
+func setupDependencies(ctx context.Context, r *gin.Engine, cfg *config.Config) {
 	// RBAC module (初始化在前，因为 AuthService 需要依赖 roleRepo)
 	roleRepo := rbacImpl.NewRoleRepository(mysql.DB)
 	permissionRepo := rbacImpl.NewPermissionRepository(mysql.DB)
@@ -146,10 +162,29 @@ func setupDependencies(r *gin.Engine, cfg *config.Config) {
 	separateRepo := financialImpl.NewSeparateAccountRepository(mysql.DB)
 	taobaoRepo := financialImpl.NewTaobaoPaymentRepository(mysql.DB)
 
+	// Separate allocation setting (used by payment splits, amendments and refunds)
+	if !orderEntity.IsValidAllocationStrategy(cfg.Separate.AllocationStrategy) {
+		logger.Fatal("Invalid separate allocation strategy", zap.String("strategy", cfg.Separate.AllocationStrategy))
+	}
+	allocationSetting := orderEntity.AllocationSetting{
+		Strategy:         cfg.Separate.AllocationStrategy,
+		ClassifyPriority: cfg.Separate.ClassifyPriority,
+	}
+
+	// Approval domain service (need to initialize before order and refund modules, which start approval flows)
+	approvalTypeRepo := approvalImpl.NewApprovalFlowTypeRepository(mysql.DB)
+	approvalTemplateRepo := approvalImpl.NewApprovalFlowTemplateRepository(mysql.DB)
+	approvalMgmtRepo := approvalImpl.NewApprovalFlowManagementRepository(mysql.DB)
+	approvalNodeRepo := approvalImpl.NewApprovalNodeCaseRepository(mysql.DB)
+	approvalDomainSvc := approvalDomainService.NewApprovalFlowService(approvalMgmtRepo, approvalNodeRepo, approvalTemplateRepo, mysql.DB)
+	approvalDomainSvc.RegisterCompletionHook(approvalDomainService.BusinessTypeRefund, refundDomain.NewApprovalHook(mysql.DB, allocationSetting))
+	approvalDomainSvc.RegisterCompletionHook(approvalDomainService.BusinessTypeOrderAmendment, orderDomainService.NewOrderAmendmentService(mysql.DB, allocationSetting))
+	approvalDomainSvc.RegisterApproverResolver(approvalDomainService.ApproverResolverSuperAdmin, approvalDomainService.SuperAdminApprovers)
+
 	// Order module
 	orderRepo := orderImpl.NewOrderRepository(mysql.DB)
 	childOrderRepo := orderImpl.NewChildOrderRepository(mysql.DB)
-	orderSvc := orderService.NewService(orderRepo, childOrderRepo, goodsRepo, paymentRepo, taobaoRepo, mysql.DB)
+	orderSvc := orderService.NewService(orderRepo, childOrderRepo, goodsRepo, paymentRepo, taobaoRepo, allocationSetting, approvalDomainSvc, mysql.DB)
 	orderHdl := handler.NewOrderHandler(orderSvc)
 
 	// Activity Template module
@@ -162,27 +197,32 @@ func setupDependencies(r *gin.Engine, cfg *config.Config) {
 	activitySvc := activityService.NewService(activityRepo, activityTemplateRepo, mysql.DB)
 	activityHdl := handler.NewActivityHandler(activitySvc)
 
-	// Approval module
-	approvalTypeRepo := approvalImpl.NewApprovalFlowTypeRepository(mysql.DB)
-	approvalTemplateRepo := approvalImpl.NewApprovalFlowTemplateRepository(mysql.DB)
-	approvalMgmtRepo := approvalImpl.NewApprovalFlowManagementRepository(mysql.DB)
-	approvalNodeRepo := approvalImpl.NewApprovalNodeCaseRepository(mysql.DB)
-	approvalDomainSvc := approvalDomainService.NewApprovalFlowService(approvalMgmtRepo, approvalNodeRepo, approvalTemplateRepo, mysql.DB)
+	// Approval module (domain service initialized earlier for order and refund dependency)
 	approvalTypeSvc := approvalService.NewApprovalFlowTypeService(approvalTypeRepo)
-	approvalTemplateSvc := approvalService.NewApprovalFlowTemplateService(approvalTemplateRepo, approvalTypeRepo)
+	approvalTemplateSvc := approvalService.NewApprovalFlowTemplateService(approvalTemplateRepo, approvalTypeRepo, approvalDomainSvc)
 	approvalMgmtSvc := approvalService.NewApprovalFlowManagementService(approvalMgmtRepo, approvalNodeRepo, approvalDomainSvc)
-	approvalHdl := approval.NewApprovalHandler(approvalTypeSvc, approvalTemplateSvc, approvalMgmtSvc)
+	attachmentStorage, err := storage.New(cfg.Storage)
+	if err != nil {
+		logger.Fatal("Failed to init file storage", zap.Error(err))
+	}
+	approvalAttachmentSvc := approvalService.NewApprovalAttachmentService(mysql.DB, attachmentStorage)
+	approvalHdl := approval.NewApprovalHandler(approvalTypeSvc, approvalTemplateSvc, approvalMgmtSvc, approvalAttachmentSvc)
 
 	// Financial module (repositories initialized earlier for order module dependency)
-	paymentDomainSvc := paymentDomainService.NewPaymentDomainService(paymentRepo, orderRepo, childOrderRepo)
-	separateDomainSvc := separateDomainService.NewSeparateAccountDomainService(separateRepo, paymentRepo, childOrderRepo)
-	paymentAppSvc := paymentAppService.NewPaymentApplicationService(mysql.DB, paymentRepo, studentRepo, paymentDomainSvc, separateDomainSvc)
+	orderStatusSvc := orderDomainService.NewOrderStatusService(mysql.DB)
+	paymentDomainSvc := paymentDomainService.NewPaymentDomainService(paymentRepo, orderRepo, childOrderRepo, orderStatusSvc)
+	separateDomainSvc := separateDomainService.NewSeparateAccountDomainService(separateRepo, paymentRepo, childOrderRepo, allocationSetting)
+	paymentGateway, err := paymentgateway.New(cfg.PaymentGateway)
+	if err != nil {
+		logger.Fatal("Failed to init payment gateway", zap.Error(err))
+	}
+	paymentAppSvc := paymentAppService.NewPaymentApplicationService(mysql.DB, paymentRepo, studentRepo, paymentDomainSvc, separateDomainSvc, paymentGateway)
 	separateAppSvc := separateAppService.NewSeparateAccountApplicationService(separateRepo)
 	paymentHdl := financialHandler.NewPaymentHandler(paymentAppSvc)
 	separateHdl := financialHandler.NewSeparateAccountHandler(separateAppSvc)
 
 	// Taobao Payment module
-	taobaoAppSvc := taobaoAppService.NewTaobaoPaymentService(mysql.DB, taobaoRepo, paymentRepo, orderRepo, childOrderRepo, separateRepo)
+	taobaoAppSvc := taobaoAppService.NewTaobaoPaymentService(mysql.DB, taobaoRepo, paymentRepo, orderRepo, childOrderRepo, separateDomainSvc)
 	taobaoHdl := financialHandler.NewTaobaoHandler(taobaoAppSvc)
 
 	// Unclaimed Payment module
@@ -190,6 +230,11 @@ func setupDependencies(r *gin.Engine, cfg *config.Config) {
 	unclaimedAppSvc := unclaimedAppService.NewUnclaimedService(mysql.DB, unclaimedRepo, paymentRepo, orderRepo, separateDomainSvc)
 	unclaimedHdl := financialHandler.NewUnclaimedHandler(unclaimedAppSvc)
 
+	// Statement Reconciliation module
+	reconciliationRepo := financialImpl.NewReconciliationRepository(mysql.DB)
+	reconciliationAppSvc := reconciliationAppService.NewReconciliationService(mysql.DB, reconciliationRepo, paymentAppSvc, statement.Parsers())
+	reconciliationHdl := financialHandler.NewReconciliationHandler(reconciliationAppSvc)
+
 	// Refund Order module
 	refundRepo := financialImpl.NewRefundRepository(mysql.DB)
 	refundAppSvc := refundAppService.NewRefundService(
@@ -198,10 +243,22 @@ func setupDependencies(r *gin.Engine, cfg *config.Config) {
 		approvalTemplateRepo,
 		approvalMgmtRepo,
 		approvalNodeRepo,
+		allocationSetting,
+		approvalDomainSvc,
 		mysql.DB,
 	)
 	refundHdl := financialHandler.NewRefundHandler(refundAppSvc)
 
+	// Notification module
+	notificationDomainSvc := notificationDomain.NewService(mysql.DB)
+	notificationAppSvc := notificationAppService.NewService(notificationDomainSvc)
+	notificationHdl := notification.NewNotificationHandler(notificationAppSvc)
+
+	// Background jobs
+	if cfg.Scheduler.Enabled {
+		startScheduler(ctx, cfg.Scheduler, approvalDomainSvc)
+	}
+
 	// Placeholder handler for unimplemented features
 	placeholderHdl := placeholder.NewPlaceholderHandler()
 
@@ -212,6 +269,9 @@ func setupDependencies(r *gin.Engine, cfg *config.Config) {
 		api.POST("/login", authHdl.Login)
 		api.POST("/logout", authHdl.Logout)
 
+		// Payment gateway async notification (authenticated by notification signature)
+		api.POST("/payment-gateway/notify", paymentHdl.HandleGatewayNotify)
+
 		// Routes that require authentication
 		authorized := api.Group("/")
 		authorized.Use(middleware.JWTAuth())
@@ -280,6 +340,7 @@ func setupDependencies(r *gin.Engine, cfg *config.Config) {
 				students.GET("/active", studentHdl.GetActiveStudents) // Must be before /:id
 				students.GET("", studentHdl.GetStudents)
 				students.GET("/:id/unpaid-orders", orderHdl.GetStudentUnpaidOrders)
+				students.GET("/:id/overdue-instalments", orderHdl.GetStudentOverdueInstalments)
 				students.POST("", studentHdl.CreateStudent)
 				students.PUT("/:id", studentHdl.UpdateStudent)
 				students.PUT("/:id/status", studentHdl.UpdateStudentStatus)
@@ -302,8 +363,16 @@ func setupDependencies(r *gin.Engine, cfg *config.Config) {
 			{
 				orders.GET("", orderHdl.GetOrders)
 				orders.POST("", orderHdl.CreateOrder)
+				orders.GET("/import-template", orderHdl.DownloadImportTemplate)
+				orders.POST("/import", orderHdl.ImportOrders)
 				orders.GET("/:id/goods", orderHdl.GetOrderGoods)
+				orders.GET("/:id/activity-discounts", orderHdl.GetOrderActivityDiscounts)
+				orders.GET("/:id/status-history", orderHdl.GetOrderStatusHistory)
 				orders.GET("/:id/pending-amount", orderHdl.GetOrderPendingAmount)
+				orders.GET("/:id/instalments", orderHdl.GetOrderInstalments)
+				orders.GET("/:id/amendments", orderHdl.GetOrderAmendments)
+				orders.GET("/:id/amendments/:amendmentId", orderHdl.GetOrderAmendment)
+				orders.POST("/:id/amendments", orderHdl.AmendOrder)
 				orders.GET("/:id/refund-info", orderHdl.GetOrderRefundInfo)
 				orders.POST("/:id/refund-payments", orderHdl.GetRefundPayments)
 				orders.PUT("/:id", orderHdl.UpdateOrder)
@@ -319,6 +388,7 @@ func setupDependencies(r *gin.Engine, cfg *config.Config) {
 			authorized.GET("/refund-orders", refundHdl.GetRefundOrders)
 			authorized.POST("/refund-orders", refundHdl.CreateRefundOrder)
 			authorized.GET("/refund-orders/:id", refundHdl.GetRefundOrderDetail)
+			authorized.POST("/refund-orders/:id/resubmit", refundHdl.ResubmitRefundOrder)
 			authorized.GET("/refund-childorders", refundHdl.GetRefundChildOrders)
 			authorized.GET("/refund-regular-supplements", refundHdl.GetRefundRegularSupplements)
 			authorized.GET("/refund-taobao-supplements", refundHdl.GetRefundTaobaoSupplements)
@@ -384,8 +454,12 @@ func setupDependencies(r *gin.Engine, cfg *config.Config) {
 			approvalTemplates := authorized.Group("/approval-flow-templates")
 			{
 				approvalTemplates.GET("", approvalHdl.GetApprovalFlowTemplates)
+				approvalTemplates.GET("/compare", approvalHdl.CompareApprovalFlowTemplates)
 				approvalTemplates.GET("/:id", approvalHdl.GetApprovalFlowTemplateDetail)
+				approvalTemplates.GET("/:id/versions", approvalHdl.GetApprovalFlowTemplateVersions)
+				approvalTemplates.GET("/:id/running-flows", approvalHdl.GetApprovalFlowTemplateRunningFlows)
 				approvalTemplates.POST("", approvalHdl.CreateApprovalFlowTemplate)
+				approvalTemplates.PUT("/:id", approvalHdl.UpdateApprovalFlowTemplate)
 				approvalTemplates.PUT("/:id/status", approvalHdl.UpdateApprovalFlowTemplateStatus)
 			}
 
@@ -401,6 +475,32 @@ func setupDependencies(r *gin.Engine, cfg *config.Config) {
 				approvalFlows.PUT("/:id/cancel", approvalHdl.CancelApprovalFlow)
 				approvalFlows.POST("/approve", approvalHdl.ApproveFlow)
 				approvalFlows.POST("/reject", approvalHdl.RejectFlow)
+				approvalFlows.POST("/transfer", approvalHdl.TransferFlow)
+				approvalFlows.POST("/add-signer", approvalHdl.AddSigner)
+			}
+
+			// 审批附件：先上传，审批通过/驳回时通过 attachment_ids 关联
+			approvalAttachments := authorized.Group("/approval-attachments")
+			{
+				approvalAttachments.POST("", approvalHdl.UploadAttachment)
+				approvalAttachments.GET("/:id", approvalHdl.DownloadAttachment)
+			}
+
+			// 审批外出委托
+			approvalDelegations := authorized.Group("/approval-delegations")
+			{
+				approvalDelegations.GET("", approvalHdl.GetDelegations)
+				approvalDelegations.POST("", approvalHdl.CreateDelegation)
+				approvalDelegations.PUT("/:id/cancel", approvalHdl.CancelDelegation)
+			}
+
+			// 通知收件箱
+			notifications := authorized.Group("/notifications")
+			{
+				notifications.GET("", notificationHdl.GetNotifications)
+				notifications.GET("/unread-count", notificationHdl.GetUnreadCount)
+				notifications.PUT("/read-all", notificationHdl.MarkAllRead)
+				notifications.PUT("/:id/read", notificationHdl.MarkRead)
 			}
 
 			// Approval Flow Management - menu placeholder
@@ -455,16 +555,32 @@ func setupDependencies(r *gin.Engine, cfg *config.Config) {
 			{
 				paymentCollections.GET("", paymentHdl.GetPaymentCollections)
 				paymentCollections.POST("", paymentHdl.CreatePaymentCollection)
+				paymentCollections.POST("/online", paymentHdl.CreateOnlineCharge)
 				paymentCollections.PUT("/:id/confirm", paymentHdl.ConfirmPaymentCollection)
 				paymentCollections.DELETE("/:id", paymentHdl.DeletePaymentCollection)
 			}
 
+			// Mock payment gateway: simulate the payer completing an online payment (dev/test only)
+			if _, ok := paymentGateway.(*paymentgateway.MockGateway); ok && cfg.PaymentGateway.EnableMockPay {
+				logger.Warn("Mock payment gateway pay route enabled, do not use in production")
+				authorized.POST("/payment-gateway/mock/pay", paymentHdl.SimulateGatewayPayment)
+			}
+
 			// Separate Account Management
 			separateAccounts := authorized.Group("/separate-accounts")
 			{
 				separateAccounts.GET("", separateHdl.GetSeparateAccounts)
 			}
 
+			// Statement Reconciliation
+			reconciliations := authorized.Group("/reconciliations")
+			{
+				reconciliations.GET("", reconciliationHdl.GetBatches)
+				reconciliations.POST("", reconciliationHdl.UploadStatement)
+				reconciliations.GET("/:id", reconciliationHdl.GetReport)
+				reconciliations.POST("/:id/confirm", reconciliationHdl.ConfirmMatched)
+			}
+
 			// Taobao Payment Management
 			taobaoPayments := authorized.Group("/taobao-payments")
 			{
@@ -501,4 +617,28 @@ func setupDependencies(r *gin.Engine, cfg *config.Config) {
 			authorized.GET("/refund_payment_detail", placeholderHdl.HandlePlaceholder)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// startScheduler 启动后台任务：审批超时扫描（提醒、升级、自动审批）
+// 多实例部署时各实例都会启动调度器，通过数据库租约保证同一任务同一时刻只有一个实例执行
+func startScheduler(ctx context.Context, cfg config.SchedulerConfig, approvalDomainSvc *approvalDomainService.ApprovalFlowService) {
+	interval := time.Duration(cfg.ApprovalSLAInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	leaseTTL := time.Duration(cfg.LeaseTTL) * time.Second
+	if leaseTTL <= interval {
+		leaseTTL = 2 * interval
+	}
+	batch := cfg.ApprovalSLABatch
+	if batch <= 0 {
+		batch = 100
+	}
+
+	jobScheduler := scheduler.New(scheduler.NewLease(mysql.DB, scheduler.DefaultOwner(), leaseTTL))
+	jobScheduler.Register("approval_sla", interval, func(ctx context.Context) error {
+		_, err := approvalDomainSvc.ProcessOverdueNodeCases(time.Now(), batch)
+		return err
+	})
+	jobScheduler.Start(ctx)
+}
@@ -0,0 +1,22 @@
+-- Migration Script: Gift-with-purchase (满赠) activities
+-- Date: 2026-10-18
+-- Description: Add gift goods to activity tiers and link gift child orders to their activity
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 满赠档位：threshold_amount 为门槛金额，discount_value 为赠品数量
+ALTER TABLE `activity_detail`
+  ADD COLUMN `gift_goods_id` INT NULL COMMENT '赠品商品ID（满赠类型使用）' AFTER `discount_value`;
+
+-- 赠品子订单：零应收，不参与分账和退费
+ALTER TABLE `childorders`
+  ADD COLUMN `gift_activity_id` INT NULL COMMENT '赠品关联的满赠活动ID（非赠品为空）' AFTER `status`,
+  ADD INDEX `idx_gift_activity_id` (`gift_activity_id`);
+
+-- Verification queries
+SELECT 'Gift activity columns added successfully!' AS status;
+DESCRIBE activity_detail;
+DESCRIBE childorders;
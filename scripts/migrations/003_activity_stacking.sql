@@ -0,0 +1,37 @@
+-- Migration Script: Activity stacking rules and per-activity discount breakdown
+-- Date: 2026-10-18
+-- Description: Add stacking mode/group to activity templates and store which activity discounted which child order
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 叠加方式：0=可叠加, 1=互斥, 2=同组择优（同一 stack_group 内只取优惠最大的活动）
+ALTER TABLE `activity_template`
+  ADD COLUMN `stack_mode` TINYINT NOT NULL DEFAULT 0 COMMENT '叠加方式：0=可叠加,1=互斥,2=同组择优' AFTER `select_type`,
+  ADD COLUMN `stack_group` VARCHAR(50) NULL COMMENT '叠加组（同组择优时使用）' AFTER `stack_mode`;
+
+-- 订单活动关联：记录该活动在订单中实际生效的优惠金额（未生效为0）
+ALTER TABLE `orders_activity`
+  ADD COLUMN `discount_amount` DECIMAL(10,2) NOT NULL DEFAULT 0.00 COMMENT '该活动实际优惠金额' AFTER `activity_id`;
+
+-- 订单活动优惠明细：每个活动对每个子订单贡献的优惠金额
+CREATE TABLE IF NOT EXISTS `orders_activity_detail` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `orders_id` INT NOT NULL COMMENT '订单ID',
+  `activity_id` INT NOT NULL COMMENT '活动ID',
+  `childorder_id` INT NOT NULL COMMENT '子订单ID',
+  `goods_id` INT NOT NULL COMMENT '商品ID',
+  `discount_amount` DECIMAL(10,2) NOT NULL DEFAULT 0.00 COMMENT '优惠金额',
+  `create_time` DATETIME DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_orders_id` (`orders_id`),
+  KEY `idx_childorder_id` (`childorder_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='订单活动优惠明细';
+
+-- Verification queries
+SELECT 'Activity stacking columns added successfully!' AS status;
+DESCRIBE activity_template;
+DESCRIBE orders_activity;
+DESCRIBE orders_activity_detail;
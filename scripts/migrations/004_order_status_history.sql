@@ -0,0 +1,25 @@
+-- Migration Script: Order status history
+-- Date: 2026-10-18
+-- Description: Audit table for order status transitions made through the order state machine
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 订单状态变更记录：每次状态流转写入一行（操作人、原状态、新状态、原因）
+CREATE TABLE IF NOT EXISTS `order_status_history` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `order_id` INT NOT NULL COMMENT '订单ID',
+  `from_status` INT NOT NULL COMMENT '原状态',
+  `to_status` INT NOT NULL COMMENT '新状态',
+  `operator` VARCHAR(100) NOT NULL DEFAULT 'system' COMMENT '操作人（系统自动变更为system）',
+  `reason` VARCHAR(255) NULL COMMENT '变更原因',
+  `create_time` DATETIME DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_order_id` (`order_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='订单状态变更记录';
+
+-- Verification queries
+SELECT 'Order status history table created successfully!' AS status;
+DESCRIBE order_status_history;
@@ -0,0 +1,31 @@
+-- Migration Script: Order instalment plans
+-- Date: 2026-10-18
+-- Description: Allow an order to carry several expected payment dates and record which instalment a payment was matched to
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 订单分期：每期一个到期日和金额，各期金额合计等于订单实收金额
+-- 未设置分期的订单仍按 orders.expected_payment_time 视为一期
+CREATE TABLE IF NOT EXISTS `order_instalment` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `order_id` INT NOT NULL COMMENT '订单ID',
+  `seq` INT NOT NULL COMMENT '期数（从1开始按到期日递增）',
+  `due_date` DATE NOT NULL COMMENT '到期日',
+  `amount` DECIMAL(10,2) NOT NULL DEFAULT 0.00 COMMENT '本期应收金额',
+  `create_time` DATETIME DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_order_seq` (`order_id`, `seq`),
+  KEY `idx_due_date` (`due_date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='订单分期';
+
+-- 收款匹配的分期（收款时第一个未收齐的分期）
+ALTER TABLE `payment_collection`
+  ADD COLUMN `instalment_id` INT NULL COMMENT '匹配的订单分期ID' AFTER `merchant_order`;
+
+-- Verification queries
+SELECT 'Order instalment table created successfully!' AS status;
+DESCRIBE order_instalment;
+DESCRIBE payment_collection;
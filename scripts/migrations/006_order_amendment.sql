@@ -0,0 +1,59 @@
+-- Migration Script: Order amendments
+-- Date: 2026-10-18
+-- Description: Allow adding or removing child orders on a submitted order through an approval flow
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 订单变更单：记录变更前后的订单金额，以及变更后的活动、活动优惠明细和分期计划
+-- 审批通过后才应用到订单（0=审批中，10=已通过，20=已驳回）
+CREATE TABLE IF NOT EXISTS `order_amendment` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `order_id` INT NOT NULL COMMENT '订单ID',
+  `approval_flow_id` INT NULL COMMENT '审批流ID',
+  `amount_receivable_before` DECIMAL(10,2) NOT NULL DEFAULT 0.00 COMMENT '变更前应收金额',
+  `amount_received_before` DECIMAL(10,2) NOT NULL DEFAULT 0.00 COMMENT '变更前实收金额',
+  `discount_amount_before` DECIMAL(10,2) NOT NULL DEFAULT 0.00 COMMENT '变更前优惠金额',
+  `amount_receivable_after` DECIMAL(10,2) NOT NULL DEFAULT 0.00 COMMENT '变更后应收金额',
+  `amount_received_after` DECIMAL(10,2) NOT NULL DEFAULT 0.00 COMMENT '变更后实收金额',
+  `discount_amount_after` DECIMAL(10,2) NOT NULL DEFAULT 0.00 COMMENT '变更后优惠金额',
+  `activity_ids` TEXT NULL COMMENT '变更后的活动ID（JSON）',
+  `activity_discounts` TEXT NULL COMMENT '变更后的活动优惠明细（JSON）',
+  `instalments` TEXT NULL COMMENT '变更后的分期计划（JSON）',
+  `reason` VARCHAR(255) NULL COMMENT '变更原因',
+  `submitter` VARCHAR(100) NULL COMMENT '提交人',
+  `submit_time` DATETIME DEFAULT CURRENT_TIMESTAMP COMMENT '提交时间',
+  `status` TINYINT NOT NULL DEFAULT 0 COMMENT '状态：0=审批中，10=已通过，20=已驳回',
+  `process_time` DATETIME NULL COMMENT '处理时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_order_id` (`order_id`),
+  KEY `idx_approval_flow_id` (`approval_flow_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='订单变更单';
+
+-- 订单变更明细：变更后每个子订单的金额（0=保留，1=新增，2=移除）
+CREATE TABLE IF NOT EXISTS `order_amendment_item` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `amendment_id` INT NOT NULL COMMENT '变更单ID',
+  `action` TINYINT NOT NULL COMMENT '动作：0=保留，1=新增，2=移除',
+  `childorder_id` INT NULL COMMENT '子订单ID（新增的子订单审批通过后回填）',
+  `goods_id` INT NOT NULL COMMENT '商品ID',
+  `gift_activity_id` INT NULL COMMENT '赠品所属满赠活动ID',
+  `amount_receivable` DECIMAL(10,2) NOT NULL DEFAULT 0.00 COMMENT '应收金额',
+  `amount_received` DECIMAL(10,2) NOT NULL DEFAULT 0.00 COMMENT '实收金额',
+  `discount_amount` DECIMAL(10,2) NOT NULL DEFAULT 0.00 COMMENT '优惠金额',
+  PRIMARY KEY (`id`),
+  KEY `idx_amendment_id` (`amendment_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='订单变更明细';
+
+-- 订单变更审批流类型（还需在审批流模板中配置并启用对应模板）
+INSERT INTO `approval_flow_type` (`name`, `status`)
+SELECT '订单变更', 0 FROM DUAL
+WHERE NOT EXISTS (SELECT 1 FROM `approval_flow_type` WHERE `name` = '订单变更');
+
+-- Verification queries
+SELECT 'Order amendment tables created successfully!' AS status;
+DESCRIBE order_amendment;
+DESCRIBE order_amendment_item;
+SELECT id, name, status FROM approval_flow_type WHERE name = '订单变更';
@@ -0,0 +1,43 @@
+-- Migration Script: Approval flow business reference
+-- Date: 2026-10-18
+-- Description: Link each approval flow to the business document it approves instead of matching by submit time
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 审批流关联的业务单据：business_type 为业务类型（refund=退费订单，order_amendment=订单变更单），business_id 为单据ID
+-- 审批完成时按 business_type 分发给对应的完成处理器
+ALTER TABLE `approval_flow_management`
+  ADD COLUMN `business_type` VARCHAR(50) NULL COMMENT '关联业务类型' AFTER `complete_time`,
+  ADD COLUMN `business_id` INT NULL COMMENT '关联业务单据ID' AFTER `business_type`,
+  ADD KEY `idx_business` (`business_type`, `business_id`);
+
+-- 回填订单变更单的审批流（变更单记录了审批流ID）
+UPDATE `approval_flow_management` afm
+INNER JOIN `order_amendment` oa ON oa.approval_flow_id = afm.id
+SET afm.business_type = 'order_amendment', afm.business_id = oa.id
+WHERE afm.business_type IS NULL;
+
+-- 回填历史退费审批流：按原匹配规则取提交时间在审批流创建时间前后5秒内最接近的退费订单
+UPDATE `approval_flow_management` afm
+INNER JOIN `approval_flow_type` aft ON aft.id = afm.approval_flow_type_id AND aft.name = '退费'
+SET afm.business_type = 'refund',
+    afm.business_id = (
+      SELECT ro.id FROM `refund_order` ro
+      WHERE ro.submit_time BETWEEN DATE_SUB(afm.create_time, INTERVAL 5 SECOND) AND DATE_ADD(afm.create_time, INTERVAL 5 SECOND)
+      ORDER BY ABS(TIMESTAMPDIFF(SECOND, ro.submit_time, afm.create_time)), ro.id
+      LIMIT 1
+    )
+WHERE afm.business_type IS NULL;
+
+-- 未匹配到退费订单的历史审批流不关联业务
+UPDATE `approval_flow_management`
+SET business_type = NULL
+WHERE business_type = 'refund' AND business_id IS NULL;
+
+-- Verification queries
+SELECT 'Approval flow business reference added successfully!' AS status;
+DESCRIBE approval_flow_management;
+SELECT business_type, COUNT(*) AS flow_count FROM approval_flow_management GROUP BY business_type;
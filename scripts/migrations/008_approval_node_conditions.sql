@@ -0,0 +1,23 @@
+-- Migration Script: Approval node conditions
+-- Date: 2026-10-18
+-- Description: Let approval template nodes carry entry conditions evaluated against the business payload of each flow
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 模板节点条件（JSON数组，全部满足才进入节点；为空表示无条件进入）
+-- 例：[{"field":"amount","op":">","value":5000}] 退费金额超过5000元才进入财务总监节点
+--     [{"field":"channel","op":"!=","value":"taobao"}] 淘宝退费跳过校区节点
+ALTER TABLE `approval_flow_template_node`
+  ADD COLUMN `conditions` TEXT NULL COMMENT '进入节点的条件（JSON）' AFTER `type`;
+
+-- 审批流业务条件数据（JSON对象，如退费金额、收款渠道），发起审批时写入，流转时据此判断节点条件
+ALTER TABLE `approval_flow_management`
+  ADD COLUMN `business_payload` TEXT NULL COMMENT '业务条件数据（JSON）' AFTER `business_id`;
+
+-- Verification queries
+SELECT 'Approval node conditions added successfully!' AS status;
+DESCRIBE approval_flow_template_node;
+DESCRIBE approval_flow_management;
@@ -0,0 +1,25 @@
+-- Migration Script: Approval node approver sources
+-- Date: 2026-10-18
+-- Description: Let template nodes name approvers by role, by the initiator's manager or by a registered resolver instead of fixed accounts
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 模板节点的审批人来源：0=指定人员（approval_node_useraccount），1=角色，2=发起人上级，3=解析器
+-- 审批人在创建节点实例时展开为 approval_node_case_user 记录，已禁用的账号不会成为审批人
+ALTER TABLE `approval_flow_template_node`
+  ADD COLUMN `approver_type` TINYINT NOT NULL DEFAULT 0 COMMENT '审批人来源：0=指定人员，1=角色，2=发起人上级，3=解析器' AFTER `type`,
+  ADD COLUMN `approver_role_id` INT NULL COMMENT '审批人角色ID（approver_type=1）' AFTER `approver_type`,
+  ADD COLUMN `approver_resolver` VARCHAR(100) NULL COMMENT '审批人解析器名称（approver_type=3）' AFTER `approver_role_id`;
+
+-- 账号的直属上级，审批节点按发起人上级审批时使用
+ALTER TABLE `useraccount`
+  ADD COLUMN `manager_id` INT UNSIGNED NULL COMMENT '直属上级账号ID' AFTER `role_id`,
+  ADD KEY `idx_manager_id` (`manager_id`);
+
+-- Verification queries
+SELECT 'Approval node approver sources added successfully!' AS status;
+DESCRIBE approval_flow_template_node;
+DESCRIBE useraccount;
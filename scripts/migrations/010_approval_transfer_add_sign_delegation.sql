@@ -0,0 +1,50 @@
+-- Migration Script: Approval transfer, add-signer and delegation
+-- Date: 2026-10-18
+-- Description: Let approvers transfer pending tasks, add signers before or after a node and set out-of-office delegates; record every action of a flow
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 加签节点：add_sign_type 0=模板节点，1=前加签（通过后原节点才能审批），2=后加签（原节点通过后审批）
+-- parent_node_case_id 为加签节点所属的节点实例
+ALTER TABLE `approval_node_case`
+  ADD COLUMN `add_sign_type` TINYINT NOT NULL DEFAULT 0 COMMENT '加签类型：0=模板节点，1=前加签，2=后加签' AFTER `sort`,
+  ADD COLUMN `parent_node_case_id` INT NULL COMMENT '加签节点所属的节点实例ID' AFTER `add_sign_type`,
+  ADD KEY `idx_parent_node_case_id` (`parent_node_case_id`);
+
+-- 审批流操作记录：审批、驳回、撤销、转交、加签、委托
+CREATE TABLE IF NOT EXISTS `approval_flow_action` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `approval_flow_management_id` INT NOT NULL COMMENT '审批流ID',
+  `approval_node_case_id` INT NULL COMMENT '节点实例ID',
+  `action` VARCHAR(30) NOT NULL COMMENT '操作：approve/reject/cancel/transfer/add_sign_before/add_sign_after/delegate',
+  `operator_id` INT NOT NULL COMMENT '操作人账号ID',
+  `from_user_id` INT NULL COMMENT '原审批人账号ID（转交、委托）',
+  `to_user_id` INT NULL COMMENT '目标审批人账号ID（转交、委托、加签）',
+  `remark` VARCHAR(500) NULL COMMENT '备注',
+  `create_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '操作时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_flow_id` (`approval_flow_management_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='审批流操作记录';
+
+-- 审批外出委托：委托期间新分配给审批人的任务自动交给委托人
+CREATE TABLE IF NOT EXISTS `approval_delegation` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `useraccount_id` INT NOT NULL COMMENT '外出的审批人账号ID',
+  `delegate_id` INT NOT NULL COMMENT '委托人账号ID',
+  `start_time` DATETIME NOT NULL COMMENT '委托开始时间',
+  `end_time` DATETIME NOT NULL COMMENT '委托结束时间（不含）',
+  `remark` VARCHAR(255) NULL COMMENT '备注',
+  `status` TINYINT NOT NULL DEFAULT 0 COMMENT '状态：0=生效，1=已取消',
+  `create_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_user_time` (`useraccount_id`, `status`, `start_time`, `end_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='审批外出委托';
+
+-- Verification queries
+SELECT 'Approval transfer, add-signer and delegation tables created successfully!' AS status;
+DESCRIBE approval_node_case;
+DESCRIBE approval_flow_action;
+DESCRIBE approval_delegation;
@@ -0,0 +1,52 @@
+-- Migration Script: Approval SLA timers, notification inbox and scheduler lease
+-- Date: 2026-10-18
+-- Description: Per-node SLA settings on approval templates, deadlines on node cases, an in-app notification inbox for reminders, and a DB lease table so background jobs run on one server instance at a time
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 模板节点处理时限：sla_hours=0 表示不限时
+-- sla_action 0=仅提醒，1=升级给备用审批人，2=自动通过，3=自动驳回；超时时总会先提醒未处理的审批人
+ALTER TABLE `approval_flow_template_node`
+  ADD COLUMN `sla_hours` INT NOT NULL DEFAULT 0 COMMENT '处理时限（小时），0=不限时' AFTER `approver_resolver`,
+  ADD COLUMN `sla_action` TINYINT NOT NULL DEFAULT 0 COMMENT '超时处理：0=仅提醒，1=升级给备用审批人，2=自动通过，3=自动驳回' AFTER `sla_hours`,
+  ADD COLUMN `sla_backup_id` INT NULL COMMENT '备用审批人账号ID（sla_action=1）' AFTER `sla_action`;
+
+-- 节点实例截止时间：创建节点实例时按模板节点处理时限计算，NULL=不限时
+ALTER TABLE `approval_node_case`
+  ADD COLUMN `deadline` DATETIME NULL COMMENT '处理截止时间' AFTER `result`,
+  ADD COLUMN `sla_status` TINYINT NOT NULL DEFAULT 0 COMMENT '超时处理状态：0=未超时，1=已超时处理' AFTER `deadline`,
+  ADD KEY `idx_sla_deadline` (`sla_status`, `deadline`);
+
+-- 站内通知收件箱
+CREATE TABLE IF NOT EXISTS `notification` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `useraccount_id` INT NOT NULL COMMENT '接收人账号ID',
+  `category` VARCHAR(50) NOT NULL COMMENT '类别：approval_reminder/approval_escalate/approval_auto',
+  `title` VARCHAR(200) NOT NULL COMMENT '标题',
+  `content` VARCHAR(1000) NULL COMMENT '内容',
+  `business_type` VARCHAR(50) NULL COMMENT '关联对象类型，如 approval_flow',
+  `business_id` INT NULL COMMENT '关联对象ID',
+  `is_read` TINYINT NOT NULL DEFAULT 0 COMMENT '0=未读，1=已读',
+  `create_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `read_time` DATETIME NULL COMMENT '已读时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_user_read` (`useraccount_id`, `is_read`, `create_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='站内通知';
+
+-- 后台任务租约：多实例部署时同一任务同一时刻只由持有租约的实例执行
+CREATE TABLE IF NOT EXISTS `scheduler_lease` (
+  `name` VARCHAR(100) NOT NULL COMMENT '任务名称',
+  `owner` VARCHAR(200) NOT NULL DEFAULT '' COMMENT '持有租约的实例标识',
+  `expires_at` DATETIME NOT NULL COMMENT '租约到期时间（数据库时间）',
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='后台任务租约';
+
+-- Verification queries
+SELECT 'Approval SLA, notification and scheduler lease tables created successfully!' AS status;
+DESCRIBE approval_flow_template_node;
+DESCRIBE approval_node_case;
+DESCRIBE notification;
+DESCRIBE scheduler_lease;
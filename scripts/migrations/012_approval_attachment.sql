@@ -0,0 +1,28 @@
+-- Migration Script: Approval decision attachments
+-- Date: 2026-10-18
+-- Description: Store files uploaded with approve/reject decisions; each attachment is linked to the approval_flow_action record of the decision, whose remark holds the decision comment
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 审批附件：审批人先上传附件，审批通过/驳回时关联到对应的操作记录
+-- 文件内容保存在文件存储中（默认本地磁盘 uploads 目录），表中只保存存储键
+CREATE TABLE IF NOT EXISTS `approval_attachment` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `file_name` VARCHAR(255) NOT NULL COMMENT '原始文件名',
+  `storage_key` VARCHAR(500) NOT NULL COMMENT '文件存储键',
+  `file_size` BIGINT NOT NULL COMMENT '文件大小（字节）',
+  `content_type` VARCHAR(100) NULL COMMENT '文件类型',
+  `uploader_id` INT NOT NULL COMMENT '上传人账号ID',
+  `approval_flow_action_id` INT NULL COMMENT '关联的审批操作记录ID，NULL=尚未使用',
+  `create_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '上传时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_action_id` (`approval_flow_action_id`),
+  KEY `idx_uploader_id` (`uploader_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='审批附件';
+
+-- Verification queries
+SELECT 'Approval attachment table created successfully!' AS status;
+DESCRIBE approval_attachment;
@@ -0,0 +1,25 @@
+-- Migration Script: Approval flow resubmission
+-- Date: 2026-10-18
+-- Description: Let the initiator edit a rejected or cancelled refund and resubmit it as a new approval flow version linked to the previous one, keeping the whole negotiation history
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 审批流版本：重新提交时新建审批流，version 递增并通过 previous_flow_id 关联上一版
+ALTER TABLE `approval_flow_management`
+  ADD COLUMN `version` INT NOT NULL DEFAULT 1 COMMENT '版本号，驳回/撤销后重新提交时递增',
+  ADD COLUMN `previous_flow_id` INT NULL COMMENT '重新提交前的审批流ID',
+  ADD KEY `idx_previous_flow_id` (`previous_flow_id`);
+
+-- 退费订单：重新提交时按修改后的内容新建退费订单，关联原退费订单
+ALTER TABLE `refund_order`
+  ADD COLUMN `previous_refund_order_id` INT NULL COMMENT '重新提交前的退费订单ID' AFTER `status`,
+  ADD KEY `idx_previous_refund_order_id` (`previous_refund_order_id`);
+
+-- Verification queries
+SELECT 'Approval flow resubmission columns added successfully!' AS status;
+SHOW COLUMNS FROM approval_flow_management LIKE 'version';
+SHOW COLUMNS FROM approval_flow_management LIKE 'previous_flow_id';
+SHOW COLUMNS FROM refund_order LIKE 'previous_refund_order_id';
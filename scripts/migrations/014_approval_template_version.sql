@@ -0,0 +1,28 @@
+-- Migration Script: Approval template versioning
+-- Date: 2026-10-18
+-- Description: Make approval templates immutable versions; editing a template creates a new version with its own node rows, new flows bind to the enabled version and running flows keep the version they started with
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 模板版本：同一模板的各版本以第一版ID（origin_template_id）关联，版本号递增
+ALTER TABLE `approval_flow_template`
+  ADD COLUMN `version` INT NOT NULL DEFAULT 1 COMMENT '版本号，编辑模板时生成新版本' AFTER `status`,
+  ADD COLUMN `origin_template_id` INT NULL COMMENT '第一版模板ID，同一模板的各版本相同' AFTER `version`;
+
+-- 已有模板均视为第一版
+UPDATE `approval_flow_template` SET `origin_template_id` = `id` WHERE `origin_template_id` IS NULL;
+
+-- 同一模板的版本号唯一，防止并发编辑产生同号版本
+ALTER TABLE `approval_flow_template`
+  ADD UNIQUE KEY `uk_origin_version` (`origin_template_id`, `version`);
+
+-- 按模板版本统计审批中的审批流
+ALTER TABLE `approval_flow_management`
+  ADD KEY `idx_template_status` (`approval_flow_template_id`, `status`);
+
+-- Verification queries
+SELECT 'Approval template versioning added successfully!' AS status;
+SELECT id, name, version, origin_template_id, status FROM approval_flow_template ORDER BY origin_template_id, version;
@@ -0,0 +1,26 @@
+-- Migration Script: Approval flow type codes and template selection rules
+-- Date: 2026-10-18
+-- Description: Give approval flow types a stable code used by business services instead of the Chinese type name, and let several enabled templates of one type carry selection rules (payee entity, amount band, payment channel) so the matching template is chosen per business document
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 审批流类型编码：业务审批按编码查找类型，名称可以自由修改
+ALTER TABLE `approval_flow_type`
+  ADD COLUMN `code` VARCHAR(50) NULL COMMENT '类型编码，业务审批按编码选择模板' AFTER `id`,
+  ADD UNIQUE KEY `uk_code` (`code`);
+
+UPDATE `approval_flow_type` SET `code` = 'refund' WHERE `name` = '退费' AND `code` IS NULL;
+UPDATE `approval_flow_type` SET `code` = 'order_amendment' WHERE `name` = '订单变更' AND `code` IS NULL;
+
+-- 模板适用条件：同类型可同时启用多个模板（每个模板只启用一个版本），发起审批时选出唯一满足条件的模板
+-- 条件格式与节点条件相同，如 [{"field":"payee_entity","op":"=","value":"xian"},{"field":"amount","op":">=","value":5000}]
+ALTER TABLE `approval_flow_template`
+  ADD COLUMN `selection_rules` JSON NULL COMMENT '适用条件，为空表示适用全部' AFTER `origin_template_id`;
+
+-- Verification queries
+SELECT 'Approval flow type codes and template selection rules added successfully!' AS status;
+SELECT id, code, name, status FROM approval_flow_type;
+SELECT id, name, approval_flow_type_id, version, status, selection_rules FROM approval_flow_template WHERE status = 0;
@@ -0,0 +1,19 @@
+-- Migration Script: Online payment gateway
+-- Date: 2026-10-18
+-- Description: Record the gateway trade number on online payment collections so signed gateway notifications confirm each payment exactly once, and index merchant_order used to look up the collection a notification belongs to
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 网关交易号：支付成功通知确认到账时写入，唯一约束防止同一笔交易重复入账
+ALTER TABLE `payment_collection`
+  ADD COLUMN `gateway_trade_no` VARCHAR(64) NULL COMMENT '在线支付网关交易号' AFTER `merchant_order`,
+  ADD UNIQUE KEY `uk_gateway_trade_no` (`gateway_trade_no`),
+  ADD KEY `idx_merchant_order` (`merchant_order`);
+
+-- Verification queries
+SELECT 'Payment gateway columns added successfully!' AS status;
+DESCRIBE payment_collection;
+SHOW INDEX FROM payment_collection WHERE Key_name IN ('uk_gateway_trade_no', 'idx_merchant_order');
@@ -0,0 +1,58 @@
+-- Migration Script: Channel statement reconciliation
+-- Date: 2026-10-18
+-- Description: Store reconciliation batches (one per uploaded channel statement) and their line-by-line results against payment_collection, taobao_payment and unclaimed, so matched unverified collections can be confirmed in bulk
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+-- 对账批次：一次账单上传
+CREATE TABLE IF NOT EXISTS `reconciliation_batch` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `channel` VARCHAR(20) NOT NULL COMMENT '对账渠道：wechat-微信 alipay-支付宝 bank-对公账户',
+  `payee_entity` TINYINT NOT NULL COMMENT '账单所属收款主体：0-北京、1-西安',
+  `file_name` VARCHAR(255) NULL COMMENT '上传的账单文件名',
+  `period_start` DATE NOT NULL COMMENT '对账期间开始日期',
+  `period_end` DATE NOT NULL COMMENT '对账期间结束日期（含）',
+  `matched_count` INT NOT NULL DEFAULT 0 COMMENT '已匹配笔数',
+  `amount_mismatch_count` INT NOT NULL DEFAULT 0 COMMENT '金额不一致笔数',
+  `missing_system_count` INT NOT NULL DEFAULT 0 COMMENT '账单有系统无笔数',
+  `missing_statement_count` INT NOT NULL DEFAULT 0 COMMENT '系统有账单无笔数',
+  `operator` INT NULL COMMENT '上传人ID',
+  `create_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_channel` (`channel`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='对账批次';
+
+-- 对账明细：一行账单和/或一条系统记录的对账结果
+CREATE TABLE IF NOT EXISTS `reconciliation_item` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `batch_id` INT NOT NULL COMMENT '对账批次ID',
+  `result` TINYINT NOT NULL COMMENT '对账结果：0-已匹配 1-金额不一致 2-账单有系统无 3-系统有账单无',
+  `matched_by` VARCHAR(20) NULL COMMENT '匹配方式：merchant_order-商户订单号 amount_date-金额和日期',
+  `line_no` INT NULL COMMENT '账单行号',
+  `line_merchant_order` VARCHAR(100) NULL COMMENT '账单商户订单号',
+  `line_trade_no` VARCHAR(100) NULL COMMENT '渠道交易号或银行流水号',
+  `line_amount` DECIMAL(10,2) NULL COMMENT '账单金额',
+  `line_trade_time` DATETIME NULL COMMENT '账单交易时间',
+  `line_payer` VARCHAR(100) NULL COMMENT '账单付款方',
+  `record_source` VARCHAR(30) NULL COMMENT '系统记录来源表：payment_collection、taobao_payment、unclaimed',
+  `record_id` INT NULL COMMENT '系统记录ID',
+  `record_amount` DECIMAL(10,2) NULL COMMENT '系统记录金额',
+  `record_status` INT NULL COMMENT '对账时系统记录的状态',
+  `confirmed` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否已按对账结果确认到账',
+  `confirm_time` DATETIME NULL COMMENT '确认到账时间',
+  `create_time` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_batch_result` (`batch_id`, `result`),
+  KEY `idx_record` (`record_source`, `record_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='对账明细';
+
+-- 对账按商户订单号查找待认领记录
+ALTER TABLE `unclaimed` ADD KEY `idx_merchant_order` (`merchant_order`);
+
+-- Verification queries
+SELECT 'Statement reconciliation tables created successfully!' AS status;
+DESCRIBE reconciliation_batch;
+DESCRIBE reconciliation_item;
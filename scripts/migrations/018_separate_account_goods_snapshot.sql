@@ -0,0 +1,21 @@
+-- Migration Script: Goods, brand and classify snapshot on separate accounts
+-- Date: 2026-10-18
+-- Description: Record the goods name, brand and classify on each separate_account row when it is written, so revenue splits can be grouped by product line without joins and are unaffected by later goods edits. Existing rows are filled by the backfill command (go run ./cmd/backfill-separate-snapshot)
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+ALTER TABLE `separate_account`
+  ADD COLUMN `brand_id` INT NULL COMMENT '分账时商品所属品牌ID' AFTER `goods_name`,
+  ADD COLUMN `brand_name` VARCHAR(100) NULL COMMENT '分账时的品牌名称' AFTER `brand_id`,
+  ADD COLUMN `classify_id` INT NULL COMMENT '分账时商品所属分类ID' AFTER `brand_name`,
+  ADD COLUMN `classify_name` VARCHAR(100) NULL COMMENT '分账时的分类名称' AFTER `classify_id`,
+  ADD KEY `idx_brand_create_time` (`brand_id`, `create_time`),
+  ADD KEY `idx_classify_create_time` (`classify_id`, `create_time`);
+
+-- Verification queries
+SELECT 'Separate account goods snapshot columns added successfully!' AS status;
+DESCRIBE separate_account;
+SELECT COUNT(*) AS rows_missing_snapshot FROM separate_account WHERE goods_name = '' OR brand_id IS NULL OR classify_id IS NULL;
@@ -0,0 +1,16 @@
+-- Migration Script: Per-order payment allocation strategy
+-- Date: 2026-10-18
+-- Description: Let an order choose how its payments are split across child orders (sequential, pro_rata or classify_priority). An empty value follows the system default configured under separate.allocation_strategy in config.yaml
+
+SET NAMES utf8mb4;
+SET CHARACTER SET utf8mb4;
+
+USE charonoms;
+
+ALTER TABLE `orders`
+  ADD COLUMN `allocation_strategy` VARCHAR(20) NOT NULL DEFAULT '' COMMENT '收款分账策略：sequential-按顺序 pro_rata-按比例 classify_priority-按分类优先级，为空时使用系统设置' AFTER `status`;
+
+-- Verification queries
+SELECT 'Order allocation strategy column added successfully!' AS status;
+DESCRIBE orders;
+SELECT allocation_strategy, COUNT(*) AS order_count FROM orders GROUP BY allocation_strategy;